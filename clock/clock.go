@@ -0,0 +1,54 @@
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is an abstraction of the current time.
+// It is intended to be injected via context, so that embedders and tests can
+// simulate time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// Func is an adapter to use an ordinary function as a Clock.
+type Func func() time.Time
+
+var _ Clock = Func(nil)
+
+// Now returns the current time by calling the function.
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// System is a Clock which returns the current system time.
+var System Clock = Func(time.Now)
+
+// Fixed returns a Clock which always returns a given time.
+func Fixed(t time.Time) Clock {
+	return Func(func() time.Time { return t })
+}
+
+// contextKey is a key of a Clock in context.
+type contextKey struct{}
+
+// WithClock returns a new context which carries a given clock.
+func WithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns a clock in a given context.
+// It returns the System clock if no clock is set.
+func FromContext(ctx context.Context) Clock {
+	if c, ok := ctx.Value(contextKey{}).(Clock); ok && c != nil {
+		return c
+	}
+	return System
+}
+
+// Now returns the current time of a clock in a given context.
+func Now(ctx context.Context) time.Time {
+	return FromContext(ctx).Now()
+}
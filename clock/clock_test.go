@@ -0,0 +1,44 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNow(t *testing.T) {
+	fixed := time.Date(2020, 11, 14, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		desc  string
+		ctx   context.Context
+		fixed bool
+	}{
+		{
+			desc:  "default",
+			ctx:   context.Background(),
+			fixed: false,
+		},
+		{
+			desc:  "fixed",
+			ctx:   WithClock(context.Background(), Fixed(fixed)),
+			fixed: true,
+		},
+		{
+			desc:  "nil clock",
+			ctx:   WithClock(context.Background(), nil),
+			fixed: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := Now(tc.ctx)
+			if tc.fixed && !got.Equal(fixed) {
+				t.Errorf("got: %s, want: %s", got, fixed)
+			}
+			if !tc.fixed && time.Since(got) > time.Minute {
+				t.Errorf("expected the system time, but got: %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	flag "github.com/spf13/pflag"
+)
+
+// AnonymizeCommand is a command which scrubs a tfstate file for sharing.
+type AnonymizeCommand struct {
+	Meta
+	out string
+}
+
+// Run runs the procedure of this command.
+func (c *AnonymizeCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("anonymize", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.out, "out", "", "Write an anonymized state to the given path")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	b, err := anonymizeStateFile(cmdFlags.Arg(0))
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if len(c.out) == 0 {
+		c.UI.Output(strings.TrimSuffix(string(b), "\n"))
+		return 0
+	}
+
+	if err := os.WriteFile(c.out, b, 0600); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to write anonymized state: %s", err))
+		return 1
+	}
+
+	return 0
+}
+
+// anonymizeStateFile reads a given tfstate file and returns an anonymized state.
+// A random salt is generated for each run, so that pseudonyms cannot be
+// reversed by guessing original values.
+func anonymizeStateFile(filename string) ([]byte, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %s", err)
+	}
+
+	state, err := tfexec.AnonymizeState(tfexec.NewState(b), salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.Bytes(), nil
+}
+
+// Help returns long-form help text.
+func (c *AnonymizeCommand) Help() string {
+	helpText := `
+Usage: tfmigrate anonymize [options] PATH
+
+Anonymize scrubs a tfstate file so that you can share it as a reproduction
+case for bug reports without leaking infrastructure details.
+
+It preserves the structure of state and resource addresses, and replaces each
+string value in attributes and outputs with a pseudonym. The same value is
+replaced with the same pseudonym within a state, so that references between
+resources are kept. Numbers and booleans are kept as they are, unless they
+are marked sensitive, in which case they are replaced with null.
+Keys of map attributes such as tags are also kept.
+Please review the result before sharing it.
+
+Only the state format version 4 (Terraform v0.12+) is supported.
+
+Arguments
+  PATH               A path to tfstate file. e.g.) terraform state pull > PATH
+
+Options:
+  --out=path         Write an anonymized state to the given path.
+                     Default to stdout.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *AnonymizeCommand) Synopsis() string {
+	return "Anonymize a tfstate file for sharing"
+}
@@ -4,41 +4,122 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strings"
+	"time"
 
 	flag "github.com/spf13/pflag"
 )
 
+// exitCodeOutsideWindow is an exit code of apply command which indicates that
+// the migration was only planned because it's outside of apply windows.
+const exitCodeOutsideWindow = 3
+
 // ApplyCommand is a command which computes a new state and pushes it to the remote state.
 type ApplyCommand struct {
 	Meta
-	backendConfig []string
+	backendConfig    []string
+	overrideWindow   bool
+	sandbox          bool
+	keepTemp         bool
+	strict           bool
+	offline          bool
+	parallelism      int
+	autoApprove      bool
+	forceUnlockStale time.Duration
 }
 
 // Run runs the procedure of this command.
 func (c *ApplyCommand) Run(args []string) int {
 	cmdFlags := flag.NewFlagSet("apply", flag.ContinueOnError)
-	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
 	cmdFlags.StringArrayVar(&c.backendConfig, "backend-config", nil, "A backend configuration for remote state")
+	cmdFlags.BoolVar(&c.overrideWindow, "override-window", false, "Apply even if it's outside of apply windows")
+	cmdFlags.BoolVar(&c.sandbox, "sandbox", false, "Apply to local copies of states without touching remote states and history")
+	cmdFlags.BoolVar(&c.keepTemp, "keep-temp", false, "Keep temporary files for debugging")
+	cmdFlags.BoolVar(&c.strict, "strict", false, "Enable a bundle of safety behaviors")
+	cmdFlags.BoolVar(&c.offline, "offline", false, "Fail if any network call other than the backend and history storage would be made")
+	cmdFlags.IntVar(&c.parallelism, "parallelism", 1, "A maximum number of migrations applied concurrently")
+	cmdFlags.BoolVar(&c.autoApprove, "auto-approve", false, "Skip interactive approval before applying")
+	cmdFlags.DurationVar(&c.forceUnlockStale, "force-unlock-stale", 0, "Force unlock a state lock older than a given duration and retry")
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
 		return 1
 	}
 
+	if c.forceUnlockStale < 0 {
+		c.UI.Error(fmt.Sprintf("--force-unlock-stale must not be negative, but got %s", c.forceUnlockStale))
+		return 1
+	}
+
+	if c.parallelism < 1 {
+		c.UI.Error(fmt.Sprintf("--parallelism must be at least 1, but got %d", c.parallelism))
+		return 1
+	}
+
 	var err error
 	if c.config, err = newConfig(c.configFile); err != nil {
 		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
 		return 1
 	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
 
 	c.Option = newOption()
 	c.Option.BackendConfig = c.backendConfig
+	c.Option.KeepTemp = c.keepTemp
+	c.Option.ForceUnlockStale = c.forceUnlockStale
+	if c.strict {
+		log.Printf("[INFO] [command] strict mode\n")
+		c.config.EnableStrictMode()
+		c.Option.CheckLineage = true
+	}
+	if c.offline {
+		log.Printf("[INFO] [command] offline mode\n")
+		if err := enableOfflineMode(c.config, c.Option); err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
 	// The option may contain sensitive values such as environment variables.
 	// So logging the option set log level to DEBUG instead of INFO.
 	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
 
+	// In sandbox mode, it never touches remote states,
+	// so read-only credentials are enough.
+	if err := setApplyCredentials(applyCredentials(os.Environ()), c.config.ReadOnlyPlan && !c.sandbox); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.sandbox {
+		// Keep the sandbox dir on exit so that users can inspect the results.
+		sandboxDir, err := os.MkdirTemp("", "tfmigrate-sandbox")
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("failed to create sandbox dir: %s", err))
+			return 1
+		}
+		c.Option.SandboxDir = sandboxDir
+		log.Printf("[INFO] [command] sandbox mode: %s\n", sandboxDir)
+	}
+
+	planOnly := false
+	// In sandbox mode, it never touches remote states,
+	// so we don't need to care about apply windows.
+	if !c.sandbox && !c.config.ApplyWindows.Contains(time.Now()) {
+		if c.overrideWindow {
+			log.Printf("[WARN] [command] override apply windows: %v\n", c.config.ApplyWindows)
+		} else {
+			c.UI.Warn(fmt.Sprintf("It's outside of apply windows: %v. Plan only without applying. Use --override-window to apply anyway.", c.config.ApplyWindows))
+			planOnly = true
+		}
+	}
+
 	if c.config.History == nil {
 		// non-history mode
 		if len(cmdFlags.Args()) != 1 {
@@ -48,11 +129,30 @@ func (c *ApplyCommand) Run(args []string) int {
 		}
 
 		migrationFile := cmdFlags.Arg(0)
+		if planOnly {
+			if err = c.planWithoutHistory(migrationFile); err != nil {
+				c.UI.Error(err.Error())
+				return 1
+			}
+			return exitCodeOutsideWindow
+		}
+
+		approved, err := c.confirm(migrationFile)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		if !approved {
+			c.UI.Error("Apply cancelled.")
+			return 1
+		}
+
 		if err = c.applyWithoutHistory(migrationFile); err != nil {
 			c.UI.Error(err.Error())
 			return 1
 		}
 
+		c.reportSandbox()
 		return 0
 	}
 
@@ -69,15 +169,91 @@ func (c *ApplyCommand) Run(args []string) int {
 		migrationFile = cmdFlags.Arg(0)
 	}
 
+	if planOnly {
+		if err = c.planWithHistory(migrationFile); err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		return exitCodeOutsideWindow
+	}
+
+	approved, err := c.confirm(migrationFile)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	if !approved {
+		c.UI.Error("Apply cancelled.")
+		return 1
+	}
+
 	// Apply all unapplied pending migrations and save them to history.
 	if err = c.applyWithHistory(migrationFile); err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
+	c.reportSandbox()
 	return 0
 }
 
+// confirm plans migrations and shows a summary of them, and then asks a user
+// to approve applying them. It returns true without asking if --auto-approve
+// is set, in sandbox mode which never touches remote states, or if stdin or
+// stdout is not a terminal, so that it never hangs in a pipeline.
+func (c *ApplyCommand) confirm(filename string) (bool, error) {
+	if c.autoApprove || c.sandbox {
+		return true, nil
+	}
+	if !isInteractive() {
+		log.Printf("[INFO] [command] skip approval in non-interactive mode\n")
+		return true, nil
+	}
+
+	runners, err := c.planForApproval(filename)
+	if err != nil {
+		return false, err
+	}
+	if len(runners) == 0 {
+		// nothing to apply.
+		return true, nil
+	}
+
+	c.UI.Output(formatPlanSummary(runners))
+	return confirmApply(c.UI)
+}
+
+// planForApproval plans migrations to be applied with diffs of addresses in
+// states, and returns their runners.
+func (c *ApplyCommand) planForApproval(filename string) ([]*FileRunner, error) {
+	showDiff := c.Option.ShowDiff
+	c.Option.ShowDiff = true
+	defer func() {
+		c.Option.ShowDiff = showDiff
+	}()
+
+	ctx := context.Background()
+	if c.config.History == nil {
+		fr, err := NewFileRunner(filename, c.config, c.Option)
+		if err != nil {
+			return nil, err
+		}
+		if err := fr.Plan(ctx); err != nil {
+			return nil, err
+		}
+		return []*FileRunner{fr}, nil
+	}
+
+	hr, err := NewHistoryRunner(ctx, filename, c.config, c.Option)
+	if err != nil {
+		return nil, err
+	}
+	if err := hr.Plan(ctx); err != nil {
+		return nil, err
+	}
+	return hr.Planned(), nil
+}
+
 // applyWithoutHistory is a helper function which applies a given migration file without history.
 func (c *ApplyCommand) applyWithoutHistory(filename string) error {
 	fr, err := NewFileRunner(filename, c.config, c.Option)
@@ -85,6 +261,11 @@ func (c *ApplyCommand) applyWithoutHistory(filename string) error {
 		return err
 	}
 
+	// Approvals are recorded in history, so that we cannot verify them.
+	if c.config.Ownership != nil && len(fr.MigrationConfig().ApprovedBy) > 0 {
+		return fmt.Errorf("approved_by requires history mode to verify approvals: %s", filename)
+	}
+
 	return fr.Apply(context.Background())
 }
 
@@ -95,10 +276,58 @@ func (c *ApplyCommand) applyWithHistory(filename string) error {
 	if err != nil {
 		return err
 	}
+	hr.SetParallelism(c.parallelism)
+
+	applier, err := resolveApplier(c.config)
+	if err != nil {
+		return err
+	}
+	hr.SetApplier(applier)
 
 	return hr.Apply(ctx)
 }
 
+// reportSandbox outputs a list of states written to the sandbox dir.
+// If not in sandbox mode, it does nothing.
+func (c *ApplyCommand) reportSandbox() {
+	if !c.sandbox {
+		return
+	}
+
+	c.UI.Output(fmt.Sprintf("Sandbox apply succeeded. The remote states and history have not been changed.\nThe new states are written to %s:", c.Option.SandboxDir))
+	files, err := os.ReadDir(c.Option.SandboxDir)
+	if err != nil {
+		c.UI.Warn(fmt.Sprintf("failed to read sandbox dir: %s", err))
+		return
+	}
+	for _, f := range files {
+		c.UI.Output("  " + f.Name())
+	}
+}
+
+// planWithoutHistory is a helper function which plans a given migration file without history.
+// It's used when apply is requested outside of apply windows.
+func (c *ApplyCommand) planWithoutHistory(filename string) error {
+	fr, err := NewFileRunner(filename, c.config, c.Option)
+	if err != nil {
+		return err
+	}
+
+	return fr.Plan(context.Background())
+}
+
+// planWithHistory is a helper function which plans unapplied pending migrations.
+// It's used when apply is requested outside of apply windows.
+func (c *ApplyCommand) planWithHistory(filename string) error {
+	ctx := context.Background()
+	hr, err := NewHistoryRunner(ctx, filename, c.config, c.Option)
+	if err != nil {
+		return err
+	}
+
+	return hr.Plan(ctx)
+}
+
 // Help returns long-form help text.
 func (c *ApplyCommand) Help() string {
 	helpText := `
@@ -107,6 +336,14 @@ Usage: tfmigrate apply [PATH]
 Apply computes a new state and pushes it to remote state.
 It will fail if terraform plan detects any diffs with the new state.
 
+When running in a terminal, it asks for approval before applying, unless
+--auto-approve is set. It never asks in non-interactive environments.
+
+Environment variables prefixed with TFMIGRATE_APPLY_ENV_ are elevated
+credentials only for apply. They are set without the prefix, such as
+TFMIGRATE_APPLY_ENV_AWS_PROFILE to AWS_PROFILE. They are required unless
+--sandbox if read_only_plan is set in the config file.
+
 Arguments
   PATH                     A path of migration file
                            Required in non-history mode. Optional in history-mode.
@@ -116,6 +353,52 @@ Options:
   --backend-config=path    A backend configuration, a path to backend configuration file or
                            key=value format backend configuraion.
                            This option is passed to terraform init when switching backend to remote.
+  --override-window        Apply even if it's outside of apply windows defined in the config file.
+                           Intended for emergencies.
+  --sandbox                Rehearse apply against local copies of remote states.
+                           It runs the full apply, but writes new states to a temporary
+                           directory instead of pushing them to remote, and doesn't
+                           save history. Apply windows are not checked.
+                           Each migration is computed from the remote states, so it
+                           fails if multiple unapplied migrations touch the same state.
+  --keep-temp              Keep temporary files such as states and plans for debugging.
+                           Note that they may contain sensitive data.
+  --strict                 Enable a bundle of safety behaviors at once:
+                           - deny force and default_force in migrations
+                           - treat warnings on checking working directories as errors
+                           - check lineage of remote states before pushing new ones
+                           - write history with compare-and-swap, which requires
+                             a history storage supporting versioning
+  --offline                Fail fast if any component would make a network call other than
+                           the backend and the history storage, such as event sinks, hooks
+                           and encryption with kms. The checkpoint service of terraform is
+                           disabled, and providers are installed only from a pre-populated
+                           local filesystem mirror in TFMIGRATE_PROVIDERS_MIRROR_DIR.
+  --parallelism=n          A maximum number of unapplied migrations applied concurrently in
+                           history mode. Default to 1. Migrations which share working directories
+                           or backends, or depend on each other, are applied in order.
+                           Once a migration fails, no more migrations start.
+  --auto-approve           Skip interactive approval before applying. When both stdin and
+                           stdout are terminals, apply plans migrations first, shows a
+                           summary of actions and diffs of addresses in states, and asks
+                           for approval. Otherwise, such as in CI, it never asks.
+  --force-unlock-stale=duration
+                           Force unlock a state lock held by someone else if it's older than
+                           a given duration such as 2h, and retry the command once. It's
+                           intended to recover from a lock left by a crashed run.
+                           A lock held by someone else is always logged with its info.
+  --var=NAME=VALUE         Set a variable which migration files can reference as var.NAME.
+                           It takes precedence over the variables block in the config file
+                           and TFMIGRATE_VAR_NAME environment variables.
+                           This option can be specified multiple times.
+  --var-file=path          A path to a variable file such as env = "prod" which sets
+                           variables for migration files. --var takes precedence over it.
+                           This option can be specified multiple times.
+
+Exit status:
+  0                        Applied successfully.
+  1                        An error occurred.
+  3                        Planned only without applying, because it's outside of apply windows.
 `
 	return strings.TrimSpace(helpText)
 }
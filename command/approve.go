@@ -0,0 +1,154 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	flag "github.com/spf13/pflag"
+)
+
+// ApproveCommand is a command which records an approval for a migration.
+type ApproveCommand struct {
+	Meta
+	approver string
+}
+
+// Run runs the procedure of this command.
+func (c *ApproveCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("approve", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	cmdFlags.StringVar(&c.approver, "approver", "", "An identity of the approver")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	if c.config.History == nil {
+		// non-history mode
+		c.UI.Error("no history setting")
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+	migrationFile := cmdFlags.Arg(0)
+
+	approver, err := resolveApprover(c.approver)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	ctx := context.Background()
+	if err := approveMigration(ctx, c.config, migrationFile, approver); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("%s approved by %s", migrationFile, approver))
+	return 0
+}
+
+// resolveApprover returns an identity of the approver.
+// If not given explicitly, it falls back to the TFMIGRATE_APPROVER
+// environment variable, and then the current OS user.
+func resolveApprover(approver string) (string, error) {
+	if len(approver) != 0 {
+		return approver, nil
+	}
+
+	if env := os.Getenv("TFMIGRATE_APPROVER"); len(env) != 0 {
+		return env, nil
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the current user. Use --approver to set an approver explicitly: %s", err)
+	}
+	return u.Username, nil
+}
+
+// resolveApplier returns an identity of the user who applies migrations in
+// the same way as the approver, so that the applier cannot approve their own
+// migrations. It returns an empty string if no approval is required.
+func resolveApplier(config *config.TfmigrateConfig) (string, error) {
+	if config.History == nil || (config.History.RequiredApprovals == 0 && config.Ownership == nil) {
+		return "", nil
+	}
+
+	applier, err := resolveApprover("")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the applier to exclude self-approvals. Set TFMIGRATE_APPROVER explicitly: %s", err)
+	}
+	return applier, nil
+}
+
+// approveMigration records an approval for a given migration and saves it to history.
+func approveMigration(ctx context.Context, config *config.TfmigrateConfig, filename string, approver string) error {
+	hc, err := history.NewController(ctx, config.MigrationDir, config.History)
+	if err != nil {
+		return err
+	}
+
+	if err := hc.Approve(filename, approver, nil); err != nil {
+		return err
+	}
+
+	approvals, err := hc.ValidApprovals(filename)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] [command] %s approved by %s (%d/%d)\n", filename, approver, len(approvals), config.History.RequiredApprovals)
+	return hc.Save(ctx)
+}
+
+// Help returns long-form help text.
+func (c *ApproveCommand) Help() string {
+	helpText := `
+Usage: tfmigrate approve [options] PATH
+
+Approve records an approval for a migration to history.
+If required_approvals is set in the history block, apply requires the number of
+approvals recorded by different approvers before it executes the migration.
+An approval by the applier is not counted. The applier is identified in the
+same way as the approver.
+An approval is bound to the content of the migration file. If the file is
+changed after approval, the approval is no longer counted.
+This command requires history mode.
+
+Note that identities are self-declared and not authenticated, so approvals are
+advisory unless write access to the history storage is restricted.
+
+Arguments
+  PATH               A migration file name in the migration directory
+
+Options:
+  --config           A path to tfmigrate config file
+  --approver         An identity of the approver.
+                     Default to the TFMIGRATE_APPROVER environment variable,
+                     or the current OS user if not set.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *ApproveCommand) Synopsis() string {
+	return "Approve a migration"
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+)
+
+func TestApproveMigration(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	historyFile := `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        }
+    },
+    "approvals": {
+        "20201109000002_test2.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:02Z",
+                "digest": "@digest(20201109000002_test2.hcl)"
+            }
+        ]
+    }
+}`
+
+	cases := []struct {
+		desc     string
+		filename string
+		approver string
+		want     int
+		ok       bool
+	}{
+		{
+			desc:     "approve",
+			filename: "20201109000002_test2.hcl",
+			approver: "bob",
+			want:     2,
+			ok:       true,
+		},
+		{
+			desc:     "same approver",
+			filename: "20201109000002_test2.hcl",
+			approver: "alice",
+			ok:       false,
+		},
+		{
+			desc:     "already applied",
+			filename: "20201109000001_test1.hcl",
+			approver: "bob",
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, migrations)
+			mockConfig := &mock.Config{
+				Data:       fillApprovalDigests(historyFile, migrations),
+				WriteError: false,
+				ReadError:  false,
+			}
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage:           mockConfig,
+					RequiredApprovals: 2,
+				},
+			}
+
+			err := approveMigration(context.Background(), config, tc.filename, tc.approver)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				h, err := history.ParseHistoryFile([]byte(mockConfig.Storage().Data()))
+				if err != nil {
+					t.Fatalf("failed to parse history file: %s", err)
+				}
+				got := len(h.Approvals(tc.filename))
+				if got != tc.want {
+					t.Errorf("got = %d, want = %d", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// CleanupCommand is a command which restores working directories and removes
+// temporary files left by crashed runs.
+type CleanupCommand struct {
+	Meta
+	dryRun        bool
+	force         bool
+	tempOlderThan time.Duration
+}
+
+// Run runs the procedure of this command.
+func (c *CleanupCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
+	cmdFlags.BoolVar(&c.dryRun, "dry-run", false, "Show leftovers without removing them")
+	cmdFlags.BoolVar(&c.force, "force", false, "Clean up even if the owner of leftovers can't be confirmed to be dead")
+	cmdFlags.DurationVar(&c.tempOlderThan, "temp-older-than", 24*time.Hour, "Remove temporary files not modified for a given duration")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	if len(c.Option.ExecPath) == 0 {
+		c.Option.ExecPath = c.config.ExecPath
+	}
+	c.Option.ExecMode = c.config.ExecMode
+	c.Option.IsBackendTerraformCloud = c.config.IsBackendTerraformCloud
+	// The option may contains sensitive values such as environment variables.
+	// So logging the option set log level to DEBUG instead of INFO.
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	dirs := cmdFlags.Args()
+	if len(dirs) == 0 {
+		dirs, err = migrationWorkDirs(c.config)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
+
+	ctx := context.Background()
+	failed := false
+	for _, dir := range dirs {
+		r, err := tfmigrate.CleanupWorkDir(ctx, dir, c.Option, c.force, c.dryRun)
+		if r != nil {
+			for _, line := range formatCleanupResult(r, c.dryRun) {
+				c.UI.Output(line)
+			}
+		}
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("%s: %s", dir, err))
+			failed = true
+		}
+	}
+
+	tempDir := c.Option.TempDir
+	if len(tempDir) == 0 {
+		tempDir = os.TempDir()
+	}
+	files, err := tfexec.StaleTempFiles(tempDir, c.tempOlderThan, time.Now())
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	for _, f := range files {
+		c.UI.Output(cleanupLine(f, "remove stale temporary file", c.dryRun))
+		if c.dryRun {
+			continue
+		}
+		if err := tfexec.RemoveStaleTempFile(f); err != nil {
+			c.UI.Error(err.Error())
+			failed = true
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// migrationWorkDirs returns a sorted list of working directories referenced by
+// migration files in the migration directory. A migration file which can't be
+// parsed is skipped with a warning, so that a broken file doesn't prevent us
+// from cleaning up the others.
+func migrationWorkDirs(config *config.TfmigrateConfig) ([]string, error) {
+	filenames, err := history.LoadMigrationFileNames(config.MigrationDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migration files: %s", err)
+	}
+
+	seen := make(map[string]bool)
+	dirs := []string{}
+	for _, filename := range filenames {
+		mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, filename), config.MigrationFileOption())
+		if err != nil {
+			log.Printf("[WARN] [command] skip a migration file which can't be parsed: %s, err: %s\n", filename, err)
+			continue
+		}
+		migrationDirs, _ := migrationDirsAndActions(mc)
+		for _, dir := range migrationDirs {
+			dir = filepath.Clean(dir)
+			if !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// formatCleanupResult returns lines which describe a result of cleaning up a
+// working directory.
+func formatCleanupResult(r *tfmigrate.CleanupResult, dryRun bool) []string {
+	if len(r.Skipped) != 0 {
+		return []string{fmt.Sprintf("%s: skipped: %s", r.Dir, r.Skipped)}
+	}
+
+	lines := []string{}
+	for _, a := range r.Actions {
+		lines = append(lines, cleanupLine(r.Dir, a, dryRun))
+	}
+	return lines
+}
+
+// cleanupLine returns a line which describes an action for a given target.
+func cleanupLine(target string, action string, dryRun bool) string {
+	if dryRun {
+		return fmt.Sprintf("%s: %s (dry-run)", target, action)
+	}
+	return fmt.Sprintf("%s: %s", target, action)
+}
+
+// Help returns long-form help text.
+func (c *CleanupCommand) Help() string {
+	helpText := `
+Usage: tfmigrate cleanup [options] [DIR...]
+
+Cleanup restores working directories and removes temporary files left by
+crashed runs. For each working directory, it removes the override file and
+the local workspace directory, switches the backend back to remote, and
+selects the workspace which was selected before the run.
+
+A working directory is skipped if it's owned by a live run, or if its owner
+can't be confirmed, such as a run on another host.
+
+Arguments
+  DIR                A working directory for executing terraform command.
+                     Multiple directories are allowed. Default to all
+                     directories referenced by migration files in the
+                     migration directory.
+
+Options:
+  --config           A path to tfmigrate config file
+  --var=NAME=VALUE   Set a variable which migration files can reference as var.NAME.
+  --var-file=path    A path to a variable file such as env = "prod" which sets
+                     variables for migration files. --var takes precedence over it.
+  --dry-run          Show leftovers without removing them
+  --force            Clean up even if the owner of leftovers can't be
+                     confirmed to be dead. Make sure no other run is in
+                     progress.
+  --temp-older-than  Remove temporary files in the temp dir which have not
+                     been modified for a given duration. Default to 24h.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *CleanupCommand) Synopsis() string {
+	return "Clean up leftovers of crashed runs"
+}
@@ -0,0 +1,114 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestMigrationWorkDirs(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "state" "test1" {
+	dir     = "network"
+	actions = [
+		"mv aws_vpc.foo module.network.aws_vpc.foo",
+	]
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "multi_state" "test2" {
+	from_dir = "network/"
+	to_dir   = "./app"
+	actions  = [
+		"mv aws_instance.foo aws_instance.foo",
+	]
+}
+`,
+		"20201109000003_test3.hcl": `
+migration "state" "test3" {
+	actions = [
+		"rm aws_instance.bar",
+	]
+}
+`,
+		"20201109000004_test4.hcl": `
+migration "state" "test4" {
+	invalid
+}
+`,
+	}
+	migrationDir := setupMigrationDir(t, migrations)
+	config := &config.TfmigrateConfig{
+		MigrationDir: migrationDir,
+	}
+
+	got, err := migrationWorkDirs(config)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	want := []string{".", "app", "network"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %#v, want = %#v", got, want)
+	}
+}
+
+func TestFormatCleanupResult(t *testing.T) {
+	cases := []struct {
+		desc   string
+		result *tfmigrate.CleanupResult
+		dryRun bool
+		want   []string
+	}{
+		{
+			desc: "actions",
+			result: &tfmigrate.CleanupResult{
+				Dir:     "network",
+				Actions: []string{"remove _tfmigrate_override.tf", "switch back to remote backend"},
+			},
+			want: []string{
+				"network: remove _tfmigrate_override.tf",
+				"network: switch back to remote backend",
+			},
+		},
+		{
+			desc: "dry-run",
+			result: &tfmigrate.CleanupResult{
+				Dir:     "network",
+				Actions: []string{"remove _tfmigrate_override.tf"},
+			},
+			dryRun: true,
+			want: []string{
+				"network: remove _tfmigrate_override.tf (dry-run)",
+			},
+		},
+		{
+			desc: "skipped",
+			result: &tfmigrate.CleanupResult{
+				Dir:     "network",
+				Skipped: "owned by a live run",
+			},
+			want: []string{
+				"network: skipped: owned by a live run",
+			},
+		},
+		{
+			desc: "nothing to do",
+			result: &tfmigrate.CleanupResult{
+				Dir: "network",
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := formatCleanupResult(tc.result, tc.dryRun)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got = %#v, want = %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	// commentCollapseLines is a number of lines of a list in a comment, over
+	// which the list is collapsed into a details block.
+	commentCollapseLines = 10
+	// commentMaxLength is a maximum length of a comment body of GitHub.
+	commentMaxLength = 65536
+)
+
+// CommentCommand is a command which posts results of the plan command to a
+// pull request as a comment.
+type CommentCommand struct {
+	Meta
+	repo   string
+	pr     int
+	apiURL string
+	id     string
+	dryRun bool
+}
+
+// Run runs the procedure of this command.
+func (c *CommentCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("comment", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.repo, "repo", os.Getenv("GITHUB_REPOSITORY"), "A repository in the format of owner/name")
+	cmdFlags.IntVar(&c.pr, "pr", 0, "A number of the pull request")
+	cmdFlags.StringVar(&c.apiURL, "api-url", "", "A URL of GitHub API")
+	cmdFlags.StringVar(&c.id, "id", "", "An identifier to distinguish multiple comments on the same pull request")
+	cmdFlags.BoolVar(&c.dryRun, "dry-run", false, "Print a comment without posting it")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) > 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 0 or 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	report, err := readPlanReport(cmdFlags.Arg(0))
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	marker := commentMarker(c.id)
+	body := formatPlanComment(report, marker)
+	if c.dryRun {
+		c.UI.Output(body)
+		return 0
+	}
+
+	if len(c.repo) == 0 {
+		c.UI.Error("no repository found. Set the --repo flag or GITHUB_REPOSITORY")
+		return 1
+	}
+	if c.pr == 0 {
+		c.pr = detectPullRequest(os.Getenv)
+	}
+	if c.pr <= 0 {
+		c.UI.Error("no pull request found. Set the --pr flag, or run it on a pull request in GitHub Actions")
+		return 1
+	}
+	token := os.Getenv("GITHUB_TOKEN")
+	if len(token) == 0 {
+		c.UI.Error("no API token found. Set GITHUB_TOKEN")
+		return 1
+	}
+
+	log.Printf("[INFO] [command] post a comment to %s#%d\n", c.repo, c.pr)
+	comment, created, err := newGitHubClient(c.apiURL, token).UpsertComment(context.Background(), c.repo, c.pr, marker, body)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if created {
+		c.UI.Output(fmt.Sprintf("Created a comment: %s", comment.HTMLURL))
+	} else {
+		c.UI.Output(fmt.Sprintf("Updated a comment: %s", comment.HTMLURL))
+	}
+	return 0
+}
+
+// readPlanReport reads an output of the plan command in JSON from a given
+// path. If the path is empty or -, it reads stdin.
+func readPlanReport(path string) (*PlanReport, error) {
+	var b []byte
+	var err error
+	if len(path) == 0 || path == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan report: %s", err)
+	}
+
+	var report PlanReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse plan report: %s", err)
+	}
+	if report.FormatVersion != planReportFormatVersion {
+		return nil, fmt.Errorf("unsupported format version of plan report: %q", report.FormatVersion)
+	}
+	return &report, nil
+}
+
+// commentMarker returns a hidden marker at the beginning of a comment, which
+// is used to find the comment to be updated.
+func commentMarker(id string) string {
+	if len(id) == 0 {
+		return "<!-- tfmigrate plan -->"
+	}
+	return fmt.Sprintf("<!-- tfmigrate plan: %s -->", id)
+}
+
+// formatPlanComment returns a comment of a given plan report in markdown.
+// A long list such as addresses in a state diff is collapsed into a details
+// block, and the comment is truncated to the limit of GitHub.
+func formatPlanComment(report *PlanReport, marker string) string {
+	var b strings.Builder
+	b.WriteString(marker + "\n")
+	if len(report.Error) == 0 {
+		b.WriteString("## tfmigrate plan: succeeded\n")
+	} else {
+		b.WriteString("## tfmigrate plan: failed\n\n")
+		writeCommentBlock(&b, "Error", "", strings.Split(report.Error, "\n"))
+	}
+
+	if len(report.Migrations) == 0 {
+		b.WriteString("\nNo migrations to plan.\n")
+	}
+
+	for _, m := range report.Migrations {
+		fmt.Fprintf(&b, "\n### `%s`\n\n", m.File)
+		if m.Skipped {
+			b.WriteString("Skipped by skip_if.\n")
+			continue
+		}
+
+		for _, a := range m.Actions {
+			fmt.Fprintf(&b, "- `%s` (%s)\n", a.Action, a.Status)
+			if len(a.Error) > 0 {
+				fmt.Fprintf(&b, "  - error: `%s`\n", strings.ReplaceAll(a.Error, "\n", " "))
+			}
+		}
+		for _, a := range m.Actions {
+			if a.Resolved == nil {
+				continue
+			}
+			b.WriteString("\n")
+			writeCommentBlock(&b, fmt.Sprintf("`%s` resolved to %d actions", a.Action, len(a.Resolved)), "", a.Resolved)
+		}
+
+		for _, d := range m.StateDiffs {
+			lines := []string{}
+			for _, address := range d.Removed {
+				lines = append(lines, "- "+address)
+			}
+			for _, address := range d.Added {
+				lines = append(lines, "+ "+address)
+			}
+			b.WriteString("\n")
+			title := fmt.Sprintf("State changes in `%s@%s`: %d added, %d removed", d.Dir, d.Workspace, len(d.Added), len(d.Removed))
+			writeCommentBlock(&b, title, "diff", lines)
+		}
+
+		for _, s := range m.PlanSummaries {
+			b.WriteString("\n")
+			title := fmt.Sprintf("Plan in `%s@%s`: %d to add, %d to change, %d to destroy", s.Dir, s.Workspace, s.Add, s.Change, s.Destroy)
+			writeCommentBlock(&b, title, "", s.Changes)
+		}
+	}
+
+	body := b.String()
+	if len(body) > commentMaxLength {
+		suffix := "\n\n(truncated)\n"
+		body = strings.ToValidUTF8(body[:commentMaxLength-len(suffix)], "") + suffix
+	}
+	return body
+}
+
+// writeCommentBlock writes a title and lines in a code block with a given
+// language for syntax highlighting. If the lines are long, they are collapsed
+// into a details block with the title as its summary. Nothing but the title
+// is written for empty lines.
+func writeCommentBlock(b *strings.Builder, title string, lang string, lines []string) {
+	if len(lines) == 0 {
+		fmt.Fprintf(b, "%s\n", title)
+		return
+	}
+
+	collapse := len(lines) > commentCollapseLines
+	if collapse {
+		// A blank line is required after the summary to render markdown.
+		fmt.Fprintf(b, "<details><summary>%s</summary>\n\n", title)
+	} else {
+		fmt.Fprintf(b, "%s\n\n", title)
+	}
+	fmt.Fprintf(b, "```%s\n%s\n```\n", lang, strings.Join(lines, "\n"))
+	if collapse {
+		b.WriteString("\n</details>\n")
+	}
+}
+
+// Help returns long-form help text.
+func (c *CommentCommand) Help() string {
+	helpText := `
+Usage: tfmigrate comment [options] [PATH]
+
+Comment posts results of the plan command to a pull request on GitHub as a
+comment. It reads an output of tfmigrate plan --format=json, and updates the
+existing comment posted by this command if any, so that a single comment is
+kept up to date on every push. Long lists such as addresses in state diffs
+are collapsed into details blocks.
+
+An API token is read from GITHUB_TOKEN. The repository and the pull request
+are detected from environment variables of GitHub Actions if not given.
+
+Arguments:
+  PATH               A path of an output of tfmigrate plan --format=json.
+                     If omitted or -, read stdin.
+
+Options:
+  --repo=owner/name  A repository. Default to GITHUB_REPOSITORY.
+  --pr=number        A number of the pull request. Default to the one detected
+                     from GITHUB_REF or GITHUB_EVENT_PATH.
+  --api-url=url      A URL of GitHub API. Default to GITHUB_API_URL or
+                     https://api.github.com.
+  --id=name          An identifier to distinguish multiple comments on the same
+                     pull request, such as a name of the directory.
+  --dry-run          Print a comment without posting it.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *CommentCommand) Synopsis() string {
+	return "Post results of plan to a pull request"
+}
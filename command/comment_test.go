@@ -0,0 +1,156 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormatPlanComment(t *testing.T) {
+	manyAddresses := []string{}
+	for i := 0; i < commentCollapseLines+1; i++ {
+		manyAddresses = append(manyAddresses, fmt.Sprintf("null_resource.foo%d", i))
+	}
+
+	cases := []struct {
+		desc   string
+		report *PlanReport
+		want   string
+	}{
+		{
+			desc: "succeeded",
+			report: &PlanReport{
+				FormatVersion: planReportFormatVersion,
+				Migrations: []*MigrationReport{
+					{
+						File: "tfmigrate/mv.hcl",
+						Actions: []*ActionReport{
+							{
+								Action:   "xmv null_resource.* null_resource.$1_2",
+								Status:   "succeeded",
+								Resolved: []string{"mv null_resource.foo null_resource.foo_2"},
+							},
+						},
+						StateDiffs: []*StateDiffReport{
+							{
+								Dir:       "dir1",
+								Workspace: "default",
+								Added:     []string{"null_resource.foo_2"},
+								Removed:   []string{"null_resource.foo"},
+							},
+						},
+						PlanSummaries: []*PlanSummaryReport{
+							{
+								Dir:       "dir1",
+								Workspace: "default",
+								Changes:   []string{},
+							},
+						},
+					},
+					{
+						File:    "tfmigrate/skip.hcl",
+						Skipped: true,
+					},
+				},
+			},
+			want: "<!-- tfmigrate plan -->\n" +
+				"## tfmigrate plan: succeeded\n" +
+				"\n### `tfmigrate/mv.hcl`\n\n" +
+				"- `xmv null_resource.* null_resource.$1_2` (succeeded)\n" +
+				"\n" +
+				"`xmv null_resource.* null_resource.$1_2` resolved to 1 actions\n\n" +
+				"```\nmv null_resource.foo null_resource.foo_2\n```\n" +
+				"\n" +
+				"State changes in `dir1@default`: 1 added, 1 removed\n\n" +
+				"```diff\n- null_resource.foo\n+ null_resource.foo_2\n```\n" +
+				"\n" +
+				"Plan in `dir1@default`: 0 to add, 0 to change, 0 to destroy\n" +
+				"\n### `tfmigrate/skip.hcl`\n\n" +
+				"Skipped by skip_if.\n",
+		},
+		{
+			desc: "failed with a long list",
+			report: &PlanReport{
+				FormatVersion: planReportFormatVersion,
+				Migrations: []*MigrationReport{
+					{
+						File: "tfmigrate/rm.hcl",
+						Actions: []*ActionReport{
+							{
+								Action: "rm null_resource.foo*",
+								Status: "succeeded",
+							},
+						},
+						StateDiffs: []*StateDiffReport{
+							{
+								Dir:       "dir1",
+								Workspace: "default",
+								Added:     []string{},
+								Removed:   manyAddresses,
+							},
+						},
+					},
+				},
+				Error: "terraform plan command returns unexpected diffs",
+			},
+			want: "<!-- tfmigrate plan -->\n" +
+				"## tfmigrate plan: failed\n\n" +
+				"Error\n\n" +
+				"```\nterraform plan command returns unexpected diffs\n```\n" +
+				"\n### `tfmigrate/rm.hcl`\n\n" +
+				"- `rm null_resource.foo*` (succeeded)\n" +
+				"\n" +
+				"<details><summary>State changes in `dir1@default`: 0 added, 11 removed</summary>\n\n" +
+				"```diff\n- " + strings.Join(manyAddresses, "\n- ") + "\n```\n" +
+				"\n</details>\n",
+		},
+		{
+			desc: "no migrations",
+			report: &PlanReport{
+				FormatVersion: planReportFormatVersion,
+				Migrations:    []*MigrationReport{},
+			},
+			want: "<!-- tfmigrate plan -->\n" +
+				"## tfmigrate plan: succeeded\n" +
+				"\nNo migrations to plan.\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := formatPlanComment(tc.report, commentMarker(""))
+			if got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatPlanCommentTruncated(t *testing.T) {
+	added := []string{}
+	for i := 0; i < commentMaxLength/10; i++ {
+		added = append(added, fmt.Sprintf("null_resource.foo%d", i))
+	}
+	report := &PlanReport{
+		FormatVersion: planReportFormatVersion,
+		Migrations: []*MigrationReport{
+			{
+				File: "tfmigrate/import.hcl",
+				StateDiffs: []*StateDiffReport{
+					{Dir: "dir1", Workspace: "default", Added: added},
+				},
+			},
+		},
+	}
+
+	got := formatPlanComment(report, commentMarker("dir1"))
+	if len(got) > commentMaxLength {
+		t.Errorf("got a comment of %d bytes, want <= %d", len(got), commentMaxLength)
+	}
+	if !strings.HasPrefix(got, "<!-- tfmigrate plan: dir1 -->\n") {
+		t.Errorf("expected to start with a marker, got: %s", got[:50])
+	}
+	if !strings.HasSuffix(got, "(truncated)\n") {
+		t.Errorf("expected to be truncated, got: %s", got[len(got)-50:])
+	}
+}
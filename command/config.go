@@ -0,0 +1,203 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	"github.com/mitchellh/cli"
+	flag "github.com/spf13/pflag"
+)
+
+// ConfigCommand is a parent command for inspecting settings.
+// It does nothing and just shows its subcommands.
+type ConfigCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *ConfigCommand) Run(_ []string) int {
+	return cli.RunResultHelp
+}
+
+// Help returns long-form help text.
+func (c *ConfigCommand) Help() string {
+	helpText := `
+Usage: tfmigrate config <subcommand>
+
+Inspect settings.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *ConfigCommand) Synopsis() string {
+	return "Inspect settings"
+}
+
+// ConfigDumpCommand is a command which prints the effective configuration.
+type ConfigDumpCommand struct {
+	Meta
+	format string
+}
+
+// Run runs the procedure of this command.
+func (c *ConfigDumpCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("config dump", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	cmdFlags.StringVar(&c.format, "format", "json", "An output format")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if c.format != "json" && c.format != "text" {
+		c.UI.Error(fmt.Sprintf("unknown format: %s", c.format))
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+
+	out, err := dumpConfig(configFilePath(c.configFile), c.config, c.Option, c.format, os.Getenv)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	c.UI.Output(out)
+	return 0
+}
+
+// configDump is an effective configuration printed by the config dump command.
+type configDump struct {
+	ConfigFile string       `json:"config_file"`
+	Config     *config.Dump `json:"config"`
+	Option     *optionDump  `json:"option"`
+}
+
+// optionDump is a dump of options set by environment variables.
+type optionDump struct {
+	ExecPath               string   `json:"exec_path"`
+	TempDir                string   `json:"temp_dir"`
+	ProvidersMirrorDir     string   `json:"providers_mirror_dir"`
+	ProvidersLockPlatforms []string `json:"providers_lock_platforms,omitempty"`
+}
+
+// dumpConfig returns the effective configuration in a given format.
+// Valid formats are json and text.
+// The filename is a path of the loaded config file, which is empty if no
+// config file is loaded.
+func dumpConfig(filename string, c *config.TfmigrateConfig, option *tfmigrate.MigratorOption, format string, getenv config.Getenv) (string, error) {
+	d := &configDump{
+		ConfigFile: filename,
+		Config:     c.Dump(getenv),
+		Option: &optionDump{
+			ExecPath:               option.ExecPath,
+			TempDir:                option.TempDir,
+			ProvidersMirrorDir:     option.ProvidersMirrorDir,
+			ProvidersLockPlatforms: option.ProvidersLockPlatforms,
+		},
+	}
+	// Show default values which are resolved on running terraform.
+	if len(d.Option.ExecPath) == 0 {
+		d.Option.ExecPath = c.ExecPath
+	}
+	if len(d.Option.ExecPath) == 0 {
+		d.Option.ExecPath = "terraform"
+		if c.ExecMode == tfexec.ExecModeTerragrunt {
+			d.Option.ExecPath = "terragrunt"
+		}
+	}
+	if len(d.Option.TempDir) == 0 {
+		d.Option.TempDir = os.TempDir()
+	}
+
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode config: %s", err)
+	}
+
+	switch format {
+	case "json":
+		return string(b), nil
+
+	case "text":
+		// Flatten the JSON so that it's easy to grep and diff.
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return "", fmt.Errorf("failed to decode config: %s", err)
+		}
+		lines := []string{}
+		flattenConfigDump("", v, &lines)
+		sort.Strings(lines)
+		return strings.Join(lines, "\n"), nil
+
+	default:
+		return "", fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// flattenConfigDump appends lines of `key = value` for leaves of a given
+// value decoded from JSON. Keys of nested values are joined with dots.
+func flattenConfigDump(prefix string, v interface{}, lines *[]string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			flattenConfigDump(joinConfigDumpKey(prefix, k), e, lines)
+		}
+	case []interface{}:
+		for i, e := range t {
+			flattenConfigDump(joinConfigDumpKey(prefix, strconv.Itoa(i)), e, lines)
+		}
+	default:
+		b, _ := json.Marshal(t)
+		*lines = append(*lines, fmt.Sprintf("%s = %s", prefix, string(b)))
+	}
+}
+
+// joinConfigDumpKey joins a given prefix and key with a dot.
+func joinConfigDumpKey(prefix string, key string) string {
+	if len(prefix) == 0 {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// Help returns long-form help text.
+func (c *ConfigDumpCommand) Help() string {
+	helpText := `
+Usage: tfmigrate config dump [options]
+
+Print the effective configuration.
+Settings are merged with the following precedence:
+command line flags > environment variables > config file.
+Default values are resolved, and sensitive values such as credentials are
+masked, so that it's safe to share the output for debugging.
+
+Options:
+  --config           A path to tfmigrate config file
+  --format           An output format. Valid values are as follows:
+                       - json (default)
+                       - text: flattened key = value lines
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *ConfigDumpCommand) Synopsis() string {
+	return "Print the effective configuration"
+}
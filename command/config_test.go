@@ -0,0 +1,145 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	"github.com/minamijoyo/tfmigrate/window"
+)
+
+func TestDumpConfig(t *testing.T) {
+	cases := []struct {
+		desc     string
+		filename string
+		config   *config.TfmigrateConfig
+		option   *tfmigrate.MigratorOption
+		format   string
+		want     string
+	}{
+		{
+			desc:     "default",
+			filename: "",
+			config:   config.NewDefaultConfig(),
+			option: &tfmigrate.MigratorOption{
+				ExecPath: "tofu",
+				TempDir:  "/tmp",
+			},
+			format: "json",
+			want: `{
+  "config_file": "",
+  "config": {
+    "migration_dir": ".",
+    "is_backend_terraform_cloud": false
+  },
+  "option": {
+    "exec_path": "tofu",
+    "temp_dir": "/tmp",
+    "providers_mirror_dir": ""
+  }
+}`,
+		},
+		{
+			desc:     "with config file",
+			filename: "tfmigrate.hcl",
+			config: &config.TfmigrateConfig{
+				MigrationDir: "tfmigrate",
+				Project:      "foo",
+				Dirs: map[string]string{
+					"network": "dir1",
+				},
+			},
+			option: &tfmigrate.MigratorOption{
+				TempDir: "/tmp",
+			},
+			format: "json",
+			want: `{
+  "config_file": "tfmigrate.hcl",
+  "config": {
+    "migration_dir": "tfmigrate",
+    "is_backend_terraform_cloud": false,
+    "project": "foo",
+    "dirs": {
+      "network": "dir1"
+    }
+  },
+  "option": {
+    "exec_path": "terraform",
+    "temp_dir": "/tmp",
+    "providers_mirror_dir": ""
+  }
+}`,
+		},
+		{
+			desc:     "text",
+			filename: "tfmigrate.hcl",
+			config: &config.TfmigrateConfig{
+				MigrationDir: "tfmigrate",
+				ApplyWindows: window.Windows{
+					&window.Window{Schedule: "* 9-17 * * 1-5"},
+				},
+			},
+			option: &tfmigrate.MigratorOption{
+				TempDir: "/tmp",
+			},
+			format: "text",
+			want: `config.apply_windows.0.schedule = "* 9-17 * * 1-5"
+config.is_backend_terraform_cloud = false
+config.migration_dir = "tfmigrate"
+config_file = "tfmigrate.hcl"
+option.exec_path = "terraform"
+option.providers_mirror_dir = ""
+option.temp_dir = "/tmp"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := dumpConfig(tc.filename, tc.config, tc.option, tc.format, func(string) string { return "" })
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfigFilePath(t *testing.T) {
+	cases := []struct {
+		desc     string
+		filename string
+		env      string
+		want     string
+	}{
+		{
+			desc:     "flag",
+			filename: "foo.hcl",
+			env:      "bar.hcl",
+			want:     "foo.hcl",
+		},
+		{
+			desc:     "env",
+			filename: "",
+			env:      "bar.hcl",
+			want:     "bar.hcl",
+		},
+		{
+			desc:     "default not found",
+			filename: "",
+			env:      "",
+			want:     "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("TFMIGRATE_CONFIG", tc.env)
+			got := configFilePath(tc.filename)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+// isInteractive returns true if both stdin and stdout are terminals, so that
+// a user can answer a prompt. It's a variable so that tests can override it.
+var isInteractive = func() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// isTerminal returns true if a given file is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmApply asks a user whether to apply, and returns true only if the
+// answer is exactly yes, in the same way as terraform apply.
+func confirmApply(ui cli.Ui) (bool, error) {
+	answer, err := ui.Ask("Do you want to apply the migrations?\n  Only 'yes' will be accepted to approve.\n\n  Enter a value:")
+	if err != nil {
+		return false, fmt.Errorf("failed to read an answer: %s", err)
+	}
+	return strings.TrimSpace(answer) == "yes", nil
+}
+
+// formatPlanSummary returns a summary of given planned runners, which lists
+// actions and diffs of addresses in states for each migration file.
+func formatPlanSummary(runners []*FileRunner) string {
+	var b strings.Builder
+	for _, fr := range runners {
+		if fr.Skipped() {
+			fmt.Fprintf(&b, "%s: skipped by skip_if\n\n", fr.Filename())
+			continue
+		}
+
+		results := fr.ActionResults()
+		fmt.Fprintf(&b, "%s: %d actions\n", fr.Filename(), len(results))
+		for _, r := range results {
+			fmt.Fprintf(&b, "  %s\n", r.Action)
+		}
+		b.WriteString("\n")
+		for _, d := range fr.StateDiffs() {
+			b.WriteString(formatStateDiff(fr.Filename(), d))
+			b.WriteString("\n\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,144 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/mitchellh/cli"
+)
+
+func TestConfirmApply(t *testing.T) {
+	cases := []struct {
+		desc   string
+		answer string
+		want   bool
+	}{
+		{
+			desc:   "yes",
+			answer: "yes\n",
+			want:   true,
+		},
+		{
+			desc:   "no",
+			answer: "no\n",
+			want:   false,
+		},
+		{
+			desc:   "y",
+			answer: "y\n",
+			want:   false,
+		},
+		{
+			desc:   "empty",
+			answer: "\n",
+			want:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			ui.InputReader = strings.NewReader(tc.answer)
+			got, err := confirmApply(ui)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyCommandConfirm(t *testing.T) {
+	cases := []struct {
+		desc        string
+		autoApprove bool
+		interactive bool
+		answer      string
+		want        bool
+		asked       bool
+	}{
+		{
+			desc:        "approved",
+			autoApprove: false,
+			interactive: true,
+			answer:      "yes\n",
+			want:        true,
+			asked:       true,
+		},
+		{
+			desc:        "cancelled",
+			autoApprove: false,
+			interactive: true,
+			answer:      "no\n",
+			want:        false,
+			asked:       true,
+		},
+		{
+			desc:        "auto approve",
+			autoApprove: true,
+			interactive: true,
+			answer:      "",
+			want:        true,
+			asked:       false,
+		},
+		{
+			desc:        "non-interactive",
+			autoApprove: false,
+			interactive: false,
+			answer:      "",
+			want:        true,
+			asked:       false,
+		},
+	}
+
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	migrationDir := setupMigrationDir(t, migrations)
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			orig := isInteractive
+			isInteractive = func() bool { return tc.interactive }
+			t.Cleanup(func() { isInteractive = orig })
+
+			ui := cli.NewMockUi()
+			ui.InputReader = strings.NewReader(tc.answer)
+			c := &ApplyCommand{
+				Meta: Meta{
+					UI:     ui,
+					config: &config.TfmigrateConfig{MigrationDir: migrationDir},
+					Option: newOption(),
+				},
+				autoApprove: tc.autoApprove,
+			}
+
+			got, err := c.confirm("20201109000001_test1.hcl")
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+
+			output := ui.OutputWriter.String()
+			if asked := strings.Contains(output, "Enter a value:"); asked != tc.asked {
+				t.Errorf("asked: %t, want: %t, output: %s", asked, tc.asked, output)
+			}
+			if tc.asked && !strings.Contains(output, "20201109000001_test1.hcl") {
+				t.Errorf("expected a plan summary, but got: %s", output)
+			}
+			if c.Option.ShowDiff {
+				t.Errorf("expected ShowDiff to be restored")
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// applyEnvPrefix is a prefix of environment variables for elevated
+// credentials used only by apply, such as TFMIGRATE_APPLY_ENV_AWS_PROFILE.
+const applyEnvPrefix = "TFMIGRATE_APPLY_ENV_"
+
+// applyCredentials returns a map of environment variables for elevated
+// credentials found in a given list of environment variables in the form of
+// key=value. The prefix is trimmed from keys.
+func applyCredentials(environ []string) map[string]string {
+	creds := make(map[string]string)
+	for _, kv := range environ {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(k, applyEnvPrefix) || len(k) == len(applyEnvPrefix) {
+			continue
+		}
+		creds[strings.TrimPrefix(k, applyEnvPrefix)] = v
+	}
+	return creds
+}
+
+// setApplyCredentials sets elevated credentials for apply to the environment
+// of the current process, so that both terraform commands and the history
+// storage use them. If required is true, it returns an error when no
+// credentials are supplied.
+func setApplyCredentials(creds map[string]string, required bool) error {
+	if len(creds) == 0 {
+		if required {
+			return fmt.Errorf("apply requires elevated credentials because read_only_plan is set, supply them as environment variables prefixed with %s, such as %sAWS_PROFILE", applyEnvPrefix, applyEnvPrefix)
+		}
+		return nil
+	}
+
+	keys := make([]string, 0, len(creds))
+	for k := range creds {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := os.Setenv(k, creds[k]); err != nil {
+			return fmt.Errorf("failed to set elevated credentials: %s", err)
+		}
+	}
+	// Never log values, which are secrets.
+	log.Printf("[INFO] [command] use elevated credentials for apply: %s\n", strings.Join(keys, ", "))
+	return nil
+}
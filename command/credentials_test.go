@@ -0,0 +1,73 @@
+package command
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestApplyCredentials(t *testing.T) {
+	environ := []string{
+		"AWS_PROFILE=readonly",
+		"TFMIGRATE_APPLY_ENV_AWS_PROFILE=admin",
+		"TFMIGRATE_APPLY_ENV_TOKEN=a=b",
+		"TFMIGRATE_APPLY_ENV_=invalid",
+		"TFMIGRATE_LOG=DEBUG",
+	}
+	got := applyCredentials(environ)
+	want := map[string]string{
+		"AWS_PROFILE": "admin",
+		"TOKEN":       "a=b",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+}
+
+func TestSetApplyCredentials(t *testing.T) {
+	cases := []struct {
+		desc     string
+		creds    map[string]string
+		required bool
+		ok       bool
+	}{
+		{
+			desc:     "not required and not supplied",
+			creds:    map[string]string{},
+			required: false,
+			ok:       true,
+		},
+		{
+			desc:     "required but not supplied",
+			creds:    map[string]string{},
+			required: true,
+			ok:       false,
+		},
+		{
+			desc:     "required and supplied",
+			creds:    map[string]string{"TFMIGRATE_TEST_APPLY_CREDENTIAL": "admin"},
+			required: true,
+			ok:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			for k := range tc.creds {
+				t.Setenv(k, "")
+			}
+			err := setApplyCredentials(tc.creds, tc.required)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			for k, v := range tc.creds {
+				if got := os.Getenv(k); got != v {
+					t.Errorf("got: %s = %s, want: %s", k, got, v)
+				}
+			}
+		})
+	}
+}
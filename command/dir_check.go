@@ -0,0 +1,97 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// terraformConfigExts is a list of file extensions of terraform configuration.
+var terraformConfigExts = []string{".tf", ".tf.json", ".tofu", ".tofu.json"}
+
+// checkMigrationDirs checks working directories referenced by a given
+// migration at load time instead of failing deep inside plan or apply.
+// A missing directory is always an error. A directory which contains no
+// terraform configuration or is not initialized is reported as a warning,
+// or an error in strict mode.
+func checkMigrationDirs(mc *tfmigrate.MigrationConfig, strict bool) ([]string, error) {
+	dirs, _ := migrationDirsAndActions(mc)
+
+	warnings := []string{}
+	for _, dir := range dirs {
+		ws, err := checkWorkDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dir in migration %s: %s", mc.Name, err)
+		}
+		warnings = append(warnings, ws...)
+	}
+
+	if strict && len(warnings) > 0 {
+		return nil, fmt.Errorf("invalid dir in migration %s (strict_dirs): %s", mc.Name, strings.Join(warnings, "; "))
+	}
+
+	return warnings, nil
+}
+
+// checkWorkDir checks if a given directory exists, contains terraform
+// configuration and has been initialized.
+func checkWorkDir(dir string) ([]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("directory %s does not exist", dir)
+		}
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	warnings := []string{}
+
+	hasConfig, err := containsTerraformConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !hasConfig {
+		warnings = append(warnings, fmt.Sprintf("directory %s contains no terraform configuration", dir))
+	}
+
+	// Respect TF_DATA_DIR, which is relative to the working directory.
+	dataDir := os.Getenv("TF_DATA_DIR")
+	if len(dataDir) == 0 {
+		dataDir = ".terraform"
+	}
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(dir, dataDir)
+	}
+	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+		warnings = append(warnings, fmt.Sprintf("directory %s is not initialized. It will be initialized with terraform init, which requires access to the backend and provider registry", dir))
+	}
+
+	return warnings, nil
+}
+
+// containsTerraformConfig returns true if a given directory contains at
+// least one terraform configuration file.
+func containsTerraformConfig(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		for _, ext := range terraformConfigExts {
+			if strings.HasSuffix(e.Name(), ext) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
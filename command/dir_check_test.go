@@ -0,0 +1,140 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestCheckMigrationDirs(t *testing.T) {
+	cases := []struct {
+		desc     string
+		files    []string
+		dirs     []string
+		mc       func(root string) *tfmigrate.MigrationConfig
+		strict   bool
+		warnings int
+		ok       bool
+	}{
+		{
+			desc:  "initialized",
+			files: []string{"dir1/main.tf"},
+			dirs:  []string{"dir1/.terraform"},
+			mc: func(root string) *tfmigrate.MigrationConfig {
+				return &tfmigrate.MigrationConfig{
+					Name:     "test",
+					Migrator: &tfmigrate.StateMigratorConfig{Dir: filepath.Join(root, "dir1")},
+				}
+			},
+			warnings: 0,
+			ok:       true,
+		},
+		{
+			desc:  "not initialized",
+			files: []string{"dir1/main.tf.json"},
+			mc: func(root string) *tfmigrate.MigrationConfig {
+				return &tfmigrate.MigrationConfig{
+					Name:     "test",
+					Migrator: &tfmigrate.StateMigratorConfig{Dir: filepath.Join(root, "dir1")},
+				}
+			},
+			warnings: 1,
+			ok:       true,
+		},
+		{
+			desc:  "no terraform configuration",
+			files: []string{"dir1/README.md", "dir2/main.tofu"},
+			dirs:  []string{"dir1/.terraform", "dir2/.terraform"},
+			mc: func(root string) *tfmigrate.MigrationConfig {
+				return &tfmigrate.MigrationConfig{
+					Name: "test",
+					Migrator: &tfmigrate.MultiStateMigratorConfig{
+						FromDir: filepath.Join(root, "dir1"),
+						ToDir:   filepath.Join(root, "dir2"),
+					},
+				}
+			},
+			warnings: 1,
+			ok:       true,
+		},
+		{
+			desc:  "strict",
+			files: []string{"dir1/main.tf"},
+			mc: func(root string) *tfmigrate.MigrationConfig {
+				return &tfmigrate.MigrationConfig{
+					Name:     "test",
+					Migrator: &tfmigrate.StateMigratorConfig{Dir: filepath.Join(root, "dir1")},
+				}
+			},
+			strict: true,
+			ok:     false,
+		},
+		{
+			desc: "does not exist",
+			mc: func(root string) *tfmigrate.MigrationConfig {
+				return &tfmigrate.MigrationConfig{
+					Name:     "test",
+					Migrator: &tfmigrate.StateMigratorConfig{Dir: filepath.Join(root, "not_exist")},
+				}
+			},
+			ok: false,
+		},
+		{
+			desc:  "not a directory",
+			files: []string{"main.tf"},
+			mc: func(root string) *tfmigrate.MigrationConfig {
+				return &tfmigrate.MigrationConfig{
+					Name:     "test",
+					Migrator: &tfmigrate.StateMigratorConfig{Dir: filepath.Join(root, "main.tf")},
+				}
+			},
+			ok: false,
+		},
+		{
+			desc: "mock",
+			mc: func(_ string) *tfmigrate.MigrationConfig {
+				return &tfmigrate.MigrationConfig{
+					Name:     "test",
+					Migrator: &tfmigrate.MockMigratorConfig{},
+				}
+			},
+			strict:   true,
+			warnings: 0,
+			ok:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("TF_DATA_DIR", "")
+			root := t.TempDir()
+			for _, d := range tc.dirs {
+				if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+			}
+			for _, f := range tc.files {
+				path := filepath.Join(root, f)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := os.WriteFile(path, []byte{}, 0600); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			got, err := checkMigrationDirs(tc.mc(root), tc.strict)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && len(got) != tc.warnings {
+				t.Errorf("got %d warnings: %#v, want: %d", len(got), got, tc.warnings)
+			}
+		})
+	}
+}
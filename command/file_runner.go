@@ -2,11 +2,14 @@ package command
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/event"
+	"github.com/minamijoyo/tfmigrate/logging"
 	"github.com/minamijoyo/tfmigrate/tfmigrate"
 )
 
@@ -20,22 +23,99 @@ type FileRunner struct {
 	mc *tfmigrate.MigrationConfig
 	// A migrator instance to be run.
 	m tfmigrate.Migrator
+	// An emitter for migration lifecycle events.
+	// It is nil if no event sink is configured.
+	emitter *event.Emitter
+	// A post-apply step which stamps resources moved or imported.
+	// It is nil if not configured.
+	stamp *tfmigrate.StampConfig
+	// Commands which run around applying the migration.
+	// It is nil if not configured.
+	hooks *tfmigrate.HooksConfig
+	// offline is true if the migration is run without access to real states.
+	offline bool
+	// rollback is true if the migration is an inverse migration to roll back
+	// the migration file.
+	rollback bool
 }
 
 // NewFileRunner returns a new FileRunner instance.
 func NewFileRunner(filename string, config *config.TfmigrateConfig, option *tfmigrate.MigratorOption) (*FileRunner, error) {
 	path := resolveMigrationFile(config.MigrationDir, filename)
 	log.Printf("[INFO] [runner] load migration file: %s\n", path)
-	mc, err := loadMigrationFile(path)
+	mc, err := loadMigrationFile(path, config.MigrationFileOption())
 	if err != nil {
 		return nil, err
 	}
 
+	return newFileRunner(filename, config, mc, option)
+}
+
+// newFileRunner returns a new FileRunner instance for a given migration
+// config loaded from a given file, which allows us to run a migration derived
+// from the file such as a rollback.
+func newFileRunner(filename string, config *config.TfmigrateConfig, mc *tfmigrate.MigrationConfig, option *tfmigrate.MigratorOption) (*FileRunner, error) {
+	if mc.Skip {
+		// A skipped migration is never run, so we don't need to validate it
+		// nor build a migrator. Its working directories may not even exist in
+		// the current environment.
+		r := &FileRunner{
+			filename: filename,
+			config:   config,
+			mc:       mc,
+		}
+		return r, nil
+	}
+
+	if config.Policy != nil {
+		planOptions, err := tfmigrate.PlanOptionsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		if err := config.Policy.Validate(mc, planOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Ownership != nil {
+		if err := config.Ownership.Validate(mc); err != nil {
+			return nil, err
+		}
+	}
+
+	strictDirs := config.Policy != nil && config.Policy.StrictDirs
+	warnings, err := checkMigrationDirs(mc, strictDirs)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		log.Printf("[WARN] [runner] %s\n", w)
+	}
+
 	if option != nil {
 		option.IsBackendTerraformCloud = config.IsBackendTerraformCloud
+		option.StatePushMethod = config.StatePushMethod
+		option.ActionPlugins = config.ActionPlugins
+		option.Retry = config.Retry
+		option.LockOptions = config.LockOptions
+		option.VarFiles = config.VarFiles
+		option.Vars = config.Vars
+		option.ExecMode = config.ExecMode
+		// The environment variable takes precedence over the config file.
+		if len(option.ExecPath) == 0 {
+			option.ExecPath = config.ExecPath
+		}
 	} else {
 		option = &tfmigrate.MigratorOption{
+			ExecPath:                config.ExecPath,
+			ExecMode:                config.ExecMode,
 			IsBackendTerraformCloud: false,
+			StatePushMethod:         config.StatePushMethod,
+			ActionPlugins:           config.ActionPlugins,
+			Retry:                   config.Retry,
+			LockOptions:             config.LockOptions,
+			VarFiles:                config.VarFiles,
+			Vars:                    config.Vars,
 		}
 	}
 
@@ -45,24 +125,47 @@ func NewFileRunner(filename string, config *config.TfmigrateConfig, option *tfmi
 		return nil, err
 	}
 
+	var emitter *event.Emitter
+	// In sandbox mode, migrations are never applied to the real states,
+	// so we don't emit any events not to mislead subscribers.
+	if len(config.EventSinks) > 0 && len(option.SandboxDir) == 0 {
+		emitter, err = event.NewEmitter(config.EventSinks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var stamp *tfmigrate.StampConfig
+	var hooks *tfmigrate.HooksConfig
+	// Similarly, we don't stamp any resources nor run hooks in sandbox mode.
+	if len(option.SandboxDir) == 0 {
+		stamp = config.Stamp
+		hooks = mc.Hooks
+	}
+
 	r := &FileRunner{
 		filename: filename,
 		config:   config,
 		mc:       mc,
 		m:        m,
+		emitter:  emitter,
+		stamp:    stamp,
+		hooks:    hooks,
+		offline:  option.Offline,
 	}
 
 	return r, nil
 }
 
 // loadMigrationFile is a helper function which reads and parses a migration file.
-func loadMigrationFile(filename string) (*tfmigrate.MigrationConfig, error) {
+// The option is derived from the config file. It may be nil.
+func loadMigrationFile(filename string, o *config.MigrationFileOption) (*tfmigrate.MigrationConfig, error) {
 	source, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	config, err := config.ParseMigrationFile(filename, source)
+	config, err := config.ParseMigrationFileWithOption(filename, source, o)
 	if err != nil {
 		return nil, err
 	}
@@ -72,12 +175,149 @@ func loadMigrationFile(filename string) (*tfmigrate.MigrationConfig, error) {
 
 // Plan plans a single migration.
 func (r *FileRunner) Plan(ctx context.Context) error {
-	return r.m.Plan(ctx)
+	if r.Skipped() {
+		logging.FromContext(ctx).Printf("[INFO] [runner] skip migration by skip_if: %s\n", r.filename)
+		return nil
+	}
+
+	err := r.m.Plan(ctx)
+	r.emit(ctx, "plan", event.TypeMigrationPlanned, err)
+	return err
 }
 
 // Apply applies a single migration.
 func (r *FileRunner) Apply(ctx context.Context) error {
-	return r.m.Apply(ctx)
+	if r.Skipped() {
+		logging.FromContext(ctx).Printf("[INFO] [runner] skip migration by skip_if: %s\n", r.filename)
+		return nil
+	}
+
+	// We cannot know what hooks do, so we refuse them rather than silently
+	// skipping commands which may be required for the migration. Plan never
+	// runs hooks, so it's checked only here.
+	if r.offline && r.hooks != nil {
+		return fmt.Errorf("hooks are not allowed in offline mode: %s", r.filename)
+	}
+
+	// The migration is not applied if the pre_apply hook fails, so it's
+	// reported as a failure of the hook, not the migration.
+	if err := r.runHook(ctx, tfmigrate.HookPreApply, nil); err != nil {
+		r.emit(ctx, tfmigrate.HookPreApply, event.TypeHookFailed, err)
+		if hookErr := r.runHook(ctx, tfmigrate.HookOnFailure, err); hookErr != nil {
+			logging.FromContext(ctx).Printf("[WARN] [runner] %s: %s\n", hookErr, r.filename)
+		}
+		return err
+	}
+
+	err := r.m.Apply(ctx)
+	r.emit(ctx, "apply", event.TypeMigrationApplied, err)
+	if err != nil {
+		// The on_failure hook is just a notification, so a failure of it
+		// doesn't hide the original error.
+		if hookErr := r.runHook(ctx, tfmigrate.HookOnFailure, err); hookErr != nil {
+			logging.FromContext(ctx).Printf("[WARN] [runner] %s: %s\n", hookErr, r.filename)
+		}
+		return err
+	}
+
+	// The migration has already been applied, so a failure of the post_apply
+	// hook doesn't fail it.
+	if err := r.runHook(ctx, tfmigrate.HookPostApply, nil); err != nil {
+		logging.FromContext(ctx).Printf("[WARN] [runner] %s: %s\n", err, r.filename)
+	}
+	if r.stamp != nil {
+		// The migration has already been applied, so a failure of stamping
+		// doesn't fail it.
+		if err := r.stamp.Stamp(ctx, r.mc); err != nil {
+			logging.FromContext(ctx).Printf("[WARN] [runner] %s: %s\n", err, r.filename)
+		}
+	}
+	return nil
+}
+
+// runHook runs commands of a given hook with information about the migration.
+// The cause is an error of the migration passed to the on_failure hook.
+func (r *FileRunner) runHook(ctx context.Context, hook string, cause error) error {
+	if r.hooks == nil {
+		return nil
+	}
+
+	env := []string{
+		"TFMIGRATE_MIGRATION_FILE=" + r.filename,
+		"TFMIGRATE_MIGRATION_TYPE=" + r.mc.Type,
+		"TFMIGRATE_MIGRATION_NAME=" + r.mc.Name,
+	}
+	if cause != nil {
+		env = append(env, "TFMIGRATE_ERROR="+cause.Error())
+	}
+	return r.hooks.Run(ctx, hook, env)
+}
+
+// emit emits a migration lifecycle event for a given operation result.
+// If the operation failed, it emits a failed event instead of a given type,
+// unless the given type is already a failed one.
+func (r *FileRunner) emit(ctx context.Context, operation string, eventType string, err error) {
+	if r.emitter == nil {
+		return
+	}
+
+	data := &event.MigrationData{
+		Filename:      r.filename,
+		MigrationType: r.mc.Type,
+		MigrationName: r.mc.Name,
+		Operation:     operation,
+	}
+	if r.rollback {
+		data.Operation = "rollback-" + operation
+	}
+	if err != nil {
+		if eventType != event.TypeHookFailed {
+			eventType = event.TypeMigrationFailed
+		}
+		data.Error = err.Error()
+	}
+
+	r.emitter.Emit(ctx, event.NewEvent(eventType, data))
+}
+
+// Skipped returns true if the migration is skipped by its condition.
+func (r *FileRunner) Skipped() bool {
+	return r.mc.Skip
+}
+
+// ActionResults returns a list of results of actions executed in the last
+// Plan or Apply. It returns nil if the migrator doesn't report them.
+func (r *FileRunner) ActionResults() []tfmigrate.ActionResult {
+	reporter, ok := r.m.(tfmigrate.ActionResultReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.ActionResults()
+}
+
+// StateDiffs returns a list of state diffs computed in the last Plan or
+// Apply. It returns nil if the migrator doesn't report them.
+func (r *FileRunner) StateDiffs() []tfmigrate.StateDiff {
+	reporter, ok := r.m.(tfmigrate.StateDiffReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.StateDiffs()
+}
+
+// PlanSummaries returns a list of plan summaries computed in the last Plan or
+// Apply. It returns nil if the migrator doesn't report them.
+func (r *FileRunner) PlanSummaries() []tfmigrate.PlanSummary {
+	reporter, ok := r.m.(tfmigrate.PlanSummaryReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.PlanSummaries()
+}
+
+// Filename returns a path to the migration file.
+func (r *FileRunner) Filename() string {
+	return r.filename
 }
 
 // MigrationConfig returns an instance of migration.
@@ -2,10 +2,15 @@ package command
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/event"
+	"github.com/minamijoyo/tfmigrate/event/mock"
 	"github.com/minamijoyo/tfmigrate/tfmigrate"
 )
 
@@ -40,7 +45,7 @@ migration "mock" "test" {
 		t.Run(tc.desc, func(t *testing.T) {
 			path := setupMigrationFile(t, tc.source)
 
-			got, err := loadMigrationFile(path)
+			got, err := loadMigrationFile(path, nil)
 			if tc.ok && err != nil {
 				t.Fatalf("unexpected err: %s", err)
 			}
@@ -80,6 +85,28 @@ migration "mock" "test" {
 	plan_error  = true
 	apply_error = false
 }
+`,
+			ok: false,
+		},
+		{
+			desc: "skip_if",
+			source: `
+migration "mock" "test" {
+	skip_if     = try(env.TFMIGRATE_TEST_UNDEFINED_ENV, "") != "prod"
+	plan_error  = true
+	apply_error = false
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "skip_if not satisfied",
+			source: `
+migration "mock" "test" {
+	skip_if     = try(env.TFMIGRATE_TEST_UNDEFINED_ENV, "") == "prod"
+	plan_error  = true
+	apply_error = false
+}
 `,
 			ok: false,
 		},
@@ -165,3 +192,353 @@ migration "mock" "test" {
 		})
 	}
 }
+
+func TestFileRunnerApplyWithHooks(t *testing.T) {
+	// Each hook appends its name to a log file given by %[1]s.
+	logHook := `/bin/sh -c 'echo $TFMIGRATE_HOOK $TFMIGRATE_MIGRATION_NAME >> %[1]s'`
+	cases := []struct {
+		desc    string
+		source  string
+		sandbox bool
+		want    string
+		ok      bool
+	}{
+		{
+			desc: "applied",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+	hooks {
+		pre_apply  = ["` + logHook + `"]
+		post_apply = ["` + logHook + `"]
+		on_failure = ["` + logHook + `"]
+	}
+}
+`,
+			want: "pre_apply test\npost_apply test\n",
+			ok:   true,
+		},
+		{
+			desc: "apply error",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = true
+	hooks {
+		pre_apply  = ["` + logHook + `"]
+		post_apply = ["` + logHook + `"]
+		on_failure = ["` + logHook + `"]
+	}
+}
+`,
+			want: "pre_apply test\non_failure test\n",
+			ok:   false,
+		},
+		{
+			desc: "pre_apply error",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+	hooks {
+		pre_apply  = ["` + logHook + `", "false"]
+		post_apply = ["` + logHook + `"]
+		on_failure = ["` + logHook + `"]
+	}
+}
+`,
+			want: "pre_apply test\non_failure test\n",
+			ok:   false,
+		},
+		{
+			desc: "post_apply error",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+	hooks {
+		post_apply = ["false", "` + logHook + `"]
+		on_failure = ["` + logHook + `"]
+	}
+}
+`,
+			want: "",
+			ok:   true,
+		},
+		{
+			desc: "sandbox",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+	hooks {
+		pre_apply  = ["` + logHook + `"]
+		post_apply = ["` + logHook + `"]
+	}
+}
+`,
+			sandbox: true,
+			want:    "",
+			ok:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "hooks.log")
+			path := setupMigrationFile(t, fmt.Sprintf(tc.source, logFile))
+
+			config := config.NewDefaultConfig()
+			option := &tfmigrate.MigratorOption{}
+			if tc.sandbox {
+				option.SandboxDir = t.TempDir()
+			}
+			r, err := NewFileRunner(path, config, option)
+			if err != nil {
+				t.Fatalf("failed to new file runner: %s", err)
+			}
+
+			err = r.Apply(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			got, err := os.ReadFile(logFile)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatalf("failed to read log file: %s", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got: %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFileRunnerWithHooksOffline(t *testing.T) {
+	path := setupMigrationFile(t, `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+	hooks {
+		pre_apply = ["true"]
+	}
+}
+`)
+
+	config := config.NewDefaultConfig()
+	option := &tfmigrate.MigratorOption{Offline: true}
+	r, err := NewFileRunner(path, config, option)
+	if err != nil {
+		t.Fatalf("failed to new runner: %s", err)
+	}
+
+	// Plan never runs hooks, so it's allowed in offline mode.
+	if err := r.Plan(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if err := r.Apply(context.Background()); err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestFileRunnerEmitEvents(t *testing.T) {
+	cases := []struct {
+		desc      string
+		source    string
+		apply     bool
+		sendError bool
+		want      string
+		ok        bool
+	}{
+		{
+			desc: "planned",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			apply:     false,
+			sendError: false,
+			want:      event.TypeMigrationPlanned,
+			ok:        true,
+		},
+		{
+			desc: "applied",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			apply:     true,
+			sendError: false,
+			want:      event.TypeMigrationApplied,
+			ok:        true,
+		},
+		{
+			desc: "failed",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = true
+}
+`,
+			apply:     true,
+			sendError: false,
+			want:      event.TypeMigrationFailed,
+			ok:        false,
+		},
+		{
+			desc: "pre_apply hook failed",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+	hooks {
+		pre_apply = ["false"]
+	}
+}
+`,
+			apply:     true,
+			sendError: false,
+			want:      event.TypeHookFailed,
+			ok:        false,
+		},
+		{
+			desc: "failed to send an event",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			apply:     true,
+			sendError: true,
+			want:      "",
+			ok:        true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			path := setupMigrationFile(t, tc.source)
+
+			sinkConfig := &mock.Config{
+				SendError: tc.sendError,
+			}
+			config := config.NewDefaultConfig()
+			config.EventSinks = []event.Config{sinkConfig}
+			r, err := NewFileRunner(path, config, nil)
+			if err != nil {
+				t.Fatalf("failed to new file runner: %s", err)
+			}
+
+			if tc.apply {
+				err = r.Apply(context.Background())
+			} else {
+				err = r.Plan(context.Background())
+			}
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			events := sinkConfig.Sink().Events()
+			if len(tc.want) == 0 {
+				if len(events) != 0 {
+					t.Fatalf("expected no event, but got: %#v", events)
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("expected 1 event, but got: %#v", events)
+			}
+			got := events[0]
+			if got.Type != tc.want {
+				t.Errorf("got type = %s, want = %s", got.Type, tc.want)
+			}
+			if got.Data.MigrationName != "test" || got.Data.Filename != path {
+				t.Errorf("unexpected data: %#v", got.Data)
+			}
+			if !tc.ok && len(got.Data.Error) == 0 {
+				t.Errorf("expected to have an error message, but got: %#v", got.Data)
+			}
+		})
+	}
+}
+
+func TestNewFileRunnerWithPolicy(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		policy *tfmigrate.MigrationPolicy
+		env    string
+		ok     bool
+	}{
+		{
+			desc: "comply",
+			source: `
+migration "state" "test" {
+  actions = ["rm null_resource.foo"]
+}
+`,
+			policy: &tfmigrate.MigrationPolicy{
+				DenyForce:           true,
+				RequiredPlanOptions: []string{"-lock-timeout"},
+			},
+			env: "-lock-timeout=60s",
+			ok:  true,
+		},
+		{
+			desc: "force denied",
+			source: `
+migration "state" "test" {
+  actions = ["rm null_resource.foo"]
+  force   = true
+}
+`,
+			policy: &tfmigrate.MigrationPolicy{
+				DenyForce: true,
+			},
+			ok: false,
+		},
+		{
+			desc: "required option not set",
+			source: `
+migration "state" "test" {
+  actions = ["rm null_resource.foo"]
+}
+`,
+			policy: &tfmigrate.MigrationPolicy{
+				RequiredPlanOptions: []string{"-lock-timeout"},
+			},
+			env: "",
+			ok:  false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("TF_CLI_ARGS", "")
+			t.Setenv("TF_CLI_ARGS_plan", tc.env)
+			path := setupMigrationFile(t, tc.source)
+
+			config := config.NewDefaultConfig()
+			config.Policy = tc.policy
+			_, err := NewFileRunner(path, config, nil)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// GenerateMovedCommand is a command which converts a migration file into
+// Terraform moved blocks.
+type GenerateMovedCommand struct {
+	Meta
+	out string
+}
+
+// Run runs the procedure of this command.
+func (c *GenerateMovedCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("generate-moved", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
+	cmdFlags.StringVar(&c.out, "out", "", "Write the moved blocks to the given path")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	path := resolveMigrationFile(c.config.MigrationDir, cmdFlags.Arg(0))
+	b, warnings, err := generateMovedBlocks(path, c.config.MigrationFileOption())
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	for _, w := range warnings {
+		c.UI.Warn(w)
+	}
+
+	if len(c.out) == 0 {
+		c.UI.Output(strings.TrimSuffix(string(b), "\n"))
+		return 0
+	}
+
+	// O_EXCL not to overwrite an existing configuration by accident.
+	f, err := os.OpenFile(c.out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("failed to create file: %s", err))
+		return 1
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to write moved blocks: %s", err))
+		return 1
+	}
+
+	return 0
+}
+
+// generateMovedBlocks loads a given migration file and returns moved blocks
+// in HCL equivalent to its mv actions, and a list of warnings for actions
+// which cannot be converted.
+func generateMovedBlocks(path string, o *config.MigrationFileOption) ([]byte, []string, error) {
+	mc, err := loadMigrationFile(path, o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blocks, warnings, err := tfmigrate.MovedBlocks(mc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, warnings, fmt.Errorf("no mv actions to convert to moved blocks: %s", path)
+	}
+
+	b, err := config.FormatMovedBlocks(blocks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := fmt.Sprintf("# Generated by tfmigrate generate-moved from %s\n\n", filepath.Base(path))
+	return append([]byte(header), b...), warnings, nil
+}
+
+// Help returns long-form help text.
+func (c *GenerateMovedCommand) Help() string {
+	helpText := `
+Usage: tfmigrate generate-moved [options] PATH
+
+Convert mv actions in a state migration file into Terraform moved blocks,
+to move from state surgery to declarative refactoring. The moved blocks
+require Terraform v1.1+. Apply them with terraform plan and apply instead of
+the migration file, and don't apply both.
+
+Chained mv actions are kept as they are, because Terraform supports chained
+moved blocks. The other actions such as rm, import and xmv cannot be
+converted and are skipped with a warning. A multi_state migration cannot be
+converted, because moved blocks cannot move resources across states.
+
+Arguments:
+  PATH               A path of migration file
+
+Options:
+  --config           A path to tfmigrate config file
+  --var=NAME=VALUE   Set a variable which migration files can reference as var.NAME.
+  --var-file=path    A path to a variable file such as env = "prod" which sets
+                     variables for migration files. --var takes precedence over it.
+  --out=path         Write the moved blocks to the given path such as
+                     moved.tf in the working directory of the migration.
+                     It fails if the file already exists.
+                     Default to stdout.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *GenerateMovedCommand) Synopsis() string {
+	return "Generate moved blocks from a migration file"
+}
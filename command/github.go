@@ -0,0 +1,200 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+)
+
+// defaultGitHubAPIURL is a URL of GitHub API, which can be overridden with
+// GITHUB_API_URL for GitHub Enterprise Server.
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// githubCommentsPerPage is a number of comments fetched per request when
+// searching an existing comment.
+const githubCommentsPerPage = 100
+
+// githubClient is a minimal client for the issue comments API of GitHub.
+// A comment on a pull request is an issue comment.
+type githubClient struct {
+	// baseURL is a URL of GitHub API such as https://api.github.com.
+	baseURL string
+	// token is an API token.
+	token string
+	// httpClient is a client for HTTP requests.
+	httpClient *http.Client
+}
+
+// newGitHubClient returns a new client for a given URL of GitHub API.
+// If the URL is empty, it falls back to GITHUB_API_URL and api.github.com.
+func newGitHubClient(baseURL string, token string) *githubClient {
+	if len(baseURL) == 0 {
+		baseURL = os.Getenv("GITHUB_API_URL")
+	}
+	if len(baseURL) == 0 {
+		baseURL = defaultGitHubAPIURL
+	}
+	return &githubClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// githubComment is an issue comment of GitHub.
+type githubComment struct {
+	// ID is an ID of the comment.
+	ID int64 `json:"id"`
+	// Body is a body of the comment in markdown.
+	Body string `json:"body"`
+	// HTMLURL is a URL of the comment.
+	HTMLURL string `json:"html_url"`
+}
+
+// UpsertComment updates a comment on a given pull request whose body starts
+// with a given marker, or creates a new one if not found, so that a single
+// comment is kept up to date on every push. The repo is in the format of
+// owner/name. It returns the comment and whether it's newly created.
+func (c *githubClient) UpsertComment(ctx context.Context, repo string, pr int, marker string, body string) (*githubComment, bool, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || len(owner) == 0 || len(name) == 0 || strings.Contains(name, "/") {
+		return nil, false, fmt.Errorf("invalid repository: %q, it must be in the format of owner/name", repo)
+	}
+	repoURL := c.baseURL + "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name)
+
+	existing, err := c.findComment(ctx, repoURL, pr, marker)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var b []byte
+	created := existing == nil
+	if created {
+		b, err = c.do(ctx, http.MethodPost, repoURL+"/issues/"+strconv.Itoa(pr)+"/comments", req, http.StatusCreated)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create a comment on %s#%d: %s", repo, pr, err)
+		}
+	} else {
+		b, err = c.do(ctx, http.MethodPatch, repoURL+"/issues/comments/"+strconv.FormatInt(existing.ID, 10), req, http.StatusOK)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update a comment on %s#%d: %s", repo, pr, err)
+		}
+	}
+
+	var comment githubComment
+	if err := json.Unmarshal(b, &comment); err != nil {
+		return nil, false, fmt.Errorf("failed to parse a comment on %s#%d: %s", repo, pr, err)
+	}
+	return &comment, created, nil
+}
+
+// findComment returns the first comment on a given pull request whose body
+// starts with a given marker. It returns nil if not found.
+func (c *githubClient) findComment(ctx context.Context, repoURL string, pr int, marker string) (*githubComment, error) {
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/issues/%d/comments?per_page=%d&page=%d", repoURL, pr, githubCommentsPerPage, page)
+		b, err := c.do(ctx, http.MethodGet, u, nil, http.StatusOK)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list comments: %s", err)
+		}
+
+		var comments []*githubComment
+		if err := json.Unmarshal(b, &comments); err != nil {
+			return nil, fmt.Errorf("failed to parse comments: %s", err)
+		}
+		for _, comment := range comments {
+			if strings.HasPrefix(strings.TrimSpace(comment.Body), marker) {
+				return comment, nil
+			}
+		}
+		if len(comments) < githubCommentsPerPage {
+			return nil, nil
+		}
+	}
+}
+
+// do sends a request with the API token and returns the response body.
+// It returns an error if the status code is not a given one.
+func (c *githubClient) do(ctx context.Context, method string, u string, body []byte, status int) ([]byte, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != status {
+		return nil, fmt.Errorf("unexpected response from %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	return b, nil
+}
+
+// detectPullRequest returns a number of the pull request of the current
+// workflow run of GitHub Actions. It reads GITHUB_REF such as
+// refs/pull/123/merge, and falls back to the event payload in
+// GITHUB_EVENT_PATH, which is required for events such as issue_comment.
+// It returns 0 if not found.
+func detectPullRequest(getenv config.Getenv) int {
+	if ref := getenv("GITHUB_REF"); strings.HasPrefix(ref, "refs/pull/") {
+		n, _, _ := strings.Cut(strings.TrimPrefix(ref, "refs/pull/"), "/")
+		if pr, err := strconv.Atoi(n); err == nil {
+			return pr
+		}
+	}
+
+	path := getenv("GITHUB_EVENT_PATH")
+	if len(path) == 0 {
+		return 0
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	var event struct {
+		PullRequest struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+		Issue struct {
+			Number int `json:"number"`
+		} `json:"issue"`
+	}
+	if err := json.Unmarshal(b, &event); err != nil {
+		return 0
+	}
+	if event.PullRequest.Number > 0 {
+		return event.PullRequest.Number
+	}
+	return event.Issue.Number
+}
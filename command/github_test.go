@@ -0,0 +1,201 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// fakeGitHubServer is a fake server of the issue comments API of GitHub.
+type fakeGitHubServer struct {
+	// comments is a list of comments on a pull request.
+	comments []*githubComment
+	// requests is a list of requests in the format of "METHOD PATH".
+	requests []string
+}
+
+func (s *fakeGitHubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer dummy" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/foo/bar/issues/1/comments":
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start := min((page-1)*perPage, len(s.comments))
+		end := min(start+perPage, len(s.comments))
+		_ = json.NewEncoder(w).Encode(s.comments[start:end])
+
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/foo/bar/issues/1/comments":
+		c := s.decodeComment(r)
+		c.ID = int64(len(s.comments) + 1)
+		s.comments = append(s.comments, c)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(c)
+
+	case r.Method == http.MethodPatch:
+		for _, c := range s.comments {
+			if r.URL.Path == fmt.Sprintf("/repos/foo/bar/issues/comments/%d", c.ID) {
+				c.Body = s.decodeComment(r).Body
+				_ = json.NewEncoder(w).Encode(c)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *fakeGitHubServer) decodeComment(r *http.Request) *githubComment {
+	b, _ := io.ReadAll(r.Body)
+	var c githubComment
+	_ = json.Unmarshal(b, &c)
+	c.HTMLURL = fmt.Sprintf("https://github.com/foo/bar/pull/1#issuecomment-%d", len(s.comments)+1)
+	return &c
+}
+
+func TestGitHubClientUpsertComment(t *testing.T) {
+	marker := commentMarker("")
+	others := []*githubComment{}
+	for i := 0; i < githubCommentsPerPage; i++ {
+		others = append(others, &githubComment{ID: int64(i + 1), Body: "LGTM"})
+	}
+
+	cases := []struct {
+		desc        string
+		comments    []*githubComment
+		repo        string
+		wantCreated bool
+		wantReqs    int
+		ok          bool
+	}{
+		{
+			desc:        "create",
+			comments:    []*githubComment{{ID: 1, Body: "LGTM"}},
+			repo:        "foo/bar",
+			wantCreated: true,
+			wantReqs:    2,
+			ok:          true,
+		},
+		{
+			desc:        "update on the second page",
+			comments:    append(append([]*githubComment{}, others...), &githubComment{ID: 101, Body: marker + "\nold"}),
+			repo:        "foo/bar",
+			wantCreated: false,
+			wantReqs:    3,
+			ok:          true,
+		},
+		{
+			desc:     "invalid repo",
+			repo:     "foo",
+			wantReqs: 0,
+			ok:       false,
+		},
+		{
+			desc:     "not found",
+			repo:     "foo/baz",
+			wantReqs: 1,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := &fakeGitHubServer{comments: tc.comments}
+			ts := httptest.NewServer(s)
+			defer ts.Close()
+
+			c := newGitHubClient(ts.URL, "dummy")
+			body := marker + "\nnew"
+			got, created, err := c.UpsertComment(context.Background(), tc.repo, 1, marker, body)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if len(s.requests) != tc.wantReqs {
+				t.Errorf("got requests: %#v, want %d requests", s.requests, tc.wantReqs)
+			}
+			if !tc.ok {
+				return
+			}
+
+			if created != tc.wantCreated {
+				t.Errorf("got created = %t, want = %t", created, tc.wantCreated)
+			}
+			if got.Body != body {
+				t.Errorf("got body = %q, want = %q", got.Body, body)
+			}
+			found := 0
+			for _, comment := range s.comments {
+				if comment.Body == body {
+					found++
+				}
+			}
+			if found != 1 {
+				t.Errorf("expected exactly one comment with the body, got: %d", found)
+			}
+		})
+	}
+}
+
+func TestDetectPullRequest(t *testing.T) {
+	dir := t.TempDir()
+	prEvent := filepath.Join(dir, "pr.json")
+	if err := os.WriteFile(prEvent, []byte(`{"pull_request": {"number": 12}}`), 0600); err != nil {
+		t.Fatalf("failed to write event: %s", err)
+	}
+	commentEvent := filepath.Join(dir, "comment.json")
+	if err := os.WriteFile(commentEvent, []byte(`{"issue": {"number": 34}}`), 0600); err != nil {
+		t.Fatalf("failed to write event: %s", err)
+	}
+
+	cases := []struct {
+		desc string
+		env  map[string]string
+		want int
+	}{
+		{
+			desc: "ref",
+			env:  map[string]string{"GITHUB_REF": "refs/pull/123/merge", "GITHUB_EVENT_PATH": prEvent},
+			want: 123,
+		},
+		{
+			desc: "pull_request event",
+			env:  map[string]string{"GITHUB_REF": "refs/heads/main", "GITHUB_EVENT_PATH": prEvent},
+			want: 12,
+		},
+		{
+			desc: "issue_comment event",
+			env:  map[string]string{"GITHUB_REF": "refs/heads/main", "GITHUB_EVENT_PATH": commentEvent},
+			want: 34,
+		},
+		{
+			desc: "not found",
+			env:  map[string]string{"GITHUB_REF": "refs/heads/main"},
+			want: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := detectPullRequest(func(key string) string { return tc.env[key] })
+			if got != tc.want {
+				t.Errorf("got: %d, want: %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// GraphCommand is a command which renders a before/after graph of a migration.
+type GraphCommand struct {
+	Meta
+	format string
+}
+
+// Run runs the procedure of this command.
+func (c *GraphCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
+	cmdFlags.StringVar(&c.format, "format", "dot", "An output format")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	if c.format != "dot" && c.format != "mermaid" {
+		c.UI.Error(fmt.Sprintf("unknown format: %s", c.format))
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	filename := resolveMigrationFile(c.config.MigrationDir, cmdFlags.Arg(0))
+	mc, err := loadMigrationFile(filename, c.config.MigrationFileOption())
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	ctx := context.Background()
+	listState := func(dir string, workspace string, env map[string]string) ([]string, error) {
+		return tfmigrate.ListStateAddresses(ctx, dir, workspace, env, c.Option)
+	}
+	g, err := tfmigrate.NewMigrationGraph(mc, listState)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	switch c.format {
+	case "dot":
+		c.UI.Output(renderGraphDOT(g))
+	case "mermaid":
+		c.UI.Output(renderGraphMermaid(g))
+	}
+	return 0
+}
+
+// graphCluster is a group of nodes in the same module of a state.
+type graphCluster struct {
+	// label is a label of the cluster.
+	label string
+	// nodes is a list of node IDs and their labels.
+	nodes [][2]string
+}
+
+// graphLayout is a layout of a migration graph with node IDs assigned.
+type graphLayout struct {
+	// before is a list of clusters before the migration.
+	before []*graphCluster
+	// after is a list of clusters after the migration.
+	after []*graphCluster
+	// edges is a list of pairs of node IDs.
+	edges [][2]string
+	// removed is a list of IDs of removed nodes.
+	removed []string
+	// imported is a list of IDs of imported nodes.
+	imported []string
+}
+
+// newGraphLayout assigns node IDs and groups nodes by module.
+func newGraphLayout(g *tfmigrate.MigrationGraph) *graphLayout {
+	l := &graphLayout{}
+	before := make(map[string]*graphCluster)
+	after := make(map[string]*graphCluster)
+	n := 0
+	addNode := func(clusters map[string]*graphCluster, node *tfmigrate.GraphNode) string {
+		module, address := tfmigrate.ModulePath(node.Address)
+		label := node.Dir
+		if len(module) > 0 {
+			label += ": " + module
+		}
+		if len(address) == 0 {
+			address = module
+		}
+		cluster, ok := clusters[label]
+		if !ok {
+			cluster = &graphCluster{label: label}
+			clusters[label] = cluster
+		}
+		id := fmt.Sprintf("n%d", n)
+		n++
+		cluster.nodes = append(cluster.nodes, [2]string{id, address})
+		return id
+	}
+
+	for _, e := range g.Edges {
+		switch {
+		case e.From == nil:
+			l.imported = append(l.imported, addNode(after, e.To))
+		case e.To == nil:
+			l.removed = append(l.removed, addNode(before, e.From))
+		default:
+			from := addNode(before, e.From)
+			to := addNode(after, e.To)
+			l.edges = append(l.edges, [2]string{from, to})
+		}
+	}
+
+	l.before = sortedGraphClusters(before)
+	l.after = sortedGraphClusters(after)
+	return l
+}
+
+// sortedGraphClusters returns a list of clusters sorted by label.
+func sortedGraphClusters(clusters map[string]*graphCluster) []*graphCluster {
+	sorted := make([]*graphCluster, 0, len(clusters))
+	for _, cluster := range clusters {
+		sorted = append(sorted, cluster)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].label < sorted[j].label
+	})
+	return sorted
+}
+
+// renderGraphDOT renders a migration graph in the DOT language of Graphviz.
+func renderGraphDOT(g *tfmigrate.MigrationGraph) string {
+	l := newGraphLayout(g)
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", quote(g.Name))
+	b.WriteString("  rankdir = LR;\n")
+	b.WriteString("  node [shape = box];\n")
+	for i, side := range []struct {
+		name     string
+		clusters []*graphCluster
+	}{
+		{name: "before", clusters: l.before},
+		{name: "after", clusters: l.after},
+	} {
+		fmt.Fprintf(&b, "  subgraph cluster_%s {\n", side.name)
+		fmt.Fprintf(&b, "    label = %s;\n", quote(side.name))
+		for j, cluster := range side.clusters {
+			fmt.Fprintf(&b, "    subgraph cluster_%d_%d {\n", i, j)
+			fmt.Fprintf(&b, "      label = %s;\n", quote(cluster.label))
+			for _, node := range cluster.nodes {
+				fmt.Fprintf(&b, "      %s [label = %s];\n", node[0], quote(node[1]))
+			}
+			b.WriteString("    }\n")
+		}
+		b.WriteString("  }\n")
+	}
+	for _, e := range l.edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", e[0], e[1])
+	}
+	for _, id := range l.removed {
+		fmt.Fprintf(&b, "  %s [color = red, style = dashed, xlabel = \"removed\"];\n", id)
+	}
+	for _, id := range l.imported {
+		fmt.Fprintf(&b, "  %s [color = green, xlabel = \"imported\"];\n", id)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// renderGraphMermaid renders a migration graph in the flowchart syntax of
+// Mermaid.
+func renderGraphMermaid(g *tfmigrate.MigrationGraph) string {
+	l := newGraphLayout(g)
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s) + `"`
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, side := range []struct {
+		name     string
+		clusters []*graphCluster
+	}{
+		{name: "before", clusters: l.before},
+		{name: "after", clusters: l.after},
+	} {
+		fmt.Fprintf(&b, "  subgraph %s\n", side.name)
+		for j, cluster := range side.clusters {
+			fmt.Fprintf(&b, "    subgraph c%d_%d [%s]\n", i, j, quote(cluster.label))
+			for _, node := range cluster.nodes {
+				fmt.Fprintf(&b, "      %s[%s]\n", node[0], quote(node[1]))
+			}
+			b.WriteString("    end\n")
+		}
+		b.WriteString("  end\n")
+	}
+	for _, e := range l.edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", e[0], e[1])
+	}
+	if len(l.removed) > 0 {
+		b.WriteString("  classDef removed stroke:#f00,stroke-dasharray:5 5\n")
+		fmt.Fprintf(&b, "  class %s removed\n", strings.Join(l.removed, ","))
+	}
+	if len(l.imported) > 0 {
+		b.WriteString("  classDef imported stroke:#0a0\n")
+		fmt.Fprintf(&b, "  class %s imported\n", strings.Join(l.imported, ","))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Help returns long-form help text.
+func (c *GraphCommand) Help() string {
+	helpText := `
+Usage: tfmigrate graph [options] PATH
+
+Render a before/after graph of resources affected by a migration, grouped by
+directory and module, for inclusion in design docs and pull requests.
+It lists resources in the current states and simulates actions on them.
+It never modifies any state. Resources which the migration doesn't touch are
+not included. Action plugins and replace-provider actions are ignored.
+
+Arguments:
+  PATH               A path of migration file
+
+Options:
+  --config           A path to tfmigrate config file
+  --var=NAME=VALUE   Set a variable which migration files can reference as var.NAME.
+  --var-file=path    A path to a variable file such as env = "prod" which sets
+                     variables for migration files. --var takes precedence over it.
+  --format           An output format. Valid values are dot (default) and
+                     mermaid.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *GraphCommand) Synopsis() string {
+	return "Render a before/after graph of a migration"
+}
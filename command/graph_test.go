@@ -0,0 +1,87 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func testMigrationGraph() *tfmigrate.MigrationGraph {
+	return &tfmigrate.MigrationGraph{
+		Name: "test",
+		Edges: []*tfmigrate.GraphEdge{
+			{
+				From: &tfmigrate.GraphNode{Dir: "dir1", Address: `module.foo["a"].null_resource.bar`},
+				To:   &tfmigrate.GraphNode{Dir: "dir1", Address: "null_resource.bar"},
+			},
+			{
+				From: &tfmigrate.GraphNode{Dir: "dir1", Address: "null_resource.baz"},
+			},
+			{
+				To: &tfmigrate.GraphNode{Dir: "dir1", Address: "null_resource.qux"},
+			},
+		},
+	}
+}
+
+func TestRenderGraphDOT(t *testing.T) {
+	want := `digraph "test" {
+  rankdir = LR;
+  node [shape = box];
+  subgraph cluster_before {
+    label = "before";
+    subgraph cluster_0_0 {
+      label = "dir1";
+      n2 [label = "null_resource.baz"];
+    }
+    subgraph cluster_0_1 {
+      label = "dir1: module.foo[\"a\"]";
+      n0 [label = "null_resource.bar"];
+    }
+  }
+  subgraph cluster_after {
+    label = "after";
+    subgraph cluster_1_0 {
+      label = "dir1";
+      n1 [label = "null_resource.bar"];
+      n3 [label = "null_resource.qux"];
+    }
+  }
+  n0 -> n1;
+  n2 [color = red, style = dashed, xlabel = "removed"];
+  n3 [color = green, xlabel = "imported"];
+}`
+
+	got := renderGraphDOT(testMigrationGraph())
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderGraphMermaid(t *testing.T) {
+	want := `flowchart LR
+  subgraph before
+    subgraph c0_0 ["dir1"]
+      n2["null_resource.baz"]
+    end
+    subgraph c0_1 ["dir1: module.foo[#quot;a#quot;]"]
+      n0["null_resource.bar"]
+    end
+  end
+  subgraph after
+    subgraph c1_0 ["dir1"]
+      n1["null_resource.bar"]
+      n3["null_resource.qux"]
+    end
+  end
+  n0 --> n1
+  classDef removed stroke:#f00,stroke-dasharray:5 5
+  class n2 removed
+  classDef imported stroke:#0a0
+  class n3 imported`
+
+	got := renderGraphMermaid(testMigrationGraph())
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
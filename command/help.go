@@ -0,0 +1,93 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	"github.com/mitchellh/cli"
+)
+
+// HelpCommand is a parent command for help topics.
+// It does nothing and just shows its subcommands.
+type HelpCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *HelpCommand) Run(_ []string) int {
+	return cli.RunResultHelp
+}
+
+// Help returns long-form help text.
+func (c *HelpCommand) Help() string {
+	helpText := `
+Usage: tfmigrate help <topic>
+
+Show help for a given topic.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HelpCommand) Synopsis() string {
+	return "Show help for topics"
+}
+
+// HelpActionsCommand is a command which shows syntax and examples of actions.
+type HelpActionsCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *HelpActionsCommand) Run(args []string) int {
+	if len(args) != 0 {
+		c.UI.Error(fmt.Sprintf("The command expects 0 argument, but got %d", len(args)))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	c.UI.Output(formatActionSpecs())
+	return 0
+}
+
+// formatActionSpecs returns a help text for all available actions.
+// The text is generated from the specs used by the action parser.
+func formatActionSpecs() string {
+	var b strings.Builder
+	b.WriteString("Actions for migration block (state):\n")
+	writeActionSpecs(&b, tfmigrate.StateActionSpecs())
+	b.WriteString("\nActions for migration block (multi_state):\n")
+	writeActionSpecs(&b, tfmigrate.MultiStateActionSpecs())
+	b.WriteString("\nNote that a dollar sign in HCL strings needs to be escaped as $$ (e.g. $${1}).\n")
+	return strings.TrimSpace(b.String())
+}
+
+// writeActionSpecs writes a given list of action specs to b.
+func writeActionSpecs(b *strings.Builder, specs []tfmigrate.ActionSpec) {
+	for _, spec := range specs {
+		fmt.Fprintf(b, "\n  %s\n", spec.Usage())
+		fmt.Fprintf(b, "    %s\n", spec.Description)
+		if len(spec.Examples) > 0 {
+			b.WriteString("    Examples:\n")
+			for _, example := range spec.Examples {
+				fmt.Fprintf(b, "      %s\n", example)
+			}
+		}
+	}
+}
+
+// Help returns long-form help text.
+func (c *HelpActionsCommand) Help() string {
+	helpText := `
+Usage: tfmigrate help actions
+
+Show syntax and examples of all available actions in migration files.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HelpActionsCommand) Synopsis() string {
+	return "Show syntax and examples of actions"
+}
@@ -0,0 +1,24 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestFormatActionSpecs(t *testing.T) {
+	got := formatActionSpecs()
+
+	specs := append(tfmigrate.StateActionSpecs(), tfmigrate.MultiStateActionSpecs()...)
+	for _, spec := range specs {
+		if !strings.Contains(got, spec.Usage()) {
+			t.Errorf("help doesn't contain usage %q: %s", spec.Usage(), got)
+		}
+		for _, example := range spec.Examples {
+			if !strings.Contains(got, example) {
+				t.Errorf("help doesn't contain example %q: %s", example, got)
+			}
+		}
+	}
+}
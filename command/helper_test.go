@@ -1,8 +1,12 @@
 package command
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -44,3 +48,14 @@ func setupMigrationDir(t *testing.T, migrations map[string]string) string {
 
 	return migrationDir
 }
+
+// fillApprovalDigests is a test helper for replacing @digest(filename)
+// placeholders in a given history file with a sha256 digest of the migration
+// file, so that test cases can record approvals for the current content.
+func fillApprovalDigests(historyFile string, migrations map[string]string) string {
+	for filename, source := range migrations {
+		sum := sha256.Sum256([]byte(source))
+		historyFile = strings.ReplaceAll(historyFile, fmt.Sprintf("@digest(%s)", filename), hex.EncodeToString(sum[:]))
+	}
+	return historyFile
+}
@@ -0,0 +1,403 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	"github.com/mitchellh/cli"
+	flag "github.com/spf13/pflag"
+)
+
+// HistoryCommand is a parent command for managing a history file.
+// It does nothing and just shows its subcommands.
+type HistoryCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryCommand) Run(_ []string) int {
+	return cli.RunResultHelp
+}
+
+// Help returns long-form help text.
+func (c *HistoryCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history <subcommand>
+
+Manage a history file.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryCommand) Synopsis() string {
+	return "Manage a history file"
+}
+
+// HistoryMigrateFormatCommand is a command which converts a history file to
+// a given file format version.
+type HistoryMigrateFormatCommand struct {
+	Meta
+	version int
+	format  string
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryMigrateFormatCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("history migrate-format", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	cmdFlags.IntVar(&c.version, "version", history.LatestFileVersion, "A file format version to convert to")
+	cmdFlags.StringVar(&c.format, "format", "", "An encoding format to convert to (json or yaml)")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	if c.config.History == nil {
+		// non-history mode
+		c.UI.Error("no history setting")
+		return 1
+	}
+
+	ctx := context.Background()
+	out, err := migrateHistoryFormat(ctx, c.config, c.version, c.format)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	c.UI.Output(out)
+	return 0
+}
+
+// migrateHistoryFormat converts a history file to a given file format version
+// and encoding format, and returns a message for the result.
+// If the format is empty, it is written in the format in the config.
+func migrateHistoryFormat(ctx context.Context, config *config.TfmigrateConfig, version int, format string) (string, error) {
+	hc, err := history.NewController(ctx, config.MigrationDir, config.History)
+	if err != nil {
+		return "", err
+	}
+
+	from := hc.FileVersion()
+	if from == 0 {
+		return "", fmt.Errorf("no history file found")
+	}
+	if from == version && len(format) == 0 {
+		return fmt.Sprintf("history file is already in v%d format", version), nil
+	}
+
+	if err := hc.SaveAs(ctx, version, format); err != nil {
+		return "", err
+	}
+	if len(format) != 0 {
+		return fmt.Sprintf("history file format migrated from v%d to v%d in %s", from, version, format), nil
+	}
+	return fmt.Sprintf("history file format migrated from v%d to v%d", from, version), nil
+}
+
+// Help returns long-form help text.
+func (c *HistoryMigrateFormatCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history migrate-format [options]
+
+Convert a history file to a given file format version.
+A history file is upgraded to the latest format automatically on the first
+write, so this command is only needed for upgrading it explicitly in advance,
+or for downgrading it to use an older version of tfmigrate.
+This command requires history mode.
+
+Options:
+  --config           A path to tfmigrate config file
+  --version          A file format version to convert to.
+                     Default to the latest version.
+  --format           An encoding format to convert to, json or yaml.
+                     Default to the format in the history block.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryMigrateFormatCommand) Synopsis() string {
+	return "Convert a history file format"
+}
+
+// HistoryPingCommand is a command which checks health of the history storage.
+type HistoryPingCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryPingCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("history ping", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	if c.config.History == nil {
+		// non-history mode
+		c.UI.Error("no history setting")
+		return 1
+	}
+
+	ctx := context.Background()
+	out, err := pingHistoryStorage(ctx, c.config)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	c.UI.Output(out)
+	return 0
+}
+
+// pingHistoryStorage checks health of the history storage and returns
+// latencies of operations.
+func pingHistoryStorage(ctx context.Context, config *config.TfmigrateConfig) (string, error) {
+	s, err := config.History.Storage.NewStorage()
+	if err != nil {
+		return "", err
+	}
+
+	r, err := s.Ping(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf(`history storage is healthy
+  read:   %s
+  write:  %s
+  delete: %s`,
+		r.ReadLatency.Round(time.Millisecond),
+		r.WriteLatency.Round(time.Millisecond),
+		r.DeleteLatency.Round(time.Millisecond),
+	)
+	return out, nil
+}
+
+// Help returns long-form help text.
+func (c *HistoryPingCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history ping [options]
+
+Check health of the history storage.
+It reads the history file, and writes and deletes a probe key next to it
+with a suffix of .tfmigrate-probe, so that missing permissions are detected
+before applying migrations. The history file itself is never modified.
+This command requires history mode.
+
+Options:
+  --config           A path to tfmigrate config file
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryPingCommand) Synopsis() string {
+	return "Check health of the history storage"
+}
+
+// HistoryVerifyCommand is a command which verifies applied migrations in
+// history against the current states.
+type HistoryVerifyCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryVerifyCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("history verify", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	if c.config.History == nil {
+		// non-history mode
+		c.UI.Error("no history setting")
+		return 1
+	}
+
+	ctx := context.Background()
+	listState := func(ctx context.Context, e *tfmigrate.StateExpectation) ([]string, error) {
+		return tfmigrate.ListStateAddresses(ctx, e.Dir, e.Workspace, e.Env, c.Option)
+	}
+	inconsistencies, verified, err := verifyHistory(ctx, c.config, listState)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	for _, i := range inconsistencies {
+		c.UI.Output(i.String())
+	}
+	if len(inconsistencies) > 0 {
+		c.UI.Error(fmt.Sprintf("found %d inconsistencies between history and the current states", len(inconsistencies)))
+		return 2
+	}
+	c.UI.Output(fmt.Sprintf("no inconsistencies found in %d applied migrations", verified))
+	return 0
+}
+
+// historyInconsistency is an expectation of an applied migration which the
+// current state doesn't meet.
+type historyInconsistency struct {
+	// filename is a migration file name.
+	filename string
+	// expectation is an expectation on the current state.
+	expectation *tfmigrate.StateExpectation
+}
+
+// String returns a human readable message for the inconsistency.
+func (i *historyInconsistency) String() string {
+	e := i.expectation
+	if e.Exists {
+		return fmt.Sprintf("%s: %s is expected to exist in %s@%s, but not found", i.filename, e.Address, e.Dir, e.Workspace)
+	}
+	return fmt.Sprintf("%s: %s is expected to be absent in %s@%s, but found", i.filename, e.Address, e.Dir, e.Workspace)
+}
+
+// stateLister returns a list of resource addresses in the current state
+// which a given expectation is on.
+type stateLister func(ctx context.Context, e *tfmigrate.StateExpectation) ([]string, error)
+
+// verifyHistory replays expectations of applied migrations in the order they
+// were applied and checks the net expectations against the current states,
+// so that a migration reverted out-of-band is detected. An expectation
+// superseded by a later migration touching an overlapping address is not
+// checked. Skipped migrations are ignored.
+// It returns a list of inconsistencies and a number of verified migrations.
+func verifyHistory(ctx context.Context, config *config.TfmigrateConfig, listState stateLister) ([]*historyInconsistency, int, error) {
+	hc, err := history.NewController(ctx, config.MigrationDir, config.History)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	type appliedMigration struct {
+		filename  string
+		appliedAt time.Time
+	}
+	applied := []appliedMigration{}
+	for _, filename := range hc.Migrations() {
+		r, ok := hc.Record(filename)
+		if !ok || r.Skipped {
+			continue
+		}
+		applied = append(applied, appliedMigration{filename: filename, appliedAt: r.AppliedAt})
+	}
+	sort.SliceStable(applied, func(i, j int) bool {
+		return applied[i].appliedAt.Before(applied[j].appliedAt)
+	})
+
+	expected := []*historyInconsistency{}
+	for _, m := range applied {
+		log.Printf("[INFO] [command] load migration file: %s\n", m.filename)
+		mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, m.filename), config.MigrationFileOption())
+		if err != nil {
+			return nil, 0, err
+		}
+		expectations, err := mc.Expectations()
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, e := range expectations {
+			remaining := []*historyInconsistency{}
+			for _, prev := range expected {
+				if !prev.expectation.Overlaps(e) {
+					remaining = append(remaining, prev)
+				}
+			}
+			expected = append(remaining, &historyInconsistency{filename: m.filename, expectation: e})
+		}
+	}
+
+	// Pull each state only once.
+	states := make(map[string][]string)
+	inconsistencies := []*historyInconsistency{}
+	for _, i := range expected {
+		e := i.expectation
+		key := filepath.Clean(e.Dir) + "@" + e.Workspace
+		addresses, ok := states[key]
+		if !ok {
+			addresses, err = listState(ctx, e)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to list resources in %s: %s", key, err)
+			}
+			states[key] = addresses
+		}
+		if !e.Satisfied(addresses) {
+			inconsistencies = append(inconsistencies, i)
+		}
+	}
+
+	return inconsistencies, len(applied), nil
+}
+
+// Help returns long-form help text.
+func (c *HistoryVerifyCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history verify [options]
+
+Verify applied migrations in history against the current states.
+It replays expectations of applied migrations in the order they were applied,
+such as a destination of mv exists and a source of mv is absent, and checks
+them against the current remote states to detect migrations reverted
+out-of-band. An expectation superseded by a later migration is not checked.
+Actions whose results are not determined by the migration file alone, such as
+xmv, replace-provider and action plugins, are not verified.
+It exits with 2 if any inconsistency is found.
+This command requires history mode.
+
+Options:
+  --config           A path to tfmigrate config file
+  --var=NAME=VALUE   Set a variable which migration files can reference as var.NAME.
+  --var-file=path    A path to a variable file such as env = "prod" which sets
+                     variables for migration files. --var takes precedence over it.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryVerifyCommand) Synopsis() string {
+	return "Verify applied migrations against the current states"
+}
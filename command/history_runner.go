@@ -3,10 +3,13 @@ package command
 import (
 	"context"
 	"fmt"
-	"log"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/minamijoyo/tfmigrate/config"
 	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/logging"
 	"github.com/minamijoyo/tfmigrate/tfmigrate"
 )
 
@@ -23,6 +26,17 @@ type HistoryRunner struct {
 	option *tfmigrate.MigratorOption
 	// A controller which manages history.
 	hc *history.Controller
+	// mu guards hc while applying migrations in parallel.
+	mu sync.Mutex
+	// A maximum number of migrations applied concurrently in directory mode.
+	// Migrations which share working directories or backends are never
+	// applied concurrently. Default to 1, which means sequential.
+	parallelism int
+	// An identity of the user who applies migrations. Approvals by the applier
+	// are not counted. If empty, all approvals are counted.
+	applier string
+	// planned is a list of runners of migrations planned in the last Plan.
+	planned []*FileRunner
 }
 
 // NewHistoryRunner returns a new HistoryRunner instance.
@@ -33,10 +47,11 @@ func NewHistoryRunner(ctx context.Context, filename string, config *config.Tfmig
 	}
 
 	r := &HistoryRunner{
-		filename: filename,
-		config:   config,
-		option:   option,
-		hc:       hc,
+		filename:    filename,
+		config:      config,
+		option:      option,
+		hc:          hc,
+		parallelism: 1,
 	}
 
 	return r, nil
@@ -46,6 +61,7 @@ func NewHistoryRunner(ctx context.Context, filename string, config *config.Tfmig
 // If a filename is set, run a single migration.
 // If not set, run all unapplied migrations.
 func (r *HistoryRunner) Plan(ctx context.Context) error {
+	r.planned = nil
 	if len(r.filename) != 0 {
 		// file mode
 		return r.planFile(ctx, r.filename)
@@ -63,22 +79,32 @@ func (r *HistoryRunner) planFile(ctx context.Context, filename string) error {
 
 	fr, err := NewFileRunner(filename, r.config, r.option)
 	if err != nil {
-		log.Printf("[ERROR] [runner] failed to plan: %s\n", filename)
+		logging.FromContext(ctx).Printf("[ERROR] [runner] failed to plan: %s\n", filename)
 		return err
 	}
 
+	r.planned = append(r.planned, fr)
 	return fr.Plan(ctx)
 }
 
+// Planned returns a list of runners of migrations planned in the last Plan
+// in order, including a failed one.
+func (r *HistoryRunner) Planned() []*FileRunner {
+	return r.planned
+}
+
 // planDir plans all unapplied migrations.
 func (r *HistoryRunner) planDir(ctx context.Context) error {
-	unapplied := r.hc.UnappliedMigrations()
+	unapplied, err := r.unappliedMigrations()
+	if err != nil {
+		return err
+	}
 
 	if len(unapplied) == 0 {
-		log.Printf("[INFO] [runner] no unapplied migrations\n")
+		logging.FromContext(ctx).Printf("[INFO] [runner] no unapplied migrations\n")
 		return nil
 	}
-	log.Printf("[INFO] [runner] unapplied migration files: %v\n", unapplied)
+	logging.FromContext(ctx).Printf("[INFO] [runner] unapplied migration files: %v\n", unapplied)
 
 	for _, filename := range unapplied {
 		err := r.planFile(ctx, filename)
@@ -100,21 +126,27 @@ func (r *HistoryRunner) Apply(ctx context.Context) (err error) {
 		// if the number of records in history doesn't change,
 		// we don't want to update a timestamp of history file.
 		afterLen := r.hc.HistoryLength()
-		log.Printf("[DEBUG] [runner] length of history records: beforeLen = %d, afterLen = %d\n", beforeLen, afterLen)
-		if beforeLen == afterLen {
+		logging.FromContext(ctx).Printf("[DEBUG] [runner] length of history records: beforeLen = %d, afterLen = %d\n", beforeLen, afterLen)
+		if beforeLen == afterLen && !r.hc.FailureAdded() {
+			return
+		}
+
+		// In sandbox mode, we never touch the real history.
+		if r.option != nil && len(r.option.SandboxDir) != 0 {
+			logging.FromContext(ctx).Print("[INFO] [runner] sandbox mode: skip saving history\n")
 			return
 		}
 
 		// be sure not to overwrite an original error generated by outside of defer
-		log.Print("[INFO] [runner] save history\n")
+		logging.FromContext(ctx).Print("[INFO] [runner] save history\n")
 		serr := r.hc.Save(ctx)
 		if serr == nil {
-			log.Print("[INFO] [runner] history saved\n")
+			logging.FromContext(ctx).Print("[INFO] [runner] history saved\n")
 			return
 		}
 
 		// return a named error from defer
-		log.Printf("[ERROR] [runner] failed to save history. The history may be inconsistent\n")
+		logging.FromContext(ctx).Printf("[ERROR] [runner] failed to save history. The history may be inconsistent\n")
 		if err == nil {
 			err = fmt.Errorf("apply succeed, but failed to save history: %v", serr)
 			return
@@ -122,6 +154,9 @@ func (r *HistoryRunner) Apply(ctx context.Context) (err error) {
 		err = fmt.Errorf("failed to save history: %v, failed to apply: %v", serr, err)
 	}()
 
+	// record the code version which motivated the migrations.
+	r.hc.SetVCS(detectVCS(ctx, r.config.MigrationDir))
+
 	if len(r.filename) != 0 {
 		// file mode
 		err = r.applyFile(ctx, r.filename)
@@ -133,39 +168,146 @@ func (r *HistoryRunner) Apply(ctx context.Context) (err error) {
 	return err
 }
 
+// SetParallelism sets a maximum number of migrations applied concurrently in
+// directory mode. A value less than 1 is treated as 1.
+func (r *HistoryRunner) SetParallelism(n int) {
+	r.parallelism = max(n, 1)
+}
+
+// SetApplier sets an identity of the user who applies migrations.
+// Approvals by the applier are not counted toward required approvals.
+func (r *HistoryRunner) SetApplier(applier string) {
+	r.applier = applier
+}
+
 // applyFile applies a single migration.
+// It is safe to call concurrently for migrations which don't share any
+// working directories.
 func (r *HistoryRunner) applyFile(ctx context.Context, filename string) error {
-	if r.hc.AlreadyApplied(filename) {
+	r.mu.Lock()
+	applied := r.hc.AlreadyApplied(filename)
+	r.mu.Unlock()
+	if applied {
 		return fmt.Errorf("a migration has already been applied: %s", filename)
 	}
 
-	fr, err := NewFileRunner(filename, r.config, r.option)
+	// NewFileRunner sets values from the config to the option, so we pass a
+	// copy not to share it across concurrent migrations.
+	option := r.option
+	if option != nil {
+		o := *option
+		option = &o
+	}
+	fr, err := NewFileRunner(filename, r.config, option)
 	if err != nil {
 		return err
 	}
 
-	err = fr.Apply(ctx)
-	if err != nil {
-		log.Printf("[ERROR] [runner] failed to apply: %s\n", filename)
+	skipped, err := r.prepareApply(ctx, filename, fr)
+	if err != nil || skipped {
 		return err
 	}
 
+	// apply without the lock, which takes most of the time.
+	err = fr.Apply(ctx)
+	r.recordApply(ctx, filename, fr, err)
+	return err
+}
+
+// prepareApply checks if a given migration can be applied, and adds a
+// skipped record to history if it's skipped by skip_if.
+// It returns true if the migration is skipped.
+func (r *HistoryRunner) prepareApply(ctx context.Context, filename string, fr *FileRunner) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// check if all dependencies have already been applied.
+	mc := fr.MigrationConfig()
+	if _, err := r.hc.SortByDependencies([]string{filename}, map[string][]string{filename: mc.DependsOn}); err != nil {
+		return false, err
+	}
+
+	if fr.Skipped() {
+		logging.FromContext(ctx).Printf("[INFO] [runner] skip migration by skip_if and add a skipped record to history: %s\n", filename)
+		r.hc.AddSkippedRecord(filename, mc.Type, mc.Name)
+		return true, nil
+	}
+
+	return false, r.checkApprovals(filename, mc)
+}
+
+// recordApply adds a record or a failure of a given migration to history
+// depending on a given error of applying it.
+func (r *HistoryRunner) recordApply(ctx context.Context, filename string, fr *FileRunner, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	mc := fr.MigrationConfig()
-	log.Printf("[INFO] [runner] add a record to history: %s\n", filename)
-	r.hc.AddRecord(filename, mc.Type, mc.Name, nil)
+	actions := newActionRecords(fr.ActionResults())
+	if err != nil {
+		logging.FromContext(ctx).Printf("[ERROR] [runner] failed to apply: %s\n", filename)
+		logging.FromContext(ctx).Printf("[INFO] [runner] add a failure to history: %s\n", filename)
+		r.hc.AddFailure(filename, mc.Type, mc.Name, actions, nil)
+		return
+	}
 
-	return nil
+	logging.FromContext(ctx).Printf("[INFO] [runner] add a record to history: %s\n", filename)
+	r.hc.AddRecord(filename, mc.Type, mc.Name, actions, nil)
 }
 
 // applyDir applies all unapplied migrations.
 func (r *HistoryRunner) applyDir(ctx context.Context) (err error) {
-	unapplied := r.hc.UnappliedMigrations()
+	unapplied, err := r.unappliedMigrations()
+	if err != nil {
+		return err
+	}
 
 	if len(unapplied) == 0 {
-		log.Printf("[INFO] [runner] no unapplied migrations\n")
+		logging.FromContext(ctx).Printf("[INFO] [runner] no unapplied migrations\n")
 		return nil
 	}
-	log.Printf("[INFO] [runner] unapplied migration files: %v\n", unapplied)
+	logging.FromContext(ctx).Printf("[INFO] [runner] unapplied migration files: %v\n", unapplied)
+
+	// check approvals for all migrations before applying any of them
+	// not to leave migrations partially applied.
+	// A skipped migration doesn't require approvals because it does nothing.
+	resources := make(map[string][]string)
+	dependsOn := make(map[string][]string)
+	sandboxTargets := make(map[string]string)
+	for _, filename := range unapplied {
+		mc, err := loadMigrationFile(resolveMigrationFile(r.config.MigrationDir, filename), r.config.MigrationFileOption())
+		if err != nil {
+			return err
+		}
+		resources[filename] = migrationResources(mc, r.option)
+		dependsOn[filename] = mc.DependsOn
+		if mc.Skip {
+			continue
+		}
+		if err := r.checkApprovals(filename, mc); err != nil {
+			return err
+		}
+		if r.option != nil && len(r.option.SandboxDir) != 0 {
+			if err := checkSandboxTargets(sandboxTargets, filename, mc); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.parallelism > 1 {
+		logging.FromContext(ctx).Printf("[INFO] [runner] apply migrations in parallel: parallelism = %d\n", r.parallelism)
+		// terraform init is serialized across migrations, because they may
+		// share a plugin cache dir, which is not safe for concurrent use.
+		o := tfmigrate.MigratorOption{}
+		if r.option != nil {
+			o = *r.option
+		}
+		o.InitLock = &sync.Mutex{}
+		r.option = &o
+		jobs := newParallelJobs(unapplied, resources, dependsOn)
+		logParallelJobs(jobs)
+		return runParallelJobs(ctx, jobs, r.parallelism, r.applyFile)
+	}
 
 	for _, filename := range unapplied {
 		err := r.applyFile(ctx, filename)
@@ -176,3 +318,93 @@ func (r *HistoryRunner) applyDir(ctx context.Context) (err error) {
 
 	return nil
 }
+
+// checkApprovals returns an error if a given migration doesn't have enough
+// approvals.
+func (r *HistoryRunner) checkApprovals(filename string, mc *tfmigrate.MigrationConfig) error {
+	return checkApprovals(r.config, r.hc, filename, mc, r.applier)
+}
+
+// checkApprovals returns an error if a given migration doesn't have enough
+// approvals required by the history config, or approvals by teams in
+// approved_by required by the ownership. Approvals by the applier are ignored.
+func checkApprovals(config *config.TfmigrateConfig, hc *history.Controller, filename string, mc *tfmigrate.MigrationConfig, applier string) error {
+	if err := hc.CheckApprovals(filename, applier); err != nil {
+		return err
+	}
+
+	if config.Ownership != nil {
+		approvals, err := hc.ValidApprovals(filename)
+		if err != nil {
+			return err
+		}
+		approvers := []string{}
+		for _, a := range approvals {
+			if len(applier) != 0 && a.Approver == applier {
+				continue
+			}
+			approvers = append(approvers, a.Approver)
+		}
+		if err := config.Ownership.CheckApprovals(mc, approvers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSandboxTargets returns an error if a given migration touches a state
+// which is also touched by a previous migration in sandbox mode.
+// Each migration is planned against the real remote state and new states
+// are written to local files, so the later migration would not see the
+// changes of the earlier one. The seen is a map of targets to filenames,
+// which is updated with targets of the given migration.
+func checkSandboxTargets(seen map[string]string, filename string, mc *tfmigrate.MigrationConfig) error {
+	for _, target := range mc.Targets() {
+		i := strings.LastIndex(target, "@")
+		dir, workspace := target[:i], target[i+1:]
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		key := dir + "@" + workspace
+		if prev, ok := seen[key]; ok && prev != filename {
+			return fmt.Errorf("sandbox mode cannot chain migrations touching the same state %s: %s and %s", target, prev, filename)
+		}
+		seen[key] = filename
+	}
+	return nil
+}
+
+// unappliedMigrations returns a list of unapplied migrations sorted in an
+// order which satisfies their dependencies.
+func (r *HistoryRunner) unappliedMigrations() ([]string, error) {
+	unapplied := r.hc.UnappliedMigrations()
+
+	dependsOn := make(map[string][]string)
+	for _, filename := range unapplied {
+		mc, err := loadMigrationFile(resolveMigrationFile(r.config.MigrationDir, filename), r.config.MigrationFileOption())
+		if err != nil {
+			return nil, err
+		}
+		if len(mc.DependsOn) > 0 {
+			dependsOn[filename] = mc.DependsOn
+		}
+	}
+
+	return r.hc.SortByDependencies(unapplied, dependsOn)
+}
+
+// newActionRecords converts results of actions to records in history.
+func newActionRecords(results []tfmigrate.ActionResult) []history.ActionRecord {
+	var records []history.ActionRecord
+	for _, r := range results {
+		records = append(records, history.ActionRecord{
+			Action:     r.Action,
+			Status:     r.Status,
+			StartedAt:  r.StartedAt,
+			FinishedAt: r.FinishedAt,
+			Error:      r.Error,
+		})
+	}
+	return records
+}
@@ -2,13 +2,25 @@ package command
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/minamijoyo/tfmigrate/config"
 	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/gcs"
+	"github.com/minamijoyo/tfmigrate/storage/local"
 	"github.com/minamijoyo/tfmigrate/storage/mock"
+	"github.com/minamijoyo/tfmigrate/storage/s3"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
 )
 
 func TestHistoryRunnerPlan(t *testing.T) {
@@ -256,8 +268,12 @@ func TestHistoryRunnerApply(t *testing.T) {
 		filename    string
 		writeError  bool
 		readError   bool
-		want        string
-		ok          bool
+		// requiredApprovals is a number of approvals required to apply.
+		requiredApprovals int
+		// applier is an identity of the user who applies migrations.
+		applier string
+		want    string
+		ok      bool
 	}{
 		{
 			desc: "no args",
@@ -578,6 +594,13 @@ migration "mock" "test4" {
             "name": "test3",
             "applied_at": "2020-11-10T00:00:03Z"
         }
+    },
+    "failures": {
+        "20201109000004_test4.hcl": {
+            "type": "mock",
+            "name": "test4",
+            "applied_at": "2020-11-10T00:00:04Z"
+        }
     }
 }`,
 			ok: false,
@@ -705,6 +728,354 @@ migration "mock" "test4" {
             "applied_at": "2020-11-10T00:00:02Z"
         }
     }
+}`,
+			ok: false,
+		},
+		{
+			desc: "dependency cycle",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	depends_on  = ["20201109000002_test2.hcl"]
+	plan_error  = false
+	apply_error = false
+}
+`,
+				"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	depends_on  = ["20201109000001_test1.hcl"]
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			historyFile: `{
+    "version": 1,
+    "records": {}
+}`,
+			filename:   "",
+			writeError: false,
+			readError:  false,
+			want: `{
+    "version": 1,
+    "records": {}
+}`,
+			ok: false,
+		},
+		{
+			desc: "a filename is given, but its dependency has not been applied",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	depends_on  = ["20201109000002_test2.hcl"]
+	plan_error  = false
+	apply_error = false
+}
+`,
+				"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			historyFile: `{
+    "version": 1,
+    "records": {}
+}`,
+			filename:   "20201109000001_test1.hcl",
+			writeError: false,
+			readError:  false,
+			want: `{
+    "version": 1,
+    "records": {}
+}`,
+			ok: false,
+		},
+		{
+			desc: "approved enough",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			historyFile: `{
+    "version": 1,
+    "records": {},
+    "approvals": {
+        "20201109000001_test1.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            },
+            {
+                "approver": "bob",
+                "approved_at": "2020-11-10T00:00:02Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            }
+        ]
+    }
+}`,
+			filename:          "",
+			writeError:        false,
+			readError:         false,
+			requiredApprovals: 2,
+			want: `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:03Z"
+        }
+    },
+    "approvals": {
+        "20201109000001_test1.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            },
+            {
+                "approver": "bob",
+                "approved_at": "2020-11-10T00:00:02Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            }
+        ]
+    }
+}`,
+			ok: true,
+		},
+		{
+			desc: "self-approval is not counted",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			historyFile: `{
+    "version": 1,
+    "records": {},
+    "approvals": {
+        "20201109000001_test1.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            },
+            {
+                "approver": "bob",
+                "approved_at": "2020-11-10T00:00:02Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            }
+        ]
+    }
+}`,
+			filename:          "",
+			writeError:        false,
+			readError:         false,
+			requiredApprovals: 2,
+			applier:           "alice",
+			want: `{
+    "version": 1,
+    "records": {},
+    "approvals": {
+        "20201109000001_test1.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            },
+            {
+                "approver": "bob",
+                "approved_at": "2020-11-10T00:00:02Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            }
+        ]
+    }
+}`,
+			ok: false,
+		},
+		{
+			desc: "not approved enough",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+				"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			historyFile: `{
+    "version": 1,
+    "records": {},
+    "approvals": {
+        "20201109000001_test1.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            }
+        ],
+        "20201109000002_test2.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000002_test2.hcl)"
+            }
+        ]
+    }
+}`,
+			filename:          "",
+			writeError:        false,
+			readError:         false,
+			requiredApprovals: 2,
+			want: `{
+    "version": 1,
+    "records": {},
+    "approvals": {
+        "20201109000001_test1.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            }
+        ],
+        "20201109000002_test2.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000002_test2.hcl)"
+            }
+        ]
+    }
+}`,
+			ok: false,
+		},
+		{
+			desc: "skip_if",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	skip_if     = true
+	plan_error  = true
+	apply_error = true
+}
+`,
+				"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	skip_if     = false
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			historyFile: `{
+    "version": 2,
+    "migrations": {
+        "20201109000002_test2.hcl": {
+            "approvals": [
+                {
+                    "approver": "alice",
+                    "approved_at": "2020-11-10T00:00:01Z",
+                    "digest": "@digest(20201109000002_test2.hcl)"
+                }
+            ]
+        }
+    }
+}`,
+			filename:   "",
+			writeError: false,
+			readError:  false,
+			// A skipped migration doesn't require approvals.
+			requiredApprovals: 1,
+			want: `{
+    "version": 2,
+    "migrations": {
+        "20201109000001_test1.hcl": {
+            "applied": {
+                "type": "mock",
+                "name": "test1",
+                "timestamp": "2020-11-10T00:00:01Z",
+                "skipped": true
+            }
+        },
+        "20201109000002_test2.hcl": {
+            "applied": {
+                "type": "mock",
+                "name": "test2",
+                "timestamp": "2020-11-10T00:00:02Z"
+            },
+            "approvals": [
+                {
+                    "approver": "alice",
+                    "approved_at": "2020-11-10T00:00:01Z",
+                    "digest": "@digest(20201109000002_test2.hcl)"
+                }
+            ]
+        }
+    }
+}`,
+			ok: true,
+		},
+		{
+			desc: "partially approved",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+				"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			historyFile: `{
+    "version": 1,
+    "records": {},
+    "approvals": {
+        "20201109000001_test1.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            }
+        ]
+    }
+}`,
+			filename:          "",
+			writeError:        false,
+			readError:         false,
+			requiredApprovals: 1,
+			want: `{
+    "version": 1,
+    "records": {},
+    "approvals": {
+        "20201109000001_test1.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-11-10T00:00:01Z",
+                "digest": "@digest(20201109000001_test1.hcl)"
+            }
+        ]
+    }
 }`,
 			ok: false,
 		},
@@ -714,14 +1085,15 @@ migration "mock" "test4" {
 		t.Run(tc.desc, func(t *testing.T) {
 			migrationDir := setupMigrationDir(t, tc.migrations)
 			mockConfig := &mock.Config{
-				Data:       tc.historyFile,
+				Data:       fillApprovalDigests(tc.historyFile, tc.migrations),
 				WriteError: tc.writeError,
 				ReadError:  tc.readError,
 			}
 			config := &config.TfmigrateConfig{
 				MigrationDir: migrationDir,
 				History: &history.Config{
-					Storage: mockConfig,
+					Storage:           mockConfig,
+					RequiredApprovals: tc.requiredApprovals,
 				},
 			}
 			r, err := NewHistoryRunner(context.Background(), tc.filename, config, nil)
@@ -729,6 +1101,8 @@ migration "mock" "test4" {
 				t.Fatalf("failed to new history runner: %s", err)
 			}
 
+			r.SetApplier(tc.applier)
+
 			err = r.Apply(context.Background())
 			if tc.ok && err != nil {
 				t.Fatalf("unexpected err: %s", err)
@@ -736,7 +1110,7 @@ migration "mock" "test4" {
 			if !tc.ok && err == nil {
 				t.Fatal("expected to return an error, but no error")
 			}
-			want, err := history.ParseHistoryFile([]byte(tc.want))
+			want, err := history.ParseHistoryFile([]byte(fillApprovalDigests(tc.want, tc.migrations)))
 			if err != nil {
 				t.Fatalf("failed to parse history file (want): %s", err)
 			}
@@ -752,3 +1126,505 @@ migration "mock" "test4" {
 		})
 	}
 }
+
+func TestHistoryRunnerApplyOwnership(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	approved_by = ["network"]
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	cases := []struct {
+		desc        string
+		historyFile string
+		ok          bool
+	}{
+		{
+			desc: "approved by a member",
+			historyFile: `{
+    "version": 2,
+    "migrations": {
+        "20201109000001_test1.hcl": {
+            "approvals": [
+                {
+                    "approver": "alice",
+                    "approved_at": "2020-11-10T00:00:01Z",
+                    "digest": "@digest(20201109000001_test1.hcl)"
+                }
+            ]
+        }
+    }
+}`,
+			ok: true,
+		},
+		{
+			desc: "approved by a non-member",
+			historyFile: `{
+    "version": 2,
+    "migrations": {
+        "20201109000001_test1.hcl": {
+            "approvals": [
+                {
+                    "approver": "bob",
+                    "approved_at": "2020-11-10T00:00:01Z",
+                    "digest": "@digest(20201109000001_test1.hcl)"
+                }
+            ]
+        }
+    }
+}`,
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, migrations)
+			mockConfig := &mock.Config{
+				Data: fillApprovalDigests(tc.historyFile, migrations),
+			}
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage: mockConfig,
+				},
+				Ownership: &tfmigrate.Ownership{
+					Owners: []*tfmigrate.Owner{
+						{
+							Team:     "network",
+							Prefixes: []string{"module.network"},
+							Members:  []string{"alice"},
+						},
+					},
+				},
+			}
+			r, err := NewHistoryRunner(context.Background(), "", config, nil)
+			if err != nil {
+				t.Fatalf("failed to new history runner: %s", err)
+			}
+
+			err = r.Apply(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestHistoryRunnerApplySandbox(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	historyFile := `{
+    "version": 1,
+    "records": {}
+}`
+
+	migrationDir := setupMigrationDir(t, migrations)
+	mockConfig := &mock.Config{
+		Data:       historyFile,
+		WriteError: false,
+		ReadError:  false,
+	}
+	config := &config.TfmigrateConfig{
+		MigrationDir: migrationDir,
+		History: &history.Config{
+			Storage: mockConfig,
+		},
+	}
+	option := &tfmigrate.MigratorOption{
+		SandboxDir: t.TempDir(),
+	}
+	r, err := NewHistoryRunner(context.Background(), "", config, option)
+	if err != nil {
+		t.Fatalf("failed to new history runner: %s", err)
+	}
+
+	err = r.Apply(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	got := mockConfig.Storage().Data()
+	if got != historyFile {
+		t.Errorf("expected not to save history in sandbox mode, but got = %s", got)
+	}
+}
+
+func TestCheckSandboxTargets(t *testing.T) {
+	stateMigration := func(dir string, workspace string) *tfmigrate.MigrationConfig {
+		return &tfmigrate.MigrationConfig{
+			Type:     "state",
+			Name:     "test",
+			Migrator: &tfmigrate.StateMigratorConfig{Dir: dir, Workspace: workspace},
+		}
+	}
+	multiStateMigration := func(fromDir string, toDir string) *tfmigrate.MigrationConfig {
+		return &tfmigrate.MigrationConfig{
+			Type:     "multi_state",
+			Name:     "test",
+			Migrator: &tfmigrate.MultiStateMigratorConfig{FromDir: fromDir, ToDir: toDir},
+		}
+	}
+
+	cases := []struct {
+		desc       string
+		migrations map[string]*tfmigrate.MigrationConfig
+		filenames  []string
+		ok         bool
+	}{
+		{
+			desc: "different dirs",
+			migrations: map[string]*tfmigrate.MigrationConfig{
+				"20201109000001_test1.hcl": stateMigration("dir1", ""),
+				"20201109000002_test2.hcl": stateMigration("dir2", ""),
+			},
+			filenames: []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl"},
+			ok:        true,
+		},
+		{
+			desc: "different workspaces",
+			migrations: map[string]*tfmigrate.MigrationConfig{
+				"20201109000001_test1.hcl": stateMigration("dir1", "foo"),
+				"20201109000002_test2.hcl": stateMigration("dir1", "bar"),
+			},
+			filenames: []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl"},
+			ok:        true,
+		},
+		{
+			desc: "same dir and workspace",
+			migrations: map[string]*tfmigrate.MigrationConfig{
+				"20201109000001_test1.hcl": stateMigration("dir1", ""),
+				"20201109000002_test2.hcl": stateMigration("./dir1", "default"),
+			},
+			filenames: []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl"},
+			ok:        false,
+		},
+		{
+			desc: "multi state",
+			migrations: map[string]*tfmigrate.MigrationConfig{
+				"20201109000001_test1.hcl": stateMigration("dir1", ""),
+				"20201109000002_test2.hcl": multiStateMigration("dir2", "dir1"),
+			},
+			filenames: []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl"},
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			seen := make(map[string]string)
+			var err error
+			for _, filename := range tc.filenames {
+				if err = checkSandboxTargets(seen, filename, tc.migrations[filename]); err != nil {
+					break
+				}
+			}
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestHistoryRunnerApplyParallel(t *testing.T) {
+	cases := []struct {
+		desc       string
+		migrations map[string]string
+		// applied is a list of migrations which must be applied.
+		applied []string
+		// unapplied is a list of migrations which must not be applied.
+		unapplied []string
+		ok        bool
+	}{
+		{
+			desc: "all succeeded",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+				"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	depends_on  = ["20201109000001_test1.hcl"]
+	plan_error  = false
+	apply_error = false
+}
+`,
+				"20201109000003_test3.hcl": `
+migration "mock" "test3" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			applied:   []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl", "20201109000003_test3.hcl"},
+			unapplied: []string{},
+			ok:        true,
+		},
+		{
+			desc: "dependency failed",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = true
+}
+`,
+				"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	depends_on  = ["20201109000001_test1.hcl"]
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			applied:   []string{},
+			unapplied: []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl"},
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, tc.migrations)
+			mockConfig := &mock.Config{
+				Data: `{
+    "version": 1,
+    "records": {}
+}`,
+			}
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage: mockConfig,
+				},
+			}
+			r, err := NewHistoryRunner(context.Background(), "", config, &tfmigrate.MigratorOption{})
+			if err != nil {
+				t.Fatalf("failed to new history runner: %s", err)
+			}
+			r.SetParallelism(4)
+
+			err = r.Apply(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			got, err := history.ParseHistoryFile([]byte(mockConfig.Storage().Data()))
+			if err != nil {
+				t.Fatalf("failed to parse history file: %s", err)
+			}
+			for _, filename := range tc.applied {
+				if !got.Contains(filename) {
+					t.Errorf("expected to be applied: %s", filename)
+				}
+			}
+			for _, filename := range tc.unapplied {
+				if got.Contains(filename) {
+					t.Errorf("expected not to be applied: %s", filename)
+				}
+			}
+		})
+	}
+}
+
+// TestAccHistoryRunnerScenario runs a full plan/apply flow in history mode
+// against a real s3 backend and history storages on localstack and
+// fake-gcs-server.
+func TestAccHistoryRunnerScenario(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+
+	cases := []struct {
+		desc string
+		// newStorage returns a storage config of history for testing.
+		// It returns nil to skip the test.
+		newStorage func(t *testing.T) storage.Config
+	}{
+		{
+			desc: "local",
+			newStorage: func(t *testing.T) storage.Config {
+				return &local.Config{Path: filepath.Join(t.TempDir(), "history.json")}
+			},
+		},
+		{
+			desc: "s3",
+			newStorage: func(t *testing.T) storage.Config {
+				endpoint := tfexec.GetTestAccS3Endpoint()
+				return &s3.Config{
+					Bucket:                    tfexec.TestS3Bucket,
+					Key:                       fmt.Sprintf("%s/%d/history.json", t.Name(), time.Now().UnixNano()),
+					Region:                    tfexec.TestS3Region,
+					Endpoint:                  endpoint,
+					AccessKey:                 tfexec.TestS3AccessKey,
+					SecretKey:                 tfexec.TestS3SecretKey,
+					SkipCredentialsValidation: true,
+					SkipMetadataAPICheck:      true,
+					ForcePathStyle:            true,
+				}
+			},
+		},
+		{
+			desc: "gcs",
+			newStorage: func(t *testing.T) storage.Config {
+				// Never run it against the real GCS.
+				if len(os.Getenv("STORAGE_EMULATOR_HOST")) == 0 {
+					return nil
+				}
+				return &gcs.Config{
+					Bucket: "tfstate-test",
+					Name:   fmt.Sprintf("%s/%d/history.json", t.Name(), time.Now().UnixNano()),
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			storageConfig := tc.newStorage(t)
+			if storageConfig == nil {
+				t.Skip("skip because the storage is not available")
+			}
+
+			backend := tfexec.GetTestAccBackendS3Config(t.Name())
+			source := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+`
+			tf := tfexec.SetupTestAccWithApply(t, "default", backend+source)
+			ctx := context.Background()
+
+			migrationDir := setupMigrationDir(t, map[string]string{})
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					MigrationDir: migrationDir,
+					Storage:      storageConfig,
+				},
+			}
+
+			// Each round adds a migration with the corresponding change of
+			// configuration, and then plans and applies unapplied migrations.
+			rounds := []struct {
+				filename string
+				action   string
+				source   string
+			}{
+				{
+					filename: "20201109000001_mv_foo.hcl",
+					action:   "mv null_resource.foo null_resource.foo2",
+					source: `
+resource "null_resource" "foo2" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+`,
+				},
+				{
+					filename: "20201109000002_mv_bar.hcl",
+					action:   "mv null_resource.bar null_resource.bar2",
+					source: `
+resource "null_resource" "foo2" {}
+resource "null_resource" "bar2" {}
+resource "null_resource" "baz" {}
+`,
+				},
+			}
+			for _, round := range rounds {
+				tfexec.UpdateTestAccSource(t, tf, backend+round.source)
+				migration := fmt.Sprintf(`
+migration "state" "test" {
+  dir     = %q
+  actions = [%q]
+}
+`, tf.Dir(), round.action)
+				if err := os.WriteFile(filepath.Join(migrationDir, round.filename), []byte(migration), 0600); err != nil {
+					t.Fatalf("failed to write migration file: %s", err)
+				}
+
+				r, err := NewHistoryRunner(ctx, "", config, nil)
+				if err != nil {
+					t.Fatalf("failed to new history runner: %s", err)
+				}
+				if err := r.Plan(ctx); err != nil {
+					t.Fatalf("failed to plan %s: %s", round.filename, err)
+				}
+				if applied := testAccAlreadyApplied(t, config, round.filename); applied {
+					t.Fatalf("plan must not record a migration in history: %s", round.filename)
+				}
+
+				r, err = NewHistoryRunner(ctx, "", config, nil)
+				if err != nil {
+					t.Fatalf("failed to new history runner: %s", err)
+				}
+				if err := r.Apply(ctx); err != nil {
+					t.Fatalf("failed to apply %s: %s", round.filename, err)
+				}
+				if applied := testAccAlreadyApplied(t, config, round.filename); !applied {
+					t.Fatalf("apply must record a migration in history: %s", round.filename)
+				}
+			}
+
+			got, err := tf.StateList(ctx, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to run terraform state list: %s", err)
+			}
+			want := []string{
+				"null_resource.bar2",
+				"null_resource.baz",
+				"null_resource.foo2",
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got state: %v, want state: %v", got, want)
+			}
+
+			changed, err := tf.PlanHasChange(ctx, nil)
+			if err != nil {
+				t.Fatalf("failed to run PlanHasChange: %s", err)
+			}
+			if changed {
+				t.Fatalf("expect not to have changes")
+			}
+
+			// Applying again is a no-op because all migrations have been applied.
+			r, err := NewHistoryRunner(ctx, "", config, nil)
+			if err != nil {
+				t.Fatalf("failed to new history runner: %s", err)
+			}
+			if err := r.Apply(ctx); err != nil {
+				t.Fatalf("failed to apply again: %s", err)
+			}
+		})
+	}
+}
+
+// testAccAlreadyApplied returns true if a given migration has been recorded
+// in the history storage.
+func testAccAlreadyApplied(t *testing.T, config *config.TfmigrateConfig, filename string) bool {
+	t.Helper()
+	hc, err := history.NewController(context.Background(), config.MigrationDir, config.History)
+	if err != nil {
+		t.Fatalf("failed to new history controller: %s", err)
+	}
+	return hc.AlreadyApplied(filename)
+}
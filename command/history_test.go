@@ -0,0 +1,361 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestMigrateHistoryFormat(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	v1 := `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        }
+    }
+}`
+	v2 := `{
+    "version": 2,
+    "migrations": {
+        "20201109000001_test1.hcl": {
+            "applied": {
+                "type": "mock",
+                "name": "test1",
+                "timestamp": "2020-11-10T00:00:01Z"
+            }
+        }
+    }
+}`
+
+	cases := []struct {
+		desc        string
+		historyFile string
+		version     int
+		format      string
+		want        string
+		wantFile    string
+		ok          bool
+	}{
+		{
+			desc:        "upgrade",
+			historyFile: v1,
+			version:     2,
+			want:        "history file format migrated from v1 to v2",
+			wantFile:    v2,
+			ok:          true,
+		},
+		{
+			desc:        "downgrade",
+			historyFile: v2,
+			version:     1,
+			want:        "history file format migrated from v2 to v1",
+			wantFile:    v1,
+			ok:          true,
+		},
+		{
+			desc:        "already latest",
+			historyFile: v2,
+			version:     2,
+			want:        "history file is already in v2 format",
+			wantFile:    v2,
+			ok:          true,
+		},
+		{
+			desc:        "convert to yaml",
+			historyFile: v2,
+			version:     2,
+			format:      "yaml",
+			want:        "history file format migrated from v2 to v2 in yaml",
+			wantFile: `migrations:
+  20201109000001_test1.hcl:
+    applied:
+      name: test1
+      timestamp: "2020-11-10T00:00:01Z"
+      type: mock
+version: 2
+`,
+			ok: true,
+		},
+		{
+			desc: "convert from yaml",
+			historyFile: `version: 1
+records:
+  20201109000001_test1.hcl:
+    type: mock
+    name: test1
+    applied_at: "2020-11-10T00:00:01Z"
+`,
+			version:  2,
+			format:   "json",
+			want:     "history file format migrated from v1 to v2 in json",
+			wantFile: v2,
+			ok:       true,
+		},
+		{
+			desc:        "unknown format",
+			historyFile: v2,
+			version:     2,
+			format:      "toml",
+			ok:          false,
+		},
+		{
+			desc:        "no history file",
+			historyFile: "",
+			version:     2,
+			ok:          false,
+		},
+		{
+			desc:        "unknown version",
+			historyFile: v1,
+			version:     99,
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, migrations)
+			storage := &mock.Config{
+				Data:       tc.historyFile,
+				WriteError: false,
+				ReadError:  false,
+			}
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage: storage,
+				},
+			}
+			got, err := migrateHistoryFormat(context.Background(), config, tc.version, tc.format)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				if got != tc.want {
+					t.Errorf("got = %s, want = %s", got, tc.want)
+				}
+				if data := storage.Storage().Data(); data != tc.wantFile {
+					t.Errorf("got file = %s, want = %s", data, tc.wantFile)
+				}
+			}
+		})
+	}
+}
+
+func TestPingHistoryStorage(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *mock.Config
+		ok     bool
+	}{
+		{
+			desc: "healthy",
+			config: &mock.Config{
+				Data:       "",
+				WriteError: false,
+				ReadError:  false,
+			},
+			ok: true,
+		},
+		{
+			desc: "write error",
+			config: &mock.Config{
+				Data:       "",
+				WriteError: true,
+				ReadError:  false,
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &config.TfmigrateConfig{
+				History: &history.Config{
+					Storage: tc.config,
+				},
+			}
+			got, err := pingHistoryStorage(context.Background(), config)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && !strings.HasPrefix(got, "history storage is healthy") {
+				t.Errorf("unexpected output: %s", got)
+			}
+		})
+	}
+}
+
+func TestVerifyHistory(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "state" "test1" {
+	dir     = "dir1"
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+		"mv null_resource.bar null_resource.bar2",
+	]
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "state" "test2" {
+	dir     = "dir1"
+	actions = [
+		"mv null_resource.bar2 null_resource.bar3",
+	]
+}
+`,
+		"20201109000003_test3.hcl": `
+migration "multi_state" "test3" {
+	from_dir = "dir1"
+	to_dir   = "dir2"
+	actions = [
+		"mv null_resource.baz null_resource.baz",
+	]
+}
+`,
+		"20201109000004_test4.hcl": `
+migration "state" "test4" {
+	dir     = "dir1"
+	actions = [
+		"rm null_resource.qux",
+	]
+}
+`,
+	}
+	historyFile := `{
+    "version": 2,
+    "migrations": {
+        "20201109000001_test1.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "test1",
+                "timestamp": "2020-11-10T00:00:01Z"
+            }
+        },
+        "20201109000002_test2.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "test2",
+                "timestamp": "2020-11-10T00:00:02Z"
+            }
+        },
+        "20201109000003_test3.hcl": {
+            "applied": {
+                "type": "multi_state",
+                "name": "test3",
+                "timestamp": "2020-11-10T00:00:03Z"
+            }
+        },
+        "20201109000004_test4.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "test4",
+                "timestamp": "2020-11-10T00:00:04Z",
+                "skipped": true
+            }
+        }
+    }
+}`
+
+	cases := []struct {
+		desc   string
+		states map[string][]string
+		want   []string
+		ok     bool
+	}{
+		{
+			desc: "consistent",
+			states: map[string][]string{
+				"dir1@default": {"null_resource.foo2", "null_resource.bar3", "null_resource.qux"},
+				"dir2@default": {"null_resource.baz"},
+			},
+			want: []string{},
+			ok:   true,
+		},
+		{
+			desc: "reverted",
+			states: map[string][]string{
+				"dir1@default": {"null_resource.foo", "null_resource.bar3", "null_resource.baz"},
+				"dir2@default": {"null_resource.baz"},
+			},
+			want: []string{
+				"20201109000001_test1.hcl: null_resource.foo is expected to be absent in dir1@default, but found",
+				"20201109000001_test1.hcl: null_resource.foo2 is expected to exist in dir1@default, but not found",
+				"20201109000003_test3.hcl: null_resource.baz is expected to be absent in dir1@default, but found",
+			},
+			ok: true,
+		},
+		{
+			desc: "failed to list",
+			states: map[string][]string{
+				"dir1@default": {"null_resource.foo2", "null_resource.bar3"},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, migrations)
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage: &mock.Config{
+						Data: historyFile,
+					},
+				},
+			}
+			listState := func(_ context.Context, e *tfmigrate.StateExpectation) ([]string, error) {
+				addresses, ok := tc.states[e.Dir+"@"+e.Workspace]
+				if !ok {
+					return nil, fmt.Errorf("state not found: %s@%s", e.Dir, e.Workspace)
+				}
+				return addresses, nil
+			}
+			inconsistencies, verified, err := verifyHistory(context.Background(), config, listState)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				got := []string{}
+				for _, i := range inconsistencies {
+					got = append(got, i.String())
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+				if verified != 3 {
+					t.Errorf("got verified = %d, want = 3", verified)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package command
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// InventoryCommand is a command which reports managed resources per directory.
+type InventoryCommand struct {
+	Meta
+	backendConfig []string
+	format        string
+	workspace     string
+	out           string
+	offline       bool
+}
+
+// Inventory is a report of managed resources.
+type Inventory struct {
+	// Directories is a list of inventories per directory.
+	Directories []*DirInventory `json:"directories"`
+}
+
+// DirInventory is a report of managed resources in a directory.
+type DirInventory struct {
+	// Dir is a working directory.
+	Dir string `json:"dir"`
+	// Workspace is a workspace of the state.
+	Workspace string `json:"workspace"`
+	// Total is a total number of resource instances.
+	Total int `json:"total"`
+	// Resources is a list of resource types and their counts.
+	Resources []*ResourceInventory `json:"resources"`
+}
+
+// ResourceInventory is a count of resource instances for a resource type.
+type ResourceInventory struct {
+	// Provider is a source address of provider.
+	// e.g.) registry.terraform.io/hashicorp/aws
+	Provider string `json:"provider"`
+	// Type is a resource type.
+	Type string `json:"type"`
+	// Count is a number of resource instances.
+	Count int `json:"count"`
+}
+
+// Run runs the procedure of this command.
+func (c *InventoryCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	cmdFlags.StringArrayVar(&c.backendConfig, "backend-config", nil, "A backend configuration for remote state")
+	cmdFlags.StringVar(&c.format, "format", "json", "An output format")
+	cmdFlags.StringVar(&c.workspace, "workspace", "", "A terraform workspace")
+	cmdFlags.StringVar(&c.out, "out", "", "Write a report to the given path")
+	cmdFlags.BoolVar(&c.offline, "offline", false, "Fail if any network call other than the backend would be made")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) == 0 {
+		c.UI.Error("The command expects at least 1 argument, but got 0")
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	if c.format != "json" && c.format != "csv" {
+		c.UI.Error(fmt.Sprintf("unknown format: %s", c.format))
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	c.Option.BackendConfig = c.backendConfig
+	if len(c.Option.ExecPath) == 0 {
+		c.Option.ExecPath = c.config.ExecPath
+	}
+	c.Option.ExecMode = c.config.ExecMode
+	c.Option.IsBackendTerraformCloud = c.config.IsBackendTerraformCloud
+	c.Option.Retry = c.config.Retry
+	c.Option.LockOptions = c.config.LockOptions
+	if c.offline {
+		log.Printf("[INFO] [command] offline mode\n")
+		if err := enableOfflineTerraform(c.Option); err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
+	// The option may contains sensitive values such as environment variables.
+	// So logging the option set log level to DEBUG instead of INFO.
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	ctx := context.Background()
+	inventory := &Inventory{
+		Directories: []*DirInventory{},
+	}
+	for _, dir := range cmdFlags.Args() {
+		tf := tfmigrate.NewTerraformCLI(dir, c.Option)
+		d, err := collectDirInventory(ctx, tf, c.workspace, inventoryInitOpts(c.Option))
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		inventory.Directories = append(inventory.Directories, d)
+	}
+
+	var w io.Writer = os.Stdout
+	if len(c.out) != 0 {
+		f, err := os.Create(c.out)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("failed to create a report file: %s", err))
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := writeInventory(w, inventory, c.format); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// inventoryInitOpts returns options of terraform init for the inventory.
+// The backend is initialized with the backend configurations in the same way
+// as a migrator reconfigures the backend with its own backend configurations.
+// In offline mode, providers are installed only from the providers mirror.
+func inventoryInitOpts(o *tfmigrate.MigratorOption) []string {
+	opts := []string{"-input=false", "-no-color"}
+	if len(o.BackendConfig) != 0 {
+		for _, b := range o.BackendConfig {
+			opts = append(opts, fmt.Sprintf("-backend-config=%s", b))
+		}
+		if !o.IsBackendTerraformCloud {
+			opts = append(opts, "-reconfigure")
+		}
+	}
+	if o.Offline {
+		opts = append(opts, "-plugin-dir="+o.ProvidersMirrorDir)
+	}
+	return opts
+}
+
+// collectDirInventory pulls the current state in a working directory and
+// returns an inventory of managed resources.
+// If workspace is empty, it uses the current workspace.
+func collectDirInventory(ctx context.Context, tf tfexec.TerraformCLI, workspace string, initOpts []string) (*DirInventory, error) {
+	log.Printf("[INFO] [inventory@%s] initialize work dir\n", tf.Dir())
+	if err := tf.Init(ctx, initOpts...); err != nil {
+		return nil, err
+	}
+
+	if len(workspace) == 0 {
+		current, err := tf.WorkspaceShow(ctx)
+		if err != nil {
+			return nil, err
+		}
+		workspace = current
+	}
+
+	// The selected workspace is restored after pulling the state, so that
+	// the inventory never changes the workspace of the user's working dir.
+	log.Printf("[INFO] [inventory@%s] get the remote state of workspace %s\n", tf.Dir(), workspace)
+	state, err := tf.StatePullFromWorkspace(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := parseResourceInventory(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state in %s: %s", tf.Dir(), err)
+	}
+
+	total := 0
+	for _, r := range resources {
+		total += r.Count
+	}
+
+	d := &DirInventory{
+		Dir:       tf.Dir(),
+		Workspace: workspace,
+		Total:     total,
+		Resources: resources,
+	}
+	return d, nil
+}
+
+// parseResourceInventory parses a given state and counts managed resource
+// instances per provider and resource type.
+// The result is sorted by provider and type.
+func parseResourceInventory(state *tfexec.State) ([]*ResourceInventory, error) {
+	// An empty state is valid if no resource has been created yet.
+	if len(strings.TrimSpace(string(state.Bytes()))) == 0 {
+		return []*ResourceInventory{}, nil
+	}
+
+	var s struct {
+		Version   int `json:"version"`
+		Resources []struct {
+			Mode      string            `json:"mode"`
+			Type      string            `json:"type"`
+			Provider  string            `json:"provider"`
+			Instances []json.RawMessage `json:"instances"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(state.Bytes(), &s); err != nil {
+		return nil, err
+	}
+	if s.Version != 4 {
+		return nil, fmt.Errorf("unsupported state version: %d", s.Version)
+	}
+
+	counts := make(map[ResourceInventory]int)
+	for _, r := range s.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		key := ResourceInventory{
+			Provider: providerSource(r.Provider),
+			Type:     r.Type,
+		}
+		counts[key] += len(r.Instances)
+	}
+
+	resources := []*ResourceInventory{}
+	for k, v := range counts {
+		resources = append(resources, &ResourceInventory{
+			Provider: k.Provider,
+			Type:     k.Type,
+			Count:    v,
+		})
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Provider != resources[j].Provider {
+			return resources[i].Provider < resources[j].Provider
+		}
+		return resources[i].Type < resources[j].Type
+	})
+
+	return resources, nil
+}
+
+// providerSource extracts a provider source address from a provider
+// configuration address in state.
+// e.g.) provider["registry.terraform.io/hashicorp/aws"].west => registry.terraform.io/hashicorp/aws
+func providerSource(provider string) string {
+	start := strings.Index(provider, `["`)
+	end := strings.Index(provider, `"]`)
+	if start == -1 || end == -1 || end < start {
+		return provider
+	}
+	return provider[start+2 : end]
+}
+
+// writeInventory writes a given inventory in a given format.
+// Valid formats are json and csv.
+func writeInventory(w io.Writer, inventory *Inventory, format string) error {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(inventory, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"dir", "workspace", "provider", "type", "count"}); err != nil {
+			return err
+		}
+		for _, d := range inventory.Directories {
+			for _, r := range d.Resources {
+				record := []string{d.Dir, d.Workspace, r.Provider, r.Type, strconv.Itoa(r.Count)}
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// Help returns long-form help text.
+func (c *InventoryCommand) Help() string {
+	helpText := `
+Usage: tfmigrate inventory [options] DIR...
+
+Inventory pulls the current state in each given directory and reports managed
+resources, that is, resource types, their counts, providers and workspaces.
+It never modifies any state.
+
+Arguments
+  DIR                A working directory for executing terraform command.
+                     Multiple directories are allowed.
+
+Options:
+  --config           A path to tfmigrate config file
+  --backend-config=path
+                     A backend configuration, a path to backend configuration
+                     file or key=value format backend configuraion.
+                     This option is passed to terraform init with -reconfigure
+                     unless is_backend_terraform_cloud is set.
+  --format           An output format. Valid values are as follows:
+                       - json (default)
+                       - csv
+  --workspace        A terraform workspace. Default to the current workspace.
+  --out=path         Write a report to the given path. Default to stdout.
+  --offline          Fail fast if any component would make a network call other
+                     than the backend. The checkpoint service of terraform is
+                     disabled, and providers are installed only from a
+                     pre-populated local filesystem mirror in
+                     TFMIGRATE_PROVIDERS_MIRROR_DIR.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *InventoryCommand) Synopsis() string {
+	return "Report managed resources per directory"
+}
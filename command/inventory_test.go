@@ -0,0 +1,223 @@
+package command
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestParseResourceInventory(t *testing.T) {
+	cases := []struct {
+		desc  string
+		state string
+		want  []*ResourceInventory
+		ok    bool
+	}{
+		{
+			desc: "simple",
+			state: `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [{"index_key": 0}, {"index_key": 1}]
+    },
+    {
+      "module": "module.bar",
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "bar",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"].west",
+      "instances": [{}]
+    },
+    {
+      "mode": "data",
+      "type": "aws_ami",
+      "name": "baz",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [{}]
+    },
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "qux",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [{}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_eip",
+      "name": "quux",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": []
+    }
+  ]
+}`,
+			want: []*ResourceInventory{
+				{Provider: "registry.terraform.io/hashicorp/aws", Type: "aws_eip", Count: 0},
+				{Provider: "registry.terraform.io/hashicorp/aws", Type: "aws_instance", Count: 3},
+				{Provider: "registry.terraform.io/hashicorp/null", Type: "null_resource", Count: 1},
+			},
+			ok: true,
+		},
+		{
+			desc:  "empty state",
+			state: "",
+			want:  []*ResourceInventory{},
+			ok:    true,
+		},
+		{
+			desc:  "unsupported version",
+			state: `{"version": 3}`,
+			want:  nil,
+			ok:    false,
+		},
+		{
+			desc:  "invalid json",
+			state: `{`,
+			want:  nil,
+			ok:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := parseResourceInventory(tfexec.NewState([]byte(tc.state)))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteInventory(t *testing.T) {
+	inventory := &Inventory{
+		Directories: []*DirInventory{
+			{
+				Dir:       "foo",
+				Workspace: "default",
+				Total:     3,
+				Resources: []*ResourceInventory{
+					{Provider: "registry.terraform.io/hashicorp/aws", Type: "aws_instance", Count: 2},
+					{Provider: "registry.terraform.io/hashicorp/null", Type: "null_resource", Count: 1},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		desc   string
+		format string
+		want   string
+		ok     bool
+	}{
+		{
+			desc:   "json",
+			format: "json",
+			want: `{
+  "directories": [
+    {
+      "dir": "foo",
+      "workspace": "default",
+      "total": 3,
+      "resources": [
+        {
+          "provider": "registry.terraform.io/hashicorp/aws",
+          "type": "aws_instance",
+          "count": 2
+        },
+        {
+          "provider": "registry.terraform.io/hashicorp/null",
+          "type": "null_resource",
+          "count": 1
+        }
+      ]
+    }
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc:   "csv",
+			format: "csv",
+			want: `dir,workspace,provider,type,count
+foo,default,registry.terraform.io/hashicorp/aws,aws_instance,2
+foo,default,registry.terraform.io/hashicorp/null,null_resource,1
+`,
+			ok: true,
+		},
+		{
+			desc:   "unknown",
+			format: "yaml",
+			want:   "",
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			err := writeInventory(&b, inventory, tc.format)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", b.String())
+			}
+			if tc.ok && b.String() != tc.want {
+				t.Errorf("got: %s, want: %s", b.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestInventoryInitOpts(t *testing.T) {
+	cases := []struct {
+		desc string
+		o    *tfmigrate.MigratorOption
+		want []string
+	}{
+		{
+			desc: "default",
+			o:    &tfmigrate.MigratorOption{ProvidersMirrorDir: "/tmp/mirror"},
+			want: []string{"-input=false", "-no-color"},
+		},
+		{
+			desc: "offline",
+			o:    &tfmigrate.MigratorOption{ProvidersMirrorDir: "/tmp/mirror", Offline: true},
+			want: []string{"-input=false", "-no-color", "-plugin-dir=/tmp/mirror"},
+		},
+		{
+			desc: "backend config",
+			o:    &tfmigrate.MigratorOption{BackendConfig: []string{"bucket=foo", "key=bar"}},
+			want: []string{"-input=false", "-no-color", "-backend-config=bucket=foo", "-backend-config=key=bar", "-reconfigure"},
+		},
+		{
+			desc: "backend config for terraform cloud",
+			o:    &tfmigrate.MigratorOption{BackendConfig: []string{"organization=foo"}, IsBackendTerraformCloud: true},
+			want: []string{"-input=false", "-no-color", "-backend-config=organization=foo"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := inventoryInitOpts(tc.o)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
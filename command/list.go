@@ -2,26 +2,49 @@ package command
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/minamijoyo/tfmigrate/config"
 	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
 	flag "github.com/spf13/pflag"
 )
 
 // ListCommand is a command which lists migrations.
 type ListCommand struct {
 	Meta
-	status string
+	status     string
+	detail     bool
+	commit     bool
+	json       bool
+	format     string
+	since      string
+	until      string
+	dir        string
+	actionType string
 }
 
 // Run runs the procedure of this command.
 func (c *ListCommand) Run(args []string) int {
 	cmdFlags := flag.NewFlagSet("list", flag.ContinueOnError)
-	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
 	cmdFlags.StringVar(&c.status, "status", "all", "A filter for migration status")
+	cmdFlags.BoolVar(&c.detail, "detail", false, "Show status and results of actions for each migration")
+	cmdFlags.BoolVar(&c.commit, "commit", false, "Show a git commit with which each migration was applied")
+	cmdFlags.BoolVar(&c.json, "json", false, "Output in JSON format")
+	cmdFlags.StringVar(&c.format, "format", "text", "An output format")
+	cmdFlags.StringVar(&c.since, "since", "", "A filter for migrations applied or failed at or after a given time")
+	cmdFlags.StringVar(&c.until, "until", "", "A filter for migrations applied or failed before a given time")
+	cmdFlags.StringVar(&c.dir, "dir", "", "A filter for migrations which touch a given directory")
+	cmdFlags.StringVar(&c.actionType, "action-type", "", "A filter for migrations which contain a given type of action")
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
@@ -33,6 +56,10 @@ func (c *ListCommand) Run(args []string) int {
 		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
 		return 1
 	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
 
 	c.Option = newOption()
@@ -46,9 +73,36 @@ func (c *ListCommand) Run(args []string) int {
 		return 1
 	}
 
+	switch c.format {
+	case "text":
+	case "json":
+		c.json = true
+	default:
+		c.UI.Error(fmt.Sprintf("unknown format: %s. Valid formats are text and json", c.format))
+		return 1
+	}
+
+	opt := listOption{
+		status:     c.status,
+		dir:        c.dir,
+		actionType: c.actionType,
+		detail:     c.detail,
+		commit:     c.commit,
+		json:       c.json,
+	}
+	now := time.Now()
+	if opt.since, err = parseTimeFilter(c.since, now); err != nil {
+		c.UI.Error(fmt.Sprintf("invalid --since: %s", err))
+		return 1
+	}
+	if opt.until, err = parseTimeFilter(c.until, now); err != nil {
+		c.UI.Error(fmt.Sprintf("invalid --until: %s", err))
+		return 1
+	}
+
 	// history mode
 	ctx := context.Background()
-	out, err := listMigrations(ctx, c.config, c.status)
+	out, err := listMigrations(ctx, c.config, opt)
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
@@ -57,42 +111,381 @@ func (c *ListCommand) Run(args []string) int {
 	return 0
 }
 
+// listOption is a set of filters and output options for listing migrations.
+type listOption struct {
+	// status is a filter for migration status.
+	// Valid values are all, unapplied, applied, skipped, failed and unknown.
+	status string
+	// since is a filter for migrations applied or failed at or after it.
+	// It is ignored if zero.
+	since time.Time
+	// until is a filter for migrations applied or failed before it.
+	// It is ignored if zero.
+	until time.Time
+	// dir is a filter for migrations which touch a given directory.
+	// It is ignored if empty.
+	dir string
+	// actionType is a filter for migrations which contain a given type of
+	// action such as mv. It is ignored if empty.
+	actionType string
+	// detail is a flag to show status and results of actions.
+	detail bool
+	// commit is a flag to show a git commit with which each migration was
+	// applied.
+	commit bool
+	// json is a flag to output in JSON format.
+	json bool
+}
+
+// parseTimeFilter parses a value of time filter.
+// Valid formats are a relative duration before now such as 90d and 12h,
+// a date such as 2020-11-09 in UTC, and RFC3339.
+// It returns a zero time for an empty string.
+func parseTimeFilter(s string, now time.Time) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("failed to parse time: %s. Valid formats are a duration such as 90d, a date such as 2020-11-09 and RFC3339", s)
+}
+
+// migrationSummary is a summary of a migration for output.
+type migrationSummary struct {
+	// Filename is a migration file name.
+	Filename string `json:"filename"`
+	// Status is one of applied, skipped, failed, unapplied and unknown.
+	Status string `json:"status"`
+	// Type is a migration type. It is empty for an unapplied migration.
+	Type string `json:"type,omitempty"`
+	// Name is a migration name. It is empty for an unapplied migration.
+	Name string `json:"name,omitempty"`
+	// Timestamp is a timestamp when the migration was applied or failed.
+	// It is nil for an unapplied migration.
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	// Actions is a list of results of actions.
+	Actions []actionSummary `json:"actions,omitempty"`
+	// VCS is version control information of the migration directory.
+	VCS *vcsSummary `json:"vcs,omitempty"`
+}
+
+// vcsSummary is a summary of version control information for output.
+type vcsSummary struct {
+	Commit string `json:"commit"`
+	Branch string `json:"branch,omitempty"`
+	Remote string `json:"remote,omitempty"`
+}
+
+// actionSummary is a summary of an action result for output.
+type actionSummary struct {
+	Action     string     `json:"action"`
+	Status     string     `json:"status"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
 // listMigrations lists migrations.
-func listMigrations(ctx context.Context, config *config.TfmigrateConfig, status string) (string, error) {
+func listMigrations(ctx context.Context, config *config.TfmigrateConfig, opt listOption) (string, error) {
 	hc, err := history.NewController(ctx, config.MigrationDir, config.History)
 	if err != nil {
 		return "", err
 	}
 
 	var migrations []string
-	switch status {
+	switch opt.status {
 	case "all":
+		// Migrations which are recorded in history but not found in the
+		// migration directory are listed with the others in order.
+		migrations = append(migrations, hc.Migrations()...)
+		migrations = append(migrations, hc.UnknownMigrations()...)
+		sort.Strings(migrations)
+
+	case "applied", "skipped", "failed":
 		migrations = hc.Migrations()
 
 	case "unapplied":
 		migrations = hc.UnappliedMigrations()
 
+	case "unknown":
+		migrations = hc.UnknownMigrations()
+
 	default:
-		return "", fmt.Errorf("unknown filter for status: %s", status)
+		return "", fmt.Errorf("unknown filter for status: %s", opt.status)
+	}
+
+	unknown := make(map[string]bool)
+	for _, m := range hc.UnknownMigrations() {
+		unknown[m] = true
 	}
 
-	out := strings.Join(migrations, "\n")
+	summaries := []migrationSummary{}
+	for _, m := range migrations {
+		s := newMigrationSummary(hc, m, unknown[m])
+		ok, err := matchListOption(config, s, opt)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			summaries = append(summaries, s)
+		}
+	}
+
+	if opt.json {
+		b, err := json.MarshalIndent(summaries, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+
+	lines := []string{}
+	for _, s := range summaries {
+		switch {
+		case opt.detail:
+			lines = append(lines, formatMigrationDetail(s)...)
+		case opt.commit:
+			lines = append(lines, formatMigrationCommit(s))
+		default:
+			lines = append(lines, s.Filename)
+		}
+	}
+	out := strings.Join(lines, "\n")
 	return out, nil
 }
 
+// newMigrationSummary returns a summary of a given migration from history.
+func newMigrationSummary(hc *history.Controller, filename string, unknown bool) migrationSummary {
+	s := migrationSummary{
+		Filename: filename,
+		Status:   "unapplied",
+	}
+
+	r, ok := hc.Record(filename)
+	if ok {
+		s.Status = "applied"
+		if r.Skipped {
+			s.Status = "skipped"
+		}
+		if unknown {
+			s.Status = "unknown"
+		}
+	} else if r, ok = hc.Failure(filename); ok {
+		s.Status = "failed"
+	} else {
+		return s
+	}
+
+	timestamp := r.AppliedAt
+	s.Type = r.Type
+	s.Name = r.Name
+	s.Timestamp = &timestamp
+	if r.VCS != nil {
+		s.VCS = &vcsSummary{
+			Commit: r.VCS.Commit,
+			Branch: r.VCS.Branch,
+			Remote: r.VCS.Remote,
+		}
+	}
+	for _, a := range r.Actions {
+		as := actionSummary{
+			Action: a.Action,
+			Status: a.Status,
+			Error:  a.Error,
+		}
+		if !a.StartedAt.IsZero() {
+			startedAt, finishedAt := a.StartedAt, a.FinishedAt
+			as.StartedAt = &startedAt
+			as.FinishedAt = &finishedAt
+		}
+		s.Actions = append(s.Actions, as)
+	}
+	return s
+}
+
+// matchListOption returns true if a given migration matches filters.
+// Filters by directory and action type require parsing the migration file.
+func matchListOption(config *config.TfmigrateConfig, s migrationSummary, opt listOption) (bool, error) {
+	switch opt.status {
+	case "applied", "skipped", "failed":
+		if s.Status != opt.status {
+			return false, nil
+		}
+	}
+
+	if !opt.since.IsZero() || !opt.until.IsZero() {
+		if s.Timestamp == nil {
+			return false, nil
+		}
+		if !opt.since.IsZero() && s.Timestamp.Before(opt.since) {
+			return false, nil
+		}
+		if !opt.until.IsZero() && !s.Timestamp.Before(opt.until) {
+			return false, nil
+		}
+	}
+
+	if len(opt.dir) == 0 && len(opt.actionType) == 0 {
+		return true, nil
+	}
+
+	if s.Status == "unknown" {
+		// The migration file is not found, so we can't tell which directories
+		// and actions it contains.
+		return false, nil
+	}
+
+	mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, s.Filename), config.MigrationFileOption())
+	if err != nil {
+		return false, err
+	}
+	dirs, actions := migrationDirsAndActions(mc)
+
+	if len(opt.dir) != 0 && !containsDir(dirs, opt.dir) {
+		return false, nil
+	}
+
+	if len(opt.actionType) != 0 {
+		found := false
+		for _, a := range actions {
+			if fields := strings.Fields(a); len(fields) > 0 && fields[0] == opt.actionType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// migrationDirsAndActions returns a list of directories touched by a given
+// migration and a list of its actions.
+func migrationDirsAndActions(mc *tfmigrate.MigrationConfig) ([]string, []string) {
+	switch m := mc.Migrator.(type) {
+	case *tfmigrate.StateMigratorConfig:
+		dir := m.Dir
+		if len(dir) == 0 {
+			dir = "."
+		}
+		return []string{dir}, m.Actions
+
+	case *tfmigrate.MultiStateMigratorConfig:
+		return append([]string{m.FromDir}, m.ToDirs()...), m.Actions
+
+	default:
+		return nil, nil
+	}
+}
+
+// containsDir returns true if a given list of directories contains a given
+// directory. Paths are compared after cleaning.
+func containsDir(dirs []string, dir string) bool {
+	for _, d := range dirs {
+		if filepath.Clean(d) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatMigrationCommit returns a line which describes a git commit with
+// which a given migration was applied or failed.
+// e.g.) 20201109000001_test1.hcl 6f1e0b2... (main) https://github.com/foo/bar.git
+func formatMigrationCommit(s migrationSummary) string {
+	if s.VCS == nil {
+		return s.Filename
+	}
+
+	parts := []string{s.Filename, s.VCS.Commit}
+	if len(s.VCS.Branch) != 0 {
+		parts = append(parts, "("+s.VCS.Branch+")")
+	}
+	if len(s.VCS.Remote) != 0 {
+		parts = append(parts, s.VCS.Remote)
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatMigrationDetail returns lines which describe status of a given
+// migration and results of its actions.
+// If the migration has failed, the actions show how far it got.
+func formatMigrationDetail(s migrationSummary) []string {
+	if s.Timestamp == nil {
+		return []string{fmt.Sprintf("%s (%s)", s.Filename, s.Status)}
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s (%s at %s)", s.Filename, s.Status, s.Timestamp.UTC().Format(time.RFC3339)),
+	}
+	for _, a := range s.Actions {
+		line := fmt.Sprintf("  - [%s] %s", a.Status, a.Action)
+		if a.StartedAt != nil {
+			line += fmt.Sprintf(" (%s)", a.FinishedAt.Sub(*a.StartedAt).Round(time.Millisecond))
+		}
+		if len(a.Error) != 0 {
+			line += ": " + a.Error
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 // Help returns long-form help text.
 func (c *ListCommand) Help() string {
 	helpText := `
-Usage: tfmigrate list
+Usage: tfmigrate list [options]
 
 List migrations.
 
 Options:
   --config           A path to tfmigrate config file
+  --var=NAME=VALUE   Set a variable which migration files can reference as var.NAME.
+  --var-file=path    A path to a variable file such as env = "prod" which sets
+                     variables for migration files. --var takes precedence over it.
   --status           A filter for migration status
                      Valid values are as follows:
                        - all (default)
                        - unapplied
+                       - applied
+                       - skipped (applied, but skipped by skip_if)
+                       - failed (unapplied, but the last attempt failed)
+                       - unknown (applied, but the migration file is not
+                         found in the migration directory)
+  --since            A filter for migrations applied or failed at or after
+                     a given time. Valid formats are a duration before now
+                     such as 90d and 12h, a date such as 2020-11-09 in UTC,
+                     and RFC3339.
+  --until            A filter for migrations applied or failed before a given
+                     time in the same format as --since.
+  --dir              A filter for migrations which touch a given directory
+                     A multi_state migration touches from_dir and all to_dirs.
+  --action-type      A filter for migrations which contain a given type of
+                     action such as mv, rm and import.
+  --detail           Show status and results of actions for each migration
+                     A failed migration shows how far it got
+  --commit           Show a git commit, branch and remote of the migration
+                     directory with which each migration was applied or failed
+  --format           An output format
+                     Valid values are text (default) and json
+  --json             Output in JSON format with details
+                     A shorthand for --format=json
 `
 	return strings.TrimSpace(helpText)
 }
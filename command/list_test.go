@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/minamijoyo/tfmigrate/config"
 	"github.com/minamijoyo/tfmigrate/history"
@@ -36,6 +37,58 @@ migration "mock" "test4" {
 }
 `,
 	}
+	detailHistoryFile := `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "state",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z",
+            "actions": [
+                {
+                    "action": "mv null_resource.foo null_resource.foo2",
+                    "status": "succeeded",
+                    "started_at": "2020-11-10T00:00:00Z",
+                    "finished_at": "2020-11-10T00:00:00.5Z"
+                }
+            ]
+        },
+        "20201109000002_test2.hcl": {
+            "type": "mock",
+            "name": "test2",
+            "applied_at": "2020-11-10T00:00:02Z"
+        }
+    },
+    "failures": {
+        "20201109000003_test3.hcl": {
+            "type": "state",
+            "name": "test3",
+            "applied_at": "2020-11-10T00:00:03Z",
+            "actions": [
+                {
+                    "action": "mv null_resource.bar null_resource.bar2",
+                    "status": "succeeded",
+                    "started_at": "2020-11-10T00:00:01Z",
+                    "finished_at": "2020-11-10T00:00:02Z"
+                },
+                {
+                    "action": "rm null_resource.baz",
+                    "status": "failed",
+                    "started_at": "2020-11-10T00:00:02Z",
+                    "finished_at": "2020-11-10T00:00:03Z",
+                    "error": "exit status 1"
+                },
+                {
+                    "action": "rm null_resource.qux",
+                    "status": "skipped",
+                    "started_at": "0001-01-01T00:00:00Z",
+                    "finished_at": "0001-01-01T00:00:00Z"
+                }
+            ]
+        }
+    }
+}`
+
 	historyFile := `{
     "version": 1,
     "records": {
@@ -55,6 +108,7 @@ migration "mock" "test4" {
 	cases := []struct {
 		desc        string
 		status      string
+		detail      bool
 		migrations  map[string]string
 		historyFile string
 		want        string
@@ -80,6 +134,35 @@ migration "mock" "test4" {
 20201109000004_test4.hcl`,
 			ok: true,
 		},
+		{
+			desc:        "all with detail",
+			status:      "all",
+			detail:      true,
+			migrations:  migrations,
+			historyFile: detailHistoryFile,
+			want: `20201109000001_test1.hcl (applied at 2020-11-10T00:00:01Z)
+  - [succeeded] mv null_resource.foo null_resource.foo2 (500ms)
+20201109000002_test2.hcl (applied at 2020-11-10T00:00:02Z)
+20201109000003_test3.hcl (failed at 2020-11-10T00:00:03Z)
+  - [succeeded] mv null_resource.bar null_resource.bar2 (1s)
+  - [failed] rm null_resource.baz (1s): exit status 1
+  - [skipped] rm null_resource.qux
+20201109000004_test4.hcl (unapplied)`,
+			ok: true,
+		},
+		{
+			desc:        "unapplied with detail",
+			status:      "unapplied",
+			detail:      true,
+			migrations:  migrations,
+			historyFile: detailHistoryFile,
+			want: `20201109000003_test3.hcl (failed at 2020-11-10T00:00:03Z)
+  - [succeeded] mv null_resource.bar null_resource.bar2 (1s)
+  - [failed] rm null_resource.baz (1s): exit status 1
+  - [skipped] rm null_resource.qux
+20201109000004_test4.hcl (unapplied)`,
+			ok: true,
+		},
 		{
 			desc:        "unknown status",
 			status:      "foo",
@@ -104,7 +187,274 @@ migration "mock" "test4" {
 					Storage: storage,
 				},
 			}
-			got, err := listMigrations(context.Background(), config, tc.status)
+			got, err := listMigrations(context.Background(), config, listOption{status: tc.status, detail: tc.detail})
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if got != tc.want {
+				t.Errorf("got = %#v, want = %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestListMigrationsFilter(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "state" "test1" {
+	dir     = "network"
+	actions = [
+		"mv aws_vpc.foo module.network.aws_vpc.foo",
+	]
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "multi_state" "test2" {
+	from_dir = "network"
+	to_dir   = "./app"
+	actions  = [
+		"mv aws_instance.foo aws_instance.foo",
+	]
+}
+`,
+		"20201109000003_test3.hcl": `
+migration "state" "test3" {
+	dir     = "app"
+	actions = [
+		"rm aws_instance.bar",
+	]
+}
+`,
+		"20201109000004_test4.hcl": `
+migration "state" "test4" {
+	dir     = "app"
+	actions = [
+		"import aws_instance.baz i-1234567890",
+	]
+}
+`,
+		"20201109000005_test5.hcl": `
+migration "state" "test5" {
+	dir     = "staging"
+	skip_if = true
+	actions = [
+		"rm aws_instance.qux",
+	]
+}
+`,
+	}
+	historyFile := `{
+    "version": 2,
+    "migrations": {
+        "20201109000000_test0.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "test0",
+                "timestamp": "2020-11-09T00:00:00Z"
+            }
+        },
+        "20201109000001_test1.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "test1",
+                "timestamp": "2020-11-10T00:00:01Z"
+            }
+        },
+        "20201109000002_test2.hcl": {
+            "applied": {
+                "type": "multi_state",
+                "name": "test2",
+                "timestamp": "2020-12-10T00:00:02Z",
+                "vcs": {
+                    "commit": "6f1e0b2c9d8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c",
+                    "branch": "main",
+                    "remote": "https://example.com/foo/bar.git"
+                }
+            }
+        },
+        "20201109000003_test3.hcl": {
+            "last_failure": {
+                "type": "state",
+                "name": "test3",
+                "timestamp": "2020-12-11T00:00:03Z",
+                "actions": [
+                    {
+                        "action": "rm aws_instance.bar",
+                        "status": "failed",
+                        "started_at": "2020-12-11T00:00:02Z",
+                        "finished_at": "2020-12-11T00:00:03Z",
+                        "error": "exit status 1"
+                    }
+                ]
+            }
+        },
+        "20201109000005_test5.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "test5",
+                "timestamp": "2020-11-10T00:00:05Z",
+                "skipped": true
+            }
+        }
+    }
+}`
+
+	cases := []struct {
+		desc string
+		opt  listOption
+		want string
+		ok   bool
+	}{
+		{
+			desc: "applied",
+			opt:  listOption{status: "applied"},
+			want: `20201109000001_test1.hcl
+20201109000002_test2.hcl`,
+			ok: true,
+		},
+		{
+			desc: "failed",
+			opt:  listOption{status: "failed"},
+			want: `20201109000003_test3.hcl`,
+			ok:   true,
+		},
+		{
+			desc: "skipped",
+			opt:  listOption{status: "skipped"},
+			want: `20201109000005_test5.hcl`,
+			ok:   true,
+		},
+		{
+			desc: "unknown",
+			opt:  listOption{status: "unknown"},
+			want: `20201109000000_test0.hcl`,
+			ok:   true,
+		},
+		{
+			desc: "all with unknown",
+			opt:  listOption{status: "all", detail: true},
+			want: `20201109000000_test0.hcl (unknown at 2020-11-09T00:00:00Z)
+20201109000001_test1.hcl (applied at 2020-11-10T00:00:01Z)
+20201109000002_test2.hcl (applied at 2020-12-10T00:00:02Z)
+20201109000003_test3.hcl (failed at 2020-12-11T00:00:03Z)
+  - [failed] rm aws_instance.bar (1s): exit status 1
+20201109000004_test4.hcl (unapplied)
+20201109000005_test5.hcl (skipped at 2020-11-10T00:00:05Z)`,
+			ok: true,
+		},
+		{
+			desc: "since",
+			opt:  listOption{status: "all", since: time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)},
+			want: `20201109000002_test2.hcl
+20201109000003_test3.hcl`,
+			ok: true,
+		},
+		{
+			desc: "since and until",
+			opt: listOption{
+				status: "all",
+				since:  time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+				until:  time.Date(2020, 12, 11, 0, 0, 0, 0, time.UTC),
+			},
+			want: `20201109000002_test2.hcl`,
+			ok:   true,
+		},
+		{
+			desc: "dir",
+			opt:  listOption{status: "all", dir: "app"},
+			want: `20201109000002_test2.hcl
+20201109000003_test3.hcl
+20201109000004_test4.hcl`,
+			ok: true,
+		},
+		{
+			desc: "dir and action type",
+			opt:  listOption{status: "all", dir: "network/", actionType: "mv"},
+			want: `20201109000001_test1.hcl
+20201109000002_test2.hcl`,
+			ok: true,
+		},
+		{
+			desc: "unapplied and action type",
+			opt:  listOption{status: "unapplied", actionType: "import"},
+			want: `20201109000004_test4.hcl`,
+			ok:   true,
+		},
+		{
+			desc: "json",
+			opt:  listOption{status: "failed", json: true},
+			want: `[
+  {
+    "filename": "20201109000003_test3.hcl",
+    "status": "failed",
+    "type": "state",
+    "name": "test3",
+    "timestamp": "2020-12-11T00:00:03Z",
+    "actions": [
+      {
+        "action": "rm aws_instance.bar",
+        "status": "failed",
+        "started_at": "2020-12-11T00:00:02Z",
+        "finished_at": "2020-12-11T00:00:03Z",
+        "error": "exit status 1"
+      }
+    ]
+  }
+]`,
+			ok: true,
+		},
+		{
+			desc: "commit",
+			opt:  listOption{status: "applied", commit: true},
+			want: `20201109000001_test1.hcl
+20201109000002_test2.hcl 6f1e0b2c9d8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c (main) https://example.com/foo/bar.git`,
+			ok: true,
+		},
+		{
+			desc: "json with vcs",
+			opt:  listOption{status: "applied", dir: "app", json: true},
+			want: `[
+  {
+    "filename": "20201109000002_test2.hcl",
+    "status": "applied",
+    "type": "multi_state",
+    "name": "test2",
+    "timestamp": "2020-12-10T00:00:02Z",
+    "vcs": {
+      "commit": "6f1e0b2c9d8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c",
+      "branch": "main",
+      "remote": "https://example.com/foo/bar.git"
+    }
+  }
+]`,
+			ok: true,
+		},
+		{
+			desc: "json with no match",
+			opt:  listOption{status: "all", dir: "foo", json: true},
+			want: `[]`,
+			ok:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, migrations)
+			storage := &mock.Config{
+				Data:       historyFile,
+				WriteError: false,
+				ReadError:  false,
+			}
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage: storage,
+				},
+			}
+			got, err := listMigrations(context.Background(), config, tc.opt)
 			if tc.ok && err != nil {
 				t.Fatalf("unexpected err: %s", err)
 			}
@@ -117,3 +467,65 @@ migration "mock" "test4" {
 		})
 	}
 }
+
+func TestParseTimeFilter(t *testing.T) {
+	now := time.Date(2020, 12, 31, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		desc string
+		s    string
+		want time.Time
+		ok   bool
+	}{
+		{
+			desc: "empty",
+			s:    "",
+			want: time.Time{},
+			ok:   true,
+		},
+		{
+			desc: "days",
+			s:    "90d",
+			want: time.Date(2020, 10, 2, 12, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			desc: "hours",
+			s:    "12h",
+			want: time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			desc: "date",
+			s:    "2020-11-09",
+			want: time.Date(2020, 11, 9, 0, 0, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			desc: "rfc3339",
+			s:    "2020-11-09T01:02:03Z",
+			want: time.Date(2020, 11, 9, 1, 2, 3, 0, time.UTC),
+			ok:   true,
+		},
+		{
+			desc: "invalid",
+			s:    "yesterday",
+			want: time.Time{},
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := parseTimeFilter(tc.s, now)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", got)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("got = %s, want = %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// MatrixCommand is a command which plans a migration with multiple terraform
+// or tofu binaries and reports differences of their behavior.
+type MatrixCommand struct {
+	Meta
+	backendConfig []string
+	execPaths     []string
+	json          bool
+}
+
+// Run runs the procedure of this command.
+func (c *MatrixCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("matrix", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	cmdFlags.StringArrayVar(&c.backendConfig, "backend-config", nil, "A backend configuration for remote state")
+	cmdFlags.StringArrayVar(&c.execPaths, "exec-path", nil, "An exec path of terraform or tofu binary to be compared")
+	cmdFlags.BoolVar(&c.json, "json", false, "Output in JSON format")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+	migrationFile := cmdFlags.Arg(0)
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	execPaths := c.execPaths
+	if len(execPaths) == 0 {
+		execPaths = c.config.MatrixExecPaths
+	}
+	if len(execPaths) == 0 {
+		c.UI.Error("no exec paths to compare. Set matrix_exec_paths in the config file or --exec-path")
+		return 1
+	}
+
+	report, err := runMatrix(context.Background(), c.config, migrationFile, execPaths, c.backendConfig)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.json {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		c.UI.Output(string(b))
+	} else {
+		c.UI.Output(formatMatrixMarkdown(report))
+	}
+
+	if report.hasProblems() {
+		return 2
+	}
+	return 0
+}
+
+// matrixReport is a report of a migration planned with multiple binaries.
+type matrixReport struct {
+	// Filename is a migration file name.
+	Filename string `json:"filename"`
+	// Results is a list of results for each binary.
+	// The first one is the baseline which the others are compared with.
+	Results []*matrixResult `json:"results"`
+}
+
+// matrixResult is a result of plan with a binary.
+type matrixResult struct {
+	// ExecPath is an exec path of the binary.
+	ExecPath string `json:"exec_path"`
+	// Version is a type and version of the binary such as `terraform 1.5.7`.
+	// It is empty if the version cannot be detected.
+	Version string `json:"version,omitempty"`
+	// Status is either succeeded or failed.
+	Status string `json:"status"`
+	// Error is an error message if the plan failed.
+	Error string `json:"error,omitempty"`
+	// Actions is a list of results of actions.
+	Actions []actionSummary `json:"actions,omitempty"`
+	// States is a list of new states computed by the migration.
+	States []matrixState `json:"states,omitempty"`
+	// Differences is a list of differences from the baseline.
+	Differences []string `json:"differences"`
+}
+
+// matrixState is a new state computed by a migration.
+type matrixState struct {
+	// Target is a state as "<dir>@<workspace>".
+	Target string `json:"target"`
+	// Addresses is a sorted list of addresses in the new state.
+	Addresses []string `json:"addresses"`
+}
+
+// hasProblems returns true if any plan failed or behaved differently from
+// the baseline.
+func (r *matrixReport) hasProblems() bool {
+	for _, res := range r.Results {
+		if res.Status == tfmigrate.ActionStatusFailed || len(res.Differences) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runMatrix plans a given migration file with each of given exec paths and
+// compares the results with the first one.
+// Plans run in read-only mode one by one, because they share working
+// directories. Note that history is not checked, so that an applied
+// migration can also be planned to certify an upgrade.
+func runMatrix(ctx context.Context, config *config.TfmigrateConfig, filename string, execPaths []string, backendConfig []string) (*matrixReport, error) {
+	report := &matrixReport{
+		Filename: filename,
+		Results:  []*matrixResult{},
+	}
+
+	for _, execPath := range execPaths {
+		log.Printf("[INFO] [command] plan %s with %s\n", filename, execPath)
+		r := &matrixResult{
+			ExecPath:    execPath,
+			Status:      tfmigrate.ActionStatusSucceeded,
+			Differences: []string{},
+		}
+		report.Results = append(report.Results, r)
+
+		tf := tfexec.NewTerraformCLI(tfexec.NewExecutor(".", os.Environ()))
+		tf.SetExecPath(execPath)
+		execType, v, err := tf.Version(ctx)
+		if err != nil {
+			r.Status = tfmigrate.ActionStatusFailed
+			r.Error = fmt.Sprintf("failed to detect version: %s", err)
+			continue
+		}
+		r.Version = execType + " " + v.String()
+
+		option := newOption()
+		option.ExecPath = execPath
+		option.BackendConfig = backendConfig
+		option.ReadOnly = true
+		option.ShowDiff = true
+		fr, err := NewFileRunner(filename, config, option)
+		if err != nil {
+			return nil, err
+		}
+		if fr.Skipped() {
+			return nil, fmt.Errorf("the migration is skipped by skip_if, so there is nothing to compare: %s", filename)
+		}
+
+		if err := fr.Plan(ctx); err != nil {
+			r.Status = tfmigrate.ActionStatusFailed
+			r.Error = err.Error()
+		}
+		for _, a := range fr.ActionResults() {
+			r.Actions = append(r.Actions, actionSummary{
+				Action: a.Action,
+				Status: a.Status,
+				Error:  a.Error,
+			})
+		}
+		for _, d := range fr.StateDiffs() {
+			r.States = append(r.States, matrixState{
+				Target:    d.Dir + "@" + d.Workspace,
+				Addresses: sortedCopy(d.After),
+			})
+		}
+	}
+
+	for _, r := range report.Results[1:] {
+		r.Differences = compareMatrixResults(report.Results[0], r)
+	}
+	return report, nil
+}
+
+// compareMatrixResults returns a list of differences of a given result from
+// the baseline. Error messages are not compared, because they naturally vary
+// across versions.
+func compareMatrixResults(base *matrixResult, r *matrixResult) []string {
+	diffs := []string{}
+	if base.Status != r.Status {
+		diffs = append(diffs, fmt.Sprintf("plan %s, but %s in the baseline", r.Status, base.Status))
+	}
+
+	if len(base.Actions) != len(r.Actions) {
+		diffs = append(diffs, fmt.Sprintf("%d actions run, but %d in the baseline", len(r.Actions), len(base.Actions)))
+	}
+	for i := 0; i < len(base.Actions) && i < len(r.Actions); i++ {
+		if base.Actions[i].Status != r.Actions[i].Status {
+			diffs = append(diffs, fmt.Sprintf("action %s %s, but %s in the baseline", r.Actions[i].Action, r.Actions[i].Status, base.Actions[i].Status))
+		}
+	}
+
+	baseStates := make(map[string][]string)
+	for _, s := range base.States {
+		baseStates[s.Target] = s.Addresses
+	}
+	states := make(map[string][]string)
+	for _, s := range r.States {
+		states[s.Target] = s.Addresses
+	}
+	targets := []string{}
+	for t := range baseStates {
+		targets = append(targets, t)
+	}
+	for t := range states {
+		if _, ok := baseStates[t]; !ok {
+			targets = append(targets, t)
+		}
+	}
+	sort.Strings(targets)
+
+	for _, t := range targets {
+		before, inBase := baseStates[t]
+		after, ok := states[t]
+		switch {
+		case !inBase:
+			diffs = append(diffs, fmt.Sprintf("new state of %s computed, but not in the baseline", t))
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("new state of %s not computed, but computed in the baseline", t))
+		default:
+			for _, l := range diffSortedLines(before, after) {
+				switch l.op {
+				case '-':
+					diffs = append(diffs, fmt.Sprintf("%s missing in new state of %s", l.text, t))
+				case '+':
+					diffs = append(diffs, fmt.Sprintf("%s added to new state of %s", l.text, t))
+				}
+			}
+		}
+	}
+
+	return diffs
+}
+
+// formatMatrixMarkdown returns a report in markdown.
+func formatMatrixMarkdown(r *matrixReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## tfmigrate matrix of %s\n\n", r.Filename)
+
+	b.WriteString("| Exec path | Version | Plan | Differences |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for i, res := range r.Results {
+		version := res.Version
+		if len(version) == 0 {
+			version = "-"
+		}
+		diffs := fmt.Sprintf("%d", len(res.Differences))
+		if i == 0 {
+			diffs = "(baseline)"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", res.ExecPath, version, res.Status, diffs)
+	}
+
+	for i, res := range r.Results {
+		if len(res.Differences) == 0 && len(res.Error) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n", res.ExecPath)
+		if len(res.Differences) > 0 {
+			fmt.Fprintf(&b, "\nDifferences from %s:\n\n", r.Results[0].ExecPath)
+			for _, d := range res.Differences {
+				fmt.Fprintf(&b, "- %s\n", d)
+			}
+		}
+		if len(res.Error) > 0 {
+			fmt.Fprintf(&b, "\n```\n%s\n```\n", res.Error)
+		}
+		if i == 0 {
+			b.WriteString("\nThe baseline failed, so the other results are compared with a failed plan.\n")
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Help returns long-form help text.
+func (c *MatrixCommand) Help() string {
+	helpText := `
+Usage: tfmigrate matrix [options] PATH
+
+Matrix plans a migration with each of multiple terraform or tofu binaries, and
+reports differences of their behavior from the first one, which is the
+baseline. It's intended to certify an upgrade of terraform across working
+directories with a representative migration before rolling it out.
+
+Binaries are listed in matrix_exec_paths of the config file, or with the
+--exec-path option, which takes precedence. Plans run in read-only mode one
+by one, and history is not checked, so that an applied migration can also be
+used. Results are compared by the plan status, results of actions and
+addresses in new states.
+
+Arguments:
+  PATH                     A path of migration file
+
+Options:
+  --config                 A path to tfmigrate config file
+  --backend-config=path    A backend configuration, a path to backend configuration file or
+                           key=value format backend configuraion.
+                           This option is passed to terraform init when switching backend to remote.
+  --exec-path=path         An exec path of terraform or tofu binary to be compared, such as
+                           terraform1.5. This option can be specified multiple times.
+  --json                   Output in JSON format
+
+Exit status:
+  0                        All plans succeeded without differences.
+  1                        An error occurred.
+  2                        A plan failed or behaved differently from the baseline.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *MatrixCommand) Synopsis() string {
+	return "Compare a plan across terraform versions"
+}
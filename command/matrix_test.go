@@ -0,0 +1,188 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// setupFakeBinary is a test helper for setting up a fake terraform binary
+// which prints a given output of terraform version.
+// It returns a path of the binary.
+func setupFakeBinary(t *testing.T, version string) string {
+	path := filepath.Join(t.TempDir(), "terraform")
+	script := "#!/bin/sh\necho '" + version + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0700); err != nil {
+		t.Fatalf("failed to write fake binary: %s", err)
+	}
+	return path
+}
+
+func TestRunMatrix(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	migrationDir := setupMigrationDir(t, migrations)
+	config := &config.TfmigrateConfig{
+		MigrationDir: migrationDir,
+	}
+	tf15 := setupFakeBinary(t, "Terraform v1.5.7")
+	tofu := setupFakeBinary(t, "OpenTofu v1.8.0")
+	broken := setupFakeBinary(t, "unknown")
+
+	got, err := runMatrix(context.Background(), config, "20201109000001_test1.hcl", []string{tf15, tofu, broken}, nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	if len(got.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(got.Results))
+	}
+	wantVersions := []string{"terraform 1.5.7", "opentofu 1.8.0", ""}
+	wantStatuses := []string{tfmigrate.ActionStatusSucceeded, tfmigrate.ActionStatusSucceeded, tfmigrate.ActionStatusFailed}
+	for i, r := range got.Results {
+		if r.Version != wantVersions[i] || r.Status != wantStatuses[i] {
+			t.Errorf("got result[%d] = (%q, %q), want (%q, %q)", i, r.Version, r.Status, wantVersions[i], wantStatuses[i])
+		}
+	}
+	if len(got.Results[1].Differences) != 0 {
+		t.Errorf("unexpected differences: %#v", got.Results[1].Differences)
+	}
+	if !reflect.DeepEqual(got.Results[2].Differences, []string{"plan failed, but succeeded in the baseline"}) {
+		t.Errorf("unexpected differences: %#v", got.Results[2].Differences)
+	}
+	if !got.hasProblems() {
+		t.Error("expected to have problems, but no problems")
+	}
+}
+
+func TestCompareMatrixResults(t *testing.T) {
+	base := &matrixResult{
+		Status: tfmigrate.ActionStatusSucceeded,
+		Actions: []actionSummary{
+			{Action: "mv null_resource.foo null_resource.foo2", Status: tfmigrate.ActionStatusSucceeded},
+			{Action: "rm null_resource.bar", Status: tfmigrate.ActionStatusSucceeded},
+		},
+		States: []matrixState{
+			{Target: "dir1@default", Addresses: []string{"null_resource.baz", "null_resource.foo2"}},
+		},
+	}
+
+	cases := []struct {
+		desc string
+		r    *matrixResult
+		want []string
+	}{
+		{
+			desc: "same",
+			r: &matrixResult{
+				Status: tfmigrate.ActionStatusSucceeded,
+				Error:  "ignored",
+				Actions: []actionSummary{
+					{Action: "mv null_resource.foo null_resource.foo2", Status: tfmigrate.ActionStatusSucceeded},
+					{Action: "rm null_resource.bar", Status: tfmigrate.ActionStatusSucceeded},
+				},
+				States: []matrixState{
+					{Target: "dir1@default", Addresses: []string{"null_resource.baz", "null_resource.foo2"}},
+				},
+			},
+			want: []string{},
+		},
+		{
+			desc: "failed action",
+			r: &matrixResult{
+				Status: tfmigrate.ActionStatusFailed,
+				Actions: []actionSummary{
+					{Action: "mv null_resource.foo null_resource.foo2", Status: tfmigrate.ActionStatusFailed},
+				},
+			},
+			want: []string{
+				"plan failed, but succeeded in the baseline",
+				"1 actions run, but 2 in the baseline",
+				"action mv null_resource.foo null_resource.foo2 failed, but succeeded in the baseline",
+				"new state of dir1@default not computed, but computed in the baseline",
+			},
+		},
+		{
+			desc: "different addresses",
+			r: &matrixResult{
+				Status: tfmigrate.ActionStatusSucceeded,
+				Actions: []actionSummary{
+					{Action: "mv null_resource.foo null_resource.foo2", Status: tfmigrate.ActionStatusSucceeded},
+					{Action: "rm null_resource.bar", Status: tfmigrate.ActionStatusSucceeded},
+				},
+				States: []matrixState{
+					{Target: "dir1@default", Addresses: []string{"null_resource.bar", "null_resource.foo2"}},
+					{Target: "dir2@default", Addresses: []string{}},
+				},
+			},
+			want: []string{
+				"null_resource.bar added to new state of dir1@default",
+				"null_resource.baz missing in new state of dir1@default",
+				"new state of dir2@default computed, but not in the baseline",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := compareMatrixResults(base, tc.r)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatMatrixMarkdown(t *testing.T) {
+	report := &matrixReport{
+		Filename: "20201109000001_test1.hcl",
+		Results: []*matrixResult{
+			{
+				ExecPath:    "terraform1.5",
+				Version:     "terraform 1.5.7",
+				Status:      tfmigrate.ActionStatusSucceeded,
+				Differences: []string{},
+			},
+			{
+				ExecPath:    "tofu",
+				Status:      tfmigrate.ActionStatusFailed,
+				Error:       "failed to detect version",
+				Differences: []string{"plan failed, but succeeded in the baseline"},
+			},
+		},
+	}
+
+	got := formatMatrixMarkdown(report)
+	want := strings.TrimSpace("" +
+		"## tfmigrate matrix of 20201109000001_test1.hcl\n" +
+		"\n" +
+		"| Exec path | Version | Plan | Differences |\n" +
+		"|---|---|---|---|\n" +
+		"| `terraform1.5` | terraform 1.5.7 | succeeded | (baseline) |\n" +
+		"| `tofu` | - | failed | 1 |\n" +
+		"\n" +
+		"### tofu\n" +
+		"\n" +
+		"Differences from terraform1.5:\n" +
+		"\n" +
+		"- plan failed, but succeeded in the baseline\n" +
+		"\n" +
+		"```\n" +
+		"failed to detect version\n" +
+		"```\n")
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
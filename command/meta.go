@@ -1,12 +1,16 @@
 package command
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/minamijoyo/tfmigrate/config"
 	"github.com/minamijoyo/tfmigrate/tfmigrate"
 	"github.com/mitchellh/cli"
+	flag "github.com/spf13/pflag"
 )
 
 // a default config file path
@@ -23,26 +27,127 @@ type Meta struct {
 	// a global configuration for tfmigrate.
 	config *config.TfmigrateConfig
 
+	// A list of variables for migration files in the format of NAME=VALUE.
+	vars []string
+
+	// A list of paths to variable files for migration files.
+	varFiles []string
+
 	// Option customizes a behavior of Migrator.
 	// It is used for shared settings across Migrator instances.
 	Option *tfmigrate.MigratorOption
 }
 
+// newConfig loads a config file and overrides settings with environment
+// variables. If no config file is found, it returns a config built only from
+// environment variables.
 func newConfig(filename string) (*config.TfmigrateConfig, error) {
+	var c *config.TfmigrateConfig
+	var err error
+	filename = configFilePath(filename)
+	if len(filename) == 0 {
+		c, err = config.NewConfigFromEnv(os.Getenv)
+	} else {
+		log.Printf("[DEBUG] [command] load configuration file: %s\n", filename)
+		c, err = config.LoadConfigurationFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.ApplyVariablesEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// addVariableFlags registers flags which set variables for migration files.
+// They are shared by all commands which load migration files.
+func (m *Meta) addVariableFlags(cmdFlags *flag.FlagSet) {
+	cmdFlags.StringArrayVar(&m.vars, "var", nil, "A variable for migration files in the format of NAME=VALUE")
+	cmdFlags.StringArrayVar(&m.varFiles, "var-file", nil, "A path to a variable file for migration files")
+}
+
+// setVariables sets variables given by flags to the config. The --var flag
+// takes precedence over the --var-file flag.
+func (m *Meta) setVariables() error {
+	if err := m.config.SetVariablesFromFiles(m.varFiles); err != nil {
+		return err
+	}
+	return m.config.SetVariables(m.vars)
+}
+
+// configFilePath resolves a path of config file. The precedence is as follows:
+// the --config flag > the TFMIGRATE_CONFIG environment variable > .tfmigrate.hcl
+// It returns an empty string if the default config file doesn't exist.
+func configFilePath(filename string) string {
+	if len(filename) == 0 {
+		filename = os.Getenv("TFMIGRATE_CONFIG")
+	}
+	if len(filename) == 0 {
+		filename = defaultConfigFile
+	}
+
 	if filename == defaultConfigFile {
 		if _, err := os.Stat(defaultConfigFile); os.IsNotExist(err) {
-			// If defaultConfigFile doesn't exist,
-			// Ignore the error and just return a default config.
-			return config.NewDefaultConfig(), nil
+			// If defaultConfigFile doesn't exist, ignore the error.
+			return ""
 		}
 	}
+	return filename
+}
+
+// enableOfflineMode validates that no component would make a network call
+// other than the backend and the history storage, and turns on offline mode
+// for terraform. It requires a pre-populated local filesystem mirror for
+// providers, because the registry is not accessible.
+func enableOfflineMode(c *config.TfmigrateConfig, o *tfmigrate.MigratorOption) error {
+	if err := c.ValidateOffline(); err != nil {
+		return err
+	}
+	return enableOfflineTerraform(o)
+}
+
+// enableOfflineTerraform turns on offline mode only for terraform, which is
+// enough for commands which never use other components of the config.
+func enableOfflineTerraform(o *tfmigrate.MigratorOption) error {
+	if len(o.ProvidersMirrorDir) == 0 {
+		return fmt.Errorf("offline mode requires a local filesystem mirror for providers. Set TFMIGRATE_PROVIDERS_MIRROR_DIR")
+	}
+	if _, err := os.Stat(o.ProvidersMirrorDir); err != nil {
+		return fmt.Errorf("failed to find providers mirror dir: %s", err)
+	}
 
-	log.Printf("[DEBUG] [command] load configuration file: %s\n", filename)
-	return config.LoadConfigurationFile(filename)
+	o.Offline = true
+	return nil
 }
 
 func newOption() *tfmigrate.MigratorOption {
+	// The providers mirror is shared across working directories of migrations,
+	// so we resolve a relative path from the current directory.
+	providersMirrorDir := os.Getenv("TFMIGRATE_PROVIDERS_MIRROR_DIR")
+	if len(providersMirrorDir) != 0 {
+		if abs, err := filepath.Abs(providersMirrorDir); err == nil {
+			providersMirrorDir = abs
+		}
+	}
+
 	return &tfmigrate.MigratorOption{
-		ExecPath: os.Getenv("TFMIGRATE_EXEC_PATH"),
+		ExecPath:               os.Getenv("TFMIGRATE_EXEC_PATH"),
+		ProvidersMirrorDir:     providersMirrorDir,
+		ProvidersLockPlatforms: parsePlatforms(os.Getenv("TFMIGRATE_PROVIDERS_LOCK_PLATFORMS")),
+		TempDir:                os.Getenv("TFMIGRATE_TEMP_DIR"),
+	}
+}
+
+// parsePlatforms parses a comma-separated list of platforms such as
+// linux_amd64,darwin_arm64. Empty elements are ignored.
+func parsePlatforms(s string) []string {
+	var platforms []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); len(p) != 0 {
+			platforms = append(platforms, p)
+		}
 	}
+	return platforms
 }
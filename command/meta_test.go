@@ -0,0 +1,40 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	cases := []struct {
+		desc string
+		s    string
+		want []string
+	}{
+		{
+			desc: "empty",
+			s:    "",
+			want: nil,
+		},
+		{
+			desc: "single",
+			s:    "linux_amd64",
+			want: []string{"linux_amd64"},
+		},
+		{
+			desc: "multiple with spaces and empty elements",
+			s:    "linux_amd64, darwin_arm64,,",
+			want: []string{"linux_amd64", "darwin_arm64"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := parsePlatforms(tc.s)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("got = %#v, want = %#v, diff = %s", got, tc.want, diff)
+			}
+		})
+	}
+}
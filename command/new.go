@@ -0,0 +1,172 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	flag "github.com/spf13/pflag"
+)
+
+// NewCommand is a command which generates a migration file from a template.
+type NewCommand struct {
+	Meta
+	template string
+	params   []string
+	out      string
+}
+
+// Run runs the procedure of this command.
+func (c *NewCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("new", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	cmdFlags.StringVar(&c.template, "template", "", "A name of migration template")
+	cmdFlags.StringArrayVar(&c.params, "param", nil, "A parameter of the template as key=value")
+	cmdFlags.StringVar(&c.out, "out", "", "Write the migration to the given path")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+	name := cmdFlags.Arg(0)
+
+	if len(c.template) == 0 {
+		c.UI.Error("--template is required")
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	t, err := config.FindMigrationTemplate(c.template)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	params, err := parseTemplateParams(c.params)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	b, err := t.Render(name, params, c.config.MigrationFileOption())
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.out == "-" {
+		c.UI.Output(strings.TrimSuffix(string(b), "\n"))
+		return 0
+	}
+
+	out := c.out
+	if len(out) == 0 {
+		out = filepath.Join(c.config.MigrationDir, newMigrationFileName(name, time.Now()))
+	}
+
+	// O_EXCL not to overwrite an existing migration by accident.
+	f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("failed to create migration file: %s", err))
+		return 1
+	}
+	defer f.Close()
+
+	if _, err := f.Write(b); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to write migration file: %s", err))
+		return 1
+	}
+
+	c.UI.Output(out)
+	return 0
+}
+
+// parseTemplateParams parses a list of parameters as key=value and returns a
+// map of them.
+func parseTemplateParams(params []string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, p := range params {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || len(k) == 0 {
+			return nil, fmt.Errorf("invalid parameter, expected key=value: %s", p)
+		}
+		if _, ok := m[k]; ok {
+			return nil, fmt.Errorf("duplicate parameter: %s", k)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// newMigrationFileName returns a migration file name prefixed with a
+// timestamp, so that migrations are sorted in the order they are created.
+// e.g.) 20201114000000_foo.hcl
+func newMigrationFileName(name string, now time.Time) string {
+	return now.UTC().Format("20060102150405") + "_" + name + ".hcl"
+}
+
+// formatMigrationTemplates returns a help text for all available templates.
+func formatMigrationTemplates() string {
+	var b strings.Builder
+	for _, t := range config.MigrationTemplates() {
+		fmt.Fprintf(&b, "  %s\n", t.Name)
+		fmt.Fprintf(&b, "    %s\n", t.Description)
+		for _, p := range t.Params {
+			desc := p.Description
+			if p.Required {
+				desc += " (required)"
+			} else if len(p.Default) > 0 {
+				desc += fmt.Sprintf(" (default: %s)", p.Default)
+			}
+			fmt.Fprintf(&b, "      %-16s %s\n", p.Name, desc)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Help returns long-form help text.
+func (c *NewCommand) Help() string {
+	helpText := `
+Usage: tfmigrate new [options] NAME
+
+Generate a new migration file from a template for a common refactoring.
+The generated file is validated, but it is a starting point to review.
+Read comments in the file before applying it.
+
+Arguments:
+  NAME               A name of the migration
+
+Options:
+  --config           A path to tfmigrate config file
+  --template         A name of migration template (required)
+  --param=key=value  A parameter of the template.
+                     Can be specified multiple times.
+  --out=path         Write the migration to the given path, or stdout if -.
+                     Default to <migration_dir>/<timestamp>_<NAME>.hcl
+
+Templates:
+`
+	return strings.TrimSpace(helpText) + "\n" + formatMigrationTemplates()
+}
+
+// Synopsis returns one-line help text.
+func (c *NewCommand) Synopsis() string {
+	return "Generate a new migration from a template"
+}
@@ -0,0 +1,71 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTemplateParams(t *testing.T) {
+	cases := []struct {
+		desc   string
+		params []string
+		want   map[string]string
+		ok     bool
+	}{
+		{
+			desc:   "simple",
+			params: []string{"src=network", "dst=vpc"},
+			want:   map[string]string{"src": "network", "dst": "vpc"},
+			ok:     true,
+		},
+		{
+			desc:   "value contains equal",
+			params: []string{"id=a=b", "to="},
+			want:   map[string]string{"id": "a=b", "to": ""},
+			ok:     true,
+		},
+		{
+			desc:   "no equal",
+			params: []string{"src"},
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "empty key",
+			params: []string{"=foo"},
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "duplicate",
+			params: []string{"src=a", "src=b"},
+			want:   nil,
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := parseTemplateParams(tc.params)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewMigrationFileName(t *testing.T) {
+	now := time.Date(2020, 11, 14, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	got := newMigrationFileName("foo", now)
+	want := "20201114000000_foo.hcl"
+	if got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+}
@@ -0,0 +1,256 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// errDependencyFailed is an error for a migration which is not run because
+// a migration it depends on has failed, or another migration has failed and
+// the run is stopping.
+var errDependencyFailed = errors.New("not applied because another migration failed")
+
+// parallelJob is a migration to be applied in parallel.
+type parallelJob struct {
+	// filename is a migration file name.
+	filename string
+	// deps is a list of jobs which must finish before this job.
+	deps []*parallelJob
+	// done is closed when the job finishes.
+	done chan struct{}
+	// err is an error of the job. It must be read after done is closed.
+	err error
+}
+
+// newParallelJobs returns a list of jobs for given migrations in order.
+// A migration depends on earlier migrations which share any of its
+// resources, or which it explicitly depends on, so that they are applied in
+// the same order as the sequential apply.
+func newParallelJobs(filenames []string, resources map[string][]string, dependsOn map[string][]string) []*parallelJob {
+	jobs := make([]*parallelJob, 0, len(filenames))
+	for i, filename := range filenames {
+		job := &parallelJob{
+			filename: filename,
+			done:     make(chan struct{}),
+		}
+		for j := 0; j < i; j++ {
+			prev := filenames[j]
+			if slices.Contains(dependsOn[filename], prev) || sharesResource(resources[filename], resources[prev]) {
+				job.deps = append(job.deps, jobs[j])
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// runParallelJobs runs given jobs with a given function concurrently up to
+// a given parallelism, respecting dependencies between them.
+// Once a job fails, no more jobs start, and running jobs are waited for.
+// It returns errors of failed jobs joined in order.
+func runParallelJobs(ctx context.Context, jobs []*parallelJob, parallelism int, run func(ctx context.Context, filename string) error) error {
+	sem := make(chan struct{}, parallelism)
+	var mu sync.Mutex
+	stopping := false
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job *parallelJob) {
+			defer wg.Done()
+			defer close(job.done)
+
+			for _, dep := range job.deps {
+				<-dep.done
+				if dep.err != nil {
+					job.err = errDependencyFailed
+					return
+				}
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			mu.Lock()
+			stop := stopping
+			mu.Unlock()
+			if stop {
+				job.err = errDependencyFailed
+				return
+			}
+
+			job.err = run(ctx, job.filename)
+			if job.err != nil {
+				mu.Lock()
+				stopping = true
+				mu.Unlock()
+			}
+		}(job)
+	}
+	wg.Wait()
+
+	errs := []error{}
+	for _, job := range jobs {
+		if job.err != nil && !errors.Is(job.err, errDependencyFailed) {
+			errs = append(errs, job.err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unresolvedBackend is a resource shared by all states whose backend can't
+// be identified from the configuration, such as a backend configured with
+// -backend-config or variables. Migrations with such states are applied
+// serially, because they may share the same backend.
+const unresolvedBackend = "backend:unresolved"
+
+// migrationResources returns a list of resources which a given migration
+// works with exclusively, that is, its working directories and the backends
+// of its states. Migrations which share any resources can't run in parallel.
+// A working directory is not shared even with different workspaces, because
+// terraform init and the override file modify the directory.
+func migrationResources(mc *tfmigrate.MigrationConfig, o *tfmigrate.MigratorOption) []string {
+	resources := []string{}
+	for _, t := range mc.BackendTargets() {
+		dir := t.Dir
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		resources = append(resources, "dir:"+dir)
+
+		backendConfig := t.BackendConfig
+		if len(backendConfig) == 0 && o != nil {
+			backendConfig = o.BackendConfig
+		}
+		key, ok := backendKey(dir)
+		switch {
+		case !ok || len(backendConfig) != 0:
+			resources = append(resources, unresolvedBackend)
+		case len(key) != 0:
+			resources = append(resources, "backend:"+key+"@"+t.Workspace)
+		}
+	}
+	return resources
+}
+
+// backendKey returns an identifier of the backend configured in a given
+// directory such as `s3 bucket=foo key=bar`, which is built from literal
+// attributes of the backend block.
+// It returns an empty string with true if no backend block is found, that
+// is, the state is stored locally in the directory.
+// It returns false if the backend can't be identified, such as a partial
+// configuration, an attribute which refers to variables, or a file which
+// can't be parsed.
+func backendKey(dir string) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+
+	parser := hclparse.NewParser()
+	for _, e := range entries {
+		if e.IsDir() || !(strings.HasSuffix(e.Name(), ".tf") || strings.HasSuffix(e.Name(), ".tofu")) {
+			continue
+		}
+		f, diags := parser.ParseHCLFile(filepath.Join(dir, e.Name()))
+		if diags.HasErrors() {
+			return "", false
+		}
+		body, ok := f.Body.(*hclsyntax.Body)
+		if !ok {
+			return "", false
+		}
+		for _, tb := range body.Blocks {
+			if tb.Type != "terraform" {
+				continue
+			}
+			for _, b := range tb.Body.Blocks {
+				switch {
+				case b.Type == "backend" && len(b.Labels) == 1:
+					return literalAttributesKey(b.Labels[0], b.Body)
+				case b.Type == "cloud":
+					return literalAttributesKey("cloud", b.Body)
+				}
+			}
+		}
+	}
+	return "", true
+}
+
+// literalAttributesKey returns a key built from a given name and literal
+// attributes in a given body, including nested blocks.
+// It returns false if no attributes are found or any of them is not a
+// literal.
+func literalAttributesKey(name string, body *hclsyntax.Body) (string, bool) {
+	attrs, ok := literalAttributes("", body)
+	if !ok || len(attrs) == 0 {
+		return "", false
+	}
+	sort.Strings(attrs)
+	return name + " " + strings.Join(attrs, " "), true
+}
+
+// literalAttributes returns a list of literal primitive attributes in a
+// given body as "name=value". Names of attributes in nested blocks are
+// prefixed with the block type.
+// It returns false if any of them is not a literal.
+func literalAttributes(prefix string, body *hclsyntax.Body) ([]string, bool) {
+	attrs := []string{}
+	for name, attr := range body.Attributes {
+		v, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || !v.IsWhollyKnown() {
+			return nil, false
+		}
+		if v.IsNull() {
+			continue
+		}
+		sv, err := convert.Convert(v, cty.String)
+		if err != nil {
+			return nil, false
+		}
+		attrs = append(attrs, fmt.Sprintf("%s%s=%s", prefix, name, sv.AsString()))
+	}
+	for _, b := range body.Blocks {
+		nested, ok := literalAttributes(prefix+b.Type+".", b.Body)
+		if !ok {
+			return nil, false
+		}
+		attrs = append(attrs, nested...)
+	}
+	return attrs, true
+}
+
+// sharesResource returns true if given lists have any resources in common.
+func sharesResource(a []string, b []string) bool {
+	for _, x := range a {
+		if slices.Contains(b, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// logParallelJobs logs dependencies between jobs for debugging.
+func logParallelJobs(jobs []*parallelJob) {
+	for _, job := range jobs {
+		deps := []string{}
+		for _, dep := range job.deps {
+			deps = append(deps, dep.filename)
+		}
+		log.Printf("[DEBUG] [runner] parallel job: %s, waits for: %v\n", job.filename, deps)
+	}
+}
@@ -0,0 +1,283 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestNewParallelJobs(t *testing.T) {
+	filenames := []string{"m1.hcl", "m2.hcl", "m3.hcl", "m4.hcl"}
+	resources := map[string][]string{
+		"m1.hcl": {"dir:/foo"},
+		"m2.hcl": {"dir:/bar"},
+		"m3.hcl": {"dir:/foo", "dir:/baz"},
+		"m4.hcl": {"dir:/qux"},
+	}
+	dependsOn := map[string][]string{
+		"m4.hcl": {"m2.hcl"},
+	}
+
+	jobs := newParallelJobs(filenames, resources, dependsOn)
+	got := map[string][]string{}
+	for _, job := range jobs {
+		deps := []string{}
+		for _, dep := range job.deps {
+			deps = append(deps, dep.filename)
+		}
+		got[job.filename] = deps
+	}
+	want := map[string][]string{
+		"m1.hcl": {},
+		"m2.hcl": {},
+		"m3.hcl": {"m1.hcl"},
+		"m4.hcl": {"m2.hcl"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %#v, want = %#v", got, want)
+	}
+}
+
+func TestRunParallelJobs(t *testing.T) {
+	filenames := []string{"m1.hcl", "m2.hcl", "m3.hcl", "m4.hcl"}
+	resources := map[string][]string{
+		"m1.hcl": {"dir:/foo"},
+		"m2.hcl": {"dir:/bar"},
+		"m3.hcl": {"dir:/foo"},
+		"m4.hcl": {"dir:/baz"},
+	}
+
+	cases := []struct {
+		desc        string
+		parallelism int
+		failed      string
+		want        []string
+		ok          bool
+	}{
+		{
+			desc:        "all succeeded",
+			parallelism: 2,
+			want:        []string{"m1.hcl", "m2.hcl", "m3.hcl", "m4.hcl"},
+			ok:          true,
+		},
+		{
+			desc:        "sequential",
+			parallelism: 1,
+			want:        []string{"m1.hcl", "m2.hcl", "m3.hcl", "m4.hcl"},
+			ok:          true,
+		},
+		{
+			desc:        "dependency failed",
+			parallelism: 4,
+			failed:      "m1.hcl",
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			jobs := newParallelJobs(filenames, resources, nil)
+
+			var mu sync.Mutex
+			running := 0
+			maxRunning := 0
+			ran := map[string]bool{}
+			err := runParallelJobs(context.Background(), jobs, tc.parallelism, func(_ context.Context, filename string) error {
+				mu.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				ran[filename] = true
+				mu.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				if filename == tc.failed {
+					return fmt.Errorf("failed to apply: %s", filename)
+				}
+				return nil
+			})
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if maxRunning > tc.parallelism {
+				t.Errorf("got max running = %d, but parallelism = %d", maxRunning, tc.parallelism)
+			}
+			for _, filename := range tc.want {
+				if !ran[filename] {
+					t.Errorf("expected to run: %s", filename)
+				}
+			}
+			if tc.failed == "m1.hcl" && ran["m3.hcl"] {
+				t.Error("expected not to run a migration which depends on a failed one")
+			}
+		})
+	}
+}
+
+func TestBackendKey(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   string
+		ok     bool
+	}{
+		{
+			desc: "s3",
+			source: `
+terraform {
+  backend "s3" {
+    bucket  = "tfstate"
+    key     = "network/terraform.tfstate"
+    region  = "ap-northeast-1"
+    encrypt = true
+  }
+}
+`,
+			want: "s3 bucket=tfstate encrypt=true key=network/terraform.tfstate region=ap-northeast-1",
+			ok:   true,
+		},
+		{
+			desc: "cloud",
+			source: `
+terraform {
+  cloud {
+    organization = "foo"
+    workspaces {
+      name = "bar"
+    }
+  }
+}
+`,
+			want: "cloud organization=foo workspaces.name=bar",
+			ok:   true,
+		},
+		{
+			desc: "partial configuration",
+			source: `
+terraform {
+  backend "s3" {}
+}
+`,
+			want: "",
+			ok:   false,
+		},
+		{
+			desc: "variable",
+			source: `
+terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "${var.env}/terraform.tfstate"
+  }
+}
+`,
+			want: "",
+			ok:   false,
+		},
+		{
+			desc: "no backend",
+			source: `
+resource "null_resource" "foo" {}
+`,
+			want: "",
+			ok:   true,
+		},
+		{
+			desc:   "invalid",
+			source: `terraform {`,
+			want:   "",
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(tc.source), 0600); err != nil {
+				t.Fatalf("failed to write a file: %s", err)
+			}
+			got, ok := backendKey(dir)
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("got = %q, %t, want = %q, %t", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestMigrationResources(t *testing.T) {
+	setupDir := func(t *testing.T, source string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(source), 0600); err != nil {
+			t.Fatalf("failed to write a file: %s", err)
+		}
+		return dir
+	}
+	s3 := `
+terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "terraform.tfstate"
+  }
+}
+`
+	partial := `
+terraform {
+  backend "s3" {}
+}
+`
+
+	cases := []struct {
+		desc   string
+		source string
+		option *tfmigrate.MigratorOption
+		want   string
+	}{
+		{
+			desc:   "literal",
+			source: s3,
+			option: nil,
+			want:   "backend:s3 bucket=tfstate key=terraform.tfstate@default",
+		},
+		{
+			desc:   "partial configuration",
+			source: partial,
+			option: nil,
+			want:   unresolvedBackend,
+		},
+		{
+			desc:   "backend config",
+			source: s3,
+			option: &tfmigrate.MigratorOption{BackendConfig: []string{"key=foo.tfstate"}},
+			want:   unresolvedBackend,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := setupDir(t, tc.source)
+			mc := &tfmigrate.MigrationConfig{
+				Type:     "state",
+				Name:     "test",
+				Migrator: &tfmigrate.StateMigratorConfig{Dir: dir},
+			}
+			got := migrationResources(mc, tc.option)
+			want := []string{"dir:" + dir, tc.want}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got = %#v, want = %#v", got, want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path/filepath"
 	"strings"
 
 	flag "github.com/spf13/pflag"
@@ -15,30 +16,85 @@ type PlanCommand struct {
 	Meta
 	backendConfig []string
 	out           string
+	keepTemp      bool
+	strict        bool
+	offline       bool
+	stateVersions []string
+	checkSources  bool
+	readOnly      bool
+	showDiff      bool
+	format        string
 }
 
 // Run runs the procedure of this command.
 func (c *PlanCommand) Run(args []string) int {
 	cmdFlags := flag.NewFlagSet("plan", flag.ContinueOnError)
-	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
 	cmdFlags.StringArrayVar(&c.backendConfig, "backend-config", nil, "A backend configuration for remote state")
 	cmdFlags.StringVar(&c.out, "out", "", "Save a plan file after dry-run migration to the given path")
+	cmdFlags.BoolVar(&c.keepTemp, "keep-temp", false, "Keep temporary files for debugging")
+	cmdFlags.BoolVar(&c.strict, "strict", false, "Enable a bundle of safety behaviors")
+	cmdFlags.BoolVar(&c.offline, "offline", false, "Fail if any network call other than the backend and history storage would be made")
+	cmdFlags.StringArrayVar(&c.stateVersions, "state-version", nil, "A version of remote state to be used instead of the current state")
+	cmdFlags.BoolVar(&c.readOnly, "read-only", false, "Refuse any terraform command which may mutate remote states or resources")
+	cmdFlags.BoolVar(&c.checkSources, "check-sources", false, "Verify that sources of mv and rm actions exist and show their key attributes")
+	cmdFlags.BoolVar(&c.showDiff, "show-diff", false, "Show a diff of addresses in states before and after migrations")
+	cmdFlags.StringVar(&c.format, "format", "text", "An output format")
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
 		return 1
 	}
 
+	if c.format != "text" && c.format != "json" {
+		c.UI.Error(fmt.Sprintf("unknown format: %s", c.format))
+		return 1
+	}
+
 	var err error
 	if c.config, err = newConfig(c.configFile); err != nil {
 		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
 		return 1
 	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
 
 	c.Option = newOption()
 	c.Option.PlanOut = c.out
 	c.Option.BackendConfig = c.backendConfig
+	c.Option.KeepTemp = c.keepTemp
+	c.Option.CheckSources = c.checkSources
+	c.Option.ShowDiff = c.showDiff
+	if c.format == "json" {
+		// The JSON output always contains diffs of addresses in states and
+		// summaries of terraform plan.
+		c.Option.ShowDiff = true
+		c.Option.PlanSummary = true
+	}
+	if c.readOnly || c.config.ReadOnlyPlan {
+		log.Printf("[INFO] [command] read-only mode\n")
+		c.Option.ReadOnly = true
+	}
+	if c.Option.StateVersions, err = parseStateVersions(c.stateVersions); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	if c.strict {
+		log.Printf("[INFO] [command] strict mode\n")
+		c.config.EnableStrictMode()
+		c.Option.CheckLineage = true
+	}
+	if c.offline {
+		log.Printf("[INFO] [command] offline mode\n")
+		if err := enableOfflineMode(c.config, c.Option); err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
 	// The option may contains sensitive values such as environment variables.
 	// So logging the option set log level to DEBUG instead of INFO.
 	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
@@ -82,6 +138,32 @@ func (c *PlanCommand) Run(args []string) int {
 	return 0
 }
 
+// parseStateVersions parses values of the --state-version flag in the
+// format of [DIR=]VERSION and returns a map of working directories to
+// versions. A version without a directory is stored with an empty key, which
+// matches any directory.
+func parseStateVersions(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	versions := make(map[string]string)
+	for _, v := range values {
+		dir, version := "", v
+		if i := strings.Index(v, "="); i >= 0 {
+			dir, version = filepath.Clean(v[:i]), v[i+1:]
+		}
+		if len(version) == 0 {
+			return nil, fmt.Errorf("invalid state version: %q, it must be in the format of [DIR=]VERSION", v)
+		}
+		if _, ok := versions[dir]; ok {
+			return nil, fmt.Errorf("duplicated state version: %q", v)
+		}
+		versions[dir] = version
+	}
+	return versions, nil
+}
+
 // planWithoutHistory is a helper function which plans a given migration file without history.
 func (c *PlanCommand) planWithoutHistory(filename string) error {
 	fr, err := NewFileRunner(filename, c.config, c.Option)
@@ -89,7 +171,9 @@ func (c *PlanCommand) planWithoutHistory(filename string) error {
 		return err
 	}
 
-	return fr.Plan(context.Background())
+	err = fr.Plan(context.Background())
+	c.outputResults([]*FileRunner{fr}, err)
+	return err
 }
 
 // planWithHistory is a helper function which plans all unapplied pending migrations.
@@ -100,7 +184,41 @@ func (c *PlanCommand) planWithHistory(filename string) error {
 		return err
 	}
 
-	return hr.Plan(ctx)
+	err = hr.Plan(ctx)
+	c.outputResults(hr.Planned(), err)
+	return err
+}
+
+// outputResults outputs results of given runners in the output format.
+// The JSON output is written even if the plan failed, so that tools can
+// report the failure with results computed before it.
+func (c *PlanCommand) outputResults(runners []*FileRunner, err error) {
+	if c.format != "json" {
+		c.outputStateDiffs(runners)
+		return
+	}
+
+	out, jerr := formatPlanReport(runners, err)
+	if jerr != nil {
+		c.UI.Error(jerr.Error())
+		return
+	}
+	c.UI.Output(out)
+}
+
+// outputStateDiffs outputs diffs of addresses in states computed by given
+// runners if the --show-diff flag is set. A diff is output even if the plan
+// failed after computing new states, such as unexpected diffs in terraform
+// plan, because it's useful to investigate the cause.
+func (c *PlanCommand) outputStateDiffs(runners []*FileRunner) {
+	if !c.showDiff {
+		return
+	}
+	for _, fr := range runners {
+		for _, d := range fr.StateDiffs() {
+			c.UI.Output(formatStateDiff(fr.Filename(), d))
+		}
+	}
 }
 
 // Help returns long-form help text.
@@ -124,6 +242,50 @@ Options:
   --out=path               Save a plan file after dry-run migration to the given path.
                            Note that the saved plan file is not applicable in Terraform 1.1+.
                            It's intended to use only for static analysis.
+  --keep-temp              Keep temporary files such as states and plans for debugging.
+                           Note that they may contain sensitive data.
+  --strict                 Enable a bundle of safety behaviors at once:
+                           - deny force and default_force in migrations
+                           - treat warnings on checking working directories as errors
+                           - check lineage of remote states before pushing new ones
+                           - write history with compare-and-swap, which requires
+                             a history storage supporting versioning
+  --offline                Fail fast if any component would make a network call other than
+                           the backend and the history storage, such as event sinks, hooks
+                           and encryption with kms. The checkpoint service of terraform is
+                           disabled, and providers are installed only from a pre-populated
+                           local filesystem mirror in TFMIGRATE_PROVIDERS_MIRROR_DIR.
+  --var=NAME=VALUE         Set a variable which migration files can reference as var.NAME.
+                           It takes precedence over the variables block in the config file
+                           and TFMIGRATE_VAR_NAME environment variables.
+                           This option can be specified multiple times.
+  --var-file=path          A path to a variable file such as env = "prod" which sets
+                           variables for migration files. --var takes precedence over it.
+                           This option can be specified multiple times.
+  --state-version=[DIR=]VERSION
+                           Plan against a historical version of the remote state instead of
+                           the current state, such as an S3 object version ID of the s3
+                           backend, or a state version ID (sv-xxx) of the remote and cloud
+                           backends. If DIR is omitted, it applies to any directory.
+                           This option can be specified multiple times for multi_state.
+  --read-only              Refuse any terraform command which may mutate remote states or
+                           real resources, such as apply and state push, to verify that
+                           the plan can run with read-only credentials. It's always enabled
+                           if read_only_plan is set in the config file.
+  --check-sources          Verify that sources of mv and rm actions in state migrations
+                           exist before running them, and show their key attributes
+                           such as id, name and arn with terraform state show.
+  --show-diff              Show a unified diff of terraform state list between the current
+                           state and the new state for each working directory, so that
+                           reviewers can see which addresses move where.
+  --format=text            An output format. Valid values are as follows:
+                             - text (default)
+                             - json: A structured document of each migration, which
+                               contains results of actions, actions resolved from
+                               wildcards such as xmv, diffs of terraform state list,
+                               and summaries of terraform plan. It's intended for
+                               tools such as bots commenting on pull requests.
+                               It's written even if the plan fails.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -0,0 +1,154 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// planReportFormatVersion is a version of the JSON output format of the plan
+// command. It's bumped on incompatible changes.
+const planReportFormatVersion = "1"
+
+// PlanReport is a result of the plan command in JSON.
+type PlanReport struct {
+	// FormatVersion is a version of the format.
+	FormatVersion string `json:"format_version"`
+	// Migrations is a list of planned migrations in order, including a
+	// failed one.
+	Migrations []*MigrationReport `json:"migrations"`
+	// Error is an error message if the plan failed.
+	Error string `json:"error,omitempty"`
+}
+
+// MigrationReport is a result of planning a migration file.
+type MigrationReport struct {
+	// File is a path to the migration file.
+	File string `json:"file"`
+	// Skipped is true if the migration is skipped by its condition.
+	Skipped bool `json:"skipped"`
+	// Actions is a list of results of actions.
+	Actions []*ActionReport `json:"actions"`
+	// StateDiffs is a list of diffs of terraform state list for each working
+	// directory.
+	StateDiffs []*StateDiffReport `json:"state_diffs"`
+	// PlanSummaries is a list of summaries of terraform plan for each working
+	// directory where terraform plan is not skipped.
+	PlanSummaries []*PlanSummaryReport `json:"plan_summaries"`
+}
+
+// ActionReport is a result of an action.
+type ActionReport struct {
+	// Action is a plain text of the action.
+	Action string `json:"action"`
+	// Status is one of succeeded, failed and skipped.
+	Status string `json:"status"`
+	// Resolved is a list of plain actions which a wildcard action such as xmv
+	// has been resolved to. It's null for other actions.
+	Resolved []string `json:"resolved"`
+	// Error is an error message if the action failed.
+	Error string `json:"error,omitempty"`
+}
+
+// StateDiffReport is a diff of terraform state list in a working directory.
+type StateDiffReport struct {
+	// Dir is a working directory.
+	Dir string `json:"dir"`
+	// Workspace is a terraform workspace.
+	Workspace string `json:"workspace"`
+	// Added is a sorted list of addresses only in the new state.
+	Added []string `json:"added"`
+	// Removed is a sorted list of addresses only in the current state.
+	Removed []string `json:"removed"`
+}
+
+// PlanSummaryReport is a summary of terraform plan in a working directory.
+type PlanSummaryReport struct {
+	// Dir is a working directory.
+	Dir string `json:"dir"`
+	// Workspace is a terraform workspace.
+	Workspace string `json:"workspace"`
+	// Add is a number of resource instances to be created.
+	Add int `json:"add"`
+	// Change is a number of resource instances to be updated in-place.
+	Change int `json:"change"`
+	// Destroy is a number of resource instances to be destroyed.
+	Destroy int `json:"destroy"`
+	// Changes is a list of changes in the format of <action>:<address>.
+	Changes []string `json:"changes"`
+}
+
+// formatPlanReport returns a result of given planned runners in JSON.
+// The err is an error of the plan, which may be nil.
+func formatPlanReport(runners []*FileRunner, err error) (string, error) {
+	report := &PlanReport{
+		FormatVersion: planReportFormatVersion,
+		Migrations:    []*MigrationReport{},
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	for _, fr := range runners {
+		report.Migrations = append(report.Migrations, newMigrationReport(fr.Filename(), fr.Skipped(), fr.ActionResults(), fr.StateDiffs(), fr.PlanSummaries()))
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plan report: %s", err)
+	}
+	return string(b), nil
+}
+
+// newMigrationReport returns a MigrationReport of given results of planning
+// a migration file.
+func newMigrationReport(filename string, skipped bool, results []tfmigrate.ActionResult, diffs []tfmigrate.StateDiff, summaries []tfmigrate.PlanSummary) *MigrationReport {
+	m := &MigrationReport{
+		File:          filename,
+		Skipped:       skipped,
+		Actions:       []*ActionReport{},
+		StateDiffs:    []*StateDiffReport{},
+		PlanSummaries: []*PlanSummaryReport{},
+	}
+
+	for _, r := range results {
+		m.Actions = append(m.Actions, &ActionReport{
+			Action:   r.Action,
+			Status:   r.Status,
+			Resolved: r.Resolved,
+			Error:    r.Error,
+		})
+	}
+
+	for _, d := range diffs {
+		sd := &StateDiffReport{
+			Dir:       d.Dir,
+			Workspace: d.Workspace,
+			Added:     []string{},
+			Removed:   []string{},
+		}
+		for _, l := range diffSortedLines(sortedCopy(d.Before), sortedCopy(d.After)) {
+			switch l.op {
+			case '+':
+				sd.Added = append(sd.Added, l.text)
+			case '-':
+				sd.Removed = append(sd.Removed, l.text)
+			}
+		}
+		m.StateDiffs = append(m.StateDiffs, sd)
+	}
+
+	for _, s := range summaries {
+		m.PlanSummaries = append(m.PlanSummaries, &PlanSummaryReport{
+			Dir:       s.Dir,
+			Workspace: s.Workspace,
+			Add:       s.Add,
+			Change:    s.Change,
+			Destroy:   s.Destroy,
+			Changes:   s.Changes,
+		})
+	}
+
+	return m
+}
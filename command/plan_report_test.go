@@ -0,0 +1,184 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestNewMigrationReport(t *testing.T) {
+	cases := []struct {
+		desc      string
+		results   []tfmigrate.ActionResult
+		diffs     []tfmigrate.StateDiff
+		summaries []tfmigrate.PlanSummary
+		want      string
+	}{
+		{
+			desc: "simple",
+			results: []tfmigrate.ActionResult{
+				{
+					Action:   "xmv null_resource.* null_resource.$1_2",
+					Status:   tfmigrate.ActionStatusSucceeded,
+					Resolved: []string{"mv null_resource.foo null_resource.foo_2"},
+				},
+				{
+					Action: "rm null_resource.bar",
+					Status: tfmigrate.ActionStatusSucceeded,
+				},
+			},
+			diffs: []tfmigrate.StateDiff{
+				{
+					Dir:       "dir1",
+					Workspace: "default",
+					Before:    []string{"null_resource.foo", "null_resource.bar", "null_resource.baz"},
+					After:     []string{"null_resource.foo_2", "null_resource.baz"},
+				},
+			},
+			summaries: []tfmigrate.PlanSummary{
+				{
+					Dir:       "dir1",
+					Workspace: "default",
+					Change:    1,
+					Changes:   []string{"update:null_resource.baz"},
+				},
+			},
+			want: `{
+  "file": "foo.hcl",
+  "skipped": false,
+  "actions": [
+    {
+      "action": "xmv null_resource.* null_resource.$1_2",
+      "status": "succeeded",
+      "resolved": [
+        "mv null_resource.foo null_resource.foo_2"
+      ]
+    },
+    {
+      "action": "rm null_resource.bar",
+      "status": "succeeded",
+      "resolved": null
+    }
+  ],
+  "state_diffs": [
+    {
+      "dir": "dir1",
+      "workspace": "default",
+      "added": [
+        "null_resource.foo_2"
+      ],
+      "removed": [
+        "null_resource.bar",
+        "null_resource.foo"
+      ]
+    }
+  ],
+  "plan_summaries": [
+    {
+      "dir": "dir1",
+      "workspace": "default",
+      "add": 0,
+      "change": 1,
+      "destroy": 0,
+      "changes": [
+        "update:null_resource.baz"
+      ]
+    }
+  ]
+}`,
+		},
+		{
+			desc: "failed",
+			results: []tfmigrate.ActionResult{
+				{
+					Action: "mv null_resource.foo null_resource.foo2",
+					Status: tfmigrate.ActionStatusFailed,
+					Error:  "failed to mv",
+				},
+				{
+					Action: "rm null_resource.bar",
+					Status: tfmigrate.ActionStatusSkipped,
+				},
+			},
+			want: `{
+  "file": "foo.hcl",
+  "skipped": false,
+  "actions": [
+    {
+      "action": "mv null_resource.foo null_resource.foo2",
+      "status": "failed",
+      "resolved": null,
+      "error": "failed to mv"
+    },
+    {
+      "action": "rm null_resource.bar",
+      "status": "skipped",
+      "resolved": null
+    }
+  ],
+  "state_diffs": [],
+  "plan_summaries": []
+}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			m := newMigrationReport("foo.hcl", false, tc.results, tc.diffs, tc.summaries)
+			b, err := json.MarshalIndent(m, "", "  ")
+			if err != nil {
+				t.Fatalf("failed to encode: %s", err)
+			}
+			if got := string(b); got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatPlanReport(t *testing.T) {
+	source := `
+migration "mock" "test" {
+	plan_error  = true
+	apply_error = false
+}
+`
+	path := setupMigrationFile(t, source)
+	fr, err := NewFileRunner(path, config.NewDefaultConfig(), nil)
+	if err != nil {
+		t.Fatalf("failed to new file runner: %s", err)
+	}
+	planErr := fr.Plan(context.Background())
+	if planErr == nil {
+		t.Fatal("expected the plan to fail, but no error")
+	}
+
+	got, err := formatPlanReport([]*FileRunner{fr}, planErr)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	var report PlanReport
+	if err := json.Unmarshal([]byte(got), &report); err != nil {
+		t.Fatalf("failed to decode: %s", err)
+	}
+	want := PlanReport{
+		FormatVersion: planReportFormatVersion,
+		Migrations: []*MigrationReport{
+			{
+				File:          path,
+				Actions:       []*ActionReport{},
+				StateDiffs:    []*StateDiffReport{},
+				PlanSummaries: []*PlanSummaryReport{},
+			},
+		},
+		Error: planErr.Error(),
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("got: %s, want: %#v", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStateVersions(t *testing.T) {
+	cases := []struct {
+		desc   string
+		values []string
+		want   map[string]string
+		ok     bool
+	}{
+		{
+			desc:   "not set",
+			values: nil,
+			want:   nil,
+			ok:     true,
+		},
+		{
+			desc:   "any directory",
+			values: []string{"3sL4kqtJlcpXroDTDmJ.rmSpXd3dIbrHY"},
+			want:   map[string]string{"": "3sL4kqtJlcpXroDTDmJ.rmSpXd3dIbrHY"},
+			ok:     true,
+		},
+		{
+			desc:   "multiple directories",
+			values: []string{"./dir1/=sv-123", "dir2=sv-456"},
+			want:   map[string]string{"dir1": "sv-123", "dir2": "sv-456"},
+			ok:     true,
+		},
+		{
+			desc:   "empty version",
+			values: []string{"dir1="},
+			ok:     false,
+		},
+		{
+			desc:   "duplicated",
+			values: []string{"dir1=sv-123", "dir1/=sv-456"},
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := parseStateVersions(tc.values)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
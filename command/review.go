@@ -0,0 +1,473 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// ReviewCommand is a command which reports the impact of migration files
+// added or changed in a pull request.
+type ReviewCommand struct {
+	Meta
+	base string
+	plan bool
+	json bool
+}
+
+// Run runs the procedure of this command.
+func (c *ReviewCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("review", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
+	cmdFlags.StringVar(&c.base, "base", "", "A git ref to compare with for finding changed migration files")
+	cmdFlags.BoolVar(&c.plan, "plan", false, "Run plan for each migration and include the results")
+	cmdFlags.BoolVar(&c.json, "json", false, "Output in JSON format")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(c.base) == 0 && len(cmdFlags.Args()) == 0 {
+		c.UI.Error("The command expects migration files or --base")
+		c.UI.Error(c.Help())
+		return 1
+	}
+	if len(c.base) != 0 && len(cmdFlags.Args()) != 0 {
+		c.UI.Error("migration files and --base cannot be specified at the same time")
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	// The option may contains sensitive values such as environment variables.
+	// So logging the option set log level to DEBUG instead of INFO.
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	ctx := context.Background()
+	filenames := cmdFlags.Args()
+	if len(c.base) != 0 {
+		filenames, err = changedMigrationFiles(ctx, c.config.MigrationDir, c.base)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
+
+	report, err := reviewMigrations(ctx, c.config, c.Option, filenames, c.plan)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.json {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		c.UI.Output(string(b))
+	} else {
+		c.UI.Output(formatReviewMarkdown(report))
+	}
+
+	if report.hasProblems() {
+		return 2
+	}
+	return 0
+}
+
+// changedMigrationFiles returns a sorted list of migration files added or
+// changed since a merge base of a given git ref and HEAD.
+func changedMigrationFiles(ctx context.Context, migrationDir string, base string) ([]string, error) {
+	out, err := runGit(ctx, migrationDir, "diff", "--name-only", "--relative", "--diff-filter=AMR", base+"...HEAD", "--", ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changed files from git: %s", err)
+	}
+
+	filenames := []string{}
+	for _, f := range strings.Split(out, "\n") {
+		// Migration files are located directly under the migration directory.
+		// Hidden files such as .tfmigrate.hcl are not migration files.
+		if len(f) == 0 || strings.Contains(f, "/") || strings.HasPrefix(f, ".") {
+			continue
+		}
+		if ext := filepath.Ext(f); ext != ".hcl" && ext != ".json" {
+			continue
+		}
+		filenames = append(filenames, f)
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+// reviewReport is a consolidated report of migrations to be reviewed.
+type reviewReport struct {
+	// Targets is a sorted list of states affected by the migrations as
+	// "<dir>@<workspace>".
+	Targets []string `json:"targets"`
+	// Migrations is a list of reports for each migration.
+	Migrations []*reviewMigration `json:"migrations"`
+}
+
+// reviewMigration is a report of a migration.
+type reviewMigration struct {
+	// Filename is a migration file name.
+	Filename string `json:"filename"`
+	// Type is a migration type.
+	Type string `json:"type,omitempty"`
+	// Name is a migration name.
+	Name string `json:"name,omitempty"`
+	// Skipped is true if the migration is skipped by skip_if.
+	Skipped bool `json:"skipped"`
+	// Targets is a list of states which the migration works with.
+	Targets []string `json:"targets"`
+	// Actions is a list of actions after loading the migration file, in
+	// which variables and import_file are expanded.
+	Actions []string `json:"actions"`
+	// Conflicts is a list of conflicts with pending migrations.
+	Conflicts []*reviewConflict `json:"conflicts"`
+	// Errors is a list of problems found on loading and validating the
+	// migration.
+	Errors []string `json:"errors"`
+	// Plan is a result of plan. It is nil if plan is not run.
+	Plan *reviewPlan `json:"plan,omitempty"`
+}
+
+// reviewConflict is a conflict between a migration and a pending migration.
+type reviewConflict struct {
+	// Filename is a file name of the pending migration.
+	Filename string `json:"filename"`
+	// Target is a state in which the conflict was found.
+	Target string `json:"target"`
+	// Address is an address touched by the migration.
+	Address string `json:"address"`
+	// PendingAddress is an address touched by the pending migration.
+	PendingAddress string `json:"pending_address"`
+}
+
+// reviewPlan is a result of plan for a migration.
+type reviewPlan struct {
+	// Status is either succeeded or failed.
+	Status string `json:"status"`
+	// Error is an error message if the plan failed.
+	Error string `json:"error,omitempty"`
+	// Actions is a list of results of actions.
+	Actions []actionSummary `json:"actions,omitempty"`
+}
+
+// hasProblems returns true if any migration has errors, conflicts or a
+// failed plan.
+func (r *reviewReport) hasProblems() bool {
+	for _, m := range r.Migrations {
+		if len(m.Errors) > 0 || len(m.Conflicts) > 0 {
+			return true
+		}
+		if m.Plan != nil && m.Plan.Status == tfmigrate.ActionStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// reviewMigrations returns a report for given migration files.
+// Conflicts are checked against pending migrations, that is, unapplied
+// migrations other than the given ones in history mode. In non-history mode,
+// there are no pending migrations. If plan is true, it also runs plan for
+// each migration which has no errors.
+func reviewMigrations(ctx context.Context, config *config.TfmigrateConfig, option *tfmigrate.MigratorOption, filenames []string, plan bool) (*reviewReport, error) {
+	reviewed := make(map[string]bool)
+	for _, filename := range filenames {
+		reviewed[filepath.Clean(filename)] = true
+	}
+
+	var hc *history.Controller
+	pending := make(map[string][]*tfmigrate.TouchedAddress)
+	var pendingFiles []string
+	if config.History != nil {
+		var err error
+		hc, err = history.NewController(ctx, config.MigrationDir, config.History)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, filename := range hc.UnappliedMigrations() {
+			if reviewed[filename] {
+				continue
+			}
+			mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, filename), config.MigrationFileOption())
+			if err != nil {
+				log.Printf("[WARN] [command] failed to load a pending migration: %s\n", err)
+				continue
+			}
+			if mc.Skip {
+				continue
+			}
+			touched, err := mc.TouchedAddresses()
+			if err != nil {
+				log.Printf("[WARN] [command] failed to parse a pending migration: %s: %s\n", filename, err)
+				continue
+			}
+			pending[filename] = touched
+			pendingFiles = append(pendingFiles, filename)
+		}
+	}
+
+	report := &reviewReport{
+		Targets:    []string{},
+		Migrations: []*reviewMigration{},
+	}
+	targets := make(map[string]bool)
+	for _, filename := range filenames {
+		m := &reviewMigration{
+			Filename:  filename,
+			Targets:   []string{},
+			Actions:   []string{},
+			Conflicts: []*reviewConflict{},
+			Errors:    []string{},
+		}
+		report.Migrations = append(report.Migrations, m)
+
+		mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, filename), config.MigrationFileOption())
+		if err != nil {
+			m.Errors = append(m.Errors, err.Error())
+			continue
+		}
+		m.Type = mc.Type
+		m.Name = mc.Name
+		m.Skipped = mc.Skip
+		m.Targets = mc.Targets()
+		if _, actions := migrationDirsAndActions(mc); actions != nil {
+			m.Actions = actions
+		}
+		if !mc.Skip {
+			for _, t := range m.Targets {
+				targets[t] = true
+			}
+		}
+
+		if hc != nil && hc.AlreadyApplied(filename) {
+			m.Errors = append(m.Errors, "the migration has already been applied, so changes to it never take effect")
+			continue
+		}
+		if mc.Skip {
+			continue
+		}
+
+		touched, err := mc.TouchedAddresses()
+		if err != nil {
+			m.Errors = append(m.Errors, err.Error())
+			continue
+		}
+		for _, p := range pendingFiles {
+			m.Conflicts = append(m.Conflicts, findReviewConflicts(p, touched, pending[p])...)
+		}
+
+		// Validate the migration in the same way as plan and apply.
+		fr, err := NewFileRunner(filename, config, option)
+		if err != nil {
+			m.Errors = append(m.Errors, err.Error())
+			continue
+		}
+
+		if plan {
+			m.Plan = &reviewPlan{Status: tfmigrate.ActionStatusSucceeded}
+			if err := fr.Plan(ctx); err != nil {
+				m.Plan.Status = tfmigrate.ActionStatusFailed
+				m.Plan.Error = err.Error()
+			}
+			for _, a := range fr.ActionResults() {
+				m.Plan.Actions = append(m.Plan.Actions, actionSummary{
+					Action: a.Action,
+					Status: a.Status,
+					Error:  a.Error,
+				})
+			}
+		}
+	}
+
+	for t := range targets {
+		report.Targets = append(report.Targets, t)
+	}
+	sort.Strings(report.Targets)
+	return report, nil
+}
+
+// findReviewConflicts returns a list of conflicts between addresses touched
+// by a migration and ones touched by a pending migration.
+func findReviewConflicts(filename string, touched []*tfmigrate.TouchedAddress, pending []*tfmigrate.TouchedAddress) []*reviewConflict {
+	conflicts := []*reviewConflict{}
+	seen := make(map[reviewConflict]bool)
+	for _, a := range touched {
+		for _, p := range pending {
+			if !a.Conflicts(p) {
+				continue
+			}
+			c := reviewConflict{
+				Filename:       filename,
+				Target:         a.Target(),
+				Address:        a.Address,
+				PendingAddress: p.Address,
+			}
+			if !seen[c] {
+				seen[c] = true
+				conflicts = append(conflicts, &c)
+			}
+		}
+	}
+	return conflicts
+}
+
+// formatReviewMarkdown returns a report in markdown, which is suitable for a
+// comment on a pull request.
+func formatReviewMarkdown(r *reviewReport) string {
+	code := func(s string) string {
+		if strings.Contains(s, "`") {
+			return "`` " + s + " ``"
+		}
+		return "`" + s + "`"
+	}
+
+	var b strings.Builder
+	b.WriteString("## tfmigrate review\n\n")
+	if len(r.Migrations) == 0 {
+		b.WriteString("No migration files to review.")
+		return b.String()
+	}
+
+	b.WriteString("| Migration | Type | Targets | Actions | Conflicts | Errors | Plan |\n")
+	b.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, m := range r.Migrations {
+		plan := "-"
+		if m.Plan != nil {
+			plan = m.Plan.Status
+		}
+		typ := m.Type
+		if m.Skipped {
+			typ += " (skipped)"
+		}
+		targets := make([]string, 0, len(m.Targets))
+		for _, t := range m.Targets {
+			targets = append(targets, code(t))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %d | %d | %d | %s |\n",
+			m.Filename, typ, strings.Join(targets, "<br>"), len(m.Actions), len(m.Conflicts), len(m.Errors), plan)
+	}
+
+	b.WriteString("\nAffected states:\n\n")
+	for _, t := range r.Targets {
+		fmt.Fprintf(&b, "- %s\n", code(t))
+	}
+
+	for _, m := range r.Migrations {
+		fmt.Fprintf(&b, "\n### %s\n", m.Filename)
+		if len(m.Name) > 0 {
+			fmt.Fprintf(&b, "\nName: %s\n", m.Name)
+		}
+		if m.Skipped {
+			b.WriteString("\nThe migration is skipped by skip_if.\n")
+		}
+
+		if len(m.Actions) > 0 {
+			b.WriteString("\nActions:\n\n")
+			for _, a := range m.Actions {
+				fmt.Fprintf(&b, "- %s\n", code(a))
+			}
+		}
+
+		if len(m.Conflicts) > 0 {
+			b.WriteString("\nConflicts with pending migrations:\n\n")
+			for _, c := range m.Conflicts {
+				fmt.Fprintf(&b, "- %s conflicts with %s in %s of %s\n", code(c.Address), code(c.PendingAddress), code(c.Target), c.Filename)
+			}
+		}
+
+		if len(m.Errors) > 0 {
+			b.WriteString("\nErrors:\n\n")
+			for _, e := range m.Errors {
+				fmt.Fprintf(&b, "- %s\n", e)
+			}
+		}
+
+		if m.Plan != nil {
+			fmt.Fprintf(&b, "\nPlan: %s\n", m.Plan.Status)
+			if len(m.Plan.Actions) > 0 {
+				b.WriteString("\n")
+				for _, a := range m.Plan.Actions {
+					line := fmt.Sprintf("- [%s] %s", a.Status, code(a.Action))
+					if len(a.Error) > 0 {
+						line += ": " + a.Error
+					}
+					b.WriteString(line + "\n")
+				}
+			}
+			if len(m.Plan.Error) > 0 {
+				fmt.Fprintf(&b, "\n```\n%s\n```\n", m.Plan.Error)
+			}
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Help returns long-form help text.
+func (c *ReviewCommand) Help() string {
+	helpText := `
+Usage: tfmigrate review [options] [PATH...]
+
+Report the impact of migration files added or changed in a pull request as a
+single consolidated report, which is intended for CI.
+
+The report contains actions of each migration after loading the file,
+states affected by the migrations, conflicts with pending migrations, errors
+found on validating the migrations such as policy violations, and results of
+plan if --plan is set. Pending migrations are unapplied migrations other than
+the given ones in history mode. Two migrations conflict if they touch the
+same resources or modules in the same state. In non-history mode, conflicts
+are not checked.
+
+It exits with 2 if any migration has conflicts, errors or a failed plan.
+
+Arguments:
+  PATH               A path of migration file relative to the migration
+                     directory. Either PATH or --base is required.
+
+Options:
+  --config           A path to tfmigrate config file
+  --var=NAME=VALUE   Set a variable which migration files can reference as var.NAME.
+  --var-file=path    A path to a variable file such as env = "prod" which sets
+                     variables for migration files. --var takes precedence over it.
+  --base=ref         Review migration files added or changed since a merge
+                     base of a given git ref and HEAD, such as origin/main.
+  --plan             Run plan for each migration and include the results.
+                     Each migration is planned against the current state
+                     independently.
+  --json             Output in JSON format. Default to markdown.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *ReviewCommand) Synopsis() string {
+	return "Report the impact of migrations in a pull request"
+}
@@ -0,0 +1,256 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestChangedMigrationFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("failed to run git %v: %s, out: %s", args, err, out)
+		}
+	}
+	write := func(filename string, source string) {
+		t.Helper()
+		path := filepath.Join(dir, filename)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %s", err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0600); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+	}
+
+	git("init", "-q")
+	git("checkout", "-q", "-b", "main")
+	write("tfmigrate/20201109000001_test1.hcl", "test1")
+	write("tfmigrate/20201109000002_test2.hcl", "test2")
+	git("add", "-A")
+	git("commit", "-q", "-m", "init")
+
+	git("checkout", "-q", "-b", "feature")
+	write("tfmigrate/20201109000002_test2.hcl", "test2 changed")
+	write("tfmigrate/20201109000003_test3.hcl", "test3")
+	write("tfmigrate/20201109000004_test4.json", "test4")
+	write("tfmigrate/.tfmigrate.hcl", "config")
+	write("tfmigrate/README.md", "readme")
+	write("tfmigrate/sub/20201109000005_test5.hcl", "test5")
+	write("other/20201109000006_test6.hcl", "test6")
+	git("add", "-A")
+	git("commit", "-q", "-m", "feature")
+	git("rm", "-q", "tfmigrate/20201109000001_test1.hcl")
+	git("commit", "-q", "-m", "remove")
+
+	got, err := changedMigrationFiles(context.Background(), filepath.Join(dir, "tfmigrate"), "main")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	want := []string{
+		"20201109000002_test2.hcl",
+		"20201109000003_test3.hcl",
+		"20201109000004_test4.json",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+}
+
+func TestReviewMigrations(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": fmt.Sprintf(`
+migration "state" "test1" {
+	dir     = "%s"
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+	]
+}
+`, dir1),
+		"20201109000002_test2.hcl": fmt.Sprintf(`
+migration "state" "test2" {
+	dir     = "%s"
+	actions = [
+		"mv null_resource.bar null_resource.bar2",
+	]
+}
+`, dir1),
+		"20201109000003_test3.hcl": fmt.Sprintf(`
+migration "multi_state" "test3" {
+	from_dir = "%s"
+	to_dir   = "%s"
+	actions = [
+		"mv null_resource.bar null_resource.bar",
+		"mv null_resource.baz null_resource.baz",
+	]
+}
+`, dir1, dir2),
+		"20201109000004_test4.hcl": fmt.Sprintf(`
+migration "state" "test4" {
+	dir     = "%s"
+	force   = true
+	actions = [
+		"rm null_resource.qux",
+	]
+}
+`, dir2),
+	}
+	historyFile := `{
+    "version": 2,
+    "migrations": {
+        "20201109000001_test1.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "test1",
+                "timestamp": "2020-11-10T00:00:01Z"
+            }
+        }
+    }
+}`
+
+	migrationDir := setupMigrationDir(t, migrations)
+	config := &config.TfmigrateConfig{
+		MigrationDir: migrationDir,
+		History: &history.Config{
+			Storage: &mock.Config{
+				Data: historyFile,
+			},
+		},
+		Policy: &tfmigrate.MigrationPolicy{DenyForce: true},
+	}
+
+	filenames := []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl", "20201109000004_test4.hcl"}
+	got, err := reviewMigrations(context.Background(), config, newOption(), filenames, false)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	wantTargets := []string{dir1 + "@default", dir2 + "@default"}
+	if !reflect.DeepEqual(got.Targets, wantTargets) {
+		t.Errorf("got targets: %#v, want: %#v", got.Targets, wantTargets)
+	}
+	if len(got.Migrations) != 3 {
+		t.Fatalf("got %d migrations, want 3", len(got.Migrations))
+	}
+
+	applied := got.Migrations[0]
+	if len(applied.Errors) != 1 || !strings.Contains(applied.Errors[0], "already been applied") {
+		t.Errorf("expected an error for an applied migration, but got: %#v", applied.Errors)
+	}
+
+	conflicted := got.Migrations[1]
+	wantConflicts := []*reviewConflict{
+		{
+			Filename:       "20201109000003_test3.hcl",
+			Target:         dir1 + "@default",
+			Address:        "null_resource.bar",
+			PendingAddress: "null_resource.bar",
+		},
+	}
+	if !reflect.DeepEqual(conflicted.Conflicts, wantConflicts) {
+		t.Errorf("got conflicts: %#v, want: %#v", conflicted.Conflicts, wantConflicts)
+	}
+	if len(conflicted.Errors) != 0 {
+		t.Errorf("unexpected errors: %#v", conflicted.Errors)
+	}
+	wantActions := []string{"mv null_resource.bar null_resource.bar2"}
+	if !reflect.DeepEqual(conflicted.Actions, wantActions) {
+		t.Errorf("got actions: %#v, want: %#v", conflicted.Actions, wantActions)
+	}
+
+	violated := got.Migrations[2]
+	if len(violated.Errors) != 1 || !strings.Contains(violated.Errors[0], "policy violation") {
+		t.Errorf("expected a policy violation, but got: %#v", violated.Errors)
+	}
+	if len(violated.Conflicts) != 0 {
+		t.Errorf("unexpected conflicts: %#v", violated.Conflicts)
+	}
+
+	if !got.hasProblems() {
+		t.Error("expected to have problems, but no problems")
+	}
+}
+
+func TestFormatReviewMarkdown(t *testing.T) {
+	report := &reviewReport{
+		Targets: []string{"dir1@default"},
+		Migrations: []*reviewMigration{
+			{
+				Filename: "20201109000001_test1.hcl",
+				Type:     "state",
+				Name:     "test1",
+				Targets:  []string{"dir1@default"},
+				Actions:  []string{"mv null_resource.foo null_resource.foo2"},
+				Conflicts: []*reviewConflict{
+					{
+						Filename:       "20201109000002_test2.hcl",
+						Target:         "dir1@default",
+						Address:        "null_resource.foo",
+						PendingAddress: "null_resource.foo",
+					},
+				},
+				Errors: []string{},
+				Plan: &reviewPlan{
+					Status: "failed",
+					Error:  "terraform plan command returns unexpected diffs",
+					Actions: []actionSummary{
+						{Action: "mv null_resource.foo null_resource.foo2", Status: "succeeded"},
+					},
+				},
+			},
+		},
+	}
+
+	got := formatReviewMarkdown(report)
+	want := "## tfmigrate review\n" +
+		"\n" +
+		"| Migration | Type | Targets | Actions | Conflicts | Errors | Plan |\n" +
+		"|---|---|---|---|---|---|---|\n" +
+		"| 20201109000001_test1.hcl | state | `dir1@default` | 1 | 1 | 0 | failed |\n" +
+		"\n" +
+		"Affected states:\n" +
+		"\n" +
+		"- `dir1@default`\n" +
+		"\n" +
+		"### 20201109000001_test1.hcl\n" +
+		"\n" +
+		"Name: test1\n" +
+		"\n" +
+		"Actions:\n" +
+		"\n" +
+		"- `mv null_resource.foo null_resource.foo2`\n" +
+		"\n" +
+		"Conflicts with pending migrations:\n" +
+		"\n" +
+		"- `null_resource.foo` conflicts with `null_resource.foo` in `dir1@default` of 20201109000002_test2.hcl\n" +
+		"\n" +
+		"Plan: failed\n" +
+		"\n" +
+		"- [succeeded] `mv null_resource.foo null_resource.foo2`\n" +
+		"\n" +
+		"```\n" +
+		"terraform plan command returns unexpected diffs\n" +
+		"```"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,269 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// RollbackCommand is a command which rolls back a migration by applying its
+// inverse migration.
+type RollbackCommand struct {
+	Meta
+	backendConfig  []string
+	planOnly       bool
+	overrideWindow bool
+}
+
+// Run runs the procedure of this command.
+func (c *RollbackCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
+	cmdFlags.StringArrayVar(&c.backendConfig, "backend-config", nil, "A backend configuration for remote state")
+	cmdFlags.BoolVar(&c.planOnly, "plan-only", false, "Plan the rollback without applying it")
+	cmdFlags.BoolVar(&c.overrideWindow, "override-window", false, "Roll back even if it's outside of apply windows")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+	migrationFile := cmdFlags.Arg(0)
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	c.Option.BackendConfig = c.backendConfig
+	// The option may contain sensitive values such as environment variables.
+	// So logging the option set log level to DEBUG instead of INFO.
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	planOnly := c.planOnly
+	exitCode := 0
+	if !planOnly && !c.config.ApplyWindows.Contains(time.Now()) {
+		if c.overrideWindow {
+			log.Printf("[WARN] [command] override apply windows: %v\n", c.config.ApplyWindows)
+		} else {
+			c.UI.Warn(fmt.Sprintf("It's outside of apply windows: %v. Plan only without rolling back. Use --override-window to roll back anyway.", c.config.ApplyWindows))
+			planOnly = true
+			exitCode = exitCodeOutsideWindow
+		}
+	}
+
+	if !planOnly {
+		if err := setApplyCredentials(applyCredentials(os.Environ()), c.config.ReadOnlyPlan); err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
+
+	ctx := context.Background()
+	rollback, err := rollbackMigration(ctx, c.config, c.Option, migrationFile, planOnly)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if rollback != nil {
+		c.UI.Output(fmt.Sprintf("Rollback actions of %s:", migrationFile))
+		for _, action := range rollbackActions(rollback) {
+			c.UI.Output("  " + action)
+		}
+	}
+
+	return exitCode
+}
+
+// rollbackMigration plans or applies an inverse migration of a given
+// migration file, and returns the inverse migration.
+// In history mode, the migration must have been applied and no other applied
+// migrations may depend on it. Once the rollback is applied, its record is
+// deleted from history. A migration skipped by its condition has nothing to
+// roll back, so that it returns nil and only deletes its record. Rolling back
+// requires the same approvals as applying the migration.
+func rollbackMigration(ctx context.Context, config *config.TfmigrateConfig, option *tfmigrate.MigratorOption, filename string, planOnly bool) (*tfmigrate.MigrationConfig, error) {
+	path := resolveMigrationFile(config.MigrationDir, filename)
+	log.Printf("[INFO] [command] load migration file: %s\n", path)
+	mc, err := loadMigrationFile(path, config.MigrationFileOption())
+	if err != nil {
+		return nil, err
+	}
+
+	var hc *history.Controller
+	skipped := mc.Skip
+	if config.History != nil {
+		hc, err = history.NewController(ctx, config.MigrationDir, config.History)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := hc.Record(filename)
+		if !ok {
+			return nil, fmt.Errorf("a migration which has not been applied cannot be rolled back: %s", filename)
+		}
+		if err := checkRollbackDependents(config, hc, filename); err != nil {
+			return nil, err
+		}
+		skipped = skipped || r.Skipped
+
+		if !planOnly {
+			applier, err := resolveApplier(config)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkApprovals(config, hc, filename, mc, applier); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var rollback *tfmigrate.MigrationConfig
+	if skipped {
+		log.Printf("[INFO] [command] nothing to roll back for a skipped migration: %s\n", filename)
+	} else {
+		rollback, err = tfmigrate.RollbackMigration(mc)
+		if err != nil {
+			return nil, err
+		}
+
+		fr, err := newFileRunner(filename, config, rollback, option)
+		if err != nil {
+			return nil, err
+		}
+		fr.rollback = true
+		// Stamps of the migration are left as they are, because the inverse
+		// migration doesn't know which resources were stamped.
+		fr.stamp = nil
+
+		if planOnly {
+			return rollback, fr.Plan(ctx)
+		}
+		if err := fr.Apply(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if hc == nil || planOnly {
+		return rollback, nil
+	}
+
+	hc.DeleteRecord(filename)
+	log.Printf("[INFO] [command] delete history record: %s\n", filename)
+	if err := hc.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return rollback, nil
+}
+
+// checkRollbackDependents returns an error if any applied migrations depend
+// on a given migration, because rolling it back breaks their assumption.
+func checkRollbackDependents(config *config.TfmigrateConfig, hc *history.Controller, filename string) error {
+	dependents := []string{}
+	for _, m := range hc.Migrations() {
+		if m == filename || !hc.AlreadyApplied(m) {
+			continue
+		}
+		mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, m), config.MigrationFileOption())
+		if err != nil {
+			return err
+		}
+		if slices.Contains(mc.DependsOn, filename) {
+			dependents = append(dependents, m)
+		}
+	}
+
+	if len(dependents) > 0 {
+		return fmt.Errorf("the migration cannot be rolled back, because applied migrations depend on it: %s, roll back them first: %s", filename, strings.Join(dependents, ", "))
+	}
+	return nil
+}
+
+// rollbackActions returns a list of actions of a given inverse migration.
+func rollbackActions(mc *tfmigrate.MigrationConfig) []string {
+	switch m := mc.Migrator.(type) {
+	case *tfmigrate.StateMigratorConfig:
+		return m.Actions
+	case *tfmigrate.MultiStateMigratorConfig:
+		return m.Actions
+	default:
+		return nil
+	}
+}
+
+// Help returns long-form help text.
+func (c *RollbackCommand) Help() string {
+	helpText := `
+Usage: tfmigrate rollback [options] PATH
+
+Rollback plans and applies an inverse migration which undoes a given
+migration. The inverse actions are computed from actions of the migration in
+reverse order. mv is reversed by swapping its arguments, and xmv is
+reversed by swapping its pattern and references. A multi_state migration
+moves resources from to_dir back to from_dir. The other actions such as rm,
+import and replace-provider have no exact inverse, so that the migration is
+rejected unless rollback_actions is set in the migration file to define
+inverse actions explicitly. For example, swapping the arguments of
+replace-provider would also change resources which already used the new
+provider before the migration.
+
+In history mode, the migration must have been applied, and applied migrations
+which depend on it must be rolled back first. Rolling back requires the same
+approvals as applying the migration, that is, required_approvals in the
+history block and approved_by in the ownership block. After rolling back,
+the record of the migration is deleted from history, so that the next apply
+will apply the migration again unless the migration file is removed or fixed.
+
+Environment variables prefixed with TFMIGRATE_APPLY_ENV_ are elevated
+credentials as well as apply.
+
+Arguments
+  PATH                     A path of migration file
+
+Options:
+  --config                 A path to tfmigrate config file
+  --var=NAME=VALUE         Set a variable which migration files can reference as var.NAME.
+  --var-file=path          A path to a variable file such as env = "prod" which sets
+                           variables for migration files. --var takes precedence over it.
+  --backend-config=path    A backend configuration, a path to backend configuration file or
+                           key=value format backend configuraion.
+                           This option is passed to terraform init when switching backend to remote.
+  --plan-only              Plan the rollback without applying it and updating history.
+  --override-window        Roll back even if it's outside of apply windows defined in the config file.
+                           Intended for emergencies.
+
+Exit status:
+  0                        Rolled back successfully.
+  1                        An error occurred.
+  3                        Planned only without rolling back, because it's outside of apply windows.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *RollbackCommand) Synopsis() string {
+	return "Roll back a migration by applying its inverse"
+}
@@ -0,0 +1,162 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+)
+
+func TestRollbackMigration(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+		"20201109000003_test3.hcl": `
+migration "mock" "test3" {
+	plan_error  = false
+	apply_error = true
+}
+`,
+		"20201109000004_test4.hcl": `
+migration "mock" "test4" {
+	depends_on  = ["20201109000002_test2.hcl"]
+	plan_error  = false
+	apply_error = false
+}
+`,
+		"20201109000005_test5.hcl": `
+migration "mock" "test5" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	historyFile := `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        },
+        "20201109000002_test2.hcl": {
+            "type": "mock",
+            "name": "test2",
+            "applied_at": "2020-11-10T00:00:02Z"
+        },
+        "20201109000003_test3.hcl": {
+            "type": "mock",
+            "name": "test3",
+            "applied_at": "2020-11-10T00:00:03Z"
+        },
+        "20201109000004_test4.hcl": {
+            "type": "mock",
+            "name": "test4",
+            "applied_at": "2020-11-10T00:00:04Z"
+        }
+    }
+}`
+
+	cases := []struct {
+		desc              string
+		filename          string
+		planOnly          bool
+		requiredApprovals int
+		applied           bool
+		ok                bool
+	}{
+		{
+			desc:     "rollback",
+			filename: "20201109000001_test1.hcl",
+			applied:  false,
+			ok:       true,
+		},
+		{
+			desc:     "plan only",
+			filename: "20201109000001_test1.hcl",
+			planOnly: true,
+			applied:  true,
+			ok:       true,
+		},
+		{
+			desc:     "apply error",
+			filename: "20201109000003_test3.hcl",
+			applied:  true,
+			ok:       false,
+		},
+		{
+			desc:     "depended by applied migration",
+			filename: "20201109000002_test2.hcl",
+			applied:  true,
+			ok:       false,
+		},
+		{
+			desc:              "unapproved rollback",
+			filename:          "20201109000001_test1.hcl",
+			requiredApprovals: 1,
+			applied:           true,
+			ok:                false,
+		},
+		{
+			desc:              "plan unapproved rollback",
+			filename:          "20201109000001_test1.hcl",
+			planOnly:          true,
+			requiredApprovals: 1,
+			applied:           true,
+			ok:                true,
+		},
+		{
+			desc:     "not applied",
+			filename: "20201109000005_test5.hcl",
+			applied:  false,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("TFMIGRATE_APPROVER", "bob")
+			migrationDir := setupMigrationDir(t, migrations)
+			mockConfig := &mock.Config{
+				Data:       historyFile,
+				WriteError: false,
+				ReadError:  false,
+			}
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage:           mockConfig,
+					RequiredApprovals: tc.requiredApprovals,
+				},
+			}
+
+			_, err := rollbackMigration(context.Background(), config, nil, tc.filename, tc.planOnly)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			h, err := history.ParseHistoryFile([]byte(mockConfig.Storage().Data()))
+			if err != nil {
+				t.Fatalf("failed to parse history file: %s", err)
+			}
+			if got := h.Contains(tc.filename); got != tc.applied {
+				t.Errorf("applied got = %t, want = %t", got, tc.applied)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// SquashCommand is a command which merges migrations into a single one.
+type SquashCommand struct {
+	Meta
+	name   string
+	out    string
+	record bool
+}
+
+// Run runs the procedure of this command.
+func (c *SquashCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("squash", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
+	cmdFlags.StringVar(&c.name, "name", "squashed", "A name of the squashed migration")
+	cmdFlags.StringVar(&c.out, "out", "", "Write the squashed migration to the given path")
+	cmdFlags.BoolVar(&c.record, "record", false, "Record the squashed migration as applied in history")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) < 2 {
+		c.UI.Error(fmt.Sprintf("The command expects at least 2 arguments, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	if c.record && len(c.out) == 0 {
+		c.UI.Error("--record requires --out")
+		return 1
+	}
+
+	mc, filenames, err := squashMigrationFiles(c.config, cmdFlags.Args(), c.name)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	b, err := config.FormatMigrationFile(mc)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	ctx := context.Background()
+	var hc *history.Controller
+	if c.config.History != nil {
+		hc, err = history.NewController(ctx, c.config.MigrationDir, c.config.History)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
+	applied, err := squashedApplied(hc, filenames)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	if c.record && !applied {
+		c.UI.Error("--record requires all the given migrations to have been applied")
+		return 1
+	}
+	if c.record && filepath.Clean(filepath.Dir(c.out)) != filepath.Clean(c.config.MigrationDir) {
+		c.UI.Error(fmt.Sprintf("--record requires --out in the migration directory: %s", c.config.MigrationDir))
+		return 1
+	}
+	if applied && !c.record {
+		c.UI.Warn("All the given migrations have been applied, but the squashed migration is not recorded in history. Use --record to avoid applying it again.")
+	}
+
+	if len(c.out) == 0 {
+		c.UI.Output(strings.TrimSuffix(string(b), "\n"))
+		return 0
+	}
+
+	if err := os.WriteFile(c.out, b, 0644); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to write squashed migration: %s", err))
+		return 1
+	}
+
+	if c.record {
+		hc.AddRecord(filepath.Base(c.out), mc.Type, mc.Name, nil, nil)
+		if err := hc.Save(ctx); err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// squashMigrationFiles loads given migration files and returns a squashed
+// migration and a list of file names relative to the migration directory.
+// Dependencies between the given files are dropped.
+func squashMigrationFiles(config *config.TfmigrateConfig, paths []string, name string) (*tfmigrate.MigrationConfig, []string, error) {
+	filenames := make([]string, 0, len(paths))
+	mcs := make([]*tfmigrate.MigrationConfig, 0, len(paths))
+	for _, path := range paths {
+		mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, path), config.MigrationFileOption())
+		if err != nil {
+			return nil, nil, err
+		}
+		mcs = append(mcs, mc)
+		filenames = append(filenames, filepath.Base(path))
+	}
+
+	squashed, err := tfmigrate.SquashStateMigrations(mcs, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, filename := range filenames {
+		seen[filename] = true
+	}
+	for _, mc := range mcs {
+		for _, dep := range mc.DependsOn {
+			if !seen[dep] {
+				seen[dep] = true
+				squashed.DependsOn = append(squashed.DependsOn, dep)
+			}
+		}
+	}
+
+	return squashed, filenames, nil
+}
+
+// squashedApplied returns true if all given migration files have been applied.
+// It returns an error if some of them have been applied but others have not,
+// because their actions cannot be squashed consistently.
+// It always returns false in non-history mode.
+func squashedApplied(hc *history.Controller, filenames []string) (bool, error) {
+	if hc == nil {
+		return false, nil
+	}
+
+	applied := []string{}
+	for _, filename := range filenames {
+		if hc.AlreadyApplied(filename) {
+			applied = append(applied, filename)
+		}
+	}
+	if len(applied) > 0 && len(applied) < len(filenames) {
+		return false, fmt.Errorf("failed to squash applied and unapplied migrations together: applied: %s", strings.Join(applied, ", "))
+	}
+	return len(applied) > 0, nil
+}
+
+// Help returns long-form help text.
+func (c *SquashCommand) Help() string {
+	helpText := `
+Usage: tfmigrate squash [options] PATH...
+
+Merge a series of state migrations working with the same directory into a
+single equivalent migration, to keep a long-lived migration directory
+manageable. Migrations are squashed in the given order.
+
+Chained mv actions such as A to B and B to C are collapsed into A to C,
+rm actions for resources moved or imported in the migrations are folded into
+them, and duplicate rm actions are dropped. The xmv, replace-provider and
+plugin actions are kept as they are, and no actions are folded across them.
+Migrations with different dir, workspace, env, to_skip_plan,
+expect_no_changes, allow or owner cannot be squashed together, and neither
+can multi_state migrations.
+
+In history mode, the given migrations must be either all applied or all
+unapplied. The squashed migration is a new migration which has not been
+applied yet. When squashing applied migrations, use --record to record it as
+applied, and then you can remove the original files.
+
+Arguments:
+  PATH               A path of migration file
+
+Options:
+  --config           A path to tfmigrate config file
+  --var=NAME=VALUE   Set a variable which migration files can reference as var.NAME.
+  --var-file=path    A path to a variable file such as env = "prod" which sets
+                     variables for migration files. --var takes precedence over it.
+  --name             A name of the squashed migration.
+                     Default to squashed.
+  --out=path         Write the squashed migration to the given path.
+                     Default to stdout.
+  --record           Record the squashed migration as applied in history.
+                     It requires --out in the migration directory and all
+                     the given migrations to have been applied.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *SquashCommand) Synopsis() string {
+	return "Merge migrations into a single one"
+}
@@ -0,0 +1,156 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+)
+
+func TestSquashMigrationFiles(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "state" "test1" {
+	dir        = "dir1"
+	depends_on = ["20201108000001_base.hcl"]
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+		"rm null_resource.bar",
+	]
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "state" "test2" {
+	dir        = "dir1"
+	depends_on = ["20201109000001_test1.hcl"]
+	actions = [
+		"mv null_resource.foo2 null_resource.foo3",
+		"rm null_resource.bar null_resource.baz",
+	]
+}
+`,
+		"20201109000003_test3.hcl": `
+migration "state" "test3" {
+	dir     = "dir2"
+	actions = [
+		"rm null_resource.qux",
+	]
+}
+`,
+	}
+	historyFile := `{
+    "version": 2,
+    "migrations": {
+        "20201109000001_test1.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "test1",
+                "timestamp": "2020-11-10T00:00:01Z"
+            }
+        }
+    }
+}`
+
+	cases := []struct {
+		desc    string
+		paths   []string
+		history bool
+		want    string
+		applied bool
+		ok      bool
+	}{
+		{
+			desc:  "non-history mode",
+			paths: []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl"},
+			want: `migration "state" "squashed" {
+  depends_on = [
+    "20201108000001_base.hcl",
+  ]
+  dir = "dir1"
+  actions = [
+    "mv null_resource.foo null_resource.foo3",
+    "rm null_resource.bar",
+    "rm null_resource.baz",
+  ]
+}
+`,
+			applied: false,
+			ok:      true,
+		},
+		{
+			desc:    "applied",
+			paths:   []string{"20201109000001_test1.hcl"},
+			history: true,
+			want: `migration "state" "squashed" {
+  depends_on = [
+    "20201108000001_base.hcl",
+  ]
+  dir = "dir1"
+  actions = [
+    "mv null_resource.foo null_resource.foo2",
+    "rm null_resource.bar",
+  ]
+}
+`,
+			applied: true,
+			ok:      true,
+		},
+		{
+			desc:    "applied and unapplied",
+			paths:   []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl"},
+			history: true,
+			ok:      false,
+		},
+		{
+			desc:  "different dirs",
+			paths: []string{"20201109000002_test2.hcl", "20201109000003_test3.hcl"},
+			ok:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, migrations)
+			tfmigrateConfig := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+			}
+
+			mc, filenames, err := squashMigrationFiles(tfmigrateConfig, tc.paths, "squashed")
+			var hc *history.Controller
+			if err == nil && tc.history {
+				hc, err = history.NewController(context.Background(), migrationDir, &history.Config{
+					Storage: &mock.Config{
+						Data: historyFile,
+					},
+				})
+				if err != nil {
+					t.Fatalf("failed to new history controller: %s", err)
+				}
+			}
+			var applied bool
+			if err == nil {
+				applied, err = squashedApplied(hc, filenames)
+			}
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				b, err := config.FormatMigrationFile(mc)
+				if err != nil {
+					t.Fatalf("failed to format migration file: %s", err)
+				}
+				if string(b) != tc.want {
+					t.Errorf("got: %s, want: %s", string(b), tc.want)
+				}
+				if applied != tc.applied {
+					t.Errorf("got applied = %t, want = %t", applied, tc.applied)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// stateDiffContext is a number of unchanged lines shown around changes in a
+// unified diff of state addresses.
+const stateDiffContext = 3
+
+// diffLine is a line of a unified diff.
+type diffLine struct {
+	// op is one of ' ', '-' and '+'.
+	op   byte
+	text string
+}
+
+// formatStateDiff returns a unified diff of addresses in a state before and
+// after a given migration file. Addresses are sorted, so that a moved address
+// appears as a pair of removed and added lines.
+func formatStateDiff(filename string, d tfmigrate.StateDiff) string {
+	name := d.Dir + "@" + d.Workspace
+	lines := diffSortedLines(sortedCopy(d.Before), sortedCopy(d.After))
+	hunks := diffHunks(lines, stateDiffContext)
+	if len(hunks) == 0 {
+		return fmt.Sprintf("No changes in %s by %s", name, filename)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s (current state)\n", name)
+	fmt.Fprintf(&b, "+++ %s (after %s)\n", name, filename)
+	for _, h := range hunks {
+		b.WriteString(h)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sortedCopy returns a sorted copy of a given list.
+func sortedCopy(s []string) []string {
+	c := append([]string{}, s...)
+	sort.Strings(c)
+	return c
+}
+
+// diffSortedLines returns diff lines of given sorted lists.
+// A minimal diff of sorted lists can be computed by merging them.
+func diffSortedLines(a []string, b []string) []diffLine {
+	lines := []diffLine{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{op: ' ', text: a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			lines = append(lines, diffLine{op: '-', text: a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{op: '+', text: b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// diffHunks returns hunks of a unified diff for given diff lines with a given
+// number of context lines. Hunks whose contexts overlap are merged.
+func diffHunks(lines []diffLine, context int) []string {
+	hunks := []string{}
+	for start := 0; start < len(lines); {
+		// find the next change.
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// extend the hunk while the contexts of the next change overlap.
+		last := first
+		for k := first + 1; k < len(lines) && k <= last+2*context+1; k++ {
+			if lines[k].op != ' ' {
+				last = k
+			}
+		}
+
+		from := max(first-context, 0)
+		to := min(last+context+1, len(lines))
+		hunks = append(hunks, formatHunk(lines, from, to))
+		start = to
+	}
+	return hunks
+}
+
+// formatHunk returns a hunk of a unified diff for lines in [from, to).
+func formatHunk(lines []diffLine, from int, to int) string {
+	// count line numbers before the hunk.
+	aStart, bStart := 0, 0
+	for _, l := range lines[:from] {
+		if l.op != '+' {
+			aStart++
+		}
+		if l.op != '-' {
+			bStart++
+		}
+	}
+
+	var body strings.Builder
+	aCount, bCount := 0, 0
+	for _, l := range lines[from:to] {
+		if l.op != '+' {
+			aCount++
+		}
+		if l.op != '-' {
+			bCount++
+		}
+		body.WriteByte(l.op)
+		body.WriteString(l.text)
+		body.WriteByte('\n')
+	}
+
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(aStart, aCount), hunkRange(bStart, bCount), body.String())
+}
+
+// hunkRange returns a range of lines in a hunk header.
+// Line numbers start from 1, but an empty range refers to the line before it.
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestFormatStateDiff(t *testing.T) {
+	cases := []struct {
+		desc string
+		diff tfmigrate.StateDiff
+		want string
+	}{
+		{
+			desc: "no changes",
+			diff: tfmigrate.StateDiff{
+				Dir:       "dir1",
+				Workspace: "default",
+				Before:    []string{"null_resource.foo"},
+				After:     []string{"null_resource.foo"},
+			},
+			want: "No changes in dir1@default by mv.hcl",
+		},
+		{
+			desc: "mv",
+			diff: tfmigrate.StateDiff{
+				Dir:       "dir1",
+				Workspace: "default",
+				Before:    []string{"null_resource.foo", "null_resource.bar"},
+				After:     []string{"null_resource.foo2", "null_resource.bar"},
+			},
+			want: `
+--- dir1@default (current state)
++++ dir1@default (after mv.hcl)
+@@ -1,2 +1,2 @@
+ null_resource.bar
+-null_resource.foo
++null_resource.foo2
+`,
+		},
+		{
+			desc: "new workspace",
+			diff: tfmigrate.StateDiff{
+				Dir:       "dir2",
+				Workspace: "foo",
+				Before:    []string{},
+				After:     []string{"null_resource.foo"},
+			},
+			want: `
+--- dir2@foo (current state)
++++ dir2@foo (after mv.hcl)
+@@ -0,0 +1,1 @@
++null_resource.foo
+`,
+		},
+		{
+			desc: "multiple hunks",
+			diff: tfmigrate.StateDiff{
+				Dir:       "dir1",
+				Workspace: "default",
+				Before:    []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+				After:     []string{"a2", "b", "c", "d", "e", "f", "g", "h", "i", "j"},
+			},
+			want: `
+--- dir1@default (current state)
++++ dir1@default (after mv.hcl)
+@@ -1,4 +1,4 @@
+-a
++a2
+ b
+ c
+ d
+@@ -8,4 +8,3 @@
+ h
+ i
+ j
+-k
+`,
+		},
+		{
+			desc: "merge close hunks",
+			diff: tfmigrate.StateDiff{
+				Dir:       "dir1",
+				Workspace: "default",
+				Before:    []string{"a", "b", "c", "d", "e", "f", "g", "h"},
+				After:     []string{"b", "c", "d", "e", "f", "g"},
+			},
+			want: `
+--- dir1@default (current state)
++++ dir1@default (after mv.hcl)
+@@ -1,8 +1,6 @@
+-a
+ b
+ c
+ d
+ e
+ f
+ g
+-h
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := formatStateDiff("mv.hcl", tc.diff)
+			want := strings.Trim(tc.want, "\n")
+			if got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	// exitCodeStatusPending is an exit code of status command which indicates
+	// that there are pending migrations.
+	exitCodeStatusPending = 2
+	// exitCodeStatusStorageError is an exit code of status command which
+	// indicates that the history storage is not accessible.
+	exitCodeStatusStorageError = 3
+	// exitCodeStatusBackendError is an exit code of status command which
+	// indicates that a backend of pending migrations is not reachable.
+	exitCodeStatusBackendError = 4
+)
+
+// StatusCommand is a command which checks whether migrations can be applied
+// and whether there are pending migrations.
+type StatusCommand struct {
+	Meta
+	backendConfig []string
+	json          bool
+	skipBackends  bool
+}
+
+// Run runs the procedure of this command.
+func (c *StatusCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("status", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", "", "A path to tfmigrate config file")
+	c.addVariableFlags(cmdFlags)
+	cmdFlags.StringArrayVar(&c.backendConfig, "backend-config", nil, "A backend configuration for remote state")
+	cmdFlags.BoolVar(&c.json, "json", false, "Output in JSON format")
+	cmdFlags.BoolVar(&c.skipBackends, "skip-backends", false, "Skip checking backends of pending migrations")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	if err := c.setVariables(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	c.Option.BackendConfig = c.backendConfig
+	if len(c.Option.ExecPath) == 0 {
+		c.Option.ExecPath = c.config.ExecPath
+	}
+	c.Option.ExecMode = c.config.ExecMode
+	c.Option.IsBackendTerraformCloud = c.config.IsBackendTerraformCloud
+	c.Option.Retry = c.config.Retry
+	c.Option.LockOptions = c.config.LockOptions
+	// The option may contains sensitive values such as environment variables.
+	// So logging the option set log level to DEBUG instead of INFO.
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	if c.config.History == nil {
+		// non-history mode
+		c.UI.Error("no history setting")
+		return 1
+	}
+
+	checker := &statusChecker{
+		terraformVersion: func(ctx context.Context) (string, error) {
+			return tfmigrate.CheckTerraformVersion(ctx, c.Option)
+		},
+	}
+	if !c.skipBackends {
+		checker.backend = func(ctx context.Context, t *tfmigrate.BackendTarget) error {
+			return tfmigrate.CheckBackend(ctx, t, c.Option)
+		}
+	}
+
+	ctx := context.Background()
+	r, err := checker.check(ctx, c.config)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.json {
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("failed to encode status in JSON: %s", err))
+			return 1
+		}
+		c.UI.Output(string(b))
+	} else {
+		c.UI.Output(r.String())
+	}
+
+	return r.exitCode()
+}
+
+// statusChecker checks status of components required to apply migrations.
+// The checks which run terraform are injectable for testing.
+type statusChecker struct {
+	// terraformVersion returns a version of the terraform command, and an
+	// error if it's not supported.
+	terraformVersion func(ctx context.Context) (string, error)
+	// backend checks that a backend of a given target is reachable.
+	// If nil, backends are not checked.
+	backend func(ctx context.Context, t *tfmigrate.BackendTarget) error
+}
+
+// statusReport is a result of checking status.
+type statusReport struct {
+	// TerraformVersion is a version of the terraform command.
+	TerraformVersion string `json:"terraform_version"`
+	// TerraformError is an error on checking the terraform command.
+	TerraformError string `json:"terraform_error,omitempty"`
+	// HistoryError is an error on reading the history storage.
+	HistoryError string `json:"history_error,omitempty"`
+	// PendingMigrations is a list of unapplied migration file names.
+	// It's nil if the history storage is not accessible.
+	PendingMigrations []string `json:"pending_migrations"`
+	// Backends is a list of results of checking backends of pending
+	// migrations.
+	Backends []*backendStatus `json:"backends"`
+}
+
+// backendStatus is a result of checking a backend.
+type backendStatus struct {
+	// Target is a working directory and a workspace as "<dir>@<workspace>".
+	Target string `json:"target"`
+	// Error is an error on checking the backend.
+	Error string `json:"error,omitempty"`
+}
+
+// check checks status of components and returns a report.
+// A failure of each check is recorded in the report, and an error is
+// returned only if a pending migration file cannot be loaded.
+func (c *statusChecker) check(ctx context.Context, config *config.TfmigrateConfig) (*statusReport, error) {
+	r := &statusReport{
+		Backends: []*backendStatus{},
+	}
+
+	v, err := c.terraformVersion(ctx)
+	r.TerraformVersion = v
+	if err != nil {
+		r.TerraformError = err.Error()
+	}
+
+	hc, err := history.NewController(ctx, config.MigrationDir, config.History)
+	if err != nil {
+		r.HistoryError = err.Error()
+		return r, nil
+	}
+	r.PendingMigrations = hc.UnappliedMigrations()
+
+	if c.backend == nil {
+		return r, nil
+	}
+
+	checked := make(map[string]bool)
+	for _, filename := range r.PendingMigrations {
+		mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, filename), config.MigrationFileOption())
+		if err != nil {
+			return nil, err
+		}
+		if mc.Skip {
+			continue
+		}
+		for _, t := range mc.BackendTargets() {
+			if checked[t.String()] {
+				continue
+			}
+			checked[t.String()] = true
+
+			s := &backendStatus{Target: t.String()}
+			if err := c.backend(ctx, t); err != nil {
+				s.Error = err.Error()
+			}
+			r.Backends = append(r.Backends, s)
+		}
+	}
+
+	return r, nil
+}
+
+// exitCode returns an exit code of status command for the report.
+// If more than one check fails, the first one in the following order wins:
+// the terraform command, the history storage and backends.
+func (r *statusReport) exitCode() int {
+	if len(r.TerraformError) > 0 {
+		return 1
+	}
+	if len(r.HistoryError) > 0 {
+		return exitCodeStatusStorageError
+	}
+	for _, b := range r.Backends {
+		if len(b.Error) > 0 {
+			return exitCodeStatusBackendError
+		}
+	}
+	if len(r.PendingMigrations) > 0 {
+		return exitCodeStatusPending
+	}
+	return 0
+}
+
+// String returns a human-readable report.
+func (r *statusReport) String() string {
+	var b strings.Builder
+	switch {
+	case len(r.TerraformError) > 0:
+		fmt.Fprintf(&b, "terraform: error: %s\n", r.TerraformError)
+	default:
+		fmt.Fprintf(&b, "terraform: %s\n", r.TerraformVersion)
+	}
+
+	if len(r.HistoryError) > 0 {
+		fmt.Fprintf(&b, "history storage: error: %s\n", r.HistoryError)
+		return strings.TrimSpace(b.String())
+	}
+	fmt.Fprintf(&b, "history storage: ok\n")
+
+	for _, s := range r.Backends {
+		if len(s.Error) > 0 {
+			fmt.Fprintf(&b, "backend %s: error: %s\n", s.Target, s.Error)
+			continue
+		}
+		fmt.Fprintf(&b, "backend %s: ok\n", s.Target)
+	}
+
+	fmt.Fprintf(&b, "pending migrations: %d\n", len(r.PendingMigrations))
+	for _, filename := range r.PendingMigrations {
+		fmt.Fprintf(&b, "  %s\n", filename)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Help returns long-form help text.
+func (c *StatusCommand) Help() string {
+	helpText := `
+Usage: tfmigrate status [options]
+
+Check whether migrations can be applied, and count pending migrations.
+It checks the version of the terraform command, access to the history
+storage, and reachability of backends of pending migrations with
+terraform init and terraform workspace list, which never modify states.
+This command requires history mode.
+
+Options:
+  --config           A path to tfmigrate config file
+  --var=NAME=VALUE   Set a variable which migration files can reference as var.NAME.
+  --var-file=path    A path to a variable file such as env = "prod" which sets
+                     variables for migration files. --var takes precedence over it.
+  --backend-config=path
+                     A backend configuration, a path to backend configuration file or
+                     key=value format backend configuraion.
+                     This option is passed to terraform init when checking backends.
+                     from_backend_config and to_backend_config in a migration file
+                     take precedence over it.
+  --json             Output in JSON format
+  --skip-backends    Skip checking backends of pending migrations,
+                     which requires terraform init in each working directory.
+
+Exit status:
+  0                  No pending migrations.
+  1                  An error occurred, or the terraform command is not supported.
+  2                  There are pending migrations.
+  3                  The history storage is not accessible.
+  4                  A backend of pending migrations is not reachable.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *StatusCommand) Synopsis() string {
+	return "Check if migrations are pending and can be applied"
+}
@@ -0,0 +1,163 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestStatusCheckerCheck(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "state" "test1" {
+  dir     = "dir1"
+  actions = ["rm null_resource.foo"]
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "multi_state" "test2" {
+  from_dir = "dir1"
+  to_dir   = "dir2"
+  actions  = ["mv null_resource.foo null_resource.foo"]
+}
+`,
+		"20201109000003_test3.hcl": `
+migration "state" "test3" {
+  dir     = "dir3"
+  skip_if = true
+  actions = ["rm null_resource.foo"]
+}
+`,
+	}
+	appliedAll := `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "state",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        },
+        "20201109000002_test2.hcl": {
+            "type": "multi_state",
+            "name": "test2",
+            "applied_at": "2020-11-10T00:00:02Z"
+        },
+        "20201109000003_test3.hcl": {
+            "type": "state",
+            "name": "test3",
+            "applied_at": "2020-11-10T00:00:03Z"
+        }
+    }
+}`
+
+	cases := []struct {
+		desc          string
+		historyFile   string
+		readError     bool
+		versionError  error
+		backendErrors map[string]error
+		skipBackends  bool
+		wantPending   []string
+		wantBackends  []*backendStatus
+		wantExitCode  int
+	}{
+		{
+			desc:         "clean",
+			historyFile:  appliedAll,
+			wantPending:  []string{},
+			wantBackends: []*backendStatus{},
+			wantExitCode: 0,
+		},
+		{
+			desc:        "pending",
+			historyFile: "",
+			wantPending: []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl", "20201109000003_test3.hcl"},
+			wantBackends: []*backendStatus{
+				{Target: "dir1@default"},
+				{Target: "dir2@default"},
+			},
+			wantExitCode: exitCodeStatusPending,
+		},
+		{
+			desc:         "skip backends",
+			historyFile:  "",
+			skipBackends: true,
+			wantPending:  []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl", "20201109000003_test3.hcl"},
+			wantBackends: []*backendStatus{},
+			wantExitCode: exitCodeStatusPending,
+		},
+		{
+			desc:        "backend error",
+			historyFile: "",
+			backendErrors: map[string]error{
+				"dir2@default": fmt.Errorf("access denied"),
+			},
+			wantPending: []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl", "20201109000003_test3.hcl"},
+			wantBackends: []*backendStatus{
+				{Target: "dir1@default"},
+				{Target: "dir2@default", Error: "access denied"},
+			},
+			wantExitCode: exitCodeStatusBackendError,
+		},
+		{
+			desc:         "storage error",
+			readError:    true,
+			wantPending:  nil,
+			wantBackends: []*backendStatus{},
+			wantExitCode: exitCodeStatusStorageError,
+		},
+		{
+			desc:         "unsupported terraform",
+			historyFile:  appliedAll,
+			versionError: fmt.Errorf("terraform v0.11.14 is not supported"),
+			wantPending:  []string{},
+			wantBackends: []*backendStatus{},
+			wantExitCode: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, migrations)
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage: &mock.Config{
+						Data:      tc.historyFile,
+						ReadError: tc.readError,
+					},
+				},
+			}
+			checker := &statusChecker{
+				terraformVersion: func(_ context.Context) (string, error) {
+					return "terraform v1.5.7", tc.versionError
+				},
+			}
+			if !tc.skipBackends {
+				checker.backend = func(_ context.Context, t *tfmigrate.BackendTarget) error {
+					return tc.backendErrors[t.String()]
+				}
+			}
+
+			got, err := checker.check(context.Background(), config)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !reflect.DeepEqual(got.PendingMigrations, tc.wantPending) {
+				t.Errorf("got pending: %#v, want: %#v", got.PendingMigrations, tc.wantPending)
+			}
+			if !reflect.DeepEqual(got.Backends, tc.wantBackends) {
+				t.Errorf("got backends: %#v, want: %#v", got.Backends, tc.wantBackends)
+			}
+			if got.exitCode() != tc.wantExitCode {
+				t.Errorf("got exit code: %d, want: %d, report: %s", got.exitCode(), tc.wantExitCode, got)
+			}
+		})
+	}
+}
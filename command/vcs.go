@@ -0,0 +1,64 @@
+package command
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/history"
+)
+
+// detectVCS returns git information of a given directory.
+// It returns nil if git is not available or the directory is not in a git
+// repository, because VCS information is optional for history.
+func detectVCS(ctx context.Context, dir string) *history.VCS {
+	commit, err := runGit(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		log.Printf("[DEBUG] [command] no git commit found in %s: %s\n", dir, err)
+		return nil
+	}
+
+	vcs := &history.VCS{
+		Commit: commit,
+	}
+
+	// For a detached HEAD, git returns HEAD as the branch name.
+	if branch, err := runGit(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		vcs.Branch = branch
+	}
+
+	if remote, err := runGit(ctx, dir, "config", "--get", "remote.origin.url"); err == nil {
+		vcs.Remote = sanitizeRemoteURL(remote)
+	}
+
+	return vcs
+}
+
+// runGit runs a git command in a given directory and returns its trimmed stdout.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// sanitizeRemoteURL removes credentials from a given remote URL not to leak
+// them to history. An scp-like address such as git@github.com:foo/bar.git is
+// returned as it is because it doesn't contain a password.
+func sanitizeRemoteURL(remote string) string {
+	if !strings.Contains(remote, "://") {
+		return remote
+	}
+
+	u, err := url.Parse(remote)
+	if err != nil {
+		// Don't record a URL we cannot sanitize.
+		return ""
+	}
+	u.User = nil
+	return u.String()
+}
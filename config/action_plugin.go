@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// ActionPluginBlock represents a block for an exec-based action plugin in HCL.
+type ActionPluginBlock struct {
+	// Name is an action type used in migration files.
+	Name string `hcl:"name,label"`
+	// Command is a command line to execute the plugin.
+	Command string `hcl:"command"`
+}
+
+// parseActionPluginBlocks parses action_plugin blocks and returns a list of
+// *tfmigrate.ActionPluginConfig.
+func parseActionPluginBlocks(bs []ActionPluginBlock) ([]*tfmigrate.ActionPluginConfig, error) {
+	if len(bs) == 0 {
+		return nil, nil
+	}
+
+	plugins := []*tfmigrate.ActionPluginConfig{}
+	seen := make(map[string]struct{})
+	for _, b := range bs {
+		if len(b.Command) == 0 {
+			return nil, fmt.Errorf("command of action_plugin %s must not be empty", b.Name)
+		}
+		if _, ok := seen[b.Name]; ok {
+			return nil, fmt.Errorf("duplicated action_plugin: %s", b.Name)
+		}
+		seen[b.Name] = struct{}{}
+
+		for _, spec := range tfmigrate.StateActionSpecs() {
+			if spec.Type == b.Name {
+				return nil, fmt.Errorf("action_plugin %s conflicts with the built-in action", b.Name)
+			}
+		}
+
+		plugins = append(plugins, &tfmigrate.ActionPluginConfig{
+			Name:    b.Name,
+			Command: b.Command,
+		})
+	}
+
+	return plugins, nil
+}
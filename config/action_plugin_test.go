@@ -0,0 +1,119 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestParseActionPluginBlocks(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   []*tfmigrate.ActionPluginConfig
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  action_plugin "cmdb_rename" {
+    command = "./bin/cmdb-rename --verbose"
+  }
+  action_plugin "rotate_key" {
+    command = "./bin/rotate-key"
+  }
+}
+`,
+			want: []*tfmigrate.ActionPluginConfig{
+				{
+					Name:    "cmdb_rename",
+					Command: "./bin/cmdb-rename --verbose",
+				},
+				{
+					Name:    "rotate_key",
+					Command: "./bin/rotate-key",
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "no plugin",
+			source: `
+tfmigrate {
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "missing command",
+			source: `
+tfmigrate {
+  action_plugin "cmdb_rename" {
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "empty command",
+			source: `
+tfmigrate {
+  action_plugin "cmdb_rename" {
+    command = ""
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "duplicated",
+			source: `
+tfmigrate {
+  action_plugin "cmdb_rename" {
+    command = "./bin/cmdb-rename"
+  }
+  action_plugin "cmdb_rename" {
+    command = "./bin/cmdb-rename"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "conflicts with built-in action",
+			source: `
+tfmigrate {
+  action_plugin "mv" {
+    command = "./bin/mv"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.ActionPlugins
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
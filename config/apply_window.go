@@ -0,0 +1,32 @@
+package config
+
+import (
+	"github.com/minamijoyo/tfmigrate/window"
+)
+
+// ApplyWindowBlock represents a block for an allowed apply window in HCL.
+type ApplyWindowBlock struct {
+	// Schedule is a cron-like expression which describes the window.
+	Schedule string `hcl:"schedule"`
+	// Timezone is a name of timezone in which the schedule is evaluated.
+	// Default to UTC.
+	Timezone string `hcl:"timezone,optional"`
+}
+
+// parseApplyWindowBlocks parses apply_window blocks and returns window.Windows.
+func parseApplyWindowBlocks(bs []ApplyWindowBlock) (window.Windows, error) {
+	if len(bs) == 0 {
+		return nil, nil
+	}
+
+	ws := window.Windows{}
+	for _, b := range bs {
+		w, err := window.NewWindow(b.Schedule, b.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		ws = append(ws, w)
+	}
+
+	return ws, nil
+}
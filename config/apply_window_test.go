@@ -0,0 +1,102 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseApplyWindowBlocks(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   []string
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  apply_window {
+    schedule = "* 22-23 * * 1-5"
+    timezone = "UTC"
+  }
+  apply_window {
+    schedule = "* * * * 0,6"
+  }
+}
+`,
+			want: []string{
+				`"* 22-23 * * 1-5" (UTC)`,
+				`"* * * * 0,6" (UTC)`,
+			},
+			ok: true,
+		},
+		{
+			desc: "no window",
+			source: `
+tfmigrate {
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "missing schedule",
+			source: `
+tfmigrate {
+  apply_window {
+    timezone = "UTC"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "invalid schedule",
+			source: `
+tfmigrate {
+  apply_window {
+    schedule = "* 24 * * *"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "invalid timezone",
+			source: `
+tfmigrate {
+  apply_window {
+    schedule = "* 22-23 * * 1-5"
+    timezone = "Foo/Bar"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				var got []string
+				for _, w := range config.ApplyWindows {
+					got = append(got, w.String())
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
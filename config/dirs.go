@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DirsBlock represents a block for directory aliases in HCL.
+// Each attribute defines an alias, such as `network = "stacks/prod/network"`.
+// Migration files can reference it as `dirs.network`, so that restructuring
+// the repository layout doesn't require editing historical migration files.
+type DirsBlock struct {
+	// Remain is a body of dirs block.
+	// Attribute names are arbitrary, so we decode it as just attributes.
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// parseDirsBlock parses a dirs block and returns a map of directory aliases.
+func parseDirsBlock(b DirsBlock) (map[string]string, error) {
+	attrs, diags := b.Remain.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	dirs := make(map[string]string, len(attrs))
+	for name, attr := range attrs {
+		v, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		if v.IsNull() || !v.Type().Equals(cty.String) {
+			return nil, fmt.Errorf("directory alias %s must be a string", name)
+		}
+		dir := v.AsString()
+		if len(dir) == 0 {
+			return nil, fmt.Errorf("directory alias %s must not be empty", name)
+		}
+		dirs[name] = dir
+	}
+
+	return dirs, nil
+}
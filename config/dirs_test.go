@@ -0,0 +1,158 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestParseDirsBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   map[string]string
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  dirs {
+    network = "stacks/prod/network"
+    app     = "stacks/prod/app"
+  }
+}
+`,
+			want: map[string]string{
+				"network": "stacks/prod/network",
+				"app":     "stacks/prod/app",
+			},
+			ok: true,
+		},
+		{
+			desc: "no dirs",
+			source: `
+tfmigrate {
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "not a string",
+			source: `
+tfmigrate {
+  dirs {
+    network = ["stacks/prod/network"]
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "empty",
+			source: `
+tfmigrate {
+  dirs {
+    network = ""
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.Dirs
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMigrationFileWithDirs(t *testing.T) {
+	dirs := map[string]string{
+		"network": "stacks/prod/network",
+		"app":     "stacks/prod/app",
+	}
+
+	cases := []struct {
+		desc   string
+		source string
+		want   tfmigrate.MigratorConfig
+		ok     bool
+	}{
+		{
+			desc: "state",
+			source: `
+migration "state" "test" {
+  dir     = dirs.network
+  actions = ["rm null_resource.foo"]
+}
+`,
+			want: &tfmigrate.StateMigratorConfig{
+				Dir:     "stacks/prod/network",
+				Actions: []string{"rm null_resource.foo"},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi_state with interpolation",
+			source: `
+migration "multi_state" "test" {
+  from_dir = dirs.network
+  to_dir   = "${dirs.app}/sub"
+  actions  = ["mv null_resource.foo null_resource.foo"]
+}
+`,
+			want: &tfmigrate.MultiStateMigratorConfig{
+				FromDir: "stacks/prod/network",
+				ToDir:   "stacks/prod/app/sub",
+				Actions: []string{"mv null_resource.foo null_resource.foo"},
+			},
+			ok: true,
+		},
+		{
+			desc: "undefined alias",
+			source: `
+migration "state" "test" {
+  dir     = dirs.foo
+  actions = ["rm null_resource.foo"]
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseMigrationFileWithOption("test.hcl", []byte(tc.source), &MigrationFileOption{Dirs: dirs})
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if !reflect.DeepEqual(got.Migrator, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got.Migrator, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/minamijoyo/tfmigrate/storage/encryption"
+)
+
+// EncryptionBlock represents a block for client-side encryption of history in HCL.
+type EncryptionBlock struct {
+	// Type is a type for key provider.
+	// Valid values are as follows:
+	// - key
+	// - kms
+	// - age
+	Type string `hcl:"type,label"`
+	// AllowPlaintextMigration is a flag to accept a plain history file, so
+	// that encryption can be enabled for an existing history.
+	AllowPlaintextMigration bool `hcl:"allow_plaintext_migration,optional"`
+	// Remain is a body of encryption block.
+	// We first decode only a block header and then decode schema depending on
+	// its type label.
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// parseEncryptionBlock parses an encryption block and returns an encryption.Config.
+func parseEncryptionBlock(b EncryptionBlock) (encryption.Config, error) {
+	var config encryption.Config
+	switch b.Type {
+	case "key":
+		config = &encryption.KeyConfig{}
+
+	case "kms":
+		config = &encryption.KMSConfig{}
+
+	case "age":
+		config = &encryption.AgeConfig{}
+
+	default:
+		return nil, fmt.Errorf("unknown history encryption type: %s", b.Type)
+	}
+
+	diags := gohcl.DecodeBody(b.Remain, nil, config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return config, nil
+}
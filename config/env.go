@@ -0,0 +1,256 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/azurerm"
+	"github.com/minamijoyo/tfmigrate/storage/consul"
+	"github.com/minamijoyo/tfmigrate/storage/etcd"
+	"github.com/minamijoyo/tfmigrate/storage/gcs"
+	"github.com/minamijoyo/tfmigrate/storage/git"
+	"github.com/minamijoyo/tfmigrate/storage/local"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+	"github.com/minamijoyo/tfmigrate/storage/s3"
+	"github.com/minamijoyo/tfmigrate/storage/tfc"
+)
+
+const (
+	// EnvMigrationDir overrides migration_dir in the config file.
+	EnvMigrationDir = "TFMIGRATE_MIGRATION_DIR"
+	// EnvProject overrides project in the config file.
+	EnvProject = "TFMIGRATE_PROJECT"
+	// EnvIsBackendTerraformCloud overrides is_backend_terraform_cloud in the
+	// config file.
+	EnvIsBackendTerraformCloud = "TFMIGRATE_IS_BACKEND_TERRAFORM_CLOUD"
+	// EnvHistoryRequiredApprovals overrides required_approvals in the history
+	// block.
+	EnvHistoryRequiredApprovals = "TFMIGRATE_HISTORY_REQUIRED_APPROVALS"
+	// EnvHistoryStorageType overrides a type of the storage block.
+	EnvHistoryStorageType = "TFMIGRATE_HISTORY_STORAGE_TYPE"
+	// EnvHistoryStoragePrefix is a prefix of environment variables which
+	// override attributes of the storage block. The rest of the name is an
+	// attribute name in upper case such as TFMIGRATE_HISTORY_STORAGE_BUCKET.
+	EnvHistoryStoragePrefix = "TFMIGRATE_HISTORY_STORAGE_"
+)
+
+// Getenv is a function to look up an environment variable.
+// It is the same signature as os.Getenv and can be replaced for testing.
+type Getenv func(key string) string
+
+// noEnv is a Getenv which doesn't set any environment variables.
+func noEnv(string) string {
+	return ""
+}
+
+// applyEnv overrides top-level settings in a given block with environment
+// variables. It is applied before building the config so that values from
+// environment variables are validated in the same way as the config file.
+func applyEnv(b *TfmigrateBlock, getenv Getenv) error {
+	if v := getenv(EnvMigrationDir); len(v) > 0 {
+		b.MigrationDir = v
+	}
+
+	if v := getenv(EnvProject); len(v) > 0 {
+		b.Project = v
+	}
+
+	if v := getenv(EnvIsBackendTerraformCloud); len(v) > 0 {
+		isTFC, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %s", EnvIsBackendTerraformCloud, err)
+		}
+		b.IsBackendTerraformCloud = isTFC
+	}
+
+	return nil
+}
+
+// applyHistoryEnv overrides a given history config with environment
+// variables and returns a new one. If no history block is defined in the
+// config file, it returns a new history config only when the storage type is
+// given by the environment variable. Note that it must be called before
+// namespacing the storage by project.
+func applyHistoryEnv(h *history.Config, getenv Getenv) (*history.Config, error) {
+	if v := getenv(EnvHistoryStorageType); len(v) > 0 && (h == nil || storageType(h.Storage) != v) {
+		// The schema of storage depends on its type, so attributes in the
+		// config file are discarded if the type is changed.
+		s, err := newStorageConfig(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", EnvHistoryStorageType, err)
+		}
+		if h == nil {
+			h = &history.Config{}
+		}
+		h.Storage = s
+	}
+
+	if h == nil {
+		if env := findHistoryEnv(getenv); len(env) > 0 {
+			return nil, fmt.Errorf("%s is set, but no history storage is defined. Set %s or define a history block in the config file", env, EnvHistoryStorageType)
+		}
+		return nil, nil
+	}
+
+	if err := setAttributesFromEnv(h.Storage, EnvHistoryStoragePrefix, getenv); err != nil {
+		return nil, err
+	}
+
+	if v := getenv(EnvHistoryRequiredApprovals); len(v) > 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", EnvHistoryRequiredApprovals, err)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("%s must not be negative: %d", EnvHistoryRequiredApprovals, n)
+		}
+		h.RequiredApprovals = n
+	}
+
+	return h, nil
+}
+
+// findHistoryEnv returns a name of environment variable for history which is
+// set, or an empty string if none.
+func findHistoryEnv(getenv Getenv) string {
+	if len(getenv(EnvHistoryRequiredApprovals)) > 0 {
+		return EnvHistoryRequiredApprovals
+	}
+	for _, typ := range storageTypes {
+		s, _ := newStorageConfig(typ)
+		for _, attr := range hclAttributes(s) {
+			if env := EnvHistoryStoragePrefix + strings.ToUpper(attr.name); len(getenv(env)) > 0 {
+				return env
+			}
+		}
+	}
+	return ""
+}
+
+// storageTypes is a list of storage types which can be set by environment
+// variables. The mock storage is only for testing and not listed here.
+var storageTypes = []string{"local", "s3", "gcs", "azurerm", "consul", "etcd", "tfc", "git"}
+
+// newStorageConfig returns a new empty storage config for a given type.
+func newStorageConfig(typ string) (storage.Config, error) {
+	switch typ {
+	case "local":
+		return &local.Config{}, nil
+	case "s3":
+		return &s3.Config{}, nil
+	case "gcs":
+		return &gcs.Config{}, nil
+	case "azurerm":
+		return &azurerm.Config{}, nil
+	case "consul":
+		return &consul.Config{}, nil
+	case "etcd":
+		return &etcd.Config{}, nil
+	case "tfc":
+		return &tfc.Config{}, nil
+	case "git":
+		return &git.Config{}, nil
+	default:
+		return nil, fmt.Errorf("unknown history storage type: %s", typ)
+	}
+}
+
+// storageType returns a type name of a given storage config.
+func storageType(c storage.Config) string {
+	switch c.(type) {
+	case *mock.Config:
+		return "mock"
+	case *local.Config:
+		return "local"
+	case *s3.Config:
+		return "s3"
+	case *gcs.Config:
+		return "gcs"
+	case *azurerm.Config:
+		return "azurerm"
+	case *consul.Config:
+		return "consul"
+	case *etcd.Config:
+		return "etcd"
+	case *tfc.Config:
+		return "tfc"
+	case *git.Config:
+		return "git"
+	default:
+		return ""
+	}
+}
+
+// hclAttribute is a settable attribute of a config struct decoded from HCL.
+type hclAttribute struct {
+	// name is an attribute name in HCL.
+	name string
+	// value is a field of the struct.
+	value reflect.Value
+}
+
+// hclAttributes returns a list of attributes of a given pointer to config
+// struct. Labels, blocks and fields without a hcl tag are ignored.
+func hclAttributes(v interface{}) []hclAttribute {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	attrs := []hclAttribute{}
+	for i := 0; i < rt.NumField(); i++ {
+		tag, ok := rt.Field(i).Tag.Lookup("hcl")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		if len(parts[0]) == 0 {
+			continue
+		}
+		if len(parts) > 1 && parts[1] != "optional" && parts[1] != "attr" {
+			continue
+		}
+		attrs = append(attrs, hclAttribute{name: parts[0], value: rv.Field(i)})
+	}
+	return attrs
+}
+
+// setAttributesFromEnv overrides attributes of a given pointer to config
+// struct with environment variables. The name of environment variable is
+// a given prefix followed by the attribute name in upper case.
+// Only string, bool and int attributes are supported.
+func setAttributesFromEnv(v interface{}, prefix string, getenv Getenv) error {
+	for _, attr := range hclAttributes(v) {
+		env := prefix + strings.ToUpper(attr.name)
+		s := getenv(env)
+		if len(s) == 0 {
+			continue
+		}
+
+		switch attr.value.Kind() {
+		case reflect.String:
+			attr.value.SetString(s)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %s", env, err)
+			}
+			attr.value.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %s", env, err)
+			}
+			attr.value.SetInt(int64(n))
+		default:
+			return fmt.Errorf("%s is not supported: unsupported attribute type: %s", env, attr.value.Kind())
+		}
+	}
+	return nil
+}
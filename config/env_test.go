@@ -0,0 +1,289 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/local"
+	"github.com/minamijoyo/tfmigrate/storage/s3"
+)
+
+func TestParseConfigurationFileWithEnv(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		env    map[string]string
+		want   *TfmigrateConfig
+		ok     bool
+	}{
+		{
+			desc: "no env",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+  }
+}
+`,
+			env: map[string]string{},
+			want: &TfmigrateConfig{
+				MigrationDir: "tfmigrate",
+				History: &history.Config{
+					Storage: &local.Config{
+						Path: "tmp/history.json",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "override top-level settings",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  project       = "foo"
+}
+`,
+			env: map[string]string{
+				"TFMIGRATE_MIGRATION_DIR":              "migrations",
+				"TFMIGRATE_PROJECT":                    "bar",
+				"TFMIGRATE_IS_BACKEND_TERRAFORM_CLOUD": "true",
+			},
+			want: &TfmigrateConfig{
+				MigrationDir:            "migrations",
+				IsBackendTerraformCloud: true,
+				Project:                 "bar",
+			},
+			ok: true,
+		},
+		{
+			desc: "override history attributes before namespacing",
+			source: `
+tfmigrate {
+  project = "foo"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+  }
+}
+`,
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_STORAGE_PATH":       "tmp/override.json",
+				"TFMIGRATE_HISTORY_REQUIRED_APPROVALS": "2",
+			},
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				Project:      "foo",
+				History: &history.Config{
+					Project: "foo",
+					Storage: &local.Config{
+						Path: "tmp/foo/override.json",
+					},
+					RequiredApprovals: 2,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "override storage type",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+  }
+}
+`,
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_STORAGE_TYPE":             "s3",
+				"TFMIGRATE_HISTORY_STORAGE_BUCKET":           "tfmigrate-test",
+				"TFMIGRATE_HISTORY_STORAGE_KEY":              "tfmigrate/history.json",
+				"TFMIGRATE_HISTORY_STORAGE_FORCE_PATH_STYLE": "true",
+			},
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				History: &history.Config{
+					Storage: &s3.Config{
+						Bucket:         "tfmigrate-test",
+						Key:            "tfmigrate/history.json",
+						ForcePathStyle: true,
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid project",
+			source: `
+tfmigrate {
+  project = "foo"
+}
+`,
+			env: map[string]string{
+				"TFMIGRATE_PROJECT": "foo/bar",
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "invalid bool",
+			source: `
+tfmigrate {
+}
+`,
+			env: map[string]string{
+				"TFMIGRATE_IS_BACKEND_TERRAFORM_CLOUD": "foo",
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "invalid storage attribute",
+			source: `
+tfmigrate {
+  history {
+    storage "s3" {
+      bucket = "tfmigrate-test"
+      key    = "tfmigrate/history.json"
+    }
+  }
+}
+`,
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_STORAGE_FORCE_PATH_STYLE": "foo",
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "negative required approvals",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+  }
+}
+`,
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_REQUIRED_APPROVALS": "-1",
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "unknown storage type",
+			source: `
+tfmigrate {
+}
+`,
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_STORAGE_TYPE": "foo",
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "storage attribute without history",
+			source: `
+tfmigrate {
+}
+`,
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_STORAGE_BUCKET": "tfmigrate-test",
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			getenv := func(key string) string {
+				return tc.env[key]
+			}
+			got, err := ParseConfigurationFileWithEnv("test.hcl", []byte(tc.source), getenv)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	cases := []struct {
+		desc string
+		env  map[string]string
+		want *TfmigrateConfig
+		ok   bool
+	}{
+		{
+			desc: "no env",
+			env:  map[string]string{},
+			want: NewDefaultConfig(),
+			ok:   true,
+		},
+		{
+			desc: "history",
+			env: map[string]string{
+				"TFMIGRATE_PROJECT":              "foo",
+				"TFMIGRATE_HISTORY_STORAGE_TYPE": "local",
+				"TFMIGRATE_HISTORY_STORAGE_PATH": "tmp/history.json",
+			},
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				Project:      "foo",
+				History: &history.Config{
+					Project: "foo",
+					Storage: &local.Config{
+						Path: "tmp/foo/history.json",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "required approvals without history",
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_REQUIRED_APPROVALS": "1",
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			getenv := func(key string) string {
+				return tc.env[key]
+			}
+			got, err := NewConfigFromEnv(getenv)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/minamijoyo/tfmigrate/event"
+	"github.com/minamijoyo/tfmigrate/event/http"
+	"github.com/minamijoyo/tfmigrate/event/mock"
+	"github.com/minamijoyo/tfmigrate/event/pubsub"
+	"github.com/minamijoyo/tfmigrate/event/sns"
+)
+
+// EventSinkBlock represents a block for a destination of migration lifecycle
+// events in HCL.
+type EventSinkBlock struct {
+	// Type is a type for event sink.
+	// Valid values are as follows:
+	// - mock
+	// - http
+	// - sns
+	// - pubsub
+	Type string `hcl:"type,label"`
+	// Remain is a body of event_sink block.
+	// We first decode only a block header and then decode schema depending on
+	// its type label.
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// parseEventSinkBlocks parses event_sink blocks and returns a list of event.Config.
+func parseEventSinkBlocks(bs []EventSinkBlock) ([]event.Config, error) {
+	if len(bs) == 0 {
+		return nil, nil
+	}
+
+	configs := []event.Config{}
+	for _, b := range bs {
+		c, err := parseEventSinkBlock(b)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, c)
+	}
+
+	return configs, nil
+}
+
+// parseEventSinkBlock parses an event_sink block and returns an event.Config.
+func parseEventSinkBlock(b EventSinkBlock) (event.Config, error) {
+	var config event.Config
+	switch b.Type {
+	case "mock": // only for testing
+		config = &mock.Config{}
+
+	case "http":
+		config = &http.Config{}
+
+	case "sns":
+		config = &sns.Config{}
+
+	case "pubsub":
+		config = &pubsub.Config{}
+
+	default:
+		return nil, fmt.Errorf("unknown event sink type: %s", b.Type)
+	}
+
+	diags := gohcl.DecodeBody(b.Remain, nil, config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return config, nil
+}
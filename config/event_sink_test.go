@@ -0,0 +1,106 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/event"
+	"github.com/minamijoyo/tfmigrate/event/http"
+	"github.com/minamijoyo/tfmigrate/event/pubsub"
+	"github.com/minamijoyo/tfmigrate/event/sns"
+)
+
+func TestParseEventSinkBlocks(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   []event.Config
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  event_sink "http" {
+    url = "https://example.com/events"
+    headers = {
+      X-Foo = "bar"
+    }
+  }
+  event_sink "sns" {
+    topic_arn = "arn:aws:sns:ap-northeast-1:123456789012:tfmigrate"
+    region    = "ap-northeast-1"
+  }
+  event_sink "pubsub" {
+    topic = "projects/tfmigrate-test/topics/tfmigrate"
+  }
+}
+`,
+			want: []event.Config{
+				&http.Config{
+					URL: "https://example.com/events",
+					Headers: map[string]string{
+						"X-Foo": "bar",
+					},
+				},
+				&sns.Config{
+					TopicARN: "arn:aws:sns:ap-northeast-1:123456789012:tfmigrate",
+					Region:   "ap-northeast-1",
+				},
+				&pubsub.Config{
+					Topic: "projects/tfmigrate-test/topics/tfmigrate",
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "no sink",
+			source: `
+tfmigrate {
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "unknown type",
+			source: `
+tfmigrate {
+  event_sink "foo" {
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "missing required attribute",
+			source: `
+tfmigrate {
+  event_sink "http" {
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.EventSinks
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// ExecBlock represents a block for settings of executing the terraform
+// command in HCL.
+type ExecBlock struct {
+	// Retries is a maximum number of retries of a terraform command which
+	// fails with a transient error. Default to 0, which means no retry.
+	Retries int `hcl:"retries,optional"`
+	// Backoff is a duration to wait before the first retry, such as 5s.
+	// It doubles for each retry. Default to 5s.
+	Backoff string `hcl:"backoff,optional"`
+	// MaxBackoff is an upper limit of the wait, such as 1m.
+	// Default to 1m.
+	MaxBackoff string `hcl:"max_backoff,optional"`
+	// RetryableErrors is a list of regular expressions of retryable errors,
+	// which are matched against stderr of the command.
+	// Default to tfexec.DefaultRetryableErrors.
+	RetryableErrors []string `hcl:"retryable_errors,optional"`
+}
+
+const (
+	// defaultRetryBackoff is a default wait before the first retry.
+	defaultRetryBackoff = 5 * time.Second
+	// defaultRetryMaxBackoff is a default upper limit of the wait.
+	defaultRetryMaxBackoff = time.Minute
+)
+
+// parseExecBlock parses an exec block and returns a *tfexec.RetryPolicy.
+// It returns nil if retries are not enabled.
+func parseExecBlock(b ExecBlock) (*tfexec.RetryPolicy, error) {
+	if b.Retries < 0 {
+		return nil, fmt.Errorf("retries of exec must not be negative: %d", b.Retries)
+	}
+	if b.Retries == 0 {
+		return nil, nil
+	}
+
+	backoff, err := parseRetryDuration("backoff", b.Backoff, defaultRetryBackoff)
+	if err != nil {
+		return nil, err
+	}
+	maxBackoff, err := parseRetryDuration("max_backoff", b.MaxBackoff, defaultRetryMaxBackoff)
+	if err != nil {
+		return nil, err
+	}
+	if maxBackoff < backoff {
+		return nil, fmt.Errorf("max_backoff of exec must not be less than backoff: %s < %s", maxBackoff, backoff)
+	}
+
+	retryableErrors := tfexec.DefaultRetryableErrors
+	if len(b.RetryableErrors) > 0 {
+		retryableErrors = make([]*regexp.Regexp, 0, len(b.RetryableErrors))
+		for _, e := range b.RetryableErrors {
+			re, err := regexp.Compile(e)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile retryable_errors of exec: %s, err: %s", e, err)
+			}
+			retryableErrors = append(retryableErrors, re)
+		}
+	}
+
+	return &tfexec.RetryPolicy{
+		MaxAttempts:     b.Retries + 1,
+		Backoff:         backoff,
+		MaxBackoff:      maxBackoff,
+		RetryableErrors: retryableErrors,
+	}, nil
+}
+
+// parseRetryDuration parses a duration of a given attribute of an exec
+// block. If empty, it returns a given default value.
+func parseRetryDuration(name string, s string, defaultValue time.Duration) (time.Duration, error) {
+	if len(s) == 0 {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s of exec: %s, err: %s", name, s, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s of exec must not be negative: %s", name, s)
+	}
+	return d, nil
+}
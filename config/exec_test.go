@@ -0,0 +1,151 @@
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestParseExecBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   *tfexec.RetryPolicy
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  exec {
+    retries          = 3
+    backoff          = "1s"
+    max_backoff      = "10s"
+    retryable_errors = ["Error acquiring the state lock", "connection reset by peer"]
+  }
+}
+`,
+			want: &tfexec.RetryPolicy{
+				MaxAttempts: 4,
+				Backoff:     time.Second,
+				MaxBackoff:  10 * time.Second,
+				RetryableErrors: []*regexp.Regexp{
+					regexp.MustCompile("Error acquiring the state lock"),
+					regexp.MustCompile("connection reset by peer"),
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "default",
+			source: `
+tfmigrate {
+  exec {
+    retries = 2
+  }
+}
+`,
+			want: &tfexec.RetryPolicy{
+				MaxAttempts:     3,
+				Backoff:         5 * time.Second,
+				MaxBackoff:      time.Minute,
+				RetryableErrors: tfexec.DefaultRetryableErrors,
+			},
+			ok: true,
+		},
+		{
+			desc: "no retries",
+			source: `
+tfmigrate {
+  exec {
+    retries = 0
+  }
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "no exec",
+			source: `
+tfmigrate {
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "negative retries",
+			source: `
+tfmigrate {
+  exec {
+    retries = -1
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "invalid backoff",
+			source: `
+tfmigrate {
+  exec {
+    retries = 1
+    backoff = "foo"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "max_backoff less than backoff",
+			source: `
+tfmigrate {
+  exec {
+    retries     = 1
+    backoff     = "10s"
+    max_backoff = "1s"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "invalid retryable_errors",
+			source: `
+tfmigrate {
+  exec {
+    retries          = 1
+    retryable_errors = ["("]
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.Retry
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
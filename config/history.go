@@ -1,11 +1,24 @@
 package config
 
-import "github.com/minamijoyo/tfmigrate/history"
+import (
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/history"
+)
 
 // HistoryBlock represents a block for migration history management in HCL.
 type HistoryBlock struct {
 	// Storage is a block for migration history data store.
 	Storage StorageBlock `hcl:"storage,block"`
+	// RequiredApprovals is a number of approvals required to apply a migration.
+	RequiredApprovals int `hcl:"required_approvals,optional"`
+	// Encryption is an optional block for client-side encryption of history.
+	Encryption *EncryptionBlock `hcl:"encryption,block"`
+	// CompareAndSwap is a flag to write the history file only if it has not
+	// been updated since it was loaded, and merge concurrent updates.
+	CompareAndSwap bool `hcl:"compare_and_swap,optional"`
+	// Format is an encoding of the history file, json or yaml.
+	Format string `hcl:"format,optional"`
 }
 
 // parseHistoryBlock parses a history block and returns a *history.Config.
@@ -15,8 +28,30 @@ func parseHistoryBlock(b HistoryBlock) (*history.Config, error) {
 		return nil, err
 	}
 
+	if b.RequiredApprovals < 0 {
+		return nil, fmt.Errorf("required_approvals must not be negative: %d", b.RequiredApprovals)
+	}
+
+	switch b.Format {
+	case "", history.FormatJSON, history.FormatYAML:
+	default:
+		return nil, fmt.Errorf("unknown history file format: %s. It must be json or yaml", b.Format)
+	}
+
 	history := &history.Config{
-		Storage: storage,
+		Storage:           storage,
+		RequiredApprovals: b.RequiredApprovals,
+		CompareAndSwap:    b.CompareAndSwap,
+		Format:            b.Format,
+	}
+
+	if b.Encryption != nil {
+		encryption, err := parseEncryptionBlock(*b.Encryption)
+		if err != nil {
+			return nil, err
+		}
+		history.Encryption = encryption
+		history.AllowPlaintextMigration = b.Encryption.AllowPlaintextMigration
 	}
 
 	return history, nil
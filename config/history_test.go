@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/encryption"
 	"github.com/minamijoyo/tfmigrate/storage/local"
 )
 
@@ -34,6 +35,258 @@ tfmigrate {
 			},
 			ok: true,
 		},
+		{
+			desc: "required_approvals",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    required_approvals = 2
+  }
+}
+`,
+			want: &history.Config{
+				Storage: &local.Config{
+					Path: "tmp/history.json",
+				},
+				RequiredApprovals: 2,
+			},
+			ok: true,
+		},
+		{
+			desc: "compare_and_swap",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    compare_and_swap = true
+  }
+}
+`,
+			want: &history.Config{
+				Storage: &local.Config{
+					Path: "tmp/history.json",
+				},
+				CompareAndSwap: true,
+			},
+			ok: true,
+		},
+		{
+			desc: "yaml format",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.yaml"
+    }
+    format = "yaml"
+  }
+}
+`,
+			want: &history.Config{
+				Storage: &local.Config{
+					Path: "tmp/history.yaml",
+				},
+				Format: "yaml",
+			},
+			ok: true,
+		},
+		{
+			desc: "unknown format",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.toml"
+    }
+    format = "toml"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "negative required_approvals",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    required_approvals = -1
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "encryption key",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    encryption "key" {
+      key_env = "MY_HISTORY_KEY"
+    }
+  }
+}
+`,
+			want: &history.Config{
+				Storage: &local.Config{
+					Path: "tmp/history.json",
+				},
+				Encryption: &encryption.KeyConfig{
+					KeyEnv: "MY_HISTORY_KEY",
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "encryption with allow_plaintext_migration",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    encryption "key" {
+      key_env                   = "MY_HISTORY_KEY"
+      allow_plaintext_migration = true
+    }
+  }
+}
+`,
+			want: &history.Config{
+				Storage: &local.Config{
+					Path: "tmp/history.json",
+				},
+				Encryption: &encryption.KeyConfig{
+					KeyEnv: "MY_HISTORY_KEY",
+				},
+				AllowPlaintextMigration: true,
+			},
+			ok: true,
+		},
+		{
+			desc: "encryption kms",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    encryption "kms" {
+      key_id = "alias/tfmigrate"
+      region = "ap-northeast-1"
+    }
+  }
+}
+`,
+			want: &history.Config{
+				Storage: &local.Config{
+					Path: "tmp/history.json",
+				},
+				Encryption: &encryption.KMSConfig{
+					KeyID:  "alias/tfmigrate",
+					Region: "ap-northeast-1",
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "encryption age",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    encryption "age" {
+      recipients    = ["age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"]
+      identity_file = "~/.config/age/key.txt"
+    }
+  }
+}
+`,
+			want: &history.Config{
+				Storage: &local.Config{
+					Path: "tmp/history.json",
+				},
+				Encryption: &encryption.AgeConfig{
+					Recipients:   []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"},
+					IdentityFile: "~/.config/age/key.txt",
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "unknown encryption type",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    encryption "foo" {
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "missing key_id (kms)",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    encryption "kms" {
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "missing recipients (age)",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    encryption "age" {
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
 		{
 			desc: "missing block (storage)",
 			source: `
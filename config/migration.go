@@ -3,12 +3,17 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
 
 	"github.com/minamijoyo/tfmigrate/tfmigrate"
 )
@@ -28,12 +33,42 @@ type MigrationBlock struct {
 	Type string `hcl:"type,label"`
 	// Name is an arbitrary name for migration.
 	Name string `hcl:"name,label"`
+	// DependsOn is a list of migration file names which must be applied
+	// before this migration.
+	DependsOn []string `hcl:"depends_on,optional"`
+	// SkipIf is a condition to skip the migration.
+	// It is an expression evaluated on loading the migration file, which can
+	// refer to environment variables and directory aliases.
+	SkipIf bool `hcl:"skip_if,optional"`
+	// Owner is a team which owns the migration.
+	Owner string `hcl:"owner,optional"`
+	// ApprovedBy is a list of teams which approved the migration touching
+	// their resources.
+	ApprovedBy []string `hcl:"approved_by,optional"`
+	// Unprotect is a list of protected address patterns which the migration
+	// is explicitly allowed to rm or mv.
+	Unprotect []string `hcl:"unprotect,optional"`
+	// Hooks is a block for commands which run around applying the migration.
+	Hooks *HooksBlock `hcl:"hooks,block"`
 	// Remain is a body of migration block.
 	// We first decode only a block header and then decode schema depending on
 	// its type label.
 	Remain hcl.Body `hcl:",remain"`
 }
 
+// HooksBlock represents a hooks block in HCL.
+type HooksBlock struct {
+	// PreApply is a list of command lines which run before applying the
+	// migration.
+	PreApply []string `hcl:"pre_apply,optional"`
+	// PostApply is a list of command lines which run after the migration has
+	// been applied.
+	PostApply []string `hcl:"post_apply,optional"`
+	// OnFailure is a list of command lines which run after a pre_apply hook
+	// or applying the migration has failed.
+	OnFailure []string `hcl:"on_failure,optional"`
+}
+
 // Return a map of environment variables.
 func envVarMap() cty.Value {
 	envMap := make(map[string]cty.Value)
@@ -44,16 +79,75 @@ func envVarMap() cty.Value {
 	return cty.MapVal(envMap)
 }
 
+// Return an object of directory aliases.
+func dirsVarMap(dirs map[string]string) cty.Value {
+	if len(dirs) == 0 {
+		return cty.EmptyObjectVal
+	}
+	dirsMap := make(map[string]cty.Value)
+	for alias, dir := range dirs {
+		dirsMap[alias] = cty.StringVal(dir)
+	}
+	return cty.ObjectVal(dirsMap)
+}
+
+// Return an object of variables.
+func varVarMap(vars map[string]string) cty.Value {
+	if len(vars) == 0 {
+		return cty.EmptyObjectVal
+	}
+	varMap := make(map[string]cty.Value)
+	for name, value := range vars {
+		varMap[name] = cty.StringVal(value)
+	}
+	return cty.ObjectVal(varMap)
+}
+
 // ParseMigrationFile parses a given source of migration file and returns a *tfmigrate.MigrationConfig.
 // Note that this method does not read a file and you should pass source of config in bytes.
 // The filename is used for error message and selecting HCL syntax (.hcl and .json).
 func ParseMigrationFile(filename string, source []byte) (*tfmigrate.MigrationConfig, error) {
+	return ParseMigrationFileWithOption(filename, source, nil)
+}
+
+// MigrationFileOption customizes parsing of migration files with settings in
+// the config file.
+type MigrationFileOption struct {
+	// Policy is an organization policy of migrations.
+	// Default values in the policy are applied to attributes which are not set
+	// in the migration file. Note that the migration is not validated against
+	// the policy on parsing.
+	Policy *tfmigrate.MigrationPolicy
+	// Dirs is a map of directory aliases, which can be referenced as
+	// `dirs.<alias>` in migration files.
+	Dirs map[string]string
+	// Variables is a map of variables, which can be referenced as
+	// `var.<name>` in migration files.
+	Variables map[string]string
+}
+
+// ParseMigrationFileWithOption is the same as ParseMigrationFile, but
+// customizes parsing with a given option. If the option is nil, it is the
+// same as ParseMigrationFile.
+func ParseMigrationFileWithOption(filename string, source []byte, o *MigrationFileOption) (*tfmigrate.MigrationConfig, error) {
+	if o == nil {
+		o = &MigrationFileOption{}
+	}
+
 	// Decode migration block header.
 	var f MigrationFile
 
 	ctx := &hcl.EvalContext{
 		Variables: map[string]cty.Value{
-			"env": envVarMap(),
+			"env":  envVarMap(),
+			"dirs": dirsVarMap(o.Dirs),
+			"var":  varVarMap(o.Variables),
+		},
+		// The try and can functions allow us to refer to environment
+		// variables which may not be set, such as `try(env.FOO, "")`.
+		Functions: map[string]function.Function{
+			"try": tryfunc.TryFunc,
+			"can": tryfunc.CanFunc,
 		},
 	}
 
@@ -62,31 +156,51 @@ func ParseMigrationFile(filename string, source []byte) (*tfmigrate.MigrationCon
 		return nil, fmt.Errorf("failed to decode migration file: %s, err: %s", filename, err)
 	}
 
-	migrator, err := parseMigrationBlock(f.Migration, ctx)
+	migrator, err := parseMigrationBlock(f.Migration, ctx, o.Policy, filepath.Dir(filename))
 	if err != nil {
 		return nil, err
 	}
 
+	var hooks *tfmigrate.HooksConfig
+	if f.Migration.Hooks != nil {
+		hooks = &tfmigrate.HooksConfig{
+			PreApply:  f.Migration.Hooks.PreApply,
+			PostApply: f.Migration.Hooks.PostApply,
+			OnFailure: f.Migration.Hooks.OnFailure,
+		}
+		if err := hooks.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	config := &tfmigrate.MigrationConfig{
-		Type:     f.Migration.Type,
-		Name:     f.Migration.Name,
-		Migrator: migrator,
+		Type:       f.Migration.Type,
+		Name:       f.Migration.Name,
+		DependsOn:  f.Migration.DependsOn,
+		Skip:       f.Migration.SkipIf,
+		Owner:      f.Migration.Owner,
+		ApprovedBy: f.Migration.ApprovedBy,
+		Unprotect:  f.Migration.Unprotect,
+		Hooks:      hooks,
+		Migrator:   migrator,
 	}
 
 	return config, nil
 }
 
 // parseMigrationBlock parses a migration block and returns a tfmigrate.MigratorConfig.
-func parseMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmigrate.MigratorConfig, error) {
+// baseDir is a directory of the migration file, which relative paths in the
+// block are resolved from.
+func parseMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext, policy *tfmigrate.MigrationPolicy, baseDir string) (tfmigrate.MigratorConfig, error) {
 	switch b.Type {
 	case "mock": // only for testing
 		return parseMockMigrationBlock(b, ctx)
 
 	case "state":
-		return parseStateMigrationBlock(b, ctx)
+		return parseStateMigrationBlock(b, ctx, policy, baseDir)
 
 	case "multi_state":
-		return parseMultiStateMigrationBlock(b, ctx)
+		return parseMultiStateMigrationBlock(b, ctx, policy)
 
 	default:
 		return nil, fmt.Errorf("unknown migration type: %s", b.Type)
@@ -105,24 +219,221 @@ func parseMockMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmigrate.
 }
 
 // parseStateMigrationBlock parses a migration block for state and returns a tfmigrate.MigratorConfig.
-func parseStateMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmigrate.MigratorConfig, error) {
+func parseStateMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext, policy *tfmigrate.MigrationPolicy, baseDir string) (tfmigrate.MigratorConfig, error) {
 	var config tfmigrate.StateMigratorConfig
+	// An optional attribute which is not set is left as it is on decoding,
+	// so we can apply defaults before decoding.
+	if policy != nil {
+		config.Force = policy.DefaultForce
+	}
 	diags := gohcl.DecodeBody(b.Remain, ctx, &config)
 	if diags.HasErrors() {
 		return nil, diags
 	}
 
+	if config.Actions == nil && config.ActionConfigs == nil && len(config.ImportFile) == 0 && config.Mapping == nil && config.Absent == nil && config.Imports == nil {
+		return nil, fmt.Errorf("either actions, action, import_file, mapping, absent or imports is required in migration block: %s", b.Name)
+	}
+
+	// Append commands of action blocks to actions, so that they can be
+	// treated in the same way as ones written in actions. The options are
+	// kept in the config to be applied on checking terraform plan.
+	config.Actions = mergeActionCommands(b.Remain, config.Actions, config.ActionConfigs)
+
+	// Expand the import file into import actions on parsing, so that they can
+	// be treated in the same way as ones written in actions.
+	if len(config.ImportFile) > 0 {
+		filename := config.ImportFile
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(baseDir, filename)
+		}
+		actions, err := tfmigrate.ReadImportFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		config.Actions = append(config.Actions, actions...)
+		config.ImportFile = ""
+	}
+
 	return &config, nil
 }
 
 // parseMultiStateMigrationBlock parses a migration block for multi_state and
 // returns a tfmigrate.MigratorConfig.
-func parseMultiStateMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmigrate.MigratorConfig, error) {
+func parseMultiStateMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext, policy *tfmigrate.MigrationPolicy) (tfmigrate.MigratorConfig, error) {
 	var config tfmigrate.MultiStateMigratorConfig
+	// An optional attribute which is not set is left as it is on decoding,
+	// so we can apply defaults before decoding.
+	if policy != nil {
+		config.Force = policy.DefaultForce
+	}
 	diags := gohcl.DecodeBody(b.Remain, ctx, &config)
 	if diags.HasErrors() {
 		return nil, diags
 	}
 
+	if config.Actions == nil && config.ActionConfigs == nil && config.Mapping == nil {
+		return nil, fmt.Errorf("either actions, action or mapping is required in migration block: %s", b.Name)
+	}
+
+	config.Actions = mergeActionCommands(b.Remain, config.Actions, config.ActionConfigs)
+
 	return &config, nil
 }
+
+// actionsSchema is a schema to find positions of the actions attribute and
+// action blocks in a migration block.
+var actionsSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "actions"}},
+	Blocks:     []hcl.BlockHeaderSchema{{Type: "action"}},
+}
+
+// mergeActionCommands merges commands of given action blocks into actions in
+// order of declaration in a given body. Since actions is a single list
+// attribute, action blocks declared before it come first, and ones declared
+// after it follow it. It returns actions as it is if no action blocks are
+// given, so that nil actions are distinguished from empty ones.
+func mergeActionCommands(body hcl.Body, actions []string, configs []tfmigrate.ActionConfig) []string {
+	if len(configs) == 0 {
+		return actions
+	}
+
+	// Action blocks have already been decoded in the same order, so the
+	// positions are always found unless the body is broken.
+	content, _, diags := body.PartialContent(actionsSchema)
+	attr, ok := content.Attributes["actions"]
+	if diags.HasErrors() || !ok || len(content.Blocks) != len(configs) {
+		for _, a := range configs {
+			actions = append(actions, a.Command)
+		}
+		return actions
+	}
+
+	merged := []string{}
+	i := 0
+	for ; i < len(configs) && content.Blocks[i].DefRange.Start.Byte < attr.Range.Start.Byte; i++ {
+		merged = append(merged, configs[i].Command)
+	}
+	merged = append(merged, actions...)
+	for ; i < len(configs); i++ {
+		merged = append(merged, configs[i].Command)
+	}
+	return merged
+}
+
+// FormatMigrationFile returns a migration file in HCL for a given migration
+// config. Only the state migration type is supported.
+func FormatMigrationFile(mc *tfmigrate.MigrationConfig) ([]byte, error) {
+	m, ok := mc.Migrator.(*tfmigrate.StateMigratorConfig)
+	if !ok {
+		return nil, fmt.Errorf("formatting a migration of type %s is not supported", mc.Type)
+	}
+
+	f := hclwrite.NewEmptyFile()
+	body := f.Body().AppendNewBlock("migration", []string{mc.Type, mc.Name}).Body()
+	if len(mc.DependsOn) > 0 {
+		body.SetAttributeRaw("depends_on", tokensForStringList(mc.DependsOn))
+	}
+	if len(mc.Owner) > 0 {
+		body.SetAttributeValue("owner", cty.StringVal(mc.Owner))
+	}
+	if len(mc.ApprovedBy) > 0 {
+		body.SetAttributeRaw("approved_by", tokensForStringList(mc.ApprovedBy))
+	}
+	if len(mc.Unprotect) > 0 {
+		body.SetAttributeRaw("unprotect", tokensForStringList(mc.Unprotect))
+	}
+	if len(m.Dir) > 0 {
+		body.SetAttributeValue("dir", cty.StringVal(m.Dir))
+	}
+	if len(m.Workspace) > 0 {
+		body.SetAttributeValue("workspace", cty.StringVal(m.Workspace))
+	}
+	if len(m.Env) > 0 {
+		env := make(map[string]cty.Value, len(m.Env))
+		for k, v := range m.Env {
+			env[k] = cty.StringVal(v)
+		}
+		body.SetAttributeValue("env", cty.MapVal(env))
+	}
+	if len(m.VarFiles) > 0 {
+		body.SetAttributeRaw("var_files", tokensForStringList(m.VarFiles))
+	}
+	if len(m.Vars) > 0 {
+		vars := make(map[string]cty.Value, len(m.Vars))
+		for k, v := range m.Vars {
+			vars[k] = cty.StringVal(v)
+		}
+		body.SetAttributeValue("vars", cty.MapVal(vars))
+	}
+	if m.Force {
+		body.SetAttributeValue("force", cty.True)
+	}
+	if m.SkipPlan {
+		body.SetAttributeValue("to_skip_plan", cty.True)
+	}
+	if m.ExpectNoChanges {
+		body.SetAttributeValue("expect_no_changes", cty.True)
+	}
+	if len(m.Allow) > 0 {
+		body.SetAttributeRaw("allow", tokensForStringList(m.Allow))
+	}
+	body.SetAttributeRaw("actions", tokensForStringList(m.Actions))
+	if mc.Hooks != nil {
+		hooks := body.AppendNewBlock("hooks", nil).Body()
+		if len(mc.Hooks.PreApply) > 0 {
+			hooks.SetAttributeRaw("pre_apply", tokensForStringList(mc.Hooks.PreApply))
+		}
+		if len(mc.Hooks.PostApply) > 0 {
+			hooks.SetAttributeRaw("post_apply", tokensForStringList(mc.Hooks.PostApply))
+		}
+		if len(mc.Hooks.OnFailure) > 0 {
+			hooks.SetAttributeRaw("on_failure", tokensForStringList(mc.Hooks.OnFailure))
+		}
+	}
+
+	return hclwrite.Format(f.Bytes()), nil
+}
+
+// tokensForStringList returns tokens of a list of strings with one element
+// per line, which is easier to read and diff than a single line.
+func tokensForStringList(list []string) hclwrite.Tokens {
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrack, Bytes: []byte("[")},
+		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	}
+	for _, s := range list {
+		tokens = append(tokens, hclwrite.TokensForValue(cty.StringVal(s))...)
+		tokens = append(tokens,
+			&hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")},
+			&hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+		)
+	}
+	return append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte("]")})
+}
+
+// FormatMovedBlocks returns Terraform moved blocks in HCL for a given list.
+func FormatMovedBlocks(blocks []tfmigrate.MovedBlock) ([]byte, error) {
+	f := hclwrite.NewEmptyFile()
+	for i, b := range blocks {
+		if i > 0 {
+			f.Body().AppendNewline()
+		}
+		body := f.Body().AppendNewBlock("moved", nil).Body()
+		for _, attr := range []struct {
+			name    string
+			address string
+		}{
+			{name: "from", address: b.From},
+			{name: "to", address: b.To},
+		} {
+			traversal, diags := hclsyntax.ParseTraversalAbs([]byte(attr.address), "", hcl.InitialPos)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("failed to parse address: %s, err: %s", attr.address, diags)
+			}
+			body.SetAttributeTraversal(attr.name, traversal)
+		}
+	}
+
+	return hclwrite.Format(f.Bytes()), nil
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// templateFS is a file system of migration templates embedded in the binary.
+//
+//go:embed templates/*.hcl
+var templateFS embed.FS
+
+// MigrationTemplate is a parameterized migration file which generates a
+// ready-to-review migration for a common refactoring.
+type MigrationTemplate struct {
+	// Name is a name of the template such as module-extract.
+	Name string
+	// Description is a short description of the template.
+	Description string
+	// Params is a list of parameters of the template.
+	Params []TemplateParam
+}
+
+// TemplateParam is a parameter of MigrationTemplate.
+type TemplateParam struct {
+	// Name is a name of the parameter such as src.
+	Name string
+	// Description is a short description of the parameter.
+	Description string
+	// Required is true if the parameter must be set.
+	Required bool
+	// Default is a value used when the parameter is not set.
+	Default string
+}
+
+// migrationTemplates is a list of available migration templates.
+// Each of them has a file named <name>.hcl in the templates directory.
+var migrationTemplates = []*MigrationTemplate{
+	{
+		Name:        "module-extract",
+		Description: "Move a module from a directory to another.",
+		Params: []TemplateParam{
+			{Name: "src", Description: "A working directory where the module is managed", Required: true},
+			{Name: "dst", Description: "A working directory where the module moves to", Required: true},
+			{Name: "module", Description: "A name of the module such as vpc", Required: true},
+			{Name: "to", Description: "A destination address. Default to the same module address"},
+		},
+	},
+	{
+		Name:        "provider-replace",
+		Description: "Replace a provider address in a state.",
+		Params: []TemplateParam{
+			{Name: "dir", Description: "A working directory", Default: "."},
+			{Name: "workspace", Description: "A terraform workspace"},
+			{Name: "from", Description: "A provider address to be replaced", Required: true},
+			{Name: "to", Description: "A new provider address", Required: true},
+		},
+	},
+	{
+		Name:        "resource-adoption",
+		Description: "Import an existing resource to a state.",
+		Params: []TemplateParam{
+			{Name: "dir", Description: "A working directory", Default: "."},
+			{Name: "workspace", Description: "A terraform workspace"},
+			{Name: "address", Description: "An address of the resource", Required: true},
+			{Name: "id", Description: "An ID of the resource", Required: true},
+		},
+	},
+	{
+		Name:        "workspace-split",
+		Description: "Move resources from a workspace to another.",
+		Params: []TemplateParam{
+			{Name: "dir", Description: "A working directory", Default: "."},
+			{Name: "from_workspace", Description: "A workspace where resources are managed", Default: "default"},
+			{Name: "to_workspace", Description: "A workspace where resources move to", Required: true},
+			{Name: "to_dir", Description: "A working directory of to_workspace. Default to dir"},
+			{Name: "addresses", Description: "A comma-separated list of resource addresses", Required: true},
+		},
+	},
+}
+
+// MigrationTemplates returns a list of available migration templates sorted
+// by name.
+func MigrationTemplates() []*MigrationTemplate {
+	templates := make([]*MigrationTemplate, len(migrationTemplates))
+	copy(templates, migrationTemplates)
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// FindMigrationTemplate returns a migration template of a given name.
+func FindMigrationTemplate(name string) (*MigrationTemplate, error) {
+	for _, t := range migrationTemplates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	names := []string{}
+	for _, t := range MigrationTemplates() {
+		names = append(names, t.Name)
+	}
+	return nil, fmt.Errorf("unknown template: %s, available templates: %s", name, strings.Join(names, ", "))
+}
+
+// Render generates a migration file named by a given name with a given map of
+// parameters. The generated file is formatted and parsed with a given option
+// to ensure that it is valid.
+func (t *MigrationTemplate) Render(name string, params map[string]string, o *MigrationFileOption) ([]byte, error) {
+	values, err := t.paramValues(params)
+	if err != nil {
+		return nil, err
+	}
+	values["name"] = name
+
+	source, err := templateFS.ReadFile("templates/" + t.Name + ".hcl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %s, err: %s", t.Name, err)
+	}
+
+	tmpl, err := template.New(t.Name).Funcs(template.FuncMap{
+		"hcl":   hclString,
+		"split": splitParamList,
+	}).Option("missingkey=error").Parse(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %s, err: %s", t.Name, err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, values); err != nil {
+		return nil, fmt.Errorf("failed to render template: %s, err: %s", t.Name, err)
+	}
+
+	out := hclwrite.Format(b.Bytes())
+	mc, err := ParseMigrationFileWithOption(t.Name+".hcl", out, o)
+	if err != nil {
+		return nil, fmt.Errorf("template %s generated an invalid migration: %s", t.Name, err)
+	}
+	if !hasActions(mc) {
+		return nil, fmt.Errorf("template %s generated a migration without actions, check parameters", t.Name)
+	}
+
+	return out, nil
+}
+
+// paramValues validates given parameters and returns a map of values for all
+// parameters of the template, in which defaults are filled.
+func (t *MigrationTemplate) paramValues(params map[string]string) (map[string]string, error) {
+	values := make(map[string]string)
+	for k, v := range params {
+		if t.findParam(k) == nil {
+			return nil, fmt.Errorf("unknown parameter for template %s: %s", t.Name, k)
+		}
+		// A value is also rendered in comments, which must not span lines.
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("parameter %s must not contain newlines", k)
+		}
+		values[k] = v
+	}
+
+	missing := []string{}
+	for _, p := range t.Params {
+		if len(values[p.Name]) > 0 {
+			continue
+		}
+		if p.Required {
+			missing = append(missing, p.Name)
+			continue
+		}
+		values[p.Name] = p.Default
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required parameters for template %s: %s", t.Name, strings.Join(missing, ", "))
+	}
+
+	return values, nil
+}
+
+// hasActions returns true if a given migration has at least one action.
+func hasActions(mc *tfmigrate.MigrationConfig) bool {
+	switch m := mc.Migrator.(type) {
+	case *tfmigrate.StateMigratorConfig:
+		return len(m.Actions) > 0
+	case *tfmigrate.MultiStateMigratorConfig:
+		return len(m.Actions) > 0
+	default:
+		return false
+	}
+}
+
+// findParam returns a parameter of a given name. It returns nil if not found.
+func (t *MigrationTemplate) findParam(name string) *TemplateParam {
+	for i := range t.Params {
+		if t.Params[i].Name == name {
+			return &t.Params[i]
+		}
+	}
+	return nil
+}
+
+// hclString returns a quoted HCL string literal of a given value, in which
+// template sequences such as ${ are escaped.
+func hclString(s string) string {
+	return string(hclwrite.TokensForValue(cty.StringVal(s)).Bytes())
+}
+
+// splitParamList splits a comma-separated parameter into a list of trimmed
+// non-empty values.
+func splitParamList(s string) []string {
+	values := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrationTemplateRender(t *testing.T) {
+	cases := []struct {
+		desc     string
+		template string
+		params   map[string]string
+		want     []string
+		ok       bool
+	}{
+		{
+			desc:     "module-extract",
+			template: "module-extract",
+			params:   map[string]string{"src": "network", "dst": "vpc", "module": "vpc"},
+			want: []string{
+				`migration "multi_state" "test" {`,
+				`from_dir = "network"`,
+				`to_dir   = "vpc"`,
+				`"mv module.vpc module.vpc",`,
+			},
+			ok: true,
+		},
+		{
+			desc:     "module-extract with to",
+			template: "module-extract",
+			params:   map[string]string{"src": "network", "dst": "vpc", "module": "vpc", "to": "module.main"},
+			want: []string{
+				`"mv module.vpc module.main",`,
+			},
+			ok: true,
+		},
+		{
+			desc:     "provider-replace",
+			template: "provider-replace",
+			params:   map[string]string{"from": "registry.terraform.io/-/null", "to": "registry.terraform.io/hashicorp/null", "workspace": "work1"},
+			want: []string{
+				`migration "state" "test" {`,
+				`dir       = "."`,
+				`workspace = "work1"`,
+				`"replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",`,
+			},
+			ok: true,
+		},
+		{
+			desc:     "resource-adoption",
+			template: "resource-adoption",
+			params:   map[string]string{"dir": "dir1", "address": `aws_iam_role.foo["a.b"]`, "id": "${foo}"},
+			want: []string{
+				`dir = "dir1"`,
+				`"import aws_iam_role.foo[\"a.b\"] $${foo}",`,
+			},
+			ok: true,
+		},
+		{
+			desc:     "workspace-split",
+			template: "workspace-split",
+			params:   map[string]string{"to_workspace": "prod", "addresses": "aws_instance.foo, aws_instance.bar,"},
+			want: []string{
+				`from_workspace = "default"`,
+				`to_dir         = "."`,
+				`to_workspace   = "prod"`,
+				`"mv aws_instance.foo aws_instance.foo",`,
+				`"mv aws_instance.bar aws_instance.bar",`,
+			},
+			ok: true,
+		},
+		{
+			desc:     "missing required",
+			template: "provider-replace",
+			params:   map[string]string{"from": "registry.terraform.io/-/null"},
+			ok:       false,
+		},
+		{
+			desc:     "unknown param",
+			template: "provider-replace",
+			params:   map[string]string{"from": "a", "to": "b", "foo": "bar"},
+			ok:       false,
+		},
+		{
+			desc:     "newline",
+			template: "resource-adoption",
+			params:   map[string]string{"address": "aws_instance.foo", "id": "foo\nbar"},
+			ok:       false,
+		},
+		{
+			desc:     "invalid migration",
+			template: "workspace-split",
+			params:   map[string]string{"to_workspace": "prod", "addresses": ","},
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tmpl, err := FindMigrationTemplate(tc.template)
+			if err != nil {
+				t.Fatalf("failed to find template: %s", err)
+			}
+			got, err := tmpl.Render("test", tc.params, nil)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error, got: %s", string(got))
+				}
+				return
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(string(got), w) {
+					t.Errorf("got doesn't contain %q:\n%s", w, string(got))
+				}
+			}
+		})
+	}
+}
+
+func TestMigrationTemplatesRenderable(t *testing.T) {
+	for _, tmpl := range MigrationTemplates() {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			params := make(map[string]string)
+			for _, p := range tmpl.Params {
+				if p.Required {
+					params[p.Name] = "foo"
+				}
+			}
+			if _, err := tmpl.Render("test", params, nil); err != nil {
+				t.Errorf("failed to render template: %s", err)
+			}
+		})
+	}
+
+	if _, err := FindMigrationTemplate("foo"); err == nil {
+		t.Error("expected to return an error for unknown template, but no error")
+	}
+}
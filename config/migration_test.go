@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -8,6 +10,7 @@ import (
 )
 
 func TestParseMigrationFileWithNativeSyntax(t *testing.T) {
+	isTFC := true
 	cases := []struct {
 		desc   string
 		env    map[string]string
@@ -33,6 +36,125 @@ migration "mock" "test" {
 			},
 			ok: true,
 		},
+		{
+			desc: "mock with depends_on",
+			source: `
+migration "mock" "test" {
+	depends_on  = ["20201109000001_test1.hcl"]
+	plan_error  = true
+	apply_error = false
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type:      "mock",
+				Name:      "test",
+				DependsOn: []string{"20201109000001_test1.hcl"},
+				Migrator: &tfmigrate.MockMigratorConfig{
+					PlanError:  true,
+					ApplyError: false,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "mock with skip_if",
+			env: map[string]string{
+				"TARGET": "staging",
+			},
+			source: `
+migration "mock" "test" {
+	skip_if     = env.TARGET != "prod" && !can(env.TFMIGRATE_TEST_UNDEFINED_ENV)
+	plan_error  = true
+	apply_error = false
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "mock",
+				Name: "test",
+				Skip: true,
+				Migrator: &tfmigrate.MockMigratorConfig{
+					PlanError:  true,
+					ApplyError: false,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "mock with owner and approved_by",
+			source: `
+migration "mock" "test" {
+	owner       = "app"
+	approved_by = ["network"]
+	plan_error  = true
+	apply_error = false
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type:       "mock",
+				Name:       "test",
+				Owner:      "app",
+				ApprovedBy: []string{"network"},
+				Migrator: &tfmigrate.MockMigratorConfig{
+					PlanError:  true,
+					ApplyError: false,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "mock with hooks",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+	hooks {
+		pre_apply  = ["./notify.sh start"]
+		post_apply = ["terraform -chdir=dir1 apply -refresh-only -auto-approve", "./notify.sh done"]
+		on_failure = ["./notify.sh failed"]
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "mock",
+				Name: "test",
+				Hooks: &tfmigrate.HooksConfig{
+					PreApply:  []string{"./notify.sh start"},
+					PostApply: []string{"terraform -chdir=dir1 apply -refresh-only -auto-approve", "./notify.sh done"},
+					OnFailure: []string{"./notify.sh failed"},
+				},
+				Migrator: &tfmigrate.MockMigratorConfig{
+					PlanError:  false,
+					ApplyError: false,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "mock with empty hook",
+			source: `
+migration "mock" "test" {
+	plan_error  = false
+	apply_error = false
+	hooks {
+		pre_apply = [""]
+	}
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "mock with invalid skip_if",
+			source: `
+migration "mock" "test" {
+	skip_if     = "foo"
+	plan_error  = true
+	apply_error = false
+}
+`,
+			want: nil,
+			ok:   false,
+		},
 		{
 			desc: "state with dir",
 			source: `
@@ -196,6 +318,351 @@ migration "multi_state" "mv_dir1_dir2" {
 			},
 			ok: true,
 		},
+		{
+			desc: "state with env",
+			source: `
+migration "state" "test" {
+	dir = "dir1"
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+	]
+	env = {
+		AWS_PROFILE   = "legacy"
+		TF_VAR_region = "eu-west-1"
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir: "dir1",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+					},
+					Env: map[string]string{
+						"AWS_PROFILE":   "legacy",
+						"TF_VAR_region": "eu-west-1",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi state with env",
+			source: `
+migration "multi_state" "mv_dir1_dir2" {
+	from_dir = "dir1"
+	to_dir   = "dir2"
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+	]
+	env = {
+		TF_VAR_region = "eu-west-1"
+	}
+	from_env = {
+		AWS_PROFILE = "legacy"
+	}
+	to_env = {
+		AWS_PROFILE = "new"
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "multi_state",
+				Name: "mv_dir1_dir2",
+				Migrator: &tfmigrate.MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+					},
+					Env: map[string]string{
+						"TF_VAR_region": "eu-west-1",
+					},
+					FromEnv: map[string]string{
+						"AWS_PROFILE": "legacy",
+					},
+					ToEnv: map[string]string{
+						"AWS_PROFILE": "new",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "state with expectations",
+			source: `
+migration "state" "test" {
+	dir = "dir1"
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+	]
+	allow = [
+		"update:null_resource.bar",
+		"replace:null_resource.baz",
+	]
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir: "dir1",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+					},
+					Allow: []string{
+						"update:null_resource.bar",
+						"replace:null_resource.baz",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "state with action blocks",
+			source: `
+migration "state" "test" {
+	dir = "dir1"
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+	]
+	action {
+		command = "mv null_resource.bar null_resource.bar2"
+		force   = true
+	}
+	action {
+		command       = "mv null_resource.baz null_resource.baz2"
+		allow_changes = ["update:null_resource.baz2"]
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir: "dir1",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						"mv null_resource.bar null_resource.bar2",
+						"mv null_resource.baz null_resource.baz2",
+					},
+					ActionConfigs: []tfmigrate.ActionConfig{
+						{
+							Command: "mv null_resource.bar null_resource.bar2",
+							Force:   true,
+						},
+						{
+							Command:      "mv null_resource.baz null_resource.baz2",
+							AllowChanges: []string{"update:null_resource.baz2"},
+						},
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "state with action blocks around actions",
+			source: `
+migration "state" "test" {
+	dir = "dir1"
+	action {
+		command = "mv null_resource.bar null_resource.bar2"
+		force   = true
+	}
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+	]
+	action {
+		command       = "mv null_resource.baz null_resource.baz2"
+		allow_changes = ["update:null_resource.baz2"]
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir: "dir1",
+					Actions: []string{
+						"mv null_resource.bar null_resource.bar2",
+						"mv null_resource.foo null_resource.foo2",
+						"mv null_resource.baz null_resource.baz2",
+					},
+					ActionConfigs: []tfmigrate.ActionConfig{
+						{
+							Command: "mv null_resource.bar null_resource.bar2",
+							Force:   true,
+						},
+						{
+							Command:      "mv null_resource.baz null_resource.baz2",
+							AllowChanges: []string{"update:null_resource.baz2"},
+						},
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi state with action blocks before actions",
+			source: `
+migration "multi_state" "mv_dir1_dir2" {
+	from_dir = "dir1"
+	to_dir   = "dir2"
+	action {
+		command = "mv null_resource.foo null_resource.foo2"
+		force   = true
+	}
+	actions = [
+		"mv null_resource.bar null_resource.bar2",
+	]
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "multi_state",
+				Name: "mv_dir1_dir2",
+				Migrator: &tfmigrate.MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						"mv null_resource.bar null_resource.bar2",
+					},
+					ActionConfigs: []tfmigrate.ActionConfig{
+						{
+							Command: "mv null_resource.foo null_resource.foo2",
+							Force:   true,
+						},
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi state with action blocks only",
+			source: `
+migration "multi_state" "mv_dir1_dir2" {
+	from_dir = "dir1"
+	to_dir   = "dir2"
+	action {
+		command = "mv null_resource.foo null_resource.foo2"
+		force   = true
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "multi_state",
+				Name: "mv_dir1_dir2",
+				Migrator: &tfmigrate.MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+					},
+					ActionConfigs: []tfmigrate.ActionConfig{
+						{
+							Command: "mv null_resource.foo null_resource.foo2",
+							Force:   true,
+						},
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi state with backends",
+			source: `
+migration "multi_state" "mv_s3_to_tfc" {
+	from_dir = "dir1"
+	to_dir   = "dir2"
+	actions = [
+		"mv null_resource.foo null_resource.foo",
+	]
+	from_backend_config = [
+		"bucket=tfstate",
+		"key=dir1/terraform.tfstate",
+	]
+	to_is_backend_terraform_cloud = true
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "multi_state",
+				Name: "mv_s3_to_tfc",
+				Migrator: &tfmigrate.MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo",
+					},
+					FromBackendConfig: []string{
+						"bucket=tfstate",
+						"key=dir1/terraform.tfstate",
+					},
+					ToIsBackendTerraformCloud: &isTFC,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "state with mapping",
+			source: `
+migration "state" "test" {
+	dir = "dir1"
+	mapping = {
+		"null_resource.foo" = "null_resource.foo2"
+	}
+	absent  = ["null_resource.bar"]
+	imports = {
+		"time_static.qux" = "2006-01-02T15:04:05Z"
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir:     "dir1",
+					Mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+					Absent:  []string{"null_resource.bar"},
+					Imports: map[string]string{"time_static.qux": "2006-01-02T15:04:05Z"},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi state with mapping",
+			source: `
+migration "multi_state" "mv_dir1_dir2" {
+	from_dir = "dir1"
+	to_dir   = "dir2"
+	mapping = {
+		"null_resource.foo" = "null_resource.foo2"
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "multi_state",
+				Name: "mv_dir1_dir2",
+				Migrator: &tfmigrate.MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi state without actions",
+			source: `
+migration "multi_state" "mv_dir1_dir2" {
+	from_dir = "dir1"
+	to_dir   = "dir2"
+}
+`,
+			want: nil,
+			ok:   false,
+		},
 		{
 			desc: "multi state without from_dir",
 			source: `
@@ -445,3 +912,305 @@ func TestParseMigrationFileWithJsonSyntax(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatMigrationFile(t *testing.T) {
+	cases := []struct {
+		desc string
+		mc   *tfmigrate.MigrationConfig
+		want string
+		ok   bool
+	}{
+		{
+			desc: "state",
+			mc: &tfmigrate.MigrationConfig{
+				Type:       "state",
+				Name:       "test",
+				DependsOn:  []string{"20201231000000_foo.hcl"},
+				Owner:      "team1",
+				ApprovedBy: []string{"team2"},
+				Unprotect:  []string{"aws_kms_key.*"},
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir:       "dir1",
+					Workspace: "work1",
+					Env:       map[string]string{"AWS_PROFILE": "foo"},
+					VarFiles:  []string{"prod.tfvars"},
+					Vars:      map[string]string{"region": "ap-northeast-1"},
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						`import null_resource.bar["a b"] ${bar}`,
+					},
+					Force:    true,
+					SkipPlan: true,
+				},
+			},
+			want: `migration "state" "test" {
+  depends_on = [
+    "20201231000000_foo.hcl",
+  ]
+  owner = "team1"
+  approved_by = [
+    "team2",
+  ]
+  unprotect = [
+    "aws_kms_key.*",
+  ]
+  dir       = "dir1"
+  workspace = "work1"
+  env = {
+    AWS_PROFILE = "foo"
+  }
+  var_files = [
+    "prod.tfvars",
+  ]
+  vars = {
+    region = "ap-northeast-1"
+  }
+  force        = true
+  to_skip_plan = true
+  actions = [
+    "mv null_resource.foo null_resource.foo2",
+    "import null_resource.bar[\"a b\"] $${bar}",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "state with expectations",
+			mc: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+					},
+					Allow: []string{"update:null_resource.bar"},
+				},
+			},
+			want: `migration "state" "test" {
+  allow = [
+    "update:null_resource.bar",
+  ]
+  actions = [
+    "mv null_resource.foo null_resource.foo2",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "state with hooks",
+			mc: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Hooks: &tfmigrate.HooksConfig{
+					PreApply:  []string{"./notify.sh start"},
+					OnFailure: []string{"./notify.sh failed"},
+				},
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+					},
+				},
+			},
+			want: `migration "state" "test" {
+  actions = [
+    "mv null_resource.foo null_resource.foo2",
+  ]
+  hooks {
+    pre_apply = [
+      "./notify.sh start",
+    ]
+    on_failure = [
+      "./notify.sh failed",
+    ]
+  }
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "multi_state",
+			mc: &tfmigrate.MigrationConfig{
+				Type: "multi_state",
+				Name: "test",
+				Migrator: &tfmigrate.MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{"mv null_resource.foo null_resource.foo"},
+				},
+			},
+			want: "",
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := FormatMigrationFile(tc.mc)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", string(got))
+			}
+			if tc.ok {
+				if string(got) != tc.want {
+					t.Errorf("got: %s, want: %s", string(got), tc.want)
+				}
+				// The result should be parsed back to the same config.
+				mc, err := ParseMigrationFile("test.hcl", got)
+				if err != nil {
+					t.Fatalf("failed to parse the result: %s", err)
+				}
+				if !reflect.DeepEqual(mc, tc.mc) {
+					t.Errorf("parsed: %#v, want: %#v", mc, tc.mc)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatMovedBlocks(t *testing.T) {
+	cases := []struct {
+		desc   string
+		blocks []tfmigrate.MovedBlock
+		want   string
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			blocks: []tfmigrate.MovedBlock{
+				{From: "null_resource.foo", To: "null_resource.bar"},
+				{From: `module.foo["a b"]`, To: "module.bar[0]"},
+			},
+			want: `moved {
+  from = null_resource.foo
+  to   = null_resource.bar
+}
+
+moved {
+  from = module.foo["a b"]
+  to   = module.bar[0]
+}
+`,
+			ok: true,
+		},
+		{
+			desc:   "empty",
+			blocks: []tfmigrate.MovedBlock{},
+			want:   "",
+			ok:     true,
+		},
+		{
+			desc: "invalid address",
+			blocks: []tfmigrate.MovedBlock{
+				{From: "null_resource.foo[", To: "null_resource.bar"},
+			},
+			want: "",
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := FormatMovedBlocks(tc.blocks)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", string(got))
+			}
+			if tc.ok && string(got) != tc.want {
+				t.Errorf("got: %s, want: %s", string(got), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseMigrationFileWithImportFile(t *testing.T) {
+	dir := t.TempDir()
+	imports := "aws_instance.example[\"prod\"],i-1234\naws_instance.example[\"stg\"],i-5678\n"
+	if err := os.WriteFile(filepath.Join(dir, "imports.csv"), []byte(imports), 0600); err != nil {
+		t.Fatalf("failed to write import file: %s", err)
+	}
+
+	cases := []struct {
+		desc   string
+		source string
+		want   *tfmigrate.MigrationConfig
+		ok     bool
+	}{
+		{
+			desc: "import_file only",
+			source: `
+migration "state" "test" {
+  dir         = "dir1"
+  import_file = "imports.csv"
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir: "dir1",
+					Actions: []string{
+						`import aws_instance.example["prod"] i-1234`,
+						`import aws_instance.example["stg"] i-5678`,
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "import_file after actions",
+			source: `
+migration "state" "test" {
+  actions = [
+    "rm aws_instance.example",
+  ]
+  import_file = "imports.csv"
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Actions: []string{
+						"rm aws_instance.example",
+						`import aws_instance.example["prod"] i-1234`,
+						`import aws_instance.example["stg"] i-5678`,
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "import_file not found",
+			source: `
+migration "state" "test" {
+  import_file = "not_found.csv"
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseMigrationFile(filepath.Join(dir, "test.hcl"), []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
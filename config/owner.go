@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// OwnerBlock represents a block for a team which owns resources in HCL.
+type OwnerBlock struct {
+	// Team is a name of the team.
+	Team string `hcl:"team,label"`
+	// Prefixes is a list of address prefixes owned by the team.
+	Prefixes []string `hcl:"prefixes"`
+	// Members is a list of approver identities who belong to the team.
+	Members []string `hcl:"members,optional"`
+}
+
+// parseOwnerBlocks parses owner blocks and returns a *tfmigrate.Ownership.
+// It returns nil if no owner block is defined.
+func parseOwnerBlocks(bs []OwnerBlock) (*tfmigrate.Ownership, error) {
+	if len(bs) == 0 {
+		return nil, nil
+	}
+
+	ownership := &tfmigrate.Ownership{}
+	teams := make(map[string]struct{})
+	prefixes := make(map[string]string)
+	for _, b := range bs {
+		if _, ok := teams[b.Team]; ok {
+			return nil, fmt.Errorf("duplicated owner: %s", b.Team)
+		}
+		teams[b.Team] = struct{}{}
+
+		if len(b.Prefixes) == 0 {
+			return nil, fmt.Errorf("prefixes of owner %s must not be empty", b.Team)
+		}
+		for _, p := range b.Prefixes {
+			if len(p) == 0 {
+				return nil, fmt.Errorf("prefix of owner %s must not be empty", b.Team)
+			}
+			if team, ok := prefixes[p]; ok {
+				return nil, fmt.Errorf("prefix %s is owned by both %s and %s", p, team, b.Team)
+			}
+			prefixes[p] = b.Team
+		}
+
+		ownership.Owners = append(ownership.Owners, &tfmigrate.Owner{
+			Team:     b.Team,
+			Prefixes: b.Prefixes,
+			Members:  b.Members,
+		})
+	}
+
+	return ownership, nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestParseOwnerBlocks(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   *tfmigrate.Ownership
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  owner "network" {
+    prefixes = ["module.network", "aws_vpc."]
+    members  = ["alice", "bob"]
+  }
+  owner "app" {
+    prefixes = ["module.app"]
+  }
+}
+`,
+			want: &tfmigrate.Ownership{
+				Owners: []*tfmigrate.Owner{
+					{
+						Team:     "network",
+						Prefixes: []string{"module.network", "aws_vpc."},
+						Members:  []string{"alice", "bob"},
+					},
+					{
+						Team:     "app",
+						Prefixes: []string{"module.app"},
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "no owner",
+			source: `
+tfmigrate {
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "duplicated owner",
+			source: `
+tfmigrate {
+  owner "network" {
+    prefixes = ["module.network"]
+  }
+  owner "network" {
+    prefixes = ["module.vpc"]
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "empty prefixes",
+			source: `
+tfmigrate {
+  owner "network" {
+    prefixes = []
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "prefix owned by multiple teams",
+			source: `
+tfmigrate {
+  owner "network" {
+    prefixes = ["module.network"]
+  }
+  owner "app" {
+    prefixes = ["module.network"]
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.Ownership
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
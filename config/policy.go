@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// PolicyBlock represents a block for organization policy of migrations in HCL.
+type PolicyBlock struct {
+	// DefaultForce is a value of force for migrations which don't set it.
+	DefaultForce bool `hcl:"default_force,optional"`
+	// DenyForce rejects migrations which set force to true.
+	DenyForce bool `hcl:"deny_force,optional"`
+	// DenySkipPlan rejects migrations which skip terraform plan.
+	DenySkipPlan bool `hcl:"deny_skip_plan,optional"`
+	// RequiredPlanOptions is a list of options which must be passed to
+	// terraform plan.
+	RequiredPlanOptions []string `hcl:"required_plan_options,optional"`
+	// BannedPlanOptions is a list of options which must not be passed to
+	// terraform plan.
+	BannedPlanOptions []string `hcl:"banned_plan_options,optional"`
+	// StrictDirs turns warnings on checking working directories into errors.
+	StrictDirs bool `hcl:"strict_dirs,optional"`
+	// ProtectedAddresses is a list of address patterns which no migration may
+	// rm or mv without unprotecting them.
+	ProtectedAddresses []string `hcl:"protected_addresses,optional"`
+}
+
+// parsePolicyBlock parses a policy block and returns a *tfmigrate.MigrationPolicy.
+func parsePolicyBlock(b PolicyBlock) (*tfmigrate.MigrationPolicy, error) {
+	if b.DefaultForce && b.DenyForce {
+		return nil, fmt.Errorf("default_force and deny_force cannot be true at the same time")
+	}
+
+	for _, o := range append(append([]string{}, b.RequiredPlanOptions...), b.BannedPlanOptions...) {
+		if !strings.HasPrefix(o, "-") {
+			return nil, fmt.Errorf("invalid plan option in policy: %q, it must start with a dash", o)
+		}
+	}
+
+	for _, a := range b.ProtectedAddresses {
+		if len(a) == 0 {
+			return nil, fmt.Errorf("protected address in policy must not be empty")
+		}
+	}
+
+	policy := &tfmigrate.MigrationPolicy{
+		DefaultForce:        b.DefaultForce,
+		DenyForce:           b.DenyForce,
+		DenySkipPlan:        b.DenySkipPlan,
+		RequiredPlanOptions: b.RequiredPlanOptions,
+		BannedPlanOptions:   b.BannedPlanOptions,
+		StrictDirs:          b.StrictDirs,
+		ProtectedAddresses:  b.ProtectedAddresses,
+	}
+
+	return policy, nil
+}
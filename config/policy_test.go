@@ -0,0 +1,171 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestParsePolicyBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   *tfmigrate.MigrationPolicy
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  policy {
+    deny_force            = true
+    deny_skip_plan        = true
+    required_plan_options = ["-lock-timeout"]
+    banned_plan_options   = ["-refresh=false"]
+    strict_dirs           = true
+    protected_addresses   = ["aws_kms_key.*", "module.prod_db"]
+  }
+}
+`,
+			want: &tfmigrate.MigrationPolicy{
+				DefaultForce:        false,
+				DenyForce:           true,
+				DenySkipPlan:        true,
+				RequiredPlanOptions: []string{"-lock-timeout"},
+				BannedPlanOptions:   []string{"-refresh=false"},
+				StrictDirs:          true,
+				ProtectedAddresses:  []string{"aws_kms_key.*", "module.prod_db"},
+			},
+			ok: true,
+		},
+		{
+			desc: "default_force",
+			source: `
+tfmigrate {
+  policy {
+    default_force = true
+  }
+}
+`,
+			want: &tfmigrate.MigrationPolicy{
+				DefaultForce: true,
+			},
+			ok: true,
+		},
+		{
+			desc: "no policy",
+			source: `
+tfmigrate {
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "default_force and deny_force",
+			source: `
+tfmigrate {
+  policy {
+    default_force = true
+    deny_force    = true
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "invalid plan option",
+			source: `
+tfmigrate {
+  policy {
+    banned_plan_options = ["refresh=false"]
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.Policy
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMigrationFileWithPolicy(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		policy *tfmigrate.MigrationPolicy
+		want   bool
+	}{
+		{
+			desc: "no policy",
+			source: `
+migration "state" "test" {
+  actions = ["rm null_resource.foo"]
+}
+`,
+			policy: nil,
+			want:   false,
+		},
+		{
+			desc: "default_force",
+			source: `
+migration "state" "test" {
+  actions = ["rm null_resource.foo"]
+}
+`,
+			policy: &tfmigrate.MigrationPolicy{DefaultForce: true},
+			want:   true,
+		},
+		{
+			desc: "override default_force",
+			source: `
+migration "multi_state" "test" {
+  from_dir = "dir1"
+  to_dir   = "dir2"
+  actions  = ["mv null_resource.foo null_resource.foo"]
+  force    = false
+}
+`,
+			policy: &tfmigrate.MigrationPolicy{DefaultForce: true},
+			want:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mc, err := ParseMigrationFileWithOption("test.hcl", []byte(tc.source), &MigrationFileOption{Policy: tc.policy})
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			var got bool
+			switch c := mc.Migrator.(type) {
+			case *tfmigrate.StateMigratorConfig:
+				got = c.Force
+			case *tfmigrate.MultiStateMigratorConfig:
+				got = c.Force
+			}
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// StampBlock represents a block for stamping resources after migrations in HCL.
+type StampBlock struct {
+	// Command is a command line to stamp a resource.
+	Command string `hcl:"command"`
+	// Key is a key of the tag.
+	// Default to tfmigrate:last-migration.
+	Key string `hcl:"key,optional"`
+}
+
+// parseStampBlock parses a stamp block and returns a *tfmigrate.StampConfig.
+func parseStampBlock(b StampBlock) (*tfmigrate.StampConfig, error) {
+	if len(b.Command) == 0 {
+		return nil, fmt.Errorf("command of stamp must not be empty")
+	}
+
+	key := b.Key
+	if len(key) == 0 {
+		key = tfmigrate.DefaultStampKey
+	}
+
+	return &tfmigrate.StampConfig{
+		Command: b.Command,
+		Key:     key,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestParseStampBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   *tfmigrate.StampConfig
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  stamp {
+    command = "./bin/stamp --dry-run"
+    key     = "migration"
+  }
+}
+`,
+			want: &tfmigrate.StampConfig{
+				Command: "./bin/stamp --dry-run",
+				Key:     "migration",
+			},
+			ok: true,
+		},
+		{
+			desc: "default key",
+			source: `
+tfmigrate {
+  stamp {
+    command = "./bin/stamp"
+  }
+}
+`,
+			want: &tfmigrate.StampConfig{
+				Command: "./bin/stamp",
+				Key:     "tfmigrate:last-migration",
+			},
+			ok: true,
+		},
+		{
+			desc: "no stamp",
+			source: `
+tfmigrate {
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "empty command",
+			source: `
+tfmigrate {
+  stamp {
+    command = ""
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.Stamp
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -2,14 +2,22 @@ package config
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/azurerm"
+	"github.com/minamijoyo/tfmigrate/storage/consul"
+	"github.com/minamijoyo/tfmigrate/storage/etcd"
 	"github.com/minamijoyo/tfmigrate/storage/gcs"
+	"github.com/minamijoyo/tfmigrate/storage/git"
 	"github.com/minamijoyo/tfmigrate/storage/local"
 	"github.com/minamijoyo/tfmigrate/storage/mock"
 	"github.com/minamijoyo/tfmigrate/storage/s3"
+	"github.com/minamijoyo/tfmigrate/storage/tfc"
 )
 
 // StorageBlock represents a block for migration history data store in HCL.
@@ -19,6 +27,11 @@ type StorageBlock struct {
 	// - mock
 	// - local
 	// - s3
+	// - gcs
+	// - consul
+	// - etcd
+	// - tfc
+	// - git
 	Type string `hcl:"type,label"`
 	// Remain is a body of storage block.
 	// We first decode only a block header and then decode schema depending on
@@ -41,6 +54,21 @@ func parseStorageBlock(b StorageBlock) (storage.Config, error) {
 	case "gcs":
 		return parseGCSStorageBlock(b)
 
+	case "azurerm":
+		return parseAzurermStorageBlock(b)
+
+	case "consul":
+		return parseConsulStorageBlock(b)
+
+	case "etcd":
+		return parseEtcdStorageBlock(b)
+
+	case "tfc":
+		return parseTFCStorageBlock(b)
+
+	case "git":
+		return parseGitStorageBlock(b)
+
 	default:
 		return nil, fmt.Errorf("unknown history storage type: %s", b.Type)
 	}
@@ -79,6 +107,7 @@ func parseS3StorageBlock(b StorageBlock) (storage.Config, error) {
 	return &config, nil
 }
 
+// parseGCSStorageBlock parses a storage block for gcs and returns a storage.Config.
 func parseGCSStorageBlock(b StorageBlock) (storage.Config, error) {
 	var config gcs.Config
 	diags := gohcl.DecodeBody(b.Remain, nil, &config)
@@ -86,5 +115,108 @@ func parseGCSStorageBlock(b StorageBlock) (storage.Config, error) {
 		return nil, diags
 	}
 
+	if len(config.Name) == 0 && len(config.Prefix) == 0 {
+		return nil, fmt.Errorf("failed to parse gcs storage block: either name or prefix is required")
+	}
+
+	return &config, nil
+}
+
+// parseAzurermStorageBlock parses a storage block for azurerm and returns a storage.Config.
+func parseAzurermStorageBlock(b StorageBlock) (storage.Config, error) {
+	var config azurerm.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return &config, nil
+}
+
+// parseConsulStorageBlock parses a storage block for consul and returns a storage.Config.
+func parseConsulStorageBlock(b StorageBlock) (storage.Config, error) {
+	var config consul.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
 	return &config, nil
 }
+
+// parseEtcdStorageBlock parses a storage block for etcd and returns a storage.Config.
+func parseEtcdStorageBlock(b StorageBlock) (storage.Config, error) {
+	var config etcd.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return &config, nil
+}
+
+// parseTFCStorageBlock parses a storage block for tfc and returns a storage.Config.
+func parseTFCStorageBlock(b StorageBlock) (storage.Config, error) {
+	var config tfc.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if config.MaxRetries < 0 {
+		return nil, fmt.Errorf("failed to parse tfc storage block: max_retries must not be negative: %d", config.MaxRetries)
+	}
+
+	return &config, nil
+}
+
+// parseGitStorageBlock parses a storage block for git and returns a storage.Config.
+func parseGitStorageBlock(b StorageBlock) (storage.Config, error) {
+	var config git.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if path.IsAbs(config.Path) || path.Clean(config.Path) != config.Path || strings.HasPrefix(config.Path, "../") {
+		return nil, fmt.Errorf("failed to parse git storage block: path must be a clean relative path in the repository: %s", config.Path)
+	}
+	if config.MaxRetries < 0 {
+		return nil, fmt.Errorf("failed to parse git storage block: max_retries must not be negative: %d", config.MaxRetries)
+	}
+
+	return &config, nil
+}
+
+// namespaceStorageConfig rewrites a location of history in a given storage
+// config to be under a directory named after the project, so that multiple
+// projects can share a storage without key collisions.
+func namespaceStorageConfig(c storage.Config, project string) {
+	switch config := c.(type) {
+	case *local.Config:
+		config.Path = filepath.Join(filepath.Dir(config.Path), project, filepath.Base(config.Path))
+	case *s3.Config:
+		config.Key = path.Join(project, config.Key)
+	case *gcs.Config:
+		name := config.Name
+		if len(name) == 0 {
+			name = gcs.DefaultName
+		}
+		config.Name = path.Join(project, name)
+	case *azurerm.Config:
+		config.Key = path.Join(project, config.Key)
+	case *consul.Config:
+		config.Prefix = path.Join(config.Prefix, project)
+	case *etcd.Config:
+		config.Prefix = path.Join(config.Prefix, project)
+	case *git.Config:
+		config.Path = path.Join(path.Dir(config.Path), project, path.Base(config.Path))
+	case *tfc.Config:
+		// A variable key cannot contain slashes and dots.
+		key := config.Key
+		if len(key) == 0 {
+			key = tfc.DefaultKey
+		}
+		config.Key = key + "_" + strings.ReplaceAll(project, ".", "_")
+	}
+}
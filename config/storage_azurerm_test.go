@@ -0,0 +1,104 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/azurerm"
+)
+
+func TestParseAzurermStorageBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   storage.Config
+		ok     bool
+	}{
+		{
+			desc: "valid (required)",
+			source: `
+tfmigrate {
+  history {
+    storage "azurerm" {
+      storage_account_name = "tfstatetest"
+      container_name       = "tfstate"
+      key                  = "tfmigrate/history.json"
+    }
+  }
+}
+`,
+			want: &azurerm.Config{
+				StorageAccountName: "tfstatetest",
+				ContainerName:      "tfstate",
+				Key:                "tfmigrate/history.json",
+			},
+			ok: true,
+		},
+		{
+			desc: "valid (with optional)",
+			source: `
+tfmigrate {
+  history {
+    storage "azurerm" {
+      storage_account_name = "tfstatetest"
+      container_name       = "tfstate"
+      key                  = "tfmigrate/history.json"
+      access_key           = "c2VjcmV0"
+      sas_token            = "sv=2021-08-06&sig=foo"
+      use_msi              = true
+      client_id            = "00000000-0000-0000-0000-000000000000"
+      msi_endpoint         = "http://localhost:8080/msi"
+      endpoint             = "http://127.0.0.1:10000/devstoreaccount1"
+    }
+  }
+}
+`,
+			want: &azurerm.Config{
+				StorageAccountName: "tfstatetest",
+				ContainerName:      "tfstate",
+				Key:                "tfmigrate/history.json",
+				AccessKey:          "c2VjcmV0",
+				SasToken:           "sv=2021-08-06&sig=foo",
+				UseMSI:             true,
+				ClientID:           "00000000-0000-0000-0000-000000000000",
+				MSIEndpoint:        "http://localhost:8080/msi",
+				Endpoint:           "http://127.0.0.1:10000/devstoreaccount1",
+			},
+			ok: true,
+		},
+		{
+			desc: "missing required attribute (container_name)",
+			source: `
+tfmigrate {
+  history {
+    storage "azurerm" {
+      storage_account_name = "tfstatetest"
+      key                  = "tfmigrate/history.json"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.History.Storage
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/consul"
+)
+
+func TestParseConsulStorageBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   storage.Config
+		ok     bool
+	}{
+		{
+			desc: "valid (required)",
+			source: `
+tfmigrate {
+  history {
+    storage "consul" {
+      prefix = "tfmigrate"
+    }
+  }
+}
+`,
+			want: &consul.Config{
+				Prefix: "tfmigrate",
+			},
+			ok: true,
+		},
+		{
+			desc: "valid (with optional)",
+			source: `
+tfmigrate {
+  history {
+    storage "consul" {
+      address      = "http://consul.example.com:8500"
+      prefix       = "tfmigrate"
+      access_token = "secret"
+      datacenter   = "dc1"
+    }
+  }
+}
+`,
+			want: &consul.Config{
+				Address:     "http://consul.example.com:8500",
+				Prefix:      "tfmigrate",
+				AccessToken: "secret",
+				Datacenter:  "dc1",
+			},
+			ok: true,
+		},
+		{
+			desc: "missing required attribute (prefix)",
+			source: `
+tfmigrate {
+  history {
+    storage "consul" {
+      address = "http://consul.example.com:8500"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.History.Storage
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/etcd"
+)
+
+func TestParseEtcdStorageBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   storage.Config
+		ok     bool
+	}{
+		{
+			desc: "valid (required)",
+			source: `
+tfmigrate {
+  history {
+    storage "etcd" {
+      prefix = "tfmigrate"
+    }
+  }
+}
+`,
+			want: &etcd.Config{
+				Prefix: "tfmigrate",
+			},
+			ok: true,
+		},
+		{
+			desc: "valid (with optional)",
+			source: `
+tfmigrate {
+  history {
+    storage "etcd" {
+      endpoint = "http://etcd.example.com:2379"
+      prefix   = "tfmigrate"
+      username = "tfmigrate"
+      password = "secret"
+    }
+  }
+}
+`,
+			want: &etcd.Config{
+				Endpoint: "http://etcd.example.com:2379",
+				Prefix:   "tfmigrate",
+				Username: "tfmigrate",
+				Password: "secret",
+			},
+			ok: true,
+		},
+		{
+			desc: "missing required attribute (prefix)",
+			source: `
+tfmigrate {
+  history {
+    storage "etcd" {
+      endpoint = "http://etcd.example.com:2379"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.History.Storage
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -33,6 +33,32 @@ tfmigrate {
 			},
 			ok: true,
 		},
+		{
+			desc: "valid (with optional)",
+			source: `
+tfmigrate {
+  history {
+    storage "gcs" {
+      bucket                                = "tfmigrate-test"
+      prefix                                = "tfmigrate"
+      credentials                           = "/path/to/credentials.json"
+      impersonate_service_account           = "tfmigrate@example.iam.gserviceaccount.com"
+      impersonate_service_account_delegates = ["delegate@example.iam.gserviceaccount.com"]
+      kms_encryption_key                    = "projects/example/locations/global/keyRings/tfmigrate/cryptoKeys/history"
+    }
+  }
+}
+`,
+			want: &gcs.Config{
+				Bucket:                             "tfmigrate-test",
+				Prefix:                             "tfmigrate",
+				Credentials:                        "/path/to/credentials.json",
+				ImpersonateServiceAccount:          "tfmigrate@example.iam.gserviceaccount.com",
+				ImpersonateServiceAccountDelegates: []string{"delegate@example.iam.gserviceaccount.com"},
+				KmsEncryptionKey:                   "projects/example/locations/global/keyRings/tfmigrate/cryptoKeys/history",
+			},
+			ok: true,
+		},
 		{
 			desc: "missing required attribute (bucket)",
 			source: `
@@ -48,7 +74,7 @@ tfmigrate {
 			ok:   false,
 		},
 		{
-			desc: "missing required attribute (name)",
+			desc: "missing required attribute (name or prefix)",
 			source: `
 tfmigrate {
   history {
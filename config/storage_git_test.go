@@ -0,0 +1,145 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/git"
+)
+
+func TestParseGitStorageBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   storage.Config
+		ok     bool
+	}{
+		{
+			desc: "valid (required)",
+			source: `
+tfmigrate {
+  history {
+    storage "git" {
+      url  = "git@github.com:foo/bar.git"
+      path = "tfmigrate/history.json"
+    }
+  }
+}
+`,
+			want: &git.Config{
+				URL:  "git@github.com:foo/bar.git",
+				Path: "tfmigrate/history.json",
+			},
+			ok: true,
+		},
+		{
+			desc: "valid (with optional)",
+			source: `
+tfmigrate {
+  history {
+    storage "git" {
+      url             = "https://github.com/foo/bar.git"
+      branch          = "tfmigrate-history"
+      path            = "history.json"
+      committer_name  = "tfmigrate-bot"
+      committer_email = "tfmigrate-bot@example.com"
+      sign            = true
+      signing_key     = "ABCDEF0123456789"
+      max_retries     = 10
+    }
+  }
+}
+`,
+			want: &git.Config{
+				URL:            "https://github.com/foo/bar.git",
+				Branch:         "tfmigrate-history",
+				Path:           "history.json",
+				CommitterName:  "tfmigrate-bot",
+				CommitterEmail: "tfmigrate-bot@example.com",
+				Sign:           true,
+				SigningKey:     "ABCDEF0123456789",
+				MaxRetries:     10,
+			},
+			ok: true,
+		},
+		{
+			desc: "missing required attribute (url)",
+			source: `
+tfmigrate {
+  history {
+    storage "git" {
+      path = "tfmigrate/history.json"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "absolute path",
+			source: `
+tfmigrate {
+  history {
+    storage "git" {
+      url  = "git@github.com:foo/bar.git"
+      path = "/tfmigrate/history.json"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "path outside of the repository",
+			source: `
+tfmigrate {
+  history {
+    storage "git" {
+      url  = "git@github.com:foo/bar.git"
+      path = "../history.json"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "negative max_retries",
+			source: `
+tfmigrate {
+  history {
+    storage "git" {
+      url         = "git@github.com:foo/bar.git"
+      path        = "tfmigrate/history.json"
+      max_retries = -1
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.History.Storage
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
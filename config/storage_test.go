@@ -5,7 +5,15 @@ import (
 	"testing"
 
 	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/azurerm"
+	"github.com/minamijoyo/tfmigrate/storage/consul"
+	"github.com/minamijoyo/tfmigrate/storage/etcd"
+	"github.com/minamijoyo/tfmigrate/storage/gcs"
+	"github.com/minamijoyo/tfmigrate/storage/git"
 	"github.com/minamijoyo/tfmigrate/storage/local"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+	"github.com/minamijoyo/tfmigrate/storage/s3"
+	"github.com/minamijoyo/tfmigrate/storage/tfc"
 )
 
 func TestParseStorageBlock(t *testing.T) {
@@ -77,3 +85,71 @@ tfmigrate {
 		})
 	}
 }
+
+func TestNamespaceStorageConfig(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config storage.Config
+		want   storage.Config
+	}{
+		{
+			desc:   "local",
+			config: &local.Config{Path: "tmp/history.json"},
+			want:   &local.Config{Path: "tmp/foo/history.json"},
+		},
+		{
+			desc:   "s3",
+			config: &s3.Config{Bucket: "tfmigrate-test", Key: "tfmigrate/history.json"},
+			want:   &s3.Config{Bucket: "tfmigrate-test", Key: "foo/tfmigrate/history.json"},
+		},
+		{
+			desc:   "gcs",
+			config: &gcs.Config{Bucket: "tfmigrate-test", Name: "history.json"},
+			want:   &gcs.Config{Bucket: "tfmigrate-test", Name: "foo/history.json"},
+		},
+		{
+			desc:   "gcs with prefix",
+			config: &gcs.Config{Bucket: "tfmigrate-test", Prefix: "tfmigrate"},
+			want:   &gcs.Config{Bucket: "tfmigrate-test", Prefix: "tfmigrate", Name: "foo/history.json"},
+		},
+		{
+			desc:   "azurerm",
+			config: &azurerm.Config{StorageAccountName: "tfstatetest", ContainerName: "tfstate", Key: "tfmigrate/history.json"},
+			want:   &azurerm.Config{StorageAccountName: "tfstatetest", ContainerName: "tfstate", Key: "foo/tfmigrate/history.json"},
+		},
+		{
+			desc:   "consul",
+			config: &consul.Config{Prefix: "tfmigrate"},
+			want:   &consul.Config{Prefix: "tfmigrate/foo"},
+		},
+		{
+			desc:   "etcd",
+			config: &etcd.Config{Prefix: "tfmigrate"},
+			want:   &etcd.Config{Prefix: "tfmigrate/foo"},
+		},
+		{
+			desc:   "tfc",
+			config: &tfc.Config{Organization: "example-org", Workspace: "tfmigrate"},
+			want:   &tfc.Config{Organization: "example-org", Workspace: "tfmigrate", Key: "tfmigrate_history_foo"},
+		},
+		{
+			desc:   "git",
+			config: &git.Config{URL: "git@github.com:foo/bar.git", Path: "tfmigrate/history.json"},
+			want:   &git.Config{URL: "git@github.com:foo/bar.git", Path: "tfmigrate/foo/history.json"},
+		},
+		{
+			desc:   "mock",
+			config: &mock.Config{Data: "{}"},
+			want:   &mock.Config{Data: "{}"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			namespaceStorageConfig(tc.config, "foo")
+			if !reflect.DeepEqual(tc.config, tc.want) {
+				t.Errorf("got: %#v, want: %#v", tc.config, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/tfc"
+)
+
+func TestParseTFCStorageBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   storage.Config
+		ok     bool
+	}{
+		{
+			desc: "valid (required)",
+			source: `
+tfmigrate {
+  history {
+    storage "tfc" {
+      organization = "example-org"
+      workspace    = "tfmigrate"
+    }
+  }
+}
+`,
+			want: &tfc.Config{
+				Organization: "example-org",
+				Workspace:    "tfmigrate",
+			},
+			ok: true,
+		},
+		{
+			desc: "valid (with optional)",
+			source: `
+tfmigrate {
+  history {
+    storage "tfc" {
+      hostname     = "tfe.example.com"
+      organization = "example-org"
+      workspace    = "tfmigrate"
+      token        = "secret"
+      key          = "history"
+      max_retries  = 3
+    }
+  }
+}
+`,
+			want: &tfc.Config{
+				Hostname:     "tfe.example.com",
+				Organization: "example-org",
+				Workspace:    "tfmigrate",
+				Token:        "secret",
+				Key:          "history",
+				MaxRetries:   3,
+			},
+			ok: true,
+		},
+		{
+			desc: "missing required attribute (workspace)",
+			source: `
+tfmigrate {
+  history {
+    storage "tfc" {
+      organization = "example-org"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "negative max_retries",
+			source: `
+tfmigrate {
+  history {
+    storage "tfc" {
+      organization = "example-org"
+      workspace    = "tfmigrate"
+      max_retries  = -1
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.History.Storage
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
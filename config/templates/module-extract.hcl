@@ -0,0 +1,10 @@
+# Extract module.{{ .module }} from {{ .src }} into {{ .dst }}.
+# Before applying, move the module block from {{ .src }} to {{ .dst }},
+# so that plans in both directories have no changes after the migration.
+migration "multi_state" {{ hcl .name }} {
+  from_dir = {{ hcl .src }}
+  to_dir   = {{ hcl .dst }}
+  actions = [
+    {{ hcl (printf "mv module.%s %s" .module (or .to (printf "module.%s" .module))) }},
+  ]
+}
@@ -0,0 +1,12 @@
+# Replace the provider {{ .from }} with {{ .to }}.
+# Before applying, update required_providers in dir to reference the new
+# provider and run terraform init to install it.
+migration "state" {{ hcl .name }} {
+  dir = {{ hcl .dir }}
+{{- if .workspace }}
+  workspace = {{ hcl .workspace }}
+{{- end }}
+  actions = [
+    {{ hcl (printf "replace-provider %s %s" .from .to) }},
+  ]
+}
@@ -0,0 +1,12 @@
+# Adopt an existing resource as {{ .address }} by importing it.
+# Before applying, add the resource block to the configuration in dir,
+# so that the plan has no changes after importing it.
+migration "state" {{ hcl .name }} {
+  dir = {{ hcl .dir }}
+{{- if .workspace }}
+  workspace = {{ hcl .workspace }}
+{{- end }}
+  actions = [
+    {{ hcl (printf "import %s %s" .address .id) }},
+  ]
+}
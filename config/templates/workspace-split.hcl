@@ -0,0 +1,16 @@
+# Split resources from the {{ .from_workspace }} workspace into the
+# {{ .to_workspace }} workspace.
+# The {{ .to_workspace }} workspace is created on apply if it doesn't exist.
+# Make sure that the configuration manages the resources only in the new
+# workspace.
+migration "multi_state" {{ hcl .name }} {
+  from_dir       = {{ hcl .dir }}
+  from_workspace = {{ hcl .from_workspace }}
+  to_dir         = {{ hcl (or .to_dir .dir) }}
+  to_workspace   = {{ hcl .to_workspace }}
+  actions = [
+{{- range split .addresses }}
+    {{ hcl (printf "mv %s %s" . .) }},
+{{- end }}
+  ]
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// TerraformBlock represents a block for options of the terraform command
+// passed to every invocation in HCL.
+type TerraformBlock struct {
+	// Lock is a flag to lock the state, which is passed as -lock to every
+	// terraform command locking a state. Default to the terraform default.
+	Lock *bool `hcl:"lock,optional"`
+	// LockTimeout is a duration to retry a state lock such as 300s, which is
+	// passed as -lock-timeout to every terraform command locking a state.
+	LockTimeout string `hcl:"lock_timeout,optional"`
+}
+
+// parseTerraformBlock parses a terraform block and returns a
+// *tfexec.LockOptions. It returns nil if no options are set.
+func parseTerraformBlock(b TerraformBlock) (*tfexec.LockOptions, error) {
+	var timeout time.Duration
+	if len(b.LockTimeout) > 0 {
+		d, err := time.ParseDuration(b.LockTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse lock_timeout of terraform: %s, err: %s", b.LockTimeout, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("lock_timeout of terraform must not be negative: %s", b.LockTimeout)
+		}
+		timeout = d
+	}
+
+	if b.Lock == nil && timeout == 0 {
+		return nil, nil
+	}
+	if b.Lock != nil && !*b.Lock && timeout > 0 {
+		return nil, fmt.Errorf("lock_timeout of terraform cannot be set with lock = false")
+	}
+
+	return &tfexec.LockOptions{
+		Lock:    b.Lock,
+		Timeout: timeout,
+	}, nil
+}
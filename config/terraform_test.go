@@ -0,0 +1,117 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestParseTerraformBlock(t *testing.T) {
+	enabled := true
+	disabled := false
+	cases := []struct {
+		desc   string
+		source string
+		want   *tfexec.LockOptions
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  terraform {
+    lock         = true
+    lock_timeout = "300s"
+  }
+}
+`,
+			want: &tfexec.LockOptions{
+				Lock:    &enabled,
+				Timeout: 5 * time.Minute,
+			},
+			ok: true,
+		},
+		{
+			desc: "lock only",
+			source: `
+tfmigrate {
+  terraform {
+    lock = false
+  }
+}
+`,
+			want: &tfexec.LockOptions{
+				Lock: &disabled,
+			},
+			ok: true,
+		},
+		{
+			desc: "empty",
+			source: `
+tfmigrate {
+  terraform {
+  }
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "invalid lock_timeout",
+			source: `
+tfmigrate {
+  terraform {
+    lock_timeout = "foo"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "negative lock_timeout",
+			source: `
+tfmigrate {
+  terraform {
+    lock_timeout = "-1s"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "lock_timeout without lock",
+			source: `
+tfmigrate {
+  terraform {
+    lock         = false
+    lock_timeout = "300s"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.LockOptions
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -3,9 +3,16 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/minamijoyo/tfmigrate/event"
 	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/encryption"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	"github.com/minamijoyo/tfmigrate/window"
 )
 
 // ConfigurationFile represents a file for CLI settings in HCL.
@@ -23,8 +30,54 @@ type TfmigrateBlock struct {
 	// IsBackendTerraformCloud is a boolean indicating whether a backend is
 	// stored remotely in Terraform Cloud. Defaults to false.
 	IsBackendTerraformCloud bool `hcl:"is_backend_terraform_cloud,optional"`
+	// StatePushMethod is a method of pushing a new state to remote.
+	// Valid values are `cli`, `api` and `auto`. Default to `cli`.
+	StatePushMethod string `hcl:"state_push_method,optional"`
+	// ExecPath is a string how terraform command is executed such as `tofu`.
+	// The TFMIGRATE_EXEC_PATH environment variable takes precedence over it.
+	ExecPath string `hcl:"exec_path,optional"`
+	// ExecMode is a mode of executing the terraform command.
+	// Valid values are `terraform` and `terragrunt`. Default to `terraform`.
+	ExecMode string `hcl:"exec_mode,optional"`
+	// MatrixExecPaths is a list of exec paths of terraform or tofu binaries
+	// to be compared by the matrix command, such as `terraform1.5`.
+	MatrixExecPaths []string `hcl:"matrix_exec_paths,optional"`
+	// ReadOnlyPlan is a boolean indicating whether plan always runs in
+	// read-only mode and apply requires elevated credentials supplied
+	// separately. Defaults to false.
+	ReadOnlyPlan bool `hcl:"read_only_plan,optional"`
+	// Project is an identifier of the project.
+	// If set, a location of history in the storage is namespaced by it.
+	Project string `hcl:"project,optional"`
+	// Exec is a block for settings of executing the terraform command.
+	Exec *ExecBlock `hcl:"exec,block"`
+	// Terraform is a block for options passed to every terraform command.
+	Terraform *TerraformBlock `hcl:"terraform,block"`
 	// History is a block for migration history management.
 	History *HistoryBlock `hcl:"history,block"`
+	// ActionPlugins is a list of blocks for exec-based action plugins.
+	ActionPlugins []ActionPluginBlock `hcl:"action_plugin,block"`
+	// ApplyWindows is a list of blocks for allowed apply windows.
+	ApplyWindows []ApplyWindowBlock `hcl:"apply_window,block"`
+	// EventSinks is a list of blocks for destinations of migration lifecycle events.
+	EventSinks []EventSinkBlock `hcl:"event_sink,block"`
+	// Policy is a block for organization policy of migrations.
+	Policy *PolicyBlock `hcl:"policy,block"`
+	// Dirs is a block for directory aliases.
+	Dirs *DirsBlock `hcl:"dirs,block"`
+	// Variables is a block for variables of migration files.
+	Variables *VariablesBlock `hcl:"variables,block"`
+	// Owners is a list of blocks for teams which own resources.
+	Owners []OwnerBlock `hcl:"owner,block"`
+	// Stamp is a block for stamping resources after migrations.
+	Stamp *StampBlock `hcl:"stamp,block"`
+	// VarFiles is a list of default variable files passed to terraform plan
+	// in every migration. A relative path is resolved from the working
+	// directory of each migration.
+	VarFiles []string `hcl:"var_files,optional"`
+	// Vars is a map of default variables passed to terraform plan in every
+	// migration.
+	Vars map[string]string `hcl:"vars,optional"`
 }
 
 // TfmigrateConfig is a config for top-level CLI settings.
@@ -37,25 +90,87 @@ type TfmigrateConfig struct {
 	// IsBackendTerraformCloud is a boolean representing whether the remote
 	// backend is TerraformCloud. Defaults to a value of false.
 	IsBackendTerraformCloud bool
+	// StatePushMethod is a method of pushing a new state to remote. If it's
+	// `api` or `auto`, a state of the remote or cloud backend is pushed with
+	// Terraform Cloud API. Default to empty, which means `cli`.
+	StatePushMethod string
+	// ExecPath is a string how terraform command is executed such as `tofu`.
+	// Default to empty, which means `terraform`.
+	ExecPath string
+	// ExecMode is a mode of executing the terraform command. If it's
+	// `terragrunt`, the terraform command is invoked via terragrunt.
+	// Default to empty, which means `terraform`.
+	ExecMode string
+	// MatrixExecPaths is a list of exec paths of terraform or tofu binaries
+	// to be compared by the matrix command. The first one is the baseline.
+	MatrixExecPaths []string
+	// ReadOnlyPlan is a boolean indicating whether plan always runs in
+	// read-only mode, which refuses any mutating terraform command, and
+	// apply requires elevated credentials supplied separately.
+	ReadOnlyPlan bool
+	// Project is an identifier of the project to share a storage with others.
+	// Default to empty, which means no namespace.
+	Project string
+	// Retry is a policy to retry a terraform command which fails with a
+	// transient error. If nil, a command is never retried.
+	Retry *tfexec.RetryPolicy
+	// LockOptions is a set of options of state locking passed to every
+	// terraform command locking a state. If nil, no options are passed.
+	LockOptions *tfexec.LockOptions
 	// History is a config for migration history management.
 	History *history.Config
+	// ActionPlugins is a list of exec-based action plugins.
+	ActionPlugins []*tfmigrate.ActionPluginConfig
+	// ApplyWindows is a list of time windows in which apply is allowed.
+	// If empty, apply is allowed at any time.
+	ApplyWindows window.Windows
+	// EventSinks is a list of destinations of migration lifecycle events.
+	EventSinks []event.Config
+	// Policy is an organization policy of migrations.
+	// If nil, no policy is enforced.
+	Policy *tfmigrate.MigrationPolicy
+	// Dirs is a map of directory aliases which migration files can reference.
+	Dirs map[string]string
+	// Variables is a map of variables which migration files can reference.
+	Variables map[string]string
+	// Ownership is a mapping of address prefixes to owning teams.
+	// If nil, ownership is not checked.
+	Ownership *tfmigrate.Ownership
+	// Stamp is a post-apply step which stamps resources moved or imported by
+	// a migration with a tag. If nil, resources are not stamped.
+	Stamp *tfmigrate.StampConfig
+	// VarFiles is a list of default variable files passed to terraform plan
+	// in every migration. Variable files of a migration are passed after them.
+	VarFiles []string
+	// Vars is a map of default variables passed to terraform plan in every
+	// migration. Variables of a migration take precedence.
+	Vars map[string]string
 }
 
 // LoadConfigurationFile is a helper function which reads and parses a given configuration file.
+// Settings in the file are overridden by environment variables.
 func LoadConfigurationFile(filename string) (*TfmigrateConfig, error) {
 	source, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	return ParseConfigurationFile(filename, source)
+	return ParseConfigurationFileWithEnv(filename, source, os.Getenv)
 }
 
 // ParseConfigurationFile parses a given source of configuration file and
 // returns a TfmigrateConfig.
 // Note that this method does not read a file and you should pass source of config in bytes.
 // The filename is used for error message and selecting HCL syntax (.hcl and .json).
+// Environment variables are not applied.
 func ParseConfigurationFile(filename string, source []byte) (*TfmigrateConfig, error) {
+	return ParseConfigurationFileWithEnv(filename, source, noEnv)
+}
+
+// ParseConfigurationFileWithEnv is the same as ParseConfigurationFile, but
+// settings in the config file are overridden by environment variables looked
+// up with a given getenv.
+func ParseConfigurationFileWithEnv(filename string, source []byte, getenv Getenv) (*TfmigrateConfig, error) {
 	// Decode tfmigrate block.
 	var f ConfigurationFile
 	err := hclsimple.Decode(filename, source, nil, &f)
@@ -63,25 +178,219 @@ func ParseConfigurationFile(filename string, source []byte) (*TfmigrateConfig, e
 		return nil, fmt.Errorf("failed to decode setting file: %s, err: %s", filename, err)
 	}
 
+	return parseTfmigrateBlock(f.Tfmigrate, getenv)
+}
+
+// NewConfigFromEnv returns a new instance of TfmigrateConfig which is built
+// only from environment variables. It is used when no config file exists.
+func NewConfigFromEnv(getenv Getenv) (*TfmigrateConfig, error) {
+	return parseTfmigrateBlock(TfmigrateBlock{}, getenv)
+}
+
+// parseTfmigrateBlock parses a tfmigrate block and returns a TfmigrateConfig.
+// Settings in the block are overridden by environment variables.
+func parseTfmigrateBlock(b TfmigrateBlock, getenv Getenv) (*TfmigrateConfig, error) {
+	if err := applyEnv(&b, getenv); err != nil {
+		return nil, err
+	}
+
 	config := NewDefaultConfig()
-	if len(f.Tfmigrate.MigrationDir) > 0 {
-		config.MigrationDir = f.Tfmigrate.MigrationDir
+	if len(b.MigrationDir) > 0 {
+		config.MigrationDir = b.MigrationDir
+	}
+	if b.IsBackendTerraformCloud {
+		config.IsBackendTerraformCloud = b.IsBackendTerraformCloud
+	}
+	if len(b.ExecPath) > 0 {
+		config.ExecPath = b.ExecPath
+	}
+	if err := tfexec.ValidateExecMode(b.ExecMode); err != nil {
+		return nil, err
+	}
+	config.ExecMode = b.ExecMode
+	if err := tfmigrate.ValidateStatePushMethod(b.StatePushMethod); err != nil {
+		return nil, err
+	}
+	config.StatePushMethod = b.StatePushMethod
+	for _, p := range b.MatrixExecPaths {
+		if len(strings.TrimSpace(p)) == 0 {
+			return nil, fmt.Errorf("matrix_exec_paths must not contain an empty exec path")
+		}
+	}
+	config.MatrixExecPaths = b.MatrixExecPaths
+	config.ReadOnlyPlan = b.ReadOnlyPlan
+
+	if len(b.Project) > 0 {
+		if err := validateProject(b.Project); err != nil {
+			return nil, err
+		}
+		config.Project = b.Project
+	}
+
+	if b.Exec != nil {
+		retry, err := parseExecBlock(*b.Exec)
+		if err != nil {
+			return nil, err
+		}
+		config.Retry = retry
 	}
-	if f.Tfmigrate.IsBackendTerraformCloud {
-		config.IsBackendTerraformCloud = f.Tfmigrate.IsBackendTerraformCloud
+
+	if b.Terraform != nil {
+		lockOptions, err := parseTerraformBlock(*b.Terraform)
+		if err != nil {
+			return nil, err
+		}
+		config.LockOptions = lockOptions
 	}
 
-	if f.Tfmigrate.History != nil {
-		history, err := parseHistoryBlock(*f.Tfmigrate.History)
+	var h *history.Config
+	if b.History != nil {
+		var err error
+		h, err = parseHistoryBlock(*b.History)
 		if err != nil {
 			return nil, err
 		}
-		config.History = history
 	}
+	// Environment variables must be applied before namespacing.
+	h, err := applyHistoryEnv(h, getenv)
+	if err != nil {
+		return nil, err
+	}
+	if h != nil {
+		if len(config.Project) > 0 {
+			h.Project = config.Project
+			namespaceStorageConfig(h.Storage, config.Project)
+		}
+		config.History = h
+	}
+
+	plugins, err := parseActionPluginBlocks(b.ActionPlugins)
+	if err != nil {
+		return nil, err
+	}
+	config.ActionPlugins = plugins
+
+	windows, err := parseApplyWindowBlocks(b.ApplyWindows)
+	if err != nil {
+		return nil, err
+	}
+	config.ApplyWindows = windows
+
+	sinks, err := parseEventSinkBlocks(b.EventSinks)
+	if err != nil {
+		return nil, err
+	}
+	config.EventSinks = sinks
+
+	if b.Policy != nil {
+		policy, err := parsePolicyBlock(*b.Policy)
+		if err != nil {
+			return nil, err
+		}
+		config.Policy = policy
+	}
+
+	if b.Dirs != nil {
+		dirs, err := parseDirsBlock(*b.Dirs)
+		if err != nil {
+			return nil, err
+		}
+		config.Dirs = dirs
+	}
+
+	if b.Variables != nil {
+		vars, err := parseVariablesBlock(*b.Variables)
+		if err != nil {
+			return nil, err
+		}
+		config.Variables = vars
+	}
+
+	ownership, err := parseOwnerBlocks(b.Owners)
+	if err != nil {
+		return nil, err
+	}
+	config.Ownership = ownership
+
+	if b.Stamp != nil {
+		stamp, err := parseStampBlock(*b.Stamp)
+		if err != nil {
+			return nil, err
+		}
+		config.Stamp = stamp
+	}
+
+	for k := range b.Vars {
+		if len(k) == 0 || strings.ContainsAny(k, "= ") {
+			return nil, fmt.Errorf("invalid variable name in vars: %q", k)
+		}
+	}
+	config.VarFiles = b.VarFiles
+	config.Vars = b.Vars
 
 	return config, nil
 }
 
+// MigrationFileOption returns an option for parsing migration files, which
+// is derived from the config.
+func (c *TfmigrateConfig) MigrationFileOption() *MigrationFileOption {
+	return &MigrationFileOption{
+		Policy:    c.Policy,
+		Dirs:      c.Dirs,
+		Variables: c.Variables,
+	}
+}
+
+// EnableStrictMode turns on a bundle of safety behaviors at once:
+// force is denied, warnings on checking working directories are treated as
+// errors, and the history file is written with compare-and-swap.
+// It overrides the policy in the config file, creating one if not set.
+func (c *TfmigrateConfig) EnableStrictMode() {
+	if c.Policy == nil {
+		c.Policy = &tfmigrate.MigrationPolicy{}
+	}
+	c.Policy.DefaultForce = false
+	c.Policy.DenyForce = true
+	c.Policy.StrictDirs = true
+
+	if c.History != nil {
+		c.History.CompareAndSwap = true
+	}
+}
+
+// ValidateOffline returns an error if any component would make a network call
+// other than the backend and the history storage, such as event sinks, a
+// stamp command and a KMS key provider for encryption.
+func (c *TfmigrateConfig) ValidateOffline() error {
+	if len(c.EventSinks) > 0 {
+		return fmt.Errorf("event sinks are not allowed in offline mode: %s", eventSinkType(c.EventSinks[0]))
+	}
+
+	if c.Stamp != nil {
+		return fmt.Errorf("stamp is not allowed in offline mode")
+	}
+
+	if c.History != nil {
+		if _, ok := c.History.Encryption.(*encryption.KMSConfig); ok {
+			return fmt.Errorf("encryption with kms is not allowed in offline mode")
+		}
+	}
+
+	return nil
+}
+
+// projectRegexp is a pattern of a valid project identifier.
+// A slash is not allowed because it is used as a part of storage keys.
+var projectRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// validateProject validates a project identifier.
+func validateProject(project string) error {
+	if !projectRegexp.MatchString(project) {
+		return fmt.Errorf("invalid project: %q, it must consist of alphanumerics, dots, underscores and hyphens, and start with an alphanumeric", project)
+	}
+	return nil
+}
+
 // NewDefaultConfig returns a new instance of TfmigrateConfig.
 func NewDefaultConfig() *TfmigrateConfig {
 	return &TfmigrateConfig{
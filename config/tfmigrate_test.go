@@ -6,6 +6,7 @@ import (
 
 	"github.com/minamijoyo/tfmigrate/history"
 	"github.com/minamijoyo/tfmigrate/storage/local"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
 )
 
 func TestParseConfigurationFile(t *testing.T) {
@@ -58,6 +59,166 @@ tfmigrate {
 			},
 			ok: true,
 		},
+		{
+			desc: "with project",
+			source: `
+tfmigrate {
+  project = "foo"
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+  }
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				Project:      "foo",
+				History: &history.Config{
+					Project: "foo",
+					Storage: &local.Config{
+						Path: "tmp/foo/history.json",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "with exec_path",
+			source: `
+tfmigrate {
+  exec_path = "tofu"
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				ExecPath:     "tofu",
+			},
+			ok: true,
+		},
+		{
+			desc: "with exec_mode",
+			source: `
+tfmigrate {
+  exec_mode = "terragrunt"
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				ExecMode:     "terragrunt",
+			},
+			ok: true,
+		},
+		{
+			desc: "unknown exec_mode",
+			source: `
+tfmigrate {
+  exec_mode = "foo"
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "with state_push_method",
+			source: `
+tfmigrate {
+  is_backend_terraform_cloud = true
+  state_push_method          = "auto"
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir:            ".",
+				IsBackendTerraformCloud: true,
+				StatePushMethod:         "auto",
+			},
+			ok: true,
+		},
+		{
+			desc: "unknown state_push_method",
+			source: `
+tfmigrate {
+  state_push_method = "foo"
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "with matrix_exec_paths",
+			source: `
+tfmigrate {
+  matrix_exec_paths = ["terraform1.5", "terraform1.9", "tofu"]
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir:    ".",
+				MatrixExecPaths: []string{"terraform1.5", "terraform1.9", "tofu"},
+			},
+			ok: true,
+		},
+		{
+			desc: "empty matrix_exec_paths",
+			source: `
+tfmigrate {
+  matrix_exec_paths = ["terraform", " "]
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "with read_only_plan",
+			source: `
+tfmigrate {
+  read_only_plan = true
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				ReadOnlyPlan: true,
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid project",
+			source: `
+tfmigrate {
+  project = "../foo"
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "with vars",
+			source: `
+tfmigrate {
+  var_files = ["common.tfvars"]
+  vars = {
+    env = "prod"
+  }
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				VarFiles:     []string{"common.tfvars"},
+				Vars:         map[string]string{"env": "prod"},
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid var name",
+			source: `
+tfmigrate {
+  vars = {
+    "env=" = "prod"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
 		{
 			desc: "missing block (history)",
 			source: `
@@ -104,3 +265,147 @@ foo {
 		})
 	}
 }
+
+func TestTfmigrateConfigEnableStrictMode(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   *TfmigrateConfig
+	}{
+		{
+			desc: "no policy",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+  }
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				History: &history.Config{
+					Storage: &local.Config{
+						Path: "tmp/history.json",
+					},
+					CompareAndSwap: true,
+				},
+				Policy: &tfmigrate.MigrationPolicy{
+					DenyForce:  true,
+					StrictDirs: true,
+				},
+			},
+		},
+		{
+			desc: "override policy",
+			source: `
+tfmigrate {
+  policy {
+    default_force  = true
+    deny_skip_plan = true
+  }
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir: ".",
+				Policy: &tfmigrate.MigrationPolicy{
+					DenyForce:    true,
+					DenySkipPlan: true,
+					StrictDirs:   true,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if err != nil {
+				t.Fatalf("failed to parse config: %s", err)
+			}
+			got.EnableStrictMode()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTfmigrateConfigValidateOffline(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		ok     bool
+	}{
+		{
+			desc: "local storage with key encryption",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    encryption "key" {
+    }
+  }
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "event sink",
+			source: `
+tfmigrate {
+  event_sink "http" {
+    url = "https://example.com/webhook"
+  }
+}
+`,
+			ok: false,
+		},
+		{
+			desc: "stamp",
+			source: `
+tfmigrate {
+  stamp {
+    command = "./bin/stamp"
+  }
+}
+`,
+			ok: false,
+		},
+		{
+			desc: "kms encryption",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    encryption "kms" {
+      key_id = "alias/tfmigrate"
+    }
+  }
+}
+`,
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if err != nil {
+				t.Fatalf("failed to parse config: %s", err)
+			}
+			err = config.ValidateOffline()
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// EnvVariablePrefix is a prefix of environment variables which set
+// variables for migration files. The rest of the name is a variable name
+// such as TFMIGRATE_VAR_env.
+const EnvVariablePrefix = "TFMIGRATE_VAR_"
+
+// VariablesBlock represents a block for variables of migration files in HCL.
+// Each attribute defines a variable, such as `env = "prod"`.
+// Migration files can reference it as `var.env`, so that the same migration
+// can be parameterized across environments.
+type VariablesBlock struct {
+	// Remain is a body of variables block.
+	// Attribute names are arbitrary, so we decode it as just attributes.
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// parseVariablesBlock parses a variables block and returns a map of
+// variables.
+func parseVariablesBlock(b VariablesBlock) (map[string]string, error) {
+	attrs, diags := b.Remain.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	vars := make(map[string]string, len(attrs))
+	for name, attr := range attrs {
+		v, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		// Allow numbers and bools for convenience, such as an account ID.
+		s, err := convert.Convert(v, cty.String)
+		if err != nil || s.IsNull() {
+			return nil, fmt.Errorf("variable %s must be a string", name)
+		}
+		vars[name] = s.AsString()
+	}
+
+	return vars, nil
+}
+
+// ApplyVariablesEnv sets variables for migration files from given
+// environment variables in the form of os.Environ(), which have the
+// TFMIGRATE_VAR_ prefix. They take precedence over the variables block.
+func (c *TfmigrateConfig) ApplyVariablesEnv(environ []string) error {
+	for _, env := range environ {
+		k, v, _ := strings.Cut(env, "=")
+		name, ok := strings.CutPrefix(k, EnvVariablePrefix)
+		if !ok {
+			continue
+		}
+		if err := c.setVariable(name, v); err != nil {
+			return fmt.Errorf("invalid environment variable %s: %s", k, err)
+		}
+	}
+	return nil
+}
+
+// SetVariables sets variables for migration files from given values in the
+// format of NAME=VALUE. They take precedence over the variables block and
+// environment variables.
+func (c *TfmigrateConfig) SetVariables(values []string) error {
+	for _, value := range values {
+		k, v, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("invalid variable: %q, it must be in the format of NAME=VALUE", value)
+		}
+		if err := c.setVariable(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetVariablesFromFiles sets variables for migration files from given
+// variable files. A variable file has the same syntax as the body of the
+// variables block, such as `env = "prod"`. Later files take precedence over
+// earlier ones, and all of them take precedence over the variables block and
+// environment variables.
+func (c *TfmigrateConfig) SetVariablesFromFiles(filenames []string) error {
+	for _, filename := range filenames {
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read variable file: %s", err)
+		}
+
+		var b VariablesBlock
+		if err := hclsimple.Decode(filename, source, nil, &b); err != nil {
+			return fmt.Errorf("failed to decode variable file: %s, err: %s", filename, err)
+		}
+		vars, err := parseVariablesBlock(b)
+		if err != nil {
+			return fmt.Errorf("failed to parse variable file: %s, err: %s", filename, err)
+		}
+
+		for k, v := range vars {
+			if err := c.setVariable(k, v); err != nil {
+				return fmt.Errorf("invalid variable in %s: %s", filename, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setVariable sets a variable for migration files.
+func (c *TfmigrateConfig) setVariable(name string, value string) error {
+	if !hclsyntax.ValidIdentifier(name) {
+		return fmt.Errorf("invalid variable name: %q", name)
+	}
+	if c.Variables == nil {
+		c.Variables = make(map[string]string)
+	}
+	c.Variables[name] = value
+	return nil
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestParseVariablesBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   map[string]string
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			source: `
+tfmigrate {
+  variables {
+    env        = "prod"
+    account_id = 123456789012
+  }
+}
+`,
+			want: map[string]string{
+				"env":        "prod",
+				"account_id": "123456789012",
+			},
+			ok: true,
+		},
+		{
+			desc: "no variables",
+			source: `
+tfmigrate {
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "not a string",
+			source: `
+tfmigrate {
+  variables {
+    env = ["prod"]
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.Variables
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyVariables(t *testing.T) {
+	cases := []struct {
+		desc    string
+		vars    map[string]string
+		environ []string
+		values  []string
+		want    map[string]string
+		ok      bool
+	}{
+		{
+			desc:    "precedence",
+			vars:    map[string]string{"env": "dev", "region": "us-east-1", "account_id": "1"},
+			environ: []string{"TFMIGRATE_VAR_env=stage", "TFMIGRATE_VAR_region=ap-northeast-1", "HOME=/root"},
+			values:  []string{"env=prod"},
+			want:    map[string]string{"env": "prod", "region": "ap-northeast-1", "account_id": "1"},
+			ok:      true,
+		},
+		{
+			desc:    "no variables block",
+			vars:    nil,
+			environ: []string{"TFMIGRATE_VAR_env=stage"},
+			values:  []string{"url=https://example.com/?a=b"},
+			want:    map[string]string{"env": "stage", "url": "https://example.com/?a=b"},
+			ok:      true,
+		},
+		{
+			desc:    "empty value",
+			vars:    nil,
+			environ: nil,
+			values:  []string{"env="},
+			want:    map[string]string{"env": ""},
+			ok:      true,
+		},
+		{
+			desc:    "invalid name in env",
+			vars:    nil,
+			environ: []string{"TFMIGRATE_VAR_=foo"},
+			ok:      false,
+		},
+		{
+			desc:   "invalid name in flags",
+			vars:   nil,
+			values: []string{"1env=prod"},
+			ok:     false,
+		},
+		{
+			desc:   "invalid format",
+			vars:   nil,
+			values: []string{"env"},
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := NewDefaultConfig()
+			config.Variables = tc.vars
+			err := config.ApplyVariablesEnv(tc.environ)
+			if err == nil {
+				err = config.SetVariables(tc.values)
+			}
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config.Variables)
+			}
+			if tc.ok {
+				if !reflect.DeepEqual(config.Variables, tc.want) {
+					t.Errorf("got: %#v, want: %#v", config.Variables, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetVariablesFromFiles(t *testing.T) {
+	cases := []struct {
+		desc   string
+		vars   map[string]string
+		files  []string
+		values []string
+		want   map[string]string
+		ok     bool
+	}{
+		{
+			desc: "precedence",
+			vars: map[string]string{"env": "dev", "region": "us-east-1"},
+			files: []string{
+				`env = "stage"
+account_id = 123456789012
+`,
+				`env = "prod"`,
+			},
+			values: []string{"region=ap-northeast-1"},
+			want:   map[string]string{"env": "prod", "region": "ap-northeast-1", "account_id": "123456789012"},
+			ok:     true,
+		},
+		{
+			desc:  "block is not allowed",
+			files: []string{`foo {}`},
+			ok:    false,
+		},
+		{
+			desc:  "not a string",
+			files: []string{`env = ["prod"]`},
+			ok:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			filenames := []string{}
+			for i, f := range tc.files {
+				filename := filepath.Join(dir, fmt.Sprintf("%d.hcl", i))
+				if err := os.WriteFile(filename, []byte(f), 0600); err != nil {
+					t.Fatalf("failed to write a variable file: %s", err)
+				}
+				filenames = append(filenames, filename)
+			}
+
+			config := NewDefaultConfig()
+			config.Variables = tc.vars
+			err := config.SetVariablesFromFiles(filenames)
+			if err == nil {
+				err = config.SetVariables(tc.values)
+			}
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config.Variables)
+			}
+			if tc.ok {
+				if !reflect.DeepEqual(config.Variables, tc.want) {
+					t.Errorf("got: %#v, want: %#v", config.Variables, tc.want)
+				}
+			}
+		})
+	}
+
+	t.Run("file not found", func(t *testing.T) {
+		config := NewDefaultConfig()
+		if err := config.SetVariablesFromFiles([]string{filepath.Join(t.TempDir(), "not_found.hcl")}); err == nil {
+			t.Fatal("expected to return an error, but no error")
+		}
+	})
+}
+
+func TestParseMigrationFileWithVariables(t *testing.T) {
+	vars := map[string]string{
+		"env":        "prod",
+		"account_id": "123456789012",
+	}
+
+	cases := []struct {
+		desc   string
+		source string
+		want   tfmigrate.MigratorConfig
+		ok     bool
+	}{
+		{
+			desc: "state",
+			source: `
+migration "state" "test" {
+  dir     = "envs/${var.env}"
+  actions = ["import aws_iam_role.foo arn:aws:iam::${var.account_id}:role/foo"]
+}
+`,
+			want: &tfmigrate.StateMigratorConfig{
+				Dir:     "envs/prod",
+				Actions: []string{"import aws_iam_role.foo arn:aws:iam::123456789012:role/foo"},
+			},
+			ok: true,
+		},
+		{
+			desc: "default value with try",
+			source: `
+migration "state" "test" {
+  dir     = "envs/${try(var.region, "us-east-1")}"
+  actions = ["rm null_resource.foo"]
+}
+`,
+			want: &tfmigrate.StateMigratorConfig{
+				Dir:     "envs/us-east-1",
+				Actions: []string{"rm null_resource.foo"},
+			},
+			ok: true,
+		},
+		{
+			desc: "undefined variable",
+			source: `
+migration "state" "test" {
+  dir     = var.region
+  actions = ["rm null_resource.foo"]
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseMigrationFileWithOption("test.hcl", []byte(tc.source), &MigrationFileOption{Variables: vars})
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if !reflect.DeepEqual(got.Migrator, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got.Migrator, tc.want)
+				}
+			}
+		})
+	}
+}
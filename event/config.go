@@ -0,0 +1,7 @@
+package event
+
+// Config is an interface of factory method for Sink
+type Config interface {
+	// NewSink returns a new instance of Sink.
+	NewSink() (Sink, error)
+}
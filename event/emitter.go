@@ -0,0 +1,44 @@
+package event
+
+import (
+	"context"
+	"log"
+)
+
+// Emitter sends events to multiple sinks.
+type Emitter struct {
+	// sinks is a list of destinations of events.
+	sinks []Sink
+}
+
+// NewEmitter returns a new Emitter instance for given sink configs.
+func NewEmitter(configs []Config) (*Emitter, error) {
+	sinks := []Sink{}
+	for _, c := range configs {
+		s, err := c.NewSink()
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+
+	return &Emitter{
+		sinks: sinks,
+	}, nil
+}
+
+// Emit sends a given event to all sinks.
+// Events are notifications and should not affect results of migrations, so a
+// failure to send an event is only logged and never returned.
+func (e *Emitter) Emit(ctx context.Context, ev *Event) {
+	if e == nil {
+		return
+	}
+
+	for _, s := range e.sinks {
+		log.Printf("[DEBUG] [event] send an event: type = %s, subject = %s\n", ev.Type, ev.Subject)
+		if err := s.Send(ctx, ev); err != nil {
+			log.Printf("[WARN] [event] failed to send an event: type = %s, subject = %s, err: %s\n", ev.Type, ev.Subject, err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// mockSink is a mock implementation for testing.
+type mockSink struct {
+	events []*Event
+	err    error
+}
+
+// Send records a given event.
+func (s *mockSink) Send(_ context.Context, e *Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+func TestEmitterEmit(t *testing.T) {
+	ok := &mockSink{}
+	ng := &mockSink{err: fmt.Errorf("failed")}
+	e := &Emitter{
+		sinks: []Sink{ng, ok},
+	}
+
+	ev := NewEvent(TypeMigrationPlanned, &MigrationData{Filename: "foo.hcl"})
+	e.Emit(context.Background(), ev)
+
+	// a failure of a sink should not prevent others from receiving events.
+	if len(ok.events) != 1 || ok.events[0] != ev {
+		t.Errorf("expected to send an event, but got: %#v", ok.events)
+	}
+}
+
+func TestEmitterEmitNil(t *testing.T) {
+	var e *Emitter
+	// should not panic.
+	e.Emit(context.Background(), NewEvent(TypeMigrationPlanned, &MigrationData{Filename: "foo.hcl"}))
+}
@@ -0,0 +1,93 @@
+package event
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	// TypeMigrationPlanned is an event type emitted when a migration is planned.
+	TypeMigrationPlanned = "io.github.minamijoyo.tfmigrate.migration.planned"
+	// TypeMigrationApplied is an event type emitted when a migration is applied.
+	TypeMigrationApplied = "io.github.minamijoyo.tfmigrate.migration.applied"
+	// TypeMigrationFailed is an event type emitted when a migration failed to
+	// plan or apply.
+	TypeMigrationFailed = "io.github.minamijoyo.tfmigrate.migration.failed"
+	// TypeHookFailed is an event type emitted when a pre_apply hook failed,
+	// and the migration was not applied.
+	TypeHookFailed = "io.github.minamijoyo.tfmigrate.hook.failed"
+
+	// specVersion is a version of CloudEvents specification.
+	specVersion = "1.0"
+	// source is a context in which an event happened.
+	source = "tfmigrate"
+	// ContentType is a media type of an event in the structured content mode.
+	ContentType = "application/cloudevents+json"
+)
+
+// Event is a CloudEvents v1.0 event for migration lifecycle.
+// https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md
+type Event struct {
+	// SpecVersion is a version of CloudEvents specification.
+	SpecVersion string `json:"specversion"`
+	// ID identifies the event.
+	ID string `json:"id"`
+	// Source is a context in which the event happened.
+	Source string `json:"source"`
+	// Type is a type of the event.
+	Type string `json:"type"`
+	// Subject is a migration file name.
+	Subject string `json:"subject,omitempty"`
+	// Time is a timestamp when the event happened.
+	Time time.Time `json:"time"`
+	// DataContentType is a media type of data.
+	DataContentType string `json:"datacontenttype"`
+	// Data is a payload of the event.
+	Data *MigrationData `json:"data"`
+}
+
+// MigrationData is a payload of migration lifecycle events.
+type MigrationData struct {
+	// Filename is a migration file name.
+	Filename string `json:"filename"`
+	// MigrationType is a type of migration such as state and multi_state.
+	MigrationType string `json:"migration_type"`
+	// MigrationName is a name of migration.
+	MigrationName string `json:"migration_name"`
+	// Operation is an operation which caused the event. plan, apply or a
+	// hook such as pre_apply.
+	// It is prefixed with rollback- for the rollback command.
+	Operation string `json:"operation"`
+	// Error is an error message if the migration failed.
+	Error string `json:"error,omitempty"`
+}
+
+// NewEvent returns a new Event instance with a given type and data.
+// The ID and Time are set automatically.
+func NewEvent(eventType string, data *MigrationData) *Event {
+	return &Event{
+		SpecVersion:     specVersion,
+		ID:              newID(),
+		Source:          source,
+		Type:            eventType,
+		Subject:         data.Filename,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// Bytes encodes the event in the structured content mode.
+func (e *Event) Bytes() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// newID returns a random identifier of event.
+func newID() string {
+	b := make([]byte, 16)
+	// rand.Read never returns an error on supported platforms.
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
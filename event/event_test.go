@@ -0,0 +1,52 @@
+package event
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewEvent(t *testing.T) {
+	data := &MigrationData{
+		Filename:      "20201109000001_test1.hcl",
+		MigrationType: "state",
+		MigrationName: "test1",
+		Operation:     "apply",
+	}
+
+	e1 := NewEvent(TypeMigrationApplied, data)
+	e2 := NewEvent(TypeMigrationApplied, data)
+	if e1.ID == e2.ID {
+		t.Errorf("expected unique IDs, but got the same ID: %s", e1.ID)
+	}
+
+	b, err := e1.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("failed to decode event: %s", err)
+	}
+
+	want := map[string]interface{}{
+		"specversion":     "1.0",
+		"source":          "tfmigrate",
+		"type":            "io.github.minamijoyo.tfmigrate.migration.applied",
+		"subject":         "20201109000001_test1.hcl",
+		"datacontenttype": "application/json",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("got[%s] = %v, want = %v", k, got[k], v)
+		}
+	}
+	for _, k := range []string{"id", "time", "data"} {
+		if _, ok := got[k]; !ok {
+			t.Errorf("expected to have %s, but not found: %s", k, string(b))
+		}
+	}
+	if _, ok := got["data"].(map[string]interface{})["error"]; ok {
+		t.Errorf("expected to omit an empty error, but got: %s", string(b))
+	}
+}
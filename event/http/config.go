@@ -0,0 +1,27 @@
+package http
+
+import "github.com/minamijoyo/tfmigrate/event"
+
+// Config is a config for http sink.
+// Events are sent in the CloudEvents HTTP structured content mode.
+type Config struct {
+	// URL is an endpoint to which events are sent with POST.
+	URL string `hcl:"url"`
+	// Headers is a set of additional HTTP headers.
+	Headers map[string]string `hcl:"headers,optional"`
+	// HeadersFromEnv is a map of an HTTP header name to a name of environment
+	// variable which contains its value.
+	// It allows us to pass a secret such as a bearer token injected by CI
+	// without writing it in the config file.
+	HeadersFromEnv map[string]string `hcl:"headers_from_env,optional"`
+	// Timeout is a timeout in seconds for each request. Default to 10.
+	Timeout int `hcl:"timeout,optional"`
+}
+
+// Config implements an event.Config.
+var _ event.Config = (*Config)(nil)
+
+// NewSink returns a new instance of event.Sink.
+func (c *Config) NewSink() (event.Sink, error) {
+	return NewSink(c, nil)
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/event"
+)
+
+// DefaultTimeout is a default timeout for each request.
+const DefaultTimeout = 10 * time.Second
+
+// Sink is an event.Sink implementation for HTTP endpoints.
+type Sink struct {
+	// config is a sink config for http.
+	config *Config
+	// headers is a set of HTTP headers resolved from the config.
+	headers map[string]string
+	// client is an HTTP client.
+	// It is intended to be replaced for testing.
+	client *http.Client
+}
+
+var _ event.Sink = (*Sink)(nil)
+
+// NewSink returns a new instance of Sink.
+func NewSink(config *Config, client *http.Client) (*Sink, error) {
+	if len(config.URL) == 0 {
+		return nil, fmt.Errorf("url of http event sink must not be empty")
+	}
+
+	headers, err := resolveHeaders(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		timeout := DefaultTimeout
+		if config.Timeout > 0 {
+			timeout = time.Duration(config.Timeout) * time.Second
+		}
+		client = &http.Client{
+			Timeout: timeout,
+		}
+	}
+
+	s := &Sink{
+		config:  config,
+		headers: headers,
+		client:  client,
+	}
+
+	return s, nil
+}
+
+// resolveHeaders merges static headers and headers read from environment
+// variables. It returns an error if a referenced environment variable is not
+// set, rather than sending a request without credentials.
+func resolveHeaders(config *Config) (map[string]string, error) {
+	headers := make(map[string]string)
+	for k, v := range config.Headers {
+		if strings.EqualFold(k, "Authorization") {
+			log.Printf("[WARN] [event@http] a static Authorization header is set in the config. Consider using headers_from_env instead")
+		}
+		headers[k] = v
+	}
+
+	for k, env := range config.HeadersFromEnv {
+		v := os.Getenv(env)
+		if len(v) == 0 {
+			return nil, fmt.Errorf("environment variable %s for header %s of http event sink is not set", env, k)
+		}
+		headers[k] = v
+	}
+
+	return headers, nil
+}
+
+// Send sends an event to the sink.
+func (s *Sink) Send(ctx context.Context, e *event.Event) error {
+	b, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", event.ContentType)
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drain the body to reuse the connection.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("http event sink returns unexpected status: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/event"
+)
+
+func TestSinkSend(t *testing.T) {
+	cases := []struct {
+		desc   string
+		status int
+		ok     bool
+	}{
+		{
+			desc:   "ok",
+			status: http.StatusOK,
+			ok:     true,
+		},
+		{
+			desc:   "accepted",
+			status: http.StatusAccepted,
+			ok:     true,
+		},
+		{
+			desc:   "server error",
+			status: http.StatusInternalServerError,
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got *http.Request
+			var body []byte
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tc.status)
+			}))
+			defer ts.Close()
+
+			config := &Config{
+				URL: ts.URL,
+				Headers: map[string]string{
+					"X-Foo": "bar",
+				},
+			}
+			s, err := NewSink(config, nil)
+			if err != nil {
+				t.Fatalf("failed to NewSink: %s", err)
+			}
+
+			e := event.NewEvent(event.TypeMigrationApplied, &event.MigrationData{Filename: "foo.hcl"})
+			err = s.Send(context.Background(), e)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if got.Method != http.MethodPost {
+				t.Errorf("got method = %s, want = %s", got.Method, http.MethodPost)
+			}
+			if ct := got.Header.Get("Content-Type"); ct != event.ContentType {
+				t.Errorf("got Content-Type = %s, want = %s", ct, event.ContentType)
+			}
+			if h := got.Header.Get("X-Foo"); h != "bar" {
+				t.Errorf("got X-Foo = %s, want = bar", h)
+			}
+			var sent event.Event
+			if err := json.Unmarshal(body, &sent); err != nil {
+				t.Fatalf("failed to decode body: %s", err)
+			}
+			if sent.ID != e.ID || sent.Type != e.Type {
+				t.Errorf("got = %#v, want = %#v", sent, e)
+			}
+		})
+	}
+}
+
+func TestSinkSendHeadersFromEnv(t *testing.T) {
+	t.Setenv("TFMIGRATE_TEST_TOKEN", "secret")
+
+	var got *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	config := &Config{
+		URL: ts.URL,
+		HeadersFromEnv: map[string]string{
+			"Authorization": "TFMIGRATE_TEST_TOKEN",
+		},
+	}
+	s, err := NewSink(config, nil)
+	if err != nil {
+		t.Fatalf("failed to NewSink: %s", err)
+	}
+
+	e := event.NewEvent(event.TypeMigrationApplied, &event.MigrationData{Filename: "foo.hcl"})
+	if err := s.Send(context.Background(), e); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	if h := got.Header.Get("Authorization"); h != "secret" {
+		t.Errorf("got Authorization = %s, want = secret", h)
+	}
+}
+
+func TestConfigNewSink(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			config: &Config{
+				URL:     "https://example.com/events",
+				Timeout: 3,
+			},
+			ok: true,
+		},
+		{
+			desc:   "empty url",
+			config: &Config{},
+			ok:     false,
+		},
+		{
+			desc: "headers from unset env",
+			config: &Config{
+				URL: "https://example.com/events",
+				HeadersFromEnv: map[string]string{
+					"Authorization": "TFMIGRATE_TEST_UNSET_TOKEN",
+				},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.config.NewSink()
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				_ = got.(*Sink)
+			}
+		})
+	}
+}
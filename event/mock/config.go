@@ -0,0 +1,29 @@
+package mock
+
+import "github.com/minamijoyo/tfmigrate/event"
+
+// Config is a config for mock sink.
+type Config struct {
+	// SendError is a flag to return an error on Send().
+	SendError bool `hcl:"send_error,optional"`
+
+	// A reference to an instance of mock sink for testing.
+	s *Sink
+}
+
+// Config implements an event.Config.
+var _ event.Config = (*Config)(nil)
+
+// NewSink returns a new instance of event.Sink.
+func (c *Config) NewSink() (event.Sink, error) {
+	s, err := NewSink(c)
+
+	// store a reference for test assertion.
+	c.s = s
+	return s, err
+}
+
+// Sink returns a reference to mock sink for testing.
+func (c *Config) Sink() *Sink {
+	return c.s
+}
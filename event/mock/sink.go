@@ -0,0 +1,41 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/event"
+)
+
+// Sink is an event.Sink implementation for mock.
+// It records sent events in memory.
+type Sink struct {
+	// config is a sink config for mock.
+	config *Config
+	// events is a list of sent events.
+	events []*event.Event
+}
+
+var _ event.Sink = (*Sink)(nil)
+
+// NewSink returns a new instance of Sink.
+func NewSink(config *Config) (*Sink, error) {
+	s := &Sink{
+		config: config,
+	}
+	return s, nil
+}
+
+// Events returns a list of sent events for testing.
+func (s *Sink) Events() []*event.Event {
+	return s.events
+}
+
+// Send sends an event to the sink.
+func (s *Sink) Send(_ context.Context, e *event.Event) error {
+	if s.config.SendError {
+		return fmt.Errorf("failed to send an event to mock sink: sendError = %t", s.config.SendError)
+	}
+	s.events = append(s.events, e)
+	return nil
+}
@@ -0,0 +1,42 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+// Client is an abstraction layer for Google Cloud Pub/Sub API.
+// It is intended to be replaced with a mock for testing.
+type Client interface {
+	// Publish publishes a message to a topic.
+	Publish(ctx context.Context, topic string, msg *pubsub.PubsubMessage) error
+}
+
+// client is a real implementation of the Client.
+type client struct {
+	service *pubsub.Service
+}
+
+// newClient returns a new instance of Client.
+// It uses the Application Default Credentials.
+func newClient(ctx context.Context) (Client, error) {
+	service, err := pubsub.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to new pubsub client with the Application Default Credentials: %s", err)
+	}
+
+	return &client{
+		service: service,
+	}, nil
+}
+
+// Publish publishes a message to a topic.
+func (c *client) Publish(ctx context.Context, topic string, msg *pubsub.PubsubMessage) error {
+	req := &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{msg},
+	}
+	_, err := c.service.Projects.Topics.Publish(topic, req).Context(ctx).Do()
+	return err
+}
@@ -0,0 +1,18 @@
+package pubsub
+
+import "github.com/minamijoyo/tfmigrate/event"
+
+// Config is a config for Google Cloud Pub/Sub sink.
+type Config struct {
+	// Topic is a full name of the topic.
+	// e.g.) projects/my-project/topics/my-topic
+	Topic string `hcl:"topic"`
+}
+
+// Config implements an event.Config.
+var _ event.Config = (*Config)(nil)
+
+// NewSink returns a new instance of event.Sink.
+func (c *Config) NewSink() (event.Sink, error) {
+	return NewSink(c, nil)
+}
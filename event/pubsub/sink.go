@@ -0,0 +1,62 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/event"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+// Sink is an event.Sink implementation for Google Cloud Pub/Sub.
+type Sink struct {
+	// config is a sink config for pubsub.
+	config *Config
+	// client is an instance of Client interface to call API.
+	// It is intended to be replaced with a mock for testing.
+	client Client
+}
+
+var _ event.Sink = (*Sink)(nil)
+
+// NewSink returns a new instance of Sink.
+func NewSink(config *Config, client Client) (*Sink, error) {
+	if len(config.Topic) == 0 {
+		return nil, fmt.Errorf("topic of pubsub event sink must not be empty")
+	}
+
+	if client == nil {
+		var err error
+		client, err = newClient(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to new pubsub client: %s", err)
+		}
+	}
+
+	s := &Sink{
+		config: config,
+		client: client,
+	}
+
+	return s, nil
+}
+
+// Send sends an event to the sink.
+// The event is published in the structured content mode.
+func (s *Sink) Send(ctx context.Context, e *event.Event) error {
+	b, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+
+	msg := &pubsub.PubsubMessage{
+		Data: base64.StdEncoding.EncodeToString(b),
+		Attributes: map[string]string{
+			"content-type": event.ContentType,
+			"ce-type":      e.Type,
+		},
+	}
+
+	return s.client.Publish(ctx, s.config.Topic, msg)
+}
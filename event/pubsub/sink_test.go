@@ -0,0 +1,93 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/event"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+// mockClient is a mock implementation for testing.
+type mockClient struct {
+	topic string
+	msg   *pubsub.PubsubMessage
+	err   error
+}
+
+// Publish records a given message and returns a mocked error.
+func (c *mockClient) Publish(_ context.Context, topic string, msg *pubsub.PubsubMessage) error {
+	c.topic = topic
+	c.msg = msg
+	return c.err
+}
+
+func TestSinkSend(t *testing.T) {
+	cases := []struct {
+		desc   string
+		client *mockClient
+		ok     bool
+	}{
+		{
+			desc:   "simple",
+			client: &mockClient{},
+			ok:     true,
+		},
+		{
+			desc: "permission denied",
+			client: &mockClient{
+				err: fmt.Errorf("permission denied"),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				Topic: "projects/tfmigrate-test/topics/tfmigrate",
+			}
+			s, err := NewSink(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewSink: %s", err)
+			}
+
+			e := event.NewEvent(event.TypeMigrationPlanned, &event.MigrationData{Filename: "foo.hcl"})
+			err = s.Send(context.Background(), e)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if tc.client.topic != config.Topic {
+				t.Errorf("got topic = %s, want = %s", tc.client.topic, config.Topic)
+			}
+			if got := tc.client.msg.Attributes["ce-type"]; got != e.Type {
+				t.Errorf("got ce-type = %s, want = %s", got, e.Type)
+			}
+			b, err := base64.StdEncoding.DecodeString(tc.client.msg.Data)
+			if err != nil {
+				t.Fatalf("failed to decode data: %s", err)
+			}
+			var sent event.Event
+			if err := json.Unmarshal(b, &sent); err != nil {
+				t.Fatalf("failed to decode message: %s", err)
+			}
+			if sent.ID != e.ID {
+				t.Errorf("got ID = %s, want = %s", sent.ID, e.ID)
+			}
+		})
+	}
+}
+
+func TestNewSinkEmptyTopic(t *testing.T) {
+	_, err := NewSink(&Config{}, &mockClient{})
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
@@ -0,0 +1,11 @@
+package event
+
+import "context"
+
+// Sink is an abstraction layer for a destination of events.
+// To support multiple messaging services, a sink just sends a given event
+// and should not contain a domain specific logic.
+type Sink interface {
+	// Send sends an event to the sink.
+	Send(ctx context.Context, e *Event) error
+}
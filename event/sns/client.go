@@ -0,0 +1,41 @@
+package sns
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	awsbase "github.com/hashicorp/aws-sdk-go-base"
+)
+
+// Client is an abstraction layer for AWS SNS API.
+// It is intended to be replaced with a mock for testing.
+type Client interface {
+	// PublishWithContext publishes a message to a topic.
+	PublishWithContext(ctx aws.Context, input *sns.PublishInput, opts ...request.Option) (*sns.PublishOutput, error)
+}
+
+// newClient returns a new instance of Client.
+func newClient(config *Config) (Client, error) {
+	cfg := &awsbase.Config{
+		AssumeRoleARN: config.RoleARN,
+		Profile:       config.Profile,
+		Region:        config.Region,
+	}
+
+	sess, err := awsbase.GetSession(cfg)
+	if err != nil {
+		if awsbase.IsNoValidCredentialSourcesError(err) {
+			return nil, fmt.Errorf("failed to new sns client: no AWS credentials found. "+
+				"The sns event sink uses ambient credentials such as an instance profile, a task role or a web identity token: %s", err)
+		}
+		return nil, fmt.Errorf("failed to new sns client: %s", err)
+	}
+
+	client := sns.New(sess.Copy(&aws.Config{
+		Endpoint: aws.String(config.Endpoint),
+	}))
+
+	return client, nil
+}
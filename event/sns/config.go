@@ -0,0 +1,26 @@
+package sns
+
+import "github.com/minamijoyo/tfmigrate/event"
+
+// Config is a config for AWS SNS sink.
+type Config struct {
+	// TopicARN is an ARN of the SNS topic.
+	TopicARN string `hcl:"topic_arn"`
+
+	// AWS region.
+	Region string `hcl:"region,optional"`
+	// Custom endpoint for the AWS SNS API.
+	Endpoint string `hcl:"endpoint,optional"`
+	// Name of AWS profile in AWS shared credentials file.
+	Profile string `hcl:"profile,optional"`
+	// Amazon Resource Name (ARN) of the IAM Role to assume.
+	RoleARN string `hcl:"role_arn,optional"`
+}
+
+// Config implements an event.Config.
+var _ event.Config = (*Config)(nil)
+
+// NewSink returns a new instance of event.Sink.
+func (c *Config) NewSink() (event.Sink, error) {
+	return NewSink(c, nil)
+}
@@ -0,0 +1,67 @@
+package sns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/minamijoyo/tfmigrate/event"
+)
+
+// Sink is an event.Sink implementation for AWS SNS.
+type Sink struct {
+	// config is a sink config for sns.
+	config *Config
+	// client is an instance of Client interface to call API.
+	// It is intended to be replaced with a mock for testing.
+	client Client
+}
+
+var _ event.Sink = (*Sink)(nil)
+
+// NewSink returns a new instance of Sink.
+func NewSink(config *Config, client Client) (*Sink, error) {
+	if len(config.TopicARN) == 0 {
+		return nil, fmt.Errorf("topic_arn of sns event sink must not be empty")
+	}
+
+	if client == nil {
+		var err error
+		client, err = newClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Sink{
+		config: config,
+		client: client,
+	}
+
+	return s, nil
+}
+
+// Send sends an event to the sink.
+// The event is published in the structured content mode, and its type is also
+// set to a message attribute so that subscribers can filter events.
+func (s *Sink) Send(ctx context.Context, e *event.Event) error {
+	b, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+
+	input := &sns.PublishInput{
+		TopicArn: aws.String(s.config.TopicARN),
+		Message:  aws.String(string(b)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"ce_type": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(e.Type),
+			},
+		},
+	}
+
+	_, err = s.client.PublishWithContext(ctx, input)
+	return err
+}
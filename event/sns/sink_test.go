@@ -0,0 +1,92 @@
+package sns
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/minamijoyo/tfmigrate/event"
+)
+
+// mockClient is a mock implementation for testing.
+type mockClient struct {
+	input *sns.PublishInput
+	err   error
+}
+
+// PublishWithContext records a given input and returns a mocked response.
+func (c *mockClient) PublishWithContext(_ aws.Context, input *sns.PublishInput, _ ...request.Option) (*sns.PublishOutput, error) {
+	c.input = input
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &sns.PublishOutput{}, nil
+}
+
+func TestSinkSend(t *testing.T) {
+	cases := []struct {
+		desc   string
+		client *mockClient
+		ok     bool
+	}{
+		{
+			desc:   "simple",
+			client: &mockClient{},
+			ok:     true,
+		},
+		{
+			desc: "topic does not exist",
+			client: &mockClient{
+				err: awserr.New("NotFound", "Topic does not exist", nil),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				TopicARN: "arn:aws:sns:ap-northeast-1:123456789012:tfmigrate",
+			}
+			s, err := NewSink(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewSink: %s", err)
+			}
+
+			e := event.NewEvent(event.TypeMigrationFailed, &event.MigrationData{Filename: "foo.hcl", Error: "failed"})
+			err = s.Send(context.Background(), e)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			input := tc.client.input
+			if aws.StringValue(input.TopicArn) != config.TopicARN {
+				t.Errorf("got TopicArn = %s, want = %s", aws.StringValue(input.TopicArn), config.TopicARN)
+			}
+			if got := aws.StringValue(input.MessageAttributes["ce_type"].StringValue); got != e.Type {
+				t.Errorf("got ce_type = %s, want = %s", got, e.Type)
+			}
+			var sent event.Event
+			if err := json.Unmarshal([]byte(aws.StringValue(input.Message)), &sent); err != nil {
+				t.Fatalf("failed to decode message: %s", err)
+			}
+			if sent.ID != e.ID {
+				t.Errorf("got ID = %s, want = %s", sent.ID, e.ID)
+			}
+		})
+	}
+}
+
+func TestNewSinkEmptyTopic(t *testing.T) {
+	_, err := NewSink(&Config{}, &mockClient{})
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
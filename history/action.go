@@ -0,0 +1,93 @@
+package history
+
+import (
+	"time"
+)
+
+// ActionRecord represents a result of an action executed in a migration.
+type ActionRecord struct {
+	// Action is a plain text of the action.
+	// e.g.) mv aws_security_group.foo aws_security_group.foo2
+	Action string
+	// Status is a result of the action.
+	// Valid values are succeeded, failed and skipped.
+	Status string
+	// StartedAt is a timestamp when the action started.
+	// It is zero if the action was skipped.
+	StartedAt time.Time
+	// FinishedAt is a timestamp when the action finished.
+	// It is zero if the action was skipped.
+	FinishedAt time.Time
+	// Error is an error message if the action failed.
+	Error string
+}
+
+// AddFailure adds a failed migration log to history.
+// If a given filename already exists, it updates the existing failure.
+func (h *History) AddFailure(filename string, r Record) {
+	if h.failures == nil {
+		h.failures = make(map[string]Record)
+	}
+	h.failures[filename] = r
+}
+
+// Failure returns a failed migration log for a given migration.
+func (h *History) Failure(filename string) (Record, bool) {
+	r, ok := h.failures[filename]
+	return r, ok
+}
+
+// Record returns an applied migration log for a given migration.
+func (h *History) Record(filename string) (Record, bool) {
+	r, ok := h.records[filename]
+	return r, ok
+}
+
+// AddFailure adds a failed migration log to history.
+// This method doesn't persist history. Call Save() to save the history.
+// If failedAt is nil, a timestamp is automatically set to the current
+// time of the clock in the context passed to NewController.
+func (c *Controller) AddFailure(filename string, migrationType string, name string, actions []ActionRecord, failedAt *time.Time) {
+	timestamp := failedAt
+	if timestamp == nil {
+		now := c.now()
+		timestamp = &now
+	}
+	r := Record{
+		Type:      migrationType,
+		Name:      name,
+		AppliedAt: *timestamp,
+		Actions:   actions,
+		VCS:       c.vcs,
+	}
+
+	c.history.AddFailure(filename, r)
+	c.failureAdded = true
+	c.changes = append(c.changes, func(h *History) error {
+		// A failure is obsolete if someone else has applied the migration
+		// concurrently.
+		if !h.Contains(filename) {
+			h.AddFailure(filename, r)
+		}
+		return nil
+	})
+}
+
+// FailureAdded returns true if a failure has been added since the history
+// was loaded.
+func (c *Controller) FailureAdded() bool {
+	return c.failureAdded
+}
+
+// Failure returns a log of the last failed attempt for a given migration.
+// The second return value is false if the migration has never failed or has
+// been applied since then.
+func (c *Controller) Failure(filename string) (Record, bool) {
+	return c.history.Failure(filename)
+}
+
+// Record returns an applied migration log for a given migration.
+// The second return value is false if the migration has not been applied.
+func (c *Controller) Record(filename string) (Record, bool) {
+	return c.history.Record(filename)
+}
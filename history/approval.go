@@ -0,0 +1,175 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Approval represents an approval for a migration.
+type Approval struct {
+	// Approver is an identity of the approver.
+	Approver string
+	// ApprovedAt is a timestamp when the migration was approved.
+	ApprovedAt time.Time
+	// Digest is a hex-encoded sha256 digest of the migration file approved.
+	// An approval is valid only while the file has the same digest.
+	Digest string
+}
+
+// AddApproval adds an approval for a given migration.
+// It returns an error if the approver has already approved the same content
+// of the migration. An approval for a previous content by the same approver
+// is replaced.
+func (h *History) AddApproval(filename string, a Approval) error {
+	for i, v := range h.approvals[filename] {
+		if v.Approver != a.Approver {
+			continue
+		}
+		if v.Digest == a.Digest {
+			return fmt.Errorf("a migration has already been approved by %s: %s", a.Approver, filename)
+		}
+		h.approvals[filename][i] = a
+		return nil
+	}
+
+	if h.approvals == nil {
+		h.approvals = make(map[string][]Approval)
+	}
+	h.approvals[filename] = append(h.approvals[filename], a)
+	return nil
+}
+
+// Approvals returns a list of approvals for a given migration.
+func (h *History) Approvals(filename string) []Approval {
+	return h.approvals[filename]
+}
+
+// Approve records an approval for a given migration by a given approver.
+// This method doesn't persist history. Call Save() to save the history.
+// If approvedAt is nil, a timestamp is automatically set to the current
+// time of the clock in the context passed to NewController.
+func (c *Controller) Approve(filename string, approver string, approvedAt *time.Time) error {
+	if len(approver) == 0 {
+		return fmt.Errorf("approver must not be empty")
+	}
+
+	known := false
+	for _, m := range c.migrations {
+		if m == filename {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("no such migration file: %s", filename)
+	}
+
+	if c.history.Contains(filename) {
+		return fmt.Errorf("a migration has already been applied: %s", filename)
+	}
+
+	digest, err := c.migrationDigest(filename)
+	if err != nil {
+		return err
+	}
+
+	timestamp := approvedAt
+	if timestamp == nil {
+		now := c.now()
+		timestamp = &now
+	}
+	a := Approval{
+		Approver:   approver,
+		ApprovedAt: *timestamp,
+		Digest:     digest,
+	}
+
+	if err := c.history.AddApproval(filename, a); err != nil {
+		return err
+	}
+
+	c.changes = append(c.changes, func(h *History) error {
+		if h.Contains(filename) {
+			return fmt.Errorf("a migration has already been applied: %s", filename)
+		}
+		for _, v := range h.Approvals(filename) {
+			// The same approval has been recorded concurrently.
+			if v.Approver == approver && v.Digest == digest {
+				return nil
+			}
+		}
+		return h.AddApproval(filename, a)
+	})
+	return nil
+}
+
+// Approvals returns a list of approvals for a given migration.
+// It includes approvals for previous contents of the migration file.
+// Use ValidApprovals to get approvals for the current content.
+func (c *Controller) Approvals(filename string) []Approval {
+	return c.history.Approvals(filename)
+}
+
+// ValidApprovals returns a list of approvals for a given migration whose
+// digest matches the current content of the migration file.
+func (c *Controller) ValidApprovals(filename string) ([]Approval, error) {
+	digest, err := c.migrationDigest(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	approvals := []Approval{}
+	for _, a := range c.history.Approvals(filename) {
+		if a.Digest == digest {
+			approvals = append(approvals, a)
+		}
+	}
+	return approvals, nil
+}
+
+// migrationDigest returns a hex-encoded sha256 digest of a given migration
+// file in the migration dir.
+func (c *Controller) migrationDigest(filename string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(c.migrationDir, filename))
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration file: %s", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CheckApprovals returns an error if a given migration doesn't have enough
+// approvals required by the config.
+// An approval by the applier is not counted, so that nobody can approve and
+// apply a migration alone. If the applier is empty, all approvals are counted.
+// Approvals for a previous content of the migration file are not counted, so
+// that nobody can change a migration after it has been approved.
+func (c *Controller) CheckApprovals(filename string, applier string) error {
+	required := c.config.RequiredApprovals
+	if required == 0 {
+		return nil
+	}
+
+	approvals, err := c.ValidApprovals(filename)
+	if err != nil {
+		return err
+	}
+	got := 0
+	for _, a := range approvals {
+		if len(applier) != 0 && a.Approver == applier {
+			continue
+		}
+		got++
+	}
+	if got < required {
+		if len(applier) != 0 {
+			return fmt.Errorf("a migration requires %d approvals by others than the applier %s, but got %d: %s", required, applier, got, filename)
+		}
+		return fmt.Errorf("a migration requires %d approvals, but got %d: %s", required, got, filename)
+	}
+	return nil
+}
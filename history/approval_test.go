@@ -0,0 +1,302 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// setupApprovalMigrationDir writes given migration files to a temporary
+// directory and returns the path to it.
+func setupApprovalMigrationDir(t *testing.T, migrations map[string]string) string {
+	t.Helper()
+	migrationDir := t.TempDir()
+	for filename, source := range migrations {
+		if err := os.WriteFile(filepath.Join(migrationDir, filename), []byte(source), 0600); err != nil {
+			t.Fatalf("failed to write migration file: %s", err)
+		}
+	}
+	return migrationDir
+}
+
+// testDigest returns a hex-encoded sha256 digest of a given source.
+func testDigest(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestControllerApprove(t *testing.T) {
+	migrations := []string{
+		"20201012010101_foo.hcl",
+		"20201012020202_foo.hcl",
+	}
+	sources := map[string]string{
+		"20201012010101_foo.hcl": `migration "state" "foo" {}`,
+		"20201012020202_foo.hcl": `migration "state" "bar" {}`,
+	}
+	digest := testDigest(sources["20201012020202_foo.hcl"])
+	approvedAt := time.Date(2020, 10, 13, 7, 8, 9, 0, time.UTC)
+
+	cases := []struct {
+		desc     string
+		history  History
+		filename string
+		approver string
+		want     []Approval
+		ok       bool
+	}{
+		{
+			desc: "first approval",
+			history: History{
+				records: map[string]Record{},
+			},
+			filename: "20201012020202_foo.hcl",
+			approver: "alice",
+			want: []Approval{
+				{Approver: "alice", ApprovedAt: approvedAt, Digest: digest},
+			},
+			ok: true,
+		},
+		{
+			desc: "second approval",
+			history: History{
+				records: map[string]Record{},
+				approvals: map[string][]Approval{
+					"20201012020202_foo.hcl": []Approval{
+						{Approver: "alice", ApprovedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC), Digest: digest},
+					},
+				},
+			},
+			filename: "20201012020202_foo.hcl",
+			approver: "bob",
+			want: []Approval{
+				{Approver: "alice", ApprovedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC), Digest: digest},
+				{Approver: "bob", ApprovedAt: approvedAt, Digest: digest},
+			},
+			ok: true,
+		},
+		{
+			desc: "same approver",
+			history: History{
+				records: map[string]Record{},
+				approvals: map[string][]Approval{
+					"20201012020202_foo.hcl": []Approval{
+						{Approver: "alice", ApprovedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC), Digest: digest},
+					},
+				},
+			},
+			filename: "20201012020202_foo.hcl",
+			approver: "alice",
+			want:     nil,
+			ok:       false,
+		},
+		{
+			desc: "same approver for a changed migration",
+			history: History{
+				records: map[string]Record{},
+				approvals: map[string][]Approval{
+					"20201012020202_foo.hcl": []Approval{
+						{Approver: "alice", ApprovedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC), Digest: testDigest("old")},
+					},
+				},
+			},
+			filename: "20201012020202_foo.hcl",
+			approver: "alice",
+			want: []Approval{
+				{Approver: "alice", ApprovedAt: approvedAt, Digest: digest},
+			},
+			ok: true,
+		},
+		{
+			desc: "empty approver",
+			history: History{
+				records: map[string]Record{},
+			},
+			filename: "20201012020202_foo.hcl",
+			approver: "",
+			want:     nil,
+			ok:       false,
+		},
+		{
+			desc: "unknown migration",
+			history: History{
+				records: map[string]Record{},
+			},
+			filename: "20201012030303_foo.hcl",
+			approver: "alice",
+			want:     nil,
+			ok:       false,
+		},
+		{
+			desc: "already applied",
+			history: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+				},
+			},
+			filename: "20201012010101_foo.hcl",
+			approver: "alice",
+			want:     nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := &Controller{
+				migrationDir: setupApprovalMigrationDir(t, sources),
+				migrations:   migrations,
+				history:      tc.history,
+			}
+
+			err := c.Approve(tc.filename, tc.approver, &approvedAt)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error")
+			}
+			if tc.ok {
+				got := c.Approvals(tc.filename)
+				if diff := cmp.Diff(got, tc.want); diff != "" {
+					t.Errorf("got = %#v, want = %#v, diff = %s", got, tc.want, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestControllerCheckApprovals(t *testing.T) {
+	sources := map[string]string{
+		"20201012010101_foo.hcl": `migration "state" "foo" {}`,
+		"20201012020202_foo.hcl": `migration "state" "bar" {}`,
+	}
+	migrationDir := setupApprovalMigrationDir(t, sources)
+	h := History{
+		records: map[string]Record{},
+		approvals: map[string][]Approval{
+			"20201012010101_foo.hcl": []Approval{
+				{Approver: "alice", ApprovedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC), Digest: testDigest(sources["20201012010101_foo.hcl"])},
+				{Approver: "bob", ApprovedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC), Digest: testDigest(sources["20201012010101_foo.hcl"])},
+			},
+		},
+	}
+
+	cases := []struct {
+		desc     string
+		required int
+		filename string
+		applier  string
+		ok       bool
+	}{
+		{
+			desc:     "not required",
+			required: 0,
+			filename: "20201012020202_foo.hcl",
+			ok:       true,
+		},
+		{
+			desc:     "enough",
+			required: 2,
+			filename: "20201012010101_foo.hcl",
+			ok:       true,
+		},
+		{
+			desc:     "not enough",
+			required: 3,
+			filename: "20201012010101_foo.hcl",
+			ok:       false,
+		},
+		{
+			desc:     "no approval",
+			required: 1,
+			filename: "20201012020202_foo.hcl",
+			ok:       false,
+		},
+		{
+			desc:     "enough approvals by others than the applier",
+			required: 2,
+			filename: "20201012010101_foo.hcl",
+			applier:  "carol",
+			ok:       true,
+		},
+		{
+			desc:     "self-approval is not counted",
+			required: 2,
+			filename: "20201012010101_foo.hcl",
+			applier:  "alice",
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := &Controller{
+				migrationDir: migrationDir,
+				history:      h,
+				config: Config{
+					RequiredApprovals: tc.required,
+				},
+			}
+
+			err := c.CheckApprovals(tc.filename, tc.applier)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestControllerCheckApprovalsChangedMigration(t *testing.T) {
+	filename := "20201012010101_foo.hcl"
+	migrationDir := setupApprovalMigrationDir(t, map[string]string{
+		filename: `migration "state" "foo" {}`,
+	})
+	c := &Controller{
+		migrationDir: migrationDir,
+		migrations:   []string{filename},
+		history: History{
+			records: map[string]Record{},
+		},
+		config: Config{
+			RequiredApprovals: 2,
+		},
+	}
+
+	for _, approver := range []string{"alice", "bob"} {
+		if err := c.Approve(filename, approver, nil); err != nil {
+			t.Fatalf("failed to approve: %s", err)
+		}
+	}
+	if err := c.CheckApprovals(filename, ""); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	// Change the migration after approval.
+	err := os.WriteFile(filepath.Join(migrationDir, filename), []byte(`migration "state" "bar" {}`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write migration file: %s", err)
+	}
+	if err := c.CheckApprovals(filename, ""); err == nil {
+		t.Fatal("expected to return an error for a changed migration, but no error")
+	}
+	got, err := c.ValidApprovals(filename)
+	if err != nil {
+		t.Fatalf("failed to get valid approvals: %s", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no valid approvals, but got = %#v", got)
+	}
+}
@@ -2,12 +2,50 @@ package history
 
 import (
 	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/encryption"
 )
 
 // Config is a set of configurations for migration history management.
 type Config struct {
 	// MigrationDir is a path to directory where migration files are stored.
 	MigrationDir string
+	// Project is an identifier of the project which owns the history.
+	// A history file which belongs to another project is rejected.
+	Project string
 	// Storage is an interface of factory method for Storage
 	Storage storage.Config
+	// RequiredApprovals is a number of approvals required to apply a migration.
+	// Approvals must be recorded by different identities.
+	// Default to 0, which means no approval is required.
+	RequiredApprovals int
+	// Encryption is an interface of factory method for a key provider.
+	// If set, the history file is encrypted at the application layer before
+	// writing it to storage. Default to nil, which means no encryption.
+	Encryption encryption.Config
+	// AllowPlaintextMigration is a flag to accept a plain history file when
+	// Encryption is set, so that encryption can be enabled for an existing
+	// history. Default to false.
+	AllowPlaintextMigration bool
+	// CompareAndSwap is a flag to write the history file only if it has not
+	// been updated since it was loaded, so that concurrent updates are never
+	// lost. The storage must support versioning.
+	CompareAndSwap bool
+	// Format is an encoding of the history file, json or yaml.
+	// Default to json. A history file is read in either encoding regardless
+	// of this setting, and written in this encoding.
+	Format string
+}
+
+// storageConfig returns a storage.Config for the history file.
+// If encryption is enabled, the storage is wrapped to encrypt data.
+func (c *Config) storageConfig() storage.Config {
+	if c.Encryption == nil {
+		return c.Storage
+	}
+
+	return &encryption.StorageConfig{
+		Storage:                 c.Storage,
+		Encryption:              c.Encryption,
+		AllowPlaintextMigration: c.AllowPlaintextMigration,
+	}
 }
@@ -2,16 +2,24 @@ package history
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/minamijoyo/tfmigrate/clock"
+	"github.com/minamijoyo/tfmigrate/logging"
 	"github.com/minamijoyo/tfmigrate/storage"
 )
 
+// maxSaveAttempts is a maximum number of attempts to write the history file
+// with compare-and-swap. On a version conflict, changes since the history was
+// loaded are merged into the latest history and written again.
+const maxSaveAttempts = 5
+
 // Controller manages a migration history.
 type Controller struct {
 	// migrationDir is a path to directory where migration files are stored.
@@ -26,35 +34,61 @@ type Controller struct {
 	history History
 	// config customizes behavior of history management.
 	config Config
+	// failureAdded is true if a failure has been added since the history was
+	// loaded.
+	failureAdded bool
+	// vcs is version control information recorded with new records.
+	// It is nil if not available.
+	vcs *VCS
+	// fileVersion is a file format version of the loaded history file.
+	// It is 0 if the history file doesn't exist yet.
+	fileVersion int
+	// version is a version of the history file in storage, which is used for
+	// compare-and-swap writes. It is set only if config.CompareAndSwap is true.
+	version string
+	// changes is a list of changes to history since it was last loaded or
+	// saved. On a version conflict, they are replayed on the latest history.
+	// Each change returns an error if it conflicts with the latest history.
+	changes []func(h *History) error
+	// clock is used for timestamps of new records. It is taken from the
+	// context passed to NewController. If nil, the system clock is used.
+	clock clock.Clock
 }
 
 // NewController returns a new Controller instance.
 func NewController(ctx context.Context, migrationDir string, config *Config) (*Controller, error) {
-	log.Printf("[DEBUG] [history] load migration dir: %s\n", migrationDir)
-	migrations, err := loadMigrationFileNames(migrationDir)
+	logging.FromContext(ctx).Printf("[DEBUG] [history] load migration dir: %s\n", migrationDir)
+	migrations, err := LoadMigrationFileNames(migrationDir)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Print("[DEBUG] [history] load history\n")
-	h, err := loadHistory(ctx, config.Storage)
+	logging.FromContext(ctx).Print("[DEBUG] [history] load history\n")
+	h, fileVersion, version, err := loadHistory(ctx, config.storageConfig(), config.CompareAndSwap)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := h.claimProject(config.Project); err != nil {
+		return nil, err
+	}
+
 	c := &Controller{
 		migrationDir: migrationDir,
 		migrations:   migrations,
 		history:      *h,
 		config:       *config,
+		fileVersion:  fileVersion,
+		version:      version,
+		clock:        clock.FromContext(ctx),
 	}
 
 	return c, nil
 }
 
-// loadMigrationDir loads a migration directory and lists migration files from local.
+// LoadMigrationFileNames lists migration files in a given directory from local.
 // The returned slice is sorted alphabetically.
-func loadMigrationFileNames(dir string) ([]string, error) {
+func LoadMigrationFileNames(dir string) ([]string, error) {
 	migrations := []string{}
 
 	files, err := os.ReadDir(dir)
@@ -81,52 +115,187 @@ func loadMigrationFileNames(dir string) ([]string, error) {
 	return migrations, nil
 }
 
-// loadHistory loads a history file from a storage.
+// loadHistory loads a history file from a storage and returns it with its
+// file format version. If cas is true, it also returns a version of the
+// history file in storage for compare-and-swap writes.
 // If a given history is not found, create a new one.
-func loadHistory(ctx context.Context, c storage.Config) (*History, error) {
+func loadHistory(ctx context.Context, c storage.Config, cas bool) (*History, int, string, error) {
 	s, err := c.NewStorage()
 	if err != nil {
-		return nil, err
+		return nil, 0, "", err
 	}
 
-	log.Printf("[DEBUG] [history] read storage %#v\n", s)
-	b, err := s.Read(ctx)
+	logging.FromContext(ctx).Printf("[DEBUG] [history] read storage %#v\n", s)
+	var b []byte
+	var version string
+	if cas {
+		vs, err := versionedStorage(s)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		b, version, err = vs.ReadWithVersion(ctx)
+		if err != nil {
+			return nil, 0, "", err
+		}
+	} else {
+		b, err = s.Read(ctx)
+		if err != nil {
+			return nil, 0, "", err
+		}
+	}
+	logging.FromContext(ctx).Printf("[TRACE] [history] read history file: %#v\n", b)
+
+	h, fileVersion, err := parseHistory(ctx, b)
 	if err != nil {
-		return nil, err
+		return nil, 0, "", err
 	}
-	log.Printf("[TRACE] [history] read history file: %#v\n", b)
 
+	return h, fileVersion, version, nil
+}
+
+// parseHistory parses a given history file and returns it with its file
+// format version.
+func parseHistory(ctx context.Context, b []byte) (*History, int, error) {
 	// If a given history is not found, s.Read returns empty bytes with no error.
 	// In this case, we assume that it's the first use and create a new history.
 	if len(b) == 0 {
-		log.Print("[DEBUG] [history] new empty history\n")
-		return newEmptyHistory(), nil
+		logging.FromContext(ctx).Print("[DEBUG] [history] new empty history\n")
+		return newEmptyHistory(), 0, nil
+	}
+
+	fileVersion, err := detectHistoryFileVersion(b)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	h, err := ParseHistoryFile(b)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return h, nil
+	return h, fileVersion, nil
+}
+
+// versionedStorage returns a given storage as a storage.VersionedStorage.
+// It returns an error if the storage doesn't support versioning.
+func versionedStorage(s storage.Storage) (storage.VersionedStorage, error) {
+	vs, ok := s.(storage.VersionedStorage)
+	if !ok {
+		return nil, fmt.Errorf("compare-and-swap writes are not supported by the history storage: %T", s)
+	}
+	return vs, nil
 }
 
 // Save persists a current state of historyFile to storage.
+// It is always written in the latest file format. If the loaded history file
+// is in an older format, it is upgraded in place.
 func (c *Controller) Save(ctx context.Context) error {
-	s, err := c.config.Storage.NewStorage()
+	return c.SaveAs(ctx, LatestFileVersion, c.config.Format)
+}
+
+// SaveAs persists a current state of historyFile to storage in a given file
+// format version and encoding format. This is intended for converting formats.
+// If the format is empty, it defaults to the format in the config.
+func (c *Controller) SaveAs(ctx context.Context, version int, format string) error {
+	if len(format) == 0 {
+		format = c.config.Format
+	}
+
+	s, err := c.config.storageConfig().NewStorage()
 	if err != nil {
 		return err
 	}
 
-	f := newFileV1(c.history)
-	b, err := f.Serialize()
+	if c.fileVersion != 0 && c.fileVersion != version {
+		logging.FromContext(ctx).Printf("[INFO] [history] convert history file format from v%d to v%d\n", c.fileVersion, version)
+	}
+
+	logging.FromContext(ctx).Printf("[DEBUG] [history] write storage: %#v\n", s)
+	if c.config.CompareAndSwap {
+		if err := c.writeIfVersion(ctx, s, version, format); err != nil {
+			return err
+		}
+	} else {
+		b, err := serializeHistoryFile(c.history, version, format)
+		if err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Printf("[TRACE] [history] write history file: %#v\n", b)
+		if err := s.Write(ctx, b); err != nil {
+			return err
+		}
+	}
+
+	c.fileVersion = version
+	c.changes = nil
+	return nil
+}
+
+// writeIfVersion writes the history file only if it has not been updated
+// since it was loaded. If it has been updated by someone else, it reloads the
+// latest history, replays changes on it and tries again.
+func (c *Controller) writeIfVersion(ctx context.Context, s storage.Storage, version int, format string) error {
+	vs, err := versionedStorage(s)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("[DEBUG] [history] write storage: %#v\n", s)
-	log.Printf("[TRACE] [history] write history file: %#v\n", b)
-	return s.Write(ctx, b)
+	for attempt := 1; ; attempt++ {
+		b, err := serializeHistoryFile(c.history, version, format)
+		if err != nil {
+			return err
+		}
+
+		logging.FromContext(ctx).Printf("[TRACE] [history] write history file: %#v\n", b)
+		newVersion, err := vs.WriteIfVersion(ctx, b, c.version)
+		if err == nil {
+			c.version = newVersion
+			return nil
+		}
+		if !errors.Is(err, storage.ErrVersionConflict) || attempt >= maxSaveAttempts {
+			return err
+		}
+
+		logging.FromContext(ctx).Printf("[WARN] [history] history file has been updated concurrently, merge changes and retry (attempt %d/%d): %s\n", attempt, maxSaveAttempts, err)
+		if err := c.reload(ctx, vs); err != nil {
+			return err
+		}
+	}
+}
+
+// reload reads the latest history from a given storage and replays changes
+// since the history was loaded on it.
+func (c *Controller) reload(ctx context.Context, vs storage.VersionedStorage) error {
+	b, version, err := vs.ReadWithVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	h, fileVersion, err := parseHistory(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	if err := h.claimProject(c.config.Project); err != nil {
+		return err
+	}
+
+	for _, change := range c.changes {
+		if err := change(h); err != nil {
+			return fmt.Errorf("failed to merge changes: %s: %w", err, storage.ErrVersionConflict)
+		}
+	}
+
+	c.history = *h
+	c.fileVersion = fileVersion
+	c.version = version
+	return nil
+}
+
+// FileVersion returns a file format version of the history file.
+// It returns 0 if the history file doesn't exist yet.
+func (c *Controller) FileVersion() int {
+	return c.fileVersion
 }
 
 // Migrations returns a list of all migration file names.
@@ -147,11 +316,39 @@ func (c *Controller) UnappliedMigrations() []string {
 	return unapplied
 }
 
+// UnknownMigrations returns a list of migration file names which have been
+// applied but are not found in the migration directory, such as files renamed
+// or deleted after applying. The returned slice is sorted alphabetically.
+func (c *Controller) UnknownMigrations() []string {
+	local := make(map[string]bool, len(c.migrations))
+	for _, m := range c.migrations {
+		local[m] = true
+	}
+
+	unknown := []string{}
+	for m := range c.history.records {
+		if !local[m] {
+			unknown = append(unknown, m)
+		}
+	}
+	sort.Strings(unknown)
+
+	return unknown
+}
+
 // HistoryLength returns a number of records in history.
 func (c *Controller) HistoryLength() int {
 	return c.history.Length()
 }
 
+// now returns the current time of the controller's clock.
+func (c *Controller) now() time.Time {
+	if c.clock == nil {
+		return clock.System.Now()
+	}
+	return c.clock.Now()
+}
+
 // AlreadyApplied returns true if a given migration file has already been applied.
 func (c *Controller) AlreadyApplied(filename string) bool {
 	return c.history.Contains(filename)
@@ -159,18 +356,62 @@ func (c *Controller) AlreadyApplied(filename string) bool {
 
 // AddRecord adds a record to history.
 // This method doesn't persist history. Call Save() to save the history.
-// If appliedAt is nil, a timestamp is automatically set to time.Now().
-func (c *Controller) AddRecord(filename string, migrationType string, name string, appliedAt *time.Time) {
+// If appliedAt is nil, a timestamp is automatically set to the current
+// time of the clock in the context passed to NewController.
+func (c *Controller) AddRecord(filename string, migrationType string, name string, actions []ActionRecord, appliedAt *time.Time) {
 	timestamp := appliedAt
 	if timestamp == nil {
-		now := time.Now()
+		now := c.now()
 		timestamp = &now
 	}
 	r := Record{
 		Type:      migrationType,
 		Name:      name,
 		AppliedAt: *timestamp,
+		Actions:   actions,
+		VCS:       c.vcs,
+	}
+
+	c.add(filename, r)
+}
+
+// AddSkippedRecord adds a record of a migration skipped by its condition to
+// history. A skipped migration is treated as applied.
+// This method doesn't persist history. Call Save() to save the history.
+func (c *Controller) AddSkippedRecord(filename string, migrationType string, name string) {
+	r := Record{
+		Type:      migrationType,
+		Name:      name,
+		AppliedAt: c.now(),
+		VCS:       c.vcs,
+		Skipped:   true,
 	}
 
+	c.add(filename, r)
+}
+
+// add adds a record to history and records the change.
+// The change conflicts with the latest history if someone else has applied
+// the migration concurrently.
+func (c *Controller) add(filename string, r Record) {
+	applied := c.history.Contains(filename)
 	c.history.Add(filename, r)
+	c.changes = append(c.changes, func(h *History) error {
+		if !applied && h.Contains(filename) {
+			return fmt.Errorf("a migration has already been applied: %s", filename)
+		}
+		h.Add(filename, r)
+		return nil
+	})
+}
+
+// DeleteRecord deletes a record of a given migration from history, so that
+// the migration is treated as unapplied again.
+// This method doesn't persist history. Call Save() to save the history.
+func (c *Controller) DeleteRecord(filename string) {
+	c.history.Delete(filename)
+	c.changes = append(c.changes, func(h *History) error {
+		h.Delete(filename)
+		return nil
+	})
 }
@@ -2,14 +2,19 @@ package history
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/minamijoyo/tfmigrate/clock"
 	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/local"
 	"github.com/minamijoyo/tfmigrate/storage/mock"
 )
 
@@ -94,7 +99,7 @@ func TestLoadMigrationFileNames(t *testing.T) {
 				}
 			}
 
-			got, err := loadMigrationFileNames(migrationDir)
+			got, err := LoadMigrationFileNames(migrationDir)
 
 			if tc.ok && err != nil {
 				t.Fatalf("unexpected err: %#v", err)
@@ -190,7 +195,7 @@ func TestLoadHistory(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			got, err := loadHistory(context.Background(), tc.config)
+			got, _, _, err := loadHistory(context.Background(), tc.config, false)
 			if tc.ok && err != nil {
 				t.Fatalf("unexpected err: %#v", err)
 			}
@@ -209,11 +214,13 @@ func TestLoadHistory(t *testing.T) {
 
 func TestControllerSave(t *testing.T) {
 	cases := []struct {
-		desc   string
-		config *mock.Config
-		h      *History
-		want   []byte
-		ok     bool
+		desc    string
+		config  *mock.Config
+		h       *History
+		version int
+		format  string
+		want    []byte
+		ok      bool
 	}{
 		{
 			desc: "simple",
@@ -224,11 +231,52 @@ func TestControllerSave(t *testing.T) {
 			},
 			h: newEmptyHistory(),
 			want: []byte(`{
+    "version": 2,
+    "migrations": {}
+}`),
+			ok: true,
+		},
+		{
+			desc: "save as v1",
+			config: &mock.Config{
+				Data:       "",
+				WriteError: false,
+				ReadError:  false,
+			},
+			h:       newEmptyHistory(),
+			version: 1,
+			want: []byte(`{
     "version": 1,
     "records": {}
 }`),
 			ok: true,
 		},
+		{
+			desc: "save in yaml",
+			config: &mock.Config{
+				Data:       "",
+				WriteError: false,
+				ReadError:  false,
+			},
+			h:      newEmptyHistory(),
+			format: FormatYAML,
+			want: []byte(`migrations: {}
+version: 2
+`),
+			ok: true,
+		},
+		{
+			desc: "unknown version",
+			config: &mock.Config{
+				Data:       "",
+				WriteError: false,
+				ReadError:  false,
+			},
+			h:       newEmptyHistory(),
+			version: 99,
+			want:    nil,
+			ok:      false,
+		},
 		{
 			desc: "write error",
 			config: &mock.Config{
@@ -238,8 +286,8 @@ func TestControllerSave(t *testing.T) {
 			},
 			h: newEmptyHistory(),
 			want: []byte(`{
-    "version": 1,
-    "records": {}
+    "version": 2,
+    "migrations": {}
 }`),
 			ok: false,
 		},
@@ -251,9 +299,15 @@ func TestControllerSave(t *testing.T) {
 				history: *tc.h,
 				config: Config{
 					Storage: tc.config,
+					Format:  tc.format,
 				},
 			}
-			err := c.Save(context.Background())
+			var err error
+			if tc.version == 0 {
+				err = c.Save(context.Background())
+			} else {
+				err = c.SaveAs(context.Background(), tc.version, "")
+			}
 			if tc.ok && err != nil {
 				t.Fatalf("unexpected err: %s", err)
 			}
@@ -370,6 +424,77 @@ func TestUnappliedMigrations(t *testing.T) {
 	}
 }
 
+func TestUnknownMigrations(t *testing.T) {
+	cases := []struct {
+		desc       string
+		migrations []string
+		history    History
+		want       []string
+	}{
+		{
+			desc: "simple",
+			migrations: []string{
+				"20201012020202_foo.hcl",
+				"20201012030303_foo.hcl",
+			},
+			history: History{
+				records: map[string]Record{
+					"20201012040404_foo.hcl": Record{
+						Type:      "state",
+						Name:      "baz",
+						AppliedAt: time.Date(2020, 10, 13, 7, 8, 9, 0, time.UTC),
+					},
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+					"20201012020202_foo.hcl": Record{
+						Type:      "state",
+						Name:      "bar",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+					},
+				},
+			},
+			want: []string{
+				"20201012010101_foo.hcl",
+				"20201012040404_foo.hcl",
+			},
+		},
+		{
+			desc: "no unknown",
+			migrations: []string{
+				"20201012010101_foo.hcl",
+				"20201012020202_foo.hcl",
+			},
+			history: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+				},
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := &Controller{
+				migrations: tc.migrations,
+				history:    tc.history,
+			}
+
+			got := c.UnknownMigrations()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got = %#v, want = %#v", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestControllerHistoryLength(t *testing.T) {
 	cases := []struct {
 		desc       string
@@ -547,7 +672,7 @@ func TestControllerAddRecord(t *testing.T) {
 				history:    tc.history,
 			}
 
-			c.AddRecord(tc.filename, tc.migrationType, currentTC.name, &currentTC.appliedAt)
+			c.AddRecord(tc.filename, tc.migrationType, currentTC.name, nil, &currentTC.appliedAt)
 			got := tc.history
 			if diff := cmp.Diff(got, tc.want, cmp.AllowUnexported(got)); diff != "" {
 				t.Errorf("got = %#v, want = %#v, diff = %s", got, tc.want, diff)
@@ -555,3 +680,233 @@ func TestControllerAddRecord(t *testing.T) {
 		})
 	}
 }
+
+func TestControllerAddSkippedRecord(t *testing.T) {
+	c := &Controller{
+		migrations: []string{"20201012010101_foo.hcl"},
+		history:    *newEmptyHistory(),
+	}
+
+	c.AddSkippedRecord("20201012010101_foo.hcl", "state", "foo")
+
+	if !c.AlreadyApplied("20201012010101_foo.hcl") {
+		t.Fatal("expected a skipped migration to be treated as applied")
+	}
+	r, ok := c.Record("20201012010101_foo.hcl")
+	if !ok {
+		t.Fatal("failed to get a record")
+	}
+	if !r.Skipped || r.Type != "state" || r.Name != "foo" || r.AppliedAt.IsZero() {
+		t.Errorf("unexpected record: %#v", r)
+	}
+	if got := c.UnappliedMigrations(); len(got) != 0 {
+		t.Errorf("got unapplied migrations: %v, want: []", got)
+	}
+}
+
+func TestControllerDeleteRecord(t *testing.T) {
+	c := &Controller{
+		migrations: []string{"20201012010101_foo.hcl", "20201012020202_foo.hcl"},
+		history:    *newEmptyHistory(),
+	}
+	c.AddRecord("20201012010101_foo.hcl", "state", "foo", nil, nil)
+	c.AddRecord("20201012020202_foo.hcl", "state", "bar", nil, nil)
+
+	c.DeleteRecord("20201012010101_foo.hcl")
+
+	if c.AlreadyApplied("20201012010101_foo.hcl") {
+		t.Fatal("expected a deleted migration to be treated as unapplied")
+	}
+	want := []string{"20201012010101_foo.hcl"}
+	if got := c.UnappliedMigrations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got unapplied migrations: %v, want: %v", got, want)
+	}
+}
+
+func TestControllerClock(t *testing.T) {
+	now := time.Date(2020, 11, 14, 0, 0, 0, 0, time.UTC)
+	ctx := clock.WithClock(context.Background(), clock.Fixed(now))
+	config := &Config{
+		Storage: &mock.Config{},
+	}
+	c, err := NewController(ctx, t.TempDir(), config)
+	if err != nil {
+		t.Fatalf("failed to new controller: %s", err)
+	}
+
+	c.AddRecord("20201012010101_foo.hcl", "state", "foo", nil, nil)
+	c.AddSkippedRecord("20201012020202_foo.hcl", "state", "bar")
+	for _, filename := range []string{"20201012010101_foo.hcl", "20201012020202_foo.hcl"} {
+		r, ok := c.Record(filename)
+		if !ok {
+			t.Fatalf("failed to get a record: %s", filename)
+		}
+		if !r.AppliedAt.Equal(now) {
+			t.Errorf("got applied_at = %s, want = %s for %s", r.AppliedAt, now, filename)
+		}
+	}
+}
+
+func TestControllerSaveCompareAndSwap(t *testing.T) {
+	cases := []struct {
+		desc          string
+		config        storage.Config
+		update        string
+		change        func(c *Controller) error
+		want          []string
+		wantApprovals int
+		conflict      bool
+		ok            bool
+	}{
+		{
+			desc:   "simple",
+			config: &mock.Config{},
+			change: func(c *Controller) error {
+				c.AddRecord("20201012010101_foo.hcl", "state", "", nil, nil)
+				return nil
+			},
+			want: []string{"20201012010101_foo.hcl"},
+			ok:   true,
+		},
+		{
+			desc:   "created by someone else without changes",
+			config: &mock.Config{},
+			update: `{"version": 2, "migrations": {"20201012010101_foo.hcl": {"applied": {"type": "state", "name": "", "timestamp": "2020-10-12T01:01:01Z"}}}}`,
+			want:   []string{"20201012010101_foo.hcl"},
+			ok:     true,
+		},
+		{
+			desc:   "merge a record applied by someone else",
+			config: &mock.Config{},
+			update: `{"version": 2, "migrations": {"20201012010101_foo.hcl": {"applied": {"type": "state", "name": "", "timestamp": "2020-10-12T01:01:01Z"}}}}`,
+			change: func(c *Controller) error {
+				c.AddRecord("20201012020202_bar.hcl", "state", "", nil, nil)
+				return nil
+			},
+			want: []string{"20201012010101_foo.hcl", "20201012020202_bar.hcl"},
+			ok:   true,
+		},
+		{
+			desc: "merge a deletion",
+			config: &mock.Config{
+				// A non-empty data always starts at the same version in mock,
+				// so inject a conflict on the first write.
+				ErrorHook: func(op mock.Op, n int) error {
+					if op == mock.OpWrite && n == 1 {
+						return fmt.Errorf("test: %w", storage.ErrVersionConflict)
+					}
+					return nil
+				},
+				Data: `{"version": 2, "migrations": {"20201012010101_foo.hcl": {"applied": {"type": "state", "name": "", "timestamp": "2020-10-12T01:01:01Z"}}}}`,
+			},
+			update: `{"version": 2, "migrations": {"20201012010101_foo.hcl": {"applied": {"type": "state", "name": "", "timestamp": "2020-10-12T01:01:01Z"}}, "20201012020202_bar.hcl": {"applied": {"type": "state", "name": "", "timestamp": "2020-10-12T01:01:01Z"}}}}`,
+			change: func(c *Controller) error {
+				c.DeleteRecord("20201012010101_foo.hcl")
+				return nil
+			},
+			want: []string{"20201012020202_bar.hcl"},
+			ok:   true,
+		},
+		{
+			desc:   "merge an approval recorded by someone else",
+			config: &mock.Config{},
+			update: `{"version": 2, "migrations": {"20201012010101_foo.hcl": {"approvals": [{"approver": "alice", "approved_at": "2020-10-12T01:01:01Z"}]}}}`,
+			change: func(c *Controller) error {
+				if err := c.Approve("20201012010101_foo.hcl", "alice", nil); err != nil {
+					return err
+				}
+				return c.Approve("20201012010101_foo.hcl", "bob", nil)
+			},
+			want:          []string{},
+			wantApprovals: 2,
+			ok:            true,
+		},
+		{
+			desc:   "applied by someone else concurrently",
+			config: &mock.Config{},
+			update: `{"version": 2, "migrations": {"20201012010101_foo.hcl": {"applied": {"type": "state", "name": "", "timestamp": "2020-10-12T01:01:01Z"}}}}`,
+			change: func(c *Controller) error {
+				c.AddRecord("20201012010101_foo.hcl", "state", "", nil, nil)
+				return nil
+			},
+			conflict: true,
+			ok:       false,
+		},
+		{
+			desc: "too many conflicts",
+			config: &mock.Config{
+				ErrorHook: func(op mock.Op, _ int) error {
+					if op == mock.OpWrite {
+						return fmt.Errorf("test: %w", storage.ErrVersionConflict)
+					}
+					return nil
+				},
+			},
+			conflict: true,
+			ok:       false,
+		},
+		{
+			desc: "not supported",
+			config: &local.Config{
+				Path: "history.json",
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			migrationDir := t.TempDir()
+			for _, filename := range []string{"20201012010101_foo.hcl", "20201012020202_bar.hcl"} {
+				err := os.WriteFile(filepath.Join(migrationDir, filename), []byte{}, 0600)
+				if err != nil {
+					t.Fatalf("failed to write dummy migration file: %s", err)
+				}
+			}
+			config := &Config{
+				Storage:        tc.config,
+				CompareAndSwap: true,
+			}
+			c, err := NewController(ctx, migrationDir, config)
+			if err == nil && tc.change != nil {
+				err = tc.change(c)
+			}
+			if err == nil {
+				if len(tc.update) != 0 {
+					// simulate an update of the history file after it's loaded.
+					tc.config.(*mock.Config).Data = tc.update
+				}
+				err = c.Save(ctx)
+			}
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.conflict && !errors.Is(err, storage.ErrVersionConflict) {
+				t.Errorf("expected a version conflict, but got: %s", err)
+			}
+			if !tc.ok {
+				return
+			}
+
+			h, err := ParseHistoryFile([]byte(tc.config.(*mock.Config).Storage().Data()))
+			if err != nil {
+				t.Fatalf("failed to parse a saved history: %s", err)
+			}
+			got := []string{}
+			for m := range h.records {
+				got = append(got, m)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("got: %v, want: %v, diff: %s", got, tc.want, diff)
+			}
+			if got := len(h.Approvals("20201012010101_foo.hcl")); got != tc.wantApprovals {
+				t.Errorf("got approvals: %d, want: %d", got, tc.wantApprovals)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortByDependencies returns a given list of unapplied migration file names
+// sorted in an order which satisfies their dependencies.
+// The dependsOn is a map of a migration file name to a list of migration file
+// names which the migration depends on.
+// Migrations without any order constraint between them are sorted
+// alphabetically as before, so that it's compatible with a plain filename
+// ordering when no dependency is declared.
+// It returns an error if a dependency is unknown, not applied and not
+// included in the given list, or the dependencies have a cycle.
+func (c *Controller) SortByDependencies(unapplied []string, dependsOn map[string][]string) ([]string, error) {
+	known := make(map[string]struct{})
+	for _, m := range c.migrations {
+		known[m] = struct{}{}
+	}
+	pending := make(map[string]struct{})
+	for _, m := range unapplied {
+		pending[m] = struct{}{}
+	}
+
+	// build a graph of unapplied migrations.
+	// An edge from dep to m means dep must be applied before m.
+	inDegree := make(map[string]int)
+	edges := make(map[string][]string)
+	for _, m := range unapplied {
+		inDegree[m] += 0
+		for _, dep := range dependsOn[m] {
+			if _, ok := known[dep]; !ok {
+				return nil, fmt.Errorf("a migration %s depends on an unknown migration: %s", m, dep)
+			}
+			if c.history.Contains(dep) {
+				// already satisfied.
+				continue
+			}
+			if _, ok := pending[dep]; !ok {
+				return nil, fmt.Errorf("a migration %s depends on a migration which has not been applied yet: %s", m, dep)
+			}
+			edges[dep] = append(edges[dep], m)
+			inDegree[m]++
+		}
+	}
+
+	// Kahn's algorithm with alphabetical tie-breaking.
+	ready := []string{}
+	for _, m := range unapplied {
+		if inDegree[m] == 0 {
+			ready = append(ready, m)
+		}
+	}
+	sort.Strings(ready)
+
+	sorted := []string{}
+	for len(ready) > 0 {
+		m := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, m)
+		for _, next := range edges[m] {
+			inDegree[next]--
+			if inDegree[next] == 0 {
+				ready = append(ready, next)
+				sort.Strings(ready)
+			}
+		}
+	}
+
+	if len(sorted) != len(unapplied) {
+		cyclic := []string{}
+		for _, m := range unapplied {
+			if inDegree[m] > 0 {
+				cyclic = append(cyclic, m)
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("migration dependencies have a cycle. unresolved migrations: %s", strings.Join(cyclic, ", "))
+	}
+
+	return sorted, nil
+}
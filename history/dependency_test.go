@@ -0,0 +1,143 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestControllerSortByDependencies(t *testing.T) {
+	migrations := []string{
+		"20201012010101_foo.hcl",
+		"20201012020202_foo.hcl",
+		"20201012030303_foo.hcl",
+		"20201012040404_foo.hcl",
+		"20201012050505_foo.hcl",
+	}
+	h := History{
+		records: map[string]Record{
+			"20201012010101_foo.hcl": Record{
+				Type:      "state",
+				Name:      "foo",
+				AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+			},
+		},
+	}
+	unapplied := []string{
+		"20201012020202_foo.hcl",
+		"20201012030303_foo.hcl",
+		"20201012040404_foo.hcl",
+		"20201012050505_foo.hcl",
+	}
+
+	cases := []struct {
+		desc      string
+		unapplied []string
+		dependsOn map[string][]string
+		want      []string
+		ok        bool
+	}{
+		{
+			desc:      "no dependencies",
+			unapplied: unapplied,
+			dependsOn: map[string][]string{},
+			want:      unapplied,
+			ok:        true,
+		},
+		{
+			desc:      "backported migration",
+			unapplied: unapplied,
+			dependsOn: map[string][]string{
+				"20201012020202_foo.hcl": {"20201012040404_foo.hcl"},
+			},
+			want: []string{
+				"20201012030303_foo.hcl",
+				"20201012040404_foo.hcl",
+				"20201012020202_foo.hcl",
+				"20201012050505_foo.hcl",
+			},
+			ok: true,
+		},
+		{
+			desc:      "depends on applied migration",
+			unapplied: unapplied,
+			dependsOn: map[string][]string{
+				"20201012020202_foo.hcl": {"20201012010101_foo.hcl"},
+			},
+			want: unapplied,
+			ok:   true,
+		},
+		{
+			desc:      "chain",
+			unapplied: unapplied,
+			dependsOn: map[string][]string{
+				"20201012020202_foo.hcl": {"20201012030303_foo.hcl"},
+				"20201012030303_foo.hcl": {"20201012050505_foo.hcl"},
+			},
+			want: []string{
+				"20201012040404_foo.hcl",
+				"20201012050505_foo.hcl",
+				"20201012030303_foo.hcl",
+				"20201012020202_foo.hcl",
+			},
+			ok: true,
+		},
+		{
+			desc:      "cycle",
+			unapplied: unapplied,
+			dependsOn: map[string][]string{
+				"20201012020202_foo.hcl": {"20201012030303_foo.hcl"},
+				"20201012030303_foo.hcl": {"20201012020202_foo.hcl"},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc:      "self dependency",
+			unapplied: unapplied,
+			dependsOn: map[string][]string{
+				"20201012020202_foo.hcl": {"20201012020202_foo.hcl"},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc:      "unknown dependency",
+			unapplied: unapplied,
+			dependsOn: map[string][]string{
+				"20201012020202_foo.hcl": {"20201012000000_unknown.hcl"},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc:      "depends on unapplied migration not in the list",
+			unapplied: []string{"20201012020202_foo.hcl"},
+			dependsOn: map[string][]string{
+				"20201012020202_foo.hcl": {"20201012030303_foo.hcl"},
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := &Controller{
+				migrations: migrations,
+				history:    h,
+			}
+
+			got, err := c.SortByDependencies(tc.unapplied, tc.dependsOn)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got = %#v, want = %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -5,14 +5,24 @@ import (
 	"fmt"
 )
 
+// LatestFileVersion is the latest version of history file format.
+// A history file is always written in the latest format. An older format is
+// upgraded automatically on the first write.
+const LatestFileVersion = 2
+
 // FileHeader contains a meta data for file format.
 type FileHeader struct {
 	// Version is a file format version.
 	Version int `json:"version"`
 }
 
-// ParseHistoryFile parses bytes and returns a History instance.
+// ParseHistoryFile parses bytes in JSON or YAML and returns a History instance.
 func ParseHistoryFile(b []byte) (*History, error) {
+	b, err := decodeHistoryFile(b)
+	if err != nil {
+		return nil, err
+	}
+
 	version, err := detectHistoryFileVersion(b)
 	if err != nil {
 		return nil, err
@@ -22,16 +32,46 @@ func ParseHistoryFile(b []byte) (*History, error) {
 	case 1:
 		return parseHistoryFileV1(b)
 
+	case 2:
+		return parseHistoryFileV2(b)
+
+	default:
+		return nil, fmt.Errorf("unknown history file version: %d. It may have been written by a newer version of tfmigrate", version)
+	}
+}
+
+// serializeHistoryFile encodes a History instance to bytes in a given file
+// format version and encoding format.
+func serializeHistoryFile(h History, version int, format string) ([]byte, error) {
+	var b []byte
+	var err error
+	switch version {
+	case 1:
+		b, err = newFileV1(h).Serialize()
+
+	case 2:
+		b, err = newFileV2(h).Serialize()
+
 	default:
 		return nil, fmt.Errorf("unknown history file version: %d", version)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeHistoryFile(b, format)
 }
 
 // detectHistoryFileVersion detects a file format version.
 func detectHistoryFileVersion(b []byte) (int, error) {
+	b, err := decodeHistoryFile(b)
+	if err != nil {
+		return 0, err
+	}
+
 	// peek a file header
 	var header FileHeader
-	err := json.Unmarshal(b, &header)
+	err = json.Unmarshal(b, &header)
 	if err != nil {
 		return 0, err
 	}
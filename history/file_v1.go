@@ -11,12 +11,23 @@ import (
 type FileV1 struct {
 	// Version is a file format version. It is always set to 1.
 	Version int `json:"version"`
+	// Project is an identifier of the project which owns the history.
+	// It is omitted if empty for compatibility with older versions.
+	Project string `json:"project,omitempty"`
 	// Records is a set of applied migration log.
 	// Only success migrations are recorded.
 	// A key is migration file name.
 	// We record only the file name not to invalidate history when the migration
 	// directory is moved.
 	Records map[string]RecordV1 `json:"records"`
+	// Approvals is a set of approvals for migrations.
+	// A key is migration file name.
+	// It is omitted if empty for compatibility with older versions.
+	Approvals map[string][]ApprovalV1 `json:"approvals,omitempty"`
+	// Failures is a set of the last failed attempt for unapplied migrations.
+	// A key is migration file name.
+	// It is omitted if empty for compatibility with older versions.
+	Failures map[string]RecordV1 `json:"failures,omitempty"`
 }
 
 // RecordV1 represents an applied migration log.
@@ -28,6 +39,46 @@ type RecordV1 struct {
 	// AppliedAt is a timestamp when the migration was applied.
 	// Note that we only record it when the migration was succeed.
 	AppliedAt time.Time `json:"applied_at"`
+	// Actions is a list of results of actions executed in the migration.
+	// It is omitted if empty for compatibility with older versions.
+	Actions []ActionRecordV1 `json:"actions,omitempty"`
+	// VCS is version control information of the migration directory.
+	// It is omitted if not available.
+	VCS *VCSV1 `json:"vcs,omitempty"`
+}
+
+// VCSV1 represents version control information of the migration directory.
+type VCSV1 struct {
+	// Commit is a commit SHA.
+	Commit string `json:"commit"`
+	// Branch is a branch name.
+	Branch string `json:"branch,omitempty"`
+	// Remote is a URL of the remote repository.
+	Remote string `json:"remote,omitempty"`
+}
+
+// ActionRecordV1 represents a result of an action executed in a migration.
+type ActionRecordV1 struct {
+	// Action is a plain text of the action.
+	Action string `json:"action"`
+	// Status is a result of the action.
+	Status string `json:"status"`
+	// StartedAt is a timestamp when the action started.
+	StartedAt time.Time `json:"started_at"`
+	// FinishedAt is a timestamp when the action finished.
+	FinishedAt time.Time `json:"finished_at"`
+	// Error is an error message if the action failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ApprovalV1 represents an approval for a migration.
+type ApprovalV1 struct {
+	// Approver is an identity of the approver.
+	Approver string `json:"approver"`
+	// ApprovedAt is a timestamp when the migration was approved.
+	ApprovedAt time.Time `json:"approved_at"`
+	// Digest is a hex-encoded sha256 digest of the migration file approved.
+	Digest string `json:"digest,omitempty"`
 }
 
 // newFileV1 converts a History to a FileV1 instance.
@@ -38,15 +89,53 @@ func newFileV1(h History) *FileV1 {
 		m[k] = r
 	}
 
+	var approvals map[string][]ApprovalV1
+	if len(h.approvals) > 0 {
+		approvals = make(map[string][]ApprovalV1)
+		for k, v := range h.approvals {
+			for _, a := range v {
+				approvals[k] = append(approvals[k], ApprovalV1(a))
+			}
+		}
+	}
+
+	var failures map[string]RecordV1
+	if len(h.failures) > 0 {
+		failures = make(map[string]RecordV1)
+		for k, v := range h.failures {
+			failures[k] = newRecordV1(v)
+		}
+	}
+
 	return &FileV1{
-		Version: 1,
-		Records: m,
+		Version:   1,
+		Project:   h.project,
+		Records:   m,
+		Approvals: approvals,
+		Failures:  failures,
 	}
 }
 
 // newRecordV1 converts a Record to a RecordV1 instance.
 func newRecordV1(r Record) RecordV1 {
-	return RecordV1(r)
+	var actions []ActionRecordV1
+	for _, a := range r.Actions {
+		actions = append(actions, ActionRecordV1(a))
+	}
+
+	var vcs *VCSV1
+	if r.VCS != nil {
+		v := VCSV1(*r.VCS)
+		vcs = &v
+	}
+
+	return RecordV1{
+		Type:      r.Type,
+		Name:      r.Name,
+		AppliedAt: r.AppliedAt,
+		Actions:   actions,
+		VCS:       vcs,
+	}
 }
 
 // Serialize encodes a FileV1 instance to bytes.
@@ -75,12 +164,51 @@ func (f *FileV1) toHistory() History {
 		r := v.toRecord()
 		m[k] = r
 	}
+
+	var approvals map[string][]Approval
+	if len(f.Approvals) > 0 {
+		approvals = make(map[string][]Approval)
+		for k, v := range f.Approvals {
+			for _, a := range v {
+				approvals[k] = append(approvals[k], Approval(a))
+			}
+		}
+	}
+
+	var failures map[string]Record
+	if len(f.Failures) > 0 {
+		failures = make(map[string]Record)
+		for k, v := range f.Failures {
+			failures[k] = v.toRecord()
+		}
+	}
+
 	return History{
-		records: m,
+		project:   f.Project,
+		records:   m,
+		approvals: approvals,
+		failures:  failures,
 	}
 }
 
 // toRecord converts a RecordV1 to a Record instance.
 func (r RecordV1) toRecord() Record {
-	return Record(r)
+	var actions []ActionRecord
+	for _, a := range r.Actions {
+		actions = append(actions, ActionRecord(a))
+	}
+
+	var vcs *VCS
+	if r.VCS != nil {
+		v := VCS(*r.VCS)
+		vcs = &v
+	}
+
+	return Record{
+		Type:      r.Type,
+		Name:      r.Name,
+		AppliedAt: r.AppliedAt,
+		Actions:   actions,
+		VCS:       vcs,
+	}
 }
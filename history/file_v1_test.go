@@ -45,6 +45,76 @@ func TestNewFileV1(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "with actions and failures",
+			h: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+						Actions: []ActionRecord{
+							{
+								Action:     "mv null_resource.foo null_resource.foo2",
+								Status:     "succeeded",
+								StartedAt:  time.Date(2020, 10, 13, 1, 2, 1, 0, time.UTC),
+								FinishedAt: time.Date(2020, 10, 13, 1, 2, 2, 0, time.UTC),
+							},
+						},
+					},
+				},
+				failures: map[string]Record{
+					"20201012020202_foo.hcl": Record{
+						Type:      "state",
+						Name:      "bar",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+						Actions: []ActionRecord{
+							{
+								Action:     "rm null_resource.bar",
+								Status:     "failed",
+								StartedAt:  time.Date(2020, 10, 13, 4, 5, 4, 0, time.UTC),
+								FinishedAt: time.Date(2020, 10, 13, 4, 5, 5, 0, time.UTC),
+								Error:      "exit status 1",
+							},
+						},
+					},
+				},
+			},
+			want: &FileV1{
+				Version: 1,
+				Records: map[string]RecordV1{
+					"20201012010101_foo.hcl": RecordV1{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+						Actions: []ActionRecordV1{
+							{
+								Action:     "mv null_resource.foo null_resource.foo2",
+								Status:     "succeeded",
+								StartedAt:  time.Date(2020, 10, 13, 1, 2, 1, 0, time.UTC),
+								FinishedAt: time.Date(2020, 10, 13, 1, 2, 2, 0, time.UTC),
+							},
+						},
+					},
+				},
+				Failures: map[string]RecordV1{
+					"20201012020202_foo.hcl": RecordV1{
+						Type:      "state",
+						Name:      "bar",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+						Actions: []ActionRecordV1{
+							{
+								Action:     "rm null_resource.bar",
+								Status:     "failed",
+								StartedAt:  time.Date(2020, 10, 13, 4, 5, 4, 0, time.UTC),
+								FinishedAt: time.Date(2020, 10, 13, 4, 5, 5, 0, time.UTC),
+								Error:      "exit status 1",
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -152,6 +222,41 @@ func TestParseHistoryFileV1(t *testing.T) {
 			},
 			ok: true,
 		},
+		{
+			desc: "with approvals",
+			b: []byte(`{
+    "version": 1,
+    "records": {},
+    "approvals": {
+        "20201012010101_foo.hcl": [
+            {
+                "approver": "alice",
+                "approved_at": "2020-10-13T01:02:03Z"
+            },
+            {
+                "approver": "bob",
+                "approved_at": "2020-10-13T04:05:06Z"
+            }
+        ]
+    }
+}`),
+			want: &History{
+				records: map[string]Record{},
+				approvals: map[string][]Approval{
+					"20201012010101_foo.hcl": []Approval{
+						{
+							Approver:   "alice",
+							ApprovedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+						},
+						{
+							Approver:   "bob",
+							ApprovedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+						},
+					},
+				},
+			},
+			ok: true,
+		},
 		{
 			desc: "invalid (empty)",
 			b:    []byte(``),
@@ -0,0 +1,209 @@
+package history
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FileV2 represents a data structure for history file format v2.
+// Unlike v1, it groups all data by migration file, so that we can enrich a
+// migration with new optional fields without adding a new top-level map.
+// Readers must ignore unknown fields, and adding an optional field doesn't
+// bump the version. The version is only bumped for incompatible changes.
+type FileV2 struct {
+	// Version is a file format version. It is always set to 2.
+	Version int `json:"version"`
+	// Project is an identifier of the project which owns the history.
+	Project string `json:"project,omitempty"`
+	// Migrations is a set of migration logs.
+	// A key is migration file name.
+	Migrations map[string]MigrationV2 `json:"migrations"`
+}
+
+// MigrationV2 represents logs for a migration.
+type MigrationV2 struct {
+	// Applied is an applied migration log.
+	// It is omitted if the migration has not been applied yet.
+	Applied *RecordV2 `json:"applied,omitempty"`
+	// LastFailure is a log of the last failed attempt for the migration.
+	// It is omitted if the migration has never failed or has been applied.
+	LastFailure *RecordV2 `json:"last_failure,omitempty"`
+	// Approvals is a list of approvals for the migration.
+	Approvals []ApprovalV2 `json:"approvals,omitempty"`
+}
+
+// RecordV2 represents a log of an attempt to apply a migration.
+type RecordV2 struct {
+	// Type is a migration type.
+	Type string `json:"type"`
+	// Name is a migration name.
+	Name string `json:"name"`
+	// Timestamp is a timestamp when the migration was applied or failed.
+	Timestamp time.Time `json:"timestamp"`
+	// Actions is a list of results of actions executed in the migration.
+	Actions []ActionRecordV2 `json:"actions,omitempty"`
+	// VCS is version control information of the migration directory.
+	// It is omitted if not available.
+	VCS *VCSV2 `json:"vcs,omitempty"`
+	// Skipped is true if the migration was skipped by its condition.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// VCSV2 represents version control information of the migration directory.
+type VCSV2 struct {
+	// Commit is a commit SHA.
+	Commit string `json:"commit"`
+	// Branch is a branch name.
+	Branch string `json:"branch,omitempty"`
+	// Remote is a URL of the remote repository.
+	Remote string `json:"remote,omitempty"`
+}
+
+// ActionRecordV2 represents a result of an action executed in a migration.
+type ActionRecordV2 struct {
+	// Action is a plain text of the action.
+	Action string `json:"action"`
+	// Status is a result of the action.
+	Status string `json:"status"`
+	// StartedAt is a timestamp when the action started.
+	StartedAt time.Time `json:"started_at"`
+	// FinishedAt is a timestamp when the action finished.
+	FinishedAt time.Time `json:"finished_at"`
+	// Error is an error message if the action failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ApprovalV2 represents an approval for a migration.
+type ApprovalV2 struct {
+	// Approver is an identity of the approver.
+	Approver string `json:"approver"`
+	// ApprovedAt is a timestamp when the migration was approved.
+	ApprovedAt time.Time `json:"approved_at"`
+	// Digest is a hex-encoded sha256 digest of the migration file approved.
+	Digest string `json:"digest,omitempty"`
+}
+
+// newFileV2 converts a History to a FileV2 instance.
+func newFileV2(h History) *FileV2 {
+	m := make(map[string]MigrationV2)
+	for k, v := range h.records {
+		mv := m[k]
+		mv.Applied = newRecordV2(v)
+		m[k] = mv
+	}
+	for k, v := range h.failures {
+		mv := m[k]
+		mv.LastFailure = newRecordV2(v)
+		m[k] = mv
+	}
+	for k, v := range h.approvals {
+		mv := m[k]
+		for _, a := range v {
+			mv.Approvals = append(mv.Approvals, ApprovalV2(a))
+		}
+		m[k] = mv
+	}
+
+	return &FileV2{
+		Version:    2,
+		Project:    h.project,
+		Migrations: m,
+	}
+}
+
+// newRecordV2 converts a Record to a RecordV2 instance.
+func newRecordV2(r Record) *RecordV2 {
+	var actions []ActionRecordV2
+	for _, a := range r.Actions {
+		actions = append(actions, ActionRecordV2(a))
+	}
+
+	var vcs *VCSV2
+	if r.VCS != nil {
+		v := VCSV2(*r.VCS)
+		vcs = &v
+	}
+
+	return &RecordV2{
+		Type:      r.Type,
+		Name:      r.Name,
+		Timestamp: r.AppliedAt,
+		Actions:   actions,
+		VCS:       vcs,
+		Skipped:   r.Skipped,
+	}
+}
+
+// Serialize encodes a FileV2 instance to bytes.
+func (f *FileV2) Serialize() ([]byte, error) {
+	return json.MarshalIndent(f, "", "    ")
+}
+
+// parseHistoryFileV2 parses bytes and returns a History instance.
+func parseHistoryFileV2(b []byte) (*History, error) {
+	var f FileV2
+
+	err := json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	h := f.toHistory()
+
+	return &h, nil
+}
+
+// toHistory converts a FileV2 to a History instance.
+func (f *FileV2) toHistory() History {
+	records := make(map[string]Record)
+	var failures map[string]Record
+	var approvals map[string][]Approval
+
+	for k, v := range f.Migrations {
+		if v.Applied != nil {
+			records[k] = v.Applied.toRecord()
+		}
+		if v.LastFailure != nil {
+			if failures == nil {
+				failures = make(map[string]Record)
+			}
+			failures[k] = v.LastFailure.toRecord()
+		}
+		for _, a := range v.Approvals {
+			if approvals == nil {
+				approvals = make(map[string][]Approval)
+			}
+			approvals[k] = append(approvals[k], Approval(a))
+		}
+	}
+
+	return History{
+		project:   f.Project,
+		records:   records,
+		approvals: approvals,
+		failures:  failures,
+	}
+}
+
+// toRecord converts a RecordV2 to a Record instance.
+func (r *RecordV2) toRecord() Record {
+	var actions []ActionRecord
+	for _, a := range r.Actions {
+		actions = append(actions, ActionRecord(a))
+	}
+
+	var vcs *VCS
+	if r.VCS != nil {
+		v := VCS(*r.VCS)
+		vcs = &v
+	}
+
+	return Record{
+		Type:      r.Type,
+		Name:      r.Name,
+		AppliedAt: r.Timestamp,
+		Actions:   actions,
+		VCS:       vcs,
+		Skipped:   r.Skipped,
+	}
+}
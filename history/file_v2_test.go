@@ -0,0 +1,159 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileV2RoundTrip(t *testing.T) {
+	h := History{
+		project: "foo",
+		records: map[string]Record{
+			"20201012010101_foo.hcl": Record{
+				Type:      "state",
+				Name:      "foo",
+				AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+				Actions: []ActionRecord{
+					{
+						Action:     "mv null_resource.foo null_resource.foo2",
+						Status:     "succeeded",
+						StartedAt:  time.Date(2020, 10, 13, 1, 2, 1, 0, time.UTC),
+						FinishedAt: time.Date(2020, 10, 13, 1, 2, 2, 0, time.UTC),
+					},
+				},
+				VCS: &VCS{
+					Commit: "6f1e0b2c9d8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c",
+					Branch: "main",
+				},
+			},
+			"20201012030303_baz.hcl": Record{
+				Type:      "state",
+				Name:      "baz",
+				AppliedAt: time.Date(2020, 10, 13, 7, 8, 9, 0, time.UTC),
+				Skipped:   true,
+			},
+		},
+		failures: map[string]Record{
+			"20201012020202_bar.hcl": Record{
+				Type:      "state",
+				Name:      "bar",
+				AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+			},
+		},
+		approvals: map[string][]Approval{
+			"20201012020202_bar.hcl": []Approval{
+				{
+					Approver:   "alice",
+					ApprovedAt: time.Date(2020, 10, 13, 3, 0, 0, 0, time.UTC),
+				},
+				{
+					Approver:   "bob",
+					ApprovedAt: time.Date(2020, 10, 13, 3, 30, 0, 0, time.UTC),
+				},
+			},
+		},
+	}
+
+	b, err := newFileV2(h).Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize: %s", err)
+	}
+
+	want := `{
+    "version": 2,
+    "project": "foo",
+    "migrations": {
+        "20201012010101_foo.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "foo",
+                "timestamp": "2020-10-13T01:02:03Z",
+                "actions": [
+                    {
+                        "action": "mv null_resource.foo null_resource.foo2",
+                        "status": "succeeded",
+                        "started_at": "2020-10-13T01:02:01Z",
+                        "finished_at": "2020-10-13T01:02:02Z"
+                    }
+                ],
+                "vcs": {
+                    "commit": "6f1e0b2c9d8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c",
+                    "branch": "main"
+                }
+            }
+        },
+        "20201012020202_bar.hcl": {
+            "last_failure": {
+                "type": "state",
+                "name": "bar",
+                "timestamp": "2020-10-13T04:05:06Z"
+            },
+            "approvals": [
+                {
+                    "approver": "alice",
+                    "approved_at": "2020-10-13T03:00:00Z"
+                },
+                {
+                    "approver": "bob",
+                    "approved_at": "2020-10-13T03:30:00Z"
+                }
+            ]
+        },
+        "20201012030303_baz.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "baz",
+                "timestamp": "2020-10-13T07:08:09Z",
+                "skipped": true
+            }
+        }
+    }
+}`
+	if string(b) != want {
+		t.Errorf("got: %s, want: %s", string(b), want)
+	}
+
+	got, err := ParseHistoryFile(b)
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	if diff := cmp.Diff(*got, h, cmp.AllowUnexported(*got)); diff != "" {
+		t.Errorf("got = %#v, want = %#v, diff = %s", got, h, diff)
+	}
+}
+
+func TestParseHistoryFileV2IgnoreUnknownFields(t *testing.T) {
+	b := []byte(`{
+    "version": 2,
+    "migrations": {
+        "20201012010101_foo.hcl": {
+            "applied": {
+                "type": "state",
+                "name": "foo",
+                "timestamp": "2020-10-13T01:02:03Z",
+                "operator": "alice"
+            },
+            "comment": "a field added in the future"
+        }
+    }
+}`)
+	got, err := parseHistoryFileV2(b)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	want := History{
+		records: map[string]Record{
+			"20201012010101_foo.hcl": Record{
+				Type:      "state",
+				Name:      "foo",
+				AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+			},
+		},
+	}
+	if diff := cmp.Diff(*got, want, cmp.AllowUnexported(*got)); diff != "" {
+		t.Errorf("got = %#v, want = %#v, diff = %s", got, want, diff)
+	}
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// FormatJSON is an encoding of history file in JSON.
+	FormatJSON = "json"
+	// FormatYAML is an encoding of history file in YAML.
+	FormatYAML = "yaml"
+)
+
+// isJSON returns true if a given history file looks like JSON.
+// A history file in JSON always starts with an object.
+func isJSON(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("{"))
+}
+
+// decodeHistoryFile returns a history file in JSON.
+// A history file in YAML is converted to JSON, so that the same parser can be
+// used regardless of its encoding.
+func decodeHistoryFile(b []byte) ([]byte, error) {
+	if isJSON(b) {
+		return b, nil
+	}
+
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse history file in YAML: %s", err)
+	}
+
+	j, err := json.Marshal(normalizeYAML(v))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert history file from YAML to JSON: %s", err)
+	}
+	return j, nil
+}
+
+// encodeHistoryFile encodes a given history file in JSON to a given format.
+func encodeHistoryFile(b []byte, format string) ([]byte, error) {
+	switch format {
+	case "", FormatJSON:
+		return b, nil
+
+	case FormatYAML:
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(v)
+
+	default:
+		return nil, fmt.Errorf("unknown history file format: %s", format)
+	}
+}
+
+// normalizeYAML converts maps decoded from YAML to maps with string keys
+// recursively, which can be encoded to JSON.
+func normalizeYAML(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			m[fmt.Sprint(k)] = normalizeYAML(e)
+		}
+		return m
+
+	case []interface{}:
+		for i, e := range x {
+			x[i] = normalizeYAML(e)
+		}
+		return x
+
+	default:
+		return v
+	}
+}
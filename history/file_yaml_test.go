@@ -0,0 +1,102 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestYAMLRoundTrip(t *testing.T) {
+	h := History{
+		records: map[string]Record{
+			"20201012010101_foo.hcl": Record{
+				Type:      "state",
+				Name:      "foo",
+				AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+			},
+		},
+		approvals: map[string][]Approval{
+			"20201012020202_bar.hcl": []Approval{
+				{
+					Approver:   "alice",
+					ApprovedAt: time.Date(2020, 10, 13, 3, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+	}
+
+	b, err := serializeHistoryFile(h, 2, FormatYAML)
+	if err != nil {
+		t.Fatalf("failed to serialize: %s", err)
+	}
+
+	want := `migrations:
+  20201012010101_foo.hcl:
+    applied:
+      name: foo
+      timestamp: "2020-10-13T01:02:03Z"
+      type: state
+  20201012020202_bar.hcl:
+    approvals:
+    - approved_at: "2020-10-13T03:00:00Z"
+      approver: alice
+version: 2
+`
+	if string(b) != want {
+		t.Errorf("got = %s, want = %s", string(b), want)
+	}
+
+	got, err := ParseHistoryFile(b)
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	if diff := cmp.Diff(*got, h, cmp.AllowUnexported(h)); diff != "" {
+		t.Errorf("got = %#v, want = %#v, diff = %s", got, h, diff)
+	}
+}
+
+func TestDecodeHistoryFile(t *testing.T) {
+	cases := []struct {
+		desc string
+		b    string
+		want string
+		ok   bool
+	}{
+		{
+			desc: "json",
+			b:    `{"version": 1, "records": {}}`,
+			want: `{"version": 1, "records": {}}`,
+			ok:   true,
+		},
+		{
+			desc: "yaml",
+			b: `version: 1
+records: {}
+`,
+			want: `{"records":{},"version":1}`,
+			ok:   true,
+		},
+		{
+			desc: "invalid yaml",
+			b:    `version: [`,
+			want: "",
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := decodeHistoryFile([]byte(tc.b))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && string(got) != tc.want {
+				t.Errorf("got = %s, want = %s", string(got), tc.want)
+			}
+		})
+	}
+}
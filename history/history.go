@@ -1,15 +1,27 @@
 package history
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // History records applied migration logs.
 type History struct {
+	// project is an identifier of the project which owns the history.
+	project string
 	// records is a set of applied migration log.
 	// Only success migrations are recorded.
 	// A key is migration file name.
 	// We record only the file name not to invalidate history when the migration
 	// directory is moved.
 	records map[string]Record
+	// approvals is a set of approvals for migrations.
+	// A key is migration file name.
+	approvals map[string][]Approval
+	// failures is a set of the last failed attempt for migrations which have
+	// not been applied yet.
+	// A key is migration file name.
+	failures map[string]Record
 }
 
 // Record represents an applied migration log.
@@ -20,7 +32,17 @@ type Record struct {
 	Name string
 	// AppliedAt is a timestamp when the migration was applied.
 	// Note that we only record it when the migration was succeed.
+	// For a failed attempt, it is a timestamp when the migration failed.
 	AppliedAt time.Time
+	// Actions is a list of results of actions executed in the migration.
+	// It is empty if the migration type doesn't report action results.
+	Actions []ActionRecord
+	// VCS is version control information of the migration directory.
+	// It is nil if not available.
+	VCS *VCS
+	// Skipped is true if the migration was skipped by its condition.
+	// A skipped migration is treated as applied, so it never runs again.
+	Skipped bool
 }
 
 // newEmptyHistory initializes a new History.
@@ -33,8 +55,10 @@ func newEmptyHistory() *History {
 
 // Add adds a new record to history.
 // If a given filename already exists, it updates the existing record.
+// A failed attempt for the migration is cleared if any.
 func (h *History) Add(filename string, r Record) {
 	h.records[filename] = r
+	delete(h.failures, filename)
 }
 
 // Contains returns true if a given migration has been applied.
@@ -52,9 +76,26 @@ func (h *History) Delete(filename string) {
 // Clear deletes all records from history.
 func (h *History) Clear() {
 	h.records = make(map[string]Record)
+	h.failures = nil
 }
 
 // Length returns a number of records in history.
 func (h *History) Length() int {
 	return len(h.records)
 }
+
+// claimProject checks that the history belongs to a given project.
+// A history without a project, which was created by an older version or
+// has never been saved, is claimed by the project.
+func (h *History) claimProject(project string) error {
+	if len(h.project) == 0 {
+		h.project = project
+		return nil
+	}
+
+	if h.project != project {
+		return fmt.Errorf("a history file belongs to another project: got = %q, want = %q", h.project, project)
+	}
+
+	return nil
+}
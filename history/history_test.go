@@ -74,6 +74,35 @@ func TestHistoryAdd(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "add a record for a failed migration",
+			h: History{
+				records: map[string]Record{},
+				failures: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+				},
+			},
+			filename: "20201012010101_foo.hcl",
+			r: Record{
+				Type:      "state",
+				Name:      "foo",
+				AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+			},
+			want: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+					},
+				},
+				failures: map[string]Record{},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -276,3 +305,58 @@ func TestHistoryLength(t *testing.T) {
 		})
 	}
 }
+
+func TestHistoryClaimProject(t *testing.T) {
+	cases := []struct {
+		desc    string
+		h       History
+		project string
+		ok      bool
+	}{
+		{
+			desc:    "no project",
+			h:       History{},
+			project: "",
+			ok:      true,
+		},
+		{
+			desc:    "claim a history without project",
+			h:       History{},
+			project: "foo",
+			ok:      true,
+		},
+		{
+			desc:    "same project",
+			h:       History{project: "foo"},
+			project: "foo",
+			ok:      true,
+		},
+		{
+			desc:    "another project",
+			h:       History{project: "bar"},
+			project: "foo",
+			ok:      false,
+		},
+		{
+			desc:    "project not set",
+			h:       History{project: "bar"},
+			project: "",
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.h.claimProject(tc.project)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && tc.h.project != tc.project {
+				t.Errorf("got = %s, want = %s", tc.h.project, tc.project)
+			}
+		})
+	}
+}
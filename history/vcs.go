@@ -0,0 +1,20 @@
+package history
+
+// VCS represents version control information of the migration directory at
+// the time a migration was applied. It ties state changes to the code change
+// which motivated them.
+type VCS struct {
+	// Commit is a commit SHA.
+	Commit string
+	// Branch is a branch name. It is empty for a detached HEAD.
+	Branch string
+	// Remote is a URL of the remote repository without credentials.
+	// It is empty if no remote is configured.
+	Remote string
+}
+
+// SetVCS sets version control information which is recorded with records and
+// failures added afterwards. If nil, no information is recorded.
+func (c *Controller) SetVCS(vcs *VCS) {
+	c.vcs = vcs
+}
@@ -0,0 +1,37 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestControllerSetVCS(t *testing.T) {
+	c := &Controller{
+		history: *newEmptyHistory(),
+	}
+	vcs := &VCS{
+		Commit: "6f1e0b2c9d8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c",
+		Branch: "main",
+		Remote: "https://example.com/foo/bar.git",
+	}
+	c.SetVCS(vcs)
+	c.AddRecord("20201012010101_foo.hcl", "state", "foo", nil, nil)
+	c.AddFailure("20201012020202_bar.hcl", "state", "bar", nil, nil)
+
+	r, ok := c.Record("20201012010101_foo.hcl")
+	if !ok {
+		t.Fatal("expected to find a record, but not found")
+	}
+	if diff := cmp.Diff(r.VCS, vcs); diff != "" {
+		t.Errorf("got = %#v, want = %#v, diff = %s", r.VCS, vcs, diff)
+	}
+
+	f, ok := c.Failure("20201012020202_bar.hcl")
+	if !ok {
+		t.Fatal("expected to find a failure, but not found")
+	}
+	if diff := cmp.Diff(f.VCS, vcs); diff != "" {
+		t.Errorf("got = %#v, want = %#v, diff = %s", f.VCS, vcs, diff)
+	}
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jsonWriter is an io.Writer which converts a log line such as
+// "[INFO] [runner] message" to a JSON object per line.
+type jsonWriter struct {
+	// w is an underlying writer.
+	w io.Writer
+	// now returns the current time. It can be replaced for testing.
+	now func() time.Time
+	// mu serializes writes to w.
+	mu sync.Mutex
+}
+
+var _ io.Writer = (*jsonWriter)(nil)
+
+// newJSONWriter returns a new jsonWriter instance.
+func newJSONWriter(w io.Writer) *jsonWriter {
+	return &jsonWriter{
+		w:   w,
+		now: time.Now,
+	}
+}
+
+// Write converts a given log line to a JSON object and writes it.
+// The object has the following keys in addition to fields written by Log.
+//   - @timestamp: A time in RFC3339 format.
+//   - @level: A log level such as INFO.
+//   - @component: A component such as runner. It may be omitted.
+//   - @message: A message.
+func (w *jsonWriter) Write(p []byte) (int, error) {
+	entry := parseLogLine(strings.TrimSuffix(string(p), "\n"))
+	entry["@timestamp"] = w.now().UTC().Format(time.RFC3339Nano)
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.w.Write(append(b, '\n')); err != nil {
+		return 0, err
+	}
+	// Report that all the given bytes are written as the log package expects.
+	return len(p), nil
+}
+
+// parseLogLine parses a log line such as "[INFO] [runner] message" and
+// returns a log entry. A line which doesn't follow the convention is stored
+// as a message as it is.
+func parseLogLine(line string) map[string]interface{} {
+	entry := make(map[string]interface{})
+
+	rest := line
+	if level, r, ok := cutBracket(rest); ok {
+		entry["@level"] = level
+		rest = r
+	}
+	if component, r, ok := cutBracket(rest); ok {
+		entry["@component"] = component
+		rest = r
+	}
+
+	if message, fields, ok := strings.Cut(rest, fieldsSeparator); ok {
+		rest = message
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(fields), &m); err == nil {
+			for k, v := range m {
+				entry[k] = v
+			}
+		}
+	}
+	entry["@message"] = rest
+
+	return entry
+}
+
+// cutBracket cuts a leading word enclosed in brackets such as [INFO] from a
+// given string, and returns the word and the rest with leading spaces trimmed.
+func cutBracket(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, "[") {
+		return "", s, false
+	}
+	i := strings.Index(s, "]")
+	if i < 0 {
+		return "", s, false
+	}
+	return s[1:i], strings.TrimLeft(s[i+1:], " "), true
+}
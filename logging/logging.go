@@ -0,0 +1,185 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/logutils"
+)
+
+const (
+	// FormatText is a log format for humans, which is the default.
+	// e.g.) 2020/11/14 00:00:00 [INFO] [runner] message
+	FormatText = "text"
+	// FormatJSON is a log format which writes an entry as a JSON object per
+	// line, so that logs can be ingested by log processors.
+	FormatJSON = "json"
+)
+
+// Levels is a list of valid log levels in ascending order of severity.
+var Levels = []logutils.LogLevel{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}
+
+// fieldsSeparator separates a message and its fields encoded in JSON in a
+// log line written by Log in JSON format. It is a non-printable character
+// not to be confused with a message.
+const fieldsSeparator = "\x1e"
+
+var (
+	// mu guards format.
+	mu sync.RWMutex
+	// format is the current log format.
+	format = FormatText
+)
+
+// Setup configures the standard logger to write logs at a given minimum
+// level or above in a given format to a given writer.
+// The level defaults to INFO and the format defaults to text.
+func Setup(w io.Writer, level string, f string) error {
+	if len(level) == 0 {
+		level = "INFO"
+	}
+	if len(f) == 0 {
+		f = FormatText
+	}
+
+	switch f {
+	case FormatText:
+	case FormatJSON:
+		w = newJSONWriter(w)
+	default:
+		return fmt.Errorf("invalid log format: %s, valid values are %s and %s", f, FormatText, FormatJSON)
+	}
+
+	filter := &logutils.LevelFilter{
+		Levels:   Levels,
+		MinLevel: logutils.LogLevel(level),
+		Writer:   w,
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	format = f
+	log.SetOutput(filter)
+	if f == FormatJSON {
+		// A timestamp is recorded in the JSON object.
+		log.SetFlags(0)
+	} else {
+		log.SetFlags(log.LstdFlags)
+	}
+
+	return nil
+}
+
+// Field is a key-value pair which is attached to a log entry.
+type Field struct {
+	// Key is a name of the field such as exit_code.
+	Key string
+	// Value is a value of the field, which must be encodable to JSON.
+	Value interface{}
+}
+
+// Log writes a log entry with fields to the standard logger.
+// In text format, fields are appended to the message as key=value.
+// In JSON format, fields are written as keys of the JSON object.
+// e.g.) Log("DEBUG", "executor@dir1", "command finished", Field{"exit_code", 0})
+func Log(level string, component string, message string, fields ...Field) {
+	mu.RLock()
+	f := format
+	mu.RUnlock()
+
+	if f == FormatJSON {
+		log.Printf("[%s] [%s] %s%s%s", level, component, message, fieldsSeparator, encodeJSONFields(fields))
+		return
+	}
+	log.Printf("[%s] [%s] %s%s", level, component, message, encodeTextFields(fields))
+}
+
+// encodeTextFields returns fields as a string like `: key1=value1 key2="a b"`.
+// It returns an empty string if no fields.
+func encodeTextFields(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(":")
+	for _, field := range fields {
+		var v string
+		switch value := field.Value.(type) {
+		case string:
+			v = value
+		case []string:
+			v = strings.Join(value, " ")
+		default:
+			v = fmt.Sprint(value)
+		}
+		if len(v) == 0 || strings.ContainsAny(v, " \t\r\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(&b, " %s=%s", field.Key, v)
+	}
+	return b.String()
+}
+
+// encodeJSONFields returns fields as a JSON object.
+// A field which cannot be encoded is written as a string.
+func encodeJSONFields(fields []Field) string {
+	m := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if _, err := json.Marshal(field.Value); err != nil {
+			m[field.Key] = fmt.Sprint(field.Value)
+			continue
+		}
+		m[field.Key] = field.Value
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// Truncate returns a given string truncated to a given number of bytes,
+// so that a large output such as a state doesn't flood logs.
+// A note of the number of truncated bytes is appended if truncated.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	// avoid splitting a multi-byte character.
+	i := n
+	for i > 0 && !isRuneStart(s[i]) {
+		i--
+	}
+	return s[:i] + fmt.Sprintf("...(truncated %d bytes)", len(s)-i)
+}
+
+// isRuneStart returns true if a given byte is a start of UTF-8 character.
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// loggerContextKey is a key of a logger in context.
+type loggerContextKey struct{}
+
+// WithLogger returns a new context which carries a given logger, so that
+// embedders can control where logs of migrations are written.
+// Note that logs of terraform commands are still written to the standard
+// logger.
+func WithLogger(ctx context.Context, l *log.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// FromContext returns a logger in a given context.
+// It returns the standard logger if no logger is set.
+func FromContext(ctx context.Context) *log.Logger {
+	if l, ok := ctx.Value(loggerContextKey{}).(*log.Logger); ok && l != nil {
+		return l
+	}
+	return log.Default()
+}
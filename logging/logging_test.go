@@ -0,0 +1,214 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	cases := []struct {
+		desc   string
+		level  string
+		format string
+		fields []Field
+		want   map[string]interface{}
+		text   string
+		ok     bool
+	}{
+		{
+			desc:   "text",
+			level:  "DEBUG",
+			format: "",
+			fields: []Field{{Key: "args", Value: []string{"terraform", "plan"}}, {Key: "exit_code", Value: 0}},
+			text:   `[DEBUG] [executor@dir1] command finished: args="terraform plan" exit_code=0`,
+			ok:     true,
+		},
+		{
+			desc:   "json",
+			level:  "DEBUG",
+			format: "json",
+			fields: []Field{{Key: "args", Value: []string{"terraform", "plan"}}, {Key: "exit_code", Value: 0}},
+			want: map[string]interface{}{
+				"@level":     "DEBUG",
+				"@component": "executor@dir1",
+				"@message":   "command finished",
+				"args":       []interface{}{"terraform", "plan"},
+				"exit_code":  float64(0),
+			},
+			ok: true,
+		},
+		{
+			desc:   "filtered by level",
+			level:  "INFO",
+			format: "json",
+			fields: nil,
+			want:   nil,
+			ok:     true,
+		},
+		{
+			desc:   "invalid format",
+			level:  "INFO",
+			format: "xml",
+			ok:     false,
+		},
+	}
+
+	defer func() {
+		if err := Setup(os.Stderr, "", ""); err != nil {
+			t.Fatalf("failed to restore logging: %s", err)
+		}
+	}()
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			err := Setup(&b, tc.level, tc.format)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatal("expected to return an error, but no error")
+				}
+				return
+			}
+
+			Log("DEBUG", "executor@dir1", "command finished", tc.fields...)
+
+			if len(tc.text) > 0 {
+				if !strings.HasSuffix(b.String(), tc.text+"\n") {
+					t.Errorf("got: %q, want suffix: %q", b.String(), tc.text)
+				}
+				return
+			}
+
+			if tc.want == nil {
+				if b.Len() != 0 {
+					t.Errorf("expected no output, but got: %s", b.String())
+				}
+				return
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse output: %s, err: %s", b.String(), err)
+			}
+			if _, ok := got["@timestamp"]; !ok {
+				t.Errorf("no @timestamp: %s", b.String())
+			}
+			delete(got, "@timestamp")
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tc.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("got: %s, want: %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestJSONWriterStandardLog(t *testing.T) {
+	var b bytes.Buffer
+	w := newJSONWriter(&b)
+	l := log.New(w, "", 0)
+	l.Printf("[INFO] [runner] unapplied migration files: %v\n", []string{"foo.hcl"})
+	l.Printf("no level")
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, but got: %s", b.String())
+	}
+
+	cases := []map[string]interface{}{
+		{"@level": "INFO", "@component": "runner", "@message": "unapplied migration files: [foo.hcl]"},
+		{"@message": "no level"},
+	}
+	for i, want := range cases {
+		var got map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("failed to parse output: %s, err: %s", lines[i], err)
+		}
+		delete(got, "@timestamp")
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("got: %s, want: %s", gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	var b bytes.Buffer
+	l := log.New(&b, "", 0)
+	cases := []struct {
+		desc string
+		ctx  context.Context
+		want *log.Logger
+	}{
+		{
+			desc: "default",
+			ctx:  context.Background(),
+			want: log.Default(),
+		},
+		{
+			desc: "with logger",
+			ctx:  WithLogger(context.Background(), l),
+			want: l,
+		},
+		{
+			desc: "nil logger",
+			ctx:  WithLogger(context.Background(), nil),
+			want: log.Default(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := FromContext(tc.ctx)
+			if got != tc.want {
+				t.Errorf("got: %p, want: %p", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		desc string
+		s    string
+		n    int
+		want string
+	}{
+		{
+			desc: "short",
+			s:    "foo",
+			n:    3,
+			want: "foo",
+		},
+		{
+			desc: "long",
+			s:    "foobar",
+			n:    3,
+			want: "foo...(truncated 3 bytes)",
+		},
+		{
+			desc: "multi-byte",
+			s:    "aあ",
+			n:    2,
+			want: "a...(truncated 3 bytes)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := Truncate(tc.s, tc.n)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
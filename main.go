@@ -2,13 +2,12 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"strings"
 
-	"github.com/hashicorp/logutils"
 	"github.com/minamijoyo/tfmigrate/command"
+	"github.com/minamijoyo/tfmigrate/logging"
 	"github.com/mitchellh/cli"
 )
 
@@ -16,7 +15,10 @@ import (
 var version = "0.3.21"
 
 func main() {
-	log.SetOutput(logOutput())
+	if err := logging.Setup(os.Stderr, os.Getenv("TFMIGRATE_LOG"), os.Getenv("TFMIGRATE_LOG_FORMAT")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up logging: %s\n", err)
+		os.Exit(1)
+	}
 	log.Printf("[DEBUG] [main] start: %s", strings.Join(os.Args, " "))
 	log.Printf("[DEBUG] [main] tfmigrate version: %s", version)
 
@@ -43,28 +45,6 @@ func main() {
 	os.Exit(exitStatus)
 }
 
-func logOutput() io.Writer {
-	levels := []logutils.LogLevel{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}
-	minLevel := os.Getenv("TFMIGRATE_LOG")
-	if len(minLevel) == 0 {
-		minLevel = "INFO" // default log level
-	}
-
-	// default log writer is null device.
-	writer := io.Discard
-	if minLevel != "" {
-		writer = os.Stderr
-	}
-
-	filter := &logutils.LevelFilter{
-		Levels:   levels,
-		MinLevel: logutils.LogLevel(minLevel),
-		Writer:   writer,
-	}
-
-	return filter
-}
-
 func initCommands(ui cli.Ui) map[string]cli.CommandFactory {
 	meta := command.Meta{
 		UI: ui,
@@ -76,16 +56,121 @@ func initCommands(ui cli.Ui) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"anonymize": func() (cli.Command, error) {
+			return &command.AnonymizeCommand{
+				Meta: meta,
+			}, nil
+		},
 		"apply": func() (cli.Command, error) {
 			return &command.ApplyCommand{
 				Meta: meta,
 			}, nil
 		},
+		"approve": func() (cli.Command, error) {
+			return &command.ApproveCommand{
+				Meta: meta,
+			}, nil
+		},
+		"cleanup": func() (cli.Command, error) {
+			return &command.CleanupCommand{
+				Meta: meta,
+			}, nil
+		},
+		"comment": func() (cli.Command, error) {
+			return &command.CommentCommand{
+				Meta: meta,
+			}, nil
+		},
+		"config": func() (cli.Command, error) {
+			return &command.ConfigCommand{
+				Meta: meta,
+			}, nil
+		},
+		"config dump": func() (cli.Command, error) {
+			return &command.ConfigDumpCommand{
+				Meta: meta,
+			}, nil
+		},
+		"generate-moved": func() (cli.Command, error) {
+			return &command.GenerateMovedCommand{
+				Meta: meta,
+			}, nil
+		},
+		"graph": func() (cli.Command, error) {
+			return &command.GraphCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history": func() (cli.Command, error) {
+			return &command.HistoryCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history migrate-format": func() (cli.Command, error) {
+			return &command.HistoryMigrateFormatCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history ping": func() (cli.Command, error) {
+			return &command.HistoryPingCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history verify": func() (cli.Command, error) {
+			return &command.HistoryVerifyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"inventory": func() (cli.Command, error) {
+			return &command.InventoryCommand{
+				Meta: meta,
+			}, nil
+		},
 		"list": func() (cli.Command, error) {
 			return &command.ListCommand{
 				Meta: meta,
 			}, nil
 		},
+		"matrix": func() (cli.Command, error) {
+			return &command.MatrixCommand{
+				Meta: meta,
+			}, nil
+		},
+		"new": func() (cli.Command, error) {
+			return &command.NewCommand{
+				Meta: meta,
+			}, nil
+		},
+		"review": func() (cli.Command, error) {
+			return &command.ReviewCommand{
+				Meta: meta,
+			}, nil
+		},
+		"rollback": func() (cli.Command, error) {
+			return &command.RollbackCommand{
+				Meta: meta,
+			}, nil
+		},
+		"squash": func() (cli.Command, error) {
+			return &command.SquashCommand{
+				Meta: meta,
+			}, nil
+		},
+		"status": func() (cli.Command, error) {
+			return &command.StatusCommand{
+				Meta: meta,
+			}, nil
+		},
+		"help": func() (cli.Command, error) {
+			return &command.HelpCommand{
+				Meta: meta,
+			}, nil
+		},
+		"help actions": func() (cli.Command, error) {
+			return &command.HelpActionsCommand{
+				Meta: meta,
+			}, nil
+		},
 	}
 
 	return commands
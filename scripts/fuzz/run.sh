@@ -0,0 +1,15 @@
+#!/bin/bash
+
+set -eo pipefail
+
+# Run all fuzz tests one by one, because go test can fuzz only a single target
+# at a time. A failing input is written to testdata/fuzz in the package, which
+# should be committed as a regression test.
+FUZZTIME=${FUZZTIME:-30s}
+
+for pkg in $(go list ./...); do
+  for target in $(go test -list '^Fuzz' "$pkg" | grep '^Fuzz' || true); do
+    echo "==> ${target} in ${pkg}"
+    go test -run '^$' -fuzz "^${target}\$" -fuzztime "$FUZZTIME" "$pkg"
+  done
+done
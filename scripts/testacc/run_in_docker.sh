@@ -0,0 +1,20 @@
+#!/bin/bash
+
+set -eo pipefail
+
+# Run acceptance tests with disposable fake backends in docker-compose.
+# localstack provides the s3 backend of Terraform and the s3 history storage,
+# and fake-gcs-server provides the gcs history storage.
+# All containers are removed on exit, so that each run starts from scratch.
+
+cleanup() {
+  docker-compose down --volumes
+}
+trap cleanup EXIT
+
+docker-compose build
+docker-compose up -d localstack fake-gcs-server
+docker-compose run --rm dockerize -wait tcp://localstack:4566 -wait tcp://fake-gcs-server:4443 -timeout 60s
+docker-compose exec -T localstack /etc/localstack/init/wait_s3_bucket_exists.sh
+
+docker-compose run --rm tfmigrate make testacc
@@ -0,0 +1,375 @@
+package azurerm
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/storage/httpapi"
+)
+
+// DefaultMSIEndpoint is a default endpoint to get a token of the managed
+// identity, which is the Azure Instance Metadata Service.
+const DefaultMSIEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// apiVersion is a version of the Blob service REST API.
+const apiVersion = "2021-08-06"
+
+// storageResource is a resource identifier of Azure Storage for a token of
+// the managed identity.
+const storageResource = "https://storage.azure.com/"
+
+// Client is an abstraction layer for Azure Blob Storage.
+// It is intended to be replaced with a mock for testing.
+type Client interface {
+	// Get returns a blob of a given key and its ETag.
+	// If the blob does not exist, it returns nil with an empty ETag.
+	Get(ctx context.Context, key string) ([]byte, string, error)
+	// Put writes a blob of a given key and returns a new ETag.
+	Put(ctx context.Context, key string, value []byte) (string, error)
+	// PutIfMatch writes a blob of a given key only if its ETag matches.
+	// The empty ETag means that the blob must not exist.
+	// It returns a new ETag and true on success, or false if the ETag
+	// doesn't match.
+	PutIfMatch(ctx context.Context, key string, value []byte, etag string) (string, bool, error)
+	// Delete deletes a blob of a given key.
+	Delete(ctx context.Context, key string) error
+}
+
+// client is a real implementation of the Client with the Blob service REST
+// API.
+type client struct {
+	// endpoint is a base URL of the Blob service.
+	endpoint string
+	// accountName is a name of the storage account.
+	accountName string
+	// containerName is a name of the blob container.
+	containerName string
+	// accessKey is a decoded access key of the storage account.
+	accessKey []byte
+	// sasToken is a SAS token without a leading "?".
+	sasToken string
+	// useMSI is true if authenticating with a managed identity.
+	useMSI bool
+	// clientID is a client ID of a user assigned managed identity.
+	clientID string
+	// msiEndpoint is an endpoint to get a token of the managed identity.
+	msiEndpoint string
+	// api sends requests to the Blob service.
+	api *httpapi.Client
+	// msiAPI sends requests to the endpoint of the managed identity.
+	msiAPI *httpapi.Client
+	// msiToken is an access token of the managed identity.
+	msiToken *httpapi.TokenCache
+}
+
+var _ Client = (*client)(nil)
+
+// newClient returns a new instance of Client.
+func newClient(config *Config) (Client, error) {
+	if len(config.StorageAccountName) == 0 {
+		return nil, fmt.Errorf("failed to new azurerm client: storage_account_name is required")
+	}
+
+	endpoint := config.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = "https://" + config.StorageAccountName + ".blob.core.windows.net"
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("failed to new azurerm client: invalid endpoint: %s", err)
+	}
+
+	accessKey := config.AccessKey
+	if len(accessKey) == 0 {
+		accessKey = os.Getenv("ARM_ACCESS_KEY")
+	}
+	sasToken := config.SasToken
+	if len(sasToken) == 0 {
+		sasToken = os.Getenv("ARM_SAS_TOKEN")
+	}
+	clientID := config.ClientID
+	if len(clientID) == 0 {
+		clientID = os.Getenv("ARM_CLIENT_ID")
+	}
+	msiEndpoint := config.MSIEndpoint
+	if len(msiEndpoint) == 0 {
+		msiEndpoint = DefaultMSIEndpoint
+	}
+
+	c := &client{
+		endpoint:      strings.TrimSuffix(endpoint, "/"),
+		accountName:   config.StorageAccountName,
+		containerName: config.ContainerName,
+		sasToken:      strings.TrimPrefix(sasToken, "?"),
+		useMSI:        config.UseMSI,
+		clientID:      clientID,
+		msiEndpoint:   msiEndpoint,
+	}
+	c.msiToken = &httpapi.TokenCache{Fetch: c.fetchMSIToken}
+	c.msiAPI = &httpapi.Client{
+		Name:       "azure managed identity endpoint",
+		MaxRetries: httpapi.DefaultMaxRetries,
+		RetryWait:  httpapi.DefaultRetryWait,
+	}
+	c.api = &httpapi.Client{
+		Name:       "azurerm",
+		Authorize:  c.authorize,
+		MaxRetries: httpapi.DefaultMaxRetries,
+		RetryWait:  httpapi.DefaultRetryWait,
+	}
+	if c.useMSI {
+		c.api.ResetAuth = c.msiToken.Reset
+	}
+
+	// The access key is used only if neither use_msi nor sas_token is set.
+	switch {
+	case c.useMSI, len(c.sasToken) != 0:
+	case len(accessKey) != 0:
+		key, err := base64.StdEncoding.DecodeString(accessKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to new azurerm client: invalid access key: %s", err)
+		}
+		c.accessKey = key
+	default:
+		return nil, fmt.Errorf("failed to new azurerm client: one of access_key, sas_token or use_msi is required")
+	}
+	return c, nil
+}
+
+// Get returns a blob of a given key and its ETag.
+func (c *client) Get(ctx context.Context, key string) ([]byte, string, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get azurerm blob %s: %s", key, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp.Body, resp.Header.Get("ETag"), nil
+	case resp.StatusCode == http.StatusNotFound && resp.Header.Get("x-ms-error-code") == "BlobNotFound":
+		return nil, "", nil
+	default:
+		return nil, "", resp.Error("failed to get azurerm blob " + key)
+	}
+}
+
+// Put writes a blob of a given key and returns a new ETag.
+func (c *client) Put(ctx context.Context, key string, value []byte) (string, error) {
+	resp, err := c.do(ctx, http.MethodPut, key, value, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to put azurerm blob %s: %s", key, err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", resp.Error("failed to put azurerm blob " + key)
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// PutIfMatch writes a blob of a given key only if its ETag matches.
+func (c *client) PutIfMatch(ctx context.Context, key string, value []byte, etag string) (string, bool, error) {
+	header := http.Header{}
+	if len(etag) == 0 {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", etag)
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, key, value, header)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to put azurerm blob %s: %s", key, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusCreated:
+		return resp.Header.Get("ETag"), true, nil
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return "", false, nil
+	case resp.StatusCode == http.StatusConflict && resp.Header.Get("x-ms-error-code") == "BlobAlreadyExists":
+		return "", false, nil
+	default:
+		return "", false, resp.Error("failed to put azurerm blob " + key)
+	}
+}
+
+// Delete deletes a blob of a given key.
+// It is not an error if the blob does not exist.
+func (c *client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete azurerm blob %s: %s", key, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		return nil
+	case resp.StatusCode == http.StatusNotFound && resp.Header.Get("x-ms-error-code") == "BlobNotFound":
+		return nil
+	default:
+		return resp.Error("failed to delete azurerm blob " + key)
+	}
+}
+
+// do sends an authenticated request for a blob of a given key and returns
+// the response with its body. A non-nil value is written as a block blob.
+// A conditional write is not idempotent, because a retry of a successful one
+// fails as a conflict.
+func (c *client) do(ctx context.Context, method string, key string, value []byte, header http.Header) (*httpapi.Response, error) {
+	u, err := url.Parse(c.endpoint + "/" + c.containerName + "/" + escapePath(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(c.sasToken) != 0 {
+		u.RawQuery = c.sasToken
+	}
+
+	if header == nil {
+		header = http.Header{}
+	}
+	idempotent := len(header.Get("If-Match")) == 0 && len(header.Get("If-None-Match")) == 0
+	header.Set("x-ms-version", apiVersion)
+	if value != nil {
+		header.Set("x-ms-blob-type", "BlockBlob")
+		header.Set("Content-Type", "application/octet-stream")
+	}
+
+	return c.api.Do(ctx, &httpapi.Request{
+		Method:     method,
+		URL:        u.String(),
+		Header:     header,
+		Body:       value,
+		Idempotent: idempotent,
+	})
+}
+
+// authorize sets an Authorization header to a given request.
+// A request with a SAS token doesn't need it.
+// It's called on every attempt, so that the timestamp is always fresh.
+func (c *client) authorize(ctx context.Context, r *http.Request) error {
+	r.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	switch {
+	case c.useMSI:
+		token, err := c.msiToken.Token(ctx)
+		if err != nil {
+			return err
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	case len(c.sasToken) != 0:
+	default:
+		r.Header.Set("Authorization", "SharedKey "+c.accountName+":"+c.sign(r))
+	}
+	return nil
+}
+
+// sign returns a signature of a given request with the Shared Key
+// authorization.
+// https://learn.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func (c *client) sign(r *http.Request) string {
+	contentLength := ""
+	if r.ContentLength > 0 {
+		contentLength = strconv.FormatInt(r.ContentLength, 10)
+	}
+
+	lines := []string{
+		r.Method,
+		r.Header.Get("Content-Encoding"),
+		r.Header.Get("Content-Language"),
+		contentLength,
+		r.Header.Get("Content-MD5"),
+		r.Header.Get("Content-Type"),
+		"", // Date is always empty because x-ms-date is set.
+		r.Header.Get("If-Modified-Since"),
+		r.Header.Get("If-Match"),
+		r.Header.Get("If-None-Match"),
+		r.Header.Get("If-Unmodified-Since"),
+		r.Header.Get("Range"),
+	}
+
+	msHeaders := []string{}
+	for k := range r.Header {
+		if k := strings.ToLower(k); strings.HasPrefix(k, "x-ms-") {
+			msHeaders = append(msHeaders, k)
+		}
+	}
+	sort.Strings(msHeaders)
+	for _, k := range msHeaders {
+		lines = append(lines, k+":"+strings.TrimSpace(r.Header.Get(k)))
+	}
+
+	resource := "/" + c.accountName + r.URL.EscapedPath()
+	query := r.URL.Query()
+	params := []string{}
+	for k := range query {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		values := query[k]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(values, ",")
+	}
+	lines = append(lines, resource)
+
+	mac := hmac.New(sha256.New, c.accessKey)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// fetchMSIToken gets a new access token of the managed identity and its
+// expiration time. The token is cached by the caller until shortly before it
+// expires.
+func (c *client) fetchMSIToken(ctx context.Context) (string, time.Time, error) {
+	u, err := url.Parse(c.msiEndpoint)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to get a token of the managed identity: invalid endpoint: %s", err)
+	}
+	query := u.Query()
+	query.Set("api-version", "2018-02-01")
+	query.Set("resource", storageResource)
+	if len(c.clientID) != 0 {
+		query.Set("client_id", c.clientID)
+	}
+	u.RawQuery = query.Encode()
+
+	resp, err := c.msiAPI.Do(ctx, &httpapi.Request{
+		Method:     http.MethodGet,
+		URL:        u.String(),
+		Header:     http.Header{"Metadata": []string{"true"}},
+		Idempotent: true,
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to get a token of the managed identity: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, resp.Error("failed to get a token of the managed identity")
+	}
+
+	// The expires_on is seconds since the epoch in a string.
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := json.Unmarshal(resp.Body, &res); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to parse a token of the managed identity: %s", err)
+	}
+	expiresOn, err := strconv.ParseInt(res.ExpiresOn, 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to parse an expiration of the managed identity token: %s", err)
+	}
+	return res.AccessToken, time.Unix(expiresOn, 0), nil
+}
+
+// escapePath escapes each segment of a given blob name.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
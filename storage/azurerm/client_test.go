@@ -0,0 +1,301 @@
+package azurerm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBlob is a blob with its ETag in fakeAzure.
+type fakeBlob struct {
+	value []byte
+	etag  string
+}
+
+// fakeAzure is a minimal fake server of the Blob service REST API and the
+// token endpoint of the managed identity.
+type fakeAzure struct {
+	mu      sync.Mutex
+	blobs   map[string]fakeBlob
+	version int
+	// verifier is a client to verify a signature of the Shared Key.
+	verifier *client
+	sasToken string
+	token    string
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/msi" {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("resource") != storageResource {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		expiresOn := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+		fmt.Fprintf(w, `{"access_token":%q,"expires_on":%q}`, f.token, expiresOn)
+		return
+	}
+
+	if !f.authorized(r) {
+		w.Header().Set("x-ms-error-code", "AuthenticationFailed")
+		http.Error(w, "authentication failed", http.StatusForbidden)
+		return
+	}
+
+	key := r.URL.Path
+	blob, exists := f.blobs[key]
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", blob.etag)
+		_, _ = w.Write(blob.value)
+
+	case http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			http.Error(w, "invalid blob type", http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.Header().Set("x-ms-error-code", "BlobAlreadyExists")
+			http.Error(w, "conflict", http.StatusConflict)
+			return
+		}
+		if etag := r.Header.Get("If-Match"); etag != "" && (!exists || etag != blob.etag) {
+			w.Header().Set("x-ms-error-code", "ConditionNotMet")
+			http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+			return
+		}
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.version++
+		etag := fmt.Sprintf(`"0x%d"`, f.version)
+		f.blobs[key] = fakeBlob{value: value, etag: etag}
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusCreated)
+
+	case http.MethodDelete:
+		if !exists {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		delete(f.blobs, key)
+		w.WriteHeader(http.StatusAccepted)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// authorized returns true if a given request is authorized with one of the
+// Shared Key, the SAS token or the token of the managed identity.
+func (f *fakeAzure) authorized(r *http.Request) bool {
+	if r.Header.Get("x-ms-version") != apiVersion || r.Header.Get("x-ms-date") == "" {
+		return false
+	}
+	auth := r.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(auth, "SharedKey "):
+		return auth == "SharedKey "+f.verifier.accountName+":"+f.verifier.sign(r)
+	case strings.HasPrefix(auth, "Bearer "):
+		return auth == "Bearer "+f.token
+	default:
+		return len(f.sasToken) != 0 && r.URL.RawQuery == f.sasToken
+	}
+}
+
+func TestClient(t *testing.T) {
+	accessKey := base64.StdEncoding.EncodeToString([]byte("secret"))
+	fake := &fakeAzure{
+		blobs:    map[string]fakeBlob{},
+		verifier: &client{accountName: "tfmigrate", accessKey: []byte("secret")},
+		sasToken: "sv=2021-08-06&sig=foo",
+		token:    "fake-token",
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cases := []struct {
+		desc   string
+		config *Config
+	}{
+		{
+			desc: "access key",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfstate",
+				AccessKey:          accessKey,
+				Endpoint:           server.URL,
+			},
+		},
+		{
+			desc: "sas token",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfstate",
+				SasToken:           "?sv=2021-08-06&sig=foo",
+				Endpoint:           server.URL,
+			},
+		},
+		{
+			desc: "managed identity",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfstate",
+				UseMSI:             true,
+				MSIEndpoint:        server.URL + "/msi",
+				Endpoint:           server.URL,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fake.blobs = map[string]fakeBlob{}
+			c, err := newClient(tc.config)
+			if err != nil {
+				t.Fatalf("failed to new client: %s", err)
+			}
+			ctx := context.Background()
+			key := "tfmigrate/history (1).json"
+
+			// blob does not exist
+			got, etag, err := c.Get(ctx, key)
+			if err != nil || got != nil || etag != "" {
+				t.Fatalf("unexpected get result: %s, %s, %v", string(got), etag, err)
+			}
+
+			// create with etag
+			etag, ok, err := c.PutIfMatch(ctx, key, []byte("foo"), "")
+			if err != nil || !ok || etag == "" {
+				t.Fatalf("unexpected put result: %s, %t, %v", etag, ok, err)
+			}
+
+			// conflict
+			if _, ok, err := c.PutIfMatch(ctx, key, []byte("bar"), ""); err != nil || ok {
+				t.Fatalf("expected to conflict, but got: %t, %v", ok, err)
+			}
+			if _, ok, err := c.PutIfMatch(ctx, key, []byte("bar"), `"0xinvalid"`); err != nil || ok {
+				t.Fatalf("expected to conflict, but got: %t, %v", ok, err)
+			}
+
+			// update with etag
+			newEtag, ok, err := c.PutIfMatch(ctx, key, []byte("bar"), etag)
+			if err != nil || !ok || newEtag == etag {
+				t.Fatalf("unexpected put result: %s, %t, %v", newEtag, ok, err)
+			}
+
+			// put and get
+			if _, err := c.Put(ctx, key, []byte("baz")); err != nil {
+				t.Fatalf("failed to put: %s", err)
+			}
+			got, _, err = c.Get(ctx, key)
+			if err != nil || string(got) != "baz" {
+				t.Fatalf("unexpected get result: %s, %v", string(got), err)
+			}
+
+			// delete
+			if err := c.Delete(ctx, key); err != nil {
+				t.Fatalf("failed to delete: %s", err)
+			}
+			if _, ok := fake.blobs["/tfstate/"+key]; ok {
+				t.Fatal("blob was not deleted")
+			}
+			if err := c.Delete(ctx, key); err != nil {
+				t.Fatalf("failed to delete a blob which does not exist: %s", err)
+			}
+		})
+	}
+}
+
+func TestClientInvalidAccessKey(t *testing.T) {
+	fake := &fakeAzure{
+		blobs:    map[string]fakeBlob{},
+		verifier: &client{accountName: "tfmigrate", accessKey: []byte("secret")},
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	c, err := newClient(&Config{
+		StorageAccountName: "tfmigrate",
+		ContainerName:      "tfstate",
+		AccessKey:          base64.StdEncoding.EncodeToString([]byte("invalid")),
+		Endpoint:           server.URL,
+	})
+	if err != nil {
+		t.Fatalf("failed to new client: %s", err)
+	}
+	if _, _, err := c.Get(context.Background(), "tfmigrate/history.json"); err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Setenv("ARM_ACCESS_KEY", "")
+	t.Setenv("ARM_SAS_TOKEN", "")
+
+	cases := []struct {
+		desc   string
+		config *Config
+		ok     bool
+	}{
+		{
+			desc: "access key",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfstate",
+				Key:                "tfmigrate/history.json",
+				AccessKey:          base64.StdEncoding.EncodeToString([]byte("secret")),
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid access key",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfstate",
+				Key:                "tfmigrate/history.json",
+				AccessKey:          "not base64",
+			},
+			ok: false,
+		},
+		{
+			desc: "no credentials",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfstate",
+				Key:                "tfmigrate/history.json",
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := newClient(tc.config)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package azurerm
+
+import "github.com/minamijoyo/tfmigrate/storage"
+
+// Config is a config for Azure Blob Storage.
+// This is expected to have almost the same options as Terraform azurerm
+// backend.
+// https://developer.hashicorp.com/terraform/language/settings/backends/azurerm
+// However, it has many minor options and it's a pain to test all options from
+// first, so we added only options we need for now.
+type Config struct {
+	// Name of the storage account.
+	StorageAccountName string `hcl:"storage_account_name"`
+	// Name of the blob container.
+	ContainerName string `hcl:"container_name"`
+	// Path to the migration history file.
+	Key string `hcl:"key"`
+
+	// Access key of the storage account.
+	// Default to the ARM_ACCESS_KEY environment variable.
+	AccessKey string `hcl:"access_key,optional"`
+	// SAS token for the container or the storage account.
+	// Default to the ARM_SAS_TOKEN environment variable.
+	SasToken string `hcl:"sas_token,optional"`
+	// Authenticate with a managed identity.
+	UseMSI bool `hcl:"use_msi,optional"`
+	// Client ID of a user assigned managed identity.
+	// Default to the ARM_CLIENT_ID environment variable.
+	ClientID string `hcl:"client_id,optional"`
+	// Endpoint to get a token of the managed identity.
+	// Default to the Azure Instance Metadata Service.
+	MSIEndpoint string `hcl:"msi_endpoint,optional"`
+	// Custom endpoint of the Blob service, such as for Azurite.
+	// Default to `https://<storage_account_name>.blob.core.windows.net`.
+	Endpoint string `hcl:"endpoint,optional"`
+}
+
+// Config implements a storage.Config.
+var _ storage.Config = (*Config)(nil)
+
+// NewStorage returns a new instance of storage.Storage.
+func (c *Config) NewStorage() (storage.Storage, error) {
+	return NewStorage(c, nil)
+}
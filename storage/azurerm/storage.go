@@ -0,0 +1,95 @@
+package azurerm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// Storage is a storage.Storage implementation for Azure Blob Storage.
+type Storage struct {
+	// config is a storage config for Azure Blob Storage.
+	config *Config
+	// client is an instance of Client interface to call API.
+	// It is intended to be replaced with a mock for testing.
+	client Client
+}
+
+var _ storage.Storage = (*Storage)(nil)
+var _ storage.VersionedStorage = (*Storage)(nil)
+
+// NewStorage returns a new instance of Storage.
+func NewStorage(config *Config, client Client) (*Storage, error) {
+	if client == nil {
+		var err error
+		client, err = newClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Storage{
+		config: config,
+		client: client,
+	}
+	return s, nil
+}
+
+// Write writes migration history data to storage.
+func (s *Storage) Write(ctx context.Context, b []byte) error {
+	_, err := s.client.Put(ctx, s.config.Key, b)
+	return err
+}
+
+// Read reads migration history data from storage.
+// If the blob does not exist, it is assumed to be uninitialized and returns
+// an empty array instead of an error.
+func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, _, err := s.ReadWithVersion(ctx)
+	return b, err
+}
+
+// ReadWithVersion reads migration history data with its ETag.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, string, error) {
+	b, etag, err := s.client.Get(ctx, s.config.Key)
+	if err != nil {
+		return nil, "", err
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, etag, nil
+}
+
+// WriteIfVersion writes migration history data only if its ETag matches a
+// given version.
+func (s *Storage) WriteIfVersion(ctx context.Context, b []byte, version string) (string, error) {
+	etag, ok, err := s.client.PutIfMatch(ctx, s.config.Key, b, version)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("failed to write azurerm blob %s with etag %q: %w", s.config.Key, version, storage.ErrVersionConflict)
+	}
+	return etag, nil
+}
+
+// Ping checks permissions to read the history blob, and to write and delete
+// a probe blob next to it.
+func (s *Storage) Ping(ctx context.Context) (*storage.PingResult, error) {
+	probe := s.config.Key + storage.ProbeKeySuffix
+	return storage.Probe(ctx,
+		func(ctx context.Context) error {
+			_, err := s.Read(ctx)
+			return err
+		},
+		func(ctx context.Context, b []byte) error {
+			_, err := s.client.Put(ctx, probe, b)
+			return err
+		},
+		func(ctx context.Context) error {
+			return s.client.Delete(ctx, probe)
+		},
+	)
+}
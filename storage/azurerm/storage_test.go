@@ -0,0 +1,295 @@
+package azurerm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/storagetest"
+)
+
+// mockBlob is a value with its ETag in mockClient.
+type mockBlob struct {
+	value []byte
+	etag  string
+}
+
+// mockClient is a mock implementation for testing.
+// It keeps values in memory.
+type mockClient struct {
+	data      map[string]mockBlob
+	version   int
+	err       error
+	deleteErr error
+}
+
+// newMockClient returns a new mockClient with given values.
+func newMockClient(values map[string]string) *mockClient {
+	c := &mockClient{data: map[string]mockBlob{}}
+	for k, v := range values {
+		_, _ = c.Put(context.Background(), k, []byte(v))
+	}
+	return c
+}
+
+// Get returns a value in memory.
+func (c *mockClient) Get(_ context.Context, key string) ([]byte, string, error) {
+	if c.err != nil {
+		return nil, "", c.err
+	}
+	b, ok := c.data[key]
+	if !ok {
+		return nil, "", nil
+	}
+	return b.value, b.etag, nil
+}
+
+// Put sets a value in memory.
+func (c *mockClient) Put(_ context.Context, key string, value []byte) (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	c.version++
+	etag := strconv.Itoa(c.version)
+	c.data[key] = mockBlob{value: value, etag: etag}
+	return etag, nil
+}
+
+// PutIfMatch sets a value in memory if the ETag matches.
+func (c *mockClient) PutIfMatch(ctx context.Context, key string, value []byte, etag string) (string, bool, error) {
+	if c.err != nil {
+		return "", false, c.err
+	}
+	if c.data[key].etag != etag {
+		return "", false, nil
+	}
+	newEtag, err := c.Put(ctx, key, value)
+	if err != nil {
+		return "", false, err
+	}
+	return newEtag, true, nil
+}
+
+// Delete deletes a value in memory.
+func (c *mockClient) Delete(_ context.Context, key string) error {
+	if c.deleteErr != nil {
+		return c.deleteErr
+	}
+	delete(c.data, key)
+	return nil
+}
+
+// testConfig is a config for testing.
+var testConfig = &Config{
+	StorageAccountName: "tfmigrate",
+	ContainerName:      "tfstate",
+	Key:                "tfmigrate/history.json",
+}
+
+func TestStorageWrite(t *testing.T) {
+	cases := []struct {
+		desc     string
+		client   *mockClient
+		contents []byte
+		ok       bool
+	}{
+		{
+			desc:     "simple",
+			client:   newMockClient(nil),
+			contents: []byte("foo"),
+			ok:       true,
+		},
+		{
+			desc: "api error",
+			client: &mockClient{
+				err: fmt.Errorf("permission denied"),
+			},
+			contents: []byte("foo"),
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(testConfig, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			err = s.Write(context.Background(), tc.contents)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				got := string(tc.client.data["tfmigrate/history.json"].value)
+				if got != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", got, string(tc.contents))
+				}
+			}
+		})
+	}
+}
+
+func TestStorageRead(t *testing.T) {
+	cases := []struct {
+		desc   string
+		client *mockClient
+		want   []byte
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			want: []byte("foo"),
+			ok:   true,
+		},
+		{
+			desc:   "blob does not exist",
+			client: newMockClient(nil),
+			want:   []byte{},
+			ok:     true,
+		},
+		{
+			desc: "api error",
+			client: &mockClient{
+				err: fmt.Errorf("permission denied"),
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(testConfig, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, err := s.Read(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", string(got))
+			}
+			if tc.ok && string(got) != string(tc.want) {
+				t.Errorf("got: %s, want: %s", string(got), string(tc.want))
+			}
+		})
+	}
+}
+
+func TestStorageWriteIfVersion(t *testing.T) {
+	cases := []struct {
+		desc     string
+		client   *mockClient
+		version  string
+		want     string
+		conflict bool
+	}{
+		{
+			desc:    "create",
+			client:  newMockClient(nil),
+			version: "",
+			want:    "1",
+		},
+		{
+			desc: "update",
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			version: "1",
+			want:    "2",
+		},
+		{
+			desc: "conflict",
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			version:  "",
+			conflict: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(testConfig, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, err := s.WriteIfVersion(context.Background(), []byte("bar"), tc.version)
+			if tc.conflict {
+				if !errors.Is(err, storage.ErrVersionConflict) {
+					t.Fatalf("expected to return ErrVersionConflict, but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStoragePing(t *testing.T) {
+	cases := []struct {
+		desc   string
+		client *mockClient
+		ok     bool
+	}{
+		{
+			desc:   "simple",
+			client: newMockClient(nil),
+			ok:     true,
+		},
+		{
+			desc: "delete error",
+			client: &mockClient{
+				data:      map[string]mockBlob{},
+				deleteErr: fmt.Errorf("permission denied"),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(testConfig, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.Ping(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				if _, ok := tc.client.data["tfmigrate/history.json"+storage.ProbeKeySuffix]; ok {
+					t.Error("probe blob was not deleted")
+				}
+			}
+		})
+	}
+}
+
+func TestStorageContract(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		s, err := NewStorage(testConfig, newMockClient(nil))
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,231 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/storage/httpapi"
+)
+
+// DefaultAddress is a default address of the Consul agent.
+const DefaultAddress = "http://127.0.0.1:8500"
+
+// Client is an abstraction layer for Consul KV API.
+// It is intended to be replaced with a mock for testing.
+type Client interface {
+	// Get returns a value of a given key and its modify index.
+	// If the key does not exist, it returns nil with the index 0.
+	Get(ctx context.Context, key string) ([]byte, uint64, error)
+	// Put sets a value of a given key.
+	Put(ctx context.Context, key string, value []byte) error
+	// CAS sets a value of a given key only if its modify index matches.
+	// The index 0 means that the key must not exist.
+	// It returns a new modify index and true on success, or false if the
+	// index doesn't match.
+	CAS(ctx context.Context, key string, value []byte, index uint64) (uint64, bool, error)
+	// Delete deletes a given key.
+	Delete(ctx context.Context, key string) error
+}
+
+// client is a real implementation of the Client with Consul HTTP API.
+type client struct {
+	// address is a base URL of the Consul agent.
+	address string
+	// datacenter is a datacenter to use.
+	datacenter string
+	// api sends requests to the Consul agent.
+	api *httpapi.Client
+}
+
+var _ Client = (*client)(nil)
+
+// newClient returns a new instance of Client.
+func newClient(config *Config) (Client, error) {
+	address := config.Address
+	if len(address) == 0 {
+		address = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if len(address) == 0 {
+		address = DefaultAddress
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	if _, err := url.Parse(address); err != nil {
+		return nil, fmt.Errorf("failed to new consul client: invalid address: %s", err)
+	}
+
+	token := config.AccessToken
+	if len(token) == 0 {
+		token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+
+	c := &client{
+		address:    strings.TrimSuffix(address, "/"),
+		datacenter: config.Datacenter,
+		api: &httpapi.Client{
+			Name: "consul",
+			Authorize: func(_ context.Context, r *http.Request) error {
+				if len(token) != 0 {
+					r.Header.Set("X-Consul-Token", token)
+				}
+				return nil
+			},
+			MaxRetries: httpapi.DefaultMaxRetries,
+			RetryWait:  httpapi.DefaultRetryWait,
+		},
+	}
+	return c, nil
+}
+
+// kvPair is a KV entry in a response of Consul KV API.
+type kvPair struct {
+	Key         string `json:"Key"`
+	Value       []byte `json:"Value"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+// Get returns a value of a given key and its modify index.
+func (c *client) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	res, err := c.do(ctx, http.MethodGet, "/v1/kv/"+key, nil, true)
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return nil, 0, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, 0, res.Error("failed to get consul key " + key)
+	}
+
+	var pairs []kvPair
+	if err := json.Unmarshal(res.Body, &pairs); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse consul response: %s", err)
+	}
+	if len(pairs) == 0 {
+		return nil, 0, nil
+	}
+	return pairs[0].Value, pairs[0].ModifyIndex, nil
+}
+
+// Put sets a value of a given key.
+func (c *client) Put(ctx context.Context, key string, value []byte) error {
+	res, err := c.do(ctx, http.MethodPut, "/v1/kv/"+key, value, true)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK || strings.TrimSpace(string(res.Body)) != "true" {
+		return res.Error("failed to put consul key " + key)
+	}
+	return nil
+}
+
+// txnOp is an operation of Consul transaction API.
+type txnOp struct {
+	KV *txnKVOp `json:"KV"`
+}
+
+// txnKVOp is a KV operation of Consul transaction API.
+type txnKVOp struct {
+	Verb  string `json:"Verb"`
+	Key   string `json:"Key"`
+	Value []byte `json:"Value,omitempty"`
+	Index uint64 `json:"Index"`
+}
+
+// txnResponse is a response of Consul transaction API.
+type txnResponse struct {
+	Results []struct {
+		KV *kvPair `json:"KV"`
+	} `json:"Results"`
+}
+
+// CAS sets a value of a given key only if its modify index matches.
+// We use the transaction API instead of the `cas` query parameter of KV API,
+// because the latter doesn't return a new modify index.
+func (c *client) CAS(ctx context.Context, key string, value []byte, index uint64) (uint64, bool, error) {
+	ops := []txnOp{
+		{KV: &txnKVOp{Verb: "cas", Key: key, Value: value, Index: index}},
+	}
+	payload, err := json.Marshal(ops)
+	if err != nil {
+		return 0, false, err
+	}
+
+	// A transaction is not idempotent, because a retry of a successful one
+	// fails as a conflict.
+	res, err := c.do(ctx, http.MethodPut, "/v1/txn", payload, false)
+	if err != nil {
+		return 0, false, err
+	}
+	// The transaction API returns 409 Conflict if the transaction is rolled back.
+	if res.StatusCode == http.StatusConflict {
+		return 0, false, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, false, res.Error("failed to cas consul key " + key)
+	}
+
+	var txn txnResponse
+	if err := json.Unmarshal(res.Body, &txn); err != nil {
+		return 0, false, fmt.Errorf("failed to parse consul response: %s", err)
+	}
+	if len(txn.Results) == 0 || txn.Results[0].KV == nil {
+		return 0, false, fmt.Errorf("failed to cas consul key %s: unexpected response: %s", key, string(res.Body))
+	}
+	return txn.Results[0].KV.ModifyIndex, true, nil
+}
+
+// Delete deletes a given key.
+func (c *client) Delete(ctx context.Context, key string) error {
+	res, err := c.do(ctx, http.MethodDelete, "/v1/kv/"+key, nil, true)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return res.Error("failed to delete consul key " + key)
+	}
+	return nil
+}
+
+// do sends an HTTP request to the Consul agent and returns a response with
+// its body.
+func (c *client) do(ctx context.Context, method string, path string, payload []byte, idempotent bool) (*httpapi.Response, error) {
+	u := c.address + path
+	if len(c.datacenter) != 0 {
+		u += "?" + url.Values{"dc": []string{c.datacenter}}.Encode()
+	}
+
+	return c.api.Do(ctx, &httpapi.Request{
+		Method:     method,
+		URL:        u,
+		Body:       payload,
+		Idempotent: idempotent,
+	})
+}
+
+// formatIndex formats a modify index as a version string.
+func formatIndex(index uint64) string {
+	if index == 0 {
+		return ""
+	}
+	return strconv.FormatUint(index, 10)
+}
+
+// parseIndex parses a version string as a modify index.
+func parseIndex(version string) (uint64, error) {
+	if len(version) == 0 {
+		return 0, nil
+	}
+	index, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid consul modify index: %s", version)
+	}
+	return index, nil
+}
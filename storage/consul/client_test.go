@@ -0,0 +1,169 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeConsul is a minimal fake server of Consul KV and transaction API.
+type fakeConsul struct {
+	mu    sync.Mutex
+	data  map[string]kvPair
+	index uint64
+	token string
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.token != "" && r.Header.Get("X-Consul-Token") != f.token {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.URL.Path == "/v1/txn" && r.Method == http.MethodPut:
+		var ops []txnOp
+		if err := json.Unmarshal(body, &ops); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		op := ops[0].KV
+		if f.data[op.Key].ModifyIndex != op.Index {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"Results":null,"Errors":[{"OpIndex":0,"What":"failed to set key"}]}`))
+			return
+		}
+		f.index++
+		p := kvPair{Key: op.Key, Value: op.Value, ModifyIndex: f.index}
+		f.data[op.Key] = p
+		_ = json.NewEncoder(w).Encode(map[string]any{"Results": []map[string]any{{"KV": p}}})
+
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		switch r.Method {
+		case http.MethodGet:
+			p, ok := f.data[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]kvPair{p})
+		case http.MethodPut:
+			f.index++
+			f.data[key] = kvPair{Key: key, Value: body, ModifyIndex: f.index}
+			_, _ = w.Write([]byte("true"))
+		case http.MethodDelete:
+			delete(f.data, key)
+			_, _ = w.Write([]byte("true"))
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestClient(t *testing.T) {
+	fake := &fakeConsul{data: map[string]kvPair{}, token: "secret"}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	c, err := newClient(&Config{Address: server.URL, AccessToken: "secret"})
+	if err != nil {
+		t.Fatalf("failed to new client: %s", err)
+	}
+	ctx := context.Background()
+
+	// key does not exist
+	got, index, err := c.Get(ctx, "tfmigrate/history.json")
+	if err != nil || got != nil || index != 0 {
+		t.Fatalf("unexpected get result: %s, %d, %v", string(got), index, err)
+	}
+
+	// create with cas
+	index, ok, err := c.CAS(ctx, "tfmigrate/history.json", []byte("foo"), 0)
+	if err != nil || !ok || index != 1 {
+		t.Fatalf("unexpected cas result: %d, %t, %v", index, ok, err)
+	}
+
+	// conflict
+	_, ok, err = c.CAS(ctx, "tfmigrate/history.json", []byte("bar"), 0)
+	if err != nil || ok {
+		t.Fatalf("expected to conflict, but got: %t, %v", ok, err)
+	}
+
+	// put and get
+	if err := c.Put(ctx, "tfmigrate/history.json", []byte("baz")); err != nil {
+		t.Fatalf("failed to put: %s", err)
+	}
+	got, index, err = c.Get(ctx, "tfmigrate/history.json")
+	if err != nil || string(got) != "baz" || index != 2 {
+		t.Fatalf("unexpected get result: %s, %d, %v", string(got), index, err)
+	}
+
+	// delete
+	if err := c.Delete(ctx, "tfmigrate/history.json"); err != nil {
+		t.Fatalf("failed to delete: %s", err)
+	}
+	if _, ok := fake.data["tfmigrate/history.json"]; ok {
+		t.Fatal("key was not deleted")
+	}
+
+	// invalid token
+	c, err = newClient(&Config{Address: server.URL, AccessToken: "invalid"})
+	if err != nil {
+		t.Fatalf("failed to new client: %s", err)
+	}
+	if _, _, err := c.Get(ctx, "tfmigrate/history.json"); err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestNewClientAddress(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		env    string
+		want   string
+	}{
+		{
+			desc:   "default",
+			config: &Config{},
+			want:   "http://127.0.0.1:8500",
+		},
+		{
+			desc:   "env",
+			config: &Config{},
+			env:    "consul.example.com:8500",
+			want:   "http://consul.example.com:8500",
+		},
+		{
+			desc:   "config",
+			config: &Config{Address: "https://consul.example.com/"},
+			env:    "consul.example.com:8500",
+			want:   "https://consul.example.com",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("CONSUL_HTTP_ADDR", tc.env)
+			c, err := newClient(tc.config)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			got := c.(*client).address
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package consul
+
+import "github.com/minamijoyo/tfmigrate/storage"
+
+// Config is a config for Consul KV storage.
+// This is expected to have a subset of options of Terraform consul backend.
+// https://developer.hashicorp.com/terraform/language/settings/backends/consul
+type Config struct {
+	// Address of the Consul agent, such as `http://127.0.0.1:8500`.
+	// If a scheme is omitted, `http` is assumed.
+	// Default to the CONSUL_HTTP_ADDR environment variable, or
+	// `http://127.0.0.1:8500` if not set.
+	Address string `hcl:"address,optional"`
+	// Prefix of keys. The history is stored at `<prefix>/history.json`.
+	Prefix string `hcl:"prefix"`
+	// ACL token.
+	// Default to the CONSUL_HTTP_TOKEN environment variable.
+	AccessToken string `hcl:"access_token,optional"`
+	// Datacenter to use. Default to the datacenter of the agent.
+	Datacenter string `hcl:"datacenter,optional"`
+}
+
+// Config implements a storage.Config.
+var _ storage.Config = (*Config)(nil)
+
+// NewStorage returns a new instance of storage.Storage.
+func (c *Config) NewStorage() (storage.Storage, error) {
+	return NewStorage(c, nil)
+}
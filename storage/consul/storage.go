@@ -0,0 +1,107 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// historyKeyName is a name of key for the history file under the prefix.
+const historyKeyName = "history.json"
+
+// Storage is a storage.Storage implementation for Consul KV.
+type Storage struct {
+	// config is a storage config for Consul.
+	config *Config
+	// client is an instance of Client interface to call API.
+	// It is intended to be replaced with a mock for testing.
+	client Client
+}
+
+var _ storage.Storage = (*Storage)(nil)
+var _ storage.VersionedStorage = (*Storage)(nil)
+
+// NewStorage returns a new instance of Storage.
+func NewStorage(config *Config, client Client) (*Storage, error) {
+	if client == nil {
+		var err error
+		client, err = newClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Storage{
+		config: config,
+		client: client,
+	}
+	return s, nil
+}
+
+// key returns a key of the history file.
+func (s *Storage) key() string {
+	return path.Join(s.config.Prefix, historyKeyName)
+}
+
+// Write writes migration history data to storage.
+func (s *Storage) Write(ctx context.Context, b []byte) error {
+	return s.client.Put(ctx, s.key(), b)
+}
+
+// Read reads migration history data from storage.
+// If the key does not exist, it is assumed to be uninitialized and returns
+// an empty array instead of an error.
+func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, _, err := s.ReadWithVersion(ctx)
+	return b, err
+}
+
+// ReadWithVersion reads migration history data with its modify index.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, string, error) {
+	b, index, err := s.client.Get(ctx, s.key())
+	if err != nil {
+		return nil, "", err
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, formatIndex(index), nil
+}
+
+// WriteIfVersion writes migration history data only if its modify index
+// matches a given version.
+func (s *Storage) WriteIfVersion(ctx context.Context, b []byte, version string) (string, error) {
+	index, err := parseIndex(version)
+	if err != nil {
+		return "", err
+	}
+
+	newIndex, ok, err := s.client.CAS(ctx, s.key(), b, index)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("failed to write consul key %s at index %q: %w", s.key(), version, storage.ErrVersionConflict)
+	}
+	return formatIndex(newIndex), nil
+}
+
+// Ping checks permissions to read the history key, and to write and delete
+// a probe key next to it.
+func (s *Storage) Ping(ctx context.Context) (*storage.PingResult, error) {
+	probe := s.key() + storage.ProbeKeySuffix
+	return storage.Probe(ctx,
+		func(ctx context.Context) error {
+			_, err := s.Read(ctx)
+			return err
+		},
+		func(ctx context.Context, b []byte) error {
+			return s.client.Put(ctx, probe, b)
+		},
+		func(ctx context.Context) error {
+			return s.client.Delete(ctx, probe)
+		},
+	)
+}
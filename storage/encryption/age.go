@@ -0,0 +1,180 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/mattn/go-shellwords"
+)
+
+const (
+	// DefaultAgeCommand is a default command line of age.
+	DefaultAgeCommand = "age"
+	// DefaultAgeIdentityFileEnv is a name of environment variable for a path
+	// to an age identity file, which is used if identity_file is not set.
+	DefaultAgeIdentityFileEnv = "TFMIGRATE_HISTORY_ENCRYPTION_AGE_IDENTITY_FILE"
+)
+
+// AgeConfig is a config for an age key provider.
+// A new data key is generated on every write, and it's stored with
+// ciphertext in a form encrypted to recipients with the age command.
+// https://age-encryption.org/
+type AgeConfig struct {
+	// Recipients is a list of recipients to which a data key is encrypted,
+	// such as age1... public keys or SSH public keys.
+	Recipients []string `hcl:"recipients"`
+	// IdentityFile is a path to an age identity file to decrypt a data key.
+	// Default to the TFMIGRATE_HISTORY_ENCRYPTION_AGE_IDENTITY_FILE
+	// environment variable. It's required only for decryption.
+	IdentityFile string `hcl:"identity_file,optional"`
+	// Command is a command line of age. It may contain spaces like a shell.
+	// Default to `age`.
+	Command string `hcl:"command,optional"`
+}
+
+// AgeConfig implements a Config.
+var _ Config = (*AgeConfig)(nil)
+
+// NewKeyProvider returns a new instance of KeyProvider.
+func (c *AgeConfig) NewKeyProvider() (KeyProvider, error) {
+	return NewAgeKeyProvider(c, nil)
+}
+
+// AgeClient is an abstraction layer for the age command.
+// It is intended to be replaced with a mock for testing.
+type AgeClient interface {
+	// Encrypt encrypts a given plaintext to given recipients.
+	Encrypt(ctx context.Context, recipients []string, plaintext []byte) ([]byte, error)
+	// Decrypt decrypts a given ciphertext with a given identity file.
+	Decrypt(ctx context.Context, identityFile string, ciphertext []byte) ([]byte, error)
+}
+
+// ageCommand is a real implementation of the AgeClient with the age command.
+type ageCommand struct {
+	// args is a command line of age split into arguments.
+	args []string
+}
+
+var _ AgeClient = (*ageCommand)(nil)
+
+// newAgeClient returns a new instance of AgeClient.
+func newAgeClient(config *AgeConfig) (AgeClient, error) {
+	command := config.Command
+	if len(command) == 0 {
+		command = DefaultAgeCommand
+	}
+
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age command: %s", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("age command is empty")
+	}
+
+	return &ageCommand{args: args}, nil
+}
+
+// Encrypt encrypts a given plaintext to given recipients.
+func (c *ageCommand) Encrypt(ctx context.Context, recipients []string, plaintext []byte) ([]byte, error) {
+	args := []string{"--encrypt"}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	return c.run(ctx, args, plaintext)
+}
+
+// Decrypt decrypts a given ciphertext with a given identity file.
+func (c *ageCommand) Decrypt(ctx context.Context, identityFile string, ciphertext []byte) ([]byte, error) {
+	return c.run(ctx, []string{"--decrypt", "--identity", identityFile}, ciphertext)
+}
+
+// run runs the age command with given arguments and stdin, and returns its
+// stdout.
+func (c *ageCommand) run(ctx context.Context, args []string, stdin []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, c.args[0], append(c.args[1:], args...)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run age: %s: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// AgeKeyProvider is a KeyProvider which encrypts data keys with age.
+type AgeKeyProvider struct {
+	// config is a config for age.
+	config AgeConfig
+	// client is an instance of AgeClient interface to run age.
+	// It is intended to be replaced with a mock for testing.
+	client AgeClient
+}
+
+var _ KeyProvider = (*AgeKeyProvider)(nil)
+
+// NewAgeKeyProvider returns a new instance of AgeKeyProvider.
+// If the client is nil, a real client is created.
+func NewAgeKeyProvider(config *AgeConfig, client AgeClient) (*AgeKeyProvider, error) {
+	if len(config.Recipients) == 0 {
+		return nil, fmt.Errorf("age recipients are required")
+	}
+
+	if client == nil {
+		var err error
+		client, err = newAgeClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := &AgeKeyProvider{
+		config: *config,
+		client: client,
+	}
+
+	return p, nil
+}
+
+// Type returns a type name of the key provider.
+func (p *AgeKeyProvider) Type() string {
+	return "age"
+}
+
+// GenerateDataKey generates a new data key and encrypts it with age.
+func (p *AgeKeyProvider) GenerateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate a data key: %s", err)
+	}
+
+	encryptedKey, err := p.client.Encrypt(ctx, p.config.Recipients, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, encryptedKey, nil
+}
+
+// DecryptDataKey decrypts an encrypted data key with age.
+func (p *AgeKeyProvider) DecryptDataKey(ctx context.Context, encryptedKey []byte) ([]byte, error) {
+	if len(encryptedKey) == 0 {
+		return nil, fmt.Errorf("encrypted data key is missing")
+	}
+
+	identityFile := p.config.IdentityFile
+	if len(identityFile) == 0 {
+		identityFile = os.Getenv(DefaultAgeIdentityFileEnv)
+	}
+	if len(identityFile) == 0 {
+		return nil, fmt.Errorf("age identity file is not set. Set identity_file or %s", DefaultAgeIdentityFileEnv)
+	}
+
+	return p.client.Decrypt(ctx, identityFile, encryptedKey)
+}
@@ -0,0 +1,164 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+// mockAgeClient is a mock implementation for testing.
+// It "encrypts" a data key by reversing bytes.
+type mockAgeClient struct {
+	identityFile string
+	err          error
+}
+
+// Encrypt returns a reversed plaintext.
+func (c *mockAgeClient) Encrypt(_ context.Context, _ []string, plaintext []byte) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return reverse(plaintext), nil
+}
+
+// Decrypt returns a reversed ciphertext and records the identity file.
+func (c *mockAgeClient) Decrypt(_ context.Context, identityFile string, ciphertext []byte) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.identityFile = identityFile
+	return reverse(ciphertext), nil
+}
+
+func TestAgeKeyProvider(t *testing.T) {
+	plaintext := []byte(`{"version": 2}`)
+
+	cases := []struct {
+		desc         string
+		config       *AgeConfig
+		env          string
+		client       *mockAgeClient
+		identityFile string
+		ok           bool
+	}{
+		{
+			desc: "simple",
+			config: &AgeConfig{
+				Recipients:   []string{"age1foo"},
+				IdentityFile: "key.txt",
+			},
+			client:       &mockAgeClient{},
+			identityFile: "key.txt",
+			ok:           true,
+		},
+		{
+			desc: "identity file from env",
+			config: &AgeConfig{
+				Recipients: []string{"age1foo"},
+			},
+			env:          "env.txt",
+			client:       &mockAgeClient{},
+			identityFile: "env.txt",
+			ok:           true,
+		},
+		{
+			desc: "missing identity file",
+			config: &AgeConfig{
+				Recipients: []string{"age1foo"},
+			},
+			client: &mockAgeClient{},
+			ok:     false,
+		},
+		{
+			desc: "age error",
+			config: &AgeConfig{
+				Recipients:   []string{"age1foo"},
+				IdentityFile: "key.txt",
+			},
+			client: &mockAgeClient{
+				err: fmt.Errorf("no identity matched any of the recipients"),
+			},
+			ok: false,
+		},
+		{
+			desc:   "missing recipients",
+			config: &AgeConfig{},
+			client: &mockAgeClient{},
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv(DefaultAgeIdentityFileEnv, tc.env)
+			ctx := context.Background()
+			p, err := NewAgeKeyProvider(tc.config, tc.client)
+			if err == nil {
+				var b []byte
+				b, err = Encrypt(ctx, p, plaintext)
+				if err == nil {
+					var got []byte
+					got, err = Decrypt(ctx, p, b)
+					if err == nil && !bytes.Equal(got, plaintext) {
+						t.Errorf("got: %s, want: %s", string(got), string(plaintext))
+					}
+				}
+			}
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && tc.client.identityFile != tc.identityFile {
+				t.Errorf("got identity file: %s, want: %s", tc.client.identityFile, tc.identityFile)
+			}
+		})
+	}
+}
+
+func TestNewAgeClient(t *testing.T) {
+	cases := []struct {
+		desc    string
+		command string
+		want    []string
+		ok      bool
+	}{
+		{
+			desc:    "default",
+			command: "",
+			want:    []string{"age"},
+			ok:      true,
+		},
+		{
+			desc:    "with args",
+			command: "rage --armor",
+			want:    []string{"rage", "--armor"},
+			ok:      true,
+		},
+		{
+			desc:    "parse error",
+			command: "age 'foo",
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := newAgeClient(&AgeConfig{Command: tc.command})
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				args := got.(*ageCommand).args
+				if fmt.Sprint(args) != fmt.Sprint(tc.want) {
+					t.Errorf("got: %v, want: %v", args, tc.want)
+				}
+			}
+		})
+	}
+}
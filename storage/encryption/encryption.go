@@ -0,0 +1,155 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// envelopeVersion is a format version of the encrypted envelope.
+const envelopeVersion = 1
+
+// KeyProvider is an abstraction layer for a data key used to encrypt history.
+// The history is always encrypted with AES-256-GCM, and a KeyProvider
+// decides where the data key comes from.
+type KeyProvider interface {
+	// Type returns a type name of the key provider, which is recorded in the
+	// envelope to detect a misconfiguration on decryption.
+	Type() string
+	// GenerateDataKey returns a new plaintext data key and an encrypted form of
+	// it to be stored with ciphertext. The encrypted form may be nil if the
+	// key doesn't need to be stored.
+	GenerateDataKey(ctx context.Context) ([]byte, []byte, error)
+	// DecryptDataKey returns a plaintext data key from an encrypted form stored
+	// with ciphertext.
+	DecryptDataKey(ctx context.Context, encryptedKey []byte) ([]byte, error)
+}
+
+// Config is an interface of factory method for KeyProvider.
+type Config interface {
+	// NewKeyProvider returns a new instance of KeyProvider.
+	NewKeyProvider() (KeyProvider, error)
+}
+
+// envelope is a JSON wrapper of encrypted data.
+type envelope struct {
+	// Encryption is a header of the envelope.
+	// We use a distinct top-level key which never appears in a plain history
+	// file to detect whether or not data is encrypted.
+	Encryption *envelopeHeader `json:"encryption"`
+	// Ciphertext is the encrypted data.
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// envelopeHeader is metadata required to decrypt the envelope.
+type envelopeHeader struct {
+	// Version is a format version of the envelope.
+	Version int `json:"version"`
+	// Type is a type name of the KeyProvider.
+	Type string `json:"type"`
+	// EncryptedKey is an encrypted data key if any.
+	EncryptedKey []byte `json:"encrypted_key,omitempty"`
+	// Nonce is a nonce for AES-GCM.
+	Nonce []byte `json:"nonce"`
+}
+
+// Encrypt encrypts a given plaintext with a data key from a given KeyProvider
+// and returns an envelope in JSON.
+func Encrypt(ctx context.Context, p KeyProvider, plaintext []byte) ([]byte, error) {
+	key, encryptedKey, err := p.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate a data key: %s", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate a nonce: %s", err)
+	}
+
+	e := envelope{
+		Encryption: &envelopeHeader{
+			Version:      envelopeVersion,
+			Type:         p.Type(),
+			EncryptedKey: encryptedKey,
+			Nonce:        nonce,
+		},
+		// Bind the key provider type to the ciphertext.
+		Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(p.Type())),
+	}
+
+	return json.MarshalIndent(e, "", "    ")
+}
+
+// Decrypt decrypts a given envelope in JSON with a data key from a given
+// KeyProvider and returns plaintext.
+func Decrypt(ctx context.Context, p KeyProvider, b []byte) ([]byte, error) {
+	var e envelope
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse an encrypted envelope: %s", err)
+	}
+
+	if e.Encryption == nil {
+		return nil, fmt.Errorf("failed to decrypt: data is not encrypted")
+	}
+
+	if e.Encryption.Version != envelopeVersion {
+		return nil, fmt.Errorf("unknown encrypted envelope version: %d", e.Encryption.Version)
+	}
+
+	if e.Encryption.Type != p.Type() {
+		return nil, fmt.Errorf("failed to decrypt: encrypted with %s, but configured with %s", e.Encryption.Type, p.Type())
+	}
+
+	key, err := p.DecryptDataKey(ctx, e.Encryption.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt a data key: %s", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(e.Encryption.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt: invalid nonce size: %d", len(e.Encryption.Nonce))
+	}
+
+	plaintext, err := aead.Open(nil, e.Encryption.Nonce, e.Ciphertext, []byte(e.Encryption.Type))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %s", err)
+	}
+
+	return plaintext, nil
+}
+
+// IsEncrypted returns true if given data is an encrypted envelope.
+func IsEncrypted(b []byte) bool {
+	var e envelope
+	if err := json.Unmarshal(b, &e); err != nil {
+		return false
+	}
+	return e.Encryption != nil
+}
+
+// newAEAD returns a new AES-256-GCM cipher with a given key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid data key size: %d bytes, expected 32 bytes", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
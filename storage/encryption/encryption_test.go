@@ -0,0 +1,209 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte(`{"version": 2}`)
+
+	cases := []struct {
+		desc    string
+		encrypt KeyProvider
+		decrypt KeyProvider
+		tamper  bool
+		ok      bool
+	}{
+		{
+			desc:    "simple",
+			encrypt: &StaticKeyProvider{key: testKey(1)},
+			decrypt: &StaticKeyProvider{key: testKey(1)},
+			ok:      true,
+		},
+		{
+			desc:    "wrong key",
+			encrypt: &StaticKeyProvider{key: testKey(1)},
+			decrypt: &StaticKeyProvider{key: testKey(2)},
+			ok:      false,
+		},
+		{
+			desc:    "wrong key provider type",
+			encrypt: &StaticKeyProvider{key: testKey(1)},
+			decrypt: &KMSKeyProvider{config: KMSConfig{KeyID: "foo"}, client: &mockKMSClient{}},
+			ok:      false,
+		},
+		{
+			desc:    "tampered",
+			encrypt: &StaticKeyProvider{key: testKey(1)},
+			decrypt: &StaticKeyProvider{key: testKey(1)},
+			tamper:  true,
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			b, err := Encrypt(ctx, tc.encrypt, plaintext)
+			if err != nil {
+				t.Fatalf("failed to encrypt: %s", err)
+			}
+			if bytes.Contains(b, plaintext) {
+				t.Fatalf("encrypted data contains plaintext: %s", string(b))
+			}
+
+			if tc.tamper {
+				var e envelope
+				if err := json.Unmarshal(b, &e); err != nil {
+					t.Fatalf("failed to parse envelope: %s", err)
+				}
+				e.Ciphertext[0] ^= 0xff
+				if b, err = json.Marshal(e); err != nil {
+					t.Fatalf("failed to marshal envelope: %s", err)
+				}
+			}
+
+			got, err := Decrypt(ctx, tc.decrypt, b)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", string(got))
+			}
+			if tc.ok && !bytes.Equal(got, plaintext) {
+				t.Errorf("got: %s, want: %s", string(got), string(plaintext))
+			}
+		})
+	}
+}
+
+func TestStorageReadWrite(t *testing.T) {
+	cases := []struct {
+		desc           string
+		config         *mock.Config
+		allowPlaintext bool
+		want           string
+		ok             bool
+	}{
+		{
+			desc:   "empty",
+			config: &mock.Config{},
+			want:   "",
+			ok:     true,
+		},
+		{
+			desc: "plain history",
+			config: &mock.Config{
+				Data: `{"version": 2}`,
+			},
+			want: "",
+			ok:   false,
+		},
+		{
+			desc: "plain history with allow_plaintext_migration",
+			config: &mock.Config{
+				Data: `{"version": 2}`,
+			},
+			allowPlaintext: true,
+			want:           `{"version": 2}`,
+			ok:             true,
+		},
+		{
+			desc: "read error",
+			config: &mock.Config{
+				ReadError: true,
+			},
+			want: "",
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			ms, err := mock.NewStorage(tc.config)
+			if err != nil {
+				t.Fatalf("failed to new mock storage: %s", err)
+			}
+			s := NewStorage(ms, &StaticKeyProvider{key: testKey(1)})
+			s.allowPlaintextMigration = tc.allowPlaintext
+
+			got, err := s.Read(ctx)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", string(got))
+			}
+			if !tc.ok {
+				return
+			}
+			if string(got) != tc.want {
+				t.Errorf("got: %s, want: %s", string(got), tc.want)
+			}
+
+			// write and read it again.
+			data := []byte(`{"version": 2, "updated": true}`)
+			if err := s.Write(ctx, data); err != nil {
+				t.Fatalf("failed to write: %s", err)
+			}
+			if !IsEncrypted([]byte(ms.Data())) {
+				t.Fatalf("expected to be encrypted, but got: %s", ms.Data())
+			}
+			got, err = s.Read(ctx)
+			if err != nil {
+				t.Fatalf("failed to read: %s", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("got: %s, want: %s", string(got), string(data))
+			}
+		})
+	}
+}
+
+func TestStorageReadWriteWithVersion(t *testing.T) {
+	ctx := context.Background()
+	ms, err := mock.NewStorage(&mock.Config{})
+	if err != nil {
+		t.Fatalf("failed to new mock storage: %s", err)
+	}
+	s := NewStorage(ms, &StaticKeyProvider{key: testKey(1)})
+
+	_, version, err := s.ReadWithVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to read: %s", err)
+	}
+
+	data := []byte(`{"version": 2}`)
+	newVersion, err := s.WriteIfVersion(ctx, data, version)
+	if err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+	if !IsEncrypted([]byte(ms.Data())) {
+		t.Fatalf("expected to be encrypted, but got: %s", ms.Data())
+	}
+
+	got, gotVersion, err := s.ReadWithVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to read: %s", err)
+	}
+	if !bytes.Equal(got, data) || gotVersion != newVersion {
+		t.Errorf("got: %s (%s), want: %s (%s)", string(got), gotVersion, string(data), newVersion)
+	}
+
+	// a stale version is rejected.
+	if _, err := s.WriteIfVersion(ctx, data, version); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("expected a version conflict, but got: %v", err)
+	}
+}
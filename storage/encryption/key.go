@@ -0,0 +1,78 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// DefaultKeyEnv is a default name of environment variable for an encryption key.
+const DefaultKeyEnv = "TFMIGRATE_HISTORY_ENCRYPTION_KEY"
+
+// KeyConfig is a config for a static key provider.
+// The key is read from an environment variable so that it's never written to
+// the config file.
+type KeyConfig struct {
+	// KeyEnv is a name of environment variable which contains a base64 encoded
+	// 32 bytes key.
+	// Default to TFMIGRATE_HISTORY_ENCRYPTION_KEY.
+	KeyEnv string `hcl:"key_env,optional"`
+}
+
+// KeyConfig implements a Config.
+var _ Config = (*KeyConfig)(nil)
+
+// NewKeyProvider returns a new instance of KeyProvider.
+func (c *KeyConfig) NewKeyProvider() (KeyProvider, error) {
+	env := DefaultKeyEnv
+	if len(c.KeyEnv) != 0 {
+		env = c.KeyEnv
+	}
+
+	encoded := os.Getenv(env)
+	if len(encoded) == 0 {
+		return nil, fmt.Errorf("encryption key is not set. Set a base64 encoded 32 bytes key to %s", env)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encryption key in %s: %s", env, err)
+	}
+
+	return NewStaticKeyProvider(key)
+}
+
+// StaticKeyProvider is a KeyProvider which uses a given key as a data key.
+type StaticKeyProvider struct {
+	// key is a 32 bytes key for AES-256.
+	key []byte
+}
+
+var _ KeyProvider = (*StaticKeyProvider)(nil)
+
+// NewStaticKeyProvider returns a new instance of StaticKeyProvider.
+func NewStaticKeyProvider(key []byte) (*StaticKeyProvider, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid encryption key size: %d bytes, expected 32 bytes", len(key))
+	}
+
+	return &StaticKeyProvider{
+		key: key,
+	}, nil
+}
+
+// Type returns a type name of the key provider.
+func (p *StaticKeyProvider) Type() string {
+	return "key"
+}
+
+// GenerateDataKey returns the static key. There is no need to store it.
+func (p *StaticKeyProvider) GenerateDataKey(_ context.Context) ([]byte, []byte, error) {
+	return p.key, nil, nil
+}
+
+// DecryptDataKey returns the static key.
+func (p *StaticKeyProvider) DecryptDataKey(_ context.Context, _ []byte) ([]byte, error) {
+	return p.key, nil
+}
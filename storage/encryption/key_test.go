@@ -0,0 +1,73 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestKeyConfigNewKeyProvider(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *KeyConfig
+		env    map[string]string
+		ok     bool
+	}{
+		{
+			desc:   "default env",
+			config: &KeyConfig{},
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_ENCRYPTION_KEY": base64.StdEncoding.EncodeToString(testKey(1)),
+			},
+			ok: true,
+		},
+		{
+			desc: "custom env",
+			config: &KeyConfig{
+				KeyEnv: "MY_HISTORY_KEY",
+			},
+			env: map[string]string{
+				"MY_HISTORY_KEY": base64.StdEncoding.EncodeToString(testKey(1)),
+			},
+			ok: true,
+		},
+		{
+			desc:   "not set",
+			config: &KeyConfig{},
+			env:    map[string]string{},
+			ok:     false,
+		},
+		{
+			desc:   "invalid base64",
+			config: &KeyConfig{},
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_ENCRYPTION_KEY": "!!!",
+			},
+			ok: false,
+		},
+		{
+			desc:   "invalid key size",
+			config: &KeyConfig{},
+			env: map[string]string{
+				"TFMIGRATE_HISTORY_ENCRYPTION_KEY": base64.StdEncoding.EncodeToString([]byte("short")),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("TFMIGRATE_HISTORY_ENCRYPTION_KEY", "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := tc.config.NewKeyProvider()
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+		})
+	}
+}
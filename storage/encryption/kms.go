@@ -0,0 +1,161 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	awsbase "github.com/hashicorp/aws-sdk-go-base"
+)
+
+// KMSConfig is a config for an AWS KMS envelope encryption key provider.
+// A new data key is generated by KMS on every write, and it's stored with
+// ciphertext in an encrypted form.
+type KMSConfig struct {
+	// KeyID is an ID, ARN or alias of the KMS key.
+	KeyID string `hcl:"key_id"`
+
+	// AWS region.
+	Region string `hcl:"region,optional"`
+	// Custom endpoint for the AWS KMS API.
+	Endpoint string `hcl:"endpoint,optional"`
+	// AWS access key.
+	AccessKey string `hcl:"access_key,optional"`
+	// AWS secret key.
+	SecretKey string `hcl:"secret_key,optional"`
+	// Name of AWS profile in AWS shared credentials file.
+	Profile string `hcl:"profile,optional"`
+	// Amazon Resource Name (ARN) of the IAM Role to assume.
+	RoleARN string `hcl:"role_arn,optional"`
+	// Skip credentials validation via the STS API.
+	SkipCredentialsValidation bool `hcl:"skip_credentials_validation,optional"`
+	// Skip usage of EC2 Metadata API.
+	SkipMetadataAPICheck bool `hcl:"skip_metadata_api_check,optional"`
+}
+
+// KMSConfig implements a Config.
+var _ Config = (*KMSConfig)(nil)
+
+// NewKeyProvider returns a new instance of KeyProvider.
+func (c *KMSConfig) NewKeyProvider() (KeyProvider, error) {
+	return NewKMSKeyProvider(c, nil)
+}
+
+// KMSClient is an abstraction layer for AWS KMS API.
+// It is intended to be replaced with a mock for testing.
+type KMSClient interface {
+	// GenerateDataKeyWithContext generates a new data key.
+	GenerateDataKeyWithContext(ctx aws.Context, input *kms.GenerateDataKeyInput, opts ...request.Option) (*kms.GenerateDataKeyOutput, error)
+	// DecryptWithContext decrypts an encrypted data key.
+	DecryptWithContext(ctx aws.Context, input *kms.DecryptInput, opts ...request.Option) (*kms.DecryptOutput, error)
+}
+
+// newKMSClient returns a new instance of KMSClient.
+func newKMSClient(config *KMSConfig) (KMSClient, error) {
+	cfg := &awsbase.Config{
+		AccessKey:            config.AccessKey,
+		AssumeRoleARN:        config.RoleARN,
+		Profile:              config.Profile,
+		Region:               config.Region,
+		SecretKey:            config.SecretKey,
+		SkipCredsValidation:  config.SkipCredentialsValidation,
+		SkipMetadataApiCheck: config.SkipMetadataAPICheck,
+	}
+
+	sess, err := awsbase.GetSession(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to new kms client: %s", err)
+	}
+
+	client := kms.New(sess.Copy(&aws.Config{
+		Endpoint: aws.String(config.Endpoint),
+	}))
+
+	return client, nil
+}
+
+// KMSKeyProvider is a KeyProvider which generates data keys with AWS KMS.
+type KMSKeyProvider struct {
+	// config is a config for KMS.
+	config KMSConfig
+	// client is an instance of KMSClient interface to call API.
+	// It is intended to be replaced with a mock for testing.
+	client KMSClient
+}
+
+var _ KeyProvider = (*KMSKeyProvider)(nil)
+
+// NewKMSKeyProvider returns a new instance of KMSKeyProvider.
+// If the client is nil, a real client is created lazily on first use.
+func NewKMSKeyProvider(config *KMSConfig, client KMSClient) (*KMSKeyProvider, error) {
+	if len(config.KeyID) == 0 {
+		return nil, fmt.Errorf("kms key_id is required")
+	}
+
+	p := &KMSKeyProvider{
+		config: *config,
+		client: client,
+	}
+
+	return p, nil
+}
+
+// init initializes a KMS client if not yet.
+func (p *KMSKeyProvider) init() error {
+	if p.client != nil {
+		return nil
+	}
+
+	client, err := newKMSClient(&p.config)
+	if err != nil {
+		return err
+	}
+
+	p.client = client
+	return nil
+}
+
+// Type returns a type name of the key provider.
+func (p *KMSKeyProvider) Type() string {
+	return "kms"
+}
+
+// GenerateDataKey generates a new data key with KMS.
+func (p *KMSKeyProvider) GenerateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	if err := p.init(); err != nil {
+		return nil, nil, err
+	}
+
+	output, err := p.client.GenerateDataKeyWithContext(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(p.config.KeyID),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return output.Plaintext, output.CiphertextBlob, nil
+}
+
+// DecryptDataKey decrypts an encrypted data key with KMS.
+func (p *KMSKeyProvider) DecryptDataKey(ctx context.Context, encryptedKey []byte) ([]byte, error) {
+	if len(encryptedKey) == 0 {
+		return nil, fmt.Errorf("encrypted data key is missing")
+	}
+
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+
+	output, err := p.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(p.config.KeyID),
+		CiphertextBlob: encryptedKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Plaintext, nil
+}
@@ -0,0 +1,108 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// mockKMSClient is a mock implementation for testing.
+// It "encrypts" a data key by reversing bytes.
+type mockKMSClient struct {
+	err error
+}
+
+// GenerateDataKeyWithContext returns a fixed data key.
+func (c *mockKMSClient) GenerateDataKeyWithContext(_ aws.Context, _ *kms.GenerateDataKeyInput, _ ...request.Option) (*kms.GenerateDataKeyOutput, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	key := testKey(3)
+	return &kms.GenerateDataKeyOutput{
+		Plaintext:      key,
+		CiphertextBlob: reverse(key),
+	}, nil
+}
+
+// DecryptWithContext returns a decrypted data key.
+func (c *mockKMSClient) DecryptWithContext(_ aws.Context, input *kms.DecryptInput, _ ...request.Option) (*kms.DecryptOutput, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &kms.DecryptOutput{
+		Plaintext: reverse(input.CiphertextBlob),
+	}, nil
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func TestKMSKeyProvider(t *testing.T) {
+	plaintext := []byte(`{"version": 2}`)
+
+	cases := []struct {
+		desc   string
+		config *KMSConfig
+		client *mockKMSClient
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			config: &KMSConfig{
+				KeyID: "alias/tfmigrate",
+			},
+			client: &mockKMSClient{},
+			ok:     true,
+		},
+		{
+			desc: "api error",
+			config: &KMSConfig{
+				KeyID: "alias/tfmigrate",
+			},
+			client: &mockKMSClient{
+				err: fmt.Errorf("access denied"),
+			},
+			ok: false,
+		},
+		{
+			desc:   "missing key_id",
+			config: &KMSConfig{},
+			client: &mockKMSClient{},
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			p, err := NewKMSKeyProvider(tc.config, tc.client)
+			if err == nil {
+				var b []byte
+				b, err = Encrypt(ctx, p, plaintext)
+				if err == nil {
+					var got []byte
+					got, err = Decrypt(ctx, p, b)
+					if err == nil && !bytes.Equal(got, plaintext) {
+						t.Errorf("got: %s, want: %s", string(got), string(plaintext))
+					}
+				}
+			}
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
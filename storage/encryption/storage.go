@@ -0,0 +1,147 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// StorageConfig is a storage.Config which encrypts data written to an
+// underlying storage.
+type StorageConfig struct {
+	// Storage is a config for the underlying storage.
+	Storage storage.Config
+	// Encryption is a config for a key provider.
+	Encryption Config
+	// AllowPlaintextMigration is a flag to accept a plain history file, so
+	// that encryption can be enabled for an existing history. It will be
+	// encrypted on the next write. Default to false, which means reading a
+	// plain history file is an error, because it may have been tampered.
+	AllowPlaintextMigration bool
+}
+
+// StorageConfig implements a storage.Config.
+var _ storage.Config = (*StorageConfig)(nil)
+
+// NewStorage returns a new instance of storage.Storage.
+func (c *StorageConfig) NewStorage() (storage.Storage, error) {
+	s, err := c.Storage.NewStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.Encryption.NewKeyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	storage := NewStorage(s, p)
+	storage.allowPlaintextMigration = c.AllowPlaintextMigration
+	return storage, nil
+}
+
+// Storage is a storage.Storage implementation which encrypts data on write
+// and decrypts it on read.
+type Storage struct {
+	// storage is an underlying storage.
+	storage storage.Storage
+	// provider is a key provider for a data key.
+	provider KeyProvider
+	// allowPlaintextMigration is a flag to accept a plain history file.
+	allowPlaintextMigration bool
+}
+
+var _ storage.VersionedStorage = (*Storage)(nil)
+
+// NewStorage returns a new instance of Storage.
+func NewStorage(s storage.Storage, p KeyProvider) *Storage {
+	return &Storage{
+		storage:  s,
+		provider: p,
+	}
+}
+
+// Write encrypts migration history data and writes it to the underlying storage.
+func (s *Storage) Write(ctx context.Context, b []byte) error {
+	encrypted, err := Encrypt(ctx, s.provider, b)
+	if err != nil {
+		return err
+	}
+
+	return s.storage.Write(ctx, encrypted)
+}
+
+// Read reads migration history data from the underlying storage and decrypts it.
+// A plain history file is rejected unless allowPlaintextMigration is set.
+func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, err := s.storage.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decrypt(ctx, b)
+}
+
+// ReadWithVersion reads migration history data with its current version from
+// the underlying storage and decrypts it.
+// It returns an error if the underlying storage doesn't support versioning.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, string, error) {
+	vs, ok := s.storage.(storage.VersionedStorage)
+	if !ok {
+		return nil, "", fmt.Errorf("versioning is not supported by the underlying storage: %T", s.storage)
+	}
+
+	b, version, err := vs.ReadWithVersion(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	decrypted, err := s.decrypt(ctx, b)
+	if err != nil {
+		return nil, "", err
+	}
+	return decrypted, version, nil
+}
+
+// WriteIfVersion encrypts migration history data and writes it to the
+// underlying storage only if the current version matches a given one.
+// It returns an error if the underlying storage doesn't support versioning.
+func (s *Storage) WriteIfVersion(ctx context.Context, b []byte, version string) (string, error) {
+	vs, ok := s.storage.(storage.VersionedStorage)
+	if !ok {
+		return "", fmt.Errorf("versioning is not supported by the underlying storage: %T", s.storage)
+	}
+
+	encrypted, err := Encrypt(ctx, s.provider, b)
+	if err != nil {
+		return "", err
+	}
+
+	return vs.WriteIfVersion(ctx, encrypted, version)
+}
+
+// decrypt decrypts data read from the underlying storage.
+// If allowPlaintextMigration is set, a plain history file is returned as it
+// is, so that encryption can be enabled for an existing history.
+func (s *Storage) decrypt(ctx context.Context, b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return b, nil
+	}
+
+	if !IsEncrypted(b) {
+		if !s.allowPlaintextMigration {
+			return nil, fmt.Errorf("history file is not encrypted. Set allow_plaintext_migration = true in the encryption block to encrypt an existing plain history file")
+		}
+		log.Printf("[WARN] [storage] history file is not encrypted. It will be encrypted on the next write\n")
+		return b, nil
+	}
+
+	return Decrypt(ctx, s.provider, b)
+}
+
+// Ping checks health of the underlying storage.
+func (s *Storage) Ping(ctx context.Context) (*storage.PingResult, error) {
+	return s.storage.Ping(ctx)
+}
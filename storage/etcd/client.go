@@ -0,0 +1,272 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/storage/httpapi"
+)
+
+// DefaultEndpoint is a default endpoint of etcd.
+const DefaultEndpoint = "http://127.0.0.1:2379"
+
+// Client is an abstraction layer for etcd KV API.
+// It is intended to be replaced with a mock for testing.
+type Client interface {
+	// Get returns a value of a given key and its mod revision.
+	// If the key does not exist, it returns nil with the revision 0.
+	Get(ctx context.Context, key string) ([]byte, int64, error)
+	// Put sets a value of a given key.
+	Put(ctx context.Context, key string, value []byte) error
+	// CAS sets a value of a given key only if its mod revision matches.
+	// The revision 0 means that the key must not exist.
+	// It returns a new mod revision and true on success, or false if the
+	// revision doesn't match.
+	CAS(ctx context.Context, key string, value []byte, revision int64) (int64, bool, error)
+	// Delete deletes a given key.
+	Delete(ctx context.Context, key string) error
+}
+
+// client is a real implementation of the Client with the JSON gateway of
+// etcd v3 API.
+type client struct {
+	// endpoint is a base URL of etcd.
+	endpoint string
+	// username is a username for authentication.
+	username string
+	// password is a password for authentication.
+	password string
+	// api sends requests to etcd with an auth token.
+	api *httpapi.Client
+	// authAPI sends requests to etcd without an auth token, which is used to
+	// issue the token.
+	authAPI *httpapi.Client
+	// token is an auth token issued by etcd.
+	token *httpapi.TokenCache
+}
+
+var _ Client = (*client)(nil)
+
+// newClient returns a new instance of Client.
+func newClient(config *Config) (Client, error) {
+	endpoint := config.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = DefaultEndpoint
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("failed to new etcd client: invalid endpoint: %s", err)
+	}
+
+	password := config.Password
+	if len(password) == 0 {
+		password = os.Getenv("ETCD_PASSWORD")
+	}
+
+	c := &client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		username: config.Username,
+		password: password,
+	}
+	c.token = &httpapi.TokenCache{Fetch: c.authenticate}
+	c.authAPI = &httpapi.Client{
+		Name:       "etcd",
+		MaxRetries: httpapi.DefaultMaxRetries,
+		RetryWait:  httpapi.DefaultRetryWait,
+	}
+	c.api = &httpapi.Client{
+		Name:       "etcd",
+		Authorize:  c.authorize,
+		ResetAuth:  c.token.Reset,
+		MaxRetries: httpapi.DefaultMaxRetries,
+		RetryWait:  httpapi.DefaultRetryWait,
+	}
+	return c, nil
+}
+
+// The etcd JSON gateway encodes bytes in base64 and int64 in strings.
+
+// keyValue is a KV entry in a response of etcd KV API.
+type keyValue struct {
+	Key         []byte `json:"key"`
+	Value       []byte `json:"value"`
+	ModRevision string `json:"mod_revision"`
+}
+
+// responseHeader is a header in a response of etcd API.
+type responseHeader struct {
+	Revision string `json:"revision"`
+}
+
+// rangeResponse is a response of range API.
+type rangeResponse struct {
+	Kvs []keyValue `json:"kvs"`
+}
+
+// Get returns a value of a given key and its mod revision.
+func (c *client) Get(ctx context.Context, key string) ([]byte, int64, error) {
+	var res rangeResponse
+	if err := c.post(ctx, c.api, "/v3/kv/range", map[string]any{"key": []byte(key)}, &res, true); err != nil {
+		return nil, 0, fmt.Errorf("failed to get etcd key %s: %s", key, err)
+	}
+	if len(res.Kvs) == 0 {
+		return nil, 0, nil
+	}
+
+	revision, err := strconv.ParseInt(res.Kvs[0].ModRevision, 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse etcd mod revision: %s", err)
+	}
+
+	value := res.Kvs[0].Value
+	if value == nil {
+		// The JSON gateway omits an empty value.
+		value = []byte{}
+	}
+	return value, revision, nil
+}
+
+// Put sets a value of a given key.
+func (c *client) Put(ctx context.Context, key string, value []byte) error {
+	req := map[string]any{"key": []byte(key), "value": value}
+	if err := c.post(ctx, c.api, "/v3/kv/put", req, nil, true); err != nil {
+		return fmt.Errorf("failed to put etcd key %s: %s", key, err)
+	}
+	return nil
+}
+
+// txnResponse is a response of txn API.
+type txnResponse struct {
+	Header    responseHeader `json:"header"`
+	Succeeded bool           `json:"succeeded"`
+}
+
+// CAS sets a value of a given key only if its mod revision matches.
+func (c *client) CAS(ctx context.Context, key string, value []byte, revision int64) (int64, bool, error) {
+	req := map[string]any{
+		"compare": []map[string]any{
+			{
+				"key":          []byte(key),
+				"result":       "EQUAL",
+				"target":       "MOD",
+				"mod_revision": strconv.FormatInt(revision, 10),
+			},
+		},
+		"success": []map[string]any{
+			{
+				"request_put": map[string]any{"key": []byte(key), "value": value},
+			},
+		},
+	}
+
+	// A transaction is not idempotent, because a retry of a successful one
+	// fails as a conflict.
+	var res txnResponse
+	if err := c.post(ctx, c.api, "/v3/kv/txn", req, &res, false); err != nil {
+		return 0, false, fmt.Errorf("failed to cas etcd key %s: %s", key, err)
+	}
+	if !res.Succeeded {
+		return 0, false, nil
+	}
+
+	// The mod revision of the key is the revision of the transaction.
+	newRevision, err := strconv.ParseInt(res.Header.Revision, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse etcd revision: %s", err)
+	}
+	return newRevision, true, nil
+}
+
+// Delete deletes a given key.
+func (c *client) Delete(ctx context.Context, key string) error {
+	if err := c.post(ctx, c.api, "/v3/kv/deleterange", map[string]any{"key": []byte(key)}, nil, true); err != nil {
+		return fmt.Errorf("failed to delete etcd key %s: %s", key, err)
+	}
+	return nil
+}
+
+// authenticate issues a new auth token.
+// The token is cached by the caller, and it never expires by time on the
+// client side, but it may expire on the server side, in which case a request
+// is rejected and the token is issued again.
+func (c *client) authenticate(ctx context.Context) (string, time.Time, error) {
+	req := map[string]any{"name": c.username, "password": c.password}
+	var res struct {
+		Token string `json:"token"`
+	}
+	if err := c.post(ctx, c.authAPI, "/v3/auth/authenticate", req, &res, true); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to authenticate etcd: %s", err)
+	}
+	return res.Token, time.Time{}, nil
+}
+
+// authorize sets an auth token to a given request if authentication is
+// enabled.
+func (c *client) authorize(ctx context.Context, r *http.Request) error {
+	if len(c.username) == 0 {
+		return nil
+	}
+	token, err := c.token.Token(ctx)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", token)
+	return nil
+}
+
+// post sends a JSON request to etcd with a given API client and decodes a
+// JSON response into res if it is not nil.
+func (c *client) post(ctx context.Context, api *httpapi.Client, path string, req any, res any, idempotent bool) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	resp, err := api.Do(ctx, &httpapi.Request{
+		Method:     http.MethodPost,
+		URL:        c.endpoint + path,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       payload,
+		Idempotent: idempotent,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp.Error("failed to call etcd " + path)
+	}
+
+	if res == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Body, res)
+}
+
+// formatRevision formats a mod revision as a version string.
+func formatRevision(revision int64) string {
+	if revision == 0 {
+		return ""
+	}
+	return strconv.FormatInt(revision, 10)
+}
+
+// parseRevision parses a version string as a mod revision.
+func parseRevision(version string) (int64, error) {
+	if len(version) == 0 {
+		return 0, nil
+	}
+	revision, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid etcd mod revision: %s", version)
+	}
+	return revision, nil
+}
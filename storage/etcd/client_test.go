@@ -0,0 +1,156 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakeEtcd is a minimal fake server of the JSON gateway of etcd v3 API.
+type fakeEtcd struct {
+	mu       sync.Mutex
+	data     map[string]keyValue
+	revision int64
+	password string
+}
+
+// fakeToken is an auth token issued by fakeEtcd.
+const fakeToken = "fake-token"
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var req struct {
+		Key      []byte `json:"key"`
+		Value    []byte `json:"value"`
+		Name     string `json:"name"`
+		Password string `json:"password"`
+		Compare  []struct {
+			Key         []byte `json:"key"`
+			ModRevision string `json:"mod_revision"`
+		} `json:"compare"`
+		Success []struct {
+			RequestPut keyValue `json:"request_put"`
+		} `json:"success"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Path == "/v3/auth/authenticate" {
+		if req.Password != f.password {
+			http.Error(w, `{"error":"authentication failed"}`, http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": fakeToken})
+		return
+	}
+	if f.password != "" && r.Header.Get("Authorization") != fakeToken {
+		http.Error(w, `{"error":"invalid auth token"}`, http.StatusUnauthorized)
+		return
+	}
+
+	put := func(key []byte, value []byte) {
+		f.revision++
+		f.data[string(key)] = keyValue{Key: key, Value: value, ModRevision: strconv.FormatInt(f.revision, 10)}
+	}
+	header := func() map[string]string {
+		return map[string]string{"revision": strconv.FormatInt(f.revision, 10)}
+	}
+
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		res := map[string]any{"header": header()}
+		if kv, ok := f.data[string(req.Key)]; ok {
+			res["kvs"] = []keyValue{kv}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+
+	case "/v3/kv/put":
+		put(req.Key, req.Value)
+		_ = json.NewEncoder(w).Encode(map[string]any{"header": header()})
+
+	case "/v3/kv/deleterange":
+		delete(f.data, string(req.Key))
+		_ = json.NewEncoder(w).Encode(map[string]any{"header": header()})
+
+	case "/v3/kv/txn":
+		cmp := req.Compare[0]
+		current := f.data[string(cmp.Key)].ModRevision
+		if current == "" {
+			current = "0"
+		}
+		if current != cmp.ModRevision {
+			// The JSON gateway omits false.
+			_ = json.NewEncoder(w).Encode(map[string]any{"header": header()})
+			return
+		}
+		put(req.Success[0].RequestPut.Key, req.Success[0].RequestPut.Value)
+		_ = json.NewEncoder(w).Encode(map[string]any{"header": header(), "succeeded": true})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestClient(t *testing.T) {
+	fake := &fakeEtcd{data: map[string]keyValue{}, password: "secret"}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	c, err := newClient(&Config{Endpoint: server.URL, Username: "tfmigrate", Password: "secret"})
+	if err != nil {
+		t.Fatalf("failed to new client: %s", err)
+	}
+	ctx := context.Background()
+
+	// key does not exist
+	got, revision, err := c.Get(ctx, "tfmigrate/history.json")
+	if err != nil || got != nil || revision != 0 {
+		t.Fatalf("unexpected get result: %s, %d, %v", string(got), revision, err)
+	}
+
+	// create with cas
+	revision, ok, err := c.CAS(ctx, "tfmigrate/history.json", []byte("foo"), 0)
+	if err != nil || !ok || revision != 1 {
+		t.Fatalf("unexpected cas result: %d, %t, %v", revision, ok, err)
+	}
+
+	// conflict
+	_, ok, err = c.CAS(ctx, "tfmigrate/history.json", []byte("bar"), 0)
+	if err != nil || ok {
+		t.Fatalf("expected to conflict, but got: %t, %v", ok, err)
+	}
+
+	// put and get
+	if err := c.Put(ctx, "tfmigrate/history.json", []byte("baz")); err != nil {
+		t.Fatalf("failed to put: %s", err)
+	}
+	got, revision, err = c.Get(ctx, "tfmigrate/history.json")
+	if err != nil || string(got) != "baz" || revision != 2 {
+		t.Fatalf("unexpected get result: %s, %d, %v", string(got), revision, err)
+	}
+
+	// delete
+	if err := c.Delete(ctx, "tfmigrate/history.json"); err != nil {
+		t.Fatalf("failed to delete: %s", err)
+	}
+	if _, ok := fake.data["tfmigrate/history.json"]; ok {
+		t.Fatal("key was not deleted")
+	}
+
+	// invalid password
+	c, err = newClient(&Config{Endpoint: server.URL, Username: "tfmigrate", Password: "invalid"})
+	if err != nil {
+		t.Fatalf("failed to new client: %s", err)
+	}
+	if _, _, err := c.Get(ctx, "tfmigrate/history.json"); err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
@@ -0,0 +1,28 @@
+package etcd
+
+import "github.com/minamijoyo/tfmigrate/storage"
+
+// Config is a config for etcd storage.
+// It uses the JSON gateway of etcd v3 API, which is available in etcd v3.4
+// or later.
+type Config struct {
+	// Endpoint of etcd, such as `http://127.0.0.1:2379`.
+	// If a scheme is omitted, `http` is assumed.
+	// Default to `http://127.0.0.1:2379`.
+	Endpoint string `hcl:"endpoint,optional"`
+	// Prefix of keys. The history is stored at `<prefix>/history.json`.
+	Prefix string `hcl:"prefix"`
+	// Username for authentication.
+	Username string `hcl:"username,optional"`
+	// Password for authentication.
+	// Default to the ETCD_PASSWORD environment variable.
+	Password string `hcl:"password,optional"`
+}
+
+// Config implements a storage.Config.
+var _ storage.Config = (*Config)(nil)
+
+// NewStorage returns a new instance of storage.Storage.
+func (c *Config) NewStorage() (storage.Storage, error) {
+	return NewStorage(c, nil)
+}
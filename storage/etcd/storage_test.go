@@ -0,0 +1,311 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/storagetest"
+)
+
+// mockEntry is a value with its mod revision in mockClient.
+type mockEntry struct {
+	value    []byte
+	revision int64
+}
+
+// mockClient is a mock implementation for testing.
+// It keeps values in memory.
+type mockClient struct {
+	data      map[string]mockEntry
+	revision  int64
+	err       error
+	deleteErr error
+}
+
+// newMockClient returns a new mockClient with given values.
+func newMockClient(values map[string]string) *mockClient {
+	c := &mockClient{data: map[string]mockEntry{}}
+	for k, v := range values {
+		c.revision++
+		c.data[k] = mockEntry{value: []byte(v), revision: c.revision}
+	}
+	return c
+}
+
+// Get returns a value in memory.
+func (c *mockClient) Get(_ context.Context, key string) ([]byte, int64, error) {
+	if c.err != nil {
+		return nil, 0, c.err
+	}
+	p, ok := c.data[key]
+	if !ok {
+		return nil, 0, nil
+	}
+	return p.value, p.revision, nil
+}
+
+// Put sets a value in memory.
+func (c *mockClient) Put(_ context.Context, key string, value []byte) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.revision++
+	c.data[key] = mockEntry{value: value, revision: c.revision}
+	return nil
+}
+
+// CAS sets a value in memory if the revision matches.
+func (c *mockClient) CAS(ctx context.Context, key string, value []byte, revision int64) (int64, bool, error) {
+	if c.err != nil {
+		return 0, false, c.err
+	}
+	if c.data[key].revision != revision {
+		return 0, false, nil
+	}
+	if err := c.Put(ctx, key, value); err != nil {
+		return 0, false, err
+	}
+	return c.revision, true, nil
+}
+
+// Delete deletes a value in memory.
+func (c *mockClient) Delete(_ context.Context, key string) error {
+	if c.deleteErr != nil {
+		return c.deleteErr
+	}
+	delete(c.data, key)
+	return nil
+}
+
+func TestStorageWrite(t *testing.T) {
+	cases := []struct {
+		desc     string
+		config   *Config
+		client   *mockClient
+		contents []byte
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Prefix: "tfmigrate",
+			},
+			client:   newMockClient(nil),
+			contents: []byte("foo"),
+			ok:       true,
+		},
+		{
+			desc: "api error",
+			config: &Config{
+				Prefix: "tfmigrate",
+			},
+			client: &mockClient{
+				err: fmt.Errorf("permission denied"),
+			},
+			contents: []byte("foo"),
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			err = s.Write(context.Background(), tc.contents)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				got := string(tc.client.data["tfmigrate/history.json"].value)
+				if got != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", got, string(tc.contents))
+				}
+			}
+		})
+	}
+}
+
+func TestStorageRead(t *testing.T) {
+	cases := []struct {
+		desc    string
+		config  *Config
+		client  *mockClient
+		want    []byte
+		version string
+		ok      bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Prefix: "tfmigrate",
+			},
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			want:    []byte("foo"),
+			version: "1",
+			ok:      true,
+		},
+		{
+			desc: "key does not exist",
+			config: &Config{
+				Prefix: "tfmigrate",
+			},
+			client:  newMockClient(nil),
+			want:    []byte{},
+			version: "",
+			ok:      true,
+		},
+		{
+			desc: "api error",
+			config: &Config{
+				Prefix: "tfmigrate",
+			},
+			client: &mockClient{
+				err: fmt.Errorf("permission denied"),
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, version, err := s.ReadWithVersion(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				if string(got) != string(tc.want) {
+					t.Errorf("got: %s, want: %s", string(got), string(tc.want))
+				}
+				if version != tc.version {
+					t.Errorf("got version: %s, want: %s", version, tc.version)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageWriteIfVersion(t *testing.T) {
+	cases := []struct {
+		desc     string
+		client   *mockClient
+		version  string
+		want     string
+		conflict bool
+	}{
+		{
+			desc:    "create",
+			client:  newMockClient(nil),
+			version: "",
+			want:    "1",
+		},
+		{
+			desc: "update",
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			version: "1",
+			want:    "2",
+		},
+		{
+			desc: "conflict",
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			version:  "",
+			conflict: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(&Config{Prefix: "tfmigrate"}, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, err := s.WriteIfVersion(context.Background(), []byte("bar"), tc.version)
+			if tc.conflict {
+				if !errors.Is(err, storage.ErrVersionConflict) {
+					t.Fatalf("expected to return ErrVersionConflict, but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStoragePing(t *testing.T) {
+	cases := []struct {
+		desc   string
+		client *mockClient
+		ok     bool
+	}{
+		{
+			desc:   "simple",
+			client: newMockClient(nil),
+			ok:     true,
+		},
+		{
+			desc: "delete error",
+			client: &mockClient{
+				data:      map[string]mockEntry{},
+				deleteErr: fmt.Errorf("permission denied"),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(&Config{Prefix: "tfmigrate"}, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.Ping(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				if _, ok := tc.client.data["tfmigrate/history.json"+storage.ProbeKeySuffix]; ok {
+					t.Error("probe key was not deleted")
+				}
+			}
+		})
+	}
+}
+
+func TestStorageContract(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		s, err := NewStorage(&Config{Prefix: "tfmigrate"}, newMockClient(nil))
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
@@ -2,10 +2,17 @@ package gcs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 
 	gcStorage "cloud.google.com/go/storage"
+	"github.com/minamijoyo/tfmigrate/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
 )
 
 // A minimal interface to mock behavior of GCS client.
@@ -15,6 +22,21 @@ type Client interface {
 
 	// Write an object onto a GCS bucket.
 	Write(ctx context.Context, p []byte) error
+
+	// Read an object from a GCS bucket with its generation.
+	ReadWithGeneration(ctx context.Context) ([]byte, int64, error)
+
+	// Write an object onto a GCS bucket only if its generation matches a
+	// given one, and return a new generation. A zero generation means that
+	// the object must not exist. It returns an error wrapping
+	// storage.ErrVersionConflict on mismatch.
+	WriteIfGeneration(ctx context.Context, p []byte, generation int64) (int64, error)
+
+	// Write a probe object next to the object for health checks.
+	WriteProbe(ctx context.Context, p []byte) error
+
+	// Delete a probe object written by WriteProbe.
+	DeleteProbe(ctx context.Context) error
 }
 
 // An implementation of Client that delegates actual operation to gcsStorage.Client.
@@ -26,35 +48,128 @@ type Adapter struct {
 }
 
 func (a Adapter) Read(ctx context.Context) ([]byte, error) {
-	r, err := a.client.Bucket(a.config.Bucket).Object(a.config.Name).NewReader(ctx)
+	body, _, err := a.ReadWithGeneration(ctx)
+	return body, err
+}
+
+func (a Adapter) Write(ctx context.Context, p []byte) error {
+	name := a.config.ObjectName()
+	w := a.newWriter(ctx, a.client.Bucket(a.config.Bucket).Object(name))
+	_, err := w.Write(p)
+
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed writing to gcs://%s/%s: %w", a.config.Bucket, name, err)
+	}
+	return w.Close()
+}
+
+func (a Adapter) ReadWithGeneration(ctx context.Context) ([]byte, int64, error) {
+	name := a.config.ObjectName()
+	r, err := a.client.Bucket(a.config.Bucket).Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, 0, err
 	}
 	defer r.Close()
 
 	body, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed reading from gcs://%s/%s: %w", a.config.Bucket, a.config.Name, err)
+		return nil, 0, fmt.Errorf("failed reading from gcs://%s/%s: %w", a.config.Bucket, name, err)
 	}
-	return body, nil
+	return body, r.Attrs.Generation, nil
 }
 
-func (a Adapter) Write(ctx context.Context, p []byte) error {
-	w := a.client.Bucket(a.config.Bucket).Object(a.config.Name).NewWriter(ctx)
-	_, err := w.Write(p)
+func (a Adapter) WriteIfGeneration(ctx context.Context, p []byte, generation int64) (int64, error) {
+	name := a.config.ObjectName()
+	cond := gcStorage.Conditions{DoesNotExist: true}
+	if generation != 0 {
+		cond = gcStorage.Conditions{GenerationMatch: generation}
+	}
+	w := a.newWriter(ctx, a.client.Bucket(a.config.Bucket).Object(name).If(cond))
 
+	// An error of the precondition may be returned by either Write or Close.
+	_, err := w.Write(p)
+	if err == nil {
+		err = w.Close()
+	}
 	if err != nil {
-		return fmt.Errorf("failed writing to gcs://%s/%s: %w", a.config.Bucket, a.config.Name, err)
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return 0, fmt.Errorf("failed writing to gcs://%s/%s with generation %d: %w", a.config.Bucket, name, generation, storage.ErrVersionConflict)
+		}
+		return 0, fmt.Errorf("failed writing to gcs://%s/%s: %w", a.config.Bucket, name, err)
+	}
+	return w.Attrs().Generation, nil
+}
+
+func (a Adapter) WriteProbe(ctx context.Context, p []byte) error {
+	name := a.config.ObjectName() + storage.ProbeKeySuffix
+	w := a.newWriter(ctx, a.client.Bucket(a.config.Bucket).Object(name))
+	if _, err := w.Write(p); err != nil {
+		return fmt.Errorf("failed writing to gcs://%s/%s: %w", a.config.Bucket, name, err)
 	}
 	return w.Close()
 }
 
+func (a Adapter) DeleteProbe(ctx context.Context) error {
+	name := a.config.ObjectName() + storage.ProbeKeySuffix
+	if err := a.client.Bucket(a.config.Bucket).Object(name).Delete(ctx); err != nil {
+		return fmt.Errorf("failed deleting gcs://%s/%s: %w", a.config.Bucket, name, err)
+	}
+	return nil
+}
+
+// newWriter returns a writer for a given object, which encrypts it with the
+// Cloud KMS key if set.
+func (a Adapter) newWriter(ctx context.Context, obj *gcStorage.ObjectHandle) *gcStorage.Writer {
+	w := obj.NewWriter(ctx)
+	w.KMSKeyName = a.config.KmsEncryptionKey
+	return w
+}
+
 // NewClient returns a new Client with given Context and Config.
 func NewClient(ctx context.Context, config Config) (Client, error) {
-	c, err := gcStorage.NewClient(ctx)
+	opts, err := clientOptions(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	c, err := gcStorage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
 	a := &Adapter{
 		config: config,
 		client: c,
 	}
-	return a, err
+	return a, nil
+}
+
+// clientOptions returns options for a GCS client to authenticate with
+// credentials and an impersonated service account in a given config.
+// If neither is set, it returns no option to use the Application Default
+// Credentials.
+func clientOptions(ctx context.Context, config Config) ([]option.ClientOption, error) {
+	opts := []option.ClientOption{}
+	if len(config.Credentials) > 0 {
+		// Like Terraform gcs backend, the credentials can be either a path to
+		// a key file or its contents.
+		if strings.HasPrefix(strings.TrimSpace(config.Credentials), "{") {
+			opts = append(opts, option.WithCredentialsJSON([]byte(config.Credentials)))
+		} else {
+			opts = append(opts, option.WithCredentialsFile(config.Credentials))
+		}
+	}
+
+	if len(config.ImpersonateServiceAccount) > 0 {
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: config.ImpersonateServiceAccount,
+			Scopes:          []string{gcStorage.ScopeReadWrite},
+			Delegates:       config.ImpersonateServiceAccountDelegates,
+		}, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to impersonate service account %s: %w", config.ImpersonateServiceAccount, err)
+		}
+		opts = []option.ClientOption{option.WithTokenSource(ts)}
+	}
+
+	return opts, nil
 }
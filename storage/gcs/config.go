@@ -1,6 +1,14 @@
 package gcs
 
-import "github.com/minamijoyo/tfmigrate/storage"
+import (
+	"fmt"
+	"path"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// DefaultName is a default path to the migration history file in a prefix.
+const DefaultName = "history.json"
 
 // Config is a config for Google Cloud Storage.
 // This is expected to have almost the same options as Terraform gcs backend.
@@ -11,7 +19,21 @@ type Config struct {
 	// The name of the GCS bucket.
 	Bucket string `hcl:"bucket"`
 	// Path to the migration history file.
-	Name string `hcl:"name"`
+	// Default to history.json if a prefix is set.
+	Name string `hcl:"name,optional"`
+	// A prefix of the path to the migration history file.
+	Prefix string `hcl:"prefix,optional"`
+
+	// A path or contents of a service account key file in JSON format.
+	// Default to the Application Default Credentials.
+	Credentials string `hcl:"credentials,optional"`
+	// The service account to impersonate for accessing the bucket.
+	ImpersonateServiceAccount string `hcl:"impersonate_service_account,optional"`
+	// The delegation chain for impersonating the service account.
+	ImpersonateServiceAccountDelegates []string `hcl:"impersonate_service_account_delegates,optional"`
+	// A Cloud KMS key to encrypt the migration history file.
+	// The format is projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{key}.
+	KmsEncryptionKey string `hcl:"kms_encryption_key,optional"`
 }
 
 // Config implements a storage.Config.
@@ -19,5 +41,20 @@ var _ storage.Config = (*Config)(nil)
 
 // NewStorage returns a new instance of storage.Storage.
 func (c *Config) NewStorage() (storage.Storage, error) {
+	if len(c.Name) == 0 && len(c.Prefix) == 0 {
+		return nil, fmt.Errorf("failed to new gcs storage: either name or prefix is required")
+	}
+	if len(c.ImpersonateServiceAccountDelegates) > 0 && len(c.ImpersonateServiceAccount) == 0 {
+		return nil, fmt.Errorf("failed to new gcs storage: impersonate_service_account_delegates requires impersonate_service_account")
+	}
 	return NewStorage(c, nil)
 }
+
+// ObjectName returns a path to the migration history file in the bucket.
+func (c *Config) ObjectName() string {
+	name := c.Name
+	if len(name) == 0 {
+		name = DefaultName
+	}
+	return path.Join(c.Prefix, name)
+}
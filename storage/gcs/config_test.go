@@ -1,6 +1,9 @@
 package gcs
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestConfigNewStorage(t *testing.T) {
 	cases := []struct {
@@ -16,6 +19,30 @@ func TestConfigNewStorage(t *testing.T) {
 			},
 			ok: true,
 		},
+		{
+			desc: "prefix",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Prefix: "tfmigrate",
+			},
+			ok: true,
+		},
+		{
+			desc: "missing name and prefix",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+			},
+			ok: false,
+		},
+		{
+			desc: "delegates without impersonate_service_account",
+			config: &Config{
+				Bucket:                             "tfmigrate-test",
+				Name:                               "tfmigrate/history.json",
+				ImpersonateServiceAccountDelegates: []string{"delegate@example.iam.gserviceaccount.com"},
+			},
+			ok: false,
+		},
 	}
 
 	for _, tc := range cases {
@@ -33,3 +60,72 @@ func TestConfigNewStorage(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigObjectName(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		want   string
+	}{
+		{
+			desc:   "name",
+			config: &Config{Name: "tfmigrate/history.json"},
+			want:   "tfmigrate/history.json",
+		},
+		{
+			desc:   "prefix",
+			config: &Config{Prefix: "tfmigrate"},
+			want:   "tfmigrate/history.json",
+		},
+		{
+			desc:   "prefix and name",
+			config: &Config{Prefix: "tfmigrate/", Name: "foo.json"},
+			want:   "tfmigrate/foo.json",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.config.ObjectName()
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config Config
+		want   int
+	}{
+		{
+			desc:   "default",
+			config: Config{Bucket: "tfmigrate-test", Name: "history.json"},
+			want:   0,
+		},
+		{
+			desc:   "credentials file",
+			config: Config{Bucket: "tfmigrate-test", Name: "history.json", Credentials: "/path/to/credentials.json"},
+			want:   1,
+		},
+		{
+			desc:   "credentials json",
+			config: Config{Bucket: "tfmigrate-test", Name: "history.json", Credentials: `{"type": "service_account"}`},
+			want:   1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := clientOptions(context.Background(), tc.config)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if len(got) != tc.want {
+				t.Errorf("got %d options, want: %d", len(got), tc.want)
+			}
+		})
+	}
+}
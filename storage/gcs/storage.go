@@ -2,6 +2,8 @@ package gcs
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 
 	gcStorage "cloud.google.com/go/storage"
 	"github.com/minamijoyo/tfmigrate/storage"
@@ -18,6 +20,7 @@ type Storage struct {
 }
 
 var _ storage.Storage = (*Storage)(nil)
+var _ storage.VersionedStorage = (*Storage)(nil)
 
 // NewStorage returns a new instance of Storage.
 func NewStorage(config *Config, client Client) (*Storage, error) {
@@ -52,16 +55,74 @@ func (s *Storage) Read(ctx context.Context) ([]byte, error) {
 	return r, nil
 }
 
+// ReadWithVersion reads migration history data with its generation as a
+// version. If the object does not exist, it returns an empty array and
+// version.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, string, error) {
+	err := s.init(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r, generation, err := s.client.ReadWithGeneration(ctx)
+	if err == gcStorage.ErrObjectNotExist {
+		return []byte{}, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	return r, strconv.FormatInt(generation, 10), nil
+}
+
+// WriteIfVersion writes migration history data only if its generation
+// matches a given version with a precondition of GCS. An empty version means
+// that the object must not exist.
+func (s *Storage) WriteIfVersion(ctx context.Context, b []byte, version string) (string, error) {
+	err := s.init(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var generation int64
+	if len(version) > 0 {
+		generation, err = strconv.ParseInt(version, 10, 64)
+		if err != nil || generation <= 0 {
+			return "", fmt.Errorf("invalid generation of gcs object: %q", version)
+		}
+	}
+
+	generation, err = s.client.WriteIfGeneration(ctx, b, generation)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(generation, 10), nil
+}
+
+// Ping checks permissions to read the history object, and to write and
+// delete a probe object next to it.
+func (s *Storage) Ping(ctx context.Context) (*storage.PingResult, error) {
+	err := s.init(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.Probe(ctx,
+		func(ctx context.Context) error {
+			_, err := s.Read(ctx)
+			return err
+		},
+		s.client.WriteProbe,
+		s.client.DeleteProbe,
+	)
+}
+
 func (s *Storage) init(ctx context.Context) error {
 	if s.client == nil {
-		client, err := gcStorage.NewClient(ctx)
+		client, err := NewClient(ctx, *s.config)
 		if err != nil {
-			return err
-		}
-		s.client = Adapter{
-			config: *s.config,
-			client: client,
+			return fmt.Errorf("failed to new gcs client. The gcs storage requires credentials or the Application Default Credentials, "+
+				"such as GOOGLE_APPLICATION_CREDENTIALS, workload identity or an attached service account: %s", err)
 		}
+		s.client = client
 	}
 	return nil
 }
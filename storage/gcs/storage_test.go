@@ -2,15 +2,23 @@ package gcs
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
 	"testing"
+	"time"
 
 	gcStorage "cloud.google.com/go/storage"
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/storagetest"
+	"github.com/minamijoyo/tfmigrate/tfexec"
 )
 
 // mockClient is a mock implementation for testing.
 type mockClient struct {
 	dataToRead []byte
 	err        error
+	deleteErr  error
 }
 
 func (c *mockClient) Read(_ context.Context) ([]byte, error) {
@@ -21,6 +29,22 @@ func (c *mockClient) Write(_ context.Context, _ []byte) error {
 	return c.err
 }
 
+func (c *mockClient) ReadWithGeneration(_ context.Context) ([]byte, int64, error) {
+	return c.dataToRead, 1, c.err
+}
+
+func (c *mockClient) WriteIfGeneration(_ context.Context, _ []byte, _ int64) (int64, error) {
+	return 2, c.err
+}
+
+func (c *mockClient) WriteProbe(_ context.Context, _ []byte) error {
+	return c.err
+}
+
+func (c *mockClient) DeleteProbe(_ context.Context) error {
+	return c.deleteErr
+}
+
 func TestStorageWrite(t *testing.T) {
 	cases := []struct {
 		desc     string
@@ -142,3 +166,165 @@ func TestStorageRead(t *testing.T) {
 		})
 	}
 }
+
+func TestStoragePing(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		client Client
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Name:   "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				dataToRead: []byte("foo"),
+			},
+			ok: true,
+		},
+		{
+			desc: "object does not exist",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Name:   "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				err: gcStorage.ErrObjectNotExist,
+			},
+			ok: false,
+		},
+		{
+			desc: "delete denied",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Name:   "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				dataToRead: []byte("foo"),
+				deleteErr:  errors.New("googleapi: Error 403: Forbidden"),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.Ping(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+// fakeClient is an in-memory implementation of Client which supports
+// preconditions on generations.
+type fakeClient struct {
+	data       []byte
+	generation int64
+	probe      []byte
+}
+
+func (c *fakeClient) Read(ctx context.Context) ([]byte, error) {
+	b, _, err := c.ReadWithGeneration(ctx)
+	return b, err
+}
+
+func (c *fakeClient) Write(_ context.Context, p []byte) error {
+	c.data = p
+	c.generation++
+	return nil
+}
+
+func (c *fakeClient) ReadWithGeneration(_ context.Context) ([]byte, int64, error) {
+	if c.data == nil {
+		return nil, 0, gcStorage.ErrObjectNotExist
+	}
+	return c.data, c.generation, nil
+}
+
+func (c *fakeClient) WriteIfGeneration(_ context.Context, p []byte, generation int64) (int64, error) {
+	current := int64(0)
+	if c.data != nil {
+		current = c.generation
+	}
+	if generation != current {
+		return 0, fmt.Errorf("generation mismatch: %w", storage.ErrVersionConflict)
+	}
+	c.data = p
+	c.generation++
+	return c.generation, nil
+}
+
+func (c *fakeClient) WriteProbe(_ context.Context, p []byte) error {
+	c.probe = p
+	return nil
+}
+
+func (c *fakeClient) DeleteProbe(_ context.Context) error {
+	c.probe = nil
+	return nil
+}
+
+func TestStorageContract(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		config := &Config{
+			Bucket: "tfmigrate-test",
+			Name:   "tfmigrate/history.json",
+		}
+		s, err := NewStorage(config, &fakeClient{})
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
+
+func TestStorageWriteIfVersionInvalid(t *testing.T) {
+	config := &Config{
+		Bucket: "tfmigrate-test",
+		Name:   "tfmigrate/history.json",
+	}
+	s, err := NewStorage(config, &fakeClient{})
+	if err != nil {
+		t.Fatalf("failed to NewStorage: %s", err)
+	}
+	if _, err := s.WriteIfVersion(context.Background(), []byte("foo"), "foo"); err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestAccStorageContract(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+	// Never run it against the real GCS.
+	if len(os.Getenv("STORAGE_EMULATOR_HOST")) == 0 {
+		t.Skip("skip acceptance tests for gcs storage because STORAGE_EMULATOR_HOST is not set")
+	}
+
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		config := &Config{
+			Bucket: testAccGCSBucket,
+			// The emulator is disposable, so we don't delete history files.
+			Name: fmt.Sprintf("%s/%d/history.json", t.Name(), time.Now().UnixNano()),
+		}
+		s, err := NewStorage(config, nil)
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
+
+// testAccGCSBucket is a bucket created on startup of fake-gcs-server.
+// See test-fixtures/fake-gcs-server.
+const testAccGCSBucket = "tfstate-test"
@@ -0,0 +1,302 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultBranch is a default branch to which the history is committed.
+	DefaultBranch = "main"
+	// DefaultCommitterName is a default name of the committer.
+	DefaultCommitterName = "tfmigrate"
+	// DefaultCommitterEmail is a default email address of the committer.
+	DefaultCommitterEmail = "tfmigrate@localhost"
+	// DefaultMaxRetries is a default maximum number of retries for a push.
+	DefaultMaxRetries = 5
+)
+
+// Client is an abstraction layer for operations on a remote git repository.
+// It is intended to be replaced with a mock for testing.
+type Client interface {
+	// Fetch fetches the branch from the remote and returns its head commit.
+	// If the branch does not exist, it returns an empty string.
+	Fetch(ctx context.Context) (string, error)
+	// ReadFile returns contents of a given file at a given commit and its
+	// blob hash. If the commit is empty or the file does not exist, it
+	// returns nil with an empty hash.
+	ReadFile(ctx context.Context, commit string, path string) ([]byte, string, error)
+	// Commit creates a new commit on top of a given parent which sets
+	// contents of a given file, and returns its hash. An empty parent means a
+	// root commit. If the contents are not changed, it returns an empty
+	// string. The commit is not pushed yet.
+	Commit(ctx context.Context, parent string, path string, contents []byte, message string) (string, error)
+	// Push updates the branch to a given commit. It returns false if the push
+	// is rejected because the branch has been updated by someone else. If
+	// dryRun is true, it checks the permission without updating the branch.
+	Push(ctx context.Context, commit string, dryRun bool) (bool, error)
+	// Close cleans up local resources such as a temporary repository.
+	Close() error
+}
+
+// client is a real implementation of the Client with the git command.
+// It fetches into a temporary bare repository and creates commits with
+// plumbing commands, so that no working tree is needed.
+type client struct {
+	// url is a URL of the remote repository.
+	url string
+	// branch is a name of the branch.
+	branch string
+	// env is a list of environment variables passed to the git command.
+	env []string
+	// signingKey is a key ID to sign commits.
+	// It is only used when sign is true.
+	signingKey string
+	// sign is a boolean indicating whether to sign commits.
+	sign bool
+	// dir is a path to the temporary bare repository.
+	// It is created lazily and removed by Close.
+	dir string
+}
+
+var _ Client = (*client)(nil)
+
+// newClient returns a new instance of Client.
+func newClient(config *Config) (Client, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("failed to new git client: git command not found: %s", err)
+	}
+
+	branch := config.Branch
+	if len(branch) == 0 {
+		branch = DefaultBranch
+	}
+	name := config.CommitterName
+	if len(name) == 0 {
+		name = DefaultCommitterName
+	}
+	email := config.CommitterEmail
+	if len(email) == 0 {
+		email = DefaultCommitterEmail
+	}
+
+	c := &client{
+		url:    config.URL,
+		branch: branch,
+		env: []string{
+			"GIT_AUTHOR_NAME=" + name,
+			"GIT_AUTHOR_EMAIL=" + email,
+			"GIT_COMMITTER_NAME=" + name,
+			"GIT_COMMITTER_EMAIL=" + email,
+			// Never prompt for credentials, because no one can answer it.
+			"GIT_TERMINAL_PROMPT=0",
+		},
+		signingKey: config.SigningKey,
+		sign:       config.Sign || len(config.SigningKey) > 0,
+	}
+	return c, nil
+}
+
+// init creates a temporary bare repository if not yet.
+func (c *client) init(ctx context.Context) error {
+	if len(c.dir) != 0 {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "tfmigrate-git-")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary directory: %s", err)
+	}
+	c.dir = dir
+
+	if _, err := c.git(ctx, nil, "init", "--quiet", "--bare"); err != nil {
+		return err
+	}
+	if _, err := c.git(ctx, nil, "remote", "add", "origin", c.url); err != nil {
+		return err
+	}
+	return nil
+}
+
+// remoteRef returns a name of the remote tracking ref of the branch.
+func (c *client) remoteRef() string {
+	return "refs/remotes/origin/" + c.branch
+}
+
+// Fetch fetches the branch from the remote and returns its head commit.
+func (c *client) Fetch(ctx context.Context) (string, error) {
+	if err := c.init(ctx); err != nil {
+		return "", err
+	}
+
+	// A fetch of a missing ref fails, so we check it first.
+	out, err := c.git(ctx, nil, "ls-remote", "--heads", "origin", "refs/heads/"+c.branch)
+	if err != nil {
+		return "", err
+	}
+	if len(out) == 0 {
+		return "", nil
+	}
+
+	// The history file is all we need, so a shallow fetch is enough.
+	refspec := "+refs/heads/" + c.branch + ":" + c.remoteRef()
+	if _, err := c.git(ctx, nil, "fetch", "--quiet", "--depth=1", "origin", refspec); err != nil {
+		return "", err
+	}
+	return c.git(ctx, nil, "rev-parse", "--verify", c.remoteRef())
+}
+
+// ReadFile returns contents of a given file at a given commit and its blob
+// hash.
+func (c *client) ReadFile(ctx context.Context, commit string, path string) ([]byte, string, error) {
+	if len(commit) == 0 {
+		return nil, "", nil
+	}
+	if err := c.init(ctx); err != nil {
+		return nil, "", err
+	}
+
+	// The ls-tree outputs nothing for a missing file, while rev-parse fails
+	// in the same way as other errors.
+	// e.g.) 100644 blob 257cc5642cb1a054f08cc83f2d943e56fd3ebe99\thistory.json
+	out, err := c.git(ctx, nil, "ls-tree", commit, "--", path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(out) == 0 {
+		return nil, "", nil
+	}
+	fields := strings.Fields(out)
+	if len(fields) < 3 || fields[1] != "blob" {
+		return nil, "", fmt.Errorf("%s is not a file in the repository: %s", path, out)
+	}
+	blob := fields[2]
+
+	contents, err := c.run(ctx, nil, nil, "cat-file", "blob", blob)
+	if err != nil {
+		return nil, "", err
+	}
+	return contents, blob, nil
+}
+
+// Commit creates a new commit on top of a given parent which sets contents of
+// a given file.
+func (c *client) Commit(ctx context.Context, parent string, path string, contents []byte, message string) (string, error) {
+	if err := c.init(ctx); err != nil {
+		return "", err
+	}
+
+	blob, err := c.git(ctx, contents, "hash-object", "-w", "--stdin")
+	if err != nil {
+		return "", err
+	}
+
+	// Build a tree with a temporary index not to depend on a working tree.
+	index := filepath.Join(c.dir, "tfmigrate-index")
+	if err := os.Remove(index); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to remove a temporary index: %s", err)
+	}
+	env := []string{"GIT_INDEX_FILE=" + index}
+	if len(parent) != 0 {
+		if _, err := c.gitWithEnv(ctx, env, nil, "read-tree", parent); err != nil {
+			return "", err
+		}
+	}
+	if _, err := c.gitWithEnv(ctx, env, nil, "update-index", "--add", "--cacheinfo", "100644,"+blob+","+path); err != nil {
+		return "", err
+	}
+	tree, err := c.gitWithEnv(ctx, env, nil, "write-tree")
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"commit-tree", tree, "-m", message}
+	if len(parent) != 0 {
+		parentTree, err := c.git(ctx, nil, "rev-parse", parent+"^{tree}")
+		if err != nil {
+			return "", err
+		}
+		if parentTree == tree {
+			return "", nil
+		}
+		args = append(args, "-p", parent)
+	}
+	if c.sign {
+		args = append(args, "-S"+c.signingKey)
+	} else {
+		// Ignore commit.gpgSign of the user's git config.
+		args = append(args, "--no-gpg-sign")
+	}
+	return c.git(ctx, nil, args...)
+}
+
+// Push updates the branch to a given commit.
+func (c *client) Push(ctx context.Context, commit string, dryRun bool) (bool, error) {
+	if err := c.init(ctx); err != nil {
+		return false, err
+	}
+
+	args := []string{"push", "--porcelain"}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	args = append(args, "origin", commit+":refs/heads/"+c.branch)
+	_, err := c.git(ctx, nil, args...)
+	if err != nil {
+		// A push which is not a fast-forward is rejected by the git command
+		// itself, while a rejection by the remote such as a protected branch
+		// is reported as `[remote rejected]` and cannot be retried.
+		if strings.Contains(err.Error(), "(fetch first)") || strings.Contains(err.Error(), "(non-fast-forward)") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Close removes the temporary bare repository.
+func (c *client) Close() error {
+	if len(c.dir) == 0 {
+		return nil
+	}
+	dir := c.dir
+	c.dir = ""
+	return os.RemoveAll(dir)
+}
+
+// git runs a git command in the temporary bare repository and returns its
+// trimmed stdout.
+func (c *client) git(ctx context.Context, stdin []byte, args ...string) (string, error) {
+	return c.gitWithEnv(ctx, nil, stdin, args...)
+}
+
+// gitWithEnv is the same as git, but appends given environment variables.
+func (c *client) gitWithEnv(ctx context.Context, env []string, stdin []byte, args ...string) (string, error) {
+	out, err := c.run(ctx, env, stdin, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// run runs a git command in the temporary bare repository and returns its
+// raw stdout.
+func (c *client) run(ctx context.Context, env []string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--git-dir", c.dir}, args...)...)
+	cmd.Env = append(append(os.Environ(), c.env...), env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run git %s: %s: %s%s", args[0], err, stdout.String(), stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,137 @@
+package git
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/storagetest"
+)
+
+// setupRemote creates a bare repository as a remote for testing.
+func setupRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command not found")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", dir).CombinedOutput(); err != nil {
+		t.Fatalf("failed to init a remote repository: %s: %s", err, out)
+	}
+	return dir
+}
+
+func TestClient(t *testing.T) {
+	remote := setupRemote(t)
+	c, err := newClient(&Config{URL: remote, Branch: "history"})
+	if err != nil {
+		t.Fatalf("failed to new client: %s", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+
+	// branch does not exist
+	head, err := c.Fetch(ctx)
+	if err != nil || head != "" {
+		t.Fatalf("unexpected fetch result: %s, %v", head, err)
+	}
+
+	// create a branch
+	commit1, err := c.Commit(ctx, "", "tfmigrate/history.json", []byte("foo\n"), commitMessage)
+	if err != nil || commit1 == "" {
+		t.Fatalf("unexpected commit result: %s, %v", commit1, err)
+	}
+	if ok, err := c.Push(ctx, commit1, false); err != nil || !ok {
+		t.Fatalf("unexpected push result: %t, %v", ok, err)
+	}
+
+	// fetch and read
+	head, err = c.Fetch(ctx)
+	if err != nil || head != commit1 {
+		t.Fatalf("unexpected fetch result: %s, %v", head, err)
+	}
+	got, blob, err := c.ReadFile(ctx, head, "tfmigrate/history.json")
+	if err != nil || string(got) != "foo\n" || blob == "" {
+		t.Fatalf("unexpected read result: %q, %s, %v", got, blob, err)
+	}
+	got, blob, err = c.ReadFile(ctx, head, "tfmigrate/missing.json")
+	if err != nil || got != nil || blob != "" {
+		t.Fatalf("unexpected read result for a missing file: %q, %s, %v", got, blob, err)
+	}
+
+	// not changed
+	commit, err := c.Commit(ctx, head, "tfmigrate/history.json", []byte("foo\n"), commitMessage)
+	if err != nil || commit != "" {
+		t.Fatalf("unexpected commit result for no changes: %s, %v", commit, err)
+	}
+
+	// someone else updates the branch
+	other, err := newClient(&Config{URL: remote, Branch: "history"})
+	if err != nil {
+		t.Fatalf("failed to new client: %s", err)
+	}
+	t.Cleanup(func() { _ = other.Close() })
+	otherHead, err := other.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("failed to fetch: %s", err)
+	}
+	commit2, err := other.Commit(ctx, otherHead, "main.tf", []byte("# foo\n"), "Update main.tf")
+	if err != nil {
+		t.Fatalf("failed to commit: %s", err)
+	}
+	if ok, err := other.Push(ctx, commit2, false); err != nil || !ok {
+		t.Fatalf("unexpected push result: %t, %v", ok, err)
+	}
+
+	// rejected
+	commit3, err := c.Commit(ctx, head, "tfmigrate/history.json", []byte("bar\n"), commitMessage)
+	if err != nil {
+		t.Fatalf("failed to commit: %s", err)
+	}
+	if ok, err := c.Push(ctx, commit3, true); err != nil || ok {
+		t.Fatalf("expected a dry-run push to be rejected, but got: %t, %v", ok, err)
+	}
+	if ok, err := c.Push(ctx, commit3, false); err != nil || ok {
+		t.Fatalf("expected a push to be rejected, but got: %t, %v", ok, err)
+	}
+
+	// a file of the other commit is kept
+	head, err = c.Fetch(ctx)
+	if err != nil || head != commit2 {
+		t.Fatalf("unexpected fetch result: %s, %v", head, err)
+	}
+	commit4, err := c.Commit(ctx, head, "tfmigrate/history.json", []byte("bar\n"), commitMessage)
+	if err != nil {
+		t.Fatalf("failed to commit: %s", err)
+	}
+	if ok, err := c.Push(ctx, commit4, false); err != nil || !ok {
+		t.Fatalf("unexpected push result: %t, %v", ok, err)
+	}
+	got, _, err = c.ReadFile(ctx, commit4, "main.tf")
+	if err != nil || string(got) != "# foo\n" {
+		t.Fatalf("unexpected read result: %q, %v", got, err)
+	}
+}
+
+func TestClientRemoteNotFound(t *testing.T) {
+	remote := setupRemote(t)
+	c, err := newClient(&Config{URL: remote + "/missing"})
+	if err != nil {
+		t.Fatalf("failed to new client: %s", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	if _, err := c.Fetch(context.Background()); err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestStorageContractWithGit(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		s, err := NewStorage(&Config{URL: setupRemote(t), Path: "tfmigrate/history.json"}, nil)
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
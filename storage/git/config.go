@@ -0,0 +1,39 @@
+package git
+
+import "github.com/minamijoyo/tfmigrate/storage"
+
+// Config is a config for git storage.
+// The history is committed into a git repository, so that it lives alongside
+// code and changes of it can be reviewed in pull requests.
+type Config struct {
+	// URL of the remote repository, such as
+	// `git@github.com:foo/bar.git` or `https://github.com/foo/bar.git`.
+	// Any URL which the git command understands is allowed, and credentials
+	// are resolved by the git command as usual.
+	URL string `hcl:"url"`
+	// Branch to which the history is committed. Default to `main`.
+	// It is created if it does not exist.
+	Branch string `hcl:"branch,optional"`
+	// Path to the history file in the repository.
+	Path string `hcl:"path"`
+	// Name of the committer. Default to `tfmigrate`.
+	CommitterName string `hcl:"committer_name,optional"`
+	// Email address of the committer. Default to `tfmigrate@localhost`.
+	CommitterEmail string `hcl:"committer_email,optional"`
+	// Sign commits with GPG. Default to false.
+	Sign bool `hcl:"sign,optional"`
+	// Key ID to sign commits. Default to the user.signingkey of git config.
+	// Setting it implies sign = true.
+	SigningKey string `hcl:"signing_key,optional"`
+	// Maximum number of retries for a push which is rejected because the
+	// branch has been updated by someone else. Default to 5.
+	MaxRetries int `hcl:"max_retries,optional"`
+}
+
+// Config implements a storage.Config.
+var _ storage.Config = (*Config)(nil)
+
+// NewStorage returns a new instance of storage.Storage.
+func (c *Config) NewStorage() (storage.Storage, error) {
+	return NewStorage(c, nil)
+}
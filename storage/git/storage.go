@@ -0,0 +1,179 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// commitMessage is a message of commits which update the history file.
+const commitMessage = "Update tfmigrate history"
+
+// probeCommitMessage is a message of a commit to check the push permission.
+// It is never pushed actually.
+const probeCommitMessage = "Check tfmigrate storage permissions"
+
+// Storage is a storage.Storage implementation for a git repository.
+// A version of the history file is its blob hash, so that commits which
+// don't change the history file, such as code changes in the same branch,
+// are not treated as a conflict.
+type Storage struct {
+	// config is a storage config for git.
+	config *Config
+	// client is an instance of Client interface to operate git.
+	// It is intended to be replaced with a mock for testing.
+	client Client
+}
+
+var _ storage.Storage = (*Storage)(nil)
+var _ storage.VersionedStorage = (*Storage)(nil)
+
+// NewStorage returns a new instance of Storage.
+func NewStorage(config *Config, client Client) (*Storage, error) {
+	if client == nil {
+		var err error
+		client, err = newClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Storage{
+		config: config,
+		client: client,
+	}
+	return s, nil
+}
+
+// maxRetries returns a maximum number of retries for a rejected push.
+func (s *Storage) maxRetries() int {
+	if s.config.MaxRetries == 0 {
+		return DefaultMaxRetries
+	}
+	return s.config.MaxRetries
+}
+
+// Write writes migration history data to storage.
+func (s *Storage) Write(ctx context.Context, b []byte) error {
+	_, err := s.write(ctx, b, nil)
+	return err
+}
+
+// Read reads migration history data from storage.
+// If the file does not exist, it is assumed to be uninitialized and returns
+// an empty array instead of an error.
+func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, _, err := s.ReadWithVersion(ctx)
+	return b, err
+}
+
+// ReadWithVersion reads migration history data with its blob hash.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, string, error) {
+	defer s.client.Close()
+
+	head, err := s.client.Fetch(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	b, blob, err := s.client.ReadFile(ctx, head, s.config.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, blob, nil
+}
+
+// WriteIfVersion writes migration history data only if its blob hash matches
+// a given version.
+func (s *Storage) WriteIfVersion(ctx context.Context, b []byte, version string) (string, error) {
+	return s.write(ctx, b, &version)
+}
+
+// write commits migration history data and pushes it. If a version is given,
+// the history file must not be changed from it.
+// A push is rejected if the branch has been updated by someone else since we
+// fetched it. In that case, we fetch it again and retry on top of the new
+// head, because the update may not touch the history file. A change of the
+// history file itself is still detected by the version check.
+func (s *Storage) write(ctx context.Context, b []byte, version *string) (string, error) {
+	defer s.client.Close()
+
+	for i := 0; ; i++ {
+		head, err := s.client.Fetch(ctx)
+		if err != nil {
+			return "", err
+		}
+		_, current, err := s.client.ReadFile(ctx, head, s.config.Path)
+		if err != nil {
+			return "", err
+		}
+		if version != nil && current != *version {
+			return "", fmt.Errorf("failed to write %s at version %q: %w", s.config.Path, *version, storage.ErrVersionConflict)
+		}
+
+		commit, err := s.client.Commit(ctx, head, s.config.Path, b, commitMessage)
+		if err != nil {
+			return "", err
+		}
+		if len(commit) == 0 {
+			// nothing changed.
+			return current, nil
+		}
+
+		ok, err := s.client.Push(ctx, commit, false)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			_, newVersion, err := s.client.ReadFile(ctx, commit, s.config.Path)
+			return newVersion, err
+		}
+
+		if i >= s.maxRetries() {
+			return "", fmt.Errorf("failed to push %s: rejected %d times because the branch has been updated", s.config.Path, i+1)
+		}
+		log.Printf("[WARN] [storage@git] push rejected because the branch has been updated, retrying (%d/%d)\n", i+1, s.maxRetries())
+	}
+}
+
+// Ping checks permissions to read the history file, and to push a commit to
+// the branch. A probe commit is pushed with dry-run, so that the branch is
+// never updated, and nothing needs to be deleted.
+func (s *Storage) Ping(ctx context.Context) (*storage.PingResult, error) {
+	defer s.client.Close()
+
+	var head string
+	return storage.Probe(ctx,
+		func(ctx context.Context) error {
+			var err error
+			head, err = s.client.Fetch(ctx)
+			if err != nil {
+				return err
+			}
+			_, _, err = s.client.ReadFile(ctx, head, s.config.Path)
+			return err
+		},
+		func(ctx context.Context, b []byte) error {
+			commit, err := s.client.Commit(ctx, head, s.config.Path+storage.ProbeKeySuffix, b, probeCommitMessage)
+			if err != nil {
+				return err
+			}
+			ok, err := s.client.Push(ctx, commit, true)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("push rejected because the branch has been updated")
+			}
+			return nil
+		},
+		func(_ context.Context) error {
+			// The probe commit only exists in the temporary repository.
+			return nil
+		},
+	)
+}
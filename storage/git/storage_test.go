@@ -0,0 +1,376 @@
+package git
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/storagetest"
+)
+
+// mockClient is a mock implementation for testing.
+// It keeps commits in memory. A commit is a snapshot of files.
+type mockClient struct {
+	commits map[string]map[string][]byte
+	head    string
+	// rejects is the number of pushes to be rejected. On each rejection, the
+	// branch is updated with an unrelated commit as if someone else pushed.
+	rejects int
+	pushes  int
+	dryRuns int
+	closed  int
+	err     error
+}
+
+// newMockClient returns a new mockClient with given files at the head.
+// If files is nil, the branch doesn't exist.
+func newMockClient(files map[string]string) *mockClient {
+	c := &mockClient{commits: map[string]map[string][]byte{}}
+	if files != nil {
+		snapshot := map[string][]byte{}
+		for k, v := range files {
+			snapshot[k] = []byte(v)
+		}
+		c.head = c.newCommit(snapshot)
+	}
+	return c
+}
+
+// newCommit stores a given snapshot and returns its hash.
+func (c *mockClient) newCommit(snapshot map[string][]byte) string {
+	commit := fmt.Sprintf("commit%d", len(c.commits)+1)
+	c.commits[commit] = snapshot
+	return commit
+}
+
+// blobHash returns a hash of given contents.
+func blobHash(b []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(b))
+}
+
+// Fetch returns the head in memory.
+func (c *mockClient) Fetch(_ context.Context) (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	return c.head, nil
+}
+
+// ReadFile returns a file in memory.
+func (c *mockClient) ReadFile(_ context.Context, commit string, path string) ([]byte, string, error) {
+	b, ok := c.commits[commit][path]
+	if !ok {
+		return nil, "", nil
+	}
+	return b, blobHash(b), nil
+}
+
+// Commit stores a new snapshot in memory.
+func (c *mockClient) Commit(_ context.Context, parent string, path string, contents []byte, _ string) (string, error) {
+	if b, ok := c.commits[parent][path]; ok && string(b) == string(contents) {
+		return "", nil
+	}
+	snapshot := map[string][]byte{}
+	for k, v := range c.commits[parent] {
+		snapshot[k] = v
+	}
+	snapshot[path] = contents
+	return c.newCommit(snapshot), nil
+}
+
+// Push updates the head in memory.
+func (c *mockClient) Push(_ context.Context, commit string, dryRun bool) (bool, error) {
+	if dryRun {
+		c.dryRuns++
+		return true, nil
+	}
+	if c.rejects > 0 {
+		c.rejects--
+		snapshot := map[string][]byte{}
+		for k, v := range c.commits[c.head] {
+			snapshot[k] = v
+		}
+		snapshot["main.tf"] = []byte(fmt.Sprintf("# %d", c.rejects))
+		c.head = c.newCommit(snapshot)
+		return false, nil
+	}
+	c.pushes++
+	c.head = commit
+	return true, nil
+}
+
+// Close counts calls.
+func (c *mockClient) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestStorageWrite(t *testing.T) {
+	cases := []struct {
+		desc     string
+		config   *Config
+		client   *mockClient
+		contents []byte
+		pushes   int
+		ok       bool
+	}{
+		{
+			desc:     "create a branch",
+			config:   &Config{Path: "tfmigrate/history.json"},
+			client:   newMockClient(nil),
+			contents: []byte("foo"),
+			pushes:   1,
+			ok:       true,
+		},
+		{
+			desc: "update",
+			config: &Config{
+				Path: "tfmigrate/history.json",
+			},
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			contents: []byte("bar"),
+			pushes:   1,
+			ok:       true,
+		},
+		{
+			desc: "not changed",
+			config: &Config{
+				Path: "tfmigrate/history.json",
+			},
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			contents: []byte("foo"),
+			pushes:   0,
+			ok:       true,
+		},
+		{
+			desc: "retry rejected pushes",
+			config: &Config{
+				Path: "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				commits: map[string]map[string][]byte{},
+				rejects: 2,
+			},
+			contents: []byte("foo"),
+			pushes:   1,
+			ok:       true,
+		},
+		{
+			desc: "too many rejected pushes",
+			config: &Config{
+				Path:       "tfmigrate/history.json",
+				MaxRetries: 1,
+			},
+			client: &mockClient{
+				commits: map[string]map[string][]byte{},
+				rejects: 2,
+			},
+			contents: []byte("foo"),
+			ok:       false,
+		},
+		{
+			desc: "git error",
+			config: &Config{
+				Path: "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				err: fmt.Errorf("permission denied"),
+			},
+			contents: []byte("foo"),
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			err = s.Write(context.Background(), tc.contents)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.client.closed == 0 {
+				t.Error("expected to close the client")
+			}
+			if tc.ok {
+				got := string(tc.client.commits[tc.client.head][tc.config.Path])
+				if got != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", got, string(tc.contents))
+				}
+				if tc.client.pushes != tc.pushes {
+					t.Errorf("got pushes: %d, want: %d", tc.client.pushes, tc.pushes)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageRead(t *testing.T) {
+	cases := []struct {
+		desc    string
+		client  *mockClient
+		want    []byte
+		version string
+		ok      bool
+	}{
+		{
+			desc: "simple",
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			want:    []byte("foo"),
+			version: blobHash([]byte("foo")),
+			ok:      true,
+		},
+		{
+			desc: "file does not exist",
+			client: newMockClient(map[string]string{
+				"main.tf": "",
+			}),
+			want:    []byte{},
+			version: "",
+			ok:      true,
+		},
+		{
+			desc:    "branch does not exist",
+			client:  newMockClient(nil),
+			want:    []byte{},
+			version: "",
+			ok:      true,
+		},
+		{
+			desc: "git error",
+			client: &mockClient{
+				err: fmt.Errorf("permission denied"),
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(&Config{Path: "tfmigrate/history.json"}, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, version, err := s.ReadWithVersion(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				if string(got) != string(tc.want) {
+					t.Errorf("got: %s, want: %s", string(got), string(tc.want))
+				}
+				if version != tc.version {
+					t.Errorf("got version: %s, want: %s", version, tc.version)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageWriteIfVersion(t *testing.T) {
+	cases := []struct {
+		desc     string
+		client   *mockClient
+		version  string
+		want     string
+		conflict bool
+	}{
+		{
+			desc:    "create",
+			client:  newMockClient(nil),
+			version: "",
+			want:    blobHash([]byte("bar")),
+		},
+		{
+			desc: "update",
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			version: blobHash([]byte("foo")),
+			want:    blobHash([]byte("bar")),
+		},
+		{
+			desc: "unrelated update of the branch",
+			client: &mockClient{
+				commits: map[string]map[string][]byte{},
+				rejects: 1,
+			},
+			version: "",
+			want:    blobHash([]byte("bar")),
+		},
+		{
+			desc: "conflict",
+			client: newMockClient(map[string]string{
+				"tfmigrate/history.json": "foo",
+			}),
+			version:  "",
+			conflict: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(&Config{Path: "tfmigrate/history.json"}, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, err := s.WriteIfVersion(context.Background(), []byte("bar"), tc.version)
+			if tc.conflict {
+				if !errors.Is(err, storage.ErrVersionConflict) {
+					t.Fatalf("expected to return ErrVersionConflict, but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStoragePing(t *testing.T) {
+	client := newMockClient(map[string]string{
+		"tfmigrate/history.json": "foo",
+	})
+	s, err := NewStorage(&Config{Path: "tfmigrate/history.json"}, client)
+	if err != nil {
+		t.Fatalf("failed to NewStorage: %s", err)
+	}
+	if _, err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if client.dryRuns != 1 || client.pushes != 0 {
+		t.Errorf("expected to push only with dry-run, but got dryRuns: %d, pushes: %d", client.dryRuns, client.pushes)
+	}
+}
+
+func TestStorageContract(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		s, err := NewStorage(&Config{Path: "tfmigrate/history.json"}, newMockClient(nil))
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,187 @@
+// Package httpapi is a helper for storages which talk to a storage service
+// with its HTTP API, such as azurerm, consul, etcd and tfc. It sends
+// authenticated requests, retries them and reports errors in the same way for
+// all of them.
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is a default maximum number of retries.
+	DefaultMaxRetries = 5
+	// DefaultRetryWait is a default wait time before the first retry.
+	DefaultRetryWait = time.Second
+	// MaxRetryWait is an upper limit of wait time between retries.
+	MaxRetryWait = 30 * time.Second
+)
+
+// Request is a request to an HTTP API.
+type Request struct {
+	// Method is an HTTP method.
+	Method string
+	// URL is a URL of the request.
+	URL string
+	// Header is a set of request headers.
+	Header http.Header
+	// Body is a request body. It is empty if nil.
+	Body []byte
+	// Idempotent is true if sending the request more than once has the same
+	// effect as sending it once. Only an idempotent request is retried on a
+	// server error or a network error, because a failed request may have been
+	// processed. A request which is not idempotent is still retried if it's
+	// rate limited, because it has not been processed.
+	Idempotent bool
+}
+
+// Response is a response of an HTTP API.
+type Response struct {
+	// StatusCode is an HTTP status code such as 200.
+	StatusCode int
+	// Status is an HTTP status such as "200 OK".
+	Status string
+	// Header is a set of response headers.
+	Header http.Header
+	// Body is a response body.
+	Body []byte
+}
+
+// Error returns an error of an unexpected response for a given operation
+// such as "failed to get consul key foo".
+func (r *Response) Error(op string) error {
+	return fmt.Errorf("%s: %s: %s", op, r.Status, string(r.Body))
+}
+
+// Client sends requests to an HTTP API with authentication and retries.
+type Client struct {
+	// Name is a name of the API used in error messages such as "consul".
+	Name string
+	// HTTPClient is an HTTP client. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+	// Authorize sets credentials to a given request if not nil.
+	// It is called on every attempt, so that a signature with a timestamp is
+	// always fresh.
+	Authorize func(ctx context.Context, r *http.Request) error
+	// ResetAuth discards cached credentials if not nil.
+	// It is called once when a request is rejected with 401 Unauthorized,
+	// and the request is sent again with new credentials, because a cached
+	// token may have expired on the server side.
+	ResetAuth func()
+	// MaxRetries is a maximum number of retries.
+	MaxRetries int
+	// RetryWait is a wait time before the first retry, which is doubled on
+	// each retry up to MaxRetryWait.
+	RetryWait time.Duration
+}
+
+// Do sends a given request and returns a response with its body.
+// A request which is rate limited, or an idempotent request which fails with
+// a server error or a network error, is retried with exponential backoff up
+// to the maximum number of retries. A response with any other status code is
+// returned as it is, and the caller is responsible for checking it.
+func (c *Client) Do(ctx context.Context, req *Request) (*Response, error) {
+	wait := c.RetryWait
+	reauthorized := false
+	for i := 0; ; i++ {
+		res, sent, err := c.doOnce(ctx, req)
+		retryable := false
+		switch {
+		case err != nil:
+			// A request which failed before being sent, such as a failure
+			// of authorization, is never retried.
+			retryable = sent && req.Idempotent && ctx.Err() == nil
+		case res.StatusCode == http.StatusUnauthorized && c.ResetAuth != nil && !reauthorized:
+			// An expired token is not counted as a retry.
+			c.ResetAuth()
+			reauthorized = true
+			i--
+			continue
+		default:
+			retryable = isRetryable(req, res)
+		}
+		if !retryable || i >= c.MaxRetries {
+			return res, err
+		}
+
+		d := wait
+		if res != nil {
+			d = retryAfter(res, wait)
+		}
+		wait = min(wait*2, MaxRetryWait)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(d):
+		}
+	}
+}
+
+// doOnce sends a given request without retries.
+// It returns true as well if the request has been sent, even if it failed.
+func (c *Client) doOnce(ctx context.Context, req *Request) (*Response, bool, error) {
+	var body io.Reader
+	if req.Body != nil {
+		body = bytes.NewReader(req.Body)
+	}
+	r, err := http.NewRequestWithContext(ctx, req.Method, req.URL, body)
+	if err != nil {
+		return nil, false, err
+	}
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	if c.Authorize != nil {
+		if err := c.Authorize(ctx, r); err != nil {
+			return nil, false, err
+		}
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(r)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to request %s: %s", c.Name, err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read %s response: %s", c.Name, err)
+	}
+	return &Response{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Header:     res.Header,
+		Body:       b,
+	}, true, nil
+}
+
+// isRetryable returns true if a given response is rate limited, or a server
+// error of an idempotent request.
+func isRetryable(req *Request, res *Response) bool {
+	if res.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return req.Idempotent && res.StatusCode >= http.StatusInternalServerError
+}
+
+// retryAfter returns a wait time before retrying a given response.
+// It respects the Retry-After header or the X-RateLimit-Reset header of
+// Terraform Cloud if any, otherwise returns a given default.
+func retryAfter(res *Response, d time.Duration) time.Duration {
+	for _, h := range []string{"Retry-After", "X-RateLimit-Reset"} {
+		if sec, err := strconv.ParseFloat(res.Header.Get(h), 64); err == nil && sec >= 0 {
+			return min(time.Duration(sec*float64(time.Second)), MaxRetryWait)
+		}
+	}
+	return d
+}
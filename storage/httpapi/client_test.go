@@ -0,0 +1,214 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a fake server which fails a given number of requests with a
+// given status code before succeeding.
+type fakeServer struct {
+	// mu protects requests.
+	mu sync.Mutex
+	// failures is a number of requests to fail.
+	failures int
+	// status is a status code of failures.
+	status int
+	// requests is a number of requests received.
+	requests int
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests++
+	if f.requests <= f.failures {
+		w.WriteHeader(f.status)
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
+
+func TestClientDo(t *testing.T) {
+	cases := []struct {
+		desc       string
+		idempotent bool
+		failures   int
+		status     int
+		maxRetries int
+		requests   int
+		want       int
+	}{
+		{
+			desc:       "retry a server error and success",
+			idempotent: true,
+			failures:   2,
+			status:     http.StatusInternalServerError,
+			maxRetries: 2,
+			requests:   3,
+			want:       http.StatusOK,
+		},
+		{
+			desc:       "exceed max retries",
+			idempotent: true,
+			failures:   3,
+			status:     http.StatusInternalServerError,
+			maxRetries: 2,
+			requests:   3,
+			want:       http.StatusInternalServerError,
+		},
+		{
+			desc:       "do not retry a server error of a non-idempotent request",
+			idempotent: false,
+			failures:   1,
+			status:     http.StatusInternalServerError,
+			maxRetries: 2,
+			requests:   1,
+			want:       http.StatusInternalServerError,
+		},
+		{
+			desc:       "retry a rate limited non-idempotent request",
+			idempotent: false,
+			failures:   1,
+			status:     http.StatusTooManyRequests,
+			maxRetries: 2,
+			requests:   2,
+			want:       http.StatusOK,
+		},
+		{
+			desc:       "do not retry a client error",
+			idempotent: true,
+			failures:   1,
+			status:     http.StatusForbidden,
+			maxRetries: 2,
+			requests:   1,
+			want:       http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fake := &fakeServer{failures: tc.failures, status: tc.status}
+			server := httptest.NewServer(fake)
+			t.Cleanup(server.Close)
+
+			c := &Client{
+				Name:       "test",
+				MaxRetries: tc.maxRetries,
+				RetryWait:  time.Millisecond,
+			}
+			res, err := c.Do(context.Background(), &Request{
+				Method:     http.MethodPost,
+				URL:        server.URL,
+				Idempotent: tc.idempotent,
+			})
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if res.StatusCode != tc.want {
+				t.Errorf("got status: %d, want: %d", res.StatusCode, tc.want)
+			}
+			if fake.requests != tc.requests {
+				t.Errorf("got requests: %d, want: %d", fake.requests, tc.requests)
+			}
+		})
+	}
+}
+
+func TestClientDoResetAuth(t *testing.T) {
+	fake := &fakeServer{failures: 1, status: http.StatusUnauthorized}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	authorized := 0
+	reset := 0
+	c := &Client{
+		Name: "test",
+		Authorize: func(_ context.Context, r *http.Request) error {
+			authorized++
+			r.Header.Set("Authorization", "token")
+			return nil
+		},
+		ResetAuth: func() { reset++ },
+	}
+	res, err := c.Do(context.Background(), &Request{Method: http.MethodGet, URL: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("got status: %d, want: %d", res.StatusCode, http.StatusOK)
+	}
+	if authorized != 2 || reset != 1 {
+		t.Errorf("got authorized: %d, reset: %d, want: 2, 1", authorized, reset)
+	}
+}
+
+func TestClientDoAuthorizeError(t *testing.T) {
+	fake := &fakeServer{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	c := &Client{
+		Name: "test",
+		Authorize: func(_ context.Context, _ *http.Request) error {
+			return errors.New("failed to authorize")
+		},
+		MaxRetries: 2,
+		RetryWait:  time.Millisecond,
+	}
+	_, err := c.Do(context.Background(), &Request{Method: http.MethodGet, URL: server.URL, Idempotent: true})
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+	if fake.requests != 0 {
+		t.Errorf("got requests: %d, want: 0", fake.requests)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cases := []struct {
+		desc   string
+		header map[string]string
+		want   time.Duration
+	}{
+		{
+			desc:   "default",
+			header: map[string]string{},
+			want:   time.Second,
+		},
+		{
+			desc:   "retry after",
+			header: map[string]string{"Retry-After": "3"},
+			want:   3 * time.Second,
+		},
+		{
+			desc:   "rate limit reset",
+			header: map[string]string{"X-RateLimit-Reset": "0.5"},
+			want:   500 * time.Millisecond,
+		},
+		{
+			desc:   "too long",
+			header: map[string]string{"Retry-After": "3600"},
+			want:   MaxRetryWait,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			res := &Response{Header: http.Header{}}
+			for k, v := range tc.header {
+				res.Header.Set(k, v)
+			}
+			got := retryAfter(res, time.Second)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
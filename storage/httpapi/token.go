@@ -0,0 +1,55 @@
+package httpapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is a margin before a token expires, within which the
+// token is refreshed not to be expired while a request is in flight.
+const tokenRefreshMargin = 5 * time.Minute
+
+// TokenCache caches an auth token until shortly before it expires.
+// It is safe for concurrent use.
+type TokenCache struct {
+	// Fetch fetches a new token and its expiration time.
+	// A zero expiration time means that the token never expires.
+	Fetch func(ctx context.Context) (string, time.Time, error)
+
+	// mu protects token and expiresAt.
+	mu sync.Mutex
+	// token is a cached token.
+	token string
+	// expiresAt is an expiration time of the token.
+	expiresAt time.Time
+}
+
+// Token returns a cached token, or fetches a new one if not cached or it's
+// about to expire.
+func (t *TokenCache) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.token) != 0 && (t.expiresAt.IsZero() || time.Now().Add(tokenRefreshMargin).Before(t.expiresAt)) {
+		return t.token, nil
+	}
+
+	token, expiresAt, err := t.Fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.token = token
+	t.expiresAt = expiresAt
+	return t.token, nil
+}
+
+// Reset discards the cached token, so that a new one is fetched on the next
+// call of Token.
+func (t *TokenCache) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.token = ""
+	t.expiresAt = time.Time{}
+}
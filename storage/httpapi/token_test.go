@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTokenCache(t *testing.T) {
+	cases := []struct {
+		desc      string
+		expiresIn time.Duration
+		fetches   int
+	}{
+		{
+			desc:      "never expires",
+			expiresIn: 0,
+			fetches:   1,
+		},
+		{
+			desc:      "valid",
+			expiresIn: time.Hour,
+			fetches:   1,
+		},
+		{
+			desc:      "about to expire",
+			expiresIn: time.Minute,
+			fetches:   2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fetches := 0
+			cache := &TokenCache{
+				Fetch: func(_ context.Context) (string, time.Time, error) {
+					fetches++
+					expiresAt := time.Time{}
+					if tc.expiresIn != 0 {
+						expiresAt = time.Now().Add(tc.expiresIn)
+					}
+					return fmt.Sprintf("token%d", fetches), expiresAt, nil
+				},
+			}
+
+			for i := 0; i < 2; i++ {
+				if _, err := cache.Token(context.Background()); err != nil {
+					t.Fatalf("unexpected err: %s", err)
+				}
+			}
+			if fetches != tc.fetches {
+				t.Errorf("got fetches: %d, want: %d", fetches, tc.fetches)
+			}
+
+			cache.Reset()
+			got, err := cache.Token(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			want := fmt.Sprintf("token%d", tc.fetches+1)
+			if got != want {
+				t.Errorf("got token after reset: %s, want: %s", got, want)
+			}
+		})
+	}
+}
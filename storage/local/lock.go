@@ -0,0 +1,72 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// lockFileSuffix is a suffix of a lock file next to the history file.
+// The lock file is never removed, because removing it while another process
+// waits for the lock would allow two processes to hold locks on different
+// files at the same time.
+const lockFileSuffix = ".lock"
+
+// lockRetryInterval is an interval to retry acquiring a lock.
+const lockRetryInterval = 100 * time.Millisecond
+
+// defaultLockTimeout is a default maximum duration to wait for a lock.
+const defaultLockTimeout = 30 * time.Second
+
+// fileLock is an exclusive OS file lock.
+type fileLock struct {
+	// f is an opened lock file.
+	f *os.File
+}
+
+// acquireLock acquires an exclusive lock for a given path.
+// It waits for the lock to be released by another process until timeout or
+// the context is cancelled.
+func acquireLock(ctx context.Context, path string, timeout time.Duration) (*fileLock, error) {
+	lockPath := path + lockFileSuffix
+	// nolint gosec
+	// G302: Expect file permissions to be 0600 or less
+	// The lock file has no content.
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %s", lockPath, err)
+		}
+		if locked {
+			return &fileLock{f: f}, nil
+		}
+
+		log.Printf("[DEBUG] [storage] waiting for lock: %s\n", lockPath)
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: another process holds the lock: %s", lockPath, ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// release releases the lock.
+func (l *fileLock) release() error {
+	if err := unlockFile(l.f); err != nil {
+		l.f.Close()
+		return fmt.Errorf("failed to unlock %s: %s", l.f.Name(), err)
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,37 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	lock, err := acquireLock(ctx, path, time.Second)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %s", err)
+	}
+
+	// A lock is held, so another attempt should time out.
+	if other, err := acquireLock(ctx, path, 200*time.Millisecond); err == nil {
+		other.release()
+		t.Fatal("expected to return an error, but no error")
+	}
+
+	if err := lock.release(); err != nil {
+		t.Fatalf("failed to release lock: %s", err)
+	}
+
+	// The lock has been released, so it can be acquired again.
+	lock, err = acquireLock(ctx, path, time.Second)
+	if err != nil {
+		t.Fatalf("failed to acquire lock after release: %s", err)
+	}
+	if err := lock.release(); err != nil {
+		t.Fatalf("failed to release lock: %s", err)
+	}
+}
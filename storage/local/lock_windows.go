@@ -0,0 +1,16 @@
+//go:build windows
+
+package local
+
+import "os"
+
+// tryLockFile is a no-op on Windows, which is not an officially supported
+// platform. Writes are still atomic.
+func tryLockFile(_ *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile is a no-op on Windows.
+func unlockFile(_ *os.File) error {
+	return nil
+}
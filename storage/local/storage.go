@@ -2,7 +2,10 @@ package local
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/minamijoyo/tfmigrate/storage"
 )
@@ -27,12 +30,65 @@ func NewStorage(config *Config) (*Storage, error) {
 }
 
 // Write writes migration history data to storage.
-func (s *Storage) Write(_ context.Context, b []byte) error {
+// It holds an exclusive lock on a lock file next to the history file while
+// writing, so that concurrent writers on a shared filesystem such as NFS are
+// serialized. The data is written to a temporary file in the same directory
+// and renamed to the history file, so that a reader never sees a partially
+// written file.
+func (s *Storage) Write(ctx context.Context, b []byte) (err error) {
+	lock, err := acquireLock(ctx, s.config.Path, defaultLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, lock.release())
+	}()
+
+	return writeFileAtomic(s.config.Path, b)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to a given path.
+func writeFileAtomic(path string, b []byte) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file: %s", err)
+	}
+	tmpName := tmp.Name()
+	// Remove the temporary file if it's not renamed.
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write a temporary file: %s", err)
+	}
+	// Flush data to disk before renaming, otherwise we may see an empty file
+	// after a crash.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync a temporary file: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close a temporary file: %s", err)
+	}
+
 	// nolint gosec
-	// G306: Expect WriteFile permissions to be 0600 or less
+	// G302: Expect file permissions to be 0600 or less
 	// We ignore it because a history file doesn't contains sensitive data.
 	// Note that changing a permission to 0600 is breaking change.
-	return os.WriteFile(s.config.Path, b, 0644)
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		return fmt.Errorf("failed to chmod a temporary file: %s", err)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to rename a temporary file: %s", err)
+	}
+
+	return nil
 }
 
 // Read reads migration history data from storage.
@@ -45,3 +101,21 @@ func (s *Storage) Read(_ context.Context) ([]byte, error) {
 	}
 	return os.ReadFile(s.config.Path)
 }
+
+// Ping checks permissions to read the history file, and to write and delete
+// a probe file in the same directory.
+func (s *Storage) Ping(ctx context.Context) (*storage.PingResult, error) {
+	probe := s.config.Path + storage.ProbeKeySuffix
+	return storage.Probe(ctx,
+		func(ctx context.Context) error {
+			_, err := s.Read(ctx)
+			return err
+		},
+		func(_ context.Context, b []byte) error {
+			return os.WriteFile(probe, b, 0600)
+		},
+		func(_ context.Context) error {
+			return os.Remove(probe)
+		},
+	)
+}
@@ -5,6 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/storagetest"
 )
 
 func TestStorageWrite(t *testing.T) {
@@ -61,6 +64,15 @@ func TestStorageWrite(t *testing.T) {
 				if string(got) != string(tc.contents) {
 					t.Errorf("got: %s, want: %s", string(got), string(tc.contents))
 				}
+
+				// A temporary file should not be left.
+				tmps, err := filepath.Glob(tc.config.Path + ".tmp-*")
+				if err != nil {
+					t.Fatalf("failed to glob temporary files: %s", err)
+				}
+				if len(tmps) != 0 {
+					t.Errorf("unexpected temporary files: %v", tmps)
+				}
 			}
 		})
 	}
@@ -125,3 +137,58 @@ func TestStorageRead(t *testing.T) {
 		})
 	}
 }
+
+func TestStoragePing(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Path: "history.json",
+			},
+			ok: true,
+		},
+		{
+			desc: "dir does not exist",
+			config: &Config{
+				Path: "not_exist/history.json",
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			localDir := t.TempDir()
+			tc.config.Path = filepath.Join(localDir, tc.config.Path)
+			s, err := NewStorage(tc.config)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.Ping(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if _, err := os.Stat(tc.config.Path + storage.ProbeKeySuffix); !os.IsNotExist(err) {
+				t.Errorf("expected a probe file to be removed, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestStorageContract(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		s, err := NewStorage(&Config{Path: filepath.Join(t.TempDir(), "history.json")})
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
@@ -1,8 +1,14 @@
 package mock
 
-import "github.com/minamijoyo/tfmigrate/storage"
+import (
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
 
 // Config is a config for mock storage.
+// It is exported for testing integrations with tfmigrate as a library as
+// well as for our own tests. Fields without hcl tags can only be set from Go.
 type Config struct {
 	// Data stores a serialized data for history.
 	Data string `hcl:"data"`
@@ -11,6 +17,15 @@ type Config struct {
 	// ReadError is a flag to return an error on Read().
 	ReadError bool `hcl:"read_error"`
 
+	// Latency is a duration to sleep before each operation to simulate a
+	// remote storage. The sleep is interrupted if the context is cancelled.
+	Latency time.Duration
+	// ErrorHook is called before each operation with its name and a 1-based
+	// call count of the operation. If it returns an error, the operation fails
+	// with it. This allows us to inject failures deterministically, for
+	// example, only on the second write.
+	ErrorHook func(op Op, n int) error
+
 	// A reference to an instance of mock storage for testing.
 	s *Storage
 }
@@ -3,48 +3,189 @@ package mock
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/minamijoyo/tfmigrate/storage"
 )
 
+// Op is a name of operation on mock storage.
+type Op string
+
+const (
+	// OpRead is a name of the read operation.
+	OpRead Op = "read"
+	// OpWrite is a name of the write operation.
+	OpWrite Op = "write"
+	// OpPing is a name of the ping operation.
+	OpPing Op = "ping"
+)
+
 // Storage is a storage.Storage implementation for mock.
 // It writes and reads data from memory.
+// It is safe for concurrent use.
 type Storage struct {
 	// config is a storage config for mock
 	config *Config
+	// mu protects the following fields.
+	mu sync.Mutex
 	// data stores a serialized data for history.
 	data string
+	// version is incremented on each write.
+	// It is 0 if no data exists.
+	version int64
+	// calls is a number of calls for each operation.
+	calls map[Op]int
 }
 
 var _ storage.Storage = (*Storage)(nil)
+var _ storage.VersionedStorage = (*Storage)(nil)
 
 // NewStorage returns a new instance of Storage.
 func NewStorage(config *Config) (*Storage, error) {
 	s := &Storage{
 		config: config,
 		data:   config.Data,
+		calls:  make(map[Op]int),
+	}
+	if len(config.Data) != 0 {
+		s.version = 1
 	}
 	return s, nil
 }
 
 // Data returns a raw data in mock storage for testing.
 func (s *Storage) Data() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.data
 }
 
+// Calls returns a number of calls of a given operation for testing.
+// It counts failed calls too.
+func (s *Storage) Calls(op Op) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[op]
+}
+
 // Write writes migration history data to storage.
-func (s *Storage) Write(_ context.Context, b []byte) error {
+func (s *Storage) Write(ctx context.Context, b []byte) error {
+	if err := s.begin(ctx, OpWrite); err != nil {
+		return err
+	}
 	if s.config.WriteError {
 		return fmt.Errorf("failed to write mock storage: writeError = %t", s.config.WriteError)
 	}
-	s.data = string(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(b)
 	return nil
 }
 
 // Read reads migration history data from storage.
-func (s *Storage) Read(_ context.Context) ([]byte, error) {
+func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, _, err := s.ReadWithVersion(ctx)
+	return b, err
+}
+
+// ReadWithVersion reads migration history data with its current version.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, string, error) {
+	if err := s.begin(ctx, OpRead); err != nil {
+		return nil, "", err
+	}
 	if s.config.ReadError {
-		return nil, fmt.Errorf("failed to read mock storage: readError = %t", s.config.ReadError)
+		return nil, "", fmt.Errorf("failed to read mock storage: readError = %t", s.config.ReadError)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return []byte(s.data), s.versionString(), nil
+}
+
+// WriteIfVersion writes migration history data only if the current version
+// matches a given one.
+func (s *Storage) WriteIfVersion(ctx context.Context, b []byte, version string) (string, error) {
+	if err := s.begin(ctx, OpWrite); err != nil {
+		return "", err
+	}
+	if s.config.WriteError {
+		return "", fmt.Errorf("failed to write mock storage: writeError = %t", s.config.WriteError)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current := s.versionString(); current != version {
+		return "", fmt.Errorf("failed to write mock storage: expected version %q, but got %q: %w", version, current, storage.ErrVersionConflict)
+	}
+	s.write(b)
+	return s.versionString(), nil
+}
+
+// Ping checks health of storage.
+// It fails if either ReadError or WriteError is set.
+func (s *Storage) Ping(ctx context.Context) (*storage.PingResult, error) {
+	if err := s.begin(ctx, OpPing); err != nil {
+		return nil, err
+	}
+	return storage.Probe(ctx,
+		func(_ context.Context) error {
+			if s.config.ReadError {
+				return fmt.Errorf("failed to read mock storage: readError = %t", s.config.ReadError)
+			}
+			return nil
+		},
+		func(_ context.Context, _ []byte) error {
+			if s.config.WriteError {
+				return fmt.Errorf("failed to write mock storage: writeError = %t", s.config.WriteError)
+			}
+			return nil
+		},
+		func(_ context.Context) error {
+			return nil
+		},
+	)
+}
+
+// begin counts a call of a given operation, simulates latency and injects
+// an error if any.
+func (s *Storage) begin(ctx context.Context, op Op) error {
+	s.mu.Lock()
+	s.calls[op]++
+	n := s.calls[op]
+	s.mu.Unlock()
+
+	if s.config.Latency > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to %s mock storage: %w", op, ctx.Err())
+		case <-time.After(s.config.Latency):
+		}
+	}
+
+	if s.config.ErrorHook != nil {
+		if err := s.config.ErrorHook(op, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write stores data and increments the version.
+// The caller must hold the lock.
+func (s *Storage) write(b []byte) {
+	s.data = string(b)
+	s.version++
+}
+
+// versionString returns the current version as a string.
+// The caller must hold the lock.
+func (s *Storage) versionString() string {
+	if s.version == 0 {
+		return ""
 	}
-	return []byte(s.data), nil
+	return strconv.FormatInt(s.version, 10)
 }
@@ -2,7 +2,13 @@ package mock
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/storagetest"
 )
 
 func TestStorageWrite(t *testing.T) {
@@ -112,3 +118,176 @@ func TestStorageRead(t *testing.T) {
 		})
 	}
 }
+
+func TestStoragePing(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Data:       "foo",
+				WriteError: false,
+				ReadError:  false,
+			},
+			ok: true,
+		},
+		{
+			desc: "read error",
+			config: &Config{
+				Data:       "foo",
+				WriteError: false,
+				ReadError:  true,
+			},
+			ok: false,
+		},
+		{
+			desc: "write error",
+			config: &Config{
+				Data:       "foo",
+				WriteError: true,
+				ReadError:  false,
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.Ping(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %#v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestStorageWriteIfVersion(t *testing.T) {
+	cases := []struct {
+		desc    string
+		config  *Config
+		version string
+		want    string
+		ok      bool
+	}{
+		{
+			desc:    "create",
+			config:  &Config{},
+			version: "",
+			want:    "1",
+			ok:      true,
+		},
+		{
+			desc:    "create conflict",
+			config:  &Config{Data: "foo"},
+			version: "",
+			ok:      false,
+		},
+		{
+			desc:    "update",
+			config:  &Config{Data: "foo"},
+			version: "1",
+			want:    "2",
+			ok:      true,
+		},
+		{
+			desc:    "update conflict",
+			config:  &Config{Data: "foo"},
+			version: "2",
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, err := s.WriteIfVersion(context.Background(), []byte("bar"), tc.version)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if !errors.Is(err, storage.ErrVersionConflict) {
+					t.Fatalf("expected to return ErrVersionConflict, but got: %v", err)
+				}
+				if s.Data() != tc.config.Data {
+					t.Errorf("data was updated on conflict: %s", s.Data())
+				}
+				return
+			}
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+			if s.Data() != "bar" {
+				t.Errorf("got: %s, want: bar", s.Data())
+			}
+		})
+	}
+}
+
+func TestStorageErrorHook(t *testing.T) {
+	config := &Config{
+		ErrorHook: func(op Op, n int) error {
+			if op == OpWrite && n == 2 {
+				return fmt.Errorf("injected error")
+			}
+			return nil
+		},
+	}
+	s, err := NewStorage(config)
+	if err != nil {
+		t.Fatalf("failed to NewStorage: %s", err)
+	}
+
+	ctx := context.Background()
+	if err := s.Write(ctx, []byte("foo")); err != nil {
+		t.Fatalf("unexpected err on the first write: %s", err)
+	}
+	if err := s.Write(ctx, []byte("bar")); err == nil {
+		t.Fatal("expected to return an error on the second write, but no error")
+	}
+	if s.Data() != "foo" {
+		t.Errorf("got: %s, want: foo", s.Data())
+	}
+	if got := s.Calls(OpWrite); got != 2 {
+		t.Errorf("got: %d calls, want: 2 calls", got)
+	}
+}
+
+func TestStorageLatency(t *testing.T) {
+	config := &Config{
+		Latency: time.Hour,
+	}
+	s, err := NewStorage(config)
+	if err != nil {
+		t.Fatalf("failed to NewStorage: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Read(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to return context.DeadlineExceeded, but got: %v", err)
+	}
+}
+
+func TestStorageContract(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		s, err := NewStorage(&Config{})
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
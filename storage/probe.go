@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProbeKeySuffix is a suffix appended to a key of the history file to make a
+// probe key used for health checks.
+const ProbeKeySuffix = ".tfmigrate-probe"
+
+// probeData is contents written to a probe key.
+var probeData = []byte("tfmigrate storage health check\n")
+
+// PingResult is a result of a health check against a storage.
+type PingResult struct {
+	// ReadLatency is a latency to read the history file.
+	ReadLatency time.Duration
+	// WriteLatency is a latency to write a probe key.
+	WriteLatency time.Duration
+	// DeleteLatency is a latency to delete a probe key.
+	DeleteLatency time.Duration
+}
+
+// Probe is a helper function for implementing Storage.Ping.
+// It reads the history file, writes a probe key and deletes it in order, and
+// measures their latencies. It stops at the first failure and returns an
+// error which tells the missing permission.
+func Probe(ctx context.Context, read func(context.Context) error, write func(context.Context, []byte) error, del func(context.Context) error) (*PingResult, error) {
+	r := &PingResult{}
+
+	start := time.Now()
+	if err := read(ctx); err != nil {
+		return r, fmt.Errorf("failed to read the history file. Check the read permission: %s", err)
+	}
+	r.ReadLatency = time.Since(start)
+
+	start = time.Now()
+	if err := write(ctx, probeData); err != nil {
+		return r, fmt.Errorf("failed to write a probe key. Check the write permission: %s", err)
+	}
+	r.WriteLatency = time.Since(start)
+
+	start = time.Now()
+	if err := del(ctx); err != nil {
+		return r, fmt.Errorf("failed to delete a probe key. Check the delete permission: %s", err)
+	}
+	r.DeleteLatency = time.Since(start)
+
+	return r, nil
+}
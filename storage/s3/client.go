@@ -17,6 +17,8 @@ type Client interface {
 	PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error)
 	// GetObjectWithContext gets a file from S3.
 	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+	// DeleteObjectWithContext deletes an object from S3.
+	DeleteObjectWithContext(ctx aws.Context, input *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error)
 }
 
 // client is a real implementation of the Client.
@@ -38,6 +40,12 @@ func newClient(config *Config) (Client, error) {
 
 	sess, err := awsbase.GetSession(cfg)
 	if err != nil {
+		if awsbase.IsNoValidCredentialSourcesError(err) {
+			return nil, fmt.Errorf("failed to new s3 client: no AWS credentials found. "+
+				"Static credentials are not required. Provide ambient credentials with "+
+				"an EC2 instance profile, an ECS task role, a web identity token for OIDC "+
+				"(AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE), a profile, or environment variables: %s", err)
+		}
 		return nil, fmt.Errorf("failed to new s3 client: %s", err)
 	}
 
@@ -58,3 +66,8 @@ func (c *client) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput,
 func (c *client) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
 	return c.s3api.GetObjectWithContext(ctx, input, opts...)
 }
+
+// DeleteObjectWithContext deletes an object from S3.
+func (c *client) DeleteObjectWithContext(ctx aws.Context, input *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error) {
+	return c.s3api.DeleteObjectWithContext(ctx, input, opts...)
+}
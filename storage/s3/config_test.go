@@ -24,6 +24,18 @@ func TestConfigNewStorage(t *testing.T) {
 			},
 			ok: true,
 		},
+		{
+			desc: "access_key without secret_key",
+			config: &Config{
+				Bucket:                    "tfmigrate-test",
+				Key:                       "tfmigrate/history.json",
+				Region:                    "ap-northeast-1",
+				AccessKey:                 "dummy",
+				SkipCredentialsValidation: true,
+				SkipMetadataAPICheck:      true,
+			},
+			ok: false,
+		},
 	}
 
 	for _, tc := range cases {
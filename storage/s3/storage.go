@@ -3,9 +3,12 @@ package s3
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/minamijoyo/tfmigrate/storage"
 )
@@ -20,9 +23,14 @@ type Storage struct {
 }
 
 var _ storage.Storage = (*Storage)(nil)
+var _ storage.VersionedStorage = (*Storage)(nil)
 
 // NewStorage returns a new instance of Storage.
 func NewStorage(config *Config, client Client) (*Storage, error) {
+	if err := validateCredentials(config); err != nil {
+		return nil, err
+	}
+
 	if client == nil {
 		var err error
 		client, err = newClient(config)
@@ -39,19 +47,27 @@ func NewStorage(config *Config, client Client) (*Storage, error) {
 	return s, nil
 }
 
-// Write writes migration history data to storage.
-func (s *Storage) Write(ctx context.Context, b []byte) error {
-	input := &s3.PutObjectInput{
-		Bucket: aws.String(s.config.Bucket),
-		Key:    aws.String(s.config.Key),
-		Body:   bytes.NewReader(b),
+// validateCredentials validates static credentials in the config.
+// Static credentials are never required. If both access_key and secret_key
+// are omitted, credentials are resolved from the environment, such as
+// environment variables, a shared credentials file, a web identity token for
+// OIDC, or an ECS task role or EC2 instance profile.
+func validateCredentials(config *Config) error {
+	if len(config.AccessKey) == 0 && len(config.SecretKey) == 0 {
+		return nil
 	}
-	if s.config.KmsKeyID != "" {
-		input.SSEKMSKeyId = &s.config.KmsKeyID
-		input.ServerSideEncryption = aws.String("aws:kms")
+
+	if len(config.AccessKey) == 0 || len(config.SecretKey) == 0 {
+		return fmt.Errorf("access_key and secret_key of s3 storage must be set together. Consider removing both of them and using ambient credentials instead")
 	}
 
-	_, err := s.client.PutObjectWithContext(ctx, input)
+	log.Printf("[WARN] [storage@s3] static credentials are set in the config. Consider using ambient credentials instead")
+	return nil
+}
+
+// Write writes migration history data to storage.
+func (s *Storage) Write(ctx context.Context, b []byte) error {
+	_, err := s.client.PutObjectWithContext(ctx, s.putObjectInput(s.config.Key, b))
 
 	return err
 }
@@ -60,6 +76,13 @@ func (s *Storage) Write(ctx context.Context, b []byte) error {
 // If the key does not exist, it is assumed to be uninitialized and returns
 // an empty array instead of an error.
 func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, _, err := s.ReadWithVersion(ctx)
+	return b, err
+}
+
+// ReadWithVersion reads migration history data with its ETag as a version.
+// If the key does not exist, it returns an empty array and version.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, string, error) {
 	input := &s3.GetObjectInput{
 		Bucket: aws.String(s.config.Bucket),
 		Key:    aws.String(s.config.Key),
@@ -69,10 +92,10 @@ func (s *Storage) Read(ctx context.Context) ([]byte, error) {
 	if err != nil {
 		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoSuchKey" {
 			// If the key does not exist
-			return []byte{}, nil
+			return []byte{}, "", nil
 		}
 		// unexpected error
-		return nil, err
+		return nil, "", err
 	}
 
 	defer output.Body.Close()
@@ -80,8 +103,72 @@ func (s *Storage) Read(ctx context.Context) ([]byte, error) {
 	buf := bytes.NewBuffer(nil)
 	_, err = buf.ReadFrom(output.Body)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return buf.Bytes(), nil
+	return buf.Bytes(), aws.StringValue(output.ETag), nil
+}
+
+// WriteIfVersion writes migration history data only if its ETag matches a
+// given version with a conditional write of S3. An empty version means that
+// the object must not exist.
+// Note that an S3 compatible endpoint which doesn't support conditional
+// writes may ignore the condition.
+func (s *Storage) WriteIfVersion(ctx context.Context, b []byte, version string) (string, error) {
+	header := map[string]string{"If-None-Match": "*"}
+	if len(version) > 0 {
+		header = map[string]string{"If-Match": version}
+	}
+
+	output, err := s.client.PutObjectWithContext(ctx, s.putObjectInput(s.config.Key, b), request.WithSetRequestHeaders(header))
+	if err != nil {
+		// S3 returns 412 Precondition Failed on mismatch, and 409 Conflict
+		// if another conditional write to the same key is in progress.
+		if awsErr, ok := err.(awserr.Error); ok && (awsErr.Code() == "PreconditionFailed" || awsErr.Code() == "ConditionalRequestConflict") {
+			return "", fmt.Errorf("failed to write s3 object %s with etag %q: %w", s.config.Key, version, storage.ErrVersionConflict)
+		}
+		return "", err
+	}
+
+	return aws.StringValue(output.ETag), nil
+}
+
+// putObjectInput returns an input to put a given object, which is encrypted
+// with the KMS key if set.
+func (s *Storage) putObjectInput(key string, b []byte) *s3.PutObjectInput {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(b),
+	}
+	if s.config.KmsKeyID != "" {
+		input.SSEKMSKeyId = &s.config.KmsKeyID
+		input.ServerSideEncryption = aws.String("aws:kms")
+	}
+	return input
+}
+
+// Ping checks permissions to read the history file, and to write and delete
+// a probe key next to it. The probe key is encrypted in the same way as the
+// history file, so that a bucket policy which enforces encryption is checked.
+func (s *Storage) Ping(ctx context.Context) (*storage.PingResult, error) {
+	probe := s.config.Key + storage.ProbeKeySuffix
+	return storage.Probe(ctx,
+		func(ctx context.Context) error {
+			_, err := s.Read(ctx)
+			return err
+		},
+		func(ctx context.Context, b []byte) error {
+			_, err := s.client.PutObjectWithContext(ctx, s.putObjectInput(probe, b))
+			return err
+		},
+		func(ctx context.Context) error {
+			input := &s3.DeleteObjectInput{
+				Bucket: aws.String(s.config.Bucket),
+				Key:    aws.String(probe),
+			}
+			_, err := s.client.DeleteObjectWithContext(ctx, input)
+			return err
+		},
+	)
 }
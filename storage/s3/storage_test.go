@@ -1,15 +1,22 @@
 package s3
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/storagetest"
+	"github.com/minamijoyo/tfmigrate/tfexec"
 )
 
 // mockClient is a mock implementation for testing.
@@ -17,6 +24,7 @@ type mockClient struct {
 	putOutput *s3.PutObjectOutput
 	getOutput *s3.GetObjectOutput
 	err       error
+	deleteErr error
 }
 
 // PutObjectWithContext returns a mocked response.
@@ -29,6 +37,11 @@ func (c *mockClient) GetObjectWithContext(_ aws.Context, _ *s3.GetObjectInput, _
 	return c.getOutput, c.err
 }
 
+// DeleteObjectWithContext returns a mocked response.
+func (c *mockClient) DeleteObjectWithContext(_ aws.Context, _ *s3.DeleteObjectInput, _ ...request.Option) (*s3.DeleteObjectOutput, error) {
+	return &s3.DeleteObjectOutput{}, c.deleteErr
+}
+
 func TestStorageWrite(t *testing.T) {
 	cases := []struct {
 		desc     string
@@ -155,3 +168,174 @@ func TestStorageRead(t *testing.T) {
 		})
 	}
 }
+
+func TestStoragePing(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		client Client
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				putOutput: &s3.PutObjectOutput{},
+				getOutput: &s3.GetObjectOutput{
+					Body: io.NopCloser(strings.NewReader("foo")),
+				},
+				err: nil,
+			},
+			ok: true,
+		},
+		{
+			desc: "access denied",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				err: awserr.New("AccessDenied", "Access Denied", nil),
+			},
+			ok: false,
+		},
+		{
+			desc: "delete denied",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				putOutput: &s3.PutObjectOutput{},
+				getOutput: &s3.GetObjectOutput{
+					Body: io.NopCloser(strings.NewReader("foo")),
+				},
+				deleteErr: awserr.New("AccessDenied", "Access Denied", nil),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.Ping(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+// fakeClient is an in-memory implementation of Client which supports
+// conditional writes with the If-Match and If-None-Match headers.
+type fakeClient struct {
+	objects map[string][]byte
+	etags   map[string]string
+	serial  int
+}
+
+// PutObjectWithContext puts an object if conditions in headers are met.
+func (c *fakeClient) PutObjectWithContext(_ aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	r := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+	r.ApplyOptions(opts...)
+
+	key := aws.StringValue(input.Key)
+	current, exists := c.etags[key]
+	if ifMatch := r.HTTPRequest.Header.Get("If-Match"); len(ifMatch) > 0 && ifMatch != current {
+		return nil, awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+	}
+	if r.HTTPRequest.Header.Get("If-None-Match") == "*" && exists {
+		return nil, awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+	}
+
+	b, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.serial++
+	c.objects[key] = b
+	c.etags[key] = fmt.Sprintf(`"%d"`, c.serial)
+	return &s3.PutObjectOutput{ETag: aws.String(c.etags[key])}, nil
+}
+
+// GetObjectWithContext gets an object with its ETag.
+func (c *fakeClient) GetObjectWithContext(_ aws.Context, input *s3.GetObjectInput, _ ...request.Option) (*s3.GetObjectOutput, error) {
+	key := aws.StringValue(input.Key)
+	b, ok := c.objects[key]
+	if !ok {
+		return nil, awserr.New("NoSuchKey", "The specified key does not exist.", nil)
+	}
+	return &s3.GetObjectOutput{
+		Body: io.NopCloser(bytes.NewReader(b)),
+		ETag: aws.String(c.etags[key]),
+	}, nil
+}
+
+// DeleteObjectWithContext deletes an object.
+func (c *fakeClient) DeleteObjectWithContext(_ aws.Context, input *s3.DeleteObjectInput, _ ...request.Option) (*s3.DeleteObjectOutput, error) {
+	key := aws.StringValue(input.Key)
+	delete(c.objects, key)
+	delete(c.etags, key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestStorageContract(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		config := &Config{
+			Bucket: "tfmigrate-test",
+			Key:    "tfmigrate/history.json",
+		}
+		client := &fakeClient{
+			objects: make(map[string][]byte),
+			etags:   make(map[string]string),
+		}
+		s, err := NewStorage(config, client)
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
+
+func TestAccStorageContract(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		endpoint := tfexec.GetTestAccS3Endpoint()
+		config := &Config{
+			Bucket:                    tfexec.TestS3Bucket,
+			Key:                       fmt.Sprintf("%s/%d/history.json", t.Name(), time.Now().UnixNano()),
+			Region:                    tfexec.TestS3Region,
+			Endpoint:                  endpoint,
+			AccessKey:                 tfexec.TestS3AccessKey,
+			SecretKey:                 tfexec.TestS3SecretKey,
+			SkipCredentialsValidation: true,
+			SkipMetadataAPICheck:      true,
+			ForcePathStyle:            true,
+		}
+		s, err := NewStorage(config, nil)
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		t.Cleanup(func() {
+			input := &s3.DeleteObjectInput{
+				Bucket: aws.String(config.Bucket),
+				Key:    aws.String(config.Key),
+			}
+			if _, err := s.client.DeleteObjectWithContext(context.Background(), input); err != nil {
+				t.Logf("failed to delete history file: %s", err)
+			}
+		})
+		return s
+	})
+}
@@ -14,4 +14,9 @@ type Storage interface {
 	// If the key does not exist, it is assumed to be uninitialized and returns
 	// an empty array instead of an error.
 	Read(ctx context.Context) ([]byte, error)
+	// Ping checks that the storage is reachable and that we have permissions
+	// to read the history file, and to write and delete a probe key next to it.
+	// It never modifies the history file itself, so that we can detect missing
+	// permissions before applying migrations.
+	Ping(ctx context.Context) (*PingResult, error)
 }
@@ -0,0 +1,128 @@
+// Package storagetest provides contract tests for storage.Storage
+// implementations, so that storage backend contributors can verify that a new
+// backend behaves the same as the existing ones.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// NewStorageFunc returns a new storage for testing.
+// It must return a storage whose history file doesn't exist yet, such as a
+// storage with a unique key for each test. Use t.Cleanup to delete it.
+type NewStorageFunc func(t *testing.T) storage.Storage
+
+// TestStorage runs contract tests against storages returned by a given
+// function. If the storage implements storage.VersionedStorage, it also runs
+// contract tests for compare-and-swap writes.
+func TestStorage(t *testing.T, newStorage NewStorageFunc) {
+	t.Helper()
+
+	t.Run("ReadUninitialized", func(t *testing.T) {
+		s := newStorage(t)
+		got := mustRead(t, s)
+		if len(got) != 0 {
+			t.Errorf("expected an empty history for an uninitialized storage, but got: %s", got)
+		}
+	})
+
+	t.Run("WriteAndRead", func(t *testing.T) {
+		s := newStorage(t)
+		mustWrite(t, s, "foo")
+		if got := mustRead(t, s); string(got) != "foo" {
+			t.Errorf("got: %s, want: foo", got)
+		}
+
+		// overwrite
+		mustWrite(t, s, "bar")
+		if got := mustRead(t, s); string(got) != "bar" {
+			t.Errorf("got: %s, want: bar", got)
+		}
+	})
+
+	t.Run("Ping", func(t *testing.T) {
+		s := newStorage(t)
+		mustWrite(t, s, "foo")
+		if _, err := s.Ping(context.Background()); err != nil {
+			t.Fatalf("failed to ping storage: %s", err)
+		}
+		if got := mustRead(t, s); string(got) != "foo" {
+			t.Errorf("ping must not modify the history file, got: %s, want: foo", got)
+		}
+	})
+
+	t.Run("CompareAndSwap", func(t *testing.T) {
+		vs, ok := newStorage(t).(storage.VersionedStorage)
+		if !ok {
+			t.Skip("storage doesn't implement storage.VersionedStorage")
+		}
+		testCompareAndSwap(t, vs)
+	})
+}
+
+// testCompareAndSwap runs contract tests for compare-and-swap writes.
+func testCompareAndSwap(t *testing.T, s storage.VersionedStorage) {
+	ctx := context.Background()
+
+	b, version, err := s.ReadWithVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to read storage with version: %s", err)
+	}
+	if len(b) != 0 || len(version) != 0 {
+		t.Fatalf("expected an empty history and version for an uninitialized storage, but got: %s, %q", b, version)
+	}
+
+	v1, err := s.WriteIfVersion(ctx, []byte("foo"), "")
+	if err != nil {
+		t.Fatalf("failed to create history file: %s", err)
+	}
+	if len(v1) == 0 {
+		t.Fatal("expected a non-empty version after write")
+	}
+
+	if _, err := s.WriteIfVersion(ctx, []byte("bar"), ""); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("expected a version conflict when creating an existing history file, but got: %v", err)
+	}
+
+	v2, err := s.WriteIfVersion(ctx, []byte("bar"), v1)
+	if err != nil {
+		t.Fatalf("failed to update history file: %s", err)
+	}
+	if v2 == v1 {
+		t.Fatalf("expected a new version after update, but got the same: %q", v2)
+	}
+
+	if _, err := s.WriteIfVersion(ctx, []byte("baz"), v1); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("expected a version conflict when updating with a stale version, but got: %v", err)
+	}
+
+	b, version, err = s.ReadWithVersion(ctx)
+	if err != nil {
+		t.Fatalf("failed to read storage with version: %s", err)
+	}
+	if string(b) != "bar" || version != v2 {
+		t.Errorf("got: %s, %q, want: bar, %q", b, version, v2)
+	}
+}
+
+// mustRead reads a given storage or fails the test.
+func mustRead(t *testing.T, s storage.Storage) []byte {
+	t.Helper()
+	b, err := s.Read(context.Background())
+	if err != nil {
+		t.Fatalf("failed to read storage: %s", err)
+	}
+	return b
+}
+
+// mustWrite writes a given data to a given storage or fails the test.
+func mustWrite(t *testing.T, s storage.Storage, data string) {
+	t.Helper()
+	if err := s.Write(context.Background(), []byte(data)); err != nil {
+		t.Fatalf("failed to write storage: %s", err)
+	}
+}
@@ -0,0 +1,417 @@
+package tfc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/httpapi"
+)
+
+const (
+	// DefaultHostname is a default hostname of Terraform Cloud.
+	DefaultHostname = "app.terraform.io"
+	// DefaultKey is a default key of the workspace variable.
+	DefaultKey = "tfmigrate_history"
+	// DefaultMaxRetries is a default maximum number of retries.
+	DefaultMaxRetries = httpapi.DefaultMaxRetries
+
+	// contentType is a media type of JSON:API used by Terraform Cloud API.
+	contentType = "application/vnd.api+json"
+	// variableCategory is a category of the workspace variable.
+	// A terraform variable is injected into runs as an input variable of the
+	// configuration, so we use an environment variable, which is only set in
+	// the environment of runs and ignored by terraform. Note that it's still
+	// limited in size by the OS in runs, so use a dedicated workspace.
+	variableCategory = "env"
+	// variableDescription is a description of the workspace variable.
+	variableDescription = "Migration history managed by tfmigrate. Do not edit."
+)
+
+// Client is an abstraction layer for Terraform Cloud workspace variables API.
+// It is intended to be replaced with a mock for testing.
+type Client interface {
+	// Get returns a value of a given variable.
+	// If the variable does not exist, it returns nil.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put sets a value of a given variable. The variable is created if it
+	// does not exist.
+	Put(ctx context.Context, key string, value []byte) error
+	// Delete deletes a given variable.
+	Delete(ctx context.Context, key string) error
+	// GetWithVersion returns a value of a given variable with its version.
+	// If the variable does not exist, it returns nil and an empty version.
+	GetWithVersion(ctx context.Context, key string) ([]byte, string, error)
+	// PutIfVersion sets a value of a given variable only if its current
+	// version matches a given one, and returns a new version.
+	// An empty version means that the variable must not exist.
+	// It returns an error wrapping storage.ErrVersionConflict on mismatch.
+	PutIfVersion(ctx context.Context, key string, value []byte, version string) (string, error)
+}
+
+// client is a real implementation of the Client with Terraform Cloud API.
+type client struct {
+	// address is a base URL of Terraform Cloud.
+	address string
+	// token is an API token.
+	token string
+	// organization is a name of the organization.
+	organization string
+	// workspace is a name of the workspace.
+	workspace string
+	// workspaceID is an ID of the workspace resolved lazily.
+	workspaceID string
+	// api sends requests to Terraform Cloud.
+	api *httpapi.Client
+}
+
+var _ Client = (*client)(nil)
+
+// newClient returns a new instance of Client.
+func newClient(config *Config) (Client, error) {
+	hostname := config.Hostname
+	if len(hostname) == 0 {
+		hostname = DefaultHostname
+	}
+	// A scheme is allowed only for testing.
+	address := hostname
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	if _, err := url.Parse(address); err != nil {
+		return nil, fmt.Errorf("failed to new tfc client: invalid hostname: %s", err)
+	}
+
+	token := config.Token
+	if len(token) == 0 {
+		token = os.Getenv(TokenEnvName(hostname))
+	}
+	if len(token) == 0 {
+		token = os.Getenv("TFE_TOKEN")
+	}
+	if len(token) == 0 {
+		token = readCredentialsToken(hostname)
+	}
+	if len(token) == 0 {
+		return nil, fmt.Errorf("failed to new tfc client: no API token for %s. Set token, %s or TFE_TOKEN, or run terraform login", hostname, TokenEnvName(hostname))
+	}
+
+	maxRetries := config.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultMaxRetries
+	}
+
+	c := &client{
+		address:      strings.TrimSuffix(address, "/"),
+		token:        token,
+		organization: config.Organization,
+		workspace:    config.Workspace,
+		api: &httpapi.Client{
+			Name: "tfc",
+			Authorize: func(_ context.Context, r *http.Request) error {
+				r.Header.Set("Authorization", "Bearer "+token)
+				return nil
+			},
+			MaxRetries: maxRetries,
+			RetryWait:  httpapi.DefaultRetryWait,
+		},
+	}
+	return c, nil
+}
+
+// TokenEnvName returns a name of environment variable for an API token of a
+// given hostname in the same way as Terraform CLI.
+// e.g.) app.terraform.io => TF_TOKEN_app_terraform_io
+func TokenEnvName(hostname string) string {
+	r := strings.NewReplacer(".", "_", "-", "__")
+	return "TF_TOKEN_" + r.Replace(hostname)
+}
+
+// readCredentialsToken returns an API token of a given hostname in the
+// credentials file written by `terraform login`.
+// It returns an empty string if not found.
+func readCredentialsToken(hostname string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	b, err := os.ReadFile(filepath.Join(home, ".terraform.d", "credentials.tfrc.json"))
+	if err != nil {
+		return ""
+	}
+
+	var creds struct {
+		Credentials map[string]struct {
+			Token string `json:"token"`
+		} `json:"credentials"`
+	}
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return ""
+	}
+	return creds.Credentials[hostname].Token
+}
+
+// variable is a workspace variable in Terraform Cloud API.
+type variable struct {
+	ID         string             `json:"id,omitempty"`
+	Type       string             `json:"type"`
+	Attributes variableAttributes `json:"attributes"`
+}
+
+// variableAttributes is attributes of a workspace variable.
+type variableAttributes struct {
+	Key         string  `json:"key,omitempty"`
+	Value       *string `json:"value"`
+	Description string  `json:"description,omitempty"`
+	Category    string  `json:"category,omitempty"`
+	HCL         bool    `json:"hcl"`
+	Sensitive   bool    `json:"sensitive"`
+	// VersionID is a hash of the variable which changes on every update.
+	// It is read-only.
+	VersionID string `json:"version-id,omitempty"`
+}
+
+// Get returns a value of a given variable.
+func (c *client) Get(ctx context.Context, key string) ([]byte, error) {
+	b, _, err := c.GetWithVersion(ctx, key)
+	return b, err
+}
+
+// GetWithVersion returns a value of a given variable with its version-id.
+func (c *client) GetWithVersion(ctx context.Context, key string) ([]byte, string, error) {
+	v, err := c.findVariable(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	if v == nil {
+		return nil, "", nil
+	}
+	if v.Attributes.Sensitive || v.Attributes.Value == nil {
+		return nil, "", fmt.Errorf("failed to get tfc variable %s: the variable is sensitive and cannot be read", key)
+	}
+	return []byte(*v.Attributes.Value), v.Attributes.VersionID, nil
+}
+
+// Put sets a value of a given variable.
+func (c *client) Put(ctx context.Context, key string, value []byte) error {
+	v, err := c.findVariable(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.put(ctx, key, value, v)
+	return err
+}
+
+// PutIfVersion sets a value of a given variable only if its version-id
+// matches a given one.
+// The API has no conditional update, so the version-id is checked right
+// before updating the variable. This detects most concurrent updates, but
+// there is still a small window between the check and the update.
+func (c *client) PutIfVersion(ctx context.Context, key string, value []byte, version string) (string, error) {
+	v, err := c.findVariable(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	current := ""
+	if v != nil {
+		current = v.Attributes.VersionID
+		if len(current) == 0 {
+			return "", fmt.Errorf("failed to update tfc variable %s: version-id is not supported by the API", key)
+		}
+	}
+	if current != version {
+		return "", fmt.Errorf("tfc variable %s has been updated by someone else: expected version-id %q, but got %q: %w", key, version, current, storage.ErrVersionConflict)
+	}
+
+	updated, err := c.put(ctx, key, value, v)
+	if err != nil {
+		return "", err
+	}
+	if len(updated.Attributes.VersionID) == 0 {
+		return "", fmt.Errorf("failed to update tfc variable %s: version-id is not supported by the API", key)
+	}
+	return updated.Attributes.VersionID, nil
+}
+
+// put creates a given variable if v is nil, otherwise updates v, and returns
+// the variable in the response.
+func (c *client) put(ctx context.Context, key string, value []byte, v *variable) (*variable, error) {
+	workspaceID, err := c.resolveWorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(value)
+	if v == nil {
+		payload, err := json.Marshal(map[string]variable{
+			"data": {
+				Type: "vars",
+				Attributes: variableAttributes{
+					Key:         key,
+					Value:       &s,
+					Description: variableDescription,
+					Category:    variableCategory,
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		res, err := c.do(ctx, http.MethodPost, "/api/v2/workspaces/"+workspaceID+"/vars", payload)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode != http.StatusCreated {
+			return nil, res.Error("failed to create tfc variable " + key)
+		}
+		return parseVariable(res.Body)
+	}
+
+	payload, err := json.Marshal(map[string]variable{
+		"data": {
+			ID:         v.ID,
+			Type:       "vars",
+			Attributes: variableAttributes{Value: &s},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.do(ctx, http.MethodPatch, "/api/v2/workspaces/"+workspaceID+"/vars/"+v.ID, payload)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, res.Error("failed to update tfc variable " + key)
+	}
+	return parseVariable(res.Body)
+}
+
+// parseVariable parses a response body of a variable.
+func parseVariable(body []byte) (*variable, error) {
+	var res struct {
+		Data variable `json:"data"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("failed to parse tfc response: %s", err)
+	}
+	return &res.Data, nil
+}
+
+// Delete deletes a given variable.
+// If the variable does not exist, no-op.
+func (c *client) Delete(ctx context.Context, key string) error {
+	v, err := c.findVariable(ctx, key)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	workspaceID, err := c.resolveWorkspaceID(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.do(ctx, http.MethodDelete, "/api/v2/workspaces/"+workspaceID+"/vars/"+v.ID, nil)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusNotFound {
+		return res.Error("failed to delete tfc variable " + key)
+	}
+	return nil
+}
+
+// findVariable returns a terraform variable of a given key in the workspace.
+// It returns nil if not found.
+func (c *client) findVariable(ctx context.Context, key string) (*variable, error) {
+	workspaceID, err := c.resolveWorkspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.do(ctx, http.MethodGet, "/api/v2/workspaces/"+workspaceID+"/vars", nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, res.Error("failed to list tfc variables of workspace " + c.workspace)
+	}
+
+	var vars struct {
+		Data []variable `json:"data"`
+	}
+	if err := json.Unmarshal(res.Body, &vars); err != nil {
+		return nil, fmt.Errorf("failed to parse tfc response: %s", err)
+	}
+	for _, v := range vars.Data {
+		if v.Attributes.Key == key && v.Attributes.Category == variableCategory {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+// resolveWorkspaceID returns an ID of the workspace.
+// The result is cached because it never changes.
+func (c *client) resolveWorkspaceID(ctx context.Context) (string, error) {
+	if len(c.workspaceID) != 0 {
+		return c.workspaceID, nil
+	}
+
+	path := "/api/v2/organizations/" + url.PathEscape(c.organization) + "/workspaces/" + url.PathEscape(c.workspace)
+	res, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return "", err
+	}
+	// Terraform Cloud returns 404 Not Found also if the token is not
+	// authorized to read the workspace.
+	if res.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("failed to get tfc workspace %s/%s: not found or not authorized", c.organization, c.workspace)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", res.Error("failed to get tfc workspace " + c.organization + "/" + c.workspace)
+	}
+
+	var ws struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(res.Body, &ws); err != nil {
+		return "", fmt.Errorf("failed to parse tfc response: %s", err)
+	}
+	if len(ws.Data.ID) == 0 {
+		return "", fmt.Errorf("failed to get tfc workspace %s/%s: unexpected response: %s", c.organization, c.workspace, string(res.Body))
+	}
+	c.workspaceID = ws.Data.ID
+	return c.workspaceID, nil
+}
+
+// do sends an HTTP request to Terraform Cloud and returns a response with its
+// body. A request which is rate limited, or an idempotent request which fails
+// with a server error, is retried with exponential backoff up to the maximum
+// number of retries. POST and PATCH are never retried on a server error,
+// because the request may have been processed.
+func (c *client) do(ctx context.Context, method string, path string, payload []byte) (*httpapi.Response, error) {
+	header := http.Header{"Accept": []string{contentType}}
+	if payload != nil {
+		header.Set("Content-Type", contentType)
+	}
+	return c.api.Do(ctx, &httpapi.Request{
+		Method:     method,
+		URL:        c.address + path,
+		Header:     header,
+		Body:       payload,
+		Idempotent: method != http.MethodPost && method != http.MethodPatch,
+	})
+}
@@ -0,0 +1,357 @@
+package tfc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// fakeTFC is a minimal fake server of Terraform Cloud workspace variables API.
+type fakeTFC struct {
+	mu    sync.Mutex
+	vars  map[string]variable
+	seq   int
+	token string
+	// failures is a number of requests which fail with 503 before success.
+	failures int
+	// failMethod limits failures to a given method if set.
+	failMethod string
+	requests   int
+}
+
+func (f *fakeTFC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests++
+	if f.failures > 0 && (f.failMethod == "" || f.failMethod == r.Method) {
+		f.failures--
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+f.token {
+		http.Error(w, `{"errors":[{"status":"401","title":"unauthorized"}]}`, http.StatusUnauthorized)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.URL.Path == "/api/v2/organizations/example-org/workspaces/tfmigrate" && r.Method == http.MethodGet:
+		_, _ = w.Write([]byte(`{"data":{"id":"ws-123","type":"workspaces"}}`))
+
+	case r.URL.Path == "/api/v2/workspaces/ws-123/vars":
+		switch r.Method {
+		case http.MethodGet:
+			data := []variable{}
+			for _, v := range f.vars {
+				data = append(data, v)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+		case http.MethodPost:
+			var req struct {
+				Data variable `json:"data"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.seq++
+			v := req.Data
+			v.ID = fmt.Sprintf("var-%d", f.seq)
+			v.Attributes.VersionID = fmt.Sprintf("v-%d", f.seq)
+			f.vars[v.ID] = v
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": v})
+		}
+
+	case strings.HasPrefix(r.URL.Path, "/api/v2/workspaces/ws-123/vars/"):
+		id := strings.TrimPrefix(r.URL.Path, "/api/v2/workspaces/ws-123/vars/")
+		v, ok := f.vars[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			var req struct {
+				Data variable `json:"data"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.seq++
+			v.Attributes.Value = req.Data.Attributes.Value
+			v.Attributes.VersionID = fmt.Sprintf("v-%d", f.seq)
+			f.vars[id] = v
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": v})
+		case http.MethodDelete:
+			delete(f.vars, id)
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestClient returns a new client for a given fake server.
+func newTestClient(t *testing.T, server *httptest.Server, token string) *client {
+	t.Helper()
+	c, err := newClient(&Config{
+		Hostname:     server.URL,
+		Organization: "example-org",
+		Workspace:    "tfmigrate",
+		Token:        token,
+	})
+	if err != nil {
+		t.Fatalf("failed to new client: %s", err)
+	}
+	c.(*client).api.RetryWait = time.Millisecond
+	return c.(*client)
+}
+
+func TestClient(t *testing.T) {
+	fake := &fakeTFC{vars: map[string]variable{}, token: "secret"}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	c := newTestClient(t, server, "secret")
+	ctx := context.Background()
+
+	// variable does not exist
+	got, err := c.Get(ctx, "tfmigrate_history")
+	if err != nil || got != nil {
+		t.Fatalf("unexpected get result: %s, %v", string(got), err)
+	}
+
+	// create
+	if err := c.Put(ctx, "tfmigrate_history", []byte(`{"foo":"bar"}`)); err != nil {
+		t.Fatalf("failed to put: %s", err)
+	}
+	v := fake.vars["var-1"]
+	if v.Attributes.Key != "tfmigrate_history" || v.Attributes.Category != "env" || v.Attributes.Sensitive {
+		t.Fatalf("unexpected variable: %#v", v)
+	}
+
+	// update and get
+	if err := c.Put(ctx, "tfmigrate_history", []byte("baz")); err != nil {
+		t.Fatalf("failed to put: %s", err)
+	}
+	if len(fake.vars) != 1 {
+		t.Fatalf("expected to update the variable, but got: %#v", fake.vars)
+	}
+	got, err = c.Get(ctx, "tfmigrate_history")
+	if err != nil || string(got) != "baz" {
+		t.Fatalf("unexpected get result: %s, %v", string(got), err)
+	}
+
+	// compare and swap
+	_, version, err := c.GetWithVersion(ctx, "tfmigrate_history")
+	if err != nil || version != "v-2" {
+		t.Fatalf("unexpected version: %s, %v", version, err)
+	}
+	version, err = c.PutIfVersion(ctx, "tfmigrate_history", []byte("qux"), version)
+	if err != nil || version != "v-3" {
+		t.Fatalf("unexpected put result: %s, %v", version, err)
+	}
+	if _, err := c.PutIfVersion(ctx, "tfmigrate_history", []byte("quux"), "v-2"); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("expected a version conflict, but got: %v", err)
+	}
+	if _, err := c.PutIfVersion(ctx, "tfmigrate_history", []byte("quux"), ""); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("expected a version conflict, but got: %v", err)
+	}
+	got, err = c.Get(ctx, "tfmigrate_history")
+	if err != nil || string(got) != "qux" {
+		t.Fatalf("unexpected get result: %s, %v", string(got), err)
+	}
+
+	// delete
+	if err := c.Delete(ctx, "tfmigrate_history"); err != nil {
+		t.Fatalf("failed to delete: %s", err)
+	}
+	if len(fake.vars) != 0 {
+		t.Fatal("variable was not deleted")
+	}
+	if err := c.Delete(ctx, "tfmigrate_history"); err != nil {
+		t.Fatalf("failed to delete a variable which does not exist: %s", err)
+	}
+
+	// sensitive
+	value := "secret"
+	fake.vars["var-9"] = variable{ID: "var-9", Type: "vars", Attributes: variableAttributes{Key: "sensitive", Value: nil, Category: "env", Sensitive: true}}
+	if _, err := c.Get(ctx, "sensitive"); err == nil {
+		t.Fatal("expected to return an error for a sensitive variable, but no error")
+	}
+	// a terraform variable with the same key is ignored.
+	fake.vars["var-10"] = variable{ID: "var-10", Type: "vars", Attributes: variableAttributes{Key: "tfvar", Value: &value, Category: "terraform"}}
+	if got, err := c.Get(ctx, "tfvar"); err != nil || got != nil {
+		t.Fatalf("unexpected get result: %s, %v", string(got), err)
+	}
+
+	// invalid token
+	c = newTestClient(t, server, "invalid")
+	if _, err := c.Get(ctx, "tfmigrate_history"); err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	cases := []struct {
+		desc       string
+		put        bool
+		failures   int
+		failMethod string
+		maxRetries int
+		requests   int
+		ok         bool
+	}{
+		{
+			desc:       "retry and success",
+			failures:   2,
+			maxRetries: 2,
+			requests:   4,
+			ok:         true,
+		},
+		{
+			desc:       "exceed max retries",
+			failures:   3,
+			maxRetries: 2,
+			requests:   3,
+			ok:         false,
+		},
+		{
+			desc:       "do not retry a server error of post",
+			put:        true,
+			failures:   1,
+			failMethod: http.MethodPost,
+			maxRetries: 2,
+			requests:   3,
+			ok:         false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fake := &fakeTFC{vars: map[string]variable{}, token: "secret", failures: tc.failures, failMethod: tc.failMethod}
+			server := httptest.NewServer(fake)
+			t.Cleanup(server.Close)
+
+			c := newTestClient(t, server, "secret")
+			c.api.MaxRetries = tc.maxRetries
+			var err error
+			if tc.put {
+				err = c.Put(context.Background(), "tfmigrate_history", []byte("foo"))
+			} else {
+				_, err = c.Get(context.Background(), "tfmigrate_history")
+			}
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if fake.requests != tc.requests {
+				t.Errorf("got requests: %d, want: %d", fake.requests, tc.requests)
+			}
+		})
+	}
+}
+
+func TestNewClientToken(t *testing.T) {
+	cases := []struct {
+		desc        string
+		config      *Config
+		env         map[string]string
+		credentials string
+		want        string
+		ok          bool
+	}{
+		{
+			desc:   "config",
+			config: &Config{Token: "foo"},
+			env:    map[string]string{"TF_TOKEN_app_terraform_io": "bar"},
+			want:   "foo",
+			ok:     true,
+		},
+		{
+			desc:   "host specific env",
+			config: &Config{Hostname: "tfe.example-corp.com"},
+			env: map[string]string{
+				"TF_TOKEN_tfe_example__corp_com": "bar",
+				"TFE_TOKEN":                      "baz",
+			},
+			want: "bar",
+			ok:   true,
+		},
+		{
+			desc:   "tfe env",
+			config: &Config{},
+			env:    map[string]string{"TFE_TOKEN": "baz"},
+			want:   "baz",
+			ok:     true,
+		},
+		{
+			desc:        "credentials file",
+			config:      &Config{},
+			env:         map[string]string{},
+			credentials: `{"credentials":{"app.terraform.io":{"token":"qux"}}}`,
+			want:        "qux",
+			ok:          true,
+		},
+		{
+			desc:   "no token",
+			config: &Config{},
+			env:    map[string]string{},
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("TF_TOKEN_app_terraform_io", "")
+			t.Setenv("TFE_TOKEN", "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			if len(tc.credentials) != 0 {
+				dir := filepath.Join(home, ".terraform.d")
+				if err := os.MkdirAll(dir, 0700); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "credentials.tfrc.json"), []byte(tc.credentials), 0600); err != nil {
+					t.Fatalf("failed to write credentials: %s", err)
+				}
+			}
+
+			c, err := newClient(tc.config)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				got := c.(*client).token
+				if got != tc.want {
+					t.Errorf("got: %s, want: %s", got, tc.want)
+				}
+			}
+		})
+	}
+}
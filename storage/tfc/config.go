@@ -0,0 +1,43 @@
+package tfc
+
+import (
+	"log"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// Config is a config for Terraform Cloud storage.
+// The history is stored in a workspace variable, so that organizations
+// already on Terraform Cloud don't need a separate storage.
+// This is expected to have a subset of options of Terraform cloud block.
+// https://developer.hashicorp.com/terraform/cli/cloud/settings
+type Config struct {
+	// Hostname of Terraform Cloud or Terraform Enterprise.
+	// Default to `app.terraform.io`.
+	Hostname string `hcl:"hostname,optional"`
+	// Name of the organization.
+	Organization string `hcl:"organization"`
+	// Name of the workspace where the history is stored.
+	Workspace string `hcl:"workspace"`
+	// API token.
+	// Default to the TF_TOKEN_<hostname> environment variable, the TFE_TOKEN
+	// environment variable, or a token in the credentials file written by
+	// `terraform login`.
+	Token string `hcl:"token,optional"`
+	// Key of the workspace variable. Default to `tfmigrate_history`.
+	Key string `hcl:"key,optional"`
+	// Maximum number of retries for an API request which is rate limited or
+	// fails with a server error. Default to 5.
+	MaxRetries int `hcl:"max_retries,optional"`
+}
+
+// Config implements a storage.Config.
+var _ storage.Config = (*Config)(nil)
+
+// NewStorage returns a new instance of storage.Storage.
+func (c *Config) NewStorage() (storage.Storage, error) {
+	if len(c.Token) != 0 {
+		log.Printf("[WARN] [storage@tfc] a static token is set in the config. Consider using TF_TOKEN_<hostname>, TFE_TOKEN or terraform login instead\n")
+	}
+	return NewStorage(c, nil)
+}
@@ -0,0 +1,106 @@
+package tfc
+
+import (
+	"context"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// probeKeySuffix is a suffix of the probe variable.
+// A variable key cannot contain dots, so we don't use storage.ProbeKeySuffix.
+const probeKeySuffix = "_tfmigrate_probe"
+
+// Storage is a storage.Storage implementation for Terraform Cloud.
+type Storage struct {
+	// config is a storage config for Terraform Cloud.
+	config *Config
+	// client is an instance of Client interface to call API.
+	// It is intended to be replaced with a mock for testing.
+	client Client
+}
+
+var _ storage.Storage = (*Storage)(nil)
+var _ storage.VersionedStorage = (*Storage)(nil)
+
+// NewStorage returns a new instance of Storage.
+func NewStorage(config *Config, client Client) (*Storage, error) {
+	if client == nil {
+		var err error
+		client, err = newClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Storage{
+		config: config,
+		client: client,
+	}
+	return s, nil
+}
+
+// key returns a key of the workspace variable.
+func (s *Storage) key() string {
+	if len(s.config.Key) == 0 {
+		return DefaultKey
+	}
+	return s.config.Key
+}
+
+// Write writes migration history data to storage.
+func (s *Storage) Write(ctx context.Context, b []byte) error {
+	return s.client.Put(ctx, s.key(), b)
+}
+
+// Read reads migration history data from storage.
+// If the variable does not exist, it is assumed to be uninitialized and
+// returns an empty array instead of an error.
+func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, err := s.client.Get(ctx, s.key())
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, nil
+}
+
+// ReadWithVersion reads migration history data with its version-id.
+// If the variable does not exist, it returns an empty array and an empty
+// version.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, string, error) {
+	b, version, err := s.client.GetWithVersion(ctx, s.key())
+	if err != nil {
+		return nil, "", err
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, version, nil
+}
+
+// WriteIfVersion writes migration history data only if the version-id of
+// the variable matches a given version. An empty version means that the
+// variable must not exist.
+func (s *Storage) WriteIfVersion(ctx context.Context, b []byte, version string) (string, error) {
+	return s.client.PutIfVersion(ctx, s.key(), b, version)
+}
+
+// Ping checks permissions to read the history variable, and to write and
+// delete a probe variable next to it.
+func (s *Storage) Ping(ctx context.Context) (*storage.PingResult, error) {
+	probe := s.key() + probeKeySuffix
+	return storage.Probe(ctx,
+		func(ctx context.Context) error {
+			_, err := s.Read(ctx)
+			return err
+		},
+		func(ctx context.Context, b []byte) error {
+			return s.client.Put(ctx, probe, b)
+		},
+		func(ctx context.Context) error {
+			return s.client.Delete(ctx, probe)
+		},
+	)
+}
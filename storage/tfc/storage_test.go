@@ -0,0 +1,267 @@
+package tfc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/storagetest"
+)
+
+// mockClient is a mock implementation for testing.
+// It keeps values in memory.
+type mockClient struct {
+	data      map[string]string
+	versions  map[string]int
+	err       error
+	deleteErr error
+}
+
+// Get returns a value in memory.
+func (c *mockClient) Get(_ context.Context, key string) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	v, ok := c.data[key]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(v), nil
+}
+
+// Put sets a value in memory.
+func (c *mockClient) Put(_ context.Context, key string, value []byte) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.data[key] = string(value)
+	if c.versions == nil {
+		c.versions = map[string]int{}
+	}
+	c.versions[key]++
+	return nil
+}
+
+// GetWithVersion returns a value in memory with its version.
+func (c *mockClient) GetWithVersion(ctx context.Context, key string) ([]byte, string, error) {
+	b, err := c.Get(ctx, key)
+	if err != nil || b == nil {
+		return nil, "", err
+	}
+	return b, fmt.Sprintf("v-%d", c.versions[key]), nil
+}
+
+// PutIfVersion sets a value in memory only if its version matches.
+func (c *mockClient) PutIfVersion(ctx context.Context, key string, value []byte, version string) (string, error) {
+	_, current, err := c.GetWithVersion(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if current != version {
+		return "", fmt.Errorf("version mismatch: %w", storage.ErrVersionConflict)
+	}
+	if err := c.Put(ctx, key, value); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("v-%d", c.versions[key]), nil
+}
+
+// Delete deletes a value in memory.
+func (c *mockClient) Delete(_ context.Context, key string) error {
+	if c.deleteErr != nil {
+		return c.deleteErr
+	}
+	delete(c.data, key)
+	return nil
+}
+
+func TestStorageWrite(t *testing.T) {
+	cases := []struct {
+		desc     string
+		config   *Config
+		client   *mockClient
+		contents []byte
+		key      string
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Organization: "example-org",
+				Workspace:    "tfmigrate",
+			},
+			client:   &mockClient{data: map[string]string{}},
+			contents: []byte("foo"),
+			key:      "tfmigrate_history",
+			ok:       true,
+		},
+		{
+			desc: "custom key",
+			config: &Config{
+				Organization: "example-org",
+				Workspace:    "tfmigrate",
+				Key:          "history",
+			},
+			client:   &mockClient{data: map[string]string{}},
+			contents: []byte("foo"),
+			key:      "history",
+			ok:       true,
+		},
+		{
+			desc: "api error",
+			config: &Config{
+				Organization: "example-org",
+				Workspace:    "tfmigrate",
+			},
+			client: &mockClient{
+				err: fmt.Errorf("unauthorized"),
+			},
+			contents: []byte("foo"),
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			err = s.Write(context.Background(), tc.contents)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				got := tc.client.data[tc.key]
+				if got != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", got, string(tc.contents))
+				}
+			}
+		})
+	}
+}
+
+func TestStorageRead(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		client *mockClient
+		want   []byte
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Organization: "example-org",
+				Workspace:    "tfmigrate",
+			},
+			client: &mockClient{data: map[string]string{
+				"tfmigrate_history": "foo",
+			}},
+			want: []byte("foo"),
+			ok:   true,
+		},
+		{
+			desc: "variable does not exist",
+			config: &Config{
+				Organization: "example-org",
+				Workspace:    "tfmigrate",
+			},
+			client: &mockClient{data: map[string]string{}},
+			want:   []byte{},
+			ok:     true,
+		},
+		{
+			desc: "api error",
+			config: &Config{
+				Organization: "example-org",
+				Workspace:    "tfmigrate",
+			},
+			client: &mockClient{
+				err: fmt.Errorf("unauthorized"),
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, err := s.Read(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && string(got) != string(tc.want) {
+				t.Errorf("got: %s, want: %s", string(got), string(tc.want))
+			}
+		})
+	}
+}
+
+func TestStoragePing(t *testing.T) {
+	cases := []struct {
+		desc   string
+		client *mockClient
+		ok     bool
+	}{
+		{
+			desc:   "simple",
+			client: &mockClient{data: map[string]string{}},
+			ok:     true,
+		},
+		{
+			desc: "delete error",
+			client: &mockClient{
+				data:      map[string]string{},
+				deleteErr: fmt.Errorf("unauthorized"),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(&Config{Organization: "example-org", Workspace: "tfmigrate"}, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.Ping(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				if _, ok := tc.client.data["tfmigrate_history"+probeKeySuffix]; ok {
+					t.Error("probe variable was not deleted")
+				}
+			}
+		})
+	}
+}
+
+func TestStorageContract(t *testing.T) {
+	storagetest.TestStorage(t, func(t *testing.T) storage.Storage {
+		config := &Config{
+			Organization: "example-org",
+			Workspace:    "tfmigrate",
+		}
+		s, err := NewStorage(config, &mockClient{data: map[string]string{}})
+		if err != nil {
+			t.Fatalf("failed to new storage: %s", err)
+		}
+		return s
+	})
+}
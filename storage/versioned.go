@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrVersionConflict is an error returned by a conditional write when the
+// history file has been updated by someone else since it was read.
+var ErrVersionConflict = errors.New("history file has been updated by someone else")
+
+// VersionedStorage is an optional interface for Storage which supports
+// compare-and-swap semantics on the history file.
+// A version is an opaque string such as an ETag or an object generation.
+// It is empty if the history file does not exist.
+type VersionedStorage interface {
+	Storage
+	// ReadWithVersion reads migration history data with its current version.
+	ReadWithVersion(ctx context.Context) ([]byte, string, error)
+	// WriteIfVersion writes migration history data only if the current version
+	// matches a given one, and returns a new version.
+	// An empty version means that the history file must not exist.
+	// It returns an error wrapping ErrVersionConflict on mismatch.
+	WriteIfVersion(ctx context.Context, b []byte, version string) (string, error)
+}
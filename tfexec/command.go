@@ -2,6 +2,7 @@ package tfexec
 
 import (
 	"bytes"
+	"io"
 	"os/exec"
 )
 
@@ -18,6 +19,9 @@ type Command interface {
 	Stderr() string
 	// Args returns args of the command.
 	Args() []string
+	// TeeStdout copies outputs of stdout to a given writer while running.
+	// It must be called before Run.
+	TeeStdout(w io.Writer)
 }
 
 // command implements the Command interface.
@@ -51,3 +55,8 @@ func (c *command) Stderr() string {
 func (c *command) Args() []string {
 	return c.osExecCmd.Args
 }
+
+// TeeStdout copies outputs of stdout to a given writer while running.
+func (c *command) TeeStdout(w io.Writer) {
+	c.osExecCmd.Stdout = io.MultiWriter(c.stdout, w)
+}
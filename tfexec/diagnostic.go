@@ -0,0 +1,216 @@
+package tfexec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// DiagnosticCategory is a category of a diagnostic of terraform.
+// It's intended to be used for branching a retry policy and error messages.
+type DiagnosticCategory string
+
+const (
+	// DiagnosticCategoryUnknown means the diagnostic is not classified.
+	DiagnosticCategoryUnknown DiagnosticCategory = "unknown"
+	// DiagnosticCategoryStateLock means it failed to acquire a state lock.
+	// It's typically transient and can be retried.
+	DiagnosticCategoryStateLock DiagnosticCategory = "state_lock"
+	// DiagnosticCategoryAuthFailure means it failed to authenticate or was
+	// not authorized.
+	DiagnosticCategoryAuthFailure DiagnosticCategory = "auth_failure"
+	// DiagnosticCategoryVersionMismatch means a version of terraform,
+	// providers or state doesn't match.
+	DiagnosticCategoryVersionMismatch DiagnosticCategory = "version_mismatch"
+	// DiagnosticCategorySyntaxError means configuration is invalid.
+	DiagnosticCategorySyntaxError DiagnosticCategory = "syntax_error"
+)
+
+// Diagnostic is a classified diagnostic of terraform.
+type Diagnostic struct {
+	// Severity is either error or warning.
+	Severity string
+	// Summary is a short description of the diagnostic.
+	Summary string
+	// Detail is a detailed description of the diagnostic.
+	Detail string
+	// Category is a classified category of the diagnostic.
+	Category DiagnosticCategory
+}
+
+// diagnosticPatterns is a list of patterns to classify diagnostics.
+// Patterns are matched against a lowercased summary and detail in order,
+// and the first matched category wins.
+var diagnosticPatterns = []struct {
+	category DiagnosticCategory
+	patterns []string
+}{
+	{
+		category: DiagnosticCategoryStateLock,
+		patterns: []string{
+			"state lock",
+			"error locking state",
+			"conditionalcheckfailedexception",
+			"lock info:",
+		},
+	},
+	{
+		category: DiagnosticCategoryAuthFailure,
+		patterns: []string{
+			"no valid credential sources",
+			"invalid credentials",
+			"unauthorized",
+			"access denied",
+			"accessdenied",
+			"invalidclienttokenid",
+			"expiredtoken",
+			"token has expired",
+			"could not find default credentials",
+			"required token could not be found",
+			"authentication failed",
+		},
+	},
+	{
+		category: DiagnosticCategoryVersionMismatch,
+		patterns: []string{
+			"unsupported terraform core version",
+			"unsupported state file format",
+			"state snapshot was created by terraform",
+			"incompatible provider version",
+			"inconsistent dependency lock file",
+			"doesn't match any of the checksums",
+			"does not match any of the checksums",
+		},
+	},
+	{
+		category: DiagnosticCategorySyntaxError,
+		patterns: []string{
+			"unsupported argument",
+			"unsupported block type",
+			"missing required argument",
+			"argument or block definition required",
+			"invalid expression",
+			"invalid block definition",
+			"unclosed configuration block",
+			"invalid character",
+			"reference to undeclared",
+		},
+	},
+}
+
+// ClassifyDiagnostic returns a category for a given summary and detail of a
+// diagnostic with heuristics.
+func ClassifyDiagnostic(summary string, detail string) DiagnosticCategory {
+	text := strings.ToLower(summary + "\n" + detail)
+	for _, p := range diagnosticPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(text, pattern) {
+				return p.category
+			}
+		}
+	}
+	return DiagnosticCategoryUnknown
+}
+
+// ParseDiagnostics parses outputs of terraform and returns a list of
+// classified diagnostics. If stdout contains JSON diagnostics of the
+// machine-readable UI, they are used. Otherwise, it parses human-readable
+// diagnostics in stderr.
+func ParseDiagnostics(stdout string, stderr string) []Diagnostic {
+	diags := parseJSONDiagnostics(stdout)
+	if len(diags) > 0 {
+		return diags
+	}
+	return parseTextDiagnostics(stderr)
+}
+
+// parseJSONDiagnostics parses diagnostics of the machine-readable UI.
+func parseJSONDiagnostics(stdout string) []Diagnostic {
+	diags := []Diagnostic{}
+	for _, l := range strings.Split(stdout, "\n") {
+		line := bytes.TrimSpace([]byte(l))
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		m, err := ParseUIMessage(line)
+		if err != nil || m.Type != "diagnostic" || m.Diagnostic == nil {
+			continue
+		}
+		diags = append(diags, Diagnostic{
+			Severity: m.Diagnostic.Severity,
+			Summary:  m.Diagnostic.Summary,
+			Detail:   m.Diagnostic.Detail,
+			Category: ClassifyDiagnostic(m.Diagnostic.Summary, m.Diagnostic.Detail),
+		})
+	}
+	return diags
+}
+
+// parseTextDiagnostics parses human-readable diagnostics such as:
+//
+//	╷
+//	│ Error: Error acquiring the state lock
+//	│
+//	│ Error message: ...
+//	╵
+//
+// Older versions of terraform print diagnostics without the box.
+func parseTextDiagnostics(stderr string) []Diagnostic {
+	diags := []Diagnostic{}
+	var current *Diagnostic
+	detail := []string{}
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Detail = strings.TrimSpace(strings.Join(detail, "\n"))
+		current.Category = ClassifyDiagnostic(current.Summary, current.Detail)
+		diags = append(diags, *current)
+		current = nil
+		detail = []string{}
+	}
+
+	for _, l := range strings.Split(stderr, "\n") {
+		if strings.HasPrefix(l, "╵") {
+			flush()
+			continue
+		}
+		line := strings.TrimPrefix(l, "│")
+		line = strings.TrimPrefix(line, "╷")
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "Error: "):
+			flush()
+			current = &Diagnostic{Severity: "error", Summary: strings.TrimPrefix(line, "Error: ")}
+		case strings.HasPrefix(line, "Warning: "):
+			flush()
+			current = &Diagnostic{Severity: "warning", Summary: strings.TrimPrefix(line, "Warning: ")}
+		case current != nil:
+			detail = append(detail, line)
+		}
+	}
+	flush()
+
+	return diags
+}
+
+// ClassifyError returns a category of the first error diagnostic of a given
+// error returned by the terraform command. It returns
+// DiagnosticCategoryUnknown if the error is not an ExitError or no error
+// diagnostic is found.
+func ClassifyError(err error) DiagnosticCategory {
+	var exitErr ExitError
+	if !errors.As(err, &exitErr) {
+		return DiagnosticCategoryUnknown
+	}
+
+	for _, d := range ParseDiagnostics(exitErr.Stdout(), exitErr.Stderr()) {
+		if d.Severity == "error" {
+			return d.Category
+		}
+	}
+
+	return DiagnosticCategoryUnknown
+}
@@ -0,0 +1,195 @@
+package tfexec
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestClassifyDiagnostic(t *testing.T) {
+	cases := []struct {
+		desc    string
+		summary string
+		detail  string
+		want    DiagnosticCategory
+	}{
+		{
+			desc:    "state lock",
+			summary: "Error acquiring the state lock",
+			detail:  "Error message: ConditionalCheckFailedException: The conditional request failed",
+			want:    DiagnosticCategoryStateLock,
+		},
+		{
+			desc:    "auth failure",
+			summary: "No valid credential sources found",
+			detail:  "Please see https://registry.terraform.io/providers/hashicorp/aws",
+			want:    DiagnosticCategoryAuthFailure,
+		},
+		{
+			desc:    "version mismatch",
+			summary: "Unsupported Terraform Core version",
+			detail:  "This configuration does not support Terraform version 1.0.0.",
+			want:    DiagnosticCategoryVersionMismatch,
+		},
+		{
+			desc:    "syntax error",
+			summary: "Unsupported argument",
+			detail:  `An argument named "foo" is not expected here.`,
+			want:    DiagnosticCategorySyntaxError,
+		},
+		{
+			desc:    "unknown",
+			summary: "Invalid provider configuration",
+			detail:  "",
+			want:    DiagnosticCategoryUnknown,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := ClassifyDiagnostic(tc.summary, tc.detail)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	cases := []struct {
+		desc   string
+		stdout string
+		stderr string
+		want   []Diagnostic
+	}{
+		{
+			desc: "json",
+			stdout: `{"@level":"info","@message":"Terraform 1.0.0","type":"version"}
+{"@level":"error","@message":"Error: Unsupported argument","diagnostic":{"severity":"error","summary":"Unsupported argument","detail":"An argument named \"foo\" is not expected here."},"type":"diagnostic"}
+`,
+			stderr: "",
+			want: []Diagnostic{
+				{
+					Severity: "error",
+					Summary:  "Unsupported argument",
+					Detail:   `An argument named "foo" is not expected here.`,
+					Category: DiagnosticCategorySyntaxError,
+				},
+			},
+		},
+		{
+			desc:   "text with box",
+			stdout: "",
+			stderr: `╷
+│ Warning: Deprecated attribute
+│
+│ foo is deprecated.
+╵
+╷
+│ Error: Error acquiring the state lock
+│
+│ Error message: resource temporarily unavailable
+│ Lock Info:
+│   ID:        1234
+╵
+`,
+			want: []Diagnostic{
+				{
+					Severity: "warning",
+					Summary:  "Deprecated attribute",
+					Detail:   "foo is deprecated.",
+					Category: DiagnosticCategoryUnknown,
+				},
+				{
+					Severity: "error",
+					Summary:  "Error acquiring the state lock",
+					Detail:   "Error message: resource temporarily unavailable\nLock Info:\nID:        1234",
+					Category: DiagnosticCategoryStateLock,
+				},
+			},
+		},
+		{
+			desc:   "legacy text",
+			stdout: "",
+			stderr: `
+Error: Unsupported Terraform Core version
+
+This configuration does not support Terraform version 0.12.31.
+`,
+			want: []Diagnostic{
+				{
+					Severity: "error",
+					Summary:  "Unsupported Terraform Core version",
+					Detail:   "This configuration does not support Terraform version 0.12.31.",
+					Category: DiagnosticCategoryVersionMismatch,
+				},
+			},
+		},
+		{
+			desc:   "no diagnostics",
+			stdout: "",
+			stderr: "",
+			want:   []Diagnostic{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := ParseDiagnostics(tc.stdout, tc.stderr)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		want         DiagnosticCategory
+	}{
+		{
+			desc: "state lock",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "pull"},
+					stderr:   "\nError: Error locking state: Error acquiring the state lock\n",
+					exitCode: 1,
+				},
+			},
+			want: DiagnosticCategoryStateLock,
+		},
+		{
+			desc: "no diagnostics",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "pull"},
+					exitCode: 1,
+				},
+			},
+			want: DiagnosticCategoryUnknown,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			_, err := terraformCLI.StatePull(context.Background())
+			if err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			got := ClassifyError(err)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+
+	if got := ClassifyError(errors.New("foo")); got != DiagnosticCategoryUnknown {
+		t.Errorf("got: %s, want: %s", got, DiagnosticCategoryUnknown)
+	}
+}
@@ -15,6 +15,10 @@ type ExitError interface {
 	Error() string
 	// ExitCode returns an exit status code of the command.
 	ExitCode() int
+	// Stdout returns outputs of stdout of the command.
+	Stdout() string
+	// Stderr returns outputs of stderr of the command.
+	Stderr() string
 }
 
 // exitError implements the ExitError interface.
@@ -48,3 +52,13 @@ func (e *exitError) Error() string {
 func (e *exitError) ExitCode() int {
 	return e.osExecErr.ExitCode()
 }
+
+// Stdout returns outputs of stdout of the command.
+func (e *exitError) Stdout() string {
+	return e.cmd.Stdout()
+}
+
+// Stderr returns outputs of stderr of the command.
+func (e *exitError) Stderr() string {
+	return e.cmd.Stderr()
+}
@@ -0,0 +1,113 @@
+package tfexec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExecContainer is a config for running the terraform command inside a
+// container instead of the host, so that a migration runner doesn't need to
+// install terraform directly.
+type ExecContainer struct {
+	// Runtime is a container runtime command such as docker or podman.
+	// Default to docker.
+	Runtime string
+	// Image is a container image which contains the terraform command.
+	Image string
+	// User is a user or uid to run the command inside the container. It's
+	// passed to the --user flag of the container runtime. It's optional.
+	// Note that temporary files are created with mode 0600, so the user must
+	// have the same uid as the tfmigrate process to read them.
+	// e.g.) 1000:1000
+	User string
+}
+
+// newExecContainerFromEnv returns a new ExecContainer from environment
+// variables. It returns nil if TFMIGRATE_EXEC_CONTAINER_IMAGE is not set.
+func newExecContainerFromEnv() *ExecContainer {
+	image := os.Getenv("TFMIGRATE_EXEC_CONTAINER_IMAGE")
+	if len(image) == 0 {
+		return nil
+	}
+
+	return &ExecContainer{
+		Runtime: os.Getenv("TFMIGRATE_EXEC_CONTAINER_RUNTIME"),
+		Image:   image,
+		User:    os.Getenv("TFMIGRATE_EXEC_CONTAINER_USER"),
+	}
+}
+
+// containerEnvPrefixes is a list of prefixes of environment variables passed
+// to the container. We don't pass all environment variables such as PATH and
+// HOME, because they are specific to the host.
+var containerEnvPrefixes = []string{
+	"TF_",
+	"AWS_",
+	"GOOGLE_",
+	"CLOUDSDK_",
+	"ARM_",
+}
+
+// wrap returns a command name and arguments to run a given command inside the
+// container. The working directory and the temporary directory are mounted at
+// the same paths as the host, because we pass paths of temporary files to the
+// terraform command. Environment variables are passed by name, so that their
+// values don't appear in the command line.
+func (c *ExecContainer) wrap(dir string, env []string, name string, args []string) (string, []string, error) {
+	if len(c.Image) == 0 {
+		return "", nil, fmt.Errorf("container image must not be empty")
+	}
+
+	runtime := c.Runtime
+	if len(runtime) == 0 {
+		runtime = "docker"
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get absolute path of %s: %s", dir, err)
+	}
+	tmpDir := os.TempDir()
+
+	wrapped := []string{
+		"run", "--rm", "-i",
+		"-v", absDir + ":" + absDir,
+		"-w", absDir,
+	}
+	if tmpDir != absDir {
+		wrapped = append(wrapped, "-v", tmpDir+":"+tmpDir, "-e", "TMPDIR="+tmpDir)
+	}
+	if len(c.User) != 0 {
+		wrapped = append(wrapped, "--user", c.User)
+	}
+	for _, key := range containerEnvKeys(env) {
+		wrapped = append(wrapped, "-e", key)
+	}
+	wrapped = append(wrapped, c.Image, name)
+	wrapped = append(wrapped, args...)
+
+	return runtime, wrapped, nil
+}
+
+// containerEnvKeys returns a list of names of environment variables which
+// should be passed to the container.
+func containerEnvKeys(env []string) []string {
+	keys := []string{}
+	seen := make(map[string]struct{})
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		for _, prefix := range containerEnvPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+				seen[key] = struct{}{}
+				break
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,107 @@
+package tfexec
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestExecContainerWrap(t *testing.T) {
+	tmpDir := os.TempDir()
+	cases := []struct {
+		desc      string
+		container *ExecContainer
+		env       []string
+		wantName  string
+		wantArgs  []string
+		ok        bool
+	}{
+		{
+			desc: "default runtime",
+			container: &ExecContainer{
+				Image: "hashicorp/terraform:1.0.0",
+			},
+			env:      []string{"PATH=/usr/bin", "TF_LOG=DEBUG", "AWS_PROFILE=dev"},
+			wantName: "docker",
+			wantArgs: []string{
+				"run", "--rm", "-i",
+				"-v", "/work/foo:/work/foo",
+				"-w", "/work/foo",
+				"-v", tmpDir + ":" + tmpDir, "-e", "TMPDIR=" + tmpDir,
+				"-e", "TF_LOG", "-e", "AWS_PROFILE",
+				"hashicorp/terraform:1.0.0", "terraform", "plan", "-input=false",
+			},
+			ok: true,
+		},
+		{
+			desc: "podman with user",
+			container: &ExecContainer{
+				Runtime: "podman",
+				Image:   "hashicorp/terraform:1.0.0",
+				User:    "1000:1000",
+			},
+			env:      []string{"TF_TOKEN_app_terraform_io=secret", "TF_TOKEN_app_terraform_io=secret2"},
+			wantName: "podman",
+			wantArgs: []string{
+				"run", "--rm", "-i",
+				"-v", "/work/foo:/work/foo",
+				"-w", "/work/foo",
+				"-v", tmpDir + ":" + tmpDir, "-e", "TMPDIR=" + tmpDir,
+				"--user", "1000:1000",
+				"-e", "TF_TOKEN_app_terraform_io",
+				"hashicorp/terraform:1.0.0", "terraform", "plan", "-input=false",
+			},
+			ok: true,
+		},
+		{
+			desc:      "no image",
+			container: &ExecContainer{},
+			wantName:  "",
+			wantArgs:  nil,
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			gotName, gotArgs, err := tc.container.wrap("/work/foo", tc.env, "terraform", []string{"plan", "-input=false"})
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s %v", gotName, gotArgs)
+			}
+			if gotName != tc.wantName {
+				t.Errorf("got name: %s, want: %s", gotName, tc.wantName)
+			}
+			if !reflect.DeepEqual(gotArgs, tc.wantArgs) {
+				t.Errorf("got args: %#v, want: %#v", gotArgs, tc.wantArgs)
+			}
+		})
+	}
+}
+
+func TestTerraformCLIRunWithExecContainer(t *testing.T) {
+	mockCommands := []*mockCommand{
+		{
+			args:     []string{"docker", "run", "...", "hashicorp/terraform:1.0.0", "terraform", "version"},
+			argsRe:   regexp.MustCompile(`^docker run --rm -i .* hashicorp/terraform:1.0.0 terraform version$`),
+			stdout:   "Terraform v1.0.0\n",
+			exitCode: 0,
+		},
+	}
+	e := NewMockExecutor(mockCommands)
+	terraformCLI := NewTerraformCLI(e)
+	terraformCLI.SetExecPath("terraform")
+	terraformCLI.SetExecContainer(&ExecContainer{Image: "hashicorp/terraform:1.0.0"})
+
+	_, got, err := terraformCLI.Version(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if got.String() != "1.0.0" {
+		t.Errorf("got: %s, want: 1.0.0", got)
+	}
+}
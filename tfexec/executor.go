@@ -8,8 +8,9 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
-	"github.com/davecgh/go-spew/spew"
+	"github.com/minamijoyo/tfmigrate/logging"
 )
 
 // Executor abstracts the os command execution layer.
@@ -22,6 +23,8 @@ type Executor interface {
 	Dir() string
 	// AppendEnv appends an environment variable.
 	AppendEnv(key string, value string)
+	// Env returns environment variables passed to a command.
+	Env() []string
 }
 
 // executor implements the Executor interface.
@@ -69,10 +72,10 @@ func (e *executor) NewCommandContext(ctx context.Context, name string, args ...s
 // Run executes a command.
 func (e *executor) Run(cmd Command) error {
 	log.Printf("[DEBUG] [executor@%s]$ %s", e.dir, strings.Join(cmd.Args(), " "))
+	start := time.Now()
 	err := cmd.Run()
-	log.Printf("[TRACE] [executor@%s] cmd=%s ", e.dir, spew.Sdump(cmd))
+	logCommand(e.dir, cmd, time.Since(start), err)
 	if err != nil {
-		log.Printf("[DEBUG] [executor@%s] failed to run command: %s", e.dir, spew.Sdump(err))
 		if osExecErr, ok := err.(*exec.ExitError); ok {
 			return &exitError{
 				osExecErr: osExecErr,
@@ -84,6 +87,42 @@ func (e *executor) Run(cmd Command) error {
 	return nil
 }
 
+// maxLogOutputBytes is a maximum size of outputs of a command in logs.
+const maxLogOutputBytes = 4096
+
+// logCommand logs a record of an executed command with its args, duration,
+// exit code and truncated outputs.
+// The stdout is logged only at TRACE level, because it may contain sensitive
+// data such as a state pulled from a remote backend.
+func logCommand(dir string, cmd Command, duration time.Duration, err error) {
+	component := "executor@" + dir
+	exitCode := 0
+	if err != nil {
+		exitCode = -1
+		if osExecErr, ok := err.(*exec.ExitError); ok {
+			exitCode = osExecErr.ExitCode()
+		}
+	}
+
+	fields := []logging.Field{
+		{Key: "args", Value: cmd.Args()},
+		{Key: "duration_ms", Value: duration.Milliseconds()},
+		{Key: "exit_code", Value: exitCode},
+		{Key: "stderr", Value: logging.Truncate(cmd.Stderr(), maxLogOutputBytes)},
+	}
+	if err != nil {
+		fields = append(fields, logging.Field{Key: "error", Value: err.Error()})
+		logging.Log("DEBUG", component, "failed to run command", fields...)
+	} else {
+		logging.Log("DEBUG", component, "command finished", fields...)
+	}
+
+	logging.Log("TRACE", component, "command output",
+		logging.Field{Key: "args", Value: cmd.Args()},
+		logging.Field{Key: "stdout", Value: logging.Truncate(cmd.Stdout(), maxLogOutputBytes)},
+	)
+}
+
 // Dir returns the current working directory.
 func (e *executor) Dir() string {
 	return e.dir
@@ -93,3 +132,8 @@ func (e *executor) Dir() string {
 func (e *executor) AppendEnv(key string, value string) {
 	e.env = append(e.env, key+"="+value)
 }
+
+// Env returns environment variables passed to a command.
+func (e *executor) Env() []string {
+	return e.env
+}
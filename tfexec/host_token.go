@@ -0,0 +1,84 @@
+package tfexec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// hostTokenEnvName returns a name of environment variable which terraform
+// reads an API token for a given hostname from.
+// A dot is replaced with an underscore and a hyphen is replaced with double
+// underscores. e.g.) app.terraform.io => TF_TOKEN_app_terraform_io
+// https://developer.hashicorp.com/terraform/cli/config/config-file#environment-variable-credentials
+func hostTokenEnvName(hostname string) (string, error) {
+	if len(hostname) == 0 {
+		return "", fmt.Errorf("hostname must not be empty")
+	}
+
+	for _, r := range hostname {
+		ok := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-'
+		if !ok {
+			return "", fmt.Errorf("invalid hostname: %q, it must be an ASCII hostname. Use punycode for an internationalized domain name", hostname)
+		}
+	}
+
+	name := strings.ReplaceAll(hostname, "-", "__")
+	name = strings.ReplaceAll(name, ".", "_")
+	return "TF_TOKEN_" + name, nil
+}
+
+// SetHostToken sets an API token for a given hostname of a private registry or
+// Terraform Cloud to environment variables passed to the terraform command.
+// It doesn't write any credentials file, so it works without a writable home
+// directory.
+func (c *terraformCLI) SetHostToken(hostname string, token string) error {
+	name, err := hostTokenEnvName(hostname)
+	if err != nil {
+		return err
+	}
+
+	if len(token) == 0 {
+		return fmt.Errorf("token for %s must not be empty", hostname)
+	}
+
+	c.AppendEnv(name, token)
+	return nil
+}
+
+// VerifyHostToken verifies that a given API token is valid for a given
+// hostname of Terraform Cloud or Terraform Enterprise by calling the account
+// details API. It returns an error if the token is invalid.
+func VerifyHostToken(ctx context.Context, hostname string, token string) error {
+	return verifyHostToken(ctx, http.DefaultClient, "https://"+hostname, token)
+}
+
+// verifyHostToken is an implementation of VerifyHostToken.
+// The baseURL is split for testing.
+func verifyHostToken(ctx context.Context, client *http.Client, baseURL string, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/v2/account/details", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to verify token for %s: %s", baseURL, err)
+	}
+	defer resp.Body.Close()
+	// drain the body to reuse the connection.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("token for %s is invalid or expired", baseURL)
+	default:
+		return fmt.Errorf("failed to verify token for %s: unexpected status: %s", baseURL, resp.Status)
+	}
+}
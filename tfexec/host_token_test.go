@@ -0,0 +1,132 @@
+package tfexec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHostTokenEnvName(t *testing.T) {
+	cases := []struct {
+		desc     string
+		hostname string
+		want     string
+		ok       bool
+	}{
+		{
+			desc:     "simple",
+			hostname: "app.terraform.io",
+			want:     "TF_TOKEN_app_terraform_io",
+			ok:       true,
+		},
+		{
+			desc:     "hyphen",
+			hostname: "tfe.my-company.example",
+			want:     "TF_TOKEN_tfe_my__company_example",
+			ok:       true,
+		},
+		{
+			desc:     "empty",
+			hostname: "",
+			want:     "",
+			ok:       false,
+		},
+		{
+			desc:     "non-ascii",
+			hostname: "例え.jp",
+			want:     "",
+			ok:       false,
+		},
+		{
+			desc:     "with port",
+			hostname: "localhost:8080",
+			want:     "",
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := hostTokenEnvName(tc.hostname)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", got)
+			}
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTerraformCLISetHostToken(t *testing.T) {
+	e := NewExecutor("", []string{"FOO=bar"})
+	terraformCLI := NewTerraformCLI(e)
+
+	if err := terraformCLI.SetHostToken("app.terraform.io", "secret"); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if err := terraformCLI.SetHostToken("app.terraform.io", ""); err == nil {
+		t.Fatal("expected to return an error for an empty token, but no error")
+	}
+
+	got := e.(*executor).env
+	want := []string{"FOO=bar", "TF_TOKEN_app_terraform_io=secret"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+}
+
+func TestVerifyHostToken(t *testing.T) {
+	cases := []struct {
+		desc   string
+		status int
+		ok     bool
+	}{
+		{
+			desc:   "valid",
+			status: http.StatusOK,
+			ok:     true,
+		},
+		{
+			desc:   "invalid",
+			status: http.StatusUnauthorized,
+			ok:     false,
+		},
+		{
+			desc:   "server error",
+			status: http.StatusInternalServerError,
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got *http.Request
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				w.WriteHeader(tc.status)
+			}))
+			defer ts.Close()
+
+			err := verifyHostToken(context.Background(), ts.Client(), ts.URL, "secret")
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if got.URL.Path != "/api/v2/account/details" {
+				t.Errorf("got path = %s, want = /api/v2/account/details", got.URL.Path)
+			}
+			if h := got.Header.Get("Authorization"); h != "Bearer secret" {
+				t.Errorf("got Authorization = %s, want = Bearer secret", h)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package tfexec
+
+import (
+	"strings"
+	"time"
+)
+
+// LockOptions is a set of options of state locking which are passed to every
+// terraform command locking a state, such as plan, import and state mv.
+type LockOptions struct {
+	// Lock is a flag to lock the state, which is passed as -lock.
+	// If nil, the flag is not passed and the terraform default (true) is used.
+	Lock *bool
+	// Timeout is a duration to retry a state lock, which is passed as
+	// -lock-timeout. If zero, the flag is not passed.
+	Timeout time.Duration
+}
+
+// SetLockOptions sets options of state locking passed to every command
+// locking a state. Set nil to disable it.
+func (c *terraformCLI) SetLockOptions(o *LockOptions) {
+	c.lockOptions = o
+}
+
+// lockSubcommands is a set of subcommands which accept the -lock and
+// -lock-timeout flags.
+var lockSubcommands = map[string]bool{
+	"apply":                  true,
+	"destroy":                true,
+	"import":                 true,
+	"init":                   true,
+	"plan":                   true,
+	"refresh":                true,
+	"taint":                  true,
+	"untaint":                true,
+	"state mv":               true,
+	"state push":             true,
+	"state replace-provider": true,
+	"state rm":               true,
+	"workspace delete":       true,
+	"workspace new":          true,
+}
+
+// withLockFlags returns a copy of given arguments of a terraform command with
+// the -lock and -lock-timeout flags inserted right after the subcommand, if
+// it accepts them. A flag already given in the arguments is not overridden.
+func (o *LockOptions) withLockFlags(args []string) []string {
+	if o == nil || len(args) == 0 {
+		return args
+	}
+
+	n := 1
+	if (args[0] == "state" || args[0] == "workspace") && len(args) > 1 {
+		n = 2
+	}
+	if !lockSubcommands[strings.Join(args[:n], " ")] {
+		return args
+	}
+
+	hasLock := false
+	hasTimeout := false
+	for _, arg := range args[n:] {
+		switch {
+		case arg == "-lock" || strings.HasPrefix(arg, "-lock="):
+			hasLock = true
+		case strings.HasPrefix(arg, "-lock-timeout="):
+			hasTimeout = true
+		}
+	}
+
+	flags := []string{}
+	if o.Lock != nil && !hasLock {
+		if *o.Lock {
+			flags = append(flags, "-lock=true")
+		} else {
+			flags = append(flags, "-lock=false")
+		}
+	}
+	if o.Timeout > 0 && !hasTimeout {
+		flags = append(flags, "-lock-timeout="+o.Timeout.String())
+	}
+	if len(flags) == 0 {
+		return args
+	}
+
+	newArgs := make([]string, 0, len(args)+len(flags))
+	newArgs = append(newArgs, args[:n]...)
+	newArgs = append(newArgs, flags...)
+	return append(newArgs, args[n:]...)
+}
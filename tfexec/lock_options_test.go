@@ -0,0 +1,99 @@
+package tfexec
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLockOptionsWithLockFlags(t *testing.T) {
+	enabled := true
+	disabled := false
+	cases := []struct {
+		desc string
+		o    *LockOptions
+		args []string
+		want []string
+	}{
+		{
+			desc: "nil",
+			o:    nil,
+			args: []string{"plan", "-input=false"},
+			want: []string{"plan", "-input=false"},
+		},
+		{
+			desc: "plan",
+			o:    &LockOptions{Lock: &enabled, Timeout: 5 * time.Minute},
+			args: []string{"plan", "-state=/tmp/tfmigrate-123", "-input=false"},
+			want: []string{"plan", "-lock=true", "-lock-timeout=5m0s", "-state=/tmp/tfmigrate-123", "-input=false"},
+		},
+		{
+			desc: "state mv",
+			o:    &LockOptions{Timeout: 30 * time.Second},
+			args: []string{"state", "mv", "-state=/tmp/tfmigrate-123", "null_resource.foo", "null_resource.bar"},
+			want: []string{"state", "mv", "-lock-timeout=30s", "-state=/tmp/tfmigrate-123", "null_resource.foo", "null_resource.bar"},
+		},
+		{
+			desc: "state push",
+			o:    &LockOptions{Lock: &disabled},
+			args: []string{"state", "push", "/tmp/state"},
+			want: []string{"state", "push", "-lock=false", "/tmp/state"},
+		},
+		{
+			desc: "workspace new",
+			o:    &LockOptions{Timeout: 30 * time.Second},
+			args: []string{"workspace", "new", "foo"},
+			want: []string{"workspace", "new", "-lock-timeout=30s", "foo"},
+		},
+		{
+			desc: "state pull",
+			o:    &LockOptions{Lock: &enabled, Timeout: 30 * time.Second},
+			args: []string{"state", "pull"},
+			want: []string{"state", "pull"},
+		},
+		{
+			desc: "workspace select",
+			o:    &LockOptions{Lock: &enabled, Timeout: 30 * time.Second},
+			args: []string{"workspace", "select", "foo"},
+			want: []string{"workspace", "select", "foo"},
+		},
+		{
+			desc: "version",
+			o:    &LockOptions{Lock: &enabled, Timeout: 30 * time.Second},
+			args: []string{"-version"},
+			want: []string{"-version"},
+		},
+		{
+			desc: "explicit flags take precedence",
+			o:    &LockOptions{Lock: &enabled, Timeout: 30 * time.Second},
+			args: []string{"import", "-lock=false", "-lock-timeout=1s", "null_resource.foo", "foo"},
+			want: []string{"import", "-lock=false", "-lock-timeout=1s", "null_resource.foo", "foo"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.o.withLockFlags(tc.args)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTerraformCLILockOptions(t *testing.T) {
+	e := NewMockExecutor([]*mockCommand{
+		{
+			args:     []string{"terraform", "state", "rm", "-lock-timeout=1m0s", "null_resource.foo"},
+			exitCode: 0,
+		},
+	})
+	terraformCLI := NewTerraformCLI(e)
+	terraformCLI.SetExecPath("terraform")
+	terraformCLI.SetLockOptions(&LockOptions{Timeout: time.Minute})
+	_, _, err := terraformCLI.Run(context.Background(), "state", "rm", "null_resource.foo")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+}
@@ -0,0 +1,81 @@
+package tfexec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetReadOnly sets a flag to refuse any terraform command which may mutate
+// remote states or real resources. It is intended to verify that a plan can
+// run with read-only credentials.
+func (c *terraformCLI) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
+// checkReadOnly returns an error if given arguments of a terraform command
+// may mutate remote states or real resources.
+// State subcommands and import operating on a local state file given by the
+// -state= option are allowed, because they never touch remote states.
+func checkReadOnly(args []string) error {
+	subcommand := []string{}
+	hasState := false
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			if strings.HasPrefix(arg, "-state=") {
+				hasState = true
+			}
+			continue
+		}
+		// The positional arguments other than subcommands such as addresses
+		// are not needed to check.
+		if len(subcommand) < 2 {
+			subcommand = append(subcommand, arg)
+		}
+	}
+
+	refuse := func() error {
+		return fmt.Errorf("refused to run a mutating command in read-only mode: terraform %s", strings.Join(args, " "))
+	}
+
+	if len(subcommand) == 0 {
+		return nil
+	}
+
+	switch subcommand[0] {
+	case "apply", "destroy", "taint", "untaint", "force-unlock":
+		return refuse()
+	case "import":
+		if !hasState {
+			return refuse()
+		}
+	case "init":
+		for _, arg := range args {
+			if arg == "-migrate-state" || arg == "-force-copy" {
+				return refuse()
+			}
+		}
+	case "workspace":
+		if len(subcommand) > 1 && (subcommand[1] == "new" || subcommand[1] == "delete") {
+			return refuse()
+		}
+		for _, arg := range args {
+			if arg == "-or-create" {
+				return refuse()
+			}
+		}
+	case "state":
+		if len(subcommand) < 2 {
+			return nil
+		}
+		switch subcommand[1] {
+		case "push":
+			return refuse()
+		case "mv", "rm", "replace-provider":
+			if !hasState {
+				return refuse()
+			}
+		}
+	}
+
+	return nil
+}
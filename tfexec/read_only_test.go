@@ -0,0 +1,65 @@
+package tfexec
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	cases := []struct {
+		desc string
+		args []string
+		ok   bool
+	}{
+		{desc: "plan", args: []string{"plan", "-state=/tmp/tfmigrate-123", "-input=false"}, ok: true},
+		{desc: "state pull", args: []string{"state", "pull"}, ok: true},
+		{desc: "state list", args: []string{"state", "list"}, ok: true},
+		{desc: "init", args: []string{"init", "-input=false", "-reconfigure"}, ok: true},
+		{desc: "init -migrate-state", args: []string{"init", "-migrate-state"}, ok: false},
+		{desc: "init -force-copy", args: []string{"init", "-force-copy"}, ok: false},
+		{desc: "workspace select", args: []string{"workspace", "select", "foo"}, ok: true},
+		{desc: "workspace new", args: []string{"workspace", "new", "foo"}, ok: false},
+		{desc: "workspace select or create", args: []string{"workspace", "select", "-or-create", "foo"}, ok: false},
+		{desc: "workspace delete", args: []string{"workspace", "delete", "foo"}, ok: false},
+		{desc: "apply", args: []string{"apply", "-auto-approve"}, ok: false},
+		{desc: "destroy", args: []string{"destroy", "-auto-approve"}, ok: false},
+		{desc: "force-unlock", args: []string{"force-unlock", "-force", "123"}, ok: false},
+		{desc: "state push", args: []string{"state", "push", "-force", "/tmp/state"}, ok: false},
+		{desc: "state mv with -state", args: []string{"state", "mv", "-state=/tmp/tfmigrate-123", "-backup=/dev/null", "null_resource.foo", "null_resource.bar"}, ok: true},
+		{desc: "state mv", args: []string{"state", "mv", "null_resource.foo", "null_resource.bar"}, ok: false},
+		{desc: "state rm", args: []string{"state", "rm", "null_resource.foo"}, ok: false},
+		{desc: "state replace-provider", args: []string{"state", "replace-provider", "-auto-approve", "a", "b"}, ok: false},
+		{desc: "import with -state", args: []string{"import", "-state=/tmp/tfmigrate-123", "null_resource.foo", "foo"}, ok: true},
+		{desc: "import", args: []string{"import", "null_resource.foo", "foo"}, ok: false},
+		{desc: "version", args: []string{"-version"}, ok: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := checkReadOnly(tc.args)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestTerraformCLIReadOnly(t *testing.T) {
+	e := NewMockExecutor([]*mockCommand{
+		{
+			args:     []string{"terraform", "apply", "-auto-approve"},
+			exitCode: 0,
+		},
+	})
+	terraformCLI := NewTerraformCLI(e)
+	terraformCLI.SetExecPath("terraform")
+	terraformCLI.SetReadOnly(true)
+
+	err := terraformCLI.Apply(context.Background(), nil, "-auto-approve")
+	if err == nil {
+		t.Fatal("expected to return an error in read-only mode, but no error")
+	}
+}
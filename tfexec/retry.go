@@ -0,0 +1,96 @@
+package tfexec
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+)
+
+// DefaultRetryableErrors is a list of patterns of transient errors retried by
+// default. They match errors which occur before terraform changes anything,
+// so that retrying a command is safe.
+var DefaultRetryableErrors = []*regexp.Regexp{
+	// state lock contention
+	regexp.MustCompile(`Error acquiring the state lock`),
+	// provider and module registry 5xx
+	regexp.MustCompile(`(?i)registry[^\n]*(50[0-9]|Service Unavailable|Bad Gateway|Gateway Time-?out)`),
+	// connection errors on querying the registry
+	regexp.MustCompile(`Failed to query available provider packages`),
+}
+
+// RetryPolicy is a policy to retry a terraform command which fails with a
+// transient error such as state lock contention.
+type RetryPolicy struct {
+	// MaxAttempts is a maximum number of attempts including the first one.
+	// A value less than 2 disables retries.
+	MaxAttempts int
+	// Backoff is a wait before the first retry. It doubles for each retry.
+	Backoff time.Duration
+	// MaxBackoff is an upper limit of the wait. If zero, it's unlimited.
+	MaxBackoff time.Duration
+	// RetryableErrors is a list of patterns of retryable errors, which are
+	// matched against stderr of the command.
+	RetryableErrors []*regexp.Regexp
+}
+
+// retryable returns true if a given stderr of a failed command matches any
+// of the retryable errors.
+func (p *RetryPolicy) retryable(stderr string) bool {
+	for _, re := range p.RetryableErrors {
+		if re.MatchString(stderr) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns a wait before a given number of retry starting from 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// SetRetryPolicy sets a policy to retry a terraform command which fails with
+// a transient error.
+func (c *terraformCLI) SetRetryPolicy(policy *RetryPolicy) {
+	c.retry = policy
+}
+
+// runWithRetry runs a given function and retries it according to the retry
+// policy while it fails with a retryable error. The function returns stdout,
+// stderr and an error of the command.
+func (c *terraformCLI) runWithRetry(ctx context.Context, f func() (string, string, error)) (string, string, error) {
+	stdout, stderr, err := f()
+	if c.retry == nil {
+		return stdout, stderr, err
+	}
+
+	for attempt := 1; err != nil && attempt < c.retry.MaxAttempts; attempt++ {
+		if _, ok := err.(ExitError); !ok || !c.retry.retryable(stderr) {
+			break
+		}
+
+		wait := c.retry.backoff(attempt)
+		logging.FromContext(ctx).Printf("[WARN] [executor@%s] retry a command in %s (%d/%d) due to a transient error: %s\n", c.Dir(), wait, attempt, c.retry.MaxAttempts-1, err)
+		select {
+		case <-ctx.Done():
+			return stdout, stderr, err
+		case <-time.After(wait):
+		}
+
+		stdout, stderr, err = f()
+	}
+
+	return stdout, stderr, err
+}
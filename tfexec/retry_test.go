@@ -0,0 +1,120 @@
+package tfexec
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTerraformCLIRunWithRetry(t *testing.T) {
+	lockError := &mockCommand{
+		args:     []string{"terraform", "plan"},
+		stderr:   "Error: Error acquiring the state lock",
+		exitCode: 1,
+	}
+	otherError := &mockCommand{
+		args:     []string{"terraform", "plan"},
+		stderr:   "Error: Unsupported argument",
+		exitCode: 1,
+	}
+	success := &mockCommand{
+		args:     []string{"terraform", "plan"},
+		stdout:   "No changes.",
+		exitCode: 0,
+	}
+
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		policy       *RetryPolicy
+		want         string
+		runCalls     int
+		ok           bool
+	}{
+		{
+			desc:         "retry a transient error",
+			mockCommands: []*mockCommand{lockError, lockError, success},
+			policy: &RetryPolicy{
+				MaxAttempts:     3,
+				RetryableErrors: DefaultRetryableErrors,
+			},
+			want:     "No changes.",
+			runCalls: 3,
+			ok:       true,
+		},
+		{
+			desc:         "exceed max attempts",
+			mockCommands: []*mockCommand{lockError, lockError, success},
+			policy: &RetryPolicy{
+				MaxAttempts:     2,
+				RetryableErrors: DefaultRetryableErrors,
+			},
+			runCalls: 2,
+			ok:       false,
+		},
+		{
+			desc:         "non-retryable error",
+			mockCommands: []*mockCommand{otherError, success},
+			policy: &RetryPolicy{
+				MaxAttempts:     3,
+				RetryableErrors: DefaultRetryableErrors,
+			},
+			runCalls: 1,
+			ok:       false,
+		},
+		{
+			desc:         "custom retryable errors",
+			mockCommands: []*mockCommand{otherError, success},
+			policy: &RetryPolicy{
+				MaxAttempts:     3,
+				RetryableErrors: []*regexp.Regexp{regexp.MustCompile("Unsupported")},
+			},
+			want:     "No changes.",
+			runCalls: 2,
+			ok:       true,
+		},
+		{
+			desc:         "no policy",
+			mockCommands: []*mockCommand{lockError, success},
+			policy:       nil,
+			runCalls:     1,
+			ok:           false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			terraformCLI.SetRetryPolicy(tc.policy)
+			got, _, err := terraformCLI.Run(context.Background(), "plan")
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got = %s", got)
+			}
+			if tc.ok && got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+			if runCalls := e.(*mockExecutor).runCalls; runCalls != tc.runCalls {
+				t.Errorf("got runCalls: %d, want: %d", runCalls, tc.runCalls)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		Backoff:    time.Second,
+		MaxBackoff: 5 * time.Second,
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Errorf("retry %d: got: %s, want: %s", i+1, got, w)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package tfexec
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// AnonymizeState returns a new state with infrastructure details scrubbed.
+// It is intended to share a reproduction state for bug reports.
+//
+// The structure of state and resource addresses are preserved, so that the
+// anonymized state can be used with tfmigrate as it is.
+// Each string value in attributes and outputs is replaced with a pseudonym
+// derived from a given salt, so that the same value is always replaced with the
+// same pseudonym within a state and references between resources are kept.
+// Numbers, booleans and nulls are kept as they are, unless they are marked
+// sensitive by sensitive outputs or sensitive_attributes of instances, in
+// which case they are replaced with null, because they may be secrets.
+// Opaque fields such as private are removed.
+//
+// Note that keys of map attributes such as tags are kept, because they cannot
+// be distinguished from attribute names without provider schemas.
+func AnonymizeState(state *State, salt []byte) (*State, error) {
+	dec := json.NewDecoder(bytes.NewReader(state.Bytes()))
+	// keep numbers as they are.
+	dec.UseNumber()
+
+	var s map[string]interface{}
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %s", err)
+	}
+
+	version, ok := s["version"].(json.Number)
+	if !ok || version.String() != "4" {
+		return nil, fmt.Errorf("unsupported state version: %v", s["version"])
+	}
+
+	a := &stateAnonymizer{salt: salt}
+
+	if _, ok := s["lineage"]; ok {
+		s["lineage"] = a.anonymize(s["lineage"])
+	}
+
+	if outputs, ok := s["outputs"].(map[string]interface{}); ok {
+		for _, v := range outputs {
+			if o, ok := v.(map[string]interface{}); ok {
+				if sensitive, _ := o["sensitive"].(bool); sensitive {
+					o["value"] = redact(o["value"])
+				}
+				o["value"] = a.anonymize(o["value"])
+			}
+		}
+	}
+
+	if resources, ok := s["resources"].([]interface{}); ok {
+		for _, v := range resources {
+			r, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			instances, ok := r["instances"].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, v := range instances {
+				i, ok := v.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				// index_key, dependencies and sensitive_attributes are
+				// a part of addresses or paths, so we keep them.
+				if attrs, ok := i["attributes"]; ok {
+					if paths, ok := i["sensitive_attributes"].([]interface{}); ok {
+						for _, p := range paths {
+							path, ok := p.([]interface{})
+							if !ok {
+								// We cannot know which value is sensitive.
+								attrs = redact(attrs)
+								continue
+							}
+							attrs = redactPath(attrs, path)
+						}
+					}
+					i["attributes"] = a.anonymize(attrs)
+				}
+				if _, ok := i["attributes_flat"]; ok {
+					i["attributes_flat"] = a.anonymize(i["attributes_flat"])
+				}
+				delete(i, "private")
+			}
+		}
+	}
+
+	// check_results may contain messages which refer to actual values.
+	delete(s, "check_results")
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode state: %s", err)
+	}
+
+	return NewState(append(b, '\n')), nil
+}
+
+// stateAnonymizer replaces values with pseudonyms.
+type stateAnonymizer struct {
+	// salt is a secret to derive pseudonyms.
+	salt []byte
+}
+
+// anonymize returns a new value which replaces strings recursively.
+func (a *stateAnonymizer) anonymize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case string:
+		if len(x) == 0 {
+			return x
+		}
+		return a.pseudonym(x)
+	case []interface{}:
+		for i := range x {
+			x[i] = a.anonymize(x[i])
+		}
+		return x
+	case map[string]interface{}:
+		for k := range x {
+			x[k] = a.anonymize(x[k])
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+// redact returns a new value which replaces numbers and booleans with null
+// recursively. Strings are kept, because they are replaced with pseudonyms
+// later.
+func redact(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number, bool:
+		return nil
+	case []interface{}:
+		for i := range x {
+			x[i] = redact(x[i])
+		}
+		return x
+	case map[string]interface{}:
+		for k := range x {
+			x[k] = redact(x[k])
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+// redactPath returns a new value which redacts a value at a given path in
+// sensitive_attributes, such as [{"type": "get_attr", "value": "password"}].
+// If the path cannot be followed, the whole value is redacted to be safe.
+func redactPath(v interface{}, path []interface{}) interface{} {
+	if len(path) == 0 {
+		return redact(v)
+	}
+
+	step, ok := path[0].(map[string]interface{})
+	if !ok {
+		return redact(v)
+	}
+	switch step["type"] {
+	case "get_attr":
+		name, ok := step["value"].(string)
+		m, isMap := v.(map[string]interface{})
+		if !ok || !isMap {
+			return redact(v)
+		}
+		if child, ok := m[name]; ok {
+			m[name] = redactPath(child, path[1:])
+		}
+		return v
+
+	case "index":
+		// The key is a typed value such as {"value": 0, "type": "number"}.
+		key, _ := step["value"].(map[string]interface{})
+		switch k := key["value"].(type) {
+		case string:
+			if m, ok := v.(map[string]interface{}); ok {
+				if child, ok := m[k]; ok {
+					m[k] = redactPath(child, path[1:])
+				}
+				return v
+			}
+		case json.Number:
+			i, err := k.Int64()
+			if l, ok := v.([]interface{}); ok && err == nil {
+				if i >= 0 && i < int64(len(l)) {
+					l[i] = redactPath(l[i], path[1:])
+				}
+				return v
+			}
+		}
+		return redact(v)
+
+	default:
+		return redact(v)
+	}
+}
+
+// pseudonym returns a pseudonym for a given string.
+func (a *stateAnonymizer) pseudonym(s string) string {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(s))
+	return "anon-" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
@@ -0,0 +1,312 @@
+package tfexec
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeState(t *testing.T) {
+	salt := []byte("salt")
+	a := &stateAnonymizer{salt: salt}
+	id := a.pseudonym("sg-1234")
+	name := a.pseudonym("prod-web")
+
+	cases := []struct {
+		desc  string
+		state string
+		want  string
+		ok    bool
+	}{
+		{
+			desc: "simple",
+			state: `{
+  "version": 4,
+  "terraform_version": "1.5.7",
+  "serial": 3,
+  "lineage": "",
+  "outputs": {
+    "sg_id": {
+      "value": "sg-1234",
+      "type": "string",
+      "sensitive": true
+    }
+  },
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_security_group",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {
+          "index_key": "web",
+          "schema_version": 1,
+          "attributes": {
+            "id": "sg-1234",
+            "name": "prod-web",
+            "description": "",
+            "revoke_rules_on_delete": false,
+            "timeouts": null,
+            "ingress": [
+              {
+                "from_port": 443,
+                "cidr_blocks": ["10.0.0.0/8"]
+              }
+            ],
+            "tags": {
+              "Name": "prod-web"
+            }
+          },
+          "sensitive_attributes": [],
+          "private": "eyJzY2hlbWFfdmVyc2lvbiI6IjEifQ==",
+          "dependencies": ["aws_vpc.main"]
+        }
+      ]
+    }
+  ],
+  "check_results": []
+}
+`,
+			want: `{
+  "lineage": "",
+  "outputs": {
+    "sg_id": {
+      "sensitive": true,
+      "type": "string",
+      "value": "` + id + `"
+    }
+  },
+  "resources": [
+    {
+      "instances": [
+        {
+          "attributes": {
+            "description": "",
+            "id": "` + id + `",
+            "ingress": [
+              {
+                "cidr_blocks": [
+                  "` + a.pseudonym("10.0.0.0/8") + `"
+                ],
+                "from_port": 443
+              }
+            ],
+            "name": "` + name + `",
+            "revoke_rules_on_delete": false,
+            "tags": {
+              "Name": "` + name + `"
+            },
+            "timeouts": null
+          },
+          "dependencies": [
+            "aws_vpc.main"
+          ],
+          "index_key": "web",
+          "schema_version": 1,
+          "sensitive_attributes": []
+        }
+      ],
+      "mode": "managed",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "type": "aws_security_group"
+    }
+  ],
+  "serial": 3,
+  "terraform_version": "1.5.7",
+  "version": 4
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "sensitive values",
+			state: `{
+  "version": 4,
+  "lineage": "",
+  "outputs": {
+    "db": {
+      "value": {"port": 5432, "password": "secret"},
+      "type": ["object", {"port": "number", "password": "string"}],
+      "sensitive": true
+    },
+    "count": {
+      "value": 3,
+      "type": "number"
+    }
+  },
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "foo",
+      "instances": [
+        {
+          "attributes": {
+            "port": 5432,
+            "enabled": true,
+            "size": 10,
+            "settings": [{"retention": 7}],
+            "limits": {"max": 100}
+          },
+          "sensitive_attributes": [
+            [{"type": "get_attr", "value": "port"}],
+            [{"type": "get_attr", "value": "enabled"}],
+            [{"type": "get_attr", "value": "settings"}, {"type": "index", "value": {"value": 0, "type": "number"}}],
+            [{"type": "get_attr", "value": "limits"}, {"type": "index", "value": {"value": "max", "type": "string"}}]
+          ]
+        }
+      ]
+    }
+  ]
+}
+`,
+			want: `{
+  "lineage": "",
+  "outputs": {
+    "count": {
+      "type": "number",
+      "value": 3
+    },
+    "db": {
+      "sensitive": true,
+      "type": [
+        "object",
+        {
+          "password": "string",
+          "port": "number"
+        }
+      ],
+      "value": {
+        "password": "` + a.pseudonym("secret") + `",
+        "port": null
+      }
+    }
+  },
+  "resources": [
+    {
+      "instances": [
+        {
+          "attributes": {
+            "enabled": null,
+            "limits": {
+              "max": null
+            },
+            "port": null,
+            "settings": [
+              {
+                "retention": null
+              }
+            ],
+            "size": 10
+          },
+          "sensitive_attributes": [
+            [
+              {
+                "type": "get_attr",
+                "value": "port"
+              }
+            ],
+            [
+              {
+                "type": "get_attr",
+                "value": "enabled"
+              }
+            ],
+            [
+              {
+                "type": "get_attr",
+                "value": "settings"
+              },
+              {
+                "type": "index",
+                "value": {
+                  "type": "number",
+                  "value": 0
+                }
+              }
+            ],
+            [
+              {
+                "type": "get_attr",
+                "value": "limits"
+              },
+              {
+                "type": "index",
+                "value": {
+                  "type": "string",
+                  "value": "max"
+                }
+              }
+            ]
+          ]
+        }
+      ],
+      "mode": "managed",
+      "name": "foo",
+      "type": "null_resource"
+    }
+  ],
+  "version": 4
+}
+`,
+			ok: true,
+		},
+		{
+			desc:  "unsupported version",
+			state: `{"version": 3}`,
+			want:  "",
+			ok:    false,
+		},
+		{
+			desc:  "invalid json",
+			state: `{`,
+			want:  "",
+			ok:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := AnonymizeState(NewState([]byte(tc.state)), salt)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", string(got.Bytes()))
+			}
+			if tc.ok && string(got.Bytes()) != tc.want {
+				t.Errorf("got: %s, want: %s", string(got.Bytes()), tc.want)
+			}
+		})
+	}
+}
+
+func TestAnonymizeStateSalt(t *testing.T) {
+	state := NewState([]byte(`{"version": 4, "lineage": "foo", "resources": []}`))
+
+	got1, err := AnonymizeState(state, []byte("salt1"))
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	got2, err := AnonymizeState(state, []byte("salt2"))
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	var s1, s2 map[string]interface{}
+	if err := json.Unmarshal(got1.Bytes(), &s1); err != nil {
+		t.Fatalf("failed to parse state: %s", err)
+	}
+	if err := json.Unmarshal(got2.Bytes(), &s2); err != nil {
+		t.Fatalf("failed to parse state: %s", err)
+	}
+	if reflect.DeepEqual(s1["lineage"], s2["lineage"]) {
+		t.Errorf("expected different pseudonyms for different salts, but got: %v", s1["lineage"])
+	}
+	if strings.Contains(string(got1.Bytes()), "foo") {
+		t.Errorf("expected to be anonymized, but got: %s", string(got1.Bytes()))
+	}
+}
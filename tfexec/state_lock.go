@@ -0,0 +1,136 @@
+package tfexec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/clock"
+	"github.com/minamijoyo/tfmigrate/logging"
+)
+
+// lockCreatedLayout is a layout of the created time of a lock, which is
+// printed by time.Time.String() in terraform.
+const lockCreatedLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// LockInfo is information of a state lock held by someone else, which
+// terraform prints on failing to acquire the lock.
+type LockInfo struct {
+	// ID is a lock ID, which is passed to terraform force-unlock.
+	ID string
+	// Path is a path of the locked state in the backend.
+	Path string
+	// Operation is a terraform operation which holds the lock.
+	Operation string
+	// Who is a user and host which holds the lock.
+	Who string
+	// Version is a version of terraform which holds the lock.
+	Version string
+	// Created is a time when the lock was created.
+	// It's zero if unknown.
+	Created time.Time
+}
+
+// String returns a human-readable description of the lock.
+func (l *LockInfo) String() string {
+	return fmt.Sprintf("ID: %s, Path: %s, Operation: %s, Who: %s, Version: %s, Created: %s", l.ID, l.Path, l.Operation, l.Who, l.Version, l.Created)
+}
+
+// ParseLockInfo parses a lock info in stderr of terraform such as:
+//
+//	│ Error: Error acquiring the state lock
+//	│
+//	│ Error message: ConditionalCheckFailedException: ...
+//	│ Lock Info:
+//	│   ID:        1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d
+//	│   Path:      tfstate-test/terraform.tfstate
+//	│   Operation: OperationTypeApply
+//	│   Who:       foo@example.local
+//	│   Version:   1.5.7
+//	│   Created:   2023-09-01 12:34:56.789012 +0000 UTC
+//	│   Info:
+//
+// It returns nil if no lock info is found.
+func ParseLockInfo(stderr string) *LockInfo {
+	var lock *LockInfo
+	for _, l := range strings.Split(stderr, "\n") {
+		line := strings.TrimPrefix(l, "│")
+		line = strings.TrimSpace(line)
+
+		if line == "Lock Info:" {
+			lock = &LockInfo{}
+			continue
+		}
+		if lock == nil {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "ID":
+			lock.ID = value
+		case "Path":
+			lock.Path = value
+		case "Operation":
+			lock.Operation = value
+		case "Who":
+			lock.Who = value
+		case "Version":
+			lock.Version = value
+		case "Created":
+			if t, err := time.Parse(lockCreatedLayout, value); err == nil {
+				lock.Created = t
+			}
+		}
+	}
+
+	if lock == nil || len(lock.ID) == 0 {
+		return nil
+	}
+	return lock
+}
+
+// SetForceUnlockStale sets a threshold of age of a stale lock. If a command
+// fails because a lock older than it is held, the lock is removed with
+// terraform force-unlock and the command is retried once. Set zero to
+// disable it.
+func (c *terraformCLI) SetForceUnlockStale(d time.Duration) {
+	c.forceUnlockStale = d
+}
+
+// recoverStaleLock reports a lock held by someone else in a given stderr of
+// a failed command, and removes it if it's stale. It returns true if the lock
+// has been removed and the command can be retried.
+func (c *terraformCLI) recoverStaleLock(ctx context.Context, stderr string) bool {
+	lock := ParseLockInfo(stderr)
+	if lock == nil {
+		return false
+	}
+	logging.FromContext(ctx).Printf("[WARN] [executor@%s] the state is locked: %s\n", c.Dir(), lock)
+
+	if c.forceUnlockStale == 0 {
+		return false
+	}
+	// We cannot tell whether the lock is stale without the created time.
+	if lock.Created.IsZero() {
+		logging.FromContext(ctx).Printf("[WARN] [executor@%s] don't force unlock the lock %s because its created time is unknown\n", c.Dir(), lock.ID)
+		return false
+	}
+	age := clock.Now(ctx).Sub(lock.Created)
+	if age < c.forceUnlockStale {
+		logging.FromContext(ctx).Printf("[WARN] [executor@%s] don't force unlock the lock %s because it's not stale: created %s ago\n", c.Dir(), lock.ID, age.Round(time.Second))
+		return false
+	}
+
+	logging.FromContext(ctx).Printf("[WARN] [executor@%s] force unlock the stale lock %s created %s ago\n", c.Dir(), lock.ID, age.Round(time.Second))
+	if err := c.ForceUnlock(ctx, lock.ID); err != nil {
+		logging.FromContext(ctx).Printf("[ERROR] [executor@%s] failed to force unlock the lock %s: %s\n", c.Dir(), lock.ID, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,277 @@
+package tfexec
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/clock"
+)
+
+// lockErrorStderr returns stderr of terraform which failed to acquire a state
+// lock created at a given time.
+func lockErrorStderr(created time.Time) string {
+	return `╷
+│ Error: Error acquiring the state lock
+│
+│ Error message: ConditionalCheckFailedException: The conditional request failed
+│ Lock Info:
+│   ID:        1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d
+│   Path:      tfstate-test/terraform.tfstate
+│   Operation: OperationTypeApply
+│   Who:       foo@example.local
+│   Version:   1.5.7
+│   Created:   ` + created.UTC().Format(lockCreatedLayout) + `
+│   Info:
+│
+│
+│ Terraform acquires a state lock to protect the state from being written
+│ by multiple users at the same time.
+╵
+`
+}
+
+func TestParseLockInfo(t *testing.T) {
+	created := time.Date(2023, 9, 1, 12, 34, 56, 789012000, time.UTC)
+	cases := []struct {
+		desc   string
+		stderr string
+		want   *LockInfo
+	}{
+		{
+			desc:   "with box",
+			stderr: lockErrorStderr(created),
+			want: &LockInfo{
+				ID:        "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d",
+				Path:      "tfstate-test/terraform.tfstate",
+				Operation: "OperationTypeApply",
+				Who:       "foo@example.local",
+				Version:   "1.5.7",
+				Created:   created,
+			},
+		},
+		{
+			desc: "without box",
+			stderr: `Error: Error locking state: Error acquiring the state lock: storage: object already exists
+Lock Info:
+  ID:        1693571696789012
+  Path:      gs://tfstate-test/default.tflock
+  Operation: OperationTypePlan
+  Who:       foo@example.local
+  Version:   0.12.31
+  Created:   2023-09-01 12:34:56.789012 +0000 UTC
+  Info:
+`,
+			want: &LockInfo{
+				ID:        "1693571696789012",
+				Path:      "gs://tfstate-test/default.tflock",
+				Operation: "OperationTypePlan",
+				Who:       "foo@example.local",
+				Version:   "0.12.31",
+				Created:   created,
+			},
+		},
+		{
+			desc: "invalid created",
+			stderr: `Lock Info:
+  ID:        1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d
+  Created:   yesterday
+`,
+			want: &LockInfo{
+				ID: "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d",
+			},
+		},
+		{
+			desc:   "no lock info",
+			stderr: "Error: Unsupported argument\n",
+			want:   nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := ParseLockInfo(tc.stderr)
+			if got != nil && tc.want != nil && got.Created.Equal(tc.want.Created) {
+				// compare time.Time values with Equal.
+				got.Created = tc.want.Created
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTerraformCLIRunForceUnlockStale(t *testing.T) {
+	stale := lockErrorStderr(time.Now().Add(-2 * time.Hour))
+	fresh := lockErrorStderr(time.Now())
+	cases := []struct {
+		desc             string
+		mockCommands     []*mockCommand
+		forceUnlockStale time.Duration
+		ok               bool
+	}{
+		{
+			desc: "unlock a stale lock and retry",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					stderr:   stale,
+					exitCode: 1,
+				},
+				{
+					args:     []string{"terraform", "force-unlock", "-force", "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					exitCode: 0,
+				},
+			},
+			forceUnlockStale: time.Hour,
+			ok:               true,
+		},
+		{
+			desc: "not stale",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					stderr:   fresh,
+					exitCode: 1,
+				},
+			},
+			forceUnlockStale: time.Hour,
+			ok:               false,
+		},
+		{
+			desc: "disabled",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					stderr:   stale,
+					exitCode: 1,
+				},
+			},
+			forceUnlockStale: 0,
+			ok:               false,
+		},
+		{
+			desc: "failed to force unlock",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					stderr:   stale,
+					exitCode: 1,
+				},
+				{
+					args:     []string{"terraform", "force-unlock", "-force", "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d"},
+					stderr:   stale,
+					exitCode: 1,
+				},
+			},
+			forceUnlockStale: time.Hour,
+			ok:               false,
+		},
+		{
+			desc: "locked again after unlock",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					stderr:   stale,
+					exitCode: 1,
+				},
+				{
+					args:     []string{"terraform", "force-unlock", "-force", "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					stderr:   fresh,
+					exitCode: 1,
+				},
+			},
+			forceUnlockStale: time.Hour,
+			ok:               false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			terraformCLI.SetForceUnlockStale(tc.forceUnlockStale)
+			_, _, err := terraformCLI.Run(context.Background(), "state", "push", "foo.tfstate")
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if calls := e.(*mockExecutor).newCommnadContextCalls; calls != len(tc.mockCommands) {
+				t.Errorf("got %d calls, want: %d", calls, len(tc.mockCommands))
+			}
+		})
+	}
+}
+
+func TestTerraformCLIRunForceUnlockStaleWithClock(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		ok           bool
+	}{
+		{
+			desc: "just under the threshold",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					stderr:   lockErrorStderr(now.Add(-time.Hour + time.Second)),
+					exitCode: 1,
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "just over the threshold",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					stderr:   lockErrorStderr(now.Add(-time.Hour - time.Second)),
+					exitCode: 1,
+				},
+				{
+					args:     []string{"terraform", "force-unlock", "-force", "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "state", "push", "foo.tfstate"},
+					exitCode: 0,
+				},
+			},
+			ok: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			terraformCLI.SetForceUnlockStale(time.Hour)
+			ctx := clock.WithClock(context.Background(), clock.Fixed(now))
+			_, _, err := terraformCLI.Run(ctx, "state", "push", "foo.tfstate")
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if calls := e.(*mockExecutor).newCommnadContextCalls; calls != len(tc.mockCommands) {
+				t.Errorf("got %d calls, want: %d", calls, len(tc.mockCommands))
+			}
+		})
+	}
+}
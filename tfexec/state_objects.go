@@ -0,0 +1,85 @@
+package tfexec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// StateObject is an object of a managed resource instance in state.
+// A resource instance has a current object and may have deposed objects,
+// which are left by create_before_destroy when destroying the old object
+// failed. Both of them are destroyed or replaced on the next apply.
+type StateObject struct {
+	// Address is an address of the resource instance.
+	// e.g.) module.foo.aws_instance.bar["a"]
+	Address string
+	// DeposedKey is a key of a deposed object.
+	// It is empty for a current object.
+	DeposedKey string
+	// Tainted is true if the object is tainted.
+	Tainted bool
+}
+
+// StateObjects returns a list of objects of managed resource instances in a
+// given state. The terraform state list command doesn't show deposed objects
+// and tainted status, so we read the state directly.
+// It returns an empty list for an empty state.
+func StateObjects(state *State) ([]StateObject, error) {
+	objects := []StateObject{}
+	if len(bytes.TrimSpace(state.Bytes())) == 0 {
+		return objects, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(state.Bytes()))
+	// keep numbers as they are.
+	dec.UseNumber()
+
+	var s map[string]interface{}
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %s", err)
+	}
+
+	version, ok := s["version"].(json.Number)
+	if !ok || version.String() != "4" {
+		return nil, fmt.Errorf("unsupported state version: %v", s["version"])
+	}
+
+	resources, _ := s["resources"].([]interface{})
+	for _, v := range resources {
+		r, ok := v.(map[string]interface{})
+		if !ok || r["mode"] != "managed" {
+			continue
+		}
+		address := stateResourceAddress(r, stringValue(r["type"]))
+		instances, _ := r["instances"].([]interface{})
+		for _, iv := range instances {
+			i, ok := iv.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			objects = append(objects, StateObject{
+				Address:    address + stateIndexKey(i["index_key"]),
+				DeposedKey: stringValue(i["deposed"]),
+				Tainted:    i["status"] == "tainted",
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+// stateIndexKey returns an index part of a resource instance address for a
+// given index_key in state. e.g.) [0], ["a"]
+// It returns an empty string if the resource doesn't have count or for_each.
+func stateIndexKey(key interface{}) string {
+	switch k := key.(type) {
+	case json.Number:
+		return "[" + k.String() + "]"
+	case string:
+		return "[" + strconv.Quote(k) + "]"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,128 @@
+package tfexec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStateObjects(t *testing.T) {
+	cases := []struct {
+		desc  string
+		state string
+		want  []StateObject
+		ok    bool
+	}{
+		{
+			desc: "deposed and tainted",
+			state: `{
+  "version": 4,
+  "terraform_version": "1.6.0",
+  "serial": 3,
+  "lineage": "foo",
+  "outputs": {},
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {
+          "schema_version": 1,
+          "attributes": {
+            "id": "i-new"
+          }
+        },
+        {
+          "deposed": "00000001",
+          "schema_version": 1,
+          "attributes": {
+            "id": "i-old"
+          }
+        }
+      ]
+    },
+    {
+      "module": "module.bar[\"a\"]",
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "bar",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {
+          "index_key": 0,
+          "status": "tainted",
+          "schema_version": 1,
+          "attributes": {
+            "id": "i-bar0"
+          }
+        },
+        {
+          "index_key": "b",
+          "schema_version": 1,
+          "attributes": {
+            "id": "i-barb"
+          }
+        }
+      ]
+    },
+    {
+      "mode": "data",
+      "type": "aws_ami",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {
+            "id": "ami-123"
+          }
+        }
+      ]
+    }
+  ]
+}
+`,
+			want: []StateObject{
+				{Address: "aws_instance.foo"},
+				{Address: "aws_instance.foo", DeposedKey: "00000001"},
+				{Address: `module.bar["a"].aws_instance.bar[0]`, Tainted: true},
+				{Address: `module.bar["a"].aws_instance.bar["b"]`},
+			},
+			ok: true,
+		},
+		{
+			desc:  "empty state",
+			state: "",
+			want:  []StateObject{},
+			ok:    true,
+		},
+		{
+			desc:  "legacy state",
+			state: `{"version": 3}`,
+			want:  nil,
+			ok:    false,
+		},
+		{
+			desc:  "invalid json",
+			state: `{`,
+			want:  nil,
+			ok:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := StateObjects(NewState([]byte(tc.state)))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package tfexec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RelinkDependencies returns a new state in which dependencies of resources
+// are updated after moving resources from a given state before to a given
+// state after, and a list of resources whose dependencies are updated.
+// The terraform state mv command doesn't update dependencies, so we rewrite
+// the state directly.
+// A dependency which no longer exists is rewritten to a new address if it is
+// moved by a given map of moves from a source address to a destination
+// address, or pruned if it existed in the state before, which means it is
+// moved away or removed. A dependency which didn't exist before is kept as it
+// is, because it is none of our business.
+// The state after is returned as is if nothing is changed.
+func RelinkDependencies(before *State, after *State, moves map[string]string) (*State, []string, error) {
+	beforeState, err := decodeStateV4(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterState, err := decodeStateV4(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	existedBefore := stateConfigResources(beforeState)
+	existsAfter := stateConfigResources(afterState)
+
+	// Dependencies are recorded as addresses without instance keys.
+	configMoves := make(map[string]string, len(moves))
+	for source, destination := range moves {
+		configMoves[configAddress(source)] = configAddress(destination)
+	}
+
+	changed := []string{}
+	resources, _ := afterState["resources"].([]interface{})
+	for _, v := range resources {
+		r, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		updated := false
+		instances, _ := r["instances"].([]interface{})
+		for _, iv := range instances {
+			i, ok := iv.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			deps, ok := i["dependencies"].([]interface{})
+			if !ok {
+				continue
+			}
+			newDeps := []interface{}{}
+			seen := make(map[string]bool)
+			for _, d := range deps {
+				dep := stringValue(d)
+				switch {
+				case existsAfter[dep]:
+				case existsAfter[moveAddress(configMoves, dep)]:
+					dep = moveAddress(configMoves, dep)
+				case existedBefore[dep]:
+					dep = ""
+				}
+				if len(dep) == 0 || seen[dep] {
+					updated = true
+					continue
+				}
+				if dep != stringValue(d) {
+					updated = true
+				}
+				seen[dep] = true
+				newDeps = append(newDeps, dep)
+			}
+			i["dependencies"] = newDeps
+		}
+		if updated {
+			changed = append(changed, stateConfigResourceAddress(r))
+		}
+	}
+
+	if len(changed) == 0 {
+		return after, changed, nil
+	}
+	sort.Strings(changed)
+
+	b, err := json.MarshalIndent(afterState, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode state: %s", err)
+	}
+
+	return NewState(append(b, '\n')), changed, nil
+}
+
+// decodeStateV4 parses a given state in the format version 4.
+func decodeStateV4(state *State) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(state.Bytes()))
+	// keep numbers as they are.
+	dec.UseNumber()
+
+	var s map[string]interface{}
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %s", err)
+	}
+
+	version, ok := s["version"].(json.Number)
+	if !ok || version.String() != "4" {
+		return nil, fmt.Errorf("unsupported state version: %v", s["version"])
+	}
+	return s, nil
+}
+
+// stateConfigResources returns a set of addresses without instance keys of
+// resources in a given state.
+func stateConfigResources(s map[string]interface{}) map[string]bool {
+	addresses := make(map[string]bool)
+	resources, _ := s["resources"].([]interface{})
+	for _, v := range resources {
+		if r, ok := v.(map[string]interface{}); ok {
+			addresses[stateConfigResourceAddress(r)] = true
+		}
+	}
+	return addresses
+}
+
+// stateConfigResourceAddress returns an address without instance keys of a
+// given resource in state, which is the same format as dependencies.
+// e.g.) module.foo.aws_instance.bar, data.aws_ami.foo
+func stateConfigResourceAddress(r map[string]interface{}) string {
+	resourceType := stringValue(r["type"])
+	if r["mode"] == "data" {
+		resourceType = "data." + resourceType
+	}
+	return configAddress(stateResourceAddress(r, resourceType))
+}
+
+// moveAddress returns a new address of a given address moved by a given map of
+// moves. An address under a moved module is also moved.
+// It returns an empty string if the address is not moved.
+func moveAddress(moves map[string]string, address string) string {
+	for source, destination := range moves {
+		if address == source {
+			return destination
+		}
+		if strings.HasPrefix(address, source+".") {
+			return destination + address[len(source):]
+		}
+	}
+	return ""
+}
+
+// configAddress returns a given address without instance keys.
+// e.g.) module.foo["a"].aws_instance.bar[0] => module.foo.aws_instance.bar
+func configAddress(address string) string {
+	var b strings.Builder
+	depth := 0
+	quoted := false
+	for i := 0; i < len(address); i++ {
+		c := address[i]
+		switch {
+		case quoted:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quoted = false
+			}
+			continue
+		case c == '"' && depth > 0:
+			quoted = true
+			continue
+		case c == '[':
+			depth++
+			continue
+		case c == ']':
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,191 @@
+package tfexec
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRelinkDependencies(t *testing.T) {
+	before := `{
+  "version": 4,
+  "serial": 3,
+  "lineage": "foo",
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_vpc",
+      "name": "foo",
+      "instances": [{"attributes": {"id": "vpc-1"}}]
+    },
+    {
+      "module": "module.net[\"a\"]",
+      "mode": "managed",
+      "type": "aws_subnet",
+      "name": "foo",
+      "instances": [{"index_key": 0, "attributes": {"id": "subnet-1"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_security_group",
+      "name": "foo",
+      "instances": [{"attributes": {"id": "sg-1"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "foo",
+      "instances": [
+        {
+          "attributes": {"id": "i-1"},
+          "dependencies": ["aws_security_group.foo", "aws_vpc.foo", "module.net.aws_subnet.foo", "aws_iam_role.unknown"]
+        }
+      ]
+    }
+  ]
+}`
+	after := `{
+  "version": 4,
+  "serial": 4,
+  "lineage": "foo",
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_vpc",
+      "name": "bar",
+      "instances": [{"attributes": {"id": "vpc-1"}}]
+    },
+    {
+      "module": "module.network[\"a\"]",
+      "mode": "managed",
+      "type": "aws_subnet",
+      "name": "foo",
+      "instances": [{"index_key": 0, "attributes": {"id": "subnet-1"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "foo",
+      "instances": [
+        {
+          "attributes": {"id": "i-1"},
+          "dependencies": ["aws_security_group.foo", "aws_vpc.foo", "module.net.aws_subnet.foo", "aws_iam_role.unknown"]
+        }
+      ]
+    }
+  ]
+}`
+
+	cases := []struct {
+		desc        string
+		before      string
+		after       string
+		moves       map[string]string
+		wantDeps    []interface{}
+		wantChanged []string
+		ok          bool
+	}{
+		{
+			desc:   "moved, pruned and unknown",
+			before: before,
+			after:  after,
+			moves: map[string]string{
+				"aws_vpc.foo":     "aws_vpc.bar",
+				`module.net["a"]`: `module.network["a"]`,
+			},
+			wantDeps:    []interface{}{"aws_vpc.bar", "module.network.aws_subnet.foo", "aws_iam_role.unknown"},
+			wantChanged: []string{"aws_instance.foo"},
+			ok:          true,
+		},
+		{
+			desc:        "no moves",
+			before:      before,
+			after:       before,
+			moves:       nil,
+			wantDeps:    []interface{}{"aws_security_group.foo", "aws_vpc.foo", "module.net.aws_subnet.foo", "aws_iam_role.unknown"},
+			wantChanged: []string{},
+			ok:          true,
+		},
+		{
+			desc:   "legacy state",
+			before: `{"version": 3}`,
+			after:  after,
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, changed, err := RelinkDependencies(NewState([]byte(tc.before)), NewState([]byte(tc.after)), tc.moves)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", string(got.Bytes()))
+			}
+			if !tc.ok {
+				return
+			}
+			if !reflect.DeepEqual(changed, tc.wantChanged) {
+				t.Errorf("got changed: %#v, want: %#v", changed, tc.wantChanged)
+			}
+
+			var s struct {
+				Serial    int `json:"serial"`
+				Resources []struct {
+					Type      string `json:"type"`
+					Instances []struct {
+						Dependencies []interface{} `json:"dependencies"`
+					} `json:"instances"`
+				} `json:"resources"`
+			}
+			if err := json.Unmarshal(got.Bytes(), &s); err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+			for _, r := range s.Resources {
+				if r.Type != "aws_instance" {
+					continue
+				}
+				if deps := r.Instances[0].Dependencies; !reflect.DeepEqual(deps, tc.wantDeps) {
+					t.Errorf("got deps: %#v, want: %#v", deps, tc.wantDeps)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigAddress(t *testing.T) {
+	cases := []struct {
+		address string
+		want    string
+	}{
+		{address: "aws_instance.foo", want: "aws_instance.foo"},
+		{address: "aws_instance.foo[0]", want: "aws_instance.foo"},
+		{address: `module.foo["a.b]"].aws_instance.bar["c"]`, want: "module.foo.aws_instance.bar"},
+		{address: `module.foo["a\"]"].aws_instance.bar`, want: "module.foo.aws_instance.bar"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.address, func(t *testing.T) {
+			got := configAddress(tc.address)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func FuzzConfigAddress(f *testing.F) {
+	for _, key := range []string{"a", "a.b]", `a"]`, `\`, "[0]", "日本語"} {
+		f.Add(key)
+	}
+
+	f.Fuzz(func(t *testing.T, key string) {
+		// An instance key in state is quoted in the same way as state list.
+		k := stateIndexKey(key)
+		address := "module.foo" + k + ".aws_instance.bar" + k
+		if got, want := configAddress(address), "module.foo.aws_instance.bar"; got != want {
+			t.Errorf("got: %s, want: %s", got, want)
+		}
+	})
+}
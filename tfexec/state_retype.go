@@ -0,0 +1,154 @@
+package tfexec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RetypedResource is a resource whose type is rewritten by RetypeState.
+type RetypedResource struct {
+	// Source is an address of the resource before rewriting.
+	// e.g.) module.foo.aws_alb.bar
+	Source string
+	// Destination is an address of the resource after rewriting.
+	// e.g.) module.foo.aws_lb.bar
+	Destination string
+	// Provider is a provider address of the resource.
+	// e.g.) registry.terraform.io/hashicorp/aws
+	Provider string
+	// SchemaVersion is the maximum schema version of its instances.
+	SchemaVersion int64
+}
+
+// RetypeState returns a new state in which managed resources of a given source
+// type are rewritten to a given destination type, and a list of them.
+// The terraform state mv command refuses to move a resource to another type,
+// so we rewrite the state directly. It is intended for resource types renamed
+// by provider upgrades, which share the same schema.
+// References to the resources in dependencies of other resources are also
+// rewritten, and the serial is incremented.
+func RetypeState(state *State, source string, destination string) (*State, []RetypedResource, error) {
+	dec := json.NewDecoder(bytes.NewReader(state.Bytes()))
+	// keep numbers as they are.
+	dec.UseNumber()
+
+	var s map[string]interface{}
+	if err := dec.Decode(&s); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse state: %s", err)
+	}
+
+	version, ok := s["version"].(json.Number)
+	if !ok || version.String() != "4" {
+		return nil, nil, fmt.Errorf("unsupported state version: %v", s["version"])
+	}
+
+	resources, _ := s["resources"].([]interface{})
+	existing := make(map[string]bool)
+	for _, v := range resources {
+		if r, ok := v.(map[string]interface{}); ok && r["mode"] == "managed" {
+			existing[stateResourceAddress(r, stringValue(r["type"]))] = true
+		}
+	}
+
+	retyped := []RetypedResource{}
+	renames := make(map[string]string)
+	for _, v := range resources {
+		r, ok := v.(map[string]interface{})
+		if !ok || r["mode"] != "managed" || r["type"] != source {
+			continue
+		}
+		rr := RetypedResource{
+			Source:      stateResourceAddress(r, source),
+			Destination: stateResourceAddress(r, destination),
+			Provider:    stateProviderAddress(stringValue(r["provider"])),
+		}
+		if existing[rr.Destination] {
+			return nil, nil, fmt.Errorf("failed to retype %s: %s already exists", rr.Source, rr.Destination)
+		}
+		instances, _ := r["instances"].([]interface{})
+		for _, iv := range instances {
+			if i, ok := iv.(map[string]interface{}); ok {
+				if n, ok := i["schema_version"].(json.Number); ok {
+					if sv, err := n.Int64(); err == nil && sv > rr.SchemaVersion {
+						rr.SchemaVersion = sv
+					}
+				}
+			}
+		}
+		r["type"] = destination
+		renames[rr.Source] = rr.Destination
+		retyped = append(retyped, rr)
+	}
+
+	// rewrite references to the retyped resources.
+	for _, v := range resources {
+		r, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		instances, _ := r["instances"].([]interface{})
+		for _, iv := range instances {
+			i, ok := iv.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			deps, _ := i["dependencies"].([]interface{})
+			for k, d := range deps {
+				if to, ok := renames[stringValue(d)]; ok {
+					deps[k] = to
+				}
+			}
+		}
+	}
+
+	if serial, ok := s["serial"].(json.Number); ok {
+		n, err := serial.Int64()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse serial: %s", err)
+		}
+		s["serial"] = n + 1
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode state: %s", err)
+	}
+
+	return NewState(append(b, '\n')), retyped, nil
+}
+
+// stateResourceAddress returns an address of a given resource in state with a
+// given type. e.g.) module.foo.aws_lb.bar
+func stateResourceAddress(r map[string]interface{}, resourceType string) string {
+	address := resourceType + "." + stringValue(r["name"])
+	if module := stringValue(r["module"]); len(module) != 0 {
+		address = module + "." + address
+	}
+	return address
+}
+
+// stateProviderAddress returns a provider address from a provider
+// configuration address in state.
+// e.g.) module.foo.provider["registry.terraform.io/hashicorp/aws"].west
+// => registry.terraform.io/hashicorp/aws
+func stateProviderAddress(s string) string {
+	start := strings.Index(s, `provider["`)
+	if start == -1 {
+		return ""
+	}
+	rest := s[start+len(`provider["`):]
+	end := strings.Index(rest, `"]`)
+	if end == -1 {
+		return ""
+	}
+	return rest[:end]
+}
+
+// stringValue returns a string if a given value is a string, otherwise an
+// empty string.
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
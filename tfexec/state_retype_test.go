@@ -0,0 +1,177 @@
+package tfexec
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRetypeState(t *testing.T) {
+	source := `{
+  "version": 4,
+  "terraform_version": "1.6.0",
+  "serial": 3,
+  "lineage": "foo",
+  "outputs": {},
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_alb",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {
+            "id": "arn:aws:elasticloadbalancing:foo"
+          }
+        }
+      ]
+    },
+    {
+      "module": "module.bar",
+      "mode": "managed",
+      "type": "aws_alb",
+      "name": "bar",
+      "provider": "module.bar.provider[\"registry.terraform.io/hashicorp/aws\"].west",
+      "instances": [
+        {
+          "index_key": 0,
+          "schema_version": 1,
+          "attributes": {
+            "id": "arn:aws:elasticloadbalancing:bar"
+          }
+        }
+      ]
+    },
+    {
+      "mode": "data",
+      "type": "aws_alb",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": []
+    },
+    {
+      "mode": "managed",
+      "type": "aws_alb_listener",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {
+            "load_balancer_arn": "arn:aws:elasticloadbalancing:foo"
+          },
+          "dependencies": [
+            "aws_alb.foo"
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+
+	cases := []struct {
+		desc        string
+		state       string
+		source      string
+		destination string
+		want        []RetypedResource
+		ok          bool
+	}{
+		{
+			desc:        "simple",
+			state:       source,
+			source:      "aws_alb",
+			destination: "aws_lb",
+			want: []RetypedResource{
+				{
+					Source:        "aws_alb.foo",
+					Destination:   "aws_lb.foo",
+					Provider:      "registry.terraform.io/hashicorp/aws",
+					SchemaVersion: 0,
+				},
+				{
+					Source:        "module.bar.aws_alb.bar",
+					Destination:   "module.bar.aws_lb.bar",
+					Provider:      "registry.terraform.io/hashicorp/aws",
+					SchemaVersion: 1,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc:        "no match",
+			state:       source,
+			source:      "aws_elb",
+			destination: "aws_lb",
+			want:        []RetypedResource{},
+			ok:          true,
+		},
+		{
+			desc:        "destination already exists",
+			state:       source,
+			source:      "aws_alb",
+			destination: "aws_alb_listener",
+			ok:          false,
+		},
+		{
+			desc:        "unsupported version",
+			state:       `{"version": 3}`,
+			source:      "aws_alb",
+			destination: "aws_lb",
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, retyped, err := RetypeState(NewState([]byte(tc.state)), tc.source, tc.destination)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if !tc.ok {
+				return
+			}
+			if !reflect.DeepEqual(retyped, tc.want) {
+				t.Errorf("got = %#v, want = %#v", retyped, tc.want)
+			}
+
+			var s struct {
+				Serial    int `json:"serial"`
+				Resources []struct {
+					Mode      string `json:"mode"`
+					Type      string `json:"type"`
+					Instances []struct {
+						Dependencies []string `json:"dependencies"`
+					} `json:"instances"`
+				} `json:"resources"`
+			}
+			if err := json.Unmarshal(got.Bytes(), &s); err != nil {
+				t.Fatalf("failed to parse a new state: %s", err)
+			}
+			if s.Serial != 4 {
+				t.Errorf("got serial = %d, want = 4", s.Serial)
+			}
+			if len(tc.want) == 0 {
+				return
+			}
+			types := []string{}
+			for _, r := range s.Resources {
+				types = append(types, r.Mode+"."+r.Type)
+			}
+			wantTypes := []string{"managed.aws_lb", "managed.aws_lb", "data.aws_alb", "managed.aws_alb_listener"}
+			if !reflect.DeepEqual(types, wantTypes) {
+				t.Errorf("got types = %v, want = %v", types, wantTypes)
+			}
+			deps := s.Resources[3].Instances[0].Dependencies
+			if !reflect.DeepEqual(deps, []string{"aws_lb.foo"}) {
+				t.Errorf("got dependencies = %v, want = [aws_lb.foo]", deps)
+			}
+		})
+	}
+}
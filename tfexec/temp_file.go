@@ -0,0 +1,135 @@
+package tfexec
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TempFilePrefix is a prefix of temporary files such as states and plans
+// written by tfmigrate, which is used to find leftovers of crashed runs.
+const TempFilePrefix = "tfmigrate-"
+
+// SetTempDir sets a directory where temporary files such as states and plans
+// are written.
+func (c *terraformCLI) SetTempDir(dir string) {
+	c.tempDir = dir
+}
+
+// SetKeepTemp sets a flag to keep temporary files for debugging.
+func (c *terraformCLI) SetKeepTemp(keep bool) {
+	c.keepTemp = keep
+}
+
+// WriteTempFile writes content to a temporary file in the temp dir and
+// returns its file.
+func (c *terraformCLI) WriteTempFile(content []byte) (*os.File, error) {
+	return writeTempFile(c.tempDir, content)
+}
+
+// RemoveTempFile overwrites a given temporary file with zeros and removes it
+// unless keeping temporary files is enabled.
+// It is intended to be called with defer, so that temporary files are removed
+// even if an error occurs. Errors are only logged.
+func (c *terraformCLI) RemoveTempFile(name string) {
+	if c.keepTemp {
+		log.Printf("[INFO] [executor@%s] keep a temporary file: %s\n", c.Dir(), name)
+		return
+	}
+
+	if err := shredFile(name); err != nil {
+		log.Printf("[WARN] [executor@%s] failed to overwrite a temporary file: %s, err: %s\n", c.Dir(), name, err)
+	}
+
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		log.Printf("[WARN] [executor@%s] failed to remove a temporary file: %s, err: %s\n", c.Dir(), name, err)
+	}
+}
+
+// shredFile overwrites contents of a given file with zeros.
+// Note that it's best-effort. It doesn't guarantee that the original data
+// cannot be recovered on journaling or copy-on-write filesystems and SSDs.
+// Use an encrypted tmpfs as the temp dir if you need a stronger guarantee.
+func shredFile(name string) error {
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	zeros := make([]byte, 32*1024)
+	for remaining := fi.Size(); remaining > 0; {
+		n := min(remaining, int64(len(zeros)))
+		if _, err := f.Write(zeros[:n]); err != nil {
+			return err
+		}
+		remaining -= n
+	}
+
+	return f.Sync()
+}
+
+// StaleTempFiles returns a list of temporary files in a given dir which were
+// written by tfmigrate and have not been modified for a given duration.
+// It also includes lock files of the local backend for temporary states,
+// which are named like .tfmigrate-123.lock.info.
+// The returned slice is sorted alphabetically.
+func StaleTempFiles(dir string, olderThan time.Duration, now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read temp dir: %s", err)
+	}
+
+	stale := []string{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !(strings.HasPrefix(name, TempFilePrefix) || isTempLockFile(name)) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				// removed by a live run after reading the dir.
+				continue
+			}
+			return nil, err
+		}
+		if now.Sub(fi.ModTime()) < olderThan {
+			continue
+		}
+		stale = append(stale, filepath.Join(dir, name))
+	}
+
+	return stale, nil
+}
+
+// isTempLockFile returns true if a given file name is a lock file of the
+// local backend for a temporary state.
+func isTempLockFile(name string) bool {
+	return strings.HasPrefix(name, "."+TempFilePrefix) && strings.HasSuffix(name, ".lock.info")
+}
+
+// RemoveStaleTempFile overwrites a given stale temporary file with zeros and
+// removes it. Unlike RemoveTempFile, it returns an error.
+func RemoveStaleTempFile(name string) error {
+	if err := shredFile(name); err != nil {
+		return fmt.Errorf("failed to overwrite a temporary file: %s, err: %s", name, err)
+	}
+
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove a temporary file: %s, err: %s", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package tfexec
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTerraformCLITempFile(t *testing.T) {
+	cases := []struct {
+		desc     string
+		keepTemp bool
+		wantKept bool
+	}{
+		{
+			desc:     "remove",
+			keepTemp: false,
+			wantKept: false,
+		},
+		{
+			desc:     "keep",
+			keepTemp: true,
+			wantKept: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tempDir := t.TempDir()
+			terraformCLI := NewTerraformCLI(NewMockExecutor(nil))
+			terraformCLI.SetTempDir(tempDir)
+			terraformCLI.SetKeepTemp(tc.keepTemp)
+
+			f, err := terraformCLI.WriteTempFile([]byte("secret"))
+			if err != nil {
+				t.Fatalf("failed to WriteTempFile: %s", err)
+			}
+			if got := filepath.Dir(f.Name()); got != tempDir {
+				t.Errorf("got dir = %s, but want = %s", got, tempDir)
+			}
+
+			terraformCLI.RemoveTempFile(f.Name())
+
+			_, err = os.Stat(f.Name())
+			if tc.wantKept && err != nil {
+				t.Errorf("expected to keep a temporary file, but got err: %s", err)
+			}
+			if !tc.wantKept && !os.IsNotExist(err) {
+				t.Errorf("expected to remove a temporary file, but got err: %v", err)
+			}
+		})
+	}
+}
+
+func TestShredFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "state")
+	if err := os.WriteFile(name, []byte("secret"), 0600); err != nil {
+		t.Fatalf("failed to write a file: %s", err)
+	}
+
+	if err := shredFile(name); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("failed to read a file: %s", err)
+	}
+	want := make([]byte, len("secret"))
+	if string(got) != string(want) {
+		t.Errorf("got = %q, but want = %q", got, want)
+	}
+
+	if err := shredFile(filepath.Join(t.TempDir(), "not-exist")); err != nil {
+		t.Errorf("unexpected err for a non-existent file: %s", err)
+	}
+}
+
+func TestStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2020, 11, 10, 0, 0, 0, 0, time.UTC)
+	files := map[string]time.Time{
+		"tfmigrate-1":                  now.Add(-48 * time.Hour),
+		"tfmigrate-2":                  now.Add(-1 * time.Hour),
+		".tfmigrate-1.lock.info":       now.Add(-48 * time.Hour),
+		"foo":                          now.Add(-48 * time.Hour),
+		".terraform.tfstate.lock.info": now.Add(-48 * time.Hour),
+	}
+	for name, mtime := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("secret"), 0600); err != nil {
+			t.Fatalf("failed to write a file: %s", err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("failed to change times: %s", err)
+		}
+	}
+
+	got, err := StaleTempFiles(dir, 24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	want := []string{
+		filepath.Join(dir, ".tfmigrate-1.lock.info"),
+		filepath.Join(dir, "tfmigrate-1"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %#v, but want = %#v", got, want)
+	}
+
+	for _, name := range got {
+		if err := RemoveStaleTempFile(name); err != nil {
+			t.Fatalf("failed to remove a stale temp file: %s", err)
+		}
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("expected to remove a stale temp file, but got err: %v", err)
+		}
+	}
+}
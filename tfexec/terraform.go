@@ -3,10 +3,14 @@ package tfexec
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	"github.com/mattn/go-shellwords"
@@ -68,10 +72,26 @@ type TerraformCLI interface {
 	// If a state is given, use it for the input state.
 	Plan(ctx context.Context, state *State, opts ...string) (*Plan, error)
 
+	// PlanJSON computes expected changes and returns the plan in JSON.
+	// If a state is given, use it for the input state.
+	PlanJSON(ctx context.Context, state *State, opts ...string) (*JSONPlan, error)
+
+	// ShowPlanJSON returns a given plan in JSON.
+	ShowPlanJSON(ctx context.Context, plan *Plan) (*JSONPlan, error)
+
 	// Apply applies changes.
 	// If a plan is given, use it for the input plan.
 	Apply(ctx context.Context, plan *Plan, opts ...string) error
 
+	// Fmt rewrites configuration files in a given directory to a canonical format.
+	// If the dir is empty, it formats the current working directory.
+	Fmt(ctx context.Context, dir string, opts ...string) error
+
+	// FmtCheck checks whether configuration files in a given directory are
+	// formatted and returns a list of files which are not formatted.
+	// It doesn't modify any file.
+	FmtCheck(ctx context.Context, dir string, opts ...string) ([]string, error)
+
 	// Destroy destroys resources.
 	Destroy(ctx context.Context, opts ...string) error
 
@@ -83,10 +103,35 @@ type TerraformCLI interface {
 	// their provider requirements.
 	Providers(ctx context.Context) (string, error)
 
+	// ProvidersTree returns a tree of modules in the referenced configuration
+	// annotated with their provider requirements.
+	ProvidersTree(ctx context.Context) (*ModuleProviders, error)
+
+	// RequiredProviders returns a list of providers required by configuration
+	// in a given directory with their version constraints.
+	// If the dir is empty, it refers the current working directory.
+	RequiredProviders(ctx context.Context, dir string) ([]RequiredProvider, error)
+
+	// ProvidersMirror saves local copies of all required provider plugins to a
+	// given directory, which can be used as a filesystem mirror.
+	ProvidersMirror(ctx context.Context, targetDir string, opts ...string) error
+
+	// ProvidersLock updates the dependency lock file with checksums of
+	// required providers.
+	ProvidersLock(ctx context.Context, opts ...string) error
+
+	// ProvidersSchema returns schemas of providers used in the configuration.
+	// The working directory must have been initialized.
+	ProvidersSchema(ctx context.Context) (*ProvidersSchema, error)
+
 	// StateList shows a list of resources.
 	// If a state is given, use it for the input state.
 	StateList(ctx context.Context, state *State, addresses []string, opts ...string) ([]string, error)
 
+	// StateShow shows attributes of a single resource instance in state.
+	// If a state is given, use it for the input state.
+	StateShow(ctx context.Context, state *State, address string, opts ...string) (map[string]string, error)
+
 	// StatePull returns the current tfstate from remote.
 	StatePull(ctx context.Context, opts ...string) (*State, error)
 
@@ -112,15 +157,37 @@ type TerraformCLI interface {
 	// StatePush pushes a given State to remote.
 	StatePush(ctx context.Context, state *State, opts ...string) error
 
+	// StatePullFromWorkspace selects a given workspace, pulls its state, and
+	// restores the previously selected workspace even if it fails.
+	StatePullFromWorkspace(ctx context.Context, workspace string, opts ...string) (*State, error)
+
+	// StatePushToWorkspace selects a given workspace, pushes a given state to
+	// it, and restores the previously selected workspace even if it fails.
+	StatePushToWorkspace(ctx context.Context, workspace string, state *State, opts ...string) error
+
+	// ForceUnlock removes a lock of the current state with a given lock ID.
+	ForceUnlock(ctx context.Context, lockID string) error
+
 	// WorkspaceNew creates a new workspace with name "workspace".
 	WorkspaceNew(ctx context.Context, workspace string, opts ...string) error
 
 	// WorkspaceShow returns the current selected workspace.
 	WorkspaceShow(ctx context.Context) (string, error)
 
+	// WorkspaceList returns a list of workspaces in the backend.
+	WorkspaceList(ctx context.Context) ([]string, error)
+
 	// WorkspaceSelect switches to the workspace with name "workspace". This workspace should already exist.
 	WorkspaceSelect(ctx context.Context, workspace string) error
 
+	// SupportsWorkspaceSelectOrCreate returns true if terraform version
+	// supports the -or-create flag for workspace select.
+	SupportsWorkspaceSelectOrCreate(ctx context.Context) (bool, error)
+
+	// WorkspaceSelectOrCreate switches to the workspace with name "workspace",
+	// and creates it if it doesn't exist.
+	WorkspaceSelectOrCreate(ctx context.Context, workspace string) error
+
 	// Run is a low-level generic method for running an arbitrary terraform command.
 	Run(ctx context.Context, args ...string) (string, string, error)
 
@@ -131,13 +198,83 @@ type TerraformCLI interface {
 	// It's intended to inject a wrapper command such as direnv.
 	SetExecPath(execPath string)
 
+	// SetExecMode sets a mode of executing the terraform command.
+	// Valid values are ExecModeTerraform and ExecModeTerragrunt.
+	// In terragrunt mode, the terraform command is invoked via terragrunt,
+	// and the default exec path is terragrunt.
+	SetExecMode(mode string)
+
+	// SetExecContainer customizes the terraform command to run inside a
+	// container. Set nil to run it on the host.
+	SetExecContainer(container *ExecContainer)
+
+	// SetRetryPolicy sets a policy to retry a command which fails with a
+	// transient error such as state lock contention. Set nil to disable it.
+	// Note that outputs of a failed attempt have already been copied to the
+	// UI stream when it's retried.
+	SetRetryPolicy(policy *RetryPolicy)
+
+	// SetForceUnlockStale sets a threshold of age of a stale lock. If a
+	// command fails because a lock older than it is held by someone else, the
+	// lock is removed and the command is retried once. Set zero to disable it.
+	// A lock held by someone else is always logged regardless of it.
+	SetForceUnlockStale(d time.Duration)
+
+	// SetLockOptions sets options of state locking such as -lock-timeout,
+	// which are passed to every command locking a state, unless the command
+	// is given the same flags explicitly. Set nil to disable it.
+	SetLockOptions(o *LockOptions)
+
+	// SetTempDir sets a directory where temporary files such as states and
+	// plans are written. e.g.) an encrypted tmpfs
+	// If empty, the default directory for temporary files is used.
+	SetTempDir(dir string)
+
+	// SetKeepTemp sets a flag to keep temporary files for debugging instead of
+	// removing them.
+	SetKeepTemp(keep bool)
+
+	// SetReadOnly sets a flag to refuse any command which may mutate remote
+	// states or real resources, such as apply and state push.
+	SetReadOnly(readOnly bool)
+
+	// SetInitLock sets a lock held while running terraform init, so that
+	// concurrent inits sharing a plugin cache dir are serialized, which is not
+	// safe for concurrent use. Set nil to disable it.
+	SetInitLock(l sync.Locker)
+
+	// WriteTempFile writes content to a temporary file in the temp dir and
+	// returns its file. The file is closed. The caller must remove it with
+	// RemoveTempFile.
+	WriteTempFile(content []byte) (*os.File, error)
+
+	// RemoveTempFile overwrites a given temporary file with zeros and removes
+	// it unless keeping temporary files is enabled.
+	RemoveTempFile(name string)
+
+	// AppendEnv appends an environment variable passed to the terraform
+	// command. It takes precedence over one inherited from the current process.
+	AppendEnv(key string, value string)
+
+	// SetHostToken sets an API token for a given hostname of a private registry
+	// or Terraform Cloud with a TF_TOKEN_hostname environment variable.
+	SetHostToken(hostname string, token string) error
+
+	// SetUIStream sets a channel to which machine-readable UI messages are sent.
+	// If set, Plan, Apply and Destroy run with the -json flag and send parsed
+	// messages to the channel while running. The caller must keep receiving
+	// from the channel, otherwise the command blocks. The channel is never
+	// closed by the TerraformCLI. Set nil to disable it.
+	SetUIStream(ch chan<- *UIMessage)
+
 	// OverrideBackendToLocal switches the backend to local and returns a function
 	// to switch it back to remote with defer.
 	// The -state flag for terraform command is not valid for remote state,
 	// so we need to switch the backend to local for temporary state operations.
 	// The filename argument must meet constraints for override file.
 	// (e.g.) _tfexec_override.tf
-	OverrideBackendToLocal(ctx context.Context, filename string, workspace string, isBackendTerraformCloud bool, backendConfig []string, supportsStateReplaceProvider bool) (func() error, error)
+	// The initOpts are passed to terraform init for switching backends.
+	OverrideBackendToLocal(ctx context.Context, filename string, workspace string, isBackendTerraformCloud bool, backendConfig []string, supportsStateReplaceProvider bool, initOpts ...string) (func() error, error)
 
 	// PlanHasChange is a helper method which runs plan and return true if the plan has change.
 	PlanHasChange(ctx context.Context, state *State, opts ...string) (bool, error)
@@ -145,6 +282,10 @@ type TerraformCLI interface {
 	// SupportsStateReplaceProvider is a helper method used to determine whether or
 	// not the terraform version supports `state replace-provider`.
 	SupportsStateReplaceProvider(ctx context.Context) (bool, version.Constraints, error)
+
+	// SupportsUIStream is a helper method used to determine whether or not the
+	// terraform version supports the -json flag for plan, apply and destroy.
+	SupportsUIStream(ctx context.Context) (bool, error)
 }
 
 // terraformCLI implements the TerraformCLI interface.
@@ -155,13 +296,58 @@ type terraformCLI struct {
 	// execPath is a string which executes the terraform command.
 	// Default to terraform. To use OpenTofu, set this to `tofu`.
 	execPath string
+
+	// execMode is a mode of executing the terraform command.
+	// If empty, it's the same as ExecModeTerraform.
+	execMode string
+
+	// goos is an operating system on which the terraform command runs, which
+	// decides how to parse execPath. Default to runtime.GOOS. It's intended
+	// to be overridden in tests.
+	goos string
+
+	// uiStream is a channel to which machine-readable UI messages are sent.
+	uiStream chan<- *UIMessage
+
+	// container is a config for running the terraform command inside a container.
+	// If nil, the terraform command runs on the host.
+	container *ExecContainer
+
+	// retry is a policy to retry a command which fails with a transient
+	// error. If nil, a command is never retried.
+	retry *RetryPolicy
+
+	// forceUnlockStale is a threshold of age of a stale lock to be removed
+	// automatically. If zero, a lock is never removed.
+	forceUnlockStale time.Duration
+
+	// lockOptions is a set of options of state locking passed to every
+	// command locking a state. If nil, no options are passed.
+	lockOptions *LockOptions
+
+	// tempDir is a directory where temporary files such as states and plans
+	// are written. If empty, the default directory for temporary files is used.
+	tempDir string
+
+	// keepTemp is a flag to keep temporary files for debugging.
+	keepTemp bool
+
+	// readOnly is a flag to refuse any command which may mutate remote states
+	// or real resources.
+	readOnly bool
+
+	// initLock is a lock held while running terraform init.
+	// If nil, init is never serialized.
+	initLock sync.Locker
 }
 
 var _ TerraformCLI = (*terraformCLI)(nil)
 
 // NewTerraformCLI returns an implementation of the TerraformCLI interface.
 // This function reads the environment variable TFMIGRATE_EXEC_PATH and sets it
-// to execPath.
+// to execPath. It also reads TFMIGRATE_EXEC_CONTAINER_IMAGE,
+// TFMIGRATE_EXEC_CONTAINER_RUNTIME and TFMIGRATE_EXEC_CONTAINER_USER to run
+// the terraform command inside a container.
 func NewTerraformCLI(e Executor) TerraformCLI {
 	execPath := os.Getenv("TFMIGRATE_EXEC_PATH")
 	if len(execPath) == 0 {
@@ -170,19 +356,53 @@ func NewTerraformCLI(e Executor) TerraformCLI {
 	}
 
 	return &terraformCLI{
-		Executor: e,
-		execPath: execPath,
+		Executor:  e,
+		execPath:  execPath,
+		goos:      runtime.GOOS,
+		container: newExecContainerFromEnv(),
 	}
 }
 
 // Run is a low-level generic method for running an arbitrary terraform command.
 func (c *terraformCLI) Run(ctx context.Context, args ...string) (string, string, error) {
+	return c.run(ctx, nil, args...)
+}
+
+// run runs a terraform command. If a stdoutTee is given, outputs of stdout
+// are also copied to it while running.
+func (c *terraformCLI) run(ctx context.Context, stdoutTee io.Writer, args ...string) (string, string, error) {
+	if c.readOnly {
+		if err := checkReadOnly(args); err != nil {
+			return "", "", err
+		}
+	}
+
+	args = c.lockOptions.withLockFlags(args)
+
+	// Keep the subcommand before wrapping args.
+	subcommand := ""
+	if len(args) > 0 {
+		subcommand = args[0]
+	}
+
 	name := c.execPath
+	if c.execMode == ExecModeTerragrunt {
+		if name == "terraform" {
+			// The default binary path is `terragrunt` in terragrunt mode.
+			name = "terragrunt"
+		}
+		flags, err := terragruntFlags(c.Dir())
+		if err != nil {
+			return "", "", err
+		}
+		args = append(flags, args...)
+	}
+
 	// If execPath is customized
 	if name != "terraform" {
 		// execPath may contain spaces and environment variables, so we parse it.
 		// e.g.) "direnv exec . terraform" => ["direnv", "exec", ".", "terraform"]
-		parts, err := shellwords.Parse(c.execPath)
+		parts, err := splitExecPath(name, c.goos)
 		if err != nil {
 			return "", "", err
 		}
@@ -194,14 +414,39 @@ func (c *terraformCLI) Run(ctx context.Context, args ...string) (string, string,
 		}
 	}
 
-	cmd, err := c.Executor.NewCommandContext(ctx, name, args...)
-	if err != nil {
-		return "", "", err
+	if c.container != nil {
+		var err error
+		name, args, err = c.container.wrap(c.Dir(), c.Env(), name, args)
+		if err != nil {
+			return "", "", err
+		}
 	}
 
-	err = c.Executor.Run(cmd)
+	// A command cannot run twice, so we build a new one for each attempt.
+	attempt := func() (string, string, error) {
+		cmd, err := c.Executor.NewCommandContext(ctx, name, args...)
+		if err != nil {
+			return "", "", err
+		}
+		if stdoutTee != nil {
+			cmd.TeeStdout(stdoutTee)
+		}
+
+		err = c.Executor.Run(cmd)
+
+		stdout := cmd.Stdout()
+		if c.execMode == ExecModeTerragrunt {
+			stdout = unwrapTerragruntOutput(stdout)
+		}
+		return stdout, cmd.Stderr(), err
+	}
 
-	return cmd.Stdout(), cmd.Stderr(), err
+	stdout, stderr, err := c.runWithRetry(ctx, attempt)
+	// Don't try to recover a failure of force-unlock itself.
+	if _, ok := err.(ExitError); ok && subcommand != "force-unlock" && c.recoverStaleLock(ctx, stderr) {
+		return c.runWithRetry(ctx, attempt)
+	}
+	return stdout, stderr, err
 }
 
 // Dir returns a working directory where terraform command is executed.
@@ -215,14 +460,31 @@ func (c *terraformCLI) SetExecPath(execPath string) {
 	c.execPath = execPath
 }
 
+// SetExecMode sets a mode of executing the terraform command.
+func (c *terraformCLI) SetExecMode(mode string) {
+	c.execMode = mode
+}
+
+// SetExecContainer customizes the terraform command to run inside a container.
+func (c *terraformCLI) SetExecContainer(container *ExecContainer) {
+	c.container = container
+}
+
+// SetUIStream sets a channel to which machine-readable UI messages are sent.
+func (c *terraformCLI) SetUIStream(ch chan<- *UIMessage) {
+	c.uiStream = ch
+}
+
 // OverrideBackendToLocal switches the backend to local and returns a function
 // that will switch it back to remote with defer.
 // The -state flag for terraform command is not valid for remote state,
 // so we need to switch the backend to local for temporary state operations.
 // The filename argument must meet constraints in order to override the file.
 // (e.g.) _tfexec_override.tf
+// The initOpts are passed to terraform init for switching backends.
+// (e.g.) -plugin-dir=/path/to/mirror
 func (c *terraformCLI) OverrideBackendToLocal(ctx context.Context, filename string,
-	workspace string, isBackendTerraformCloud bool, backendConfig []string, supportsStateReplaceProvider bool) (func() error, error) {
+	workspace string, isBackendTerraformCloud bool, backendConfig []string, supportsStateReplaceProvider bool, initOpts ...string) (func() error, error) {
 	// create local backend override file.
 	path := filepath.Join(c.Dir(), filename)
 	contents := `
@@ -245,7 +507,9 @@ terraform {
 	}
 
 	log.Printf("[INFO] [executor@%s] switch backend to local\n", c.Dir())
-	err := c.Init(ctx, "-input=false", "-no-color", "-reconfigure")
+	args := []string{"-input=false", "-no-color", "-reconfigure"}
+	args = append(args, initOpts...)
+	err := c.Init(ctx, args...)
 	if err != nil {
 		// remove the override file before return an error.
 		os.Remove(path)
@@ -286,6 +550,7 @@ terraform {
 		if !isBackendTerraformCloud {
 			args = append(args, "-reconfigure")
 		}
+		args = append(args, initOpts...)
 
 		err = c.Init(ctx, args...)
 		if err != nil {
@@ -333,19 +598,86 @@ func mergeOptions(a []string, b []string) []string {
 	return uniq
 }
 
+// splitExecPath splits a given execPath like a shell and returns a list of
+// the binary path and its arguments.
+// On Windows, a backslash is a path separator, not an escape character, so we
+// quote it before parsing not to mangle a path such as C:\tools\terraform.exe.
+// Note that arguments are passed to the process without a shell, so that
+// arguments such as bracketed addresses never need to be quoted.
+func splitExecPath(execPath string, goos string) ([]string, error) {
+	if goos == "windows" {
+		execPath = quoteWindowsPathSeparators(execPath)
+	}
+
+	parts, err := shellwords.Parse(execPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("failed to parse execPath: %q", execPath)
+	}
+
+	return parts, nil
+}
+
+// quoteWindowsPathSeparators quotes each backslash in a given execPath with
+// single quotes, so that a shell-like parser reads it literally. A backslash
+// followed by a double quote is kept as is, because it's an escaped double
+// quote rather than a path separator. A backslash in double quotes is quoted
+// by closing and reopening the double quotes, which are concatenated into
+// the same argument.
+func quoteWindowsPathSeparators(execPath string) string {
+	var b strings.Builder
+	var quote rune
+	runes := []rune(execPath)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && quote != '\'':
+			if i+1 < len(runes) && runes[i+1] == '"' {
+				b.WriteString(`\"`)
+				i++
+				continue
+			}
+			if quote == '"' {
+				b.WriteString(`"'\'"`)
+			} else {
+				b.WriteString(`'\'`)
+			}
+		case (r == '"' || r == '\'') && quote == 0:
+			quote = r
+			b.WriteRune(r)
+		case r == quote:
+			quote = 0
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
 // writeTempFile writes content to a temporary file and return its file.
-func writeTempFile(content []byte) (*os.File, error) {
-	tmpfile, err := os.CreateTemp("", "tmp")
+// If an error occurs, the temporary file is removed and it returns nil.
+// Note that the file is always closed before return, because an open file
+// cannot be removed or reopened by another process on Windows.
+// If the dir is empty, it uses the default directory for temporary files.
+func writeTempFile(dir string, content []byte) (*os.File, error) {
+	tmpfile, err := os.CreateTemp(dir, TempFilePrefix+"*")
 	if err != nil {
-		return tmpfile, fmt.Errorf("failed to create temporary file: %s", err)
+		return nil, fmt.Errorf("failed to create temporary file: %s", err)
 	}
 
 	if _, err := tmpfile.Write(content); err != nil {
-		return tmpfile, fmt.Errorf("failed to write temporary file: %s", err)
+		tmpfile.Close()
+		os.Remove(tmpfile.Name())
+		return nil, fmt.Errorf("failed to write temporary file: %s", err)
 	}
 
 	if err := tmpfile.Close(); err != nil {
-		return tmpfile, fmt.Errorf("failed to close temporary file: %s", err)
+		os.Remove(tmpfile.Name())
+		return nil, fmt.Errorf("failed to close temporary file: %s", err)
 	}
 
 	return tmpfile, nil
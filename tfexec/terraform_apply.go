@@ -2,7 +2,6 @@ package tfexec
 
 import (
 	"context"
-	"os"
 )
 
 // Apply applies changes.
@@ -12,15 +11,15 @@ func (c *terraformCLI) Apply(ctx context.Context, plan *Plan, opts ...string) er
 	args = append(args, opts...)
 
 	if plan != nil {
-		tmpPlan, err := writeTempFile(plan.Bytes())
-		defer os.Remove(tmpPlan.Name())
+		tmpPlan, err := c.WriteTempFile(plan.Bytes())
 		if err != nil {
 			return err
 		}
+		defer c.RemoveTempFile(tmpPlan.Name())
 		args = append(args, tmpPlan.Name())
 	}
 
-	_, _, err := c.Run(ctx, args...)
+	_, _, err := c.runWithUIStream(ctx, args...)
 
 	return err
 }
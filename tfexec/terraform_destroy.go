@@ -6,6 +6,6 @@ import "context"
 func (c *terraformCLI) Destroy(ctx context.Context, opts ...string) error {
 	args := []string{"destroy"}
 	args = append(args, opts...)
-	_, _, err := c.Run(ctx, args...)
+	_, _, err := c.runWithUIStream(ctx, args...)
 	return err
 }
@@ -0,0 +1,53 @@
+package tfexec
+
+import (
+	"context"
+	"strings"
+)
+
+// Fmt rewrites configuration files in a given directory to a canonical format.
+// If the dir is empty, it formats the current working directory.
+func (c *terraformCLI) Fmt(ctx context.Context, dir string, opts ...string) error {
+	args := []string{"fmt"}
+	args = append(args, opts...)
+	if len(dir) != 0 {
+		args = append(args, dir)
+	}
+	_, _, err := c.Run(ctx, args...)
+	return err
+}
+
+// FmtCheck checks whether configuration files in a given directory are
+// formatted without modifying them, and returns a list of files which are
+// not formatted. If the dir is empty, it checks the current working directory.
+func (c *terraformCLI) FmtCheck(ctx context.Context, dir string, opts ...string) ([]string, error) {
+	merged := mergeOptions(opts, []string{"-check", "-list=true", "-no-color"})
+	args := []string{"fmt"}
+	args = append(args, merged...)
+	if len(dir) != 0 {
+		args = append(args, dir)
+	}
+	stdout, _, err := c.Run(ctx, args...)
+	if err != nil {
+		// terraform fmt -check returns the exit code 3 if any file is not formatted.
+		if exitErr, ok := err.(ExitError); ok && exitErr.ExitCode() == 3 {
+			return parseFmtList(stdout), nil
+		}
+		return nil, err
+	}
+
+	return []string{}, nil
+}
+
+// parseFmtList parses an output of terraform fmt -list=true and returns a
+// list of file names.
+func parseFmtList(stdout string) []string {
+	files := []string{}
+	for _, l := range strings.Split(stdout, "\n") {
+		l = strings.TrimSpace(l)
+		if len(l) != 0 {
+			files = append(files, l)
+		}
+	}
+	return files
+}
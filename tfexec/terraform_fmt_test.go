@@ -0,0 +1,190 @@
+package tfexec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTerraformCLIFmt(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		dir          string
+		opts         []string
+		ok           bool
+	}{
+		{
+			desc: "no dir",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "fmt"},
+					exitCode: 0,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "with dir and opts",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "fmt", "-recursive", "foo"},
+					exitCode: 0,
+				},
+			},
+			dir:  "foo",
+			opts: []string{"-recursive"},
+			ok:   true,
+		},
+		{
+			desc: "failed to run terraform fmt",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "fmt", "foo"},
+					exitCode: 2,
+				},
+			},
+			dir: "foo",
+			ok:  false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			err := terraformCLI.Fmt(context.Background(), tc.dir, tc.opts...)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestTerraformCLIFmtCheck(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		dir          string
+		opts         []string
+		want         []string
+		ok           bool
+	}{
+		{
+			desc: "formatted",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "fmt", "-check", "-list=true", "-no-color", "foo"},
+					exitCode: 0,
+				},
+			},
+			dir:  "foo",
+			want: []string{},
+			ok:   true,
+		},
+		{
+			desc: "not formatted",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "fmt", "-check", "-list=true", "-no-color", "foo"},
+					stdout:   "foo/main.tf\nfoo/moved.tf\n",
+					exitCode: 3,
+				},
+			},
+			dir:  "foo",
+			want: []string{"foo/main.tf", "foo/moved.tf"},
+			ok:   true,
+		},
+		{
+			desc: "with opts",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "fmt", "-recursive", "-check", "-list=true", "-no-color"},
+					exitCode: 0,
+				},
+			},
+			opts: []string{"-recursive"},
+			want: []string{},
+			ok:   true,
+		},
+		{
+			desc: "failed to run terraform fmt",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "fmt", "-check", "-list=true", "-no-color", "foo"},
+					exitCode: 2,
+				},
+			},
+			dir:  "foo",
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.FmtCheck(context.Background(), tc.dir, tc.opts...)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAccTerraformCLIFmt(t *testing.T) {
+	SkipUnlessAcceptanceTestEnabled(t)
+
+	source := `resource "null_resource" "foo" {
+  triggers = {
+    a = "1"
+    bbb = "2"
+  }
+}
+`
+	e := SetupTestAcc(t, source)
+	terraformCLI := NewTerraformCLI(e)
+
+	got, err := terraformCLI.FmtCheck(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to run terraform fmt -check: %s", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 unformatted file, but got: %#v", got)
+	}
+
+	err = terraformCLI.Fmt(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to run terraform fmt: %s", err)
+	}
+
+	got, err = terraformCLI.FmtCheck(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to run terraform fmt -check: %s", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected no unformatted file, but got: %#v", got)
+	}
+
+	b, err := os.ReadFile(filepath.Join(e.Dir(), testAccSourceFileName))
+	if err != nil {
+		t.Fatalf("failed to read source file: %s", err)
+	}
+	if string(b) == source {
+		t.Error("expected the source file to be formatted, but not changed")
+	}
+}
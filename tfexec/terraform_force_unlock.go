@@ -0,0 +1,16 @@
+package tfexec
+
+import (
+	"context"
+	"fmt"
+)
+
+// ForceUnlock removes a lock of the current state with a given lock ID.
+// It never asks for confirmation.
+func (c *terraformCLI) ForceUnlock(ctx context.Context, lockID string) error {
+	if len(lockID) == 0 {
+		return fmt.Errorf("failed to force unlock: lock ID is empty")
+	}
+	_, _, err := c.Run(ctx, "force-unlock", "-force", lockID)
+	return err
+}
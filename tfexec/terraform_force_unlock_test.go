@@ -0,0 +1,59 @@
+package tfexec
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTerraformCLIForceUnlock(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		lockID       string
+		ok           bool
+	}{
+		{
+			desc: "simple",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "force-unlock", "-force", "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d"},
+					exitCode: 0,
+				},
+			},
+			lockID: "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d",
+			ok:     true,
+		},
+		{
+			desc: "failed to run terraform force-unlock",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "force-unlock", "-force", "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d"},
+					exitCode: 1,
+				},
+			},
+			lockID: "1c4b1b4c-8b3a-8ec5-5a3b-0c0f3a1b2c3d",
+			ok:     false,
+		},
+		{
+			desc:         "empty lock ID",
+			mockCommands: []*mockCommand{},
+			lockID:       "",
+			ok:           false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			err := terraformCLI.ForceUnlock(context.Background(), tc.lockID)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
@@ -15,11 +15,11 @@ func (c *terraformCLI) Import(ctx context.Context, state *State, address string,
 		if hasPrefixOptions(opts, "-state=") {
 			return nil, fmt.Errorf("failed to build options. The state argument (!= nil) and the -state= option cannot be set at the same time: state=%v, opts=%v", state, opts)
 		}
-		tmpState, err := writeTempFile(state.Bytes())
-		defer os.Remove(tmpState.Name())
+		tmpState, err := c.WriteTempFile(state.Bytes())
 		if err != nil {
 			return nil, err
 		}
+		defer c.RemoveTempFile(tmpState.Name())
 		args = append(args, "-state="+tmpState.Name())
 	}
 
@@ -28,11 +28,11 @@ func (c *terraformCLI) Import(ctx context.Context, state *State, address string,
 		return nil, fmt.Errorf("failed to build options. The -state-out= option is not allowed. Read a return value: %v", opts)
 	}
 
-	tmpStateOut, err := os.CreateTemp("", "tfstate")
+	tmpStateOut, err := os.CreateTemp(c.tempDir, "tfstate")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temporary state out file: %s", err)
 	}
-	defer os.Remove(tmpStateOut.Name())
+	defer c.RemoveTempFile(tmpStateOut.Name())
 
 	if err := tmpStateOut.Close(); err != nil {
 		return nil, fmt.Errorf("failed to close temporary state out file: %s", err)
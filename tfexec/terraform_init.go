@@ -2,12 +2,24 @@ package tfexec
 
 import (
 	"context"
+	"sync"
 )
 
 // Init initializes the current work directory.
+// If an init lock is set, it is held while running terraform init.
 func (c *terraformCLI) Init(ctx context.Context, opts ...string) error {
+	if c.initLock != nil {
+		c.initLock.Lock()
+		defer c.initLock.Unlock()
+	}
+
 	args := []string{"init"}
 	args = append(args, opts...)
 	_, _, err := c.Run(ctx, args...)
 	return err
 }
+
+// SetInitLock sets a lock held while running terraform init.
+func (c *terraformCLI) SetInitLock(l sync.Locker) {
+	c.initLock = l
+}
@@ -63,6 +63,64 @@ func TestTerraformCLIInit(t *testing.T) {
 	}
 }
 
+// countingLocker is a sync.Locker which counts calls for testing.
+type countingLocker struct {
+	locked   int
+	unlocked int
+}
+
+func (l *countingLocker) Lock()   { l.locked++ }
+func (l *countingLocker) Unlock() { l.unlocked++ }
+
+func TestTerraformCLIInitWithLock(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		ok           bool
+	}{
+		{
+			desc: "succeeded",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "init"},
+					exitCode: 0,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "failed",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "init"},
+					exitCode: 1,
+				},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			l := &countingLocker{}
+			terraformCLI.SetInitLock(l)
+			err := terraformCLI.Init(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if l.locked != 1 || l.unlocked != 1 {
+				t.Errorf("expected to lock and unlock once, but got locked = %d, unlocked = %d", l.locked, l.unlocked)
+			}
+		})
+	}
+}
+
 func TestAccTerraformCLIInit(t *testing.T) {
 	SkipUnlessAcceptanceTestEnabled(t)
 
@@ -15,11 +15,11 @@ func (c *terraformCLI) Plan(ctx context.Context, state *State, opts ...string) (
 		if hasPrefixOptions(opts, "-state=") {
 			return nil, fmt.Errorf("failed to build options. The state argument (!= nil) and the -state= option cannot be set at the same time: state=%v, opts=%v", state, opts)
 		}
-		tmpState, err := writeTempFile(state.Bytes())
-		defer os.Remove(tmpState.Name())
+		tmpState, err := c.WriteTempFile(state.Bytes())
 		if err != nil {
 			return nil, err
 		}
+		defer c.RemoveTempFile(tmpState.Name())
 		args = append(args, "-state="+tmpState.Name())
 	}
 
@@ -29,12 +29,12 @@ func (c *terraformCLI) Plan(ctx context.Context, state *State, opts ...string) (
 	if hasPrefixOptions(opts, "-out=") {
 		planOut = getOptionValue(opts, "-out=")
 	} else {
-		tmpPlan, err := os.CreateTemp("", "tfplan")
+		tmpPlan, err := os.CreateTemp(c.tempDir, "tfplan")
 		if err != nil {
 			return nil, fmt.Errorf("failed to create temporary plan file: %s", err)
 		}
 		planOut = tmpPlan.Name()
-		defer os.Remove(planOut)
+		defer c.RemoveTempFile(planOut)
 
 		if err := tmpPlan.Close(); err != nil {
 			return nil, fmt.Errorf("failed to close temporary plan file: %s", err)
@@ -44,7 +44,7 @@ func (c *terraformCLI) Plan(ctx context.Context, state *State, opts ...string) (
 
 	args = append(args, opts...)
 
-	_, _, err := c.Run(ctx, args...)
+	_, _, err := c.runWithUIStream(ctx, args...)
 
 	// terraform plan -detailed-exitcode returns 2 if there is a diff.
 	// So we intentionally ignore an error of read the plan file and returns the
@@ -0,0 +1,103 @@
+package tfexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// JSONPlan is a plan in the JSON output format of terraform show -json.
+// Only attributes required to assert changes are parsed.
+type JSONPlan struct {
+	// FormatVersion is a version of the JSON output format.
+	FormatVersion string `json:"format_version"`
+	// ResourceChanges is a list of changes of resource instances.
+	ResourceChanges []ResourceChange `json:"resource_changes"`
+}
+
+// ResourceChange is a planned change of a resource instance.
+type ResourceChange struct {
+	// Address is an absolute address of the resource instance.
+	Address string `json:"address"`
+	// Mode is either managed or data.
+	Mode string `json:"mode"`
+	// Type is a resource type.
+	Type string `json:"type"`
+	// Name is a resource name.
+	Name string `json:"name"`
+	// Change is a planned change.
+	Change Change `json:"change"`
+}
+
+// Change is a planned change of an object.
+type Change struct {
+	// Actions is a list of actions such as ["update"] and ["delete", "create"].
+	Actions []string `json:"actions"`
+}
+
+// Action returns a single action of the change. A replacement, which is a pair
+// of delete and create in either order, is returned as replace.
+func (c ResourceChange) Action() string {
+	actions := c.Change.Actions
+	if len(actions) == 2 && slices.Contains(actions, "create") && slices.Contains(actions, "delete") {
+		return "replace"
+	}
+	return strings.Join(actions, "-")
+}
+
+// Changes returns a list of resource changes except no-op and read, which
+// don't change any real resources.
+func (p *JSONPlan) Changes() []ResourceChange {
+	changes := []ResourceChange{}
+	for _, rc := range p.ResourceChanges {
+		switch rc.Action() {
+		case "no-op", "read", "":
+			continue
+		}
+		changes = append(changes, rc)
+	}
+	return changes
+}
+
+// PlanJSON computes expected changes and returns the plan in JSON.
+// If a state is given, use it for the input state.
+// Unlike Plan, a plan with changes under the -detailed-exitcode option is not
+// an error, because the caller is expected to inspect the changes.
+func (c *terraformCLI) PlanJSON(ctx context.Context, state *State, opts ...string) (*JSONPlan, error) {
+	plan, err := c.Plan(ctx, state, opts...)
+	if err != nil {
+		// terraform plan -detailed-exitcode returns 2 if there is a diff.
+		if exitErr, ok := err.(ExitError); !ok || exitErr.ExitCode() != 2 {
+			return nil, err
+		}
+	}
+
+	return c.ShowPlanJSON(ctx, plan)
+}
+
+// ShowPlanJSON returns a given plan in JSON with terraform show -json.
+func (c *terraformCLI) ShowPlanJSON(ctx context.Context, plan *Plan) (*JSONPlan, error) {
+	tmpPlan, err := c.WriteTempFile(plan.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	defer c.RemoveTempFile(tmpPlan.Name())
+
+	stdout, _, err := c.Run(ctx, "show", "-json", tmpPlan.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	return parseJSONPlan([]byte(stdout))
+}
+
+// parseJSONPlan parses an output of terraform show -json for a plan file.
+func parseJSONPlan(b []byte) (*JSONPlan, error) {
+	var p JSONPlan
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan in JSON: %s", err)
+	}
+	return &p, nil
+}
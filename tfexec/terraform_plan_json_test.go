@@ -0,0 +1,186 @@
+package tfexec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var terraformShowPlanJSONStdout = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "aws_iam_role.foo",
+      "mode": "managed",
+      "type": "aws_iam_role",
+      "name": "foo",
+      "change": {"actions": ["update"]}
+    },
+    {
+      "address": "aws_instance.bar",
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "bar",
+      "change": {"actions": ["delete", "create"]}
+    },
+    {
+      "address": "aws_s3_bucket.baz",
+      "mode": "managed",
+      "type": "aws_s3_bucket",
+      "name": "baz",
+      "change": {"actions": ["no-op"]}
+    },
+    {
+      "address": "data.aws_caller_identity.current",
+      "mode": "data",
+      "type": "aws_caller_identity",
+      "name": "current",
+      "change": {"actions": ["read"]}
+    }
+  ]
+}`
+
+func TestTerraformCLIPlanJSON(t *testing.T) {
+	// mock writing plan to a temporary file.
+	runFunc := func(args ...string) error {
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "-out=") {
+				planFile := arg[len("-out="):]
+				return os.WriteFile(planFile, []byte("dummy plan"), 0600)
+			}
+		}
+		return fmt.Errorf("failed to find -out= option: %v", args)
+	}
+
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		opts         []string
+		want         []string
+		ok           bool
+	}{
+		{
+			desc: "no changes",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "plan", "-out=/path/to/planfile", "-detailed-exitcode"},
+					argsRe:   regexp.MustCompile(`^terraform plan -out=.+ -detailed-exitcode$`),
+					runFunc:  runFunc,
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "show", "-json", "/path/to/planfile"},
+					argsRe:   regexp.MustCompile(`^terraform show -json .+$`),
+					stdout:   `{"format_version": "1.2"}`,
+					exitCode: 0,
+				},
+			},
+			opts: []string{"-detailed-exitcode"},
+			want: []string{},
+			ok:   true,
+		},
+		{
+			desc: "changes",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "plan", "-out=/path/to/planfile", "-detailed-exitcode"},
+					argsRe:   regexp.MustCompile(`^terraform plan -out=.+ -detailed-exitcode$`),
+					runFunc:  runFunc,
+					exitCode: 2,
+				},
+				{
+					args:     []string{"terraform", "show", "-json", "/path/to/planfile"},
+					argsRe:   regexp.MustCompile(`^terraform show -json .+$`),
+					stdout:   terraformShowPlanJSONStdout,
+					exitCode: 0,
+				},
+			},
+			opts: []string{"-detailed-exitcode"},
+			want: []string{"update:aws_iam_role.foo", "replace:aws_instance.bar"},
+			ok:   true,
+		},
+		{
+			desc: "failed to run terraform plan",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "plan", "-out=/path/to/planfile", "-detailed-exitcode"},
+					argsRe:   regexp.MustCompile(`^terraform plan -out=.+ -detailed-exitcode$`),
+					exitCode: 1,
+				},
+			},
+			opts: []string{"-detailed-exitcode"},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "failed to parse json",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "plan", "-out=/path/to/planfile"},
+					argsRe:   regexp.MustCompile(`^terraform plan -out=.+$`),
+					runFunc:  runFunc,
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "show", "-json", "/path/to/planfile"},
+					argsRe:   regexp.MustCompile(`^terraform show -json .+$`),
+					stdout:   `foo`,
+					exitCode: 0,
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.PlanJSON(context.Background(), nil, tc.opts...)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				changes := []string{}
+				for _, rc := range got.Changes() {
+					changes = append(changes, rc.Action()+":"+rc.Address)
+				}
+				if !reflect.DeepEqual(changes, tc.want) {
+					t.Errorf("got: %#v, want: %#v", changes, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAccTerraformCLIPlanJSON(t *testing.T) {
+	SkipUnlessAcceptanceTestEnabled(t)
+
+	source := `resource "null_resource" "foo" {}`
+	e := SetupTestAcc(t, source)
+	terraformCLI := NewTerraformCLI(e)
+
+	err := terraformCLI.Init(context.Background(), "-input=false", "-no-color")
+	if err != nil {
+		t.Fatalf("failed to run terraform init: %s", err)
+	}
+
+	plan, err := terraformCLI.PlanJSON(context.Background(), nil, "-input=false", "-no-color", "-detailed-exitcode")
+	if err != nil {
+		t.Fatalf("failed to run terraform plan: %s", err)
+	}
+
+	changes := plan.Changes()
+	if len(changes) != 1 || changes[0].Action() != "create" || changes[0].Address != "null_resource.foo" {
+		t.Errorf("unexpected changes: %#v", changes)
+	}
+}
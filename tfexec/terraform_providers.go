@@ -2,6 +2,11 @@ package tfexec
 
 import (
 	"context"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 // Providers prints out a tree of modules in the referenced configuration annotated with
@@ -16,3 +21,176 @@ func (c *terraformCLI) Providers(ctx context.Context) (string, error) {
 
 	return stdout, nil
 }
+
+// ProvidersTree returns a tree of modules in the referenced configuration
+// annotated with their provider requirements.
+// Child modules must have been installed with terraform init.
+func (c *terraformCLI) ProvidersTree(ctx context.Context) (*ModuleProviders, error) {
+	stdout, err := c.Providers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProvidersTree(stdout), nil
+}
+
+// ModuleProviders is a node of a provider requirement tree.
+type ModuleProviders struct {
+	// Address is an absolute address of the module.
+	// e.g.) module.foo.module.bar
+	// It's empty for the root module.
+	Address string
+	// Providers is a list of providers required by the module itself.
+	// It doesn't include providers required by child modules.
+	Providers []RequiredProvider
+	// Children is a list of child modules.
+	Children []*ModuleProviders
+}
+
+// References returns true if a given provider address is required by the
+// module or any of its descendants.
+// The address is matched against the fully qualified source address, so that
+// a short form such as hashicorp/null also matches
+// registry.terraform.io/hashicorp/null.
+func (m *ModuleProviders) References(address string) bool {
+	for _, p := range m.Providers {
+		if p.Source == address || strings.HasSuffix(p.Source, "/"+address) {
+			return true
+		}
+	}
+	for _, child := range m.Children {
+		if child.References(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProvidersTree parses an output of terraform providers and returns a
+// tree of modules required by configuration.
+// Each level of the tree is indented with 4 characters, such as "├── " and
+// "│   ".
+func parseProvidersTree(stdout string) *ModuleProviders {
+	root := &ModuleProviders{}
+	// stack[i] is the last module seen at depth i.
+	stack := []*ModuleProviders{root}
+	for _, l := range strings.Split(stdout, "\n") {
+		// Providers only required by state are not required by configuration.
+		if strings.HasPrefix(l, "Providers required by state") {
+			break
+		}
+
+		body := strings.TrimLeft(l, "│├└─ \u00a0")
+		if len(body) == 0 || body == "." {
+			continue
+		}
+		depth := utf8.RuneCountInString(l[:len(l)-len(body)]) / 4
+		if depth < 1 || depth > len(stack) {
+			continue
+		}
+		parent := stack[depth-1]
+
+		switch {
+		case strings.HasPrefix(body, "module."):
+			name := strings.Fields(body)[0]
+			address := name
+			if len(parent.Address) != 0 {
+				address = parent.Address + "." + name
+			}
+			child := &ModuleProviders{Address: address}
+			parent.Children = append(parent.Children, child)
+			stack = append(stack[:depth], child)
+
+		case strings.HasPrefix(body, "provider"):
+			parent.Providers = append(parent.Providers, parseRequiredProviders(body)...)
+		}
+	}
+
+	return root
+}
+
+// RequiredProvider is a provider required by configuration.
+type RequiredProvider struct {
+	// Source is a source address of the provider.
+	// e.g.) registry.terraform.io/hashicorp/null
+	// Note that it's a local name such as null in Terraform v0.12.
+	Source string
+	// Constraints is a list of version constraints declared in the root module
+	// and child modules. It's empty if no constraint is declared.
+	// e.g.) ["~> 3.0", ">= 3.1.0"]
+	Constraints []string
+}
+
+// RequiredProviders returns a list of providers required by configuration in
+// a given directory with their version constraints, sorted by source.
+// If the dir is empty, it refers the current working directory.
+// Note that a non-empty dir requires Terraform v0.14+ for the -chdir flag.
+// Child modules must have been installed with terraform init.
+func (c *terraformCLI) RequiredProviders(ctx context.Context, dir string) ([]RequiredProvider, error) {
+	args := []string{}
+	if len(dir) != 0 {
+		args = append(args, "-chdir="+dir)
+	}
+	args = append(args, "providers")
+
+	stdout, _, err := c.Run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRequiredProviders(stdout), nil
+}
+
+// providerLineRe is a pattern to parse a line of terraform providers.
+// The first pattern is for Terraform v0.13+ and the second one is for v0.12.
+// e.g.)
+// ├── provider[registry.terraform.io/hashicorp/null] ~> 3.0
+// └── provider.null ~> 2.1
+var providerLineRe = regexp.MustCompile(`provider(?:\[([^\]]+)\]|\.(\S+))(.*)$`)
+
+// parseRequiredProviders parses an output of terraform providers and returns
+// a list of providers required by configuration.
+func parseRequiredProviders(stdout string) []RequiredProvider {
+	constraints := make(map[string][]string)
+	for _, l := range strings.Split(stdout, "\n") {
+		// Providers only required by state are not required by configuration.
+		if strings.HasPrefix(l, "Providers required by state") {
+			break
+		}
+
+		matched := providerLineRe.FindStringSubmatch(l)
+		if matched == nil {
+			continue
+		}
+
+		source := matched[1]
+		if len(source) == 0 {
+			source = matched[2]
+		}
+		if _, ok := constraints[source]; !ok {
+			constraints[source] = []string{}
+		}
+
+		rest := strings.TrimSpace(matched[3])
+		rest = strings.TrimSpace(strings.TrimSuffix(rest, "(inherited)"))
+		if len(rest) == 0 {
+			continue
+		}
+		if !slices.Contains(constraints[source], rest) {
+			constraints[source] = append(constraints[source], rest)
+		}
+	}
+
+	providers := []RequiredProvider{}
+	for source, cs := range constraints {
+		providers = append(providers, RequiredProvider{
+			Source:      source,
+			Constraints: cs,
+		})
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Source < providers[j].Source
+	})
+
+	return providers
+}
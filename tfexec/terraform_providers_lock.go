@@ -0,0 +1,16 @@
+package tfexec
+
+import (
+	"context"
+)
+
+// ProvidersLock updates the dependency lock file with checksums of required
+// providers. Use the -platform option to record checksums for platforms
+// other than the current one, and the -fs-mirror option to compute them from
+// a local filesystem mirror instead of the registry.
+func (c *terraformCLI) ProvidersLock(ctx context.Context, opts ...string) error {
+	args := []string{"providers", "lock"}
+	args = append(args, opts...)
+	_, _, err := c.Run(ctx, args...)
+	return err
+}
@@ -0,0 +1,86 @@
+package tfexec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTerraformCLIProvidersLock(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		opts         []string
+		ok           bool
+	}{
+		{
+			desc: "no opts",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers", "lock"},
+					exitCode: 0,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "failed to run terraform providers lock",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers", "lock"},
+					exitCode: 1,
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "with opts",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers", "lock", "-platform=linux_amd64", "-platform=darwin_arm64", "-fs-mirror=/tmp/mirror"},
+					exitCode: 0,
+				},
+			},
+			opts: []string{"-platform=linux_amd64", "-platform=darwin_arm64", "-fs-mirror=/tmp/mirror"},
+			ok:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			err := terraformCLI.ProvidersLock(context.Background(), tc.opts...)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestAccTerraformCLIProvidersLock(t *testing.T) {
+	SkipUnlessAcceptanceTestEnabled(t)
+
+	source := `resource "null_resource" "foo" {}`
+	e := SetupTestAcc(t, source)
+	terraformCLI := NewTerraformCLI(e)
+
+	err := terraformCLI.ProvidersLock(context.Background(), "-platform=linux_amd64", "-platform=darwin_arm64")
+	if err != nil {
+		t.Fatalf("failed to run terraform providers lock: %s", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(e.Dir(), ".terraform.lock.hcl"))
+	if err != nil {
+		t.Fatalf("failed to read the dependency lock file: %s", err)
+	}
+	if !strings.Contains(string(b), "registry.terraform.io/hashicorp/null") {
+		t.Errorf("failed to find the null provider in the dependency lock file: %s", string(b))
+	}
+}
@@ -0,0 +1,15 @@
+package tfexec
+
+import (
+	"context"
+)
+
+// ProvidersMirror saves local copies of all required provider plugins to a
+// given directory, which can be used as a filesystem mirror.
+func (c *terraformCLI) ProvidersMirror(ctx context.Context, targetDir string, opts ...string) error {
+	args := []string{"providers", "mirror"}
+	args = append(args, opts...)
+	args = append(args, targetDir)
+	_, _, err := c.Run(ctx, args...)
+	return err
+}
@@ -0,0 +1,95 @@
+package tfexec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTerraformCLIProvidersMirror(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		targetDir    string
+		opts         []string
+		ok           bool
+	}{
+		{
+			desc: "no opts",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers", "mirror", "/tmp/mirror"},
+					exitCode: 0,
+				},
+			},
+			targetDir: "/tmp/mirror",
+			ok:        true,
+		},
+		{
+			desc: "failed to run terraform providers mirror",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers", "mirror", "/tmp/mirror"},
+					exitCode: 1,
+				},
+			},
+			targetDir: "/tmp/mirror",
+			ok:        false,
+		},
+		{
+			desc: "with opts",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers", "mirror", "-platform=linux_amd64", "/tmp/mirror"},
+					exitCode: 0,
+				},
+			},
+			targetDir: "/tmp/mirror",
+			opts:      []string{"-platform=linux_amd64"},
+			ok:        true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			err := terraformCLI.ProvidersMirror(context.Background(), tc.targetDir, tc.opts...)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestAccTerraformCLIProvidersMirror(t *testing.T) {
+	SkipUnlessAcceptanceTestEnabled(t)
+
+	source := `resource "null_resource" "foo" {}`
+	e := SetupTestAcc(t, source)
+	terraformCLI := NewTerraformCLI(e)
+
+	targetDir, err := os.MkdirTemp("", "mirror")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(targetDir) })
+
+	err = terraformCLI.ProvidersMirror(context.Background(), targetDir)
+	if err != nil {
+		t.Fatalf("failed to run terraform providers mirror: %s", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(targetDir, "*", "hashicorp", "null", "*"))
+	if err != nil {
+		t.Fatalf("failed to glob mirror: %s", err)
+	}
+	if len(matches) == 0 {
+		t.Fatalf("failed to find the null provider in mirror: %s", targetDir)
+	}
+}
@@ -0,0 +1,65 @@
+package tfexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ProvidersSchema is a set of schemas of providers.
+// Only versions of resource schemas are parsed, because they are enough to
+// validate a resource type in state.
+type ProvidersSchema struct {
+	// resources is a map from a provider address to a map from a resource type
+	// to its schema version.
+	resources map[string]map[string]int64
+}
+
+// providersSchemaJSON is a JSON representation of terraform providers schema.
+type providersSchemaJSON struct {
+	ProviderSchemas map[string]struct {
+		ResourceSchemas map[string]struct {
+			Version int64 `json:"version"`
+		} `json:"resource_schemas"`
+	} `json:"provider_schemas"`
+}
+
+// ProvidersSchema returns schemas of providers used in the configuration.
+func (c *terraformCLI) ProvidersSchema(ctx context.Context) (*ProvidersSchema, error) {
+	stdout, _, err := c.Run(ctx, "providers", "schema", "-json")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProvidersSchema([]byte(stdout))
+}
+
+// parseProvidersSchema parses an output of terraform providers schema -json.
+func parseProvidersSchema(b []byte) (*ProvidersSchema, error) {
+	var j providersSchemaJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse providers schema: %s", err)
+	}
+
+	s := &ProvidersSchema{resources: make(map[string]map[string]int64)}
+	for provider, ps := range j.ProviderSchemas {
+		resources := make(map[string]int64)
+		for typ, rs := range ps.ResourceSchemas {
+			resources[typ] = rs.Version
+		}
+		s.resources[provider] = resources
+	}
+	return s, nil
+}
+
+// ResourceSchemaVersion returns a schema version of a given resource type in a
+// given provider. The second return value is false if the provider doesn't
+// have the resource type.
+func (s *ProvidersSchema) ResourceSchemaVersion(provider string, resourceType string) (int64, bool) {
+	resources, ok := s.resources[provider]
+	if !ok {
+		return 0, false
+	}
+	v, ok := resources[resourceType]
+	return v, ok
+}
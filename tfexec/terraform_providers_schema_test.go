@@ -0,0 +1,114 @@
+package tfexec
+
+import (
+	"context"
+	"testing"
+)
+
+var terraformProvidersSchemaStdout = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/hashicorp/aws": {
+      "resource_schemas": {
+        "aws_alb": {"version": 0, "block": {}},
+        "aws_lb": {"version": 0, "block": {}},
+        "aws_instance": {"version": 1, "block": {}}
+      }
+    }
+  }
+}`
+
+func TestTerraformCLIProvidersSchema(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		provider     string
+		resourceType string
+		want         int64
+		found        bool
+		ok           bool
+	}{
+		{
+			desc: "found",
+			mockCommands: []*mockCommand{
+				{
+					stdout:   terraformProvidersSchemaStdout,
+					exitCode: 0,
+				},
+			},
+			provider:     "registry.terraform.io/hashicorp/aws",
+			resourceType: "aws_instance",
+			want:         1,
+			found:        true,
+			ok:           true,
+		},
+		{
+			desc: "unknown resource type",
+			mockCommands: []*mockCommand{
+				{
+					stdout:   terraformProvidersSchemaStdout,
+					exitCode: 0,
+				},
+			},
+			provider:     "registry.terraform.io/hashicorp/aws",
+			resourceType: "aws_foo",
+			found:        false,
+			ok:           true,
+		},
+		{
+			desc: "unknown provider",
+			mockCommands: []*mockCommand{
+				{
+					stdout:   terraformProvidersSchemaStdout,
+					exitCode: 0,
+				},
+			},
+			provider:     "registry.terraform.io/hashicorp/null",
+			resourceType: "null_resource",
+			found:        false,
+			ok:           true,
+		},
+		{
+			desc: "failed to run terraform providers schema",
+			mockCommands: []*mockCommand{
+				{
+					exitCode: 1,
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "invalid json",
+			mockCommands: []*mockCommand{
+				{
+					stdout:   "foo",
+					exitCode: 0,
+				},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.mockCommands[0].args = []string{"terraform", "providers", "schema", "-json"}
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.ProvidersSchema(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if !tc.ok {
+				return
+			}
+			v, found := got.ResourceSchemaVersion(tc.provider, tc.resourceType)
+			if found != tc.found || v != tc.want {
+				t.Errorf("got = %d, %t, want = %d, %t", v, found, tc.want, tc.found)
+			}
+		})
+	}
+}
@@ -84,6 +84,281 @@ func TestTerraformCLIProviders(t *testing.T) {
 	}
 }
 
+func TestTerraformCLIRequiredProviders(t *testing.T) {
+	stdout := `
+Providers required by configuration:
+.
+├── provider[registry.terraform.io/hashicorp/null] ~> 3.0
+├── provider[registry.terraform.io/hashicorp/random]
+└── module.foo
+    ├── provider[registry.terraform.io/hashicorp/null] >= 3.1.0
+    └── provider[registry.terraform.io/hashicorp/aws] >= 4.0.0, < 5.0.0
+
+Providers required by state:
+
+    provider[registry.terraform.io/hashicorp/null]
+
+    provider[registry.terraform.io/hashicorp/time]
+
+`
+	legacyStdout := `.
+├── provider.null ~> 2.1
+└── module.foo
+    └── provider.null (inherited)
+
+`
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		dir          string
+		want         []RequiredProvider
+		ok           bool
+	}{
+		{
+			desc: "current dir",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers"},
+					stdout:   stdout,
+					exitCode: 0,
+				},
+			},
+			want: []RequiredProvider{
+				{Source: "registry.terraform.io/hashicorp/aws", Constraints: []string{">= 4.0.0, < 5.0.0"}},
+				{Source: "registry.terraform.io/hashicorp/null", Constraints: []string{"~> 3.0", ">= 3.1.0"}},
+				{Source: "registry.terraform.io/hashicorp/random", Constraints: []string{}},
+			},
+			ok: true,
+		},
+		{
+			desc: "with dir",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "-chdir=foo", "providers"},
+					stdout:   stdout,
+					exitCode: 0,
+				},
+			},
+			dir: "foo",
+			want: []RequiredProvider{
+				{Source: "registry.terraform.io/hashicorp/aws", Constraints: []string{">= 4.0.0, < 5.0.0"}},
+				{Source: "registry.terraform.io/hashicorp/null", Constraints: []string{"~> 3.0", ">= 3.1.0"}},
+				{Source: "registry.terraform.io/hashicorp/random", Constraints: []string{}},
+			},
+			ok: true,
+		},
+		{
+			desc: "legacy",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers"},
+					stdout:   legacyStdout,
+					exitCode: 0,
+				},
+			},
+			want: []RequiredProvider{
+				{Source: "null", Constraints: []string{"~> 2.1"}},
+			},
+			ok: true,
+		},
+		{
+			desc: "failed to run terraform providers",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers"},
+					exitCode: 1,
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.RequiredProviders(context.Background(), tc.dir)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTerraformCLIProvidersTree(t *testing.T) {
+	stdout := `
+Providers required by configuration:
+.
+├── provider[registry.terraform.io/hashicorp/null] ~> 3.0
+├── module.foo
+│   ├── provider[registry.terraform.io/hashicorp/aws] >= 4.0.0
+│   └── module.bar
+│       └── provider[registry.terraform.io/hashicorp/random]
+└── module.baz
+    └── provider[registry.terraform.io/hashicorp/null] (inherited)
+
+Providers required by state:
+
+    provider[registry.terraform.io/hashicorp/time]
+
+`
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		want         *ModuleProviders
+		ok           bool
+	}{
+		{
+			desc: "nested modules",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers"},
+					stdout:   stdout,
+					exitCode: 0,
+				},
+			},
+			want: &ModuleProviders{
+				Providers: []RequiredProvider{
+					{Source: "registry.terraform.io/hashicorp/null", Constraints: []string{"~> 3.0"}},
+				},
+				Children: []*ModuleProviders{
+					{
+						Address: "module.foo",
+						Providers: []RequiredProvider{
+							{Source: "registry.terraform.io/hashicorp/aws", Constraints: []string{">= 4.0.0"}},
+						},
+						Children: []*ModuleProviders{
+							{
+								Address: "module.foo.module.bar",
+								Providers: []RequiredProvider{
+									{Source: "registry.terraform.io/hashicorp/random", Constraints: []string{}},
+								},
+							},
+						},
+					},
+					{
+						Address: "module.baz",
+						Providers: []RequiredProvider{
+							{Source: "registry.terraform.io/hashicorp/null", Constraints: []string{}},
+						},
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "legacy",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers"},
+					stdout:   legacyTerraformProvidersStdout,
+					exitCode: 0,
+				},
+			},
+			want: &ModuleProviders{
+				Providers: []RequiredProvider{
+					{Source: "null", Constraints: []string{}},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "failed to run terraform providers",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "providers"},
+					exitCode: 1,
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.ProvidersTree(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestModuleProvidersReferences(t *testing.T) {
+	tree := &ModuleProviders{
+		Providers: []RequiredProvider{
+			{Source: "registry.terraform.io/hashicorp/null"},
+		},
+		Children: []*ModuleProviders{
+			{
+				Address: "module.foo",
+				Providers: []RequiredProvider{
+					{Source: "registry.terraform.io/hashicorp/aws"},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		desc    string
+		address string
+		want    bool
+	}{
+		{
+			desc:    "root module",
+			address: "registry.terraform.io/hashicorp/null",
+			want:    true,
+		},
+		{
+			desc:    "child module",
+			address: "registry.terraform.io/hashicorp/aws",
+			want:    true,
+		},
+		{
+			desc:    "short form",
+			address: "hashicorp/aws",
+			want:    true,
+		},
+		{
+			desc:    "not referenced",
+			address: "registry.terraform.io/hashicorp/random",
+			want:    false,
+		},
+		{
+			desc:    "partial name",
+			address: "cloud/aws",
+			want:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tree.References(tc.address)
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestAccTerraformCLIProviders(t *testing.T) {
 	SkipUnlessAcceptanceTestEnabled(t)
 
@@ -3,7 +3,6 @@ package tfexec
 import (
 	"context"
 	"fmt"
-	"os"
 	"strings"
 )
 
@@ -16,11 +15,11 @@ func (c *terraformCLI) StateList(ctx context.Context, state *State, addresses []
 		if hasPrefixOptions(opts, "-state=") {
 			return nil, fmt.Errorf("failed to build options. The state argument (!= nil) and the -state= option cannot be set at the same time: state=%v, opts=%v", state, opts)
 		}
-		tmpState, err := writeTempFile(state.Bytes())
-		defer os.Remove(tmpState.Name())
+		tmpState, err := c.WriteTempFile(state.Bytes())
 		if err != nil {
 			return nil, err
 		}
+		defer c.RemoveTempFile(tmpState.Name())
 		args = append(args, "-state="+tmpState.Name())
 	}
 
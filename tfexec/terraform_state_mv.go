@@ -21,11 +21,11 @@ func (c *terraformCLI) StateMv(ctx context.Context, state *State, stateOut *Stat
 		if hasPrefixOptions(opts, "-state=") {
 			return nil, nil, fmt.Errorf("failed to build options. The state argument (!= nil) and the -state= option cannot be set at the same time: state=%v, opts=%v", state, opts)
 		}
-		tmpState, err = writeTempFile(state.Bytes())
-		defer os.Remove(tmpState.Name())
+		tmpState, err = c.WriteTempFile(state.Bytes())
 		if err != nil {
 			return nil, nil, err
 		}
+		defer c.RemoveTempFile(tmpState.Name())
 		args = append(args, "-state="+tmpState.Name())
 	}
 
@@ -33,11 +33,11 @@ func (c *terraformCLI) StateMv(ctx context.Context, state *State, stateOut *Stat
 		if hasPrefixOptions(opts, "-state-out=") {
 			return nil, nil, fmt.Errorf("failed to build options. The stateOut argument (!= nil) and the -state-out= option cannot be set at the same time: stateOut=%v, opts=%v", stateOut, opts)
 		}
-		tmpStateOut, err = writeTempFile(stateOut.Bytes())
-		defer os.Remove(tmpStateOut.Name())
+		tmpStateOut, err = c.WriteTempFile(stateOut.Bytes())
 		if err != nil {
 			return nil, nil, err
 		}
+		defer c.RemoveTempFile(tmpStateOut.Name())
 		args = append(args, "-state-out="+tmpStateOut.Name())
 	}
 
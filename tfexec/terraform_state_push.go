@@ -2,7 +2,6 @@ package tfexec
 
 import (
 	"context"
-	"os"
 )
 
 // StatePush pushes a given State to remote.
@@ -10,11 +9,11 @@ func (c *terraformCLI) StatePush(ctx context.Context, state *State, opts ...stri
 	args := []string{"state", "push"}
 	args = append(args, opts...)
 
-	tmpState, err := writeTempFile(state.Bytes())
-	defer os.Remove(tmpState.Name())
+	tmpState, err := c.WriteTempFile(state.Bytes())
 	if err != nil {
 		return err
 	}
+	defer c.RemoveTempFile(tmpState.Name())
 
 	args = append(args, tmpState.Name())
 	_, _, err = c.Run(ctx, args...)
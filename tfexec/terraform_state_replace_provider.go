@@ -67,11 +67,11 @@ func (c *terraformCLI) StateReplaceProvider(ctx context.Context, state *State, s
 		if hasPrefixOptions(opts, "-state=") {
 			return nil, fmt.Errorf("failed to build options. The state argument (!= nil) and the -state= option cannot be set at the same time: state=%v, opts=%v", state, opts)
 		}
-		tmpState, err = writeTempFile(state.Bytes())
-		defer os.Remove(tmpState.Name())
+		tmpState, err = c.WriteTempFile(state.Bytes())
 		if err != nil {
 			return nil, err
 		}
+		defer c.RemoveTempFile(tmpState.Name())
 		args = append(args, "-state="+tmpState.Name())
 	}
 
@@ -0,0 +1,115 @@
+package tfexec
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// StateShow shows attributes of a single resource instance in state.
+// If a state is given, use it for the input state.
+// It returns a map of top-level attributes of the resource instance to their
+// values. A string value is unquoted. A value of a nested block or a
+// multi-line collection is omitted, because it is just for humans to check
+// the resource.
+func (c *terraformCLI) StateShow(ctx context.Context, state *State, address string, opts ...string) (map[string]string, error) {
+	args := []string{"state", "show"}
+
+	if state != nil {
+		if hasPrefixOptions(opts, "-state=") {
+			return nil, fmt.Errorf("failed to build options. The state argument (!= nil) and the -state= option cannot be set at the same time: state=%v, opts=%v", state, opts)
+		}
+		tmpState, err := c.WriteTempFile(state.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		defer c.RemoveTempFile(tmpState.Name())
+		args = append(args, "-state="+tmpState.Name())
+	}
+
+	args = append(args, opts...)
+	args = append(args, address)
+
+	stdout, _, err := c.Run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStateShow(stdout), nil
+}
+
+// ansiEscapeRegexp is a pattern of ANSI escape sequences for colors.
+var ansiEscapeRegexp = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// parseStateShow parses an output of terraform state show and returns a map of
+// top-level attributes to their values.
+// e.g.)
+//
+//	# aws_security_group.foo:
+//	resource "aws_security_group" "foo" {
+//	    id   = "sg-0123456789abcdef0"
+//	    name = "foo"
+//	    tags = {
+//	        "Name" = "foo"
+//	    }
+//	}
+func parseStateShow(stdout string) map[string]string {
+	attrs := make(map[string]string)
+	depth := 0
+	heredoc := ""
+	for _, line := range strings.Split(ansiEscapeRegexp.ReplaceAllString(stdout, ""), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		// skip contents of a heredoc.
+		if len(heredoc) > 0 {
+			if trimmed == heredoc {
+				heredoc = ""
+			}
+			continue
+		}
+
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "}") || strings.HasPrefix(trimmed, "]") || strings.HasPrefix(trimmed, ")") {
+			depth--
+		}
+
+		if depth == 1 {
+			if key, value, ok := strings.Cut(trimmed, "="); ok {
+				key = strings.Trim(strings.TrimSpace(key), `"`)
+				value = strings.TrimSpace(value)
+				switch {
+				case strings.HasPrefix(value, "<<"):
+					heredoc = strings.TrimLeft(value, "<-")
+				case strings.HasSuffix(value, "{") || strings.HasSuffix(value, "[") || strings.HasSuffix(value, "("):
+					// a multi-line value is omitted.
+				default:
+					attrs[key] = unquoteStateShowValue(value)
+				}
+			}
+		}
+
+		if strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, "[") || strings.HasSuffix(trimmed, "(") {
+			depth++
+		}
+	}
+
+	return attrs
+}
+
+// unquoteStateShowValue returns an unquoted string if a given value is a
+// quoted string. Otherwise, it returns the value as it is.
+func unquoteStateShowValue(value string) string {
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return value
+	}
+	s, err := strconv.Unquote(value)
+	if err != nil {
+		return value
+	}
+	return s
+}
@@ -0,0 +1,186 @@
+package tfexec
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestTerraformCLIStateShow(t *testing.T) {
+	state := NewState([]byte("dummy state"))
+	stdout := "# aws_security_group.foo:\n" +
+		"resource \"aws_security_group\" \"foo\" {\n" +
+		"    arn         = \"arn:aws:ec2:ap-northeast-1:123456789012:security-group/sg-0123456789abcdef0\"\n" +
+		"    description = \"Managed by Terraform\"\n" +
+		"    egress      = []\n" +
+		"    id          = \"sg-0123456789abcdef0\"\n" +
+		"    ingress     = [\n" +
+		"        {\n" +
+		"            description = \"nested\"\n" +
+		"        },\n" +
+		"    ]\n" +
+		"    name        = \"foo\"\n" +
+		"    policy      = <<-EOT\n" +
+		"        id = \"heredoc\"\n" +
+		"    EOT\n" +
+		"    port        = 443\n" +
+		"    tags        = {\n" +
+		"        \"Name\" = \"foo\"\n" +
+		"    }\n" +
+		"    timeouts {\n" +
+		"        create = \"10m\"\n" +
+		"    }\n" +
+		"    vpc_id      = \"vpc-0123456789abcdef0\"\n" +
+		"}\n"
+	want := map[string]string{
+		"arn":         "arn:aws:ec2:ap-northeast-1:123456789012:security-group/sg-0123456789abcdef0",
+		"description": "Managed by Terraform",
+		"egress":      "[]",
+		"id":          "sg-0123456789abcdef0",
+		"name":        "foo",
+		"port":        "443",
+		"vpc_id":      "vpc-0123456789abcdef0",
+	}
+
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		state        *State
+		address      string
+		opts         []string
+		want         map[string]string
+		ok           bool
+	}{
+		{
+			desc: "no state",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "show", "aws_security_group.foo"},
+					stdout:   stdout,
+					exitCode: 0,
+				},
+			},
+			state:   nil,
+			address: "aws_security_group.foo",
+			want:    want,
+			ok:      true,
+		},
+		{
+			desc: "with color",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "show", "null_resource.foo"},
+					stdout:   "# null_resource.foo:\nresource \"null_resource\" \"foo\" {\n    \x1b[1m\x1b[0mid\x1b[0m\x1b[0m = \"123\"\n}\n",
+					exitCode: 0,
+				},
+			},
+			state:   nil,
+			address: "null_resource.foo",
+			want:    map[string]string{"id": "123"},
+			ok:      true,
+		},
+		{
+			desc: "failed to run terraform state show",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "show", "aws_security_group.foo"},
+					exitCode: 1,
+				},
+			},
+			state:   nil,
+			address: "aws_security_group.foo",
+			want:    nil,
+			ok:      false,
+		},
+		{
+			desc: "with state",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "show", "-state=/path/to/tempfile", "aws_security_group.foo"},
+					argsRe:   regexp.MustCompile(`^terraform state show -state=.+ aws_security_group.foo$`),
+					stdout:   stdout,
+					exitCode: 0,
+				},
+			},
+			state:   state,
+			address: "aws_security_group.foo",
+			want:    want,
+			ok:      true,
+		},
+		{
+			desc: "with state and -state= (conflict error)",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "show", "-state=/path/to/tempfile", "-state=foo.tfstate", "aws_security_group.foo"},
+					argsRe:   regexp.MustCompile(`^terraform state show -state=\S+ -state=foo.tfstate aws_security_group.foo$`),
+					exitCode: 0,
+				},
+			},
+			state:   state,
+			address: "aws_security_group.foo",
+			opts:    []string{"-state=foo.tfstate"},
+			want:    nil,
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.StateShow(context.Background(), tc.state, tc.address, tc.opts...)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAccTerraformCLIStateShow(t *testing.T) {
+	SkipUnlessAcceptanceTestEnabled(t)
+
+	source := `
+resource "null_resource" "foo" {
+  triggers = {
+    foo = "bar"
+  }
+}
+`
+	e := SetupTestAcc(t, source)
+	terraformCLI := NewTerraformCLI(e)
+
+	err := terraformCLI.Init(context.Background(), "-input=false", "-no-color")
+	if err != nil {
+		t.Fatalf("failed to run terraform init: %s", err)
+	}
+
+	err = terraformCLI.Apply(context.Background(), nil, "-input=false", "-no-color", "-auto-approve")
+	if err != nil {
+		t.Fatalf("failed to run terraform apply: %s", err)
+	}
+
+	got, err := terraformCLI.StateShow(context.Background(), nil, "null_resource.foo")
+	if err != nil {
+		t.Fatalf("failed to run terraform state show: %s", err)
+	}
+
+	if len(got["id"]) == 0 {
+		t.Errorf("no id attribute: %v", got)
+	}
+	if _, ok := got["triggers"]; ok {
+		t.Errorf("unexpected multi-line attribute: %v", got)
+	}
+
+	_, err = terraformCLI.StateShow(context.Background(), nil, "null_resource.bar")
+	if err == nil {
+		t.Error("expected to return an error for a resource not in state, but no error")
+	}
+}
@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestTerraformCLIRun(t *testing.T) {
@@ -14,6 +18,8 @@ func TestTerraformCLIRun(t *testing.T) {
 		mockCommands []*mockCommand
 		args         []string
 		execPath     string
+		execMode     string
+		goos         string
 		want         string
 		ok           bool
 	}{
@@ -86,14 +92,105 @@ func TestTerraformCLIRun(t *testing.T) {
 			want:     "OpenTofu v1.6.0-alpha3\n",
 			ok:       true,
 		},
+		{
+			desc: "with execMode (terragrunt)",
+			mockCommands: []*mockCommand{
+				{
+					argsRe:   regexp.MustCompile(`^terragrunt --terragrunt-non-interactive --terragrunt-no-auto-init --terragrunt-working-dir /\S* version$`),
+					stdout:   "Terraform v1.6.0\n",
+					exitCode: 0,
+				},
+			},
+			args:     []string{"version"},
+			execPath: "terraform",
+			execMode: "terragrunt",
+			want:     "Terraform v1.6.0\n",
+			ok:       true,
+		},
+		{
+			desc: "with execMode (terragrunt) and execPath",
+			mockCommands: []*mockCommand{
+				{
+					argsRe:   regexp.MustCompile(`^direnv exec \. terragrunt --terragrunt-non-interactive --terragrunt-no-auto-init --terragrunt-working-dir /\S* version$`),
+					stdout:   "Terraform v1.6.0\n",
+					exitCode: 0,
+				},
+			},
+			args:     []string{"version"},
+			execPath: "direnv exec . terragrunt",
+			execMode: "terragrunt",
+			want:     "Terraform v1.6.0\n",
+			ok:       true,
+		},
+		{
+			desc: "with execMode (terragrunt) and wrapped output",
+			mockCommands: []*mockCommand{
+				{
+					argsRe:   regexp.MustCompile(`^terragrunt .* version$`),
+					stdout:   "10:00:00.000 STDOUT terraform: Terraform v1.6.0\n10:00:00.000 STDOUT terraform: on linux_amd64\n",
+					exitCode: 0,
+				},
+			},
+			args:     []string{"version"},
+			execPath: "terraform",
+			execMode: "terragrunt",
+			want:     "Terraform v1.6.0\non linux_amd64\n",
+			ok:       true,
+		},
+		{
+			desc: "with bracketed addresses",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "mv", `aws_instance.foo["bar"]`, `aws_instance.foo["baz qux"]`},
+					exitCode: 0,
+				},
+			},
+			args:     []string{"state", "mv", `aws_instance.foo["bar"]`, `aws_instance.foo["baz qux"]`},
+			execPath: "terraform",
+			want:     "",
+			ok:       true,
+		},
+		{
+			desc: "with execPath (windows)",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{`C:\tools\terraform.exe`, "version"},
+					stdout:   "Terraform v1.6.0\n",
+					exitCode: 0,
+				},
+			},
+			args:     []string{"version"},
+			execPath: `C:\tools\terraform.exe`,
+			goos:     "windows",
+			want:     "Terraform v1.6.0\n",
+			ok:       true,
+		},
+		{
+			desc: "with execPath (windows) and bracketed addresses",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{`C:\Program Files\Terraform\terraform.exe`, "state", "mv", `aws_instance.foo["bar"]`, `module.foo[0].aws_instance.bar["baz"]`},
+					exitCode: 0,
+				},
+			},
+			args:     []string{"state", "mv", `aws_instance.foo["bar"]`, `module.foo[0].aws_instance.bar["baz"]`},
+			execPath: `"C:\Program Files\Terraform\terraform.exe"`,
+			goos:     "windows",
+			want:     "",
+			ok:       true,
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
 			e := NewMockExecutor(tc.mockCommands)
-			terraformCLI := NewTerraformCLI(e)
-			terraformCLI.SetExecPath(tc.execPath)
-			got, _, err := terraformCLI.Run(context.Background(), tc.args...)
+			tf := NewTerraformCLI(e)
+			tf.SetExecPath(tc.execPath)
+			tf.SetExecMode(tc.execMode)
+			if len(tc.goos) > 0 {
+				tf.(*terraformCLI).goos = tc.goos
+			}
+			got, _, err := tf.Run(context.Background(), tc.args...)
 			if tc.ok && err != nil {
 				t.Fatalf("unexpected err: %s", err)
 			}
@@ -107,6 +204,121 @@ func TestTerraformCLIRun(t *testing.T) {
 	}
 }
 
+func TestTerraformCLIOverrideBackendToLocal(t *testing.T) {
+	execPath := `"C:\Program Files\Terraform\terraform.exe"`
+	bin := `C:\Program Files\Terraform\terraform.exe`
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		goos         string
+		ok           bool
+	}{
+		{
+			desc: "switch backend to local and back to remote",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "init", "-input=false", "-no-color", "-reconfigure"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "init", "-input=false", "-no-color", "-reconfigure"},
+					exitCode: 0,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "switch backend to local and back to remote (windows)",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{bin, "init", "-input=false", "-no-color", "-reconfigure"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{bin, "init", "-input=false", "-no-color", "-reconfigure"},
+					exitCode: 0,
+				},
+			},
+			goos: "windows",
+			ok:   true,
+		},
+		{
+			desc: "failed to switch backend to local (windows)",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{bin, "init", "-input=false", "-no-color", "-reconfigure"},
+					exitCode: 1,
+				},
+			},
+			goos: "windows",
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			// A working directory may contain spaces, especially on Windows.
+			dir := filepath.Join(t.TempDir(), "work dir")
+			if err := os.Mkdir(dir, 0700); err != nil {
+				t.Fatalf("failed to create a working directory: %s", err)
+			}
+			e := NewMockExecutor(tc.mockCommands)
+			e.(*mockExecutor).dir = dir
+			tf := NewTerraformCLI(e)
+			if len(tc.goos) > 0 {
+				tf.SetExecPath(execPath)
+				tf.(*terraformCLI).goos = tc.goos
+			} else {
+				tf.SetExecPath("terraform")
+			}
+
+			filename := "_tfexec_override.tf"
+			path := filepath.Join(dir, filename)
+			workspacePath := filepath.Join(dir, "terraform.tfstate.d")
+			switchBackToRemoteFunc, err := tf.OverrideBackendToLocal(context.Background(), filename, "work1", false, nil, false)
+			if !tc.ok {
+				if err == nil {
+					t.Fatal("expected to return an error, but no error")
+				}
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("the override file is left: %s", path)
+				}
+				if _, err := os.Stat(workspacePath); !os.IsNotExist(err) {
+					t.Errorf("the local workspace directory is left: %s", workspacePath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read the override file: %s", err)
+			}
+			if !strings.Contains(string(b), `backend "local"`) {
+				t.Errorf("unexpected override file: %s", b)
+			}
+			if _, err := os.Stat(filepath.Join(workspacePath, "work1")); err != nil {
+				t.Errorf("the local workspace state directory does not exist: %s", err)
+			}
+
+			if err := switchBackToRemoteFunc(); err != nil {
+				t.Fatalf("failed to switch back to remote: %s", err)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("the override file is left: %s", path)
+			}
+			if _, err := os.Stat(workspacePath); !os.IsNotExist(err) {
+				t.Errorf("the local workspace directory is left: %s", workspacePath)
+			}
+			if calls := e.(*mockExecutor).newCommnadContextCalls; calls != len(tc.mockCommands) {
+				t.Errorf("got %d calls, want: %d", calls, len(tc.mockCommands))
+			}
+		})
+	}
+}
+
 func TestAccTerraformCLIOverrideBackendToLocal(t *testing.T) {
 	SkipUnlessAcceptanceTestEnabled(t)
 
@@ -389,3 +601,144 @@ func TestGetOptionValue(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitExecPath(t *testing.T) {
+	cases := []struct {
+		desc     string
+		execPath string
+		goos     string
+		want     []string
+		ok       bool
+	}{
+		{
+			desc:     "no space",
+			execPath: "terraform",
+			goos:     "linux",
+			want:     []string{"terraform"},
+			ok:       true,
+		},
+		{
+			desc:     "spaces",
+			execPath: "direnv exec . terraform",
+			goos:     "linux",
+			want:     []string{"direnv", "exec", ".", "terraform"},
+			ok:       true,
+		},
+		{
+			desc:     "escaped space on linux",
+			execPath: `/opt/my\ tools/terraform`,
+			goos:     "linux",
+			want:     []string{"/opt/my tools/terraform"},
+			ok:       true,
+		},
+		{
+			desc:     "windows path",
+			execPath: `C:\tools\terraform.exe`,
+			goos:     "windows",
+			want:     []string{`C:\tools\terraform.exe`},
+			ok:       true,
+		},
+		{
+			desc:     "quoted windows path with spaces",
+			execPath: `"C:\Program Files\Terraform\terraform.exe" -chdir=.`,
+			goos:     "windows",
+			want:     []string{`C:\Program Files\Terraform\terraform.exe`, "-chdir=."},
+			ok:       true,
+		},
+		{
+			desc:     "escaped double quotes on windows",
+			execPath: `C:\tools\wrapper.exe --label=\"foo bar\"`,
+			goos:     "windows",
+			want:     []string{`C:\tools\wrapper.exe`, `--label="foo`, `bar"`},
+			ok:       true,
+		},
+		{
+			desc:     "escaped double quotes in double quotes on windows",
+			execPath: `"C:\tools\wrapper.exe" "--label=\"foo bar\""`,
+			goos:     "windows",
+			want:     []string{`C:\tools\wrapper.exe`, `--label="foo bar"`},
+			ok:       true,
+		},
+		{
+			desc:     "single quoted windows path with spaces",
+			execPath: `'C:\Program Files\Terraform\terraform.exe'`,
+			goos:     "windows",
+			want:     []string{`C:\Program Files\Terraform\terraform.exe`},
+			ok:       true,
+		},
+		{
+			desc:     "unc path on windows",
+			execPath: `\\server\share\terraform.exe`,
+			goos:     "windows",
+			want:     []string{`\\server\share\terraform.exe`},
+			ok:       true,
+		},
+		{
+			desc:     "empty",
+			execPath: "",
+			goos:     "linux",
+			want:     nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := splitExecPath(tc.execPath, tc.goos)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got = %#v", got)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteTempFile(t *testing.T) {
+	content := []byte("dummy state")
+	f, err := writeTempFile("", content)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	// The file must be closed so that another process can open it on Windows.
+	if _, err := f.Write([]byte("foo")); err == nil {
+		t.Errorf("expected the temporary file to be closed: %s", f.Name())
+	}
+
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("failed to read temporary file: %s", err)
+	}
+	if string(got) != string(content) {
+		t.Errorf("got: %s, want: %s", got, content)
+	}
+}
+
+func FuzzSplitExecPath(f *testing.F) {
+	f.Add(`C:\tools\terraform.exe`)
+	f.Add("/usr/local/bin/terraform")
+	f.Add(`\\server\share\tofu.exe`)
+
+	f.Fuzz(func(t *testing.T, path string) {
+		// Shell metacharacters are not supported in a path, and backslashes
+		// alone cannot be a path of binary.
+		if len(strings.Trim(path, `\`)) == 0 || !utf8.ValidString(path) || strings.ContainsAny(path, " \t\r\n'\"`;&|<>()") {
+			t.Skip()
+		}
+		// A path without spaces and quotes must be kept as it is on Windows,
+		// where a backslash is a path separator.
+		got, err := splitExecPath(path, "windows")
+		if err != nil {
+			t.Fatalf("unexpected err: %s", err)
+		}
+		if want := []string{path}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got: %#v, want: %#v", got, want)
+		}
+	})
+}
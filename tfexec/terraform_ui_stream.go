@@ -0,0 +1,202 @@
+package tfexec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/go-version"
+)
+
+// MinimumTerraformVersionForUIStream is the minimum version of Terraform
+// which supports the -json flag for plan, apply and destroy.
+const MinimumTerraformVersionForUIStream = "0.15.3"
+
+// UIMessage is a message of the machine-readable UI of terraform.
+// We define only fields we need. See the Terraform documentation for details.
+// https://developer.hashicorp.com/terraform/internals/machine-readable-ui
+type UIMessage struct {
+	// Level is a log level such as info, warn and error.
+	Level string `json:"@level"`
+	// Message is a human-readable message.
+	Message string `json:"@message"`
+	// Module is a module which emits the message. e.g.) terraform.ui
+	Module string `json:"@module"`
+	// Timestamp is a timestamp in RFC3339 format.
+	Timestamp string `json:"@timestamp"`
+	// Type is a type of the message.
+	// e.g.) version, diagnostic, planned_change, change_summary,
+	// apply_start, apply_progress, apply_complete, apply_errored, outputs
+	Type string `json:"type"`
+	// Diagnostic is set if the type is diagnostic.
+	Diagnostic *UIDiagnostic `json:"diagnostic,omitempty"`
+	// Hook is set if the type is one of resource progress, such as apply_start.
+	Hook *UIHook `json:"hook,omitempty"`
+	// Change is set if the type is planned_change or resource_drift.
+	Change *UIResourceChange `json:"change,omitempty"`
+	// Changes is set if the type is change_summary.
+	Changes *UIChangeSummary `json:"changes,omitempty"`
+}
+
+// UIDiagnostic is a diagnostic of the machine-readable UI.
+type UIDiagnostic struct {
+	// Severity is either error or warning.
+	Severity string `json:"severity"`
+	// Summary is a short description of the diagnostic.
+	Summary string `json:"summary"`
+	// Detail is a detailed description of the diagnostic.
+	Detail string `json:"detail"`
+	// Address is an address of the resource related to the diagnostic if any.
+	Address string `json:"address,omitempty"`
+}
+
+// UIResource is a resource of the machine-readable UI.
+type UIResource struct {
+	// Addr is an absolute address of the resource.
+	Addr string `json:"addr"`
+	// Module is an address of the module which contains the resource.
+	Module string `json:"module"`
+	// ResourceType is a type of the resource.
+	ResourceType string `json:"resource_type"`
+	// ResourceName is a name of the resource.
+	ResourceName string `json:"resource_name"`
+}
+
+// UIHook is a progress of an operation for a resource.
+type UIHook struct {
+	// Resource is a resource which the operation is applied to.
+	Resource UIResource `json:"resource"`
+	// Action is an action of the operation. e.g.) create, update, delete
+	Action string `json:"action"`
+	// IDKey is a key of the resource ID attribute if known.
+	IDKey string `json:"id_key,omitempty"`
+	// IDValue is a value of the resource ID attribute if known.
+	IDValue string `json:"id_value,omitempty"`
+	// ElapsedSeconds is an elapsed time of the operation.
+	ElapsedSeconds float64 `json:"elapsed_seconds,omitempty"`
+}
+
+// UIResourceChange is a planned change for a resource.
+type UIResourceChange struct {
+	// Resource is a resource which the change is applied to.
+	Resource UIResource `json:"resource"`
+	// PreviousResource is set if the resource is moved.
+	PreviousResource *UIResource `json:"previous_resource,omitempty"`
+	// Action is an action of the change. e.g.) noop, create, read, update, replace, delete, move
+	Action string `json:"action"`
+	// Reason is a reason of the change if any.
+	Reason string `json:"reason,omitempty"`
+}
+
+// UIChangeSummary is a summary of changes.
+type UIChangeSummary struct {
+	// Add is a number of resources to be added.
+	Add int `json:"add"`
+	// Change is a number of resources to be changed.
+	Change int `json:"change"`
+	// Import is a number of resources to be imported.
+	Import int `json:"import"`
+	// Remove is a number of resources to be removed.
+	Remove int `json:"remove"`
+	// Operation is either plan, apply or destroy.
+	Operation string `json:"operation"`
+}
+
+// ParseUIMessage parses a line of the machine-readable UI and returns a UIMessage.
+func ParseUIMessage(line []byte) (*UIMessage, error) {
+	var m UIMessage
+	if err := json.Unmarshal(line, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse UI message: %s", err)
+	}
+	return &m, nil
+}
+
+// SupportsUIStream is a helper method used to determine whether or not the
+// terraform version supports the -json flag for plan, apply and destroy.
+func (c *terraformCLI) SupportsUIStream(ctx context.Context) (bool, error) {
+	constraints, err := version.NewConstraint(fmt.Sprintf(">= %s", MinimumTerraformVersionForUIStream))
+	if err != nil {
+		return false, err
+	}
+
+	_, v, err := c.Version(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	ver, err := truncatePreReleaseVersion(v)
+	if err != nil {
+		return false, err
+	}
+
+	return constraints.Check(ver), nil
+}
+
+// runWithUIStream runs a terraform command with the -json flag if the UI
+// stream is set, and sends parsed messages to the stream while running.
+// The first argument must be a subcommand, and the -json flag is inserted
+// right after it.
+func (c *terraformCLI) runWithUIStream(ctx context.Context, args ...string) (string, string, error) {
+	if c.uiStream == nil {
+		return c.Run(ctx, args...)
+	}
+
+	jsonArgs := []string{args[0], "-json"}
+	jsonArgs = append(jsonArgs, args[1:]...)
+
+	w := &uiStreamWriter{ch: c.uiStream}
+	stdout, stderr, err := c.run(ctx, w, jsonArgs...)
+	w.flush()
+	return stdout, stderr, err
+}
+
+// uiStreamWriter is an io.Writer which parses lines of the machine-readable
+// UI and sends them to a channel.
+type uiStreamWriter struct {
+	// ch is a channel to which parsed messages are sent.
+	ch chan<- *UIMessage
+	// buf is a buffer for an incomplete line.
+	buf bytes.Buffer
+}
+
+// Write buffers given bytes and sends messages for each complete line.
+func (w *uiStreamWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		b := w.buf.Bytes()
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			break
+		}
+		line := make([]byte, i)
+		copy(line, b[:i])
+		w.buf.Next(i + 1)
+		w.send(line)
+	}
+	return len(p), nil
+}
+
+// flush sends a message for the remaining incomplete line if any.
+func (w *uiStreamWriter) flush() {
+	if w.buf.Len() > 0 {
+		w.send(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// send parses a line and sends it to the channel.
+// A line which is not a valid message is ignored not to stop the command.
+func (w *uiStreamWriter) send(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	m, err := ParseUIMessage(line)
+	if err != nil {
+		log.Printf("[DEBUG] [executor] ignore an invalid UI message: %s, err: %s\n", line, err)
+		return
+	}
+	w.ch <- m
+}
@@ -0,0 +1,224 @@
+package tfexec
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseUIMessage(t *testing.T) {
+	cases := []struct {
+		desc string
+		line string
+		want *UIMessage
+		ok   bool
+	}{
+		{
+			desc: "diagnostic",
+			line: `{"@level":"error","@message":"Error: foo","@module":"terraform.ui","@timestamp":"2021-05-25T13:32:41.275359-04:00","diagnostic":{"severity":"error","summary":"foo","detail":"bar","address":"null_resource.foo"},"type":"diagnostic"}`,
+			want: &UIMessage{
+				Level:     "error",
+				Message:   "Error: foo",
+				Module:    "terraform.ui",
+				Timestamp: "2021-05-25T13:32:41.275359-04:00",
+				Type:      "diagnostic",
+				Diagnostic: &UIDiagnostic{
+					Severity: "error",
+					Summary:  "foo",
+					Detail:   "bar",
+					Address:  "null_resource.foo",
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "apply_complete",
+			line: `{"@level":"info","@message":"null_resource.foo: Creation complete after 0s [id=123]","@module":"terraform.ui","@timestamp":"2021-05-25T13:32:41.275359-04:00","hook":{"resource":{"addr":"null_resource.foo","module":"","resource":"null_resource.foo","implied_provider":"null","resource_type":"null_resource","resource_name":"foo","resource_key":null},"action":"create","id_key":"id","id_value":"123","elapsed_seconds":0},"type":"apply_complete"}`,
+			want: &UIMessage{
+				Level:     "info",
+				Message:   "null_resource.foo: Creation complete after 0s [id=123]",
+				Module:    "terraform.ui",
+				Timestamp: "2021-05-25T13:32:41.275359-04:00",
+				Type:      "apply_complete",
+				Hook: &UIHook{
+					Resource: UIResource{
+						Addr:         "null_resource.foo",
+						ResourceType: "null_resource",
+						ResourceName: "foo",
+					},
+					Action:  "create",
+					IDKey:   "id",
+					IDValue: "123",
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "change_summary",
+			line: `{"@level":"info","@message":"Plan: 1 to add, 0 to change, 0 to destroy.","@module":"terraform.ui","@timestamp":"2021-05-25T13:32:41.275359-04:00","changes":{"add":1,"change":0,"import":0,"remove":0,"operation":"plan"},"type":"change_summary"}`,
+			want: &UIMessage{
+				Level:     "info",
+				Message:   "Plan: 1 to add, 0 to change, 0 to destroy.",
+				Module:    "terraform.ui",
+				Timestamp: "2021-05-25T13:32:41.275359-04:00",
+				Type:      "change_summary",
+				Changes: &UIChangeSummary{
+					Add:       1,
+					Operation: "plan",
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid json",
+			line: `Terraform will perform the following actions:`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseUIMessage([]byte(tc.line))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTerraformCLIApplyWithUIStream(t *testing.T) {
+	stdout := `{"@level":"info","@message":"Terraform 1.0.0","@module":"terraform.ui","type":"version"}
+not a json line
+{"@level":"info","@message":"null_resource.foo: Creating...","@module":"terraform.ui","hook":{"resource":{"addr":"null_resource.foo"},"action":"create"},"type":"apply_start"}
+{"@level":"info","@message":"Apply complete!","@module":"terraform.ui","changes":{"add":1,"change":0,"remove":0,"operation":"apply"},"type":"change_summary"}`
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		opts         []string
+		want         []string
+		ok           bool
+	}{
+		{
+			desc: "with ui stream",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "apply", "-json", "-auto-approve"},
+					stdout:   stdout,
+					exitCode: 0,
+				},
+			},
+			opts: []string{"-auto-approve"},
+			want: []string{"version", "apply_start", "change_summary"},
+			ok:   true,
+		},
+		{
+			desc: "failed to run terraform apply",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "apply", "-json", "-auto-approve"},
+					stdout:   `{"@level":"error","@message":"Error: foo","diagnostic":{"severity":"error","summary":"foo"},"type":"diagnostic"}` + "\n",
+					exitCode: 1,
+				},
+			},
+			opts: []string{"-auto-approve"},
+			want: []string{"diagnostic"},
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			ch := make(chan *UIMessage, 10)
+			terraformCLI.SetUIStream(ch)
+			err := terraformCLI.Apply(context.Background(), nil, tc.opts...)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			close(ch)
+
+			got := []string{}
+			for m := range ch {
+				got = append(got, m.Type)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTerraformCLISupportsUIStream(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		want         bool
+		ok           bool
+	}{
+		{
+			desc: "supported",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "version"},
+					stdout:   "Terraform v1.0.0\n",
+					exitCode: 0,
+				},
+			},
+			want: true,
+			ok:   true,
+		},
+		{
+			desc: "not supported",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "version"},
+					stdout:   "Terraform v0.14.11\n",
+					exitCode: 0,
+				},
+			},
+			want: false,
+			ok:   true,
+		},
+		{
+			desc: "failed to run terraform version",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "version"},
+					exitCode: 1,
+				},
+			},
+			want: false,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.SupportsUIStream(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,10 @@ import (
 	"github.com/hashicorp/go-version"
 )
 
+// MinimumTerraformVersion is the minimum version of Terraform which
+// tfmigrate supports.
+const MinimumTerraformVersion = "0.12"
+
 // tfVersionRe is a pattern to parse outputs from terraform version.
 var tfVersionRe = regexp.MustCompile(`^(Terraform|OpenTofu) v(.+)\s*\n`)
 
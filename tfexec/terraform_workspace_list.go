@@ -0,0 +1,29 @@
+package tfexec
+
+import (
+	"context"
+	"strings"
+)
+
+// WorkspaceList returns a list of workspaces in the backend.
+// It is read-only and doesn't require any workspace to be selected.
+func (c *terraformCLI) WorkspaceList(ctx context.Context) ([]string, error) {
+	args := []string{"workspace", "list"}
+	stdout, _, err := c.Run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The output is such as:
+	// * default
+	//   foo
+	workspaces := []string{}
+	for _, l := range strings.Split(stdout, "\n") {
+		w := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "*"))
+		if len(w) == 0 {
+			continue
+		}
+		workspaces = append(workspaces, w)
+	}
+	return workspaces, nil
+}
@@ -0,0 +1,57 @@
+package tfexec
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTerraformCLIWorkspaceList(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		want         []string
+		ok           bool
+	}{
+		{
+			desc: "parse output of terraform workspace list",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "workspace", "list"},
+					stdout:   "  default\n* foo\n  bar\n\n",
+					exitCode: 0,
+				},
+			},
+			want: []string{"default", "foo", "bar"},
+			ok:   true,
+		},
+		{
+			desc: "failed to run terraform workspace list",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "workspace", "list"},
+					exitCode: 1,
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.WorkspaceList(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
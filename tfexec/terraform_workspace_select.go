@@ -2,8 +2,17 @@ package tfexec
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/go-version"
 )
 
+// MinimumTerraformVersionForWorkspaceSelectOrCreate is the minimum version of
+// Terraform which supports the -or-create flag for workspace select.
+const MinimumTerraformVersionForWorkspaceSelectOrCreate = "1.4.0"
+
 // WorkspaceSelect selects the workspace "workspace". The workspace needs to exist
 // in order for the switch to be successful
 func (c *terraformCLI) WorkspaceSelect(ctx context.Context, workspace string) error {
@@ -14,3 +23,50 @@ func (c *terraformCLI) WorkspaceSelect(ctx context.Context, workspace string) er
 	_, _, err := c.Run(ctx, args...)
 	return err
 }
+
+// SupportsWorkspaceSelectOrCreate returns true if terraform version supports
+// the -or-create flag for workspace select.
+func (c *terraformCLI) SupportsWorkspaceSelectOrCreate(ctx context.Context) (bool, error) {
+	constraints, err := version.NewConstraint(fmt.Sprintf(">= %s", MinimumTerraformVersionForWorkspaceSelectOrCreate))
+	if err != nil {
+		return false, err
+	}
+
+	_, v, err := c.Version(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	ver, err := truncatePreReleaseVersion(v)
+	if err != nil {
+		return false, err
+	}
+
+	return constraints.Check(ver), nil
+}
+
+// WorkspaceSelectOrCreate selects the workspace "workspace", and creates it if
+// it doesn't exist. For older versions of terraform which don't support the
+// -or-create flag, it falls back to creating the workspace if selecting it
+// fails.
+func (c *terraformCLI) WorkspaceSelectOrCreate(ctx context.Context, workspace string) error {
+	supports, err := c.SupportsWorkspaceSelectOrCreate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if supports {
+		_, _, err := c.Run(ctx, "workspace", "select", "-or-create", workspace)
+		return err
+	}
+
+	selectErr := c.WorkspaceSelect(ctx, workspace)
+	if selectErr == nil {
+		return nil
+	}
+	log.Printf("[INFO] [executor@%s] failed to select workspace %s, try to create it\n", c.Dir(), workspace)
+	if err := c.WorkspaceNew(ctx, workspace); err != nil {
+		return errors.Join(selectErr, err)
+	}
+	return nil
+}
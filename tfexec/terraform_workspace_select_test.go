@@ -61,6 +61,129 @@ func TestTerraformCLIWorkspaceSelect(t *testing.T) {
 	}
 }
 
+func TestTerraformCLIWorkspaceSelectOrCreate(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		workspace    string
+		ok           bool
+	}{
+		{
+			desc: "with -or-create",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "version"},
+					stdout:   "Terraform v1.4.0\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "-or-create", "foo"},
+					exitCode: 0,
+				},
+			},
+			workspace: "foo",
+			ok:        true,
+		},
+		{
+			desc: "failed to run terraform workspace select -or-create",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "version"},
+					stdout:   "Terraform v1.4.0\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "-or-create", "foo"},
+					exitCode: 1,
+				},
+			},
+			workspace: "foo",
+			ok:        false,
+		},
+		{
+			desc: "existing workspace without -or-create",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "version"},
+					stdout:   "Terraform v1.3.9\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "foo"},
+					exitCode: 0,
+				},
+			},
+			workspace: "foo",
+			ok:        true,
+		},
+		{
+			desc: "new workspace without -or-create",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "version"},
+					stdout:   "Terraform v1.3.9\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "foo"},
+					exitCode: 1,
+				},
+				{
+					args:     []string{"terraform", "workspace", "new", "foo"},
+					exitCode: 0,
+				},
+			},
+			workspace: "foo",
+			ok:        true,
+		},
+		{
+			desc: "failed to create workspace without -or-create",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "version"},
+					stdout:   "Terraform v1.3.9\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "foo"},
+					exitCode: 1,
+				},
+				{
+					args:     []string{"terraform", "workspace", "new", "foo"},
+					exitCode: 1,
+				},
+			},
+			workspace: "foo",
+			ok:        false,
+		},
+		{
+			desc: "failed to run terraform version",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "version"},
+					exitCode: 1,
+				},
+			},
+			workspace: "foo",
+			ok:        false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			err := terraformCLI.WorkspaceSelectOrCreate(context.Background(), tc.workspace)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
 func TestAccTerraformCLIWorkspaceSelect(t *testing.T) {
 	SkipUnlessAcceptanceTestEnabled(t)
 
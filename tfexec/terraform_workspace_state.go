@@ -0,0 +1,68 @@
+package tfexec
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// StatePullFromWorkspace selects a given workspace, pulls its state, and
+// restores the previously selected workspace.
+func (c *terraformCLI) StatePullFromWorkspace(ctx context.Context, workspace string, opts ...string) (*State, error) {
+	var state *State
+	err := c.withWorkspace(ctx, workspace, func() error {
+		var err error
+		state, err = c.StatePull(ctx, opts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// StatePushToWorkspace selects a given workspace, pushes a given state to it,
+// and restores the previously selected workspace.
+func (c *terraformCLI) StatePushToWorkspace(ctx context.Context, workspace string, state *State, opts ...string) error {
+	return c.withWorkspace(ctx, workspace, func() error {
+		return c.StatePush(ctx, state, opts...)
+	})
+}
+
+// withWorkspace runs a given function with a given workspace selected.
+// The previously selected workspace is restored even if the function fails,
+// so that the working directory is never left on the wrong workspace.
+// If it fails to restore, the error is returned with the original error.
+func (c *terraformCLI) withWorkspace(ctx context.Context, workspace string, f func() error) (err error) {
+	current, err := c.WorkspaceShow(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current == workspace {
+		return f()
+	}
+
+	log.Printf("[DEBUG] [executor@%s] switch workspace from %s to %s\n", c.Dir(), current, workspace)
+	if err := c.WorkspaceSelect(ctx, workspace); err != nil {
+		return err
+	}
+
+	defer func() {
+		log.Printf("[DEBUG] [executor@%s] switch workspace back to %s\n", c.Dir(), current)
+		serr := c.WorkspaceSelect(ctx, current)
+		if serr == nil {
+			return
+		}
+
+		log.Printf("[ERROR] [executor@%s] failed to switch workspace back to %s. Please run terraform workspace select %s\n", c.Dir(), current, current)
+		if err == nil {
+			err = fmt.Errorf("failed to switch workspace back to %s: %s", current, serr)
+			return
+		}
+		err = fmt.Errorf("failed to switch workspace back to %s: %s, original error: %s", current, serr, err)
+	}()
+
+	return f()
+}
@@ -0,0 +1,226 @@
+package tfexec
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestTerraformCLIStatePullFromWorkspace(t *testing.T) {
+	stdout := "dummy state"
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		workspace    string
+		want         *State
+		ok           bool
+	}{
+		{
+			desc: "current workspace",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "workspace", "show"},
+					stdout:   "default\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "state", "pull"},
+					stdout:   stdout,
+					exitCode: 0,
+				},
+			},
+			workspace: "default",
+			want:      NewState([]byte(stdout)),
+			ok:        true,
+		},
+		{
+			desc: "another workspace",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "workspace", "show"},
+					stdout:   "default\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "foo"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "state", "pull"},
+					stdout:   stdout,
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "default"},
+					exitCode: 0,
+				},
+			},
+			workspace: "foo",
+			want:      NewState([]byte(stdout)),
+			ok:        true,
+		},
+		{
+			desc: "failed to select workspace",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "workspace", "show"},
+					stdout:   "default\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "foo"},
+					exitCode: 1,
+				},
+			},
+			workspace: "foo",
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc: "failed to pull state and switch back",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "workspace", "show"},
+					stdout:   "default\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "foo"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "state", "pull"},
+					exitCode: 1,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "default"},
+					exitCode: 0,
+				},
+			},
+			workspace: "foo",
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc: "failed to switch back",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "workspace", "show"},
+					stdout:   "default\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "foo"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "state", "pull"},
+					stdout:   stdout,
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "default"},
+					exitCode: 1,
+				},
+			},
+			workspace: "foo",
+			want:      nil,
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.StatePullFromWorkspace(context.Background(), tc.workspace)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTerraformCLIStatePushToWorkspace(t *testing.T) {
+	state := NewState([]byte("dummy state"))
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		workspace    string
+		ok           bool
+	}{
+		{
+			desc: "another workspace",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "workspace", "show"},
+					stdout:   "default\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "foo"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "state", "push", "/path/to/tempfile"},
+					argsRe:   regexp.MustCompile(`^terraform state push \S+$`),
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "default"},
+					exitCode: 0,
+				},
+			},
+			workspace: "foo",
+			ok:        true,
+		},
+		{
+			desc: "failed to push state and switch back",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "workspace", "show"},
+					stdout:   "default\n",
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "foo"},
+					exitCode: 0,
+				},
+				{
+					args:     []string{"terraform", "state", "push", "/path/to/tempfile"},
+					argsRe:   regexp.MustCompile(`^terraform state push \S+$`),
+					exitCode: 1,
+				},
+				{
+					args:     []string{"terraform", "workspace", "select", "default"},
+					exitCode: 0,
+				},
+			},
+			workspace: "foo",
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			err := terraformCLI.StatePushToWorkspace(context.Background(), tc.workspace, state)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package tfexec
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ExecModeTerraform is an exec mode which runs terraform or tofu directly.
+	ExecModeTerraform = "terraform"
+	// ExecModeTerragrunt is an exec mode which runs terraform via terragrunt.
+	ExecModeTerragrunt = "terragrunt"
+)
+
+// ValidateExecMode returns an error if a given exec mode is unknown.
+// An empty string is valid, which means ExecModeTerraform.
+func ValidateExecMode(mode string) error {
+	switch mode {
+	case "", ExecModeTerraform, ExecModeTerragrunt:
+		return nil
+	default:
+		return fmt.Errorf("unknown exec mode: %s, it must be either %s or %s", mode, ExecModeTerraform, ExecModeTerragrunt)
+	}
+}
+
+// terragruntFlags returns flags for terragrunt prepended to a terraform
+// command. Terragrunt must never prompt, because no one can answer it.
+// Auto-init is disabled, because it would re-initialize a working directory
+// switched to the local backend with the remote backend. The working
+// directory is given explicitly as an absolute path, so that paths are not
+// affected by a cache directory where terragrunt runs terraform.
+func terragruntFlags(dir string) ([]string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of %s: %s", dir, err)
+	}
+
+	return []string{
+		"--terragrunt-non-interactive",
+		"--terragrunt-no-auto-init",
+		"--terragrunt-working-dir", absDir,
+	}, nil
+}
+
+// terragruntOutputPrefixRe is a regular expression for a prefix which
+// terragrunt adds to each line of stdout of terraform in its log format,
+// such as `10:00:00.000 STDOUT terraform: ` or
+// `10:00:00.000 STDOUT [dir] tofu: `.
+var terragruntOutputPrefixRe = regexp.MustCompile(`^(\S+ )?STDOUT (\[[^\]]*\] )?(terraform|tofu): ?`)
+
+// unwrapTerragruntOutput returns stdout of terraform wrapped by terragrunt
+// with prefixes of its log format removed, so that outputs such as a state
+// pulled can be parsed as they are. A line without the prefix is kept as it
+// is, because older versions of terragrunt don't wrap outputs.
+func unwrapTerragruntOutput(stdout string) string {
+	if !strings.Contains(stdout, "STDOUT ") {
+		return stdout
+	}
+
+	lines := strings.SplitAfter(stdout, "\n")
+	for i, line := range lines {
+		lines[i] = terragruntOutputPrefixRe.ReplaceAllString(line, "")
+	}
+	return strings.Join(lines, "")
+}
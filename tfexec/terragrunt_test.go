@@ -0,0 +1,89 @@
+package tfexec
+
+import (
+	"testing"
+)
+
+func TestValidateExecMode(t *testing.T) {
+	cases := []struct {
+		desc string
+		mode string
+		ok   bool
+	}{
+		{
+			desc: "empty",
+			mode: "",
+			ok:   true,
+		},
+		{
+			desc: "terraform",
+			mode: "terraform",
+			ok:   true,
+		},
+		{
+			desc: "terragrunt",
+			mode: "terragrunt",
+			ok:   true,
+		},
+		{
+			desc: "unknown",
+			mode: "foo",
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := ValidateExecMode(tc.mode)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestUnwrapTerragruntOutput(t *testing.T) {
+	cases := []struct {
+		desc   string
+		stdout string
+		want   string
+	}{
+		{
+			desc:   "not wrapped",
+			stdout: "null_resource.foo\nnull_resource.bar\n",
+			want:   "null_resource.foo\nnull_resource.bar\n",
+		},
+		{
+			desc:   "wrapped",
+			stdout: "10:00:00.000 STDOUT terraform: null_resource.foo\n10:00:00.000 STDOUT terraform: null_resource.bar\n",
+			want:   "null_resource.foo\nnull_resource.bar\n",
+		},
+		{
+			desc:   "wrapped with a directory (tofu)",
+			stdout: "10:00:00.000 STDOUT [foo/bar] tofu: null_resource.foo\n",
+			want:   "null_resource.foo\n",
+		},
+		{
+			desc:   "wrapped with an empty line",
+			stdout: "10:00:00.000 STDOUT terraform: {\n10:00:00.000 STDOUT terraform:\n10:00:00.000 STDOUT terraform: }\n",
+			want:   "{\n\n}\n",
+		},
+		{
+			desc:   "mixed",
+			stdout: "10:00:00.000 STDOUT terraform: null_resource.foo\nnull_resource.bar",
+			want:   "null_resource.foo\nnull_resource.bar",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := unwrapTerragruntOutput(tc.stdout)
+			if got != tc.want {
+				t.Errorf("got: %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package tfexec
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -32,6 +33,8 @@ type mockExecutor struct {
 	newCommnadContextCalls int
 	// runCalls counts the Run method calls.
 	runCalls int
+	// dir is a working directory returned by the Dir method.
+	dir string
 }
 
 var _ Executor = (*mockExecutor)(nil)
@@ -75,8 +78,7 @@ func (e *mockExecutor) Run(cmd Command) error {
 
 // Dir returns the current working directory.
 func (e *mockExecutor) Dir() string {
-	// no op.
-	return ""
+	return e.dir
 }
 
 // AppendEnv appends an environment variable.
@@ -84,6 +86,12 @@ func (e *mockExecutor) AppendEnv(_ string, _ string) {
 	// no op.
 }
 
+// Env returns environment variables passed to a command.
+func (e *mockExecutor) Env() []string {
+	// no op.
+	return nil
+}
+
 // mockRunFunc is a type for callback of mockCommand.Run() to allow us to cause side effects.
 type mockRunFunc func(args ...string) error
 
@@ -107,6 +115,8 @@ type mockCommand struct {
 	stderr string
 	// mockExitCode is a mocked exit code.
 	exitCode int
+	// tee is a writer to which the mocked stdout is copied.
+	tee io.Writer
 }
 
 var _ Command = (*mockCommand)(nil)
@@ -120,6 +130,12 @@ func (c *mockCommand) Run() error {
 		}
 	}
 
+	if c.tee != nil {
+		if _, err := io.WriteString(c.tee, c.stdout); err != nil {
+			return err
+		}
+	}
+
 	if c.exitCode != 0 {
 		return &mockExitError{
 			exitCode: c.exitCode,
@@ -144,6 +160,11 @@ func (c *mockCommand) Args() []string {
 	return c.args
 }
 
+// TeeStdout copies the mocked stdout to a given writer on Run.
+func (c *mockCommand) TeeStdout(w io.Writer) {
+	c.tee = w
+}
+
 // mockExitError implements the ExitError interface for testing.
 type mockExitError struct {
 	// exitCode is a mocked exit code.
@@ -178,6 +199,16 @@ func (e *mockExitError) ExitCode() int {
 	return e.exitCode
 }
 
+// Stdout returns outputs of stdout of the command.
+func (e *mockExitError) Stdout() string {
+	return e.cmd.Stdout()
+}
+
+// Stderr returns outputs of stderr of the command.
+func (e *mockExitError) Stderr() string {
+	return e.cmd.Stderr()
+}
+
 // testAccSourceFileName is a filename of terraform configuration for testing.
 var testAccSourceFileName = "main.tf"
 
@@ -0,0 +1,98 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ActionConfig is a config for an action with options which relax the check
+// of terraform plan only for the action, so that one known-noisy resource
+// doesn't require forcing the whole migration.
+type ActionConfig struct {
+	// Command is a plain text of the action in the same format as an element
+	// of actions, such as "mv <source> <destination>".
+	Command string `hcl:"command"`
+	// Force allows any changes in terraform plan to addresses touched by the
+	// action and addresses under them.
+	Force bool `hcl:"force,optional"`
+	// AllowChanges is a list of changes allowed in terraform plan in the
+	// format of <action>:<address> in the same way as allow of the migration.
+	AllowChanges []string `hcl:"allow_changes,optional"`
+}
+
+// anyActionForced returns true if force is set to any of given action configs.
+func anyActionForced(configs []ActionConfig) bool {
+	for _, a := range configs {
+		if a.Force {
+			return true
+		}
+	}
+	return false
+}
+
+// newPlanExpectationWithActions returns a new PlanExpectation for given
+// attributes of a migration and options of actions. Changes allowed by
+// actions are added to ones allowed by the migration. Options of actions are
+// redundant if the migration is forced without expectations, in which case it
+// returns nil, which means the default check with -detailed-exitcode.
+func newPlanExpectationWithActions(noChanges bool, allow []string, force bool, configs []ActionConfig) (*PlanExpectation, error) {
+	expect, err := NewPlanExpectation(noChanges, allow)
+	if err != nil {
+		return nil, err
+	}
+
+	allowChanges := []string{}
+	var forced []string
+	for _, a := range configs {
+		allowChanges = append(allowChanges, a.AllowChanges...)
+		if !a.Force {
+			continue
+		}
+		addresses, err := actionAddresses(a.Command)
+		if err != nil {
+			return nil, err
+		}
+		if len(addresses) == 0 {
+			return nil, fmt.Errorf("force cannot be set to an action which doesn't take addresses: %s", a.Command)
+		}
+		forced = append(forced, addresses...)
+	}
+
+	if len(allowChanges) == 0 && len(forced) == 0 {
+		return expect, nil
+	}
+	if noChanges {
+		return nil, fmt.Errorf("expect_no_changes cannot be set with force or allow_changes of actions")
+	}
+	// validate the format of allowed changes.
+	if _, err := NewPlanExpectation(false, allowChanges); err != nil {
+		return nil, err
+	}
+	if expect == nil && force {
+		return nil, nil
+	}
+
+	return &PlanExpectation{
+		Allow:  append(append([]string{}, allow...), allowChanges...),
+		Forced: forced,
+	}, nil
+}
+
+// isForcedAddress returns true if a given address of a change is one of given
+// addresses touched by forced actions or under them. An address which
+// contains a wildcard or a reference to matched values of xmv is compared
+// only by a part before them.
+func isForcedAddress(address string, forced []string) bool {
+	for _, f := range forced {
+		if i := strings.IndexAny(f, "*$"); i >= 0 {
+			if strings.HasPrefix(address, f[:i]) {
+				return true
+			}
+			continue
+		}
+		if hasAddressPrefix(address, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package tfmigrate
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewPlanExpectationWithActions(t *testing.T) {
+	cases := []struct {
+		desc      string
+		noChanges bool
+		allow     []string
+		force     bool
+		configs   []ActionConfig
+		want      *PlanExpectation
+		ok        bool
+	}{
+		{
+			desc: "no options",
+			configs: []ActionConfig{
+				{Command: "mv aws_iam_role.foo aws_iam_role.bar"},
+			},
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc:  "allow without options",
+			allow: []string{"update:aws_iam_role.foo"},
+			configs: []ActionConfig{
+				{Command: "mv aws_iam_role.foo aws_iam_role.bar"},
+			},
+			want: &PlanExpectation{
+				Allow: []string{"update:aws_iam_role.foo"},
+			},
+			ok: true,
+		},
+		{
+			desc:  "merge allow and allow_changes",
+			allow: []string{"update:aws_iam_role.foo"},
+			configs: []ActionConfig{
+				{
+					Command:      "mv aws_instance.foo aws_instance.bar",
+					AllowChanges: []string{"update:aws_instance.bar"},
+				},
+			},
+			want: &PlanExpectation{
+				Allow: []string{"update:aws_iam_role.foo", "update:aws_instance.bar"},
+			},
+			ok: true,
+		},
+		{
+			desc: "forced actions",
+			configs: []ActionConfig{
+				{Command: "mv aws_instance.foo aws_instance.bar", Force: true},
+				{Command: "rm aws_iam_role.baz"},
+				{Command: "import aws_s3_bucket.qux qux", Force: true},
+			},
+			want: &PlanExpectation{
+				Allow:  []string{},
+				Forced: []string{"aws_instance.foo", "aws_instance.bar", "aws_s3_bucket.qux"},
+			},
+			ok: true,
+		},
+		{
+			desc:  "migration forced",
+			force: true,
+			configs: []ActionConfig{
+				{Command: "mv aws_instance.foo aws_instance.bar", Force: true},
+			},
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc:  "migration forced with allow",
+			allow: []string{"update:aws_iam_role.foo"},
+			force: true,
+			configs: []ActionConfig{
+				{Command: "mv aws_instance.foo aws_instance.bar", Force: true},
+			},
+			want: &PlanExpectation{
+				Allow:  []string{"update:aws_iam_role.foo"},
+				Forced: []string{"aws_instance.foo", "aws_instance.bar"},
+			},
+			ok: true,
+		},
+		{
+			desc:      "expect_no_changes",
+			noChanges: true,
+			configs: []ActionConfig{
+				{Command: "mv aws_instance.foo aws_instance.bar", Force: true},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "invalid allow_changes",
+			configs: []ActionConfig{
+				{
+					Command:      "mv aws_instance.foo aws_instance.bar",
+					AllowChanges: []string{"aws_instance.bar"},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "force an action without addresses",
+			configs: []ActionConfig{
+				{Command: "replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null", Force: true},
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := newPlanExpectationWithActions(tc.noChanges, tc.allow, tc.force, tc.configs)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if diff := cmp.Diff(got, tc.want); diff != "" {
+					t.Errorf("got: %#v, want: %#v, diff: %s", got, tc.want, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestIsForcedAddress(t *testing.T) {
+	cases := []struct {
+		desc    string
+		address string
+		forced  []string
+		want    bool
+	}{
+		{
+			desc:    "exact",
+			address: "aws_instance.foo",
+			forced:  []string{"aws_instance.foo"},
+			want:    true,
+		},
+		{
+			desc:    "instance",
+			address: `aws_instance.foo["a"]`,
+			forced:  []string{"aws_instance.foo"},
+			want:    true,
+		},
+		{
+			desc:    "under module",
+			address: "module.network.aws_vpc.main",
+			forced:  []string{"module.network"},
+			want:    true,
+		},
+		{
+			desc:    "different name with the same prefix",
+			address: "aws_instance.foo2",
+			forced:  []string{"aws_instance.foo"},
+			want:    false,
+		},
+		{
+			desc:    "wildcard",
+			address: "aws_instance.foo2",
+			forced:  []string{"aws_instance.*"},
+			want:    true,
+		},
+		{
+			desc:    "reference to matched values of xmv",
+			address: "aws_instance.bar",
+			forced:  []string{"aws_instance.foo_$1"},
+			want:    false,
+		},
+		{
+			desc:    "not forced",
+			address: "aws_instance.foo",
+			forced:  nil,
+			want:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := isForcedAddress(tc.address, tc.forced)
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
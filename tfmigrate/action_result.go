@@ -0,0 +1,91 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/clock"
+)
+
+const (
+	// ActionStatusSucceeded means that the action succeeded.
+	ActionStatusSucceeded = "succeeded"
+	// ActionStatusFailed means that the action failed.
+	ActionStatusFailed = "failed"
+	// ActionStatusSkipped means that the action was not executed because a
+	// preceding action failed.
+	ActionStatusSkipped = "skipped"
+)
+
+// ActionResult is a result of an action executed in a migration.
+type ActionResult struct {
+	// Action is a plain text of the action.
+	Action string
+	// Status is a result of the action.
+	// Valid values are succeeded, failed and skipped.
+	Status string
+	// StartedAt is a timestamp when the action started.
+	StartedAt time.Time
+	// FinishedAt is a timestamp when the action finished.
+	FinishedAt time.Time
+	// Error is an error message if the action failed.
+	Error string
+	// Resolved is a list of plain actions which a wildcard action such as xmv
+	// and xrm has been resolved to against the state, such as
+	// "mv aws_instance.foo aws_instance.bar". It's nil for other actions.
+	Resolved []string
+}
+
+// ActionResultReporter is an optional interface for a Migrator which reports
+// results of actions executed in the last Plan or Apply.
+// Note that actions are executed against temporary states in the plan phase,
+// so a succeeded action doesn't mean that the new state has been pushed.
+type ActionResultReporter interface {
+	// ActionResults returns a list of results of actions.
+	ActionResults() []ActionResult
+}
+
+// actionResolver is an optional interface for an action with wildcards, which
+// reports plain actions resolved against the state in the last update.
+type actionResolver interface {
+	// resolvedActions returns a list of resolved actions.
+	resolvedActions() []string
+}
+
+// runActions is a helper function which runs a given function for each action
+// in order and records results. The actions are described with fmt.Sprint, so
+// they are expected to implement fmt.Stringer. If an action fails, it stops
+// and marks the remaining actions as skipped.
+func runActions(ctx context.Context, actions []any, run func(i int) error) ([]ActionResult, error) {
+	results := make([]ActionResult, 0, len(actions))
+	for i, action := range actions {
+		r := ActionResult{
+			Action:    fmt.Sprint(action),
+			StartedAt: clock.Now(ctx),
+		}
+		err := run(i)
+		r.FinishedAt = clock.Now(ctx)
+		if resolver, ok := action.(actionResolver); ok {
+			r.Resolved = resolver.resolvedActions()
+		}
+		if err == nil {
+			r.Status = ActionStatusSucceeded
+			results = append(results, r)
+			continue
+		}
+
+		r.Status = ActionStatusFailed
+		r.Error = err.Error()
+		results = append(results, r)
+		for _, rest := range actions[i+1:] {
+			results = append(results, ActionResult{
+				Action: fmt.Sprint(rest),
+				Status: ActionStatusSkipped,
+			})
+		}
+		return results, err
+	}
+
+	return results, nil
+}
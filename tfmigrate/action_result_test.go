@@ -0,0 +1,189 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/clock"
+)
+
+func TestRunActions(t *testing.T) {
+	now := time.Date(2020, 11, 14, 0, 0, 0, 0, time.UTC)
+	ctx := clock.WithClock(context.Background(), clock.Fixed(now))
+
+	cases := []struct {
+		desc       string
+		actions    []any
+		failAt     int
+		wantStatus []string
+		ok         bool
+	}{
+		{
+			desc: "all succeeded",
+			actions: []any{
+				NewStateMvAction("null_resource.foo", "null_resource.foo2"),
+				NewStateRmAction([]string{"null_resource.bar", "null_resource.baz"}),
+			},
+			failAt:     -1,
+			wantStatus: []string{ActionStatusSucceeded, ActionStatusSucceeded},
+			ok:         true,
+		},
+		{
+			desc: "failed in the middle",
+			actions: []any{
+				NewStateMvAction("null_resource.foo", "null_resource.foo2"),
+				NewStateRmAction([]string{"null_resource.bar", "null_resource.baz"}),
+				NewStateImportAction("null_resource.qux", "qux"),
+			},
+			failAt:     1,
+			wantStatus: []string{ActionStatusSucceeded, ActionStatusFailed, ActionStatusSkipped},
+			ok:         false,
+		},
+		{
+			desc:       "no actions",
+			actions:    []any{},
+			failAt:     -1,
+			wantStatus: []string{},
+			ok:         true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := runActions(ctx, tc.actions, func(i int) error {
+				if i == tc.failAt {
+					return fmt.Errorf("failed at %d", i)
+				}
+				return nil
+			})
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if len(got) != len(tc.actions) {
+				t.Fatalf("got %d results, want %d: %#v", len(got), len(tc.actions), got)
+			}
+			for i, r := range got {
+				if r.Action != fmt.Sprint(tc.actions[i]) {
+					t.Errorf("got action = %s, want = %s", r.Action, tc.actions[i])
+				}
+				if r.Status != tc.wantStatus[i] {
+					t.Errorf("got status = %s, want = %s for %s", r.Status, tc.wantStatus[i], r.Action)
+				}
+				if r.Status == ActionStatusFailed && len(r.Error) == 0 {
+					t.Errorf("expected an error message for %s", r.Action)
+				}
+				if r.Status == ActionStatusSkipped && !r.StartedAt.IsZero() {
+					t.Errorf("expected a zero timestamp for a skipped action: %s", r.Action)
+				}
+				if r.Status != ActionStatusSkipped && (!r.StartedAt.Equal(now) || !r.FinishedAt.Equal(now)) {
+					t.Errorf("got timestamps = %s, %s, want = %s for %s", r.StartedAt, r.FinishedAt, now, r.Action)
+				}
+			}
+		})
+	}
+}
+
+func TestRunActionsResolved(t *testing.T) {
+	xmv := NewStateXmvAction("null_resource.*", "null_resource.$1_2")
+	xrm := NewStateXrmAction([]string{"null_resource.qux*"})
+	mv := NewStateMvAction("null_resource.bar", "null_resource.bar2")
+	actions := []any{xmv, xrm, mv}
+
+	got, err := runActions(context.Background(), actions, func(i int) error {
+		// simulate wildcards expanded against the state.
+		switch i {
+		case 0:
+			xmv.resolved = []*StateMvAction{
+				NewStateMvAction("null_resource.foo", "null_resource.foo_2"),
+				NewStateMvAction("null_resource.baz", "null_resource.baz_2"),
+			}
+		case 1:
+			xrm.removed = []string{}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	want := [][]string{
+		{"mv null_resource.foo null_resource.foo_2", "mv null_resource.baz null_resource.baz_2"},
+		{},
+		nil,
+	}
+	for i, r := range got {
+		if !reflect.DeepEqual(r.Resolved, want[i]) {
+			t.Errorf("got resolved = %#v, want = %#v for %s", r.Resolved, want[i], r.Action)
+		}
+	}
+}
+
+func TestActionString(t *testing.T) {
+	cases := []struct {
+		desc   string
+		action fmt.Stringer
+		want   string
+	}{
+		{
+			desc:   "mv",
+			action: NewStateMvAction("null_resource.foo", "null_resource.foo2"),
+			want:   "mv null_resource.foo null_resource.foo2",
+		},
+		{
+			desc:   "rm",
+			action: NewStateRmAction([]string{"null_resource.foo", "null_resource.bar"}),
+			want:   "rm null_resource.foo null_resource.bar",
+		},
+		{
+			desc:   "import",
+			action: NewStateImportAction("null_resource.foo", "foo"),
+			want:   "import null_resource.foo foo",
+		},
+		{
+			desc:   "replace-provider",
+			action: NewStateReplaceProviderAction("registry.terraform.io/-/null", "registry.terraform.io/hashicorp/null"),
+			want:   "replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+		},
+		{
+			desc:   "retype",
+			action: NewStateRetypeAction("aws_alb", "aws_lb"),
+			want:   "retype aws_alb aws_lb",
+		},
+		{
+			desc:   "xmv",
+			action: NewStateXmvAction("null_resource.*", "null_resource.new_$1"),
+			want:   "xmv null_resource.* null_resource.new_$1",
+		},
+		{
+			desc:   "plugin",
+			action: NewStatePluginAction(&ActionPluginConfig{Name: "rename", Command: "./rename"}, []string{"foo", "bar"}),
+			want:   "rename foo bar",
+		},
+		{
+			desc:   "multi state mv",
+			action: NewMultiStateMvAction("null_resource.foo", "null_resource.foo2"),
+			want:   "mv null_resource.foo null_resource.foo2",
+		},
+		{
+			desc:   "multi state xmv",
+			action: NewMultiStateXmvAction("null_resource.*", "null_resource.$1"),
+			want:   "xmv null_resource.* null_resource.$1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.action.String()
+			if got != tc.want {
+				t.Errorf("got = %s, want = %s", got, tc.want)
+			}
+		})
+	}
+}
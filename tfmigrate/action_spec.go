@@ -0,0 +1,254 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ActionSpec describes a syntax of migration action.
+// It is a single source of truth for both the action parser and the help
+// message, so that the help never drifts from the actual behavior.
+type ActionSpec struct {
+	// Type is an action type such as mv.
+	Type string
+	// Args is a list of placeholders for arguments such as <source>.
+	// If the last placeholder ends with "...", it accepts one or more arguments.
+	// If the last placeholder is enclosed in brackets such as [<destination>],
+	// it is optional.
+	Args []string
+	// Description is a short description of the action.
+	Description string
+	// Examples is a list of example actions.
+	// They are parsed in tests to ensure that they are valid.
+	Examples []string
+}
+
+// Usage returns a usage string of the action.
+// e.g.) "mv <source> <destination>"
+func (s ActionSpec) Usage() string {
+	return strings.Join(append([]string{s.Type}, s.Args...), " ")
+}
+
+// isVariadic returns true if the last argument accepts one or more arguments.
+func (s ActionSpec) isVariadic() bool {
+	return len(s.Args) > 0 && strings.HasSuffix(s.Args[len(s.Args)-1], "...")
+}
+
+// isOptional returns true if the last argument can be omitted.
+func (s ActionSpec) isOptional() bool {
+	return len(s.Args) > 0 && strings.HasPrefix(s.Args[len(s.Args)-1], "[")
+}
+
+// validateArgs checks if a given list of arguments matches the spec.
+// Note that args doesn't contain the action type.
+func (s ActionSpec) validateArgs(args []string) error {
+	if s.isOptional() {
+		if len(args) < len(s.Args)-1 || len(args) > len(s.Args) {
+			return fmt.Errorf("expected %d or %d arguments, but got %d", len(s.Args)-1, len(s.Args), len(args))
+		}
+		return nil
+	}
+	if s.isVariadic() {
+		if len(args) < len(s.Args) {
+			return fmt.Errorf("expected at least %d arguments, but got %d", len(s.Args), len(args))
+		}
+		return nil
+	}
+	if len(args) != len(s.Args) {
+		return fmt.Errorf("expected %d arguments, but got %d", len(s.Args), len(args))
+	}
+	return nil
+}
+
+// stateActionSpec is a pair of an ActionSpec and a factory method for StateAction.
+type stateActionSpec struct {
+	ActionSpec
+	// newAction builds a StateAction from validated arguments.
+	newAction func(args []string) (StateAction, error)
+}
+
+// multiStateActionSpec is a pair of an ActionSpec and a factory method for MultiStateAction.
+type multiStateActionSpec struct {
+	ActionSpec
+	// newAction builds a MultiStateAction from validated arguments.
+	newAction func(args []string) (MultiStateAction, error)
+}
+
+// stateActionSpecs is a list of available state actions.
+var stateActionSpecs = []stateActionSpec{
+	{
+		ActionSpec: ActionSpec{
+			Type:        "mv",
+			Args:        []string{"<source>", "<destination>"},
+			Description: "Move a resource or module from source address to destination address.",
+			Examples: []string{
+				"mv aws_security_group.foo aws_security_group.foo2",
+				`mv aws_security_group.foo[0] 'aws_security_group.foo["baz"]'`,
+			},
+		},
+		newAction: func(args []string) (StateAction, error) {
+			return NewStateMvAction(args[0], args[1]), nil
+		},
+	},
+	{
+		ActionSpec: ActionSpec{
+			Type:        "xmv",
+			Args:        []string{"<source>", "<destination>"},
+			Description: "Move resources matching a wildcard source pattern. The matched values can be referred in the destination via $1, $2, ...",
+			Examples: []string{
+				"xmv aws_security_group.* aws_security_group.${1}2",
+			},
+		},
+		newAction: func(args []string) (StateAction, error) {
+			return NewStateXmvAction(args[0], args[1]), nil
+		},
+	},
+	{
+		ActionSpec: ActionSpec{
+			Type:        "rm",
+			Args:        []string{"<addresses>..."},
+			Description: "Remove resources from state.",
+			Examples: []string{
+				"rm aws_security_group.baz",
+				"rm aws_security_group.foo aws_security_group.bar",
+			},
+		},
+		newAction: func(args []string) (StateAction, error) {
+			return NewStateRmAction(args), nil
+		},
+	},
+	{
+		ActionSpec: ActionSpec{
+			Type:        "xrm",
+			Args:        []string{"<patterns>..."},
+			Description: "Remove resources matching wildcard patterns from state. A pattern must match a whole address. The matched resources are shown in the plan output.",
+			Examples: []string{
+				"xrm module.legacy.*",
+				`xrm aws_security_group.* 'aws_instance.foo["*"]'`,
+			},
+		},
+		newAction: func(args []string) (StateAction, error) {
+			if err := checkXrmPatterns(args); err != nil {
+				return nil, err
+			}
+			return NewStateXrmAction(args), nil
+		},
+	},
+	{
+		ActionSpec: ActionSpec{
+			Type:        "import",
+			Args:        []string{"<address>", "<id>"},
+			Description: "Import an existing resource to state.",
+			Examples: []string{
+				"import aws_security_group.qux qux",
+			},
+		},
+		newAction: func(args []string) (StateAction, error) {
+			return NewStateImportAction(args[0], args[1]), nil
+		},
+	},
+	{
+		ActionSpec: ActionSpec{
+			Type:        "replace-provider",
+			Args:        []string{"<source>", "<destination>"},
+			Description: "Replace a provider address in state. It requires Terraform v0.13+.",
+			Examples: []string{
+				"replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+			},
+		},
+		newAction: func(args []string) (StateAction, error) {
+			return NewStateReplaceProviderAction(args[0], args[1]), nil
+		},
+	},
+	{
+		ActionSpec: ActionSpec{
+			Type:        "retype",
+			Args:        []string{"<source_type>", "[<destination_type>]"},
+			Description: "Rewrite a resource type in state, such as a type renamed by a provider upgrade. The destination type defaults to the canonical type of known renames and must exist in the provider schema.",
+			Examples: []string{
+				"retype aws_alb aws_lb",
+				"retype aws_alb_listener",
+			},
+		},
+		newAction: func(args []string) (StateAction, error) {
+			if len(args) == 2 {
+				return NewStateRetypeAction(args[0], args[1]), nil
+			}
+			destination, ok := TranslateType(args[0])
+			if !ok {
+				return nil, fmt.Errorf("unknown renamed type, destination type is required: %s", args[0])
+			}
+			return NewStateRetypeAction(args[0], destination), nil
+		},
+	},
+}
+
+// multiStateActionSpecs is a list of available multi state actions.
+var multiStateActionSpecs = []multiStateActionSpec{
+	{
+		ActionSpec: ActionSpec{
+			Type:        "mv",
+			Args:        []string{"<source>", "<destination>", "[<to_dir>]"},
+			Description: "Move a resource or module from from_dir to to_dir. It also can rename an address. The to_dir can be overridden per action.",
+			Examples: []string{
+				"mv aws_security_group.foo aws_security_group.foo2",
+				"mv aws_instance.app aws_instance.app dir3",
+			},
+		},
+		newAction: func(args []string) (MultiStateAction, error) {
+			return NewMultiStateMvAction(args[0], args[1]), nil
+		},
+	},
+	{
+		ActionSpec: ActionSpec{
+			Type:        "xmv",
+			Args:        []string{"<source>", "<destination>", "[<to_dir>]"},
+			Description: "Move resources matching a wildcard source pattern from from_dir to to_dir. The to_dir can be overridden per action.",
+			Examples: []string{
+				"xmv aws_security_group.* aws_security_group.${1}2",
+				"xmv * $1",
+			},
+		},
+		newAction: func(args []string) (MultiStateAction, error) {
+			return NewMultiStateXmvAction(args[0], args[1]), nil
+		},
+	},
+}
+
+// StateActionSpecs returns a list of specs for available state actions.
+func StateActionSpecs() []ActionSpec {
+	specs := []ActionSpec{}
+	for _, s := range stateActionSpecs {
+		specs = append(specs, s.ActionSpec)
+	}
+	return specs
+}
+
+// MultiStateActionSpecs returns a list of specs for available multi state actions.
+func MultiStateActionSpecs() []ActionSpec {
+	specs := []ActionSpec{}
+	for _, s := range multiStateActionSpecs {
+		specs = append(specs, s.ActionSpec)
+	}
+	return specs
+}
+
+// findStateActionSpec returns a spec for a given state action type.
+func findStateActionSpec(actionType string) (*stateActionSpec, bool) {
+	for i := range stateActionSpecs {
+		if stateActionSpecs[i].Type == actionType {
+			return &stateActionSpecs[i], true
+		}
+	}
+	return nil, false
+}
+
+// findMultiStateActionSpec returns a spec for a given multi state action type.
+func findMultiStateActionSpec(actionType string) (*multiStateActionSpec, bool) {
+	for i := range multiStateActionSpecs {
+		if multiStateActionSpecs[i].Type == actionType {
+			return &multiStateActionSpecs[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,127 @@
+package tfmigrate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStateActionSpecsExamples(t *testing.T) {
+	for _, spec := range StateActionSpecs() {
+		for _, example := range spec.Examples {
+			t.Run(example, func(t *testing.T) {
+				if !strings.HasPrefix(example, spec.Type+" ") {
+					t.Errorf("example doesn't start with the action type %s: %s", spec.Type, example)
+				}
+				if _, err := NewStateActionFromString(example); err != nil {
+					t.Errorf("failed to parse example: %s", err)
+				}
+			})
+		}
+	}
+}
+
+func TestMultiStateActionSpecsExamples(t *testing.T) {
+	for _, spec := range MultiStateActionSpecs() {
+		for _, example := range spec.Examples {
+			t.Run(example, func(t *testing.T) {
+				if !strings.HasPrefix(example, spec.Type+" ") {
+					t.Errorf("example doesn't start with the action type %s: %s", spec.Type, example)
+				}
+				if _, err := NewMultiStateActionFromString(example); err != nil {
+					t.Errorf("failed to parse example: %s", err)
+				}
+			})
+		}
+	}
+}
+
+func TestActionSpecValidateArgs(t *testing.T) {
+	cases := []struct {
+		desc string
+		spec ActionSpec
+		args []string
+		ok   bool
+	}{
+		{
+			desc: "fixed (valid)",
+			spec: ActionSpec{Type: "mv", Args: []string{"<source>", "<destination>"}},
+			args: []string{"foo", "bar"},
+			ok:   true,
+		},
+		{
+			desc: "fixed (too few)",
+			spec: ActionSpec{Type: "mv", Args: []string{"<source>", "<destination>"}},
+			args: []string{"foo"},
+			ok:   false,
+		},
+		{
+			desc: "fixed (too many)",
+			spec: ActionSpec{Type: "mv", Args: []string{"<source>", "<destination>"}},
+			args: []string{"foo", "bar", "baz"},
+			ok:   false,
+		},
+		{
+			desc: "variadic (one)",
+			spec: ActionSpec{Type: "rm", Args: []string{"<addresses>..."}},
+			args: []string{"foo"},
+			ok:   true,
+		},
+		{
+			desc: "variadic (many)",
+			spec: ActionSpec{Type: "rm", Args: []string{"<addresses>..."}},
+			args: []string{"foo", "bar", "baz"},
+			ok:   true,
+		},
+		{
+			desc: "variadic (none)",
+			spec: ActionSpec{Type: "rm", Args: []string{"<addresses>..."}},
+			args: []string{},
+			ok:   false,
+		},
+		{
+			desc: "optional (given)",
+			spec: ActionSpec{Type: "retype", Args: []string{"<source_type>", "[<destination_type>]"}},
+			args: []string{"foo", "bar"},
+			ok:   true,
+		},
+		{
+			desc: "optional (omitted)",
+			spec: ActionSpec{Type: "retype", Args: []string{"<source_type>", "[<destination_type>]"}},
+			args: []string{"foo"},
+			ok:   true,
+		},
+		{
+			desc: "optional (too few)",
+			spec: ActionSpec{Type: "retype", Args: []string{"<source_type>", "[<destination_type>]"}},
+			args: []string{},
+			ok:   false,
+		},
+		{
+			desc: "optional (too many)",
+			spec: ActionSpec{Type: "retype", Args: []string{"<source_type>", "[<destination_type>]"}},
+			args: []string{"foo", "bar", "baz"},
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.spec.validateArgs(tc.args)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestActionSpecUsage(t *testing.T) {
+	spec := ActionSpec{Type: "rm", Args: []string{"<addresses>..."}}
+	got := spec.Usage()
+	want := "rm <addresses>..."
+	if got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+}
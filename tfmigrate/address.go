@@ -0,0 +1,94 @@
+package tfmigrate
+
+import (
+	"strings"
+)
+
+// quoteAddressKeys returns a given action string with string keys of
+// unquoted addresses wrapped in single quotes, so that they survive splitting
+// an action like a shell.
+//
+// A for_each key is a string literal in HCL such as foo["a:b/c"], which
+// conflicts with the shell-like syntax of actions: double quotes are removed
+// and a white space splits an address unless the whole address is quoted
+// again. Since the key is always a double-quoted string in brackets, we can
+// find its end unambiguously and pass it through as it is, including unicode
+// characters, white spaces and escape sequences of HCL such as \".
+// An address which is already quoted is left as it is for backward
+// compatibility. An index which is not a string such as [0] or [*] doesn't
+// need quoting.
+//
+// Note that actions are never evaluated by an OS shell. Split arguments are
+// passed to terraform as they are, so the result doesn't depend on the OS
+// nor the terraform version.
+func quoteAddressKeys(cmdStr string) string {
+	var b strings.Builder
+	// quote is a quote character of the current shell-like quoted string.
+	// It is 0 if not quoted.
+	var quote byte
+	for i := 0; i < len(cmdStr); i++ {
+		c := cmdStr[i]
+		switch {
+		case quote == '\'':
+			// A backslash is not special in single quotes.
+			if c == '\'' {
+				quote = 0
+			}
+		case quote == '"':
+			if c == '\\' && i+1 < len(cmdStr) {
+				b.WriteByte(c)
+				i++
+				c = cmdStr[i]
+			} else if c == '"' {
+				quote = 0
+			}
+		case c == '\\' && i+1 < len(cmdStr):
+			b.WriteByte(c)
+			i++
+			c = cmdStr[i]
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			if end, ok := findStringKeyEnd(cmdStr, i); ok {
+				b.WriteString(singleQuote(cmdStr[i : end+1]))
+				i = end
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// findStringKeyEnd returns an index of the closing bracket of a string key
+// such as ["foo"] which starts at a given index of an opening bracket.
+// It returns false if it's not a string key or the key is not closed.
+func findStringKeyEnd(s string, start int) (int, bool) {
+	i := start + 1
+	if i >= len(s) || s[i] != '"' {
+		return 0, false
+	}
+
+	// Skip the HCL string literal, which may contain escaped double quotes.
+	for i++; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == '"' {
+			break
+		}
+	}
+
+	i++
+	if i >= len(s) || s[i] != ']' {
+		return 0, false
+	}
+	return i, true
+}
+
+// singleQuote wraps a given string in single quotes for shell-like splitting.
+// A single quote in the string is closed, escaped and reopened.
+func singleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,189 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestQuoteAddressKeys(t *testing.T) {
+	cases := []struct {
+		desc   string
+		cmdStr string
+		want   string
+	}{
+		{
+			desc:   "no key",
+			cmdStr: "mv null_resource.foo null_resource.foo2",
+			want:   "mv null_resource.foo null_resource.foo2",
+		},
+		{
+			desc:   "string key",
+			cmdStr: `mv null_resource.foo["a:b/c"] null_resource.foo2`,
+			want:   `mv null_resource.foo'["a:b/c"]' null_resource.foo2`,
+		},
+		{
+			desc:   "number and wildcard index",
+			cmdStr: "xmv null_resource.foo[*] null_resource.foo[0]",
+			want:   "xmv null_resource.foo[*] null_resource.foo[0]",
+		},
+		{
+			desc:   "single quoted address",
+			cmdStr: `mv 'null_resource.foo["a b"]' null_resource.foo2`,
+			want:   `mv 'null_resource.foo["a b"]' null_resource.foo2`,
+		},
+		{
+			desc:   "double quoted address",
+			cmdStr: `mv "null_resource.foo[\"a b\"]" null_resource.foo2`,
+			want:   `mv "null_resource.foo[\"a b\"]" null_resource.foo2`,
+		},
+		{
+			desc:   "single quote in key",
+			cmdStr: `mv null_resource.foo["it's"] null_resource.foo2`,
+			want:   `mv null_resource.foo'["it'\''s"]' null_resource.foo2`,
+		},
+		{
+			desc:   "escaped double quote in key",
+			cmdStr: `mv null_resource.foo["a\"]b"] null_resource.foo2`,
+			want:   `mv null_resource.foo'["a\"]b"]' null_resource.foo2`,
+		},
+		{
+			desc:   "unclosed key",
+			cmdStr: `mv null_resource.foo["a b null_resource.foo2`,
+			want:   `mv null_resource.foo["a b null_resource.foo2`,
+		},
+		{
+			desc:   "escaped bracket",
+			cmdStr: `mv null_resource.foo\["a"] null_resource.foo2`,
+			want:   `mv null_resource.foo\["a"] null_resource.foo2`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := quoteAddressKeys(tc.cmdStr)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSplitStateActionAddressKeys(t *testing.T) {
+	keys := []string{
+		"",
+		"foo",
+		"a:b/c",
+		"arn:aws:iam::123456789012:role/foo/bar",
+		"https://example.com/foo?bar=baz&qux=1#frag",
+		"with space",
+		"  leading and trailing  ",
+		"tab\tand\nnewline",
+		"日本語 キー",
+		"émoji 🚀",
+		"it's",
+		`quo"te`,
+		`back\slash`,
+		`\"`,
+		"[bracket]",
+		`["nested"]`,
+		"#;|&<>(){}!~*?",
+		"$HOME ${var} %{if}",
+		"`backtick`",
+		"-flag",
+	}
+
+	for _, key := range keys {
+		// HCL accepts the same escape sequences as Go for the above keys.
+		literal := strconv.Quote(key)
+		src := "module.foo[" + literal + "].null_resource.bar[" + literal + "]"
+		dst := "null_resource.baz[" + literal + "]"
+		want := []string{"mv", src, dst}
+
+		forms := map[string]string{
+			"unquoted":      "mv " + src + " " + dst,
+			"double quoted": `mv "` + shellEscapeDoubleQuoted(src) + `" "` + shellEscapeDoubleQuoted(dst) + `"`,
+		}
+		if !strings.Contains(key, "'") {
+			forms["single quoted"] = "mv '" + src + "' '" + dst + "'"
+		}
+
+		for form, cmdStr := range forms {
+			t.Run(literal+" "+form, func(t *testing.T) {
+				got, err := splitStateAction(cmdStr)
+				if err != nil {
+					t.Fatalf("unexpected err: %s", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("got: %#v, want: %#v", got, want)
+				}
+			})
+		}
+	}
+}
+
+// shellEscapeDoubleQuoted escapes a given string to be placed in double quotes.
+func shellEscapeDoubleQuoted(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return r.Replace(s)
+}
+
+func FuzzSplitStateActionAddressKeys(f *testing.F) {
+	for _, key := range []string{"", "foo", "a:b/c", "with space", "it's", `quo"te`, `back\slash`, `["nested"]`, "$HOME `x`", "日本語", "\x00\xff"} {
+		f.Add(key)
+	}
+
+	f.Fuzz(func(t *testing.T, key string) {
+		// An address in the output of terraform state list, which users copy
+		// into actions, has a string key as an escaped HCL string literal.
+		literal := strconv.Quote(key)
+		src := "module.foo[" + literal + "].null_resource.bar[" + literal + "]"
+		dst := "null_resource.baz[" + literal + "]"
+		want := []string{"mv", src, dst}
+
+		forms := map[string]string{
+			"unquoted":      "mv " + src + " " + dst,
+			"double quoted": `mv "` + shellEscapeDoubleQuoted(src) + `" "` + shellEscapeDoubleQuoted(dst) + `"`,
+			"joined":        joinStateAction(want...),
+		}
+		if !strings.Contains(literal, "'") {
+			forms["single quoted"] = "mv '" + src + "' '" + dst + "'"
+		}
+
+		for form, cmdStr := range forms {
+			got, err := splitStateAction(cmdStr)
+			if err != nil {
+				t.Fatalf("%s: unexpected err: %s", form, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got: %#v, want: %#v", form, got, want)
+			}
+		}
+	})
+}
+
+func FuzzQuoteAddressKeys(f *testing.F) {
+	for _, cmdStr := range []string{
+		"mv null_resource.foo null_resource.foo2",
+		`mv null_resource.foo["a:b/c"] null_resource.foo2`,
+		`mv 'null_resource.foo["a b"]' null_resource.foo2`,
+		`mv null_resource.foo["a\"]b"] null_resource.foo2`,
+		`mv null_resource.foo["a b null_resource.foo2`,
+		`mv null_resource.foo\["a"] null_resource.foo2`,
+	} {
+		f.Add(cmdStr)
+	}
+
+	f.Fuzz(func(t *testing.T, cmdStr string) {
+		quoted := quoteAddressKeys(cmdStr)
+		if !strings.Contains(cmdStr, "[") && quoted != cmdStr {
+			t.Errorf("an action without keys must be kept as it is, got: %q, want: %q", quoted, cmdStr)
+		}
+		if again := quoteAddressKeys(quoted); again != quoted {
+			t.Errorf("quoting must be idempotent, got: %q, want: %q", again, quoted)
+		}
+		// It must not panic on any input.
+		_, _ = splitStateAction(cmdStr)
+	})
+}
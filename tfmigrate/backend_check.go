@@ -0,0 +1,144 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// BackendTarget is a working directory which a migration works with.
+type BackendTarget struct {
+	// Dir is a working directory.
+	Dir string
+	// Workspace is a terraform workspace.
+	Workspace string
+	// Env is a map of environment variables for terraform commands.
+	Env map[string]string
+	// BackendConfig is a list of backend configurations of the working
+	// directory, which overrides the --backend-config option.
+	BackendConfig []string
+	// IsBackendTerraformCloud overrides is_backend_terraform_cloud of the
+	// tfmigrate config. A nil value means not overridden.
+	IsBackendTerraformCloud *bool
+}
+
+// String returns the target as "<dir>@<workspace>".
+func (t *BackendTarget) String() string {
+	return t.Dir + "@" + t.Workspace
+}
+
+// BackendTargets returns a list of working directories which the migration
+// works with. They are in the same order as Targets.
+func (mc *MigrationConfig) BackendTargets() []*BackendTarget {
+	switch m := mc.Migrator.(type) {
+	case *StateMigratorConfig:
+		dir := m.Dir
+		if len(dir) == 0 {
+			dir = "."
+		}
+		workspace := m.Workspace
+		if len(workspace) == 0 {
+			workspace = "default"
+		}
+		return []*BackendTarget{{Dir: dir, Workspace: workspace, Env: m.Env}}
+
+	case *MultiStateMigratorConfig:
+		fromWorkspace := m.FromWorkspace
+		if len(fromWorkspace) == 0 {
+			fromWorkspace = "default"
+		}
+		toWorkspace := m.ToWorkspace
+		if len(toWorkspace) == 0 {
+			toWorkspace = "default"
+		}
+		targets := []*BackendTarget{{
+			Dir:                     m.FromDir,
+			Workspace:               fromWorkspace,
+			Env:                     mergeEnv(m.Env, m.FromEnv),
+			BackendConfig:           m.FromBackendConfig,
+			IsBackendTerraformCloud: m.FromIsBackendTerraformCloud,
+		}}
+		for _, toDir := range m.ToDirs() {
+			targets = append(targets, &BackendTarget{
+				Dir:                     toDir,
+				Workspace:               toWorkspace,
+				Env:                     mergeEnv(m.Env, m.ToEnv),
+				BackendConfig:           m.ToBackendConfig,
+				IsBackendTerraformCloud: m.ToIsBackendTerraformCloud,
+			})
+		}
+		return targets
+
+	default:
+		return []*BackendTarget{}
+	}
+}
+
+// CheckBackend checks that the backend of a given target is reachable.
+// It initializes the working directory with the same backend settings as the
+// migrator and lists workspaces in the backend, which never modifies states.
+// Note that the workspace doesn't need to exist, because the migrator creates
+// it if needed.
+func CheckBackend(ctx context.Context, t *BackendTarget, o *MigratorOption) error {
+	if err := validateEnv(t.Env); err != nil {
+		return err
+	}
+	tf := newTerraformCLI(t.Dir, o)
+	appendEnv(tf, t.Env)
+
+	logging.FromContext(ctx).Printf("[INFO] [checker@%s] initialize work dir\n", t.Dir)
+	if err := tf.Init(ctx, backendInitOpts(t, o)...); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Printf("[INFO] [checker@%s] list workspaces in the backend\n", t.Dir)
+	_, err := tf.WorkspaceList(ctx)
+	return err
+}
+
+// backendInitOpts returns options of terraform init for a given target.
+// Backend configurations of the target take precedence over the option, and
+// the working directory is reinitialized with -reconfigure as well as the
+// migrator does, because it may have been initialized with a backend of a
+// different type.
+func backendInitOpts(t *BackendTarget, o *MigratorOption) []string {
+	override := &backendOverride{isTerraformCloud: t.IsBackendTerraformCloud, config: t.BackendConfig}
+	isBackendTerraformCloud, backendConfig, reconfigure := resolveBackendSettings(o, override)
+
+	opts := []string{"-input=false", "-no-color"}
+	for _, b := range backendConfig {
+		opts = append(opts, fmt.Sprintf("-backend-config=%s", b))
+	}
+	if reconfigure && !isBackendTerraformCloud {
+		opts = append(opts, "-reconfigure")
+	}
+	return opts
+}
+
+// CheckTerraformVersion returns a version of the terraform command such as
+// "terraform v1.5.7", and an error if it's older than the minimum required
+// version.
+func CheckTerraformVersion(ctx context.Context, o *MigratorOption) (string, error) {
+	tf := newTerraformCLI(".", o)
+	execType, v, err := tf.Version(ctx)
+	if err != nil {
+		return "", err
+	}
+	s := fmt.Sprintf("%s v%s", execType, v)
+
+	// All versions of OpenTofu are compatible with Terraform v1.6+.
+	if execType != "terraform" {
+		return s, nil
+	}
+	constraints, err := version.NewConstraint(fmt.Sprintf(">= %s", tfexec.MinimumTerraformVersion))
+	if err != nil {
+		return s, err
+	}
+	if !constraints.Check(v.Core()) {
+		return s, fmt.Errorf("%s is not supported, the minimum required version is v%s", s, tfexec.MinimumTerraformVersion)
+	}
+	return s, nil
+}
@@ -0,0 +1,102 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBackendTargets(t *testing.T) {
+	isTerraformCloud := true
+	cases := []struct {
+		desc string
+		mc   *MigrationConfig
+		want []*BackendTarget
+	}{
+		{
+			desc: "state",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir: "dir1",
+					Env: map[string]string{"FOO": "foo"},
+				},
+			},
+			want: []*BackendTarget{
+				{Dir: "dir1", Workspace: "default", Env: map[string]string{"FOO": "foo"}},
+			},
+		},
+		{
+			desc: "multi_state with backend settings",
+			mc: &MigrationConfig{
+				Type: "multi_state",
+				Name: "test",
+				Migrator: &MultiStateMigratorConfig{
+					FromDir:                   "dir1",
+					ToDir:                     "dir2",
+					FromWorkspace:             "work1",
+					FromBackendConfig:         []string{"bucket=from"},
+					ToBackendConfig:           []string{"bucket=to"},
+					ToIsBackendTerraformCloud: &isTerraformCloud,
+				},
+			},
+			want: []*BackendTarget{
+				{Dir: "dir1", Workspace: "work1", Env: map[string]string{}, BackendConfig: []string{"bucket=from"}},
+				{Dir: "dir2", Workspace: "default", Env: map[string]string{}, BackendConfig: []string{"bucket=to"}, IsBackendTerraformCloud: &isTerraformCloud},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.mc.BackendTargets()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBackendInitOpts(t *testing.T) {
+	isTerraformCloud := true
+	cases := []struct {
+		desc   string
+		target *BackendTarget
+		o      *MigratorOption
+		want   []string
+	}{
+		{
+			desc:   "no backend config",
+			target: &BackendTarget{Dir: "dir1"},
+			o:      nil,
+			want:   []string{"-input=false", "-no-color"},
+		},
+		{
+			desc:   "backend config of the option",
+			target: &BackendTarget{Dir: "dir1"},
+			o:      &MigratorOption{BackendConfig: []string{"bucket=foo"}},
+			want:   []string{"-input=false", "-no-color", "-backend-config=bucket=foo"},
+		},
+		{
+			desc:   "backend config of the target",
+			target: &BackendTarget{Dir: "dir1", BackendConfig: []string{"bucket=bar"}},
+			o:      &MigratorOption{BackendConfig: []string{"bucket=foo"}},
+			want:   []string{"-input=false", "-no-color", "-backend-config=bucket=bar", "-reconfigure"},
+		},
+		{
+			desc:   "terraform cloud",
+			target: &BackendTarget{Dir: "dir1", BackendConfig: []string{"organization=bar"}, IsBackendTerraformCloud: &isTerraformCloud},
+			o:      &MigratorOption{},
+			want:   []string{"-input=false", "-no-color", "-backend-config=organization=bar"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := backendInitOpts(tc.target, tc.o)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
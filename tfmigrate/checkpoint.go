@@ -0,0 +1,88 @@
+package tfmigrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckpointFilePrefix is a prefix of files which record progress of batched
+// multi_state migrations in the temp dir. A checkpoint is removed after all
+// batches are applied, so that a leftover means the last apply failed in the
+// middle. Unlike temporary files, it isn't removed by the cleanup command.
+const CheckpointFilePrefix = "_tfmigrate_checkpoint_"
+
+// Checkpoint is a progress of a batched multi_state migration.
+type Checkpoint struct {
+	// Digest is a digest of the migration, which is used to detect that the
+	// migration has been changed since the checkpoint was written.
+	Digest string `json:"digest"`
+	// Batches is a number of batches already applied.
+	Batches int `json:"batches"`
+	// PushedToStates is a list of indexes of to states already pushed in the
+	// next batch. The from state is pushed last in a batch, so that a batch
+	// is applied when it's pushed.
+	PushedToStates []int `json:"pushed_to_states,omitempty"`
+	// UpdatedAt is a timestamp when the checkpoint was written.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckpointPath returns a path to a checkpoint of a migration from a given
+// dir and workspace. The checkpoint is written to a given temp dir, or the
+// default directory for temporary files if empty, so that it's never
+// committed with the working directory. Set the temp dir to a persistent
+// directory to resume on an ephemeral CI runner.
+func CheckpointPath(tempDir string, dir string, workspace string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get an absolute path of %s: %s", dir, err)
+	}
+	if len(tempDir) == 0 {
+		tempDir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(abs + "@" + workspace))
+	return filepath.Join(tempDir, CheckpointFilePrefix+hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// writeCheckpoint writes a checkpoint to a given path.
+func writeCheckpoint(path string, c *Checkpoint) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %s", err)
+	}
+	return nil
+}
+
+// removeCheckpoint removes a checkpoint at a given path.
+// If it doesn't exist, no-op.
+func removeCheckpoint(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint: %s", err)
+	}
+	return nil
+}
+
+// ReadCheckpoint reads a checkpoint at a given path.
+// It returns nil if the checkpoint doesn't exist.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %s", err)
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %s", err)
+	}
+	return &c, nil
+}
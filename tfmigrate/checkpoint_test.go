@@ -0,0 +1,52 @@
+package tfmigrate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckpointPath(t *testing.T) {
+	tempDir := t.TempDir()
+	path, err := CheckpointPath(tempDir, "dir1", "default")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if filepath.Dir(path) != tempDir {
+		t.Errorf("expect checkpoint to be in the temp dir, but got: %s", path)
+	}
+	if !strings.HasPrefix(filepath.Base(path), CheckpointFilePrefix) {
+		t.Errorf("expect checkpoint to have the prefix %s, but got: %s", CheckpointFilePrefix, path)
+	}
+
+	abs, err := filepath.Abs("dir1")
+	if err != nil {
+		t.Fatalf("failed to get an absolute path: %s", err)
+	}
+	same, err := CheckpointPath(tempDir, abs, "default")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if same != path {
+		t.Errorf("expect the same checkpoint for the same dir, but got: %s and %s", path, same)
+	}
+
+	for _, other := range [][2]string{{"dir2", "default"}, {"dir1", "foo"}} {
+		got, err := CheckpointPath(tempDir, other[0], other[1])
+		if err != nil {
+			t.Fatalf("unexpected err: %s", err)
+		}
+		if got == path {
+			t.Errorf("expect a different checkpoint for %s@%s, but got: %s", other[0], other[1], got)
+		}
+	}
+
+	def, err := CheckpointPath("", "dir1", "default")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if filepath.Dir(def) != filepath.Clean(os.TempDir()) {
+		t.Errorf("expect checkpoint to be in the default temp dir, but got: %s", def)
+	}
+}
@@ -0,0 +1,172 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+)
+
+// CleanupResult is a result of cleaning up a working directory.
+type CleanupResult struct {
+	// Dir is a working directory.
+	Dir string
+	// Owner is a run which left the working directory.
+	// It is nil if no run marker is found.
+	Owner *RunMarker
+	// Skipped is a reason why the cleanup is skipped.
+	// It is empty if not skipped.
+	Skipped string
+	// Actions is a list of human-readable actions done, or to be done in
+	// dry-run mode.
+	Actions []string
+}
+
+// CleanupWorkDir restores a working directory left by a crashed run.
+// It removes the override file and the local workspace directory, switches
+// the backend back to remote, and selects the workspace which was selected
+// before the run. If the working directory may be owned by a live run, it is
+// skipped unless force is true. A working directory which has an override
+// file but no run marker is also skipped unless force is true, because we
+// can't confirm its owner.
+// In dry-run mode, it only reports actions to be done.
+func CleanupWorkDir(ctx context.Context, dir string, o *MigratorOption, force bool, dryRun bool) (*CleanupResult, error) {
+	r := &CleanupResult{Dir: dir}
+
+	owner, err := ReadRunMarker(dir)
+	if err != nil {
+		return nil, err
+	}
+	r.Owner = owner
+
+	overridePath := filepath.Join(dir, OverrideFileName)
+	_, err = os.Stat(overridePath)
+	hasOverride := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to stat override file: %s", err)
+	}
+
+	if owner == nil && !hasOverride {
+		// nothing to do.
+		return r, nil
+	}
+
+	if !force {
+		if owner == nil {
+			r.Skipped = "no run marker found, so the owner of the override file can't be confirmed"
+			return r, nil
+		}
+		if owner.Alive() {
+			r.Skipped = fmt.Sprintf("owned by a live run (%s)", owner)
+			return r, nil
+		}
+	}
+
+	if hasOverride {
+		r.Actions = append(r.Actions, "remove "+OverrideFileName)
+		if !dryRun {
+			if err := os.Remove(overridePath); err != nil && !os.IsNotExist(err) {
+				return r, fmt.Errorf("failed to remove override file: %s", err)
+			}
+		}
+	}
+
+	// Remove local workspace directories only if they are empty, because a
+	// state in it may not have been created by tfmigrate.
+	workspaces, err := emptyLocalWorkspaceDirs(dir)
+	if err != nil {
+		return r, err
+	}
+	for _, ws := range workspaces {
+		rel, _ := filepath.Rel(dir, ws)
+		r.Actions = append(r.Actions, "remove "+filepath.ToSlash(rel))
+		if !dryRun {
+			if err := os.Remove(ws); err != nil && !os.IsNotExist(err) {
+				return r, fmt.Errorf("failed to remove local workspace directory: %s", err)
+			}
+		}
+	}
+
+	tf := newTerraformCLI(dir, o)
+	args := []string{"-input=false", "-no-color"}
+	isBackendTerraformCloud := false
+	if o != nil {
+		for _, b := range o.BackendConfig {
+			args = append(args, fmt.Sprintf("-backend-config=%s", b))
+		}
+		isBackendTerraformCloud = o.IsBackendTerraformCloud
+	}
+	if !isBackendTerraformCloud {
+		args = append(args, "-reconfigure")
+	}
+	r.Actions = append(r.Actions, "switch back to remote backend")
+	if !dryRun {
+		logging.FromContext(ctx).Printf("[INFO] [cleanup@%s] switch back to remote\n", dir)
+		if err := tf.Init(ctx, args...); err != nil {
+			return r, fmt.Errorf("failed to switch back to remote: %s", err)
+		}
+	}
+
+	if owner != nil && len(owner.Workspace) != 0 {
+		r.Actions = append(r.Actions, "select workspace "+owner.Workspace)
+		if !dryRun {
+			currentWorkspace, err := tf.WorkspaceShow(ctx)
+			if err != nil {
+				return r, err
+			}
+			if currentWorkspace != owner.Workspace {
+				logging.FromContext(ctx).Printf("[INFO] [cleanup@%s] switch back to workspace %s\n", dir, owner.Workspace)
+				if err := tf.WorkspaceSelect(ctx, owner.Workspace); err != nil {
+					return r, err
+				}
+			}
+		}
+	}
+
+	if owner != nil {
+		r.Actions = append(r.Actions, "remove "+RunMarkerFileName)
+		if !dryRun {
+			if err := removeRunMarker(dir); err != nil {
+				return r, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// emptyLocalWorkspaceDirs returns a list of empty local workspace directories
+// created for overriding the backend to local, followed by the parent
+// directory if it becomes empty after removing them.
+func emptyLocalWorkspaceDirs(dir string) ([]string, error) {
+	parent := filepath.Join(dir, "terraform.tfstate.d")
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read local workspace directory: %s", err)
+	}
+
+	dirs := []string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		ws := filepath.Join(parent, e.Name())
+		children, err := os.ReadDir(ws)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local workspace directory: %s", err)
+		}
+		if len(children) == 0 {
+			dirs = append(dirs, ws)
+		}
+	}
+
+	if len(dirs) == len(entries) {
+		dirs = append(dirs, parent)
+	}
+	return dirs, nil
+}
@@ -0,0 +1,182 @@
+package tfmigrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunMarker(t *testing.T) {
+	dir := t.TempDir()
+
+	got, err := ReadRunMarker(dir)
+	if err != nil || got != nil {
+		t.Fatalf("expected no marker, but got: %#v, %v", got, err)
+	}
+
+	want := newRunMarker(context.Background(), "foo")
+	if err := writeRunMarker(dir, want); err != nil {
+		t.Fatalf("failed to write run marker: %s", err)
+	}
+	got, err = ReadRunMarker(dir)
+	if err != nil {
+		t.Fatalf("failed to read run marker: %s", err)
+	}
+	if !got.StartedAt.Equal(want.StartedAt) {
+		t.Errorf("got started_at = %s, want = %s", got.StartedAt, want.StartedAt)
+	}
+	got.StartedAt = want.StartedAt
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %#v, want = %#v", got, want)
+	}
+	if !got.Alive() {
+		t.Error("expected the current process to be alive")
+	}
+
+	if err := removeRunMarker(dir); err != nil {
+		t.Fatalf("failed to remove run marker: %s", err)
+	}
+	if err := removeRunMarker(dir); err != nil {
+		t.Fatalf("failed to remove run marker which doesn't exist: %s", err)
+	}
+}
+
+func TestRunMarkerAlive(t *testing.T) {
+	hostname, _ := os.Hostname()
+	cases := []struct {
+		desc   string
+		marker *RunMarker
+		want   bool
+	}{
+		{
+			desc:   "current process",
+			marker: &RunMarker{PID: os.Getpid(), Hostname: hostname},
+			want:   true,
+		},
+		{
+			desc:   "no process",
+			marker: &RunMarker{PID: 0, Hostname: hostname},
+			want:   false,
+		},
+		{
+			desc:   "another host",
+			marker: &RunMarker{PID: 0, Hostname: hostname + "-another"},
+			want:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.marker.Alive()
+			if got != tc.want {
+				t.Errorf("got = %t, want = %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCleanupWorkDir(t *testing.T) {
+	hostname, _ := os.Hostname()
+	crashed := &RunMarker{PID: 0, Hostname: hostname, StartedAt: time.Now(), Workspace: "default"}
+	live := &RunMarker{PID: os.Getpid(), Hostname: hostname, StartedAt: time.Now(), Workspace: "default"}
+
+	cases := []struct {
+		desc      string
+		marker    *RunMarker
+		override  bool
+		workspace bool
+		force     bool
+		skipped   bool
+		want      []string
+	}{
+		{
+			desc: "clean",
+			want: nil,
+		},
+		{
+			desc:      "crashed",
+			marker:    crashed,
+			override:  true,
+			workspace: true,
+			want: []string{
+				"remove _tfmigrate_override.tf",
+				"remove terraform.tfstate.d/foo",
+				"remove terraform.tfstate.d",
+				"switch back to remote backend",
+				"select workspace default",
+				"remove _tfmigrate_run.json",
+			},
+		},
+		{
+			desc:     "crashed after removing override file",
+			marker:   crashed,
+			override: false,
+			want: []string{
+				"switch back to remote backend",
+				"select workspace default",
+				"remove _tfmigrate_run.json",
+			},
+		},
+		{
+			desc:     "live",
+			marker:   live,
+			override: true,
+			skipped:  true,
+		},
+		{
+			desc:     "no marker",
+			override: true,
+			skipped:  true,
+		},
+		{
+			desc:     "no marker with force",
+			override: true,
+			force:    true,
+			want: []string{
+				"remove _tfmigrate_override.tf",
+				"switch back to remote backend",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			if tc.marker != nil {
+				if err := writeRunMarker(dir, tc.marker); err != nil {
+					t.Fatalf("failed to write run marker: %s", err)
+				}
+			}
+			if tc.override {
+				if err := os.WriteFile(filepath.Join(dir, OverrideFileName), []byte{}, 0600); err != nil {
+					t.Fatalf("failed to write override file: %s", err)
+				}
+			}
+			if tc.workspace {
+				if err := os.MkdirAll(filepath.Join(dir, "terraform.tfstate.d", "foo"), 0755); err != nil {
+					t.Fatalf("failed to create workspace dir: %s", err)
+				}
+			}
+
+			// dry-run mode doesn't invoke terraform.
+			got, err := CleanupWorkDir(context.Background(), dir, nil, tc.force, true)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if (len(got.Skipped) != 0) != tc.skipped {
+				t.Errorf("got skipped = %q, want = %t", got.Skipped, tc.skipped)
+			}
+			if !reflect.DeepEqual(got.Actions, tc.want) {
+				t.Errorf("got = %#v, want = %#v", got.Actions, tc.want)
+			}
+			if tc.override {
+				if _, err := os.Stat(filepath.Join(dir, OverrideFileName)); err != nil {
+					t.Errorf("expected not to remove override file in dry-run mode: %s", err)
+				}
+			}
+		})
+	}
+}
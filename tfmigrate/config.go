@@ -1,5 +1,12 @@
 package tfmigrate
 
+import (
+	"sync"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
 // MigrationConfig is a config for a migration.
 type MigrationConfig struct {
 	// Type is a type for migration.
@@ -7,6 +14,27 @@ type MigrationConfig struct {
 	Type string
 	// Name is an arbitrary name for migration.
 	Name string
+	// DependsOn is a list of migration file names which must be applied
+	// before this migration. In history mode, unapplied migrations are
+	// applied in an order which satisfies their dependencies.
+	DependsOn []string
+	// Skip is true if the migration is skipped by its condition.
+	// A skipped migration is never run, but it is recorded in history as
+	// skipped, so that a shared set of migrations can include ones only for
+	// specific environments.
+	Skip bool
+	// Owner is a team which owns the migration.
+	// It is used for checking ownership of resources touched by the migration.
+	Owner string
+	// ApprovedBy is a list of teams which approved the migration touching
+	// their resources. Approvals are verified against the history.
+	ApprovedBy []string
+	// Unprotect is a list of protected address patterns defined in the policy
+	// which the migration is explicitly allowed to rm or mv.
+	Unprotect []string
+	// Hooks is a config for commands which run around applying the
+	// migration. If nil, no hooks run.
+	Hooks *HooksConfig
 	// Migrator is an interface of factory method for Migrator.
 	Migrator MigratorConfig
 }
@@ -26,12 +54,118 @@ type MigratorOption struct {
 	// To use OpenTofu, set this to `tofu`.
 	ExecPath string
 
+	// ExecMode is a mode of executing the terraform command.
+	// If it's `terragrunt`, the terraform command is invoked via terragrunt.
+	ExecMode string
+
 	// PlanOut is a path to plan file to be saved.
 	PlanOut string
 
+	// VarFiles is a list of default variable files passed to terraform plan
+	// as -var-file options. Variable files of a migration are passed after
+	// them.
+	VarFiles []string
+
+	// Vars is a map of default variables passed to terraform plan as -var
+	// options. Variables of a migration take precedence.
+	Vars map[string]string
+
 	// IsBackendTerraformCloud is a boolean indicating if the remote backend is Terraform Cloud
 	IsBackendTerraformCloud bool
 
 	// BackendConfig is a -backend-config option for remote state
 	BackendConfig []string
+
+	// StatePushMethod is a method of pushing a new state to remote, which is
+	// one of StatePushMethodCLI, StatePushMethodAPI and StatePushMethodAuto.
+	// If empty, StatePushMethodCLI is used.
+	StatePushMethod string
+
+	// ActionPlugins is a list of exec-based action plugins which can be used
+	// as custom actions in state migrations.
+	ActionPlugins []*ActionPluginConfig
+
+	// Retry is a policy to retry a terraform command which fails with a
+	// transient error. If nil, a command is never retried.
+	Retry *tfexec.RetryPolicy
+
+	// ForceUnlockStale is a threshold of age of a stale state lock. If a
+	// terraform command fails because a lock older than it is held, the lock
+	// is removed with terraform force-unlock and the command is retried once.
+	// If zero, a lock is never removed.
+	ForceUnlockStale time.Duration
+
+	// LockOptions is a set of options of state locking such as -lock-timeout
+	// passed to every terraform command locking a state. If nil, no options
+	// are passed.
+	LockOptions *tfexec.LockOptions
+
+	// SandboxDir is a path to directory where new states are written instead
+	// of pushing them to remote. If set, Apply never touches remote states.
+	SandboxDir string
+
+	// ProvidersMirrorDir is a path to directory of a local filesystem mirror
+	// for provider plugins. If set, the mirror is populated before switching
+	// the backend to local, and terraform init uses it instead of the registry.
+	ProvidersMirrorDir string
+
+	// ProvidersLockPlatforms is a list of platforms such as linux_amd64 for
+	// which checksums of providers are recorded in the dependency lock file
+	// with terraform providers lock after switching the backend to local.
+	// If a providers mirror is set, checksums are computed from the mirror,
+	// which is also populated for these platforms. The original lock file is
+	// restored when switching back to remote.
+	ProvidersLockPlatforms []string
+
+	// TempDir is a path to directory where temporary files such as states and
+	// plans are written. If empty, the default directory for temporary files
+	// is used.
+	TempDir string
+
+	// KeepTemp is a flag to keep temporary files for debugging.
+	KeepTemp bool
+
+	// Offline is a flag to guarantee that terraform makes no network calls
+	// other than the configured backend. It disables the checkpoint service,
+	// and providers are installed only from a pre-populated local filesystem
+	// mirror in ProvidersMirrorDir instead of the registry.
+	Offline bool
+
+	// CheckLineage is a flag to check that the lineage of the remote state
+	// matches the new state right before pushing it. It detects that the
+	// remote state has been replaced by another one during the migration.
+	CheckLineage bool
+
+	// ReadOnly is a flag to refuse any terraform command which may mutate
+	// remote states or real resources, such as apply and state push. It
+	// verifies that a plan can run with read-only credentials.
+	ReadOnly bool
+
+	// CheckSources is a flag to verify that source resources of mv and rm
+	// actions exist in state before running them, and to show their key
+	// attributes such as id with terraform state show.
+	CheckSources bool
+
+	// ShowDiff is a flag to list addresses in states before and after a
+	// migration, so that a caller can show which addresses move where.
+	// See also StateDiffReporter.
+	ShowDiff bool
+
+	// PlanSummary is a flag to summarize changes in terraform plan with new
+	// states, which requires an extra terraform show for each plan.
+	// See also PlanSummaryReporter.
+	PlanSummary bool
+
+	// StateVersions is a map of working directories to versions of remote
+	// states, such as an S3 object version ID and a Terraform Cloud state
+	// version ID. If set, the given versions are used as inputs of plan
+	// instead of the current states. An empty key matches any directory.
+	// A new state computed from a historical state is never pushed.
+	StateVersions map[string]string
+
+	// InitLock is a lock held while running terraform init. It is shared by
+	// migrations running in parallel, because a plugin cache dir set by
+	// TF_PLUGIN_CACHE_DIR is not safe for concurrent inits. If nil, init is
+	// never serialized.
+	InitLock sync.Locker
 }
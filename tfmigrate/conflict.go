@@ -0,0 +1,103 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// TouchedAddress is a resource address touched by a migration in a state.
+type TouchedAddress struct {
+	// Dir is a working directory of the state.
+	Dir string
+	// Workspace is a workspace of the state.
+	Workspace string
+	// Address is a resource address, which may contain wildcards of xmv.
+	Address string
+}
+
+// Target returns the state of the address as "<dir>@<workspace>".
+func (a *TouchedAddress) Target() string {
+	return a.Dir + "@" + a.Workspace
+}
+
+// Conflicts returns true if the address may refer to the same resources or
+// modules as a given address in the same state.
+// Resource types renamed by providers are compared by their canonical types,
+// so that aws_alb.foo conflicts with aws_lb.foo.
+func (a *TouchedAddress) Conflicts(other *TouchedAddress) bool {
+	if filepath.Clean(a.Dir) != filepath.Clean(other.Dir) || a.Workspace != other.Workspace {
+		return false
+	}
+	x := NormalizeAddress(a.Address)
+	y := NormalizeAddress(other.Address)
+	return touchesAddressPrefix(x, y) || touchesAddressPrefix(y, x)
+}
+
+// TouchedAddresses returns a list of resource addresses touched by the
+// migration, which are arguments of the mv, xmv, rm and import actions.
+// For a migration with an end state, all addresses declared in it are
+// included.
+// Sources of a multi_state migration are in from_dir, and destinations are
+// in to_dir. Addresses passed to action plugins are not included, because we
+// don't know their meanings.
+func (mc *MigrationConfig) TouchedAddresses() ([]*TouchedAddress, error) {
+	touched := []*TouchedAddress{}
+	switch m := mc.Migrator.(type) {
+	case *StateMigratorConfig:
+		dir := m.Dir
+		if len(dir) == 0 {
+			dir = "."
+		}
+		workspace := m.Workspace
+		if len(workspace) == 0 {
+			workspace = "default"
+		}
+		for _, action := range m.declaredActions() {
+			addresses, err := actionAddresses(action)
+			if err != nil {
+				return nil, err
+			}
+			for _, address := range addresses {
+				touched = append(touched, &TouchedAddress{Dir: dir, Workspace: workspace, Address: address})
+			}
+		}
+
+	case *MultiStateMigratorConfig:
+		fromWorkspace := m.FromWorkspace
+		if len(fromWorkspace) == 0 {
+			fromWorkspace = "default"
+		}
+		toWorkspace := m.ToWorkspace
+		if len(toWorkspace) == 0 {
+			toWorkspace = "default"
+		}
+		for _, action := range m.declaredActions() {
+			args, err := splitStateAction(action)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse action: %s, err: %s", action, err)
+			}
+			if len(args) != 3 && len(args) != 4 {
+				return nil, fmt.Errorf("multi state action is invalid: %s", action)
+			}
+			touched = append(touched,
+				&TouchedAddress{Dir: m.FromDir, Workspace: fromWorkspace, Address: args[1]},
+				&TouchedAddress{Dir: m.actionToDir(action), Workspace: toWorkspace, Address: args[2]},
+			)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported migration type: %s", mc.Type)
+	}
+
+	return touched, nil
+}
+
+// Targets returns a list of states which the migration works with as
+// "<dir>@<workspace>".
+func (mc *MigrationConfig) Targets() []string {
+	targets := []string{}
+	for _, t := range mc.BackendTargets() {
+		targets = append(targets, t.String())
+	}
+	return targets
+}
@@ -0,0 +1,158 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMigrationConfigTouchedAddresses(t *testing.T) {
+	cases := []struct {
+		desc string
+		mc   *MigrationConfig
+		want []*TouchedAddress
+		ok   bool
+	}{
+		{
+			desc: "state",
+			mc: &MigrationConfig{
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						"rm null_resource.bar null_resource.baz",
+						"import null_resource.qux qux",
+						"replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+					},
+				},
+			},
+			want: []*TouchedAddress{
+				{Dir: ".", Workspace: "default", Address: "null_resource.foo"},
+				{Dir: ".", Workspace: "default", Address: "null_resource.foo2"},
+				{Dir: ".", Workspace: "default", Address: "null_resource.bar"},
+				{Dir: ".", Workspace: "default", Address: "null_resource.baz"},
+				{Dir: ".", Workspace: "default", Address: "null_resource.qux"},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi_state",
+			mc: &MigrationConfig{
+				Migrator: &MultiStateMigratorConfig{
+					FromDir:     "dir1",
+					ToDir:       "dir2",
+					ToWorkspace: "work2",
+					Actions:     []string{"xmv null_resource.* module.foo.null_resource.$1"},
+				},
+			},
+			want: []*TouchedAddress{
+				{Dir: "dir1", Workspace: "default", Address: "null_resource.*"},
+				{Dir: "dir2", Workspace: "work2", Address: "module.foo.null_resource.$1"},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi_state with to_dir override",
+			mc: &MigrationConfig{
+				Migrator: &MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo",
+						"mv null_resource.bar null_resource.bar dir3",
+					},
+				},
+			},
+			want: []*TouchedAddress{
+				{Dir: "dir1", Workspace: "default", Address: "null_resource.foo"},
+				{Dir: "dir2", Workspace: "default", Address: "null_resource.foo"},
+				{Dir: "dir1", Workspace: "default", Address: "null_resource.bar"},
+				{Dir: "dir3", Workspace: "default", Address: "null_resource.bar"},
+			},
+			ok: true,
+		},
+		{
+			desc: "unknown type",
+			mc: &MigrationConfig{
+				Type:     "mock",
+				Migrator: &MockMigratorConfig{},
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.mc.TouchedAddresses()
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTouchedAddressConflicts(t *testing.T) {
+	cases := []struct {
+		desc  string
+		a     *TouchedAddress
+		other *TouchedAddress
+		want  bool
+	}{
+		{
+			desc:  "same address",
+			a:     &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "null_resource.foo"},
+			other: &TouchedAddress{Dir: "./dir1", Workspace: "default", Address: "null_resource.foo"},
+			want:  true,
+		},
+		{
+			desc:  "module",
+			a:     &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "module.foo"},
+			other: &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "module.foo.null_resource.foo"},
+			want:  true,
+		},
+		{
+			desc:  "wildcard",
+			a:     &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "null_resource.foo"},
+			other: &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "null_resource.*"},
+			want:  true,
+		},
+		{
+			desc:  "renamed type",
+			a:     &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "module.foo.aws_alb.foo"},
+			other: &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "module.foo.aws_lb.foo"},
+			want:  true,
+		},
+		{
+			desc:  "different address",
+			a:     &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "null_resource.foo"},
+			other: &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "null_resource.foo2"},
+			want:  false,
+		},
+		{
+			desc:  "different workspace",
+			a:     &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "null_resource.foo"},
+			other: &TouchedAddress{Dir: "dir1", Workspace: "work1", Address: "null_resource.foo"},
+			want:  false,
+		},
+		{
+			desc:  "different dir",
+			a:     &TouchedAddress{Dir: "dir1", Workspace: "default", Address: "null_resource.foo"},
+			other: &TouchedAddress{Dir: "dir2", Workspace: "default", Address: "null_resource.foo"},
+			want:  false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.a.Conflicts(tc.other)
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package tfmigrate
+
+import (
+	"context"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// relinkDependencies updates dependencies of resources in a given state after
+// moving resources, so that they refer to new addresses, or are pruned if
+// moved away. See tfexec.RelinkDependencies for details.
+// It logs a warning and returns the state after as is if the states cannot be
+// parsed, such as a legacy state, because dependencies are only used for
+// ordering and a stale one is harmless.
+func relinkDependencies(ctx context.Context, dir string, before *tfexec.State, after *tfexec.State, moves map[string]string) *tfexec.State {
+	newState, changed, err := tfexec.RelinkDependencies(before, after, moves)
+	if err != nil {
+		logging.FromContext(ctx).Printf("[WARN] [migrator@%s] skip relinking dependencies: %s\n", dir, err)
+		return after
+	}
+	if len(changed) > 0 {
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] relinked dependencies of %s\n", dir, strings.Join(changed, ", "))
+	}
+	return newState
+}
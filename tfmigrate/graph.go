@@ -0,0 +1,309 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GraphNode is a resource address in a state.
+type GraphNode struct {
+	// Dir is a working directory of the state.
+	Dir string
+	// Address is a resource address.
+	Address string
+}
+
+// GraphEdge is an effect of a migration on a resource.
+// From is nil for an imported resource, and To is nil for a removed resource.
+type GraphEdge struct {
+	// From is a resource before the migration.
+	From *GraphNode
+	// To is a resource after the migration.
+	To *GraphNode
+}
+
+// MigrationGraph is a before/after graph of resources affected by a
+// migration. Resources which the migration doesn't touch are not included.
+type MigrationGraph struct {
+	// Name is a name of the migration.
+	Name string
+	// Edges is a list of effects on resources.
+	Edges []*GraphEdge
+}
+
+// StateListFunc returns a list of resource addresses in the current state
+// of a given working directory and workspace.
+type StateListFunc func(dir string, workspace string, env map[string]string) ([]string, error)
+
+// graphEntry is a resource in a simulated state.
+type graphEntry struct {
+	// address is a current address of the resource.
+	address string
+	// origin is the resource before the migration.
+	// It is nil if the resource has been imported.
+	origin *GraphNode
+}
+
+// graphState is a simulated state which tracks where each resource came from.
+type graphState struct {
+	// dir is a working directory of the state.
+	dir string
+	// entries is a list of resources in the state.
+	entries []*graphEntry
+}
+
+// newGraphState returns a new graphState with a given list of addresses.
+func newGraphState(dir string, addresses []string) *graphState {
+	s := &graphState{dir: dir}
+	for _, address := range addresses {
+		s.entries = append(s.entries, &graphEntry{
+			address: address,
+			origin:  &GraphNode{Dir: dir, Address: address},
+		})
+	}
+	return s
+}
+
+// addresses returns a list of current addresses in the state.
+func (s *graphState) addresses() []string {
+	addresses := make([]string, 0, len(s.entries))
+	for _, e := range s.entries {
+		addresses = append(addresses, e.address)
+	}
+	return addresses
+}
+
+// take removes resources at a given address or under it from the state and
+// returns them.
+func (s *graphState) take(address string) []*graphEntry {
+	taken := []*graphEntry{}
+	remaining := []*graphEntry{}
+	for _, e := range s.entries {
+		if hasAddressPrefix(e.address, address) {
+			taken = append(taken, e)
+		} else {
+			remaining = append(remaining, e)
+		}
+	}
+	s.entries = remaining
+	return taken
+}
+
+// mv moves resources at a given source address or under it to a destination
+// address in another state, which may be the same state.
+func (s *graphState) mv(to *graphState, source string, destination string) error {
+	taken := s.take(source)
+	if len(taken) == 0 {
+		return fmt.Errorf("no resources found in %s: %s", s.dir, source)
+	}
+	for _, e := range taken {
+		e.address = destination + e.address[len(source):]
+		to.entries = append(to.entries, e)
+	}
+	return nil
+}
+
+// expandXmv returns a list of mv actions for a given xmv action against the
+// state.
+func (s *graphState) expandXmv(source string, destination string) ([]*StateMvAction, error) {
+	e := newXmvExpander(NewStateXmvAction(source, destination))
+	return e.expand(s.addresses())
+}
+
+// NewMigrationGraph simulates actions of a given migration on the current
+// states listed by a given function and returns a before/after graph.
+// The states are never modified. Actions which don't change addresses such
+// as replace-provider, and action plugins are ignored.
+func NewMigrationGraph(mc *MigrationConfig, listState StateListFunc) (*MigrationGraph, error) {
+	var states []*graphState
+	var removed []*GraphNode
+	switch m := mc.Migrator.(type) {
+	case *StateMigratorConfig:
+		dir := m.Dir
+		if len(dir) == 0 {
+			dir = "."
+		}
+		workspace := m.Workspace
+		if len(workspace) == 0 {
+			workspace = "default"
+		}
+		addresses, err := listState(dir, workspace, m.Env)
+		if err != nil {
+			return nil, err
+		}
+		s := newGraphState(dir, addresses)
+		states = []*graphState{s}
+
+		for _, cmdStr := range m.Actions {
+			args, err := splitStateAction(cmdStr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+			}
+			if len(args) < 2 {
+				continue
+			}
+			switch args[0] {
+			case "mv":
+				if len(args) != 3 {
+					return nil, fmt.Errorf("state mv action is invalid: %s", cmdStr)
+				}
+				if err := s.mv(s, args[1], args[2]); err != nil {
+					return nil, err
+				}
+			case "xmv":
+				if len(args) != 3 {
+					return nil, fmt.Errorf("state xmv action is invalid: %s", cmdStr)
+				}
+				mvs, err := s.expandXmv(args[1], args[2])
+				if err != nil {
+					return nil, err
+				}
+				for _, mv := range mvs {
+					if err := s.mv(s, mv.source, mv.destination); err != nil {
+						return nil, err
+					}
+				}
+			case "rm":
+				for _, address := range args[1:] {
+					for _, e := range s.take(address) {
+						if e.origin != nil {
+							removed = append(removed, e.origin)
+						}
+					}
+				}
+			case "xrm":
+				addresses, err := expandXrm(args[1:], s.addresses())
+				if err != nil {
+					return nil, err
+				}
+				for _, address := range addresses {
+					for _, e := range s.take(address) {
+						if e.origin != nil {
+							removed = append(removed, e.origin)
+						}
+					}
+				}
+			case "import":
+				s.entries = append(s.entries, &graphEntry{address: args[1]})
+			}
+		}
+
+	case *MultiStateMigratorConfig:
+		fromWorkspace := m.FromWorkspace
+		if len(fromWorkspace) == 0 {
+			fromWorkspace = "default"
+		}
+		toWorkspace := m.ToWorkspace
+		if len(toWorkspace) == 0 {
+			toWorkspace = "default"
+		}
+		fromAddresses, err := listState(m.FromDir, fromWorkspace, mergeEnv(m.Env, m.FromEnv))
+		if err != nil {
+			return nil, err
+		}
+		from := newGraphState(m.FromDir, fromAddresses)
+		states = []*graphState{from}
+		toStates := make(map[string]*graphState)
+		for _, toDir := range m.ToDirs() {
+			toAddresses, err := listState(toDir, toWorkspace, mergeEnv(m.Env, m.ToEnv))
+			if err != nil {
+				return nil, err
+			}
+			to := newGraphState(toDir, toAddresses)
+			states = append(states, to)
+			toStates[filepath.Clean(toDir)] = to
+		}
+
+		for _, cmdStr := range m.Actions {
+			args, err := splitStateAction(cmdStr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+			}
+			if len(args) != 3 && len(args) != 4 {
+				return nil, fmt.Errorf("multi state action is invalid: %s", cmdStr)
+			}
+			to := toStates[filepath.Clean(m.actionToDir(cmdStr))]
+			switch args[0] {
+			case "mv":
+				if err := from.mv(to, args[1], args[2]); err != nil {
+					return nil, err
+				}
+			case "xmv":
+				mvs, err := from.expandXmv(args[1], args[2])
+				if err != nil {
+					return nil, err
+				}
+				for _, mv := range mvs {
+					if err := from.mv(to, mv.source, mv.destination); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported migration type for graph: %s", mc.Type)
+	}
+
+	g := &MigrationGraph{
+		Name:  mc.Name,
+		Edges: []*GraphEdge{},
+	}
+	for _, s := range states {
+		for _, e := range s.entries {
+			to := &GraphNode{Dir: s.dir, Address: e.address}
+			if e.origin != nil && *e.origin == *to {
+				continue
+			}
+			g.Edges = append(g.Edges, &GraphEdge{From: e.origin, To: to})
+		}
+	}
+	for _, origin := range removed {
+		g.Edges = append(g.Edges, &GraphEdge{From: origin})
+	}
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		return g.Edges[i].key() < g.Edges[j].key()
+	})
+
+	return g, nil
+}
+
+// key returns a string for sorting edges.
+func (e *GraphEdge) key() string {
+	node := e.From
+	if node == nil {
+		node = e.To
+	}
+	return node.Dir + "\x00" + node.Address
+}
+
+// ModulePath returns a module path of a given resource address and a
+// relative address in the module.
+// e.g.) module.foo["a"].aws_instance.bar => module.foo["a"], aws_instance.bar
+func ModulePath(address string) (string, string) {
+	i := 0
+	for strings.HasPrefix(address[i:], "module.") {
+		j := i + len("module.")
+		for j < len(address) && address[j] != '.' && address[j] != '[' {
+			j++
+		}
+		if j < len(address) && address[j] == '[' {
+			if end, ok := findStringKeyEnd(address, j); ok {
+				j = end + 1
+			} else if end := strings.IndexByte(address[j:], ']'); end >= 0 {
+				j += end + 1
+			}
+		}
+		if j >= len(address) || address[j] != '.' {
+			// The address is a module itself.
+			return address, ""
+		}
+		i = j + 1
+	}
+	if i == 0 {
+		return "", address
+	}
+	return address[:i-1], address[i:]
+}
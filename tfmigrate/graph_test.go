@@ -0,0 +1,196 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNewMigrationGraph(t *testing.T) {
+	states := map[string][]string{
+		"dir1@default": {
+			"null_resource.foo",
+			"null_resource.bar[0]",
+			"null_resource.bar[1]",
+			"null_resource.baz",
+			`module.qux["a"].null_resource.quux`,
+		},
+		"dir2@default": {
+			"null_resource.corge",
+		},
+	}
+	listState := func(dir string, workspace string, _ map[string]string) ([]string, error) {
+		addresses, ok := states[dir+"@"+workspace]
+		if !ok {
+			return nil, fmt.Errorf("state not found: %s@%s", dir, workspace)
+		}
+		return addresses, nil
+	}
+
+	cases := []struct {
+		desc string
+		mc   *MigrationConfig
+		want []*GraphEdge
+		ok   bool
+	}{
+		{
+			desc: "state",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir: "dir1",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						"mv null_resource.foo2 null_resource.foo3",
+						"mv null_resource.bar module.bar.null_resource.bar",
+						"xmv module.qux[*] module.new[$1]",
+						"rm null_resource.baz",
+						"import null_resource.grault grault",
+						"replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+					},
+				},
+			},
+			want: []*GraphEdge{
+				{From: &GraphNode{Dir: "dir1", Address: `module.qux["a"].null_resource.quux`}, To: &GraphNode{Dir: "dir1", Address: `module.new["a"].null_resource.quux`}},
+				{From: &GraphNode{Dir: "dir1", Address: "null_resource.bar[0]"}, To: &GraphNode{Dir: "dir1", Address: "module.bar.null_resource.bar[0]"}},
+				{From: &GraphNode{Dir: "dir1", Address: "null_resource.bar[1]"}, To: &GraphNode{Dir: "dir1", Address: "module.bar.null_resource.bar[1]"}},
+				{From: &GraphNode{Dir: "dir1", Address: "null_resource.baz"}},
+				{From: &GraphNode{Dir: "dir1", Address: "null_resource.foo"}, To: &GraphNode{Dir: "dir1", Address: "null_resource.foo3"}},
+				{To: &GraphNode{Dir: "dir1", Address: "null_resource.grault"}},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi_state",
+			mc: &MigrationConfig{
+				Type: "multi_state",
+				Name: "test",
+				Migrator: &MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo",
+						"xmv null_resource.bar[*] null_resource.bar2[$1]",
+					},
+				},
+			},
+			want: []*GraphEdge{
+				{From: &GraphNode{Dir: "dir1", Address: "null_resource.bar[0]"}, To: &GraphNode{Dir: "dir2", Address: "null_resource.bar2[0]"}},
+				{From: &GraphNode{Dir: "dir1", Address: "null_resource.bar[1]"}, To: &GraphNode{Dir: "dir2", Address: "null_resource.bar2[1]"}},
+				{From: &GraphNode{Dir: "dir1", Address: "null_resource.foo"}, To: &GraphNode{Dir: "dir2", Address: "null_resource.foo"}},
+			},
+			ok: true,
+		},
+		{
+			desc: "xrm",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir: "dir1",
+					Actions: []string{
+						"xrm null_resource.bar[*] module.qux*",
+					},
+				},
+			},
+			want: []*GraphEdge{
+				{From: &GraphNode{Dir: "dir1", Address: `module.qux["a"].null_resource.quux`}},
+				{From: &GraphNode{Dir: "dir1", Address: "null_resource.bar[0]"}},
+				{From: &GraphNode{Dir: "dir1", Address: "null_resource.bar[1]"}},
+			},
+			ok: true,
+		},
+		{
+			desc: "resource not found",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir:     "dir1",
+					Actions: []string{"mv null_resource.foo2 null_resource.foo3"},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "state not found",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir:     "dir3",
+					Actions: []string{"mv null_resource.foo null_resource.foo2"},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "unsupported migration type",
+			mc: &MigrationConfig{
+				Type:     "mock",
+				Name:     "test",
+				Migrator: &MockMigratorConfig{},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := NewMigrationGraph(tc.mc, listState)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got.Edges, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got.Edges, tc.want)
+			}
+		})
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	cases := []struct {
+		desc       string
+		address    string
+		wantModule string
+		wantRel    string
+	}{
+		{
+			desc:       "root",
+			address:    "null_resource.foo",
+			wantModule: "",
+			wantRel:    "null_resource.foo",
+		},
+		{
+			desc:       "module",
+			address:    "module.foo.null_resource.bar[0]",
+			wantModule: "module.foo",
+			wantRel:    "null_resource.bar[0]",
+		},
+		{
+			desc:       "nested module with keys",
+			address:    `module.foo["a.b"].module.bar[0].data.null_data_source.baz`,
+			wantModule: `module.foo["a.b"].module.bar[0]`,
+			wantRel:    "data.null_data_source.baz",
+		},
+		{
+			desc:       "module itself",
+			address:    `module.foo["a"]`,
+			wantModule: `module.foo["a"]`,
+			wantRel:    "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			gotModule, gotRel := ModulePath(tc.address)
+			if gotModule != tc.wantModule || gotRel != tc.wantRel {
+				t.Errorf("got: %s, %s, want: %s, %s", gotModule, gotRel, tc.wantModule, tc.wantRel)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+const (
+	// HookPreApply is a hook which runs before applying a migration.
+	HookPreApply = "pre_apply"
+	// HookPostApply is a hook which runs after a migration has been applied.
+	HookPostApply = "post_apply"
+	// HookOnFailure is a hook which runs after a pre_apply hook or applying a
+	// migration has failed.
+	HookOnFailure = "on_failure"
+)
+
+// HooksConfig is a config for commands which run around applying a migration,
+// such as sending a notification and invalidating a cache.
+//
+// Each command is executed in the current directory in order. Information
+// about the migration is passed via environment variables prefixed with
+// TFMIGRATE_, such as TFMIGRATE_HOOK and TFMIGRATE_MIGRATION_NAME.
+type HooksConfig struct {
+	// PreApply is a list of command lines which run before applying a
+	// migration. If any of them fails, the migration is not applied.
+	PreApply []string
+	// PostApply is a list of command lines which run after a migration has
+	// been applied.
+	PostApply []string
+	// OnFailure is a list of command lines which run after a pre_apply hook
+	// or applying a migration has failed.
+	OnFailure []string
+}
+
+// Validate returns an error if any command line is invalid.
+func (c *HooksConfig) Validate() error {
+	for _, hook := range []string{HookPreApply, HookPostApply, HookOnFailure} {
+		for _, command := range c.commands(hook) {
+			parts, err := shellwords.Parse(command)
+			if err != nil {
+				return fmt.Errorf("failed to parse command of %s hook: %s", hook, err)
+			}
+			if len(parts) == 0 {
+				return fmt.Errorf("command of %s hook is empty", hook)
+			}
+		}
+	}
+	return nil
+}
+
+// commands returns a list of command lines for a given hook.
+func (c *HooksConfig) commands(hook string) []string {
+	switch hook {
+	case HookPreApply:
+		return c.PreApply
+	case HookPostApply:
+		return c.PostApply
+	case HookOnFailure:
+		return c.OnFailure
+	default:
+		return nil
+	}
+}
+
+// Run runs commands for a given hook in order with given environment
+// variables in the KEY=VALUE format in addition to the current ones, and
+// TFMIGRATE_HOOK set to the hook. It stops at the first command which fails.
+// The stdout of each command is captured and logged.
+func (c *HooksConfig) Run(ctx context.Context, hook string, env []string) error {
+	env = append(append(os.Environ(), env...), "TFMIGRATE_HOOK="+hook)
+	ex := tfexec.NewExecutor(".", env)
+	for _, command := range c.commands(hook) {
+		parts, err := shellwords.Parse(command)
+		if err != nil {
+			return fmt.Errorf("failed to parse command of %s hook: %s", hook, err)
+		}
+		if len(parts) == 0 {
+			return fmt.Errorf("command of %s hook is empty", hook)
+		}
+
+		logging.FromContext(ctx).Printf("[INFO] [hook] run %s hook: %s\n", hook, command)
+		cmd, err := ex.NewCommandContext(ctx, parts[0], parts[1:]...)
+		if err != nil {
+			return fmt.Errorf("failed to run %s hook: %s", hook, err)
+		}
+		if err := ex.Run(cmd); err != nil {
+			return fmt.Errorf("failed to run %s hook: %s", hook, err)
+		}
+		if out := strings.TrimSpace(cmd.Stdout()); len(out) > 0 {
+			logging.FromContext(ctx).Printf("[INFO] [hook] output of %s hook: %s\n%s\n", hook, command, out)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package tfmigrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHooksConfigRun(t *testing.T) {
+	cases := []struct {
+		desc  string
+		hooks *HooksConfig
+		hook  string
+		want  string
+		ok    bool
+	}{
+		{
+			desc: "run in order",
+			hooks: &HooksConfig{
+				PreApply: []string{
+					`/bin/sh -c 'echo 1 $TFMIGRATE_HOOK $FOO >> "$LOG"'`,
+					`/bin/sh -c 'echo 2 $TFMIGRATE_HOOK $FOO >> "$LOG"'`,
+				},
+				PostApply: []string{
+					`/bin/sh -c 'echo 3 $TFMIGRATE_HOOK $FOO >> "$LOG"'`,
+				},
+			},
+			hook: HookPreApply,
+			want: "1 pre_apply bar\n2 pre_apply bar\n",
+			ok:   true,
+		},
+		{
+			desc: "stop at failure",
+			hooks: &HooksConfig{
+				OnFailure: []string{
+					`/bin/sh -c 'echo 1 $TFMIGRATE_HOOK $FOO >> "$LOG"'`,
+					"false",
+					`/bin/sh -c 'echo 2 $TFMIGRATE_HOOK $FOO >> "$LOG"'`,
+				},
+			},
+			hook: HookOnFailure,
+			want: "1 on_failure bar\n",
+			ok:   false,
+		},
+		{
+			desc:  "no commands",
+			hooks: &HooksConfig{},
+			hook:  HookPostApply,
+			want:  "",
+			ok:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			log := filepath.Join(t.TempDir(), "hooks.log")
+			err := tc.hooks.Run(context.Background(), tc.hook, []string{"FOO=bar", "LOG=" + log})
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			got, err := os.ReadFile(log)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatalf("failed to read hooks.log: %s", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got: %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHooksConfigValidate(t *testing.T) {
+	cases := []struct {
+		desc  string
+		hooks *HooksConfig
+		ok    bool
+	}{
+		{
+			desc:  "valid",
+			hooks: &HooksConfig{PreApply: []string{"./notify.sh 'migration started'"}},
+			ok:    true,
+		},
+		{
+			desc:  "empty command",
+			hooks: &HooksConfig{PostApply: []string{" "}},
+			ok:    false,
+		},
+		{
+			desc:  "unclosed quote",
+			hooks: &HooksConfig{OnFailure: []string{"./notify.sh 'failed"}},
+			ok:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.hooks.Validate()
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
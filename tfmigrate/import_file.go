@@ -0,0 +1,94 @@
+package tfmigrate
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadImportFile reads a mapping file of resource addresses to IDs and
+// returns a list of import actions.
+// The format is detected by the file extension. A .json file is an object
+// whose keys are addresses and values are IDs, and the actions are sorted by
+// address. Any other file is a CSV file with a pair of address and ID per
+// line, and the actions are in the same order. An optional header line of
+// "address,id" and lines starting with # are ignored in the CSV file.
+// Addresses can contain indexes such as aws_instance.example["prod"].
+func ReadImportFile(filename string) ([]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import file: %s", err)
+	}
+
+	var pairs [][2]string
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		pairs, err = parseImportJSON(b)
+	} else {
+		pairs, err = parseImportCSV(b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse import file: %s, err: %s", filename, err)
+	}
+
+	actions := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		if len(p[0]) == 0 || len(p[1]) == 0 {
+			return nil, fmt.Errorf("address and id must not be empty in import file: %s", filename)
+		}
+		actions = append(actions, "import "+quoteStateActionArg(p[0])+" "+quoteStateActionArg(p[1]))
+	}
+	return actions, nil
+}
+
+// parseImportJSON parses an object of addresses to IDs.
+func parseImportJSON(b []byte) ([][2]string, error) {
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(m))
+	for address := range m {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	pairs := make([][2]string, 0, len(m))
+	for _, address := range addresses {
+		pairs = append(pairs, [2]string{address, m[address]})
+	}
+	return pairs, nil
+}
+
+// parseImportCSV parses lines of address and ID pairs.
+func parseImportCSV(b []byte) ([][2]string, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	// An address can contain double quotes in an unquoted field such as
+	// aws_instance.example["prod"].
+	r.LazyQuotes = true
+
+	pairs := [][2]string{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(pairs) == 0 && record[0] == "address" && record[1] == "id" {
+			continue
+		}
+		pairs = append(pairs, [2]string{strings.TrimSpace(record[0]), strings.TrimSpace(record[1])})
+	}
+	return pairs, nil
+}
@@ -0,0 +1,112 @@
+package tfmigrate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadImportFile(t *testing.T) {
+	cases := []struct {
+		desc     string
+		filename string
+		source   string
+		want     []string
+		ok       bool
+	}{
+		{
+			desc:     "csv",
+			filename: "imports.csv",
+			source: `address,id
+# instances
+aws_instance.example["prod"],i-1234
+aws_instance.example["stg"], i-5678
+aws_instance.example["a b"],i-9012
+aws_iam_role_policy_attachment.foo,foo-role/arn:aws:iam::123456789012:policy/foo
+null_resource.bar,"id with space"
+`,
+			want: []string{
+				`import aws_instance.example["prod"] i-1234`,
+				`import aws_instance.example["stg"] i-5678`,
+				`import aws_instance.example["a b"] i-9012`,
+				"import aws_iam_role_policy_attachment.foo foo-role/arn:aws:iam::123456789012:policy/foo",
+				"import null_resource.bar 'id with space'",
+			},
+			ok: true,
+		},
+		{
+			desc:     "json",
+			filename: "imports.json",
+			source: `{
+  "aws_instance.example[\"stg\"]": "i-5678",
+  "aws_instance.example[\"prod\"]": "i-1234"
+}`,
+			want: []string{
+				`import aws_instance.example["prod"] i-1234`,
+				`import aws_instance.example["stg"] i-5678`,
+			},
+			ok: true,
+		},
+		{
+			desc:     "csv with wrong number of fields",
+			filename: "imports.csv",
+			source:   "aws_instance.example,i-1234,foo\n",
+			want:     nil,
+			ok:       false,
+		},
+		{
+			desc:     "csv with an empty id",
+			filename: "imports.csv",
+			source:   "aws_instance.example,\n",
+			want:     nil,
+			ok:       false,
+		},
+		{
+			desc:     "invalid json",
+			filename: "imports.json",
+			source:   `["aws_instance.example"]`,
+			want:     nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), tc.filename)
+			if err := os.WriteFile(filename, []byte(tc.source), 0600); err != nil {
+				t.Fatalf("failed to write import file: %s", err)
+			}
+
+			got, err := ReadImportFile(filename)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+				// The actions should be parsed back to import actions.
+				for _, cmdStr := range got {
+					action, err := NewStateActionFromString(cmdStr)
+					if err != nil {
+						t.Fatalf("failed to parse action: %s", err)
+					}
+					if _, ok := action.(*StateImportAction); !ok {
+						t.Errorf("unexpected action type: %T", action)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReadImportFileNotFound(t *testing.T) {
+	_, err := ReadImportFile(filepath.Join(t.TempDir(), "imports.csv"))
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
@@ -1,10 +1,19 @@
 package tfmigrate
 
 import (
+	"bytes"
 	"context"
-	"log"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
+	"github.com/minamijoyo/tfmigrate/logging"
 	"github.com/minamijoyo/tfmigrate/tfexec"
 )
 
@@ -21,15 +30,165 @@ type Migrator interface {
 	Apply(ctx context.Context) error
 }
 
+// disableBackupOpt is an option for state subcommands to disable unnecessary
+// state backups, because we never restore state from the backup generated by
+// each state action. Note that os.DevNull is /dev/null on Unix-like systems,
+// but NUL on Windows.
+var disableBackupOpt = "-backup=" + os.DevNull
+
+// newTerraformCLI is a common helper function to build a TerraformCLI for a
+// given directory customized with a given option.
+func newTerraformCLI(dir string, o *MigratorOption) tfexec.TerraformCLI {
+	tf := tfexec.NewTerraformCLI(tfexec.NewExecutor(dir, os.Environ()))
+	if o == nil {
+		return tf
+	}
+
+	if len(o.ExecPath) > 0 {
+		// While NewTerraformCLI reads the environment variable TFMIGRATE_EXEC_PATH
+		// at initialization, the MigratorOption takes precedence over it.
+		tf.SetExecPath(o.ExecPath)
+	}
+	tf.SetExecMode(o.ExecMode)
+	tf.SetTempDir(o.TempDir)
+	tf.SetKeepTemp(o.KeepTemp)
+	tf.SetReadOnly(o.ReadOnly)
+	tf.SetInitLock(o.InitLock)
+	tf.SetRetryPolicy(o.Retry)
+	tf.SetForceUnlockStale(o.ForceUnlockStale)
+	tf.SetLockOptions(o.LockOptions)
+	if o.Offline {
+		// Disable the checkpoint service which checks for upgrades and
+		// security bulletins.
+		tf.AppendEnv("CHECKPOINT_DISABLE", "1")
+	}
+
+	return tf
+}
+
+// NewTerraformCLI returns a TerraformCLI for a given directory customized
+// with a given option in the same way as migrators. It is intended for
+// commands which run terraform outside of migrations.
+func NewTerraformCLI(dir string, o *MigratorOption) tfexec.TerraformCLI {
+	return newTerraformCLI(dir, o)
+}
+
+// validateEnv validates a map of environment variables defined in a
+// migration file.
+func validateEnv(env map[string]string) error {
+	for k := range env {
+		if len(k) == 0 || strings.ContainsAny(k, "= ") {
+			return fmt.Errorf("invalid environment variable name: %q", k)
+		}
+	}
+	return nil
+}
+
+// validateVars validates names of terraform variables.
+func validateVars(vars map[string]string) error {
+	for k := range vars {
+		if len(k) == 0 || strings.ContainsAny(k, "= ") {
+			return fmt.Errorf("invalid variable name: %q", k)
+		}
+	}
+	return nil
+}
+
+// planVarOptions returns -var-file and -var options passed to terraform plan.
+// Default variable files and variables in a given option come first, and
+// variables of a migration take precedence. Variables are sorted by name to
+// make options deterministic.
+func planVarOptions(o *MigratorOption, varFiles []string, vars map[string]string) []string {
+	var defaultVarFiles []string
+	var defaultVars map[string]string
+	if o != nil {
+		defaultVarFiles = o.VarFiles
+		defaultVars = o.Vars
+	}
+
+	opts := []string{}
+	for _, f := range append(append([]string{}, defaultVarFiles...), varFiles...) {
+		opts = append(opts, "-var-file="+f)
+	}
+
+	merged := mergeEnv(defaultVars, vars)
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		opts = append(opts, "-var="+k+"="+merged[k])
+	}
+	return opts
+}
+
+// mergeEnv returns a new map of environment variables merged from given maps.
+// A value in a later map takes precedence.
+func mergeEnv(envs ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, env := range envs {
+		for k, v := range env {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// appendEnv appends a map of environment variables to a given TerraformCLI
+// in a deterministic order.
+func appendEnv(tf tfexec.TerraformCLI, env map[string]string) {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tf.AppendEnv(k, env[k])
+	}
+}
+
+// missingWorkspace is how setupWorkDir treats a workspace which doesn't exist.
+type missingWorkspace int
+
+const (
+	// missingWorkspaceError fails if the workspace doesn't exist.
+	missingWorkspaceError missingWorkspace = iota
+	// missingWorkspaceCreate creates the workspace if it doesn't exist.
+	missingWorkspaceCreate
+	// missingWorkspaceEmpty treats the workspace as an empty state without
+	// creating it.
+	missingWorkspaceEmpty
+)
+
+// destinationMissingWorkspace returns how to treat a missing workspace where
+// resources move to. It's created only on apply, because a plan must not
+// change anything, and a plan for a mistyped workspace should not leave an
+// empty workspace behind. In read-only mode, it's never created either.
+func destinationMissingWorkspace(ctx context.Context, o *MigratorOption) missingWorkspace {
+	if modeFromContext(ctx) == modeApply && (o == nil || !o.ReadOnly) {
+		return missingWorkspaceCreate
+	}
+	return missingWorkspaceEmpty
+}
+
 // setupWorkDir is a common helper function to set up work dir and returns the
 // current state and a switch back function.
-func setupWorkDir(ctx context.Context, tf tfexec.TerraformCLI, workspace string, isBackendTerraformCloud bool, backendConfig []string, ignoreLegacyStateInitErr bool) (*tfexec.State, func() error, error) {
+// In offline mode, providers are installed only from a pre-populated local
+// filesystem mirror, so that terraform init never accesses the registry.
+// If a state version is given, it returns the historical state instead of
+// the current state.
+// A workspace which doesn't exist is treated by a given missingWorkspace.
+// If reconfigure is true, the work dir is initialized with the backend
+// configurations and -reconfigure, so that it ignores a backend which the work
+// dir has been initialized with before.
+func setupWorkDir(ctx context.Context, tf tfexec.TerraformCLI, workspace string, missing missingWorkspace, isBackendTerraformCloud bool, backendConfig []string, reconfigure bool, ignoreLegacyStateInitErr bool, providersMirrorDir string, providersLockPlatforms []string, offline bool, stateVersion string) (_ *tfexec.State, _ func() error, err error) {
 	// check if terraform command is available.
 	execType, version, err := tf.Version(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	log.Printf("[INFO] [migrator@%s] %s version: %s\n", tf.Dir(), execType, version)
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] %s version: %s\n", tf.Dir(), execType, version)
 
 	supportsStateReplaceProvider, constraints, err := tf.SupportsStateReplaceProvider(ctx)
 	if err != nil {
@@ -37,11 +196,24 @@ func setupWorkDir(ctx context.Context, tf tfexec.TerraformCLI, workspace string,
 	}
 
 	// init folder
-	log.Printf("[INFO] [migrator@%s] initialize work dir\n", tf.Dir())
-	err = tf.Init(ctx, "-input=false", "-no-color")
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] initialize work dir\n", tf.Dir())
+	remoteInitOpts := []string{"-input=false", "-no-color"}
+	if reconfigure {
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] reconfigure backend with its own backend configuration\n", tf.Dir())
+		for _, b := range backendConfig {
+			remoteInitOpts = append(remoteInitOpts, fmt.Sprintf("-backend-config=%s", b))
+		}
+		if !isBackendTerraformCloud {
+			remoteInitOpts = append(remoteInitOpts, "-reconfigure")
+		}
+	}
+	if offline {
+		remoteInitOpts = append(remoteInitOpts, "-plugin-dir="+providersMirrorDir)
+	}
+	err = tf.Init(ctx, remoteInitOpts...)
 	if err != nil {
 		if supportsStateReplaceProvider && ignoreLegacyStateInitErr && strings.Contains(err.Error(), tfexec.AcceptableLegacyStateInitError) {
-			log.Printf("[INFO] [migrator@%s] ignoring error '%s' initilizing work dir; the error is expected when using Terraform %s with a legacy Terraform state\n", tf.Dir(), tfexec.AcceptableLegacyStateInitError, constraints)
+			logging.FromContext(ctx).Printf("[INFO] [migrator@%s] ignoring error '%s' initilizing work dir; the error is expected when using Terraform %s with a legacy Terraform state\n", tf.Dir(), tfexec.AcceptableLegacyStateInitError, constraints)
 		} else {
 			return nil, nil, err
 		}
@@ -52,27 +224,251 @@ func setupWorkDir(ctx context.Context, tf tfexec.TerraformCLI, workspace string,
 	if err != nil {
 		return nil, nil, err
 	}
-	log.Printf("[DEBUG] [migrator@%s] currentWorkspace = %s, workspace = %s\n", tf.Dir(), currentWorkspace, workspace)
-	if currentWorkspace != workspace {
+	logging.FromContext(ctx).Printf("[DEBUG] [migrator@%s] currentWorkspace = %s, workspace = %s\n", tf.Dir(), currentWorkspace, workspace)
+
+	// mark the work dir as in use, so that the cleanup command can restore it
+	// if this run crashes. The marker is removed after switching back to remote.
+	if err := writeRunMarker(tf.Dir(), newRunMarker(ctx, currentWorkspace)); err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rmErr := removeRunMarker(tf.Dir()); rmErr != nil {
+				logging.FromContext(ctx).Printf("[ERROR] [migrator@%s] %s\n", tf.Dir(), rmErr)
+			}
+		}
+	}()
+
+	exists := true
+	if currentWorkspace != workspace && missing == missingWorkspaceEmpty {
+		workspaces, err := tf.WorkspaceList(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		exists = slices.Contains(workspaces, workspace)
+	}
+
+	if currentWorkspace != workspace && exists {
 		// switch to workspace
-		log.Printf("[INFO] [migrator@%s] switch to remote workspace %s\n", tf.Dir(), workspace)
-		err = tf.WorkspaceSelect(ctx, workspace)
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] switch to remote workspace %s\n", tf.Dir(), workspace)
+		if missing == missingWorkspaceCreate {
+			err = tf.WorkspaceSelectOrCreate(ctx, workspace)
+		} else {
+			err = tf.WorkspaceSelect(ctx, workspace)
+		}
 		if err != nil {
 			return nil, nil, err
 		}
 	}
 
-	// get the current remote state.
-	log.Printf("[INFO] [migrator@%s] get the current remote state\n", tf.Dir())
-	currentState, err := tf.StatePull(ctx)
+	var currentState *tfexec.State
+	if !exists {
+		// A new workspace has no state, so we don't need to create it.
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] remote workspace %s doesn't exist, so treat it as an empty state\n", tf.Dir(), workspace)
+		currentState = tfexec.NewState([]byte{})
+	} else if len(stateVersion) != 0 {
+		// get the historical remote state. This must be done before
+		// overriding the backend, because the backend config is lost.
+		currentState, err = pullStateVersion(ctx, tf.Dir(), workspace, stateVersion)
+	} else {
+		// get the current remote state.
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] get the current remote state\n", tf.Dir())
+		currentState, err = tf.StatePull(ctx)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
+
+	// populate a local filesystem mirror for provider plugins so that
+	// switching backends doesn't require access to the registry.
+	platformOpts := []string{}
+	for _, p := range providersLockPlatforms {
+		platformOpts = append(platformOpts, "-platform="+p)
+	}
+	initOpts := []string{}
+	if offline {
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] offline mode: use providers mirror: %s\n", tf.Dir(), providersMirrorDir)
+		initOpts = append(initOpts, "-plugin-dir="+providersMirrorDir)
+	} else if len(providersMirrorDir) != 0 {
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] populate providers mirror: %s\n", tf.Dir(), providersMirrorDir)
+		err = tf.ProvidersMirror(ctx, providersMirrorDir, platformOpts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		initOpts = append(initOpts, "-plugin-dir="+providersMirrorDir)
+	}
+
 	// override backend to local
-	log.Printf("[INFO] [migrator@%s] override backend to local\n", tf.Dir())
-	switchBackToRemoteFunc, err := tf.OverrideBackendToLocal(ctx, "_tfmigrate_override.tf", workspace, isBackendTerraformCloud, backendConfig, ignoreLegacyStateInitErr)
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] override backend to local\n", tf.Dir())
+	switchBackToRemoteFunc, err := tf.OverrideBackendToLocal(ctx, OverrideFileName, workspace, isBackendTerraformCloud, backendConfig, ignoreLegacyStateInitErr, initOpts...)
 	if err != nil {
 		return nil, nil, err
 	}
-	return currentState, switchBackToRemoteFunc, nil
+
+	restoreLockFileFunc := func() error { return nil }
+	if len(providersLockPlatforms) != 0 {
+		restoreLockFileFunc, err = lockProviders(ctx, tf, providersMirrorDir, platformOpts)
+		if err != nil {
+			if switchErr := switchBackToRemoteFunc(); switchErr != nil {
+				logging.FromContext(ctx).Printf("[ERROR] [migrator@%s] failed to switch back to remote: %s\n", tf.Dir(), switchErr)
+			}
+			return nil, nil, err
+		}
+	}
+
+	return currentState, func() error {
+		if err := restoreLockFileFunc(); err != nil {
+			return err
+		}
+		if err := switchBackToRemoteFunc(); err != nil {
+			// keep the marker to restore the work dir with the cleanup command.
+			return err
+		}
+		return removeRunMarker(tf.Dir())
+	}, nil
+}
+
+// dependencyLockFileName is a name of the dependency lock file of terraform.
+const dependencyLockFileName = ".terraform.lock.hcl"
+
+// lockProviders records checksums of providers for given platform options in
+// the dependency lock file, so that terraform plan doesn't fail with
+// mismatched checksums for providers installed from a mirror. If a providers
+// mirror is set, checksums are computed from the mirror instead of the
+// registry. It returns a function to restore the original lock file.
+func lockProviders(ctx context.Context, tf tfexec.TerraformCLI, providersMirrorDir string, platformOpts []string) (func() error, error) {
+	path := filepath.Join(tf.Dir(), dependencyLockFileName)
+	orig, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read the dependency lock file: %s", err)
+	}
+	exists := err == nil
+
+	restore := func() error {
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] restore the dependency lock file\n", tf.Dir())
+		if !exists {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove the dependency lock file: %s", err)
+			}
+			return nil
+		}
+		if err := os.WriteFile(path, orig, 0644); err != nil {
+			return fmt.Errorf("failed to restore the dependency lock file: %s", err)
+		}
+		return nil
+	}
+
+	opts := append([]string{}, platformOpts...)
+	if len(providersMirrorDir) != 0 {
+		opts = append(opts, "-fs-mirror="+providersMirrorDir)
+	}
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] lock providers: %s\n", tf.Dir(), strings.Join(opts, " "))
+	if err := tf.ProvidersLock(ctx, opts...); err != nil {
+		if restoreErr := restore(); restoreErr != nil {
+			logging.FromContext(ctx).Printf("[ERROR] [migrator@%s] %s\n", tf.Dir(), restoreErr)
+		}
+		return nil, err
+	}
+
+	return restore, nil
+}
+
+// pushState pushes a given state to remote. The base is the remote state which
+// the new state was computed from.
+// In sandbox mode, it writes the state to a local file in the sandbox
+// directory instead, so that the remote state is never touched.
+func pushState(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State, base *tfexec.State, workspace string, o *MigratorOption) error {
+	if o == nil || len(o.SandboxDir) == 0 {
+		if o != nil && len(o.StateVersions) != 0 {
+			return fmt.Errorf("refuse to push a new state computed from a historical state version")
+		}
+		if o != nil && o.CheckLineage {
+			logging.FromContext(ctx).Printf("[INFO] [migrator@%s] check lineage of the remote state\n", tf.Dir())
+			remoteState, err := tf.StatePull(ctx)
+			if err != nil {
+				return err
+			}
+			if err := checkLineage(remoteState, state); err != nil {
+				return err
+			}
+		}
+		if o != nil && o.ReadOnly && o.StatePushMethod == StatePushMethodAPI {
+			return fmt.Errorf("refused to push a new state with Terraform Cloud API in read-only mode")
+		}
+		method := ""
+		if o != nil {
+			method = o.StatePushMethod
+		}
+		return pushRemoteState(ctx, tf, state, base, workspace, method)
+	}
+
+	// Each migration plans against the real remote state, so a state written
+	// by a previous migration in the same sandbox is never used as an input.
+	// Refuse to overwrite it rather than silently discarding its changes.
+	path := filepath.Join(o.SandboxDir, sandboxStateFileName(tf.Dir(), workspace))
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] sandbox mode: write the new state to %s\n", tf.Dir(), path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("a state has already been written to sandbox by another migration, which cannot be chained: %s", path)
+		}
+		return fmt.Errorf("failed to write the new state to sandbox: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(state.Bytes()); err != nil {
+		return fmt.Errorf("failed to write the new state to sandbox: %s", err)
+	}
+	return f.Close()
+}
+
+// checkLineage returns an error if the lineage of a given remote state doesn't
+// match the new state. If the remote state doesn't exist yet, any lineage is
+// accepted, because a new state is created with a new lineage.
+func checkLineage(remoteState *tfexec.State, newState *tfexec.State) error {
+	remoteLineage, err := stateLineage(remoteState)
+	if err != nil {
+		return fmt.Errorf("failed to parse the remote state: %s", err)
+	}
+	if len(remoteLineage) == 0 {
+		return nil
+	}
+
+	newLineage, err := stateLineage(newState)
+	if err != nil {
+		return fmt.Errorf("failed to parse the new state: %s", err)
+	}
+	if remoteLineage != newLineage {
+		return fmt.Errorf("lineage mismatch: the remote state has been replaced during the migration: remote = %s, new = %s", remoteLineage, newLineage)
+	}
+	return nil
+}
+
+// stateLineage returns the lineage of a given state.
+// It returns an empty string if the state is empty.
+func stateLineage(state *tfexec.State) (string, error) {
+	b := bytes.TrimSpace(state.Bytes())
+	if len(b) == 0 {
+		return "", nil
+	}
+
+	var s struct {
+		Lineage string `json:"lineage"`
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return "", err
+	}
+	return s.Lineage, nil
+}
+
+// sandboxStateFileName returns a file name of state in sandbox for a given
+// working directory and workspace.
+// The name starts with a readable form of them, but different directories
+// may have the same readable form such as foo/bar and foo_bar, so it ends
+// with a short hash of the cleaned path and the workspace to avoid collisions.
+// e.g.) dir=foo/bar, workspace=default => foo_bar@default-0123456789abcdef.tfstate
+func sandboxStateFileName(dir string, workspace string) string {
+	path := filepath.ToSlash(filepath.Clean(dir))
+	r := strings.NewReplacer("/", "_", "\\", "_", ":", "_", ".", "_")
+	sum := sha256.Sum256([]byte(path + "\x00" + workspace))
+	return fmt.Sprintf("%s@%s-%s.tfstate", r.Replace(path), workspace, hex.EncodeToString(sum[:8]))
 }
@@ -0,0 +1,323 @@
+package tfmigrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestSandboxStateFileName(t *testing.T) {
+	cases := []struct {
+		desc      string
+		dir       string
+		workspace string
+		want      string
+	}{
+		{
+			desc:      "simple",
+			dir:       "foo",
+			workspace: "default",
+			want:      "foo@default-",
+		},
+		{
+			desc:      "nested",
+			dir:       "foo/bar/",
+			workspace: "default",
+			want:      "foo_bar@default-",
+		},
+		{
+			desc:      "current dir",
+			dir:       ".",
+			workspace: "work1",
+			want:      "_@work1-",
+		},
+		{
+			desc:      "parent dir",
+			dir:       "../foo",
+			workspace: "default",
+			want:      "___foo@default-",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := sandboxStateFileName(tc.dir, tc.workspace)
+			if !strings.HasPrefix(got, tc.want) || !strings.HasSuffix(got, ".tfstate") {
+				t.Errorf("got = %s, but want = %s<hash>.tfstate", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSandboxStateFileNameCollision(t *testing.T) {
+	targets := []struct {
+		dir       string
+		workspace string
+	}{
+		{dir: "a/b", workspace: "default"},
+		{dir: "a_b", workspace: "default"},
+		{dir: "a.b", workspace: "default"},
+		{dir: "a:b", workspace: "default"},
+		{dir: "a", workspace: "b@default"},
+		{dir: "a@b", workspace: "default"},
+	}
+
+	seen := make(map[string]string)
+	for _, target := range targets {
+		got := sandboxStateFileName(target.dir, target.workspace)
+		key := target.dir + "@" + target.workspace
+		if prev, ok := seen[got]; ok {
+			t.Errorf("%s and %s have the same file name: %s", prev, key, got)
+		}
+		seen[got] = key
+	}
+
+	// The same directory in a different form has the same file name.
+	if sandboxStateFileName("foo/bar/", "default") != sandboxStateFileName("./foo/bar", "default") {
+		t.Error("expected the same file name for the same directory")
+	}
+}
+
+func TestAppendEnv(t *testing.T) {
+	cases := []struct {
+		desc string
+		envs []map[string]string
+		want []string
+	}{
+		{
+			desc: "empty",
+			envs: nil,
+			want: []string{"FOO=foo"},
+		},
+		{
+			desc: "sorted",
+			envs: []map[string]string{
+				{
+					"TF_VAR_region": "eu-west-1",
+					"AWS_PROFILE":   "legacy",
+				},
+			},
+			want: []string{"FOO=foo", "AWS_PROFILE=legacy", "TF_VAR_region=eu-west-1"},
+		},
+		{
+			desc: "later takes precedence",
+			envs: []map[string]string{
+				{
+					"AWS_PROFILE": "legacy",
+					"FOO":         "bar",
+				},
+				{
+					"AWS_PROFILE": "new",
+				},
+			},
+			want: []string{"FOO=foo", "AWS_PROFILE=new", "FOO=bar"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := tfexec.NewExecutor("foo", []string{"FOO=foo"})
+			tf := tfexec.NewTerraformCLI(e)
+			appendEnv(tf, mergeEnv(tc.envs...))
+			got := e.Env()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got = %v, but want = %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlanVarOptions(t *testing.T) {
+	cases := []struct {
+		desc     string
+		o        *MigratorOption
+		varFiles []string
+		vars     map[string]string
+		want     []string
+	}{
+		{
+			desc:     "empty",
+			o:        nil,
+			varFiles: nil,
+			vars:     nil,
+			want:     []string{},
+		},
+		{
+			desc:     "migration only",
+			o:        nil,
+			varFiles: []string{"prod.tfvars"},
+			vars: map[string]string{
+				"region": "ap-northeast-1",
+				"env":    "prod",
+			},
+			want: []string{"-var-file=prod.tfvars", "-var=env=prod", "-var=region=ap-northeast-1"},
+		},
+		{
+			desc: "with defaults",
+			o: &MigratorOption{
+				VarFiles: []string{"common.tfvars"},
+				Vars: map[string]string{
+					"region": "us-east-1",
+					"owner":  "platform",
+				},
+			},
+			varFiles: []string{"prod.tfvars"},
+			vars: map[string]string{
+				"region": "ap-northeast-1",
+			},
+			want: []string{"-var-file=common.tfvars", "-var-file=prod.tfvars", "-var=owner=platform", "-var=region=ap-northeast-1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := planVarOptions(tc.o, tc.varFiles, tc.vars)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got = %v, but want = %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPushStateSandbox(t *testing.T) {
+	sandboxDir := t.TempDir()
+	// StatePush is never called in sandbox mode,
+	// so there is no need to mock any command.
+	tf := tfexec.NewTerraformCLI(tfexec.NewExecutor("foo", os.Environ()))
+	state := tfexec.NewState([]byte("dummy state"))
+	o := &MigratorOption{
+		SandboxDir: sandboxDir,
+	}
+
+	err := pushState(context.Background(), tf, state, nil, "default", o)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(sandboxDir, sandboxStateFileName("foo", "default")))
+	if err != nil {
+		t.Fatalf("failed to read state in sandbox: %s", err)
+	}
+	if string(got) != "dummy state" {
+		t.Errorf("got = %s, but want = %s", string(got), "dummy state")
+	}
+
+	// A state written by a previous migration must not be overwritten.
+	err = pushState(context.Background(), tf, tfexec.NewState([]byte("another state")), nil, "default", o)
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestPushStateWithStateVersions(t *testing.T) {
+	// StatePush is never called for a state computed from a historical state
+	// version, so there is no need to mock any command.
+	tf := tfexec.NewTerraformCLI(tfexec.NewExecutor("foo", os.Environ()))
+	state := tfexec.NewState([]byte("dummy state"))
+	o := &MigratorOption{
+		StateVersions: map[string]string{"": "v1"},
+	}
+
+	err := pushState(context.Background(), tf, state, nil, "default", o)
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestCheckLineage(t *testing.T) {
+	cases := []struct {
+		desc        string
+		remoteState string
+		newState    string
+		ok          bool
+	}{
+		{
+			desc:        "match",
+			remoteState: `{"version": 4, "serial": 1, "lineage": "foo"}`,
+			newState:    `{"version": 4, "serial": 2, "lineage": "foo"}`,
+			ok:          true,
+		},
+		{
+			desc:        "mismatch",
+			remoteState: `{"version": 4, "serial": 1, "lineage": "bar"}`,
+			newState:    `{"version": 4, "serial": 2, "lineage": "foo"}`,
+			ok:          false,
+		},
+		{
+			desc:        "remote state not found",
+			remoteState: "",
+			newState:    `{"version": 4, "serial": 1, "lineage": "foo"}`,
+			ok:          true,
+		},
+		{
+			desc:        "invalid remote state",
+			remoteState: "dummy state",
+			newState:    `{"version": 4, "serial": 1, "lineage": "foo"}`,
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := checkLineage(tfexec.NewState([]byte(tc.remoteState)), tfexec.NewState([]byte(tc.newState)))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestDestinationMissingWorkspace(t *testing.T) {
+	cases := []struct {
+		desc  string
+		apply bool
+		o     *MigratorOption
+		want  missingWorkspace
+	}{
+		{
+			desc:  "plan",
+			apply: false,
+			o:     &MigratorOption{},
+			want:  missingWorkspaceEmpty,
+		},
+		{
+			desc:  "apply",
+			apply: true,
+			o:     &MigratorOption{},
+			want:  missingWorkspaceCreate,
+		},
+		{
+			desc:  "read-only plan",
+			apply: false,
+			o:     &MigratorOption{ReadOnly: true},
+			want:  missingWorkspaceEmpty,
+		},
+		{
+			desc:  "read-only apply",
+			apply: true,
+			o:     &MigratorOption{ReadOnly: true},
+			want:  missingWorkspaceEmpty,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.apply {
+				ctx = withApplyMode(ctx)
+			}
+			got := destinationMissingWorkspace(ctx, tc.o)
+			if got != tc.want {
+				t.Errorf("got: %d, want: %d", got, tc.want)
+			}
+		})
+	}
+}
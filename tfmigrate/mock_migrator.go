@@ -3,8 +3,8 @@ package tfmigrate
 import (
 	"context"
 	"fmt"
-	"log"
 
+	"github.com/minamijoyo/tfmigrate/logging"
 	"github.com/minamijoyo/tfmigrate/tfexec"
 )
 
@@ -55,28 +55,28 @@ func (m *MockMigrator) plan(_ context.Context) (*tfexec.State, error) {
 // Plan computes a new state by applying state migration operations to a temporary state.
 // It does nothing, but can return an error.
 func (m *MockMigrator) Plan(ctx context.Context) error {
-	log.Printf("[INFO] [migrator] start state migrator plan\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] start state migrator plan\n")
 	_, err := m.plan(ctx)
 	if err != nil {
 		return err
 	}
-	log.Printf("[INFO] [migrator] state migrator plan success!\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] state migrator plan success!\n")
 	return nil
 }
 
 // Apply computes a new state and pushes it to remote state.
 // It does nothing, but can return an error.
 func (m *MockMigrator) Apply(ctx context.Context) error {
-	log.Printf("[INFO] [migrator] start state migrator plan phase for apply\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] start state migrator plan phase for apply\n")
 	_, err := m.plan(ctx)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("[INFO] [migrator] start state migrator apply phase\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] start state migrator apply phase\n")
 	if m.applyError {
 		return fmt.Errorf("failed to apply mock migrator: applyError = %t", m.applyError)
 	}
-	log.Printf("[INFO] [migrator] state migrator apply success!\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] state migrator apply success!\n")
 	return nil
 }
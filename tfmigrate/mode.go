@@ -0,0 +1,29 @@
+package tfmigrate
+
+import "context"
+
+const (
+	// modePlan is a mode of a migration which only computes new states.
+	modePlan = "plan"
+	// modeApply is a mode of a migration which pushes new states.
+	modeApply = "apply"
+)
+
+// modeContextKey is a key of a mode of a migration in context.
+type modeContextKey struct{}
+
+// withApplyMode returns a new context which marks the migration as apply.
+// Actions are executed in both plan and apply, so that action plugins need to
+// know the mode to skip side effects in plan.
+func withApplyMode(ctx context.Context) context.Context {
+	return context.WithValue(ctx, modeContextKey{}, modeApply)
+}
+
+// modeFromContext returns a mode of the migration in a given context.
+// It returns modePlan if no mode is set.
+func modeFromContext(ctx context.Context) string {
+	if mode, ok := ctx.Value(modeContextKey{}).(string); ok {
+		return mode
+	}
+	return modePlan
+}
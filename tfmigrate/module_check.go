@@ -0,0 +1,163 @@
+package tfmigrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+)
+
+// moduleManifestEntry is an entry of the module manifest which terraform init
+// writes to .terraform/modules/modules.json.
+type moduleManifestEntry struct {
+	// Key is a path of module calls without instance keys.
+	// e.g.) foo.bar for module.foo["a"].module.bar
+	Key string `json:"Key"`
+	// Source is a source address of the module.
+	Source string `json:"Source"`
+	// Version is a resolved version of a registry module.
+	// It is empty for other modules.
+	Version string `json:"Version"`
+	// Dir is a path to a directory where the module is installed, relative
+	// to the working directory.
+	Dir string `json:"Dir"`
+}
+
+// moduleManifest is a map of module keys to entries of the module manifest.
+type moduleManifest map[string]moduleManifestEntry
+
+// readModuleManifest reads the module manifest in a given working directory.
+// It returns nil if the manifest doesn't exist, which means that the
+// configuration has no module calls.
+func readModuleManifest(dir string) (moduleManifest, error) {
+	dataDir := os.Getenv("TF_DATA_DIR")
+	if len(dataDir) == 0 {
+		dataDir = ".terraform"
+	}
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(dir, dataDir)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dataDir, "modules", "modules.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read module manifest: %s", err)
+	}
+
+	var m struct {
+		Modules []moduleManifestEntry `json:"Modules"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse module manifest: %s", err)
+	}
+
+	manifest := make(moduleManifest)
+	for _, e := range m.Modules {
+		manifest[e.Key] = e
+	}
+	return manifest, nil
+}
+
+// moduleKey returns a key of the module manifest for the module which
+// contains a given address, and whether the address is a module itself.
+// It returns an empty key for an address in the root module.
+// e.g.) module.foo["a"].module.bar.aws_instance.baz => foo.bar, false
+func moduleKey(address string) (string, bool) {
+	modulePath, rest := ModulePath(address)
+	if len(modulePath) == 0 {
+		return "", false
+	}
+
+	names := []string{}
+	parts := splitAddress(modulePath)
+	for i := 0; i+1 < len(parts); i += 2 {
+		name, _, _ := strings.Cut(parts[i+1], "[")
+		names = append(names, name)
+	}
+	return strings.Join(names, "."), len(rest) == 0
+}
+
+// moduleSource returns a source of a given module entry, which can be
+// compared across working directories. A local module is identified by its
+// absolute path, because a relative source depends on the caller.
+func moduleSource(dir string, e moduleManifestEntry) string {
+	if strings.HasPrefix(e.Source, "./") || strings.HasPrefix(e.Source, "../") {
+		if abs, err := filepath.Abs(filepath.Join(dir, e.Dir)); err == nil {
+			return abs
+		}
+	}
+	return e.Source
+}
+
+// checkModuleMove returns a warning if an mv action moves a given source
+// address in fromDir to a given destination address in toDir between module
+// calls which are incompatible, which otherwise guarantees diffs after the
+// migration. Versions of the same registry module are compared for any
+// address in a module, and sources are compared only for a whole module,
+// because moving a resource between different modules is common in
+// refactoring. It returns an empty string for addresses in the root module
+// and modules not found in the manifests.
+func checkModuleMove(ctx context.Context, fromDir string, fromManifest moduleManifest, toDir string, toManifest moduleManifest, source string, destination string) string {
+	fromKey, fromWhole := moduleKey(source)
+	toKey, toWhole := moduleKey(destination)
+	if len(fromKey) == 0 || len(toKey) == 0 {
+		return ""
+	}
+
+	from, ok := fromManifest[fromKey]
+	if !ok {
+		logging.FromContext(ctx).Printf("[DEBUG] [migrator@%s] module %s not found in module manifest\n", fromDir, fromKey)
+		return ""
+	}
+	to, ok := toManifest[toKey]
+	if !ok {
+		logging.FromContext(ctx).Printf("[DEBUG] [migrator@%s] module %s not found in module manifest\n", toDir, toKey)
+		return ""
+	}
+
+	if moduleSource(fromDir, from) != moduleSource(toDir, to) {
+		if fromWhole && toWhole {
+			return fmt.Sprintf("module sources differ in mv %s %s: %s in %s, but %s in %s", source, destination, from.Source, fromDir, to.Source, toDir)
+		}
+		return ""
+	}
+
+	if from.Version != to.Version {
+		return fmt.Sprintf("module versions differ in mv %s %s: %s %s in %s, but %s in %s, which will cause diffs after the migration", source, destination, from.Source, from.Version, fromDir, to.Version, toDir)
+	}
+	return ""
+}
+
+// checkModuleMoves logs warnings for mv actions which move addresses between
+// incompatible module calls. See checkModuleMove for details.
+// The check is skipped if the module manifest cannot be read.
+func checkModuleMoves(ctx context.Context, fromDir string, toDir string, moves [][2]string) {
+	if len(moves) == 0 {
+		return
+	}
+	fromManifest, err := readModuleManifest(fromDir)
+	if err != nil {
+		logging.FromContext(ctx).Printf("[WARN] [migrator@%s] skip checking module versions: %s\n", fromDir, err)
+		return
+	}
+	toManifest := fromManifest
+	if toDir != fromDir {
+		toManifest, err = readModuleManifest(toDir)
+		if err != nil {
+			logging.FromContext(ctx).Printf("[WARN] [migrator@%s] skip checking module versions: %s\n", toDir, err)
+			return
+		}
+	}
+
+	for _, mv := range moves {
+		if warning := checkModuleMove(ctx, fromDir, fromManifest, toDir, toManifest, mv[0], mv[1]); len(warning) != 0 {
+			logging.FromContext(ctx).Printf("[WARN] [migrator@%s] %s\n", fromDir, warning)
+		}
+	}
+}
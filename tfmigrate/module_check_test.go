@@ -0,0 +1,216 @@
+package tfmigrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestModuleKey(t *testing.T) {
+	cases := []struct {
+		desc    string
+		address string
+		key     string
+		whole   bool
+	}{
+		{
+			desc:    "root module",
+			address: "aws_instance.foo",
+			key:     "",
+			whole:   false,
+		},
+		{
+			desc:    "resource in module",
+			address: "module.foo.aws_instance.bar",
+			key:     "foo",
+			whole:   false,
+		},
+		{
+			desc:    "module",
+			address: "module.foo",
+			key:     "foo",
+			whole:   true,
+		},
+		{
+			desc:    "nested module with keys",
+			address: `module.foo["a.b"].module.bar[0].aws_instance.baz`,
+			key:     "foo.bar",
+			whole:   false,
+		},
+		{
+			desc:    "module instance",
+			address: `module.foo["a"]`,
+			key:     "foo",
+			whole:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			key, whole := moduleKey(tc.address)
+			if key != tc.key || whole != tc.whole {
+				t.Errorf("got: %s, %t, want: %s, %t", key, whole, tc.key, tc.whole)
+			}
+		})
+	}
+}
+
+func TestReadModuleManifest(t *testing.T) {
+	cases := []struct {
+		desc     string
+		manifest string
+		want     moduleManifest
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			manifest: `{"Modules":[
+  {"Key":"","Source":"","Dir":"."},
+  {"Key":"vpc","Source":"registry.terraform.io/terraform-aws-modules/vpc/aws","Version":"5.1.0","Dir":".terraform/modules/vpc"}
+]}`,
+			want: moduleManifest{
+				"":    {Key: "", Source: "", Dir: "."},
+				"vpc": {Key: "vpc", Source: "registry.terraform.io/terraform-aws-modules/vpc/aws", Version: "5.1.0", Dir: ".terraform/modules/vpc"},
+			},
+			ok: true,
+		},
+		{
+			desc:     "not found",
+			manifest: "",
+			want:     nil,
+			ok:       true,
+		},
+		{
+			desc:     "invalid json",
+			manifest: "{",
+			want:     nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("TF_DATA_DIR", "")
+			dir := t.TempDir()
+			if len(tc.manifest) != 0 {
+				modulesDir := filepath.Join(dir, ".terraform", "modules")
+				if err := os.MkdirAll(modulesDir, 0755); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := os.WriteFile(filepath.Join(modulesDir, "modules.json"), []byte(tc.manifest), 0644); err != nil {
+					t.Fatalf("failed to write manifest: %s", err)
+				}
+			}
+
+			got, err := readModuleManifest(dir)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckModuleMove(t *testing.T) {
+	vpc := func(version string) moduleManifestEntry {
+		return moduleManifestEntry{Key: "vpc", Source: "registry.terraform.io/terraform-aws-modules/vpc/aws", Version: version, Dir: ".terraform/modules/vpc"}
+	}
+	local := func(key string, source string, dir string) moduleManifestEntry {
+		return moduleManifestEntry{Key: key, Source: source, Dir: dir}
+	}
+
+	cases := []struct {
+		desc         string
+		fromManifest moduleManifest
+		toManifest   moduleManifest
+		source       string
+		destination  string
+		want         string
+	}{
+		{
+			desc:         "same version",
+			fromManifest: moduleManifest{"vpc": vpc("5.1.0")},
+			toManifest:   moduleManifest{"vpc": vpc("5.1.0")},
+			source:       "module.vpc",
+			destination:  "module.vpc",
+			want:         "",
+		},
+		{
+			desc:         "different versions",
+			fromManifest: moduleManifest{"vpc": vpc("5.1.0")},
+			toManifest:   moduleManifest{"vpc": vpc("4.0.0")},
+			source:       "module.vpc",
+			destination:  "module.vpc",
+			want:         "module versions differ",
+		},
+		{
+			desc:         "different versions for a resource in module",
+			fromManifest: moduleManifest{"vpc": vpc("5.1.0")},
+			toManifest:   moduleManifest{"vpc": vpc("4.0.0")},
+			source:       "module.vpc.aws_vpc.this[0]",
+			destination:  "module.vpc.aws_vpc.this[0]",
+			want:         "module versions differ",
+		},
+		{
+			desc:         "different sources",
+			fromManifest: moduleManifest{"vpc": vpc("5.1.0")},
+			toManifest:   moduleManifest{"network": local("network", "./modules/network", "modules/network")},
+			source:       "module.vpc",
+			destination:  "module.network",
+			want:         "module sources differ",
+		},
+		{
+			desc:         "move a resource between different modules",
+			fromManifest: moduleManifest{"vpc": vpc("5.1.0")},
+			toManifest:   moduleManifest{"network": local("network", "./modules/network", "modules/network")},
+			source:       "module.vpc.aws_vpc.this[0]",
+			destination:  "module.network.aws_vpc.this",
+			want:         "",
+		},
+		{
+			desc:         "same local module from different callers",
+			fromManifest: moduleManifest{"foo": local("foo", "../modules/foo", "../modules/foo")},
+			toManifest:   moduleManifest{"foo": local("foo", "../../modules/foo", "../../modules/foo")},
+			source:       "module.foo",
+			destination:  "module.foo",
+			want:         "",
+		},
+		{
+			desc:         "root module",
+			fromManifest: moduleManifest{"vpc": vpc("5.1.0")},
+			toManifest:   moduleManifest{"vpc": vpc("4.0.0")},
+			source:       "aws_vpc.this",
+			destination:  "module.vpc.aws_vpc.this[0]",
+			want:         "",
+		},
+		{
+			desc:         "not found in manifest",
+			fromManifest: nil,
+			toManifest:   moduleManifest{"vpc": vpc("4.0.0")},
+			source:       "module.vpc",
+			destination:  "module.vpc",
+			want:         "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			// The same local module is referenced from dir1 and sub/dir2.
+			got := checkModuleMove(context.Background(), "env/dir1", tc.fromManifest, "env/sub/dir2", tc.toManifest, tc.source, tc.destination)
+			if len(tc.want) == 0 && len(got) != 0 {
+				t.Errorf("expected no warning, but got: %s", got)
+			}
+			if len(tc.want) != 0 && !strings.Contains(got, tc.want) {
+				t.Errorf("got: %q, want to contain: %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package tfmigrate
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// MovedBlock is a Terraform moved block, which declares the same refactoring
+// as a state mv action in configuration. It requires Terraform v1.1+.
+type MovedBlock struct {
+	// From is an address before the move.
+	From string
+	// To is an address after the move.
+	To string
+}
+
+// MovedBlocks returns a list of moved blocks equivalent to mv actions in a
+// given state migration, and a list of warnings for actions which cannot be
+// converted. Only mv actions can be converted to moved blocks, and the other
+// actions are skipped with a warning. Chained mv actions are kept as they are
+// because Terraform supports chained moved blocks.
+// A multi_state migration cannot be converted because moved blocks cannot
+// move resources across states.
+func MovedBlocks(mc *MigrationConfig) ([]MovedBlock, []string, error) {
+	m, ok := mc.Migrator.(*StateMigratorConfig)
+	if !ok {
+		return nil, nil, fmt.Errorf("generating moved blocks from a migration of type %s is not supported, because moved blocks cannot move resources across states", mc.Type)
+	}
+
+	blocks := []MovedBlock{}
+	warnings := []string{}
+	for _, cmdStr := range m.Actions {
+		args, err := splitStateAction(cmdStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+		}
+		if len(args) == 0 {
+			return nil, nil, fmt.Errorf("state action is empty: %s", cmdStr)
+		}
+
+		if args[0] != "mv" {
+			warnings = append(warnings, fmt.Sprintf("skip %s action which cannot be converted to a moved block: %s", args[0], cmdStr))
+			continue
+		}
+		if len(args) != 3 {
+			return nil, nil, fmt.Errorf("state mv action is invalid: %s", cmdStr)
+		}
+
+		from, err := parseMovedAddress(args[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		to, err := parseMovedAddress(args[2])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case from.data || to.data:
+			warnings = append(warnings, fmt.Sprintf("skip mv action for a data resource, which is not supported by moved blocks: %s", cmdStr))
+			continue
+		case from.module != to.module:
+			warnings = append(warnings, fmt.Sprintf("skip mv action between a module and a resource, which is not supported by moved blocks: %s", cmdStr))
+			continue
+		case from.resourceType != to.resourceType:
+			warnings = append(warnings, fmt.Sprintf("moving a resource to a different type requires Terraform v1.8+ and a provider which supports it: %s", cmdStr))
+		}
+
+		blocks = append(blocks, MovedBlock{From: args[1], To: args[2]})
+	}
+
+	return blocks, warnings, nil
+}
+
+// movedAddress is a parsed address of a moved block.
+type movedAddress struct {
+	// module is true if the address refers to a module.
+	module bool
+	// data is true if the address refers to a data resource.
+	data bool
+	// resourceType is a type of resource. It is empty for a module.
+	resourceType string
+}
+
+// parseMovedAddress parses a given address of a resource or module.
+func parseMovedAddress(address string) (movedAddress, error) {
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(address), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return movedAddress{}, fmt.Errorf("failed to parse address: %s, err: %s", address, diags)
+	}
+
+	// names is a list of names in the address without instance keys.
+	names := []string{}
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			names = append(names, s.Name)
+		case hcl.TraverseAttr:
+			names = append(names, s.Name)
+		}
+	}
+
+	// Skip module calls.
+	for len(names) >= 2 && names[0] == "module" {
+		names = names[2:]
+	}
+
+	switch {
+	case len(names) == 0:
+		return movedAddress{module: true}, nil
+	case len(names) == 3 && names[0] == "data":
+		return movedAddress{data: true, resourceType: names[1]}, nil
+	case len(names) == 2:
+		return movedAddress{resourceType: names[0]}, nil
+	default:
+		return movedAddress{}, fmt.Errorf("invalid address of resource or module: %s", address)
+	}
+}
@@ -0,0 +1,141 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMovedBlocks(t *testing.T) {
+	cases := []struct {
+		desc     string
+		mc       *MigrationConfig
+		want     []MovedBlock
+		warnings []string
+		ok       bool
+	}{
+		{
+			desc: "mv",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"mv null_resource.foo null_resource.bar",
+						"mv null_resource.bar module.baz.null_resource.bar",
+						`mv module.foo["a b"] module.qux[0]`,
+						`mv null_resource.baz[0] 'null_resource.baz["x"]'`,
+					},
+				},
+			},
+			want: []MovedBlock{
+				{From: "null_resource.foo", To: "null_resource.bar"},
+				{From: "null_resource.bar", To: "module.baz.null_resource.bar"},
+				{From: `module.foo["a b"]`, To: "module.qux[0]"},
+				{From: "null_resource.baz[0]", To: `null_resource.baz["x"]`},
+			},
+			warnings: []string{},
+			ok:       true,
+		},
+		{
+			desc: "unsupported actions",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"rm null_resource.foo",
+						"mv null_resource.foo null_resource.bar",
+						"xmv null_resource.* null_resource.new_$1",
+						"mv data.null_data_source.foo data.null_data_source.bar",
+						"mv module.foo module.bar.null_resource.foo",
+					},
+				},
+			},
+			want: []MovedBlock{
+				{From: "null_resource.foo", To: "null_resource.bar"},
+			},
+			warnings: []string{
+				"skip rm action",
+				"skip xmv action",
+				"data resource",
+				"between a module and a resource",
+			},
+			ok: true,
+		},
+		{
+			desc: "different types",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"mv aws_alb.foo aws_lb.foo",
+					},
+				},
+			},
+			want: []MovedBlock{
+				{From: "aws_alb.foo", To: "aws_lb.foo"},
+			},
+			warnings: []string{
+				"different type",
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid address",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"mv null_resource null_resource.foo",
+					},
+				},
+			},
+			want:     nil,
+			warnings: nil,
+			ok:       false,
+		},
+		{
+			desc: "multi_state",
+			mc: &MigrationConfig{
+				Type: "multi_state",
+				Name: "test",
+				Migrator: &MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{"mv null_resource.foo null_resource.foo"},
+				},
+			},
+			want:     nil,
+			warnings: nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, warnings, err := MovedBlocks(tc.mc)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+				if len(warnings) != len(tc.warnings) {
+					t.Fatalf("got warnings: %#v, want: %#v", warnings, tc.warnings)
+				}
+				for i, w := range tc.warnings {
+					if !strings.Contains(warnings[i], w) {
+						t.Errorf("got warning: %s, want to contain: %s", warnings[i], w)
+					}
+				}
+			}
+		})
+	}
+}
@@ -19,8 +19,11 @@ type MultiStateAction interface {
 // cmdStr is a plain text for state operation.
 // This method is useful to build an action from terraform state command.
 // Valid formats are the following.
-// "mv <source> <destination>"
-// "xmv <source> <destination>"
+// "mv <source> <destination> [<to_dir>]"
+// "xmv <source> <destination> [<to_dir>]"
+// The optional to_dir is not a part of the action. It is read by
+// MultiStateMigratorConfig to choose a destination state of the action.
+// The list of valid formats is defined in multiStateActionSpecs.
 func NewMultiStateActionFromString(cmdStr string) (MultiStateAction, error) {
 	args, err := splitStateAction(cmdStr)
 	if err != nil {
@@ -32,28 +35,14 @@ func NewMultiStateActionFromString(cmdStr string) (MultiStateAction, error) {
 	}
 	actionType := args[0]
 
-	// switch by action type and parse arguments and build an action.
-	var action MultiStateAction
-	switch actionType {
-	case "mv":
-		if len(args) != 3 {
-			return nil, fmt.Errorf("multi state mv action is invalid: %s", cmdStr)
-		}
-		src := args[1]
-		dst := args[2]
-		action = NewMultiStateMvAction(src, dst)
-
-	case "xmv":
-		if len(args) != 3 {
-			return nil, fmt.Errorf("multi state xmv action is invalid: %s", cmdStr)
-		}
-		src := args[1]
-		dst := args[2]
-		action = NewMultiStateXmvAction(src, dst)
-
-	default:
+	spec, ok := findMultiStateActionSpec(actionType)
+	if !ok {
 		return nil, fmt.Errorf("unknown multi state action type: %s", cmdStr)
 	}
 
-	return action, nil
+	if err := spec.validateArgs(args[1:]); err != nil {
+		return nil, fmt.Errorf("multi state %s action is invalid: %s, err: %s", actionType, cmdStr, err)
+	}
+
+	return spec.newAction(args[1:])
 }
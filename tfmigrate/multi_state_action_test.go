@@ -34,8 +34,17 @@ func TestNewMultiStateActionFromString(t *testing.T) {
 			ok:     false,
 		},
 		{
-			desc:   "mv action (3 args)",
-			cmdStr: "mv null_resource.foo null_resource.foo2  null_resource.foo3",
+			desc:   "mv action (with to_dir)",
+			cmdStr: "mv null_resource.foo null_resource.foo2 dir3",
+			want: &MultiStateMvAction{
+				source:      "null_resource.foo",
+				destination: "null_resource.foo2",
+			},
+			ok: true,
+		},
+		{
+			desc:   "mv action (4 args)",
+			cmdStr: "mv null_resource.foo null_resource.foo2 dir3 dir4",
 			want:   nil,
 			ok:     false,
 		},
@@ -61,8 +70,17 @@ func TestNewMultiStateActionFromString(t *testing.T) {
 			ok:     false,
 		},
 		{
-			desc:   "xmv action (3 args)",
-			cmdStr: "xmv null_resource.foo null_resource.foo2  null_resource.foo3",
+			desc:   "xmv action (with to_dir)",
+			cmdStr: "xmv null_resource.foo null_resource.foo2 dir3",
+			want: &MultiStateXmvAction{
+				source:      "null_resource.foo",
+				destination: "null_resource.foo2",
+			},
+			ok: true,
+		},
+		{
+			desc:   "xmv action (4 args)",
+			cmdStr: "xmv null_resource.foo null_resource.foo2 dir3 dir4",
 			want:   nil,
 			ok:     false,
 		},
@@ -1,12 +1,19 @@
 package tfmigrate
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
-	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/minamijoyo/tfmigrate/clock"
+	"github.com/minamijoyo/tfmigrate/logging"
 	"github.com/minamijoyo/tfmigrate/tfexec"
 )
 
@@ -18,6 +25,9 @@ type MultiStateMigratorConfig struct {
 	// within the from_dir.
 	FromSkipPlan bool `hcl:"from_skip_plan,optional"`
 	// ToDir is a working directory where states of resources move to.
+	// It can be overridden per action, so that resources move to more than one
+	// directory. Settings for to_dir such as to_workspace and to_env apply to
+	// all of them.
 	ToDir string `hcl:"to_dir"`
 	// ToSkipPlan controls whether or not to run and analyze Terraform plan
 	// within the to_dir.
@@ -29,11 +39,88 @@ type MultiStateMigratorConfig struct {
 	// Actions is a list of multi state action.
 	// Each action is a plain text for state operation.
 	// Valid formats are the following.
-	// "mv <source> <destination>"
-	Actions []string `hcl:"actions"`
+	// "mv <source> <destination> [<to_dir>]"
+	// "xmv <source> <destination> [<to_dir>]"
+	// In addition, action plugins defined in the config file are available.
+	// They always move resources to to_dir.
+	// It is required unless Mapping is set.
+	Actions []string `hcl:"actions,optional"`
+	// ActionConfigs is a list of action blocks, which are actions with
+	// options such as force for each action. Their commands are merged into
+	// Actions in order of declaration on parsing the migration file, and the
+	// options are applied on checking terraform plan in all directories.
+	ActionConfigs []ActionConfig `hcl:"action,block"`
 	// Force option controls behaviour in case of unexpected diff in plan.
 	// When set forces applying even if plan shows diff.
 	Force bool `hcl:"force,optional"`
+	// BatchSize is a number of actions applied at once. If set, new states
+	// are pushed for each batch of actions, and progress is recorded in a
+	// checkpoint file in the temp dir, so that a failed apply can resume from
+	// where it stopped. Default to 0, which applies all actions at once.
+	BatchSize int `hcl:"batch_size,optional"`
+	// Env is a map of environment variables passed to every terraform command
+	// in both from_dir and to_dir, such as AWS_PROFILE.
+	Env map[string]string `hcl:"env,optional"`
+	// FromEnv is a map of environment variables passed to terraform commands
+	// in from_dir. It takes precedence over Env.
+	FromEnv map[string]string `hcl:"from_env,optional"`
+	// ToEnv is a map of environment variables passed to terraform commands in
+	// to_dir. It takes precedence over Env.
+	ToEnv map[string]string `hcl:"to_env,optional"`
+	// VarFiles is a list of variable files passed to terraform plan in both
+	// from_dir and to_dir as -var-file options. A relative path is resolved
+	// from each working directory.
+	VarFiles []string `hcl:"var_files,optional"`
+	// FromVarFiles is a list of variable files passed to terraform plan in
+	// from_dir after VarFiles.
+	FromVarFiles []string `hcl:"from_var_files,optional"`
+	// ToVarFiles is a list of variable files passed to terraform plan in
+	// to_dir after VarFiles.
+	ToVarFiles []string `hcl:"to_var_files,optional"`
+	// Vars is a map of variables passed to terraform plan in both from_dir
+	// and to_dir as -var options.
+	Vars map[string]string `hcl:"vars,optional"`
+	// FromVars is a map of variables passed to terraform plan in from_dir.
+	// It takes precedence over Vars.
+	FromVars map[string]string `hcl:"from_vars,optional"`
+	// ToVars is a map of variables passed to terraform plan in to_dir.
+	// It takes precedence over Vars.
+	ToVars map[string]string `hcl:"to_vars,optional"`
+	// RollbackActions is a list of multi state actions which undo the
+	// migration by moving resources from to_dir back to from_dir.
+	// It's used by the rollback command instead of inverse actions computed
+	// from Actions.
+	RollbackActions []string `hcl:"rollback_actions,optional"`
+	// FromBackendConfig is a list of backend configurations for from_dir,
+	// which overrides the --backend-config option. If set, from_dir is
+	// initialized with them and -reconfigure before pulling the state, so that
+	// from_dir and to_dir can use backends of different types.
+	FromBackendConfig []string `hcl:"from_backend_config,optional"`
+	// ToBackendConfig is a list of backend configurations for to_dir, which
+	// overrides the --backend-config option. If set, to_dir is initialized
+	// with them and -reconfigure before pulling the state.
+	ToBackendConfig []string `hcl:"to_backend_config,optional"`
+	// FromIsBackendTerraformCloud overrides is_backend_terraform_cloud of the
+	// tfmigrate config for from_dir.
+	FromIsBackendTerraformCloud *bool `hcl:"from_is_backend_terraform_cloud,optional"`
+	// ToIsBackendTerraformCloud overrides is_backend_terraform_cloud of the
+	// tfmigrate config for to_dir.
+	ToIsBackendTerraformCloud *bool `hcl:"to_is_backend_terraform_cloud,optional"`
+	// ExpectNoChanges asserts that terraform plan has no changes in all
+	// directories after the migration. Unlike the default check, it takes
+	// precedence over Force, and unexpected changes are reported.
+	ExpectNoChanges bool `hcl:"expect_no_changes,optional"`
+	// Allow is a list of changes allowed in terraform plan in any directory
+	// after the migration in the format of <action>:<address>, such as
+	// update:aws_iam_role.foo. Any other changes fail the plan.
+	// It takes precedence over Force.
+	Allow []string `hcl:"allow,optional"`
+	// Mapping is a map of addresses in from_dir to desired addresses in
+	// to_dir, which declares an end state instead of actions.
+	// This is experimental. On every plan, only resources which have not been
+	// moved yet are moved, so that the migration can be re-run after it has
+	// been applied. It cannot be set with actions or batch_size.
+	Mapping map[string]string `hcl:"mapping,optional"`
 }
 
 // MultiStateMigratorConfig implements a MigratorConfig.
@@ -41,18 +128,64 @@ var _ MigratorConfig = (*MultiStateMigratorConfig)(nil)
 
 // NewMigrator returns a new instance of MultiStateMigrator.
 func (c *MultiStateMigratorConfig) NewMigrator(o *MigratorOption) (Migrator, error) {
-	if len(c.Actions) == 0 {
+	reconcile, err := NewReconciliation(c.Mapping, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to NewMigrator: %s", err)
+	}
+	if reconcile != nil && len(c.Actions) != 0 {
+		return nil, fmt.Errorf("failed to NewMigrator: actions cannot be set with mapping")
+	}
+
+	if len(c.Actions) == 0 && reconcile == nil {
 		return nil, fmt.Errorf("failed to NewMigrator with no actions")
 	}
 
+	if c.BatchSize < 0 {
+		return nil, fmt.Errorf("failed to NewMigrator: batch_size must not be negative: %d", c.BatchSize)
+	}
+	if reconcile != nil && c.BatchSize != 0 {
+		return nil, fmt.Errorf("failed to NewMigrator: batch_size cannot be set with mapping")
+	}
+
+	for _, env := range []map[string]string{c.Env, c.FromEnv, c.ToEnv} {
+		if err := validateEnv(env); err != nil {
+			return nil, fmt.Errorf("failed to NewMigrator: %s", err)
+		}
+	}
+
+	for _, vars := range []map[string]string{c.Vars, c.FromVars, c.ToVars} {
+		if err := validateVars(vars); err != nil {
+			return nil, fmt.Errorf("failed to NewMigrator: %s", err)
+		}
+	}
+
+	expect, err := newPlanExpectationWithActions(c.ExpectNoChanges, c.Allow, c.Force, c.ActionConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to NewMigrator: %s", err)
+	}
+	if expect != nil && (c.FromSkipPlan || c.ToSkipPlan) {
+		return nil, fmt.Errorf("failed to NewMigrator: from_skip_plan and to_skip_plan cannot be set with expect_no_changes, allow or options of actions")
+	}
+
 	// build actions from config.
+	var plugins []*ActionPluginConfig
+	if o != nil {
+		plugins = o.ActionPlugins
+	}
+
 	actions := []MultiStateAction{}
+	actionToDirs := []string{}
 	for _, cmdStr := range c.Actions {
-		action, err := NewMultiStateActionFromString(cmdStr)
+		action, err := newMultiStateActionWithPlugins(cmdStr, plugins)
 		if err != nil {
 			return nil, err
 		}
 		actions = append(actions, action)
+		toDir := c.actionToDir(cmdStr)
+		if sameDir(toDir, c.FromDir) {
+			return nil, fmt.Errorf("failed to NewMigrator: to_dir of action must be different from from_dir: %s", cmdStr)
+		}
+		actionToDirs = append(actionToDirs, toDir)
 	}
 
 	// use default workspace if not specified by user
@@ -63,7 +196,71 @@ func (c *MultiStateMigratorConfig) NewMigrator(o *MigratorOption) (Migrator, err
 		c.ToWorkspace = "default"
 	}
 
-	return NewMultiStateMigrator(c.FromDir, c.ToDir, c.FromWorkspace, c.ToWorkspace, actions, o, c.Force, c.FromSkipPlan, c.ToSkipPlan), nil
+	m := NewMultiStateMigrator(c.FromDir, c.ToDir, c.FromWorkspace, c.ToWorkspace, actions, o, c.Force, c.FromSkipPlan, c.ToSkipPlan)
+	for i, toDir := range actionToDirs {
+		m.setActionToDir(i, toDir)
+	}
+	m.batchSize = c.BatchSize
+	m.expect = expect
+	m.reconcile = reconcile
+	m.fromBackend = &backendOverride{isTerraformCloud: c.FromIsBackendTerraformCloud, config: c.FromBackendConfig}
+	m.toBackend = &backendOverride{isTerraformCloud: c.ToIsBackendTerraformCloud, config: c.ToBackendConfig}
+	appendEnv(m.fromTf, mergeEnv(c.Env, c.FromEnv))
+	for _, toTf := range m.toTfs {
+		appendEnv(toTf, mergeEnv(c.Env, c.ToEnv))
+	}
+	m.fromVarOptions = planVarOptions(o, append(append([]string{}, c.VarFiles...), c.FromVarFiles...), mergeEnv(c.Vars, c.FromVars))
+	m.toVarOptions = planVarOptions(o, append(append([]string{}, c.VarFiles...), c.ToVarFiles...), mergeEnv(c.Vars, c.ToVars))
+	return m, nil
+}
+
+// declaredActions returns a list of actions and all actions which may be
+// computed from the end state, for static checks of the migration.
+func (c *MultiStateMigratorConfig) declaredActions() []string {
+	r := &Reconciliation{Mapping: c.Mapping}
+	return append(append([]string{}, c.Actions...), r.Actions()...)
+}
+
+// actionToDir returns a directory where a given action moves resources to,
+// which is the to_dir argument of the action if any, or the to_dir of the
+// migration. An invalid action is assumed to use the to_dir of the migration,
+// because it is rejected when building the migrator.
+func (c *MultiStateMigratorConfig) actionToDir(cmdStr string) string {
+	args, err := splitStateAction(cmdStr)
+	if err != nil || len(args) != 4 {
+		return c.ToDir
+	}
+	// arguments of action plugins are opaque.
+	if _, ok := findMultiStateActionSpec(args[0]); !ok {
+		return c.ToDir
+	}
+	return args[3]
+}
+
+// ToDirs returns a list of directories where resources move to.
+// The first one is the to_dir of the migration, followed by to_dir overrides
+// of actions in order of appearance without duplicates.
+func (c *MultiStateMigratorConfig) ToDirs() []string {
+	dirs := []string{c.ToDir}
+	for _, cmdStr := range c.Actions {
+		toDir := c.actionToDir(cmdStr)
+		found := false
+		for _, dir := range dirs {
+			if sameDir(dir, toDir) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dirs = append(dirs, toDir)
+		}
+	}
+	return dirs
+}
+
+// sameDir returns true if given two paths refer to the same directory.
+func sameDir(a string, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
 }
 
 // MultiStateMigrator implements the Migrator interface.
@@ -72,8 +269,10 @@ type MultiStateMigrator struct {
 	fromTf tfexec.TerraformCLI
 	// fromSkipPlan disables the running of Terraform plan in fromDir.
 	fromSkipPlan bool
-	// fromTf is an instance of TerraformCLI which executes terraform command in a toDir.
-	toTf tfexec.TerraformCLI
+	// toTfs is a list of instances of TerraformCLI which execute terraform
+	// commands in directories where resources move to. The first one is for
+	// the toDir, and the rest are for to_dir overrides of actions.
+	toTfs []tfexec.TerraformCLI
 	// toSkipPlan disables the running of Terraform plan in toDir.
 	toSkipPlan bool
 	//fromWorkspace is the workspace from which the resource will be migrated
@@ -82,31 +281,118 @@ type MultiStateMigrator struct {
 	toWorkspace string
 	// actions is a list of multi state migration operations.
 	actions []MultiStateAction
+	// actionToTfs is a list of indexes of toTfs for each action.
+	// If it is nil, all actions move resources to the toDir.
+	actionToTfs []int
 	// o is an option for migrator.
 	// It is used for shared settings across Migrator instances.
 	o *MigratorOption
 	// force operation in case of unexpected diff
 	force bool
+	// fromVarOptions is a list of -var-file and -var options passed to
+	// terraform plan in fromDir.
+	fromVarOptions []string
+	// toVarOptions is a list of -var-file and -var options passed to
+	// terraform plan in toDir and to_dir overrides of actions.
+	toVarOptions []string
+	// results is a list of results of actions executed in the last plan.
+	results []ActionResult
+	// batchSize is a number of actions applied at once.
+	// If it is 0, all actions are applied at once.
+	batchSize int
+	// skip is a number of actions already applied in previous runs, which is
+	// restored from a checkpoint.
+	skip int
+	// pushed is a list of indexes of toTfs whose states have already been
+	// pushed in the first batch to be applied, which is restored from a
+	// checkpoint.
+	pushed []int
+	// batches is a list of new states at the end of each batch of actions
+	// executed in the last plan.
+	batches []*multiStateBatch
+	// diffs is a list of state diffs computed in the last plan.
+	diffs []StateDiff
+	// summaries is a list of plan summaries computed in the last plan.
+	summaries []PlanSummary
+	// fromBase is the remote state of fromDir pulled in the last plan, which
+	// the new state is computed from.
+	fromBase *tfexec.State
+	// toBases is a list of remote states of toTfs pulled in the last plan.
+	toBases []*tfexec.State
+	// fromBackend overrides backend settings of the option for fromDir.
+	fromBackend *backendOverride
+	// toBackend overrides backend settings of the option for toDir and to_dir
+	// overrides of actions.
+	toBackend *backendOverride
+	// expect is an expected result of terraform plan in all directories.
+	// If nil, the plans must have no changes unless force is set.
+	expect *PlanExpectation
+	// reconcile is a declared end state. If set, actions are computed from it
+	// and the current states on every plan.
+	reconcile *Reconciliation
+}
+
+// backendOverride is a set of backend settings of a working directory, which
+// overrides the option. A nil value means not overridden.
+type backendOverride struct {
+	// isTerraformCloud overrides IsBackendTerraformCloud of the option.
+	isTerraformCloud *bool
+	// config overrides BackendConfig of the option.
+	config []string
+}
+
+// backendSettings returns whether a backend is Terraform Cloud and a list of
+// backend configurations for a working directory with a given override.
+// It also returns true if the working directory has its own backend
+// configurations, which require reinitializing it with -reconfigure, because
+// it may have been initialized with a backend of a different type.
+func (m *MultiStateMigrator) backendSettings(override *backendOverride) (isBackendTerraformCloud bool, backendConfig []string, reconfigure bool) {
+	return resolveBackendSettings(m.o, override)
+}
+
+// resolveBackendSettings returns backend settings of a working directory
+// from a given option with a given override. See backendSettings for details.
+func resolveBackendSettings(o *MigratorOption, override *backendOverride) (isBackendTerraformCloud bool, backendConfig []string, reconfigure bool) {
+	if o != nil {
+		isBackendTerraformCloud = o.IsBackendTerraformCloud
+		backendConfig = o.BackendConfig
+	}
+	if override == nil {
+		return isBackendTerraformCloud, backendConfig, false
+	}
+	if override.isTerraformCloud != nil {
+		isBackendTerraformCloud = *override.isTerraformCloud
+	}
+	if len(override.config) != 0 {
+		backendConfig = override.config
+		reconfigure = true
+	}
+	return isBackendTerraformCloud, backendConfig, reconfigure
+}
+
+// multiStateBatch is a set of new states at the end of a batch of actions.
+type multiStateBatch struct {
+	// fromState is a new state of fromDir.
+	fromState *tfexec.State
+	// toStates is a list of new states for each of toTfs.
+	toStates []*tfexec.State
 }
 
 var _ Migrator = (*MultiStateMigrator)(nil)
+var _ ActionResultReporter = (*MultiStateMigrator)(nil)
+var _ StateDiffReporter = (*MultiStateMigrator)(nil)
+var _ PlanSummaryReporter = (*MultiStateMigrator)(nil)
 
 // NewMultiStateMigrator returns a new MultiStateMigrator instance.
 func NewMultiStateMigrator(fromDir string, toDir string, fromWorkspace string, toWorkspace string,
 	actions []MultiStateAction, o *MigratorOption, force bool, fromSkipPlan bool, toSkipPlan bool) *MultiStateMigrator {
-	fromTf := tfexec.NewTerraformCLI(tfexec.NewExecutor(fromDir, os.Environ()))
-	toTf := tfexec.NewTerraformCLI(tfexec.NewExecutor(toDir, os.Environ()))
-	if o != nil && len(o.ExecPath) > 0 {
-		// While NewTerraformCLI reads the environment variable TFMIGRATE_EXEC_PATH
-		// at initialization, the MigratorOption takes precedence over it.
-		fromTf.SetExecPath(o.ExecPath)
-		toTf.SetExecPath(o.ExecPath)
-	}
+	fromTf := newTerraformCLI(fromDir, o)
+	toTf := newTerraformCLI(toDir, o)
 
 	return &MultiStateMigrator{
 		fromTf:        fromTf,
 		fromSkipPlan:  fromSkipPlan,
-		toTf:          toTf,
+		toTfs:         []tfexec.TerraformCLI{toTf},
 		toSkipPlan:    toSkipPlan,
 		fromWorkspace: fromWorkspace,
 		toWorkspace:   toWorkspace,
@@ -116,13 +402,43 @@ func NewMultiStateMigrator(fromDir string, toDir string, fromWorkspace string, t
 	}
 }
 
+// setActionToDir sets a directory where the i-th action moves resources to.
+// A new TerraformCLI is created if the directory is not used yet.
+func (m *MultiStateMigrator) setActionToDir(i int, dir string) {
+	if m.actionToTfs == nil {
+		m.actionToTfs = make([]int, len(m.actions))
+	}
+	for j, toTf := range m.toTfs {
+		if sameDir(toTf.Dir(), dir) {
+			m.actionToTfs[i] = j
+			return
+		}
+	}
+	m.toTfs = append(m.toTfs, newTerraformCLI(dir, m.o))
+	m.actionToTfs[i] = len(m.toTfs) - 1
+}
+
+// actionToTf returns an index of toTfs where the i-th action moves resources to.
+func (m *MultiStateMigrator) actionToTf(i int) int {
+	if m.actionToTfs == nil {
+		return 0
+	}
+	return m.actionToTfs[i]
+}
+
 // plan computes new states by applying multi state migration operations to temporary states.
 // It will fail if terraform plan detects any diffs with at least one new state.
+// It returns a new state of fromDir and a list of new states for each of toTfs.
 // We intentionally make this method private to avoid exposing internal states and unify
 // the Migrator interface between a single and multi state migrator.
-func (m *MultiStateMigrator) plan(ctx context.Context) (fromCurrentState *tfexec.State, toCurrentState *tfexec.State, err error) {
+func (m *MultiStateMigrator) plan(ctx context.Context) (fromCurrentState *tfexec.State, toCurrentStates []*tfexec.State, err error) {
+	m.results = nil
+	m.diffs = nil
+	m.summaries = nil
+
 	// setup fromDir.
-	fromCurrentState, fromSwitchBackToRemoteFunc, err := setupWorkDir(ctx, m.fromTf, m.fromWorkspace, m.o.IsBackendTerraformCloud, m.o.BackendConfig, false)
+	fromIsBackendTerraformCloud, fromBackendConfig, fromReconfigure := m.backendSettings(m.fromBackend)
+	fromCurrentState, fromSwitchBackToRemoteFunc, err := setupWorkDir(ctx, m.fromTf, m.fromWorkspace, missingWorkspaceError, fromIsBackendTerraformCloud, fromBackendConfig, fromReconfigure, false, m.o.ProvidersMirrorDir, m.o.ProvidersLockPlatforms, m.o.Offline, m.o.stateVersion(m.fromTf.Dir()))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -131,26 +447,108 @@ func (m *MultiStateMigrator) plan(ctx context.Context) (fromCurrentState *tfexec
 		err = errors.Join(err, fromSwitchBackToRemoteFunc())
 	}()
 
-	// setup toDir.
-	toCurrentState, toSwitchBackToRemoteFunc, err := setupWorkDir(ctx, m.toTf, m.toWorkspace, m.o.IsBackendTerraformCloud, m.o.BackendConfig, false)
+	// setup toDirs.
+	toCurrentStates = make([]*tfexec.State, len(m.toTfs))
+	toDirs := make([]string, len(m.toTfs))
+	toIsBackendTerraformCloud, toBackendConfig, toReconfigure := m.backendSettings(m.toBackend)
+	for i, toTf := range m.toTfs {
+		var toSwitchBackToRemoteFunc func() error
+		toCurrentStates[i], toSwitchBackToRemoteFunc, err = setupWorkDir(ctx, toTf, m.toWorkspace, destinationMissingWorkspace(ctx, m.o), toIsBackendTerraformCloud, toBackendConfig, toReconfigure, false, m.o.ProvidersMirrorDir, m.o.ProvidersLockPlatforms, m.o.Offline, m.o.stateVersion(toTf.Dir()))
+		if err != nil {
+			return nil, nil, err
+		}
+		// switch back it to remote on exit.
+		defer func() {
+			err = errors.Join(err, toSwitchBackToRemoteFunc())
+		}()
+		toDirs[i] = toTf.Dir()
+	}
+
+	if m.reconcile != nil {
+		if err := m.reconcileActions(ctx, fromCurrentState, toCurrentStates[0]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// warn about moves between incompatible module calls.
+	moves := make([][][2]string, len(m.toTfs))
+	for i, action := range m.actions {
+		if a, ok := action.(*MultiStateMvAction); ok {
+			j := m.actionToTf(i)
+			moves[j] = append(moves[j], [2]string{a.source, a.destination})
+		}
+	}
+	for j, toTf := range m.toTfs {
+		checkModuleMoves(ctx, m.fromTf.Dir(), toTf.Dir(), moves[j])
+	}
+
+	// computes new states by applying state migration operations to temporary states.
+	logging.FromContext(ctx).Printf("[INFO] [migrator] compute new states (%s => %s)\n", m.fromTf.Dir(), strings.Join(toDirs, ", "))
+	if m.skip > 0 {
+		logging.FromContext(ctx).Printf("[INFO] [migrator] skip %d actions already applied\n", m.skip)
+	}
+	m.batches = nil
+	fromOriginalState := fromCurrentState
+	toOriginalStates := append([]*tfexec.State{}, toCurrentStates...)
+	m.fromBase = fromOriginalState
+	m.toBases = toOriginalStates
+	actions := make([]any, len(m.actions)-m.skip)
+	for i, action := range m.actions[m.skip:] {
+		actions[i] = action
+	}
+	m.results, err = runActions(ctx, actions, func(i int) error {
+		i += m.skip
+		j := m.actionToTf(i)
+		toState := toCurrentStates[j]
+		// If the to state has already been pushed in a previous run which
+		// failed in the middle of the batch, it already has the resources.
+		// Move them to a scratch state instead, so that they are only
+		// removed from the from state.
+		pushed := i < m.skip+m.batchSize && slices.Contains(m.pushed, j)
+		if pushed {
+			toState = tfexec.NewState([]byte{})
+		}
+		fromNewState, toNewState, err := m.actions[i].MultiStateUpdate(ctx, m.fromTf, m.toTfs[j], fromCurrentState, toState)
+		if err != nil {
+			return err
+		}
+		dirs := []string{m.fromTf.Dir(), m.toTfs[j].Dir()}
+		before := []*tfexec.State{fromCurrentState, toState}
+		after := []*tfexec.State{fromNewState, toNewState}
+		if err := checkStateObjects(ctx, m.actions[i], dirs, before, after); err != nil {
+			return err
+		}
+		fromCurrentState = tfexec.NewState(fromNewState.Bytes())
+		if !pushed {
+			toCurrentStates[j] = tfexec.NewState(toNewState.Bytes())
+		}
+		if m.isBatchEnd(i) {
+			m.batches = append(m.batches, &multiStateBatch{
+				fromState: fromCurrentState,
+				toStates:  append([]*tfexec.State{}, toCurrentStates...),
+			})
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, nil, err
 	}
-	// switch back it to remote on exit.
-	defer func() {
-		err = errors.Join(err, toSwitchBackToRemoteFunc())
-	}()
 
-	// computes new states by applying state migration operations to temporary states.
-	log.Printf("[INFO] [migrator] compute new states (%s => %s)\n", m.fromTf.Dir(), m.toTf.Dir())
-	var fromNewState, toNewState *tfexec.State
-	for _, action := range m.actions {
-		fromNewState, toNewState, err = action.MultiStateUpdate(ctx, m.fromTf, m.toTf, fromCurrentState, toCurrentState)
+	// list addresses before checking diffs, so that they are available even
+	// if terraform plan detects unexpected diffs.
+	if m.o.ShowDiff {
+		diff, err := newStateDiff(ctx, m.fromTf, m.fromWorkspace, fromOriginalState, fromCurrentState)
 		if err != nil {
 			return nil, nil, err
 		}
-		fromCurrentState = tfexec.NewState(fromNewState.Bytes())
-		toCurrentState = tfexec.NewState(toNewState.Bytes())
+		m.diffs = append(m.diffs, diff)
+		for i, toTf := range m.toTfs {
+			diff, err := newStateDiff(ctx, toTf, m.toWorkspace, toOriginalStates[i], toCurrentStates[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			m.diffs = append(m.diffs, diff)
+		}
 	}
 
 	// build plan options
@@ -158,91 +556,371 @@ func (m *MultiStateMigrator) plan(ctx context.Context) (fromCurrentState *tfexec
 	if m.o.PlanOut != "" {
 		planOpts = append(planOpts, "-out="+m.o.PlanOut)
 	}
+	fromPlanOpts := append(append([]string{}, planOpts...), m.fromVarOptions...)
+	toPlanOpts := append(append([]string{}, planOpts...), m.toVarOptions...)
 
 	if m.fromSkipPlan {
-		log.Printf("[INFO] [migrator@%s] skipping check diffs\n", m.fromTf.Dir())
-	} else {
-		// check if a plan in fromDir has no changes.
-		log.Printf("[INFO] [migrator@%s] check diffs\n", m.fromTf.Dir())
-		_, err = m.fromTf.Plan(ctx, fromCurrentState, planOpts...)
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] skipping check diffs\n", m.fromTf.Dir())
+	} else if err := m.checkDiffs(ctx, m.fromTf, m.fromWorkspace, fromCurrentState, "from_dir", fromPlanOpts); err != nil {
+		return nil, nil, err
+	}
+
+	// check diffs for each destination with all actions applied to it.
+	for i, toTf := range m.toTfs {
+		if m.toSkipPlan {
+			logging.FromContext(ctx).Printf("[INFO] [migrator@%s] skipping check diffs\n", toTf.Dir())
+			continue
+		}
+		if err := m.checkDiffs(ctx, toTf, m.toWorkspace, toCurrentStates[i], "to_dir", toPlanOpts); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return fromCurrentState, toCurrentStates, nil
+}
+
+// reconcileActions replaces actions with ones computed from the end state and
+// given current states of fromDir and toDir.
+func (m *MultiStateMigrator) reconcileActions(ctx context.Context, fromState *tfexec.State, toState *tfexec.State) error {
+	fromExists, err := stateAddressExistsFunc(ctx, m.fromTf, fromState)
+	if err != nil {
+		return err
+	}
+	toExists, err := stateAddressExistsFunc(ctx, m.toTfs[0], toState)
+	if err != nil {
+		return err
+	}
+	cmdStrs, err := m.reconcile.resolve(fromExists, toExists)
+	if err != nil {
+		return err
+	}
+	logReconciledActions(ctx, m.fromTf.Dir(), cmdStrs)
+
+	m.actions = []MultiStateAction{}
+	m.actionToTfs = nil
+	for _, cmdStr := range cmdStrs {
+		action, err := NewMultiStateActionFromString(cmdStr)
 		if err != nil {
-			if exitErr, ok := err.(tfexec.ExitError); ok && exitErr.ExitCode() == 2 {
-				if !m.force {
-					log.Printf("[ERROR] [migrator@%s] unexpected diffs\n", m.fromTf.Dir())
-					return nil, nil, fmt.Errorf("terraform plan command returns unexpected diffs in %s from_dir: %s", m.fromTf.Dir(), err)
-				}
-				log.Printf("[INFO] [migrator@%s] unexpected diffs, ignoring as force option is true: %s", m.fromTf.Dir(), err)
-				// reset err to nil to intentionally ignore unexpected diffs.
-				err = nil
-			} else {
-				return nil, nil, err
-			}
+			return err
 		}
+		m.actions = append(m.actions, action)
 	}
+	return nil
+}
 
-	if m.toSkipPlan {
-		log.Printf("[INFO] [migrator@%s] skipping check diffs\n", m.toTf.Dir())
-	} else {
-		// check if a plan in toDir has no changes.
-		log.Printf("[INFO] [migrator@%s] check diffs\n", m.toTf.Dir())
-		_, err = m.toTf.Plan(ctx, toCurrentState, planOpts...)
+// checkDiffs checks if a plan in a given directory has no changes with a
+// given state. Unexpected diffs are ignored if the force option is true.
+// If expectations are declared, changes are checked against them instead.
+// The kind is either from_dir or to_dir, which is used in an error message.
+// If the PlanSummary option is set, a summary of the plan is recorded.
+func (m *MultiStateMigrator) checkDiffs(ctx context.Context, tf tfexec.TerraformCLI, workspace string, state *tfexec.State, kind string, planOpts []string) error {
+	if m.expect != nil {
+		plan, err := checkPlanExpectation(ctx, tf, state, m.expect, planOpts)
+		if plan != nil && m.o.PlanSummary {
+			m.summaries = append(m.summaries, newPlanSummary(tf.Dir(), workspace, plan))
+		}
+		return err
+	}
+
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] check diffs\n", tf.Dir())
+	plan, err := tf.Plan(ctx, state, planOpts...)
+	if m.o.PlanSummary && (err == nil || isPlanDiffs(err)) {
+		summary, err := summarizePlan(ctx, tf, workspace, plan)
 		if err != nil {
-			if exitErr, ok := err.(tfexec.ExitError); ok && exitErr.ExitCode() == 2 {
-				if !m.force {
-					log.Printf("[ERROR] [migrator@%s] unexpected diffs\n", m.toTf.Dir())
-					return nil, nil, fmt.Errorf("terraform plan command returns unexpected diffs in %s to_dir: %s", m.toTf.Dir(), err)
-				}
-				log.Printf("[INFO] [migrator@%s] unexpected diffs, ignoring as force option is true: %s", m.toTf.Dir(), err)
-				// reset err to nil to intentionally ignore unexpected diffs.
-				err = nil
-			} else {
-				return nil, nil, err
+			return err
+		}
+		m.summaries = append(m.summaries, summary)
+	}
+	if err != nil {
+		if isPlanDiffs(err) {
+			if !m.force {
+				logging.FromContext(ctx).Printf("[ERROR] [migrator@%s] unexpected diffs\n", tf.Dir())
+				return fmt.Errorf("terraform plan command returns unexpected diffs in %s %s: %s", tf.Dir(), kind, err)
 			}
+			logging.FromContext(ctx).Printf("[INFO] [migrator@%s] unexpected diffs, ignoring as force option is true: %s", tf.Dir(), err)
+			// intentionally ignore unexpected diffs.
+			return nil
 		}
+		return err
 	}
-
-	return fromCurrentState, toCurrentState, err
+	return nil
 }
 
 // Plan computes new states by applying multi state migration operations to temporary states.
 // It will fail if terraform plan detects any diffs with at least one new state.
 func (m *MultiStateMigrator) Plan(ctx context.Context) error {
-	log.Printf("[INFO] [migrator] multi start state migrator plan\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] multi start state migrator plan\n")
+	if err := m.loadCheckpoint(ctx); err != nil {
+		return err
+	}
 	_, _, err := m.plan(ctx)
 	if err != nil {
 		return err
 	}
-	log.Printf("[INFO] [migrator] multi state migrator plan success!\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] multi state migrator plan success!\n")
 	return nil
 }
 
+// ActionResults returns a list of results of actions executed in the last
+// Plan or Apply.
+func (m *MultiStateMigrator) ActionResults() []ActionResult {
+	return m.results
+}
+
+// StateDiffs returns a list of state diffs computed in the last Plan or Apply.
+// The first one is for the fromDir, and the rest are for directories where
+// resources move to.
+func (m *MultiStateMigrator) StateDiffs() []StateDiff {
+	return m.diffs
+}
+
+// PlanSummaries returns a list of plan summaries computed in the last Plan or
+// Apply. Unlike StateDiffs, a directory where terraform plan is skipped has
+// no summary.
+func (m *MultiStateMigrator) PlanSummaries() []PlanSummary {
+	return m.summaries
+}
+
 // Apply computes new states and pushes them to remote states.
 // It will fail if terraform plan detects any diffs with at least one new state.
 // We are intended to this is used for state refactoring.
 // Any state migration operations should not break any real resources.
 func (m *MultiStateMigrator) Apply(ctx context.Context) error {
+	ctx = withApplyMode(ctx)
 	// Check if new states don't have any diffs compared to real resources
 	// before push new states to remote.
-	log.Printf("[INFO] [migrator] start multi state migrator plan phase for apply\n")
-	fromState, toState, err := m.plan(ctx)
+	logging.FromContext(ctx).Printf("[INFO] [migrator] start multi state migrator plan phase for apply\n")
+	if err := m.loadCheckpoint(ctx); err != nil {
+		return err
+	}
+	fromState, toStates, err := m.plan(ctx)
 	if err != nil {
 		return err
 	}
 
+	if m.batchSize > 0 {
+		logging.FromContext(ctx).Printf("[INFO] [migrator] start multi state migrator apply phase in batches\n")
+		if err := m.applyBatches(ctx); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Printf("[INFO] [migrator] multi state migrator apply success!\n")
+		return nil
+	}
+
 	// push the new states to remote.
-	// We push toState before fromState, because when moving resources across
-	// states, write them to new state first and then remove them from old one.
-	log.Printf("[INFO] [migrator] start multi state migrator apply phase\n")
-	log.Printf("[INFO] [migrator@%s] push the new state to remote\n", m.toTf.Dir())
-	err = m.toTf.StatePush(ctx, toState)
+	// We push toStates before fromState, because when moving resources across
+	// states, write them to new states first and then remove them from old one.
+	logging.FromContext(ctx).Printf("[INFO] [migrator] start multi state migrator apply phase\n")
+	for i, toTf := range m.toTfs {
+		err = pushState(ctx, toTf, toStates[i], m.toBases[i], m.toWorkspace, m.o)
+		if err != nil {
+			return err
+		}
+	}
+	err = pushState(ctx, m.fromTf, fromState, m.fromBase, m.fromWorkspace, m.o)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Printf("[INFO] [migrator] multi state migrator apply success!\n")
+	return nil
+}
+
+// isBatchEnd returns true if the i-th action is the last one of a batch.
+func (m *MultiStateMigrator) isBatchEnd(i int) bool {
+	if m.batchSize == 0 {
+		return false
+	}
+	return (i+1)%m.batchSize == 0 || i+1 == len(m.actions)
+}
+
+// useCheckpoint returns true if progress of batches is recorded in a
+// checkpoint. It is not used in sandbox mode, because remote states are
+// never updated.
+func (m *MultiStateMigrator) useCheckpoint() bool {
+	return m.batchSize > 0 && len(m.o.SandboxDir) == 0
+}
+
+// digest returns a digest of the migration which identifies a checkpoint.
+func (m *MultiStateMigrator) digest() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s@%s\n", m.fromTf.Dir(), m.fromWorkspace)
+	fmt.Fprintf(h, "workspace=%s batch_size=%d\n", m.toWorkspace, m.batchSize)
+	for i, action := range m.actions {
+		fmt.Fprintf(h, "%s => %s\n", action, m.toTfs[m.actionToTf(i)].Dir())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checkpointPath returns a path to the checkpoint of the migration.
+func (m *MultiStateMigrator) checkpointPath() (string, error) {
+	return CheckpointPath(m.o.TempDir, m.fromTf.Dir(), m.fromWorkspace)
+}
+
+// loadCheckpoint restores a number of actions already applied and states
+// already pushed from a checkpoint if any.
+func (m *MultiStateMigrator) loadCheckpoint(ctx context.Context) error {
+	m.skip = 0
+	m.pushed = nil
+	if !m.useCheckpoint() {
+		return nil
+	}
+
+	path, err := m.checkpointPath()
 	if err != nil {
 		return err
 	}
-	log.Printf("[INFO] [migrator@%s] push the new state to remote\n", m.fromTf.Dir())
-	err = m.fromTf.StatePush(ctx, fromState)
+	c, err := ReadCheckpoint(path)
 	if err != nil {
 		return err
 	}
-	log.Printf("[INFO] [migrator] multi state migrator apply success!\n")
+	if c == nil {
+		return nil
+	}
+
+	if c.Digest != m.digest() {
+		return fmt.Errorf("checkpoint %s doesn't match the migration in %s, which has been changed since the last apply. Remove it if you are sure that no batches were applied", path, m.fromTf.Dir())
+	}
+	if c.Batches < 0 || c.Batches*m.batchSize >= len(m.actions) {
+		return fmt.Errorf("invalid checkpoint %s: batches = %d", path, c.Batches)
+	}
+	for _, i := range c.PushedToStates {
+		if i < 0 || i >= len(m.toTfs) {
+			return fmt.Errorf("invalid checkpoint %s: pushed_to_states = %v", path, c.PushedToStates)
+		}
+	}
+
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] resume from batch %d, %d batches were applied at %s\n", m.fromTf.Dir(), c.Batches+1, c.Batches, c.UpdatedAt.Format(time.RFC3339))
+	for _, i := range c.PushedToStates {
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] the state of %s has already been pushed in batch %d\n", m.fromTf.Dir(), m.toTfs[i].Dir(), c.Batches+1)
+	}
+	m.skip = c.Batches * m.batchSize
+	m.pushed = c.PushedToStates
 	return nil
 }
+
+// writeCheckpoint records a number of batches applied and a list of indexes
+// of to states pushed in the next batch.
+func (m *MultiStateMigrator) writeCheckpoint(ctx context.Context, path string, batches int, pushed []int) error {
+	c := &Checkpoint{
+		Digest:         m.digest(),
+		Batches:        batches,
+		PushedToStates: pushed,
+		UpdatedAt:      clock.Now(ctx).UTC(),
+	}
+	return writeCheckpoint(path, c)
+}
+
+// applyBatches pushes new states for each batch computed in the last plan,
+// and verifies that remote states match them. The whole migration is verified
+// with terraform plan before pushing the first batch, because an intermediate
+// state is expected to have diffs against the configuration for the final
+// state. After each state push, progress is recorded in a checkpoint, so that
+// a failed apply can resume without pushing the same changes twice.
+func (m *MultiStateMigrator) applyBatches(ctx context.Context) error {
+	var path string
+	if m.useCheckpoint() {
+		var err error
+		path, err = m.checkpointPath()
+		if err != nil {
+			return err
+		}
+	}
+
+	total := (len(m.actions) + m.batchSize - 1) / m.batchSize
+	done := m.skip / m.batchSize
+	pushed := append([]int{}, m.pushed...)
+	prevToStates := append([]*tfexec.State{}, m.toBases...)
+	fromBase := m.fromBase
+	toBases := append([]*tfexec.State{}, m.toBases...)
+	for k, b := range m.batches {
+		logging.FromContext(ctx).Printf("[INFO] [migrator] apply batch %d/%d\n", done+k+1, total)
+		// We push toStates before fromState for the same reason as Apply.
+		for i, toTf := range m.toTfs {
+			// skip a state which is not changed in this batch, including one
+			// already pushed in a previous run.
+			if bytes.Equal(prevToStates[i].Bytes(), b.toStates[i].Bytes()) {
+				continue
+			}
+			remoteState, err := m.pushBatchState(ctx, toTf, b.toStates[i], toBases[i], m.toWorkspace)
+			if err != nil {
+				return err
+			}
+			toBases[i] = remoteState
+			pushed = append(pushed, i)
+			if m.useCheckpoint() {
+				if err := m.writeCheckpoint(ctx, path, done+k, pushed); err != nil {
+					return err
+				}
+			}
+		}
+		remoteState, err := m.pushBatchState(ctx, m.fromTf, b.fromState, fromBase, m.fromWorkspace)
+		if err != nil {
+			return err
+		}
+		fromBase = remoteState
+		prevToStates = b.toStates
+		pushed = nil
+
+		if m.useCheckpoint() {
+			if err := m.writeCheckpoint(ctx, path, done+k+1, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.useCheckpoint() {
+		return removeCheckpoint(path)
+	}
+	return nil
+}
+
+// pushBatchState pushes a given state to remote, and verifies that the remote
+// state has the same resource instances as the given state. The base is the
+// remote state which the new state was computed from. It returns the remote
+// state after the push, which is the base for the next batch.
+func (m *MultiStateMigrator) pushBatchState(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State, base *tfexec.State, workspace string) (*tfexec.State, error) {
+	if err := pushState(ctx, tf, state, base, workspace, m.o); err != nil {
+		return nil, err
+	}
+	if len(m.o.SandboxDir) != 0 {
+		return state, nil
+	}
+
+	remoteState, err := tf.StatePull(ctx)
+	if err != nil {
+		return nil, err
+	}
+	want, err := tfexec.StateObjects(state)
+	if err != nil {
+		logging.FromContext(ctx).Printf("[WARN] [migrator@%s] skip verifying the remote state: %s\n", tf.Dir(), err)
+		return remoteState, nil
+	}
+	got, err := tfexec.StateObjects(remoteState)
+	if err != nil {
+		logging.FromContext(ctx).Printf("[WARN] [migrator@%s] skip verifying the remote state: %s\n", tf.Dir(), err)
+		return remoteState, nil
+	}
+	if !sameStateObjects(got, want) {
+		return nil, fmt.Errorf("the remote state in %s doesn't match the pushed state", tf.Dir())
+	}
+	return remoteState, nil
+}
+
+// sameStateObjects returns true if given two lists of objects have the same
+// objects regardless of their order.
+func sameStateObjects(a []tfexec.StateObject, b []tfexec.StateObject) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := make(map[tfexec.StateObject]int)
+	for _, o := range a {
+		count[o]++
+	}
+	for _, o := range b {
+		if count[o] == 0 {
+			return false
+		}
+		count[o]--
+	}
+	return true
+}
@@ -101,6 +101,59 @@ func TestMultiStateMigratorConfigNewMigrator(t *testing.T) {
 			},
 			ok: true,
 		},
+		{
+			desc: "valid with to_dir override",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+					"mv null_resource.bar null_resource.bar2 dir3",
+					"xmv null_resource.baz* null_resource.$1 dir4",
+				},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "to_dir override same as from_dir",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2 ./dir1",
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "valid with batch_size",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+					"mv null_resource.bar null_resource.bar2",
+				},
+				BatchSize: 1,
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "negative batch_size",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				BatchSize: -1,
+			},
+			o:  nil,
+			ok: false,
+		},
 		{
 			desc: "invalid action",
 			config: &MultiStateMigratorConfig{
@@ -143,6 +196,127 @@ func TestMultiStateMigratorConfigNewMigrator(t *testing.T) {
 			o:  nil,
 			ok: true,
 		},
+		{
+			desc: "with env",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Env: map[string]string{
+					"TF_VAR_region": "eu-west-1",
+				},
+				FromEnv: map[string]string{
+					"AWS_PROFILE": "legacy",
+				},
+				ToEnv: map[string]string{
+					"AWS_PROFILE": "new",
+				},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "invalid from_env name",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				FromEnv: map[string]string{
+					"": "legacy",
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "invalid to_vars name",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				ToVars: map[string]string{
+					"": "ap-northeast-1",
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "with expect_no_changes",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				ExpectNoChanges: true,
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "allow with to_skip_plan",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Allow:      []string{"update:null_resource.bar"},
+				ToSkipPlan: true,
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "with mapping",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "mapping with actions",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Mapping: map[string]string{"null_resource.bar": "null_resource.bar2"},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "mapping with batch_size",
+			config: &MultiStateMigratorConfig{
+				FromDir:   "dir1",
+				ToDir:     "dir2",
+				Mapping:   map[string]string{"null_resource.foo": "null_resource.foo2"},
+				BatchSize: 1,
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "no actions",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+			},
+			o:  nil,
+			ok: false,
+		},
 	}
 
 	for _, tc := range cases {
@@ -161,6 +335,329 @@ func TestMultiStateMigratorConfigNewMigrator(t *testing.T) {
 	}
 }
 
+func TestMultiStateMigratorConfigNewMigratorWithVars(t *testing.T) {
+	config := &MultiStateMigratorConfig{
+		FromDir: "dir1",
+		ToDir:   "dir2",
+		Actions: []string{
+			"mv null_resource.foo null_resource.foo2",
+		},
+		VarFiles:     []string{"common.tfvars"},
+		FromVarFiles: []string{"legacy.tfvars"},
+		Vars: map[string]string{
+			"region": "eu-west-1",
+		},
+		ToVars: map[string]string{
+			"region": "ap-northeast-1",
+		},
+	}
+	o := &MigratorOption{
+		Vars: map[string]string{
+			"env": "prod",
+		},
+	}
+
+	got, err := config.NewMigrator(o)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	m := got.(*MultiStateMigrator)
+
+	wantFrom := []string{"-var-file=common.tfvars", "-var-file=legacy.tfvars", "-var=env=prod", "-var=region=eu-west-1"}
+	if !reflect.DeepEqual(m.fromVarOptions, wantFrom) {
+		t.Errorf("got = %v, but want = %v", m.fromVarOptions, wantFrom)
+	}
+	wantTo := []string{"-var-file=common.tfvars", "-var=env=prod", "-var=region=ap-northeast-1"}
+	if !reflect.DeepEqual(m.toVarOptions, wantTo) {
+		t.Errorf("got = %v, but want = %v", m.toVarOptions, wantTo)
+	}
+}
+
+func TestMultiStateMigratorConfigNewMigratorWithToDirOverride(t *testing.T) {
+	config := &MultiStateMigratorConfig{
+		FromDir: "dir1",
+		ToDir:   "dir2",
+		Actions: []string{
+			"mv null_resource.foo null_resource.foo",
+			"mv null_resource.bar null_resource.bar dir3",
+			"mv null_resource.baz null_resource.baz ./dir2",
+			"xmv null_resource.qux* null_resource.$1 dir3/",
+		},
+	}
+
+	wantToDirs := []string{"dir2", "dir3"}
+	if got := config.ToDirs(); !reflect.DeepEqual(got, wantToDirs) {
+		t.Errorf("got = %v, but want = %v", got, wantToDirs)
+	}
+
+	got, err := config.NewMigrator(&MigratorOption{})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	m := got.(*MultiStateMigrator)
+
+	gotDirs := []string{}
+	for _, tf := range m.toTfs {
+		gotDirs = append(gotDirs, tf.Dir())
+	}
+	if !reflect.DeepEqual(gotDirs, wantToDirs) {
+		t.Errorf("got = %v, but want = %v", gotDirs, wantToDirs)
+	}
+	wantActionToTfs := []int{0, 1, 0, 1}
+	if !reflect.DeepEqual(m.actionToTfs, wantActionToTfs) {
+		t.Errorf("got = %v, but want = %v", m.actionToTfs, wantActionToTfs)
+	}
+}
+
+func TestMultiStateMigratorBackendSettings(t *testing.T) {
+	isTFC := true
+	isNotTFC := false
+	cases := []struct {
+		desc           string
+		config         *MultiStateMigratorConfig
+		o              *MigratorOption
+		wantFromTFC    bool
+		wantFromConfig []string
+		wantFromReinit bool
+		wantToTFC      bool
+		wantToConfig   []string
+		wantToReinit   bool
+	}{
+		{
+			desc: "not overridden",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo",
+				},
+			},
+			o: &MigratorOption{
+				IsBackendTerraformCloud: true,
+				BackendConfig:           []string{"prefix=foo"},
+			},
+			wantFromTFC:    true,
+			wantFromConfig: []string{"prefix=foo"},
+			wantFromReinit: false,
+			wantToTFC:      true,
+			wantToConfig:   []string{"prefix=foo"},
+			wantToReinit:   false,
+		},
+		{
+			desc: "from s3 to tfc",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo",
+				},
+				FromBackendConfig:         []string{"s3.tfbackend"},
+				ToIsBackendTerraformCloud: &isTFC,
+			},
+			o: &MigratorOption{
+				BackendConfig: []string{"prefix=foo"},
+			},
+			wantFromTFC:    false,
+			wantFromConfig: []string{"s3.tfbackend"},
+			wantFromReinit: true,
+			wantToTFC:      true,
+			wantToConfig:   []string{"prefix=foo"},
+			wantToReinit:   false,
+		},
+		{
+			desc: "from tfc to gcs",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo",
+				},
+				ToBackendConfig:           []string{"bucket=foo", "prefix=bar"},
+				ToIsBackendTerraformCloud: &isNotTFC,
+			},
+			o: &MigratorOption{
+				IsBackendTerraformCloud: true,
+			},
+			wantFromTFC:    true,
+			wantFromConfig: nil,
+			wantFromReinit: false,
+			wantToTFC:      false,
+			wantToConfig:   []string{"bucket=foo", "prefix=bar"},
+			wantToReinit:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.config.NewMigrator(tc.o)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			m := got.(*MultiStateMigrator)
+
+			gotTFC, gotConfig, gotReinit := m.backendSettings(m.fromBackend)
+			if gotTFC != tc.wantFromTFC || !reflect.DeepEqual(gotConfig, tc.wantFromConfig) || gotReinit != tc.wantFromReinit {
+				t.Errorf("from_dir: got = (%t, %v, %t), but want = (%t, %v, %t)", gotTFC, gotConfig, gotReinit, tc.wantFromTFC, tc.wantFromConfig, tc.wantFromReinit)
+			}
+			gotTFC, gotConfig, gotReinit = m.backendSettings(m.toBackend)
+			if gotTFC != tc.wantToTFC || !reflect.DeepEqual(gotConfig, tc.wantToConfig) || gotReinit != tc.wantToReinit {
+				t.Errorf("to_dir: got = (%t, %v, %t), but want = (%t, %v, %t)", gotTFC, gotConfig, gotReinit, tc.wantToTFC, tc.wantToConfig, tc.wantToReinit)
+			}
+		})
+	}
+}
+
+func TestMultiStateMigratorIsBatchEnd(t *testing.T) {
+	actions := []MultiStateAction{
+		NewMultiStateMvAction("null_resource.foo", "null_resource.foo"),
+		NewMultiStateMvAction("null_resource.bar", "null_resource.bar"),
+		NewMultiStateMvAction("null_resource.baz", "null_resource.baz"),
+	}
+	cases := []struct {
+		desc      string
+		batchSize int
+		want      []bool
+	}{
+		{
+			desc:      "no batches",
+			batchSize: 0,
+			want:      []bool{false, false, false},
+		},
+		{
+			desc:      "batch size 2",
+			batchSize: 2,
+			want:      []bool{false, true, true},
+		},
+		{
+			desc:      "batch size larger than actions",
+			batchSize: 5,
+			want:      []bool{false, false, true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			m := NewMultiStateMigrator("dir1", "dir2", "default", "default", actions, &MigratorOption{}, false, false, false)
+			m.batchSize = tc.batchSize
+			got := []bool{}
+			for i := range actions {
+				got = append(got, m.isBatchEnd(i))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got = %v, but want = %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMultiStateMigratorLoadCheckpoint(t *testing.T) {
+	actions := []MultiStateAction{
+		NewMultiStateMvAction("null_resource.foo", "null_resource.foo"),
+		NewMultiStateMvAction("null_resource.bar", "null_resource.bar"),
+		NewMultiStateMvAction("null_resource.baz", "null_resource.baz"),
+	}
+	cases := []struct {
+		desc       string
+		o          *MigratorOption
+		batches    int
+		pushed     []int
+		digest     string
+		noFile     bool
+		want       int
+		wantPushed []int
+		ok         bool
+	}{
+		{
+			desc:   "no checkpoint",
+			o:      &MigratorOption{},
+			noFile: true,
+			want:   0,
+			ok:     true,
+		},
+		{
+			desc:    "resume",
+			o:       &MigratorOption{},
+			batches: 1,
+			want:    2,
+			ok:      true,
+		},
+		{
+			desc:       "resume a partially pushed batch",
+			o:          &MigratorOption{},
+			batches:    1,
+			pushed:     []int{0},
+			want:       2,
+			wantPushed: []int{0},
+			ok:         true,
+		},
+		{
+			desc:    "invalid pushed to states",
+			o:       &MigratorOption{},
+			batches: 1,
+			pushed:  []int{1},
+			want:    0,
+			ok:      false,
+		},
+		{
+			desc:    "digest mismatch",
+			o:       &MigratorOption{},
+			batches: 1,
+			digest:  "foo",
+			want:    0,
+			ok:      false,
+		},
+		{
+			desc:    "all batches applied",
+			o:       &MigratorOption{},
+			batches: 2,
+			want:    0,
+			ok:      false,
+		},
+		{
+			desc:    "sandbox",
+			o:       &MigratorOption{SandboxDir: "sandbox"},
+			batches: 1,
+			want:    0,
+			ok:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.o.TempDir = t.TempDir()
+			m := NewMultiStateMigrator("dir1", "dir2", "default", "default", actions, tc.o, false, false, false)
+			m.batchSize = 2
+			if !tc.noFile {
+				digest := tc.digest
+				if len(digest) == 0 {
+					digest = m.digest()
+				}
+				path, err := m.checkpointPath()
+				if err != nil {
+					t.Fatalf("failed to get checkpoint path: %s", err)
+				}
+				if err := writeCheckpoint(path, &Checkpoint{Digest: digest, Batches: tc.batches, PushedToStates: tc.pushed}); err != nil {
+					t.Fatalf("failed to write checkpoint: %s", err)
+				}
+			}
+
+			err := m.loadCheckpoint(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && m.skip != tc.want {
+				t.Errorf("got = %d, but want = %d", m.skip, tc.want)
+			}
+			if tc.ok && !reflect.DeepEqual(m.pushed, tc.wantPushed) {
+				t.Errorf("got pushed = %v, but want = %v", m.pushed, tc.wantPushed)
+			}
+		})
+	}
+}
+
 func TestAccMultiStateMigratorApplySimple(t *testing.T) {
 	tfexec.SkipUnlessAcceptanceTestEnabled(t)
 	ctx := context.Background()
@@ -275,6 +772,302 @@ resource "null_resource" "qux" {}
 	}
 }
 
+func TestAccMultiStateMigratorApplyWithToDirOverride(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+	ctx := context.Background()
+
+	// setup the initial files and states
+	fromBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/fromDir")
+	fromSource := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+`
+	workspace := "default"
+	fromTf := tfexec.SetupTestAccWithApply(t, workspace, fromBackend+fromSource)
+
+	toBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/toDir")
+	toSource := `
+resource "null_resource" "qux" {}
+`
+	toTf := tfexec.SetupTestAccWithApply(t, workspace, toBackend+toSource)
+
+	to2Backend := tfexec.GetTestAccBackendS3Config(t.Name() + "/to2Dir")
+	to2Source := `
+resource "null_resource" "quux" {}
+`
+	to2Tf := tfexec.SetupTestAccWithApply(t, workspace, to2Backend+to2Source)
+
+	// update terraform resource files for migration
+	fromUpdatedSource := `
+resource "null_resource" "baz" {}
+`
+	tfexec.UpdateTestAccSource(t, fromTf, fromBackend+fromUpdatedSource)
+
+	toUpdatedSource := `
+resource "null_resource" "foo" {}
+resource "null_resource" "qux" {}
+`
+	tfexec.UpdateTestAccSource(t, toTf, toBackend+toUpdatedSource)
+
+	to2UpdatedSource := `
+resource "null_resource" "bar2" {}
+resource "null_resource" "quux" {}
+`
+	tfexec.UpdateTestAccSource(t, to2Tf, to2Backend+to2UpdatedSource)
+
+	// perform state migration
+	config := &MultiStateMigratorConfig{
+		FromDir: fromTf.Dir(),
+		ToDir:   toTf.Dir(),
+		Actions: []string{
+			"mv null_resource.foo null_resource.foo",
+			"mv null_resource.bar null_resource.bar2 " + to2Tf.Dir(),
+		},
+	}
+	m, err := config.NewMigrator(&MigratorOption{})
+	if err != nil {
+		t.Fatalf("failed to new migrator: %s", err)
+	}
+	err = m.Plan(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+
+	err = m.Apply(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator apply: %s", err)
+	}
+
+	// verify state migration results
+	cases := []struct {
+		tf   tfexec.TerraformCLI
+		want []string
+	}{
+		{tf: fromTf, want: []string{"null_resource.baz"}},
+		{tf: toTf, want: []string{"null_resource.foo", "null_resource.qux"}},
+		{tf: to2Tf, want: []string{"null_resource.bar2", "null_resource.quux"}},
+	}
+	for _, tc := range cases {
+		got, err := tc.tf.StateList(ctx, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to run terraform state list in %s: %s", tc.tf.Dir(), err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("got state: %v, want state: %v in %s", got, tc.want, tc.tf.Dir())
+		}
+
+		changed, err := tc.tf.PlanHasChange(ctx, nil)
+		if err != nil {
+			t.Fatalf("failed to run PlanHasChange in %s: %s", tc.tf.Dir(), err)
+		}
+		if changed {
+			t.Errorf("expect not to have changes in %s", tc.tf.Dir())
+		}
+	}
+}
+
+func TestAccMultiStateMigratorApplyWithBatchSize(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+	ctx := context.Background()
+
+	// setup the initial files and states
+	fromBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/fromDir")
+	fromSource := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+`
+	workspace := "default"
+	fromTf := tfexec.SetupTestAccWithApply(t, workspace, fromBackend+fromSource)
+
+	toBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/toDir")
+	toSource := `
+resource "null_resource" "qux" {}
+`
+	toTf := tfexec.SetupTestAccWithApply(t, workspace, toBackend+toSource)
+
+	// update terraform resource files for migration
+	tfexec.UpdateTestAccSource(t, fromTf, fromBackend)
+	toUpdatedSource := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+resource "null_resource" "qux" {}
+`
+	tfexec.UpdateTestAccSource(t, toTf, toBackend+toUpdatedSource)
+
+	// perform state migration
+	actions := []MultiStateAction{
+		NewMultiStateMvAction("null_resource.foo", "null_resource.foo"),
+		NewMultiStateMvAction("null_resource.bar", "null_resource.bar"),
+		NewMultiStateMvAction("null_resource.baz", "null_resource.baz"),
+	}
+	o := &MigratorOption{TempDir: t.TempDir()}
+	m := NewMultiStateMigrator(fromTf.Dir(), toTf.Dir(), workspace, workspace, actions, o, false, false, false)
+	m.batchSize = 2
+
+	// simulate that the first batch was applied in a previous run.
+	// force is required because an intermediate state has diffs.
+	first := NewMultiStateMigrator(fromTf.Dir(), toTf.Dir(), workspace, workspace, actions[:2], o, true, false, false)
+	if err := first.Apply(ctx); err != nil {
+		t.Fatalf("failed to apply the first batch: %s", err)
+	}
+	path, err := m.checkpointPath()
+	if err != nil {
+		t.Fatalf("failed to get checkpoint path: %s", err)
+	}
+	if err := writeCheckpoint(path, &Checkpoint{Digest: m.digest(), Batches: 1}); err != nil {
+		t.Fatalf("failed to write checkpoint: %s", err)
+	}
+
+	err = m.Plan(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+
+	err = m.Apply(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator apply: %s", err)
+	}
+
+	// verify state migration results
+	fromGot, err := fromTf.StateList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to run terraform state list in fromDir: %s", err)
+	}
+	if len(fromGot) != 0 {
+		t.Errorf("got state: %v, want empty state in fromDir", fromGot)
+	}
+
+	toGot, err := toTf.StateList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to run terraform state list in toDir: %s", err)
+	}
+	toWant := []string{
+		"null_resource.bar",
+		"null_resource.baz",
+		"null_resource.foo",
+		"null_resource.qux",
+	}
+	sort.Strings(toGot)
+	if !reflect.DeepEqual(toGot, toWant) {
+		t.Errorf("got state: %v, want state: %v in toDir", toGot, toWant)
+	}
+
+	c, err := ReadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %s", err)
+	}
+	if c != nil {
+		t.Errorf("expect checkpoint to be removed, but got: %#v", c)
+	}
+}
+
+func TestAccMultiStateMigratorApplyWithBatchSizePartiallyPushed(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+	ctx := context.Background()
+
+	// setup the initial files and states
+	fromBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/fromDir")
+	fromSource := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+`
+	workspace := "default"
+	fromTf := tfexec.SetupTestAccWithApply(t, workspace, fromBackend+fromSource)
+
+	toBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/toDir")
+	toSource := `
+resource "null_resource" "qux" {}
+`
+	toTf := tfexec.SetupTestAccWithApply(t, workspace, toBackend+toSource)
+
+	// update terraform resource files for migration
+	tfexec.UpdateTestAccSource(t, fromTf, fromBackend)
+	toUpdatedSource := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+resource "null_resource" "qux" {}
+`
+	tfexec.UpdateTestAccSource(t, toTf, toBackend+toUpdatedSource)
+
+	actions := []MultiStateAction{
+		NewMultiStateMvAction("null_resource.foo", "null_resource.foo"),
+		NewMultiStateMvAction("null_resource.bar", "null_resource.bar"),
+		NewMultiStateMvAction("null_resource.baz", "null_resource.baz"),
+	}
+	o := &MigratorOption{TempDir: t.TempDir()}
+	m := NewMultiStateMigrator(fromTf.Dir(), toTf.Dir(), workspace, workspace, actions, o, false, false, false)
+	m.batchSize = 2
+
+	// simulate that a previous run applied the first batch, and failed to
+	// push the from state of the second batch after pushing the to state.
+	// force is required because an intermediate state has diffs.
+	first := NewMultiStateMigrator(fromTf.Dir(), toTf.Dir(), workspace, workspace, actions[:2], o, true, false, false)
+	if err := first.Apply(ctx); err != nil {
+		t.Fatalf("failed to apply the first batch: %s", err)
+	}
+	fromState, err := fromTf.StatePull(ctx)
+	if err != nil {
+		t.Fatalf("failed to pull the from state: %s", err)
+	}
+	second := NewMultiStateMigrator(fromTf.Dir(), toTf.Dir(), workspace, workspace, actions[2:], o, true, false, false)
+	if err := second.Apply(ctx); err != nil {
+		t.Fatalf("failed to apply the second batch: %s", err)
+	}
+	if err := fromTf.StatePush(ctx, fromState, "-force"); err != nil {
+		t.Fatalf("failed to restore the from state: %s", err)
+	}
+	path, err := m.checkpointPath()
+	if err != nil {
+		t.Fatalf("failed to get checkpoint path: %s", err)
+	}
+	if err := writeCheckpoint(path, &Checkpoint{Digest: m.digest(), Batches: 1, PushedToStates: []int{0}}); err != nil {
+		t.Fatalf("failed to write checkpoint: %s", err)
+	}
+
+	err = m.Apply(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator apply: %s", err)
+	}
+
+	// verify state migration results
+	fromGot, err := fromTf.StateList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to run terraform state list in fromDir: %s", err)
+	}
+	if len(fromGot) != 0 {
+		t.Errorf("got state: %v, want empty state in fromDir", fromGot)
+	}
+
+	toGot, err := toTf.StateList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to run terraform state list in toDir: %s", err)
+	}
+	toWant := []string{
+		"null_resource.bar",
+		"null_resource.baz",
+		"null_resource.foo",
+		"null_resource.qux",
+	}
+	sort.Strings(toGot)
+	if !reflect.DeepEqual(toGot, toWant) {
+		t.Errorf("got state: %v, want state: %v in toDir", toGot, toWant)
+	}
+
+	c, err := ReadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %s", err)
+	}
+	if c != nil {
+		t.Errorf("expect checkpoint to be removed, but got: %#v", c)
+	}
+}
+
 func TestAccMultiStateMigratorApplyWithFromSkipPlan(t *testing.T) {
 	tfexec.SkipUnlessAcceptanceTestEnabled(t)
 	ctx := context.Background()
@@ -26,22 +26,31 @@ func NewMultiStateMvAction(source string, destination string) *MultiStateMvActio
 	}
 }
 
+// String returns the action as "mv <source> <destination>".
+func (a *MultiStateMvAction) String() string {
+	return "mv " + a.source + " " + a.destination
+}
+
 // MultiStateUpdate updates given two states and returns new two states.
 // It moves a resource from a dir to another.
 // It also can rename an address of resource.
+// Dependencies referring to the moved resource are pruned in the from state,
+// and rewritten to the new address in the to state.
 func (a *MultiStateMvAction) MultiStateUpdate(ctx context.Context, fromTf tfexec.TerraformCLI, toTf tfexec.TerraformCLI, fromState *tfexec.State, toState *tfexec.State) (*tfexec.State, *tfexec.State, error) {
 	// move a resource from fromState to a temporary diffState.
 	diffState := tfexec.NewState([]byte{})
-	fromNewState, diffNewState, err := fromTf.StateMv(ctx, fromState, diffState, a.source, a.source, "-backup=/dev/null")
+	fromNewState, diffNewState, err := fromTf.StateMv(ctx, fromState, diffState, a.source, a.source, disableBackupOpt)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// move the resource from the diffState to toState.
-	_, toNewState, err := toTf.StateMv(ctx, diffNewState, toState, a.source, a.destination, "-backup=/dev/null")
+	_, toNewState, err := toTf.StateMv(ctx, diffNewState, toState, a.source, a.destination, disableBackupOpt)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	fromNewState = relinkDependencies(ctx, fromTf.Dir(), fromState, fromNewState, nil)
+	toNewState = relinkDependencies(ctx, toTf.Dir(), toState, toNewState, map[string]string{a.source: a.destination})
 	return fromNewState, toNewState, nil
 }
@@ -14,9 +14,12 @@ type MultiStateXmvAction struct {
 	source string
 	// destination is a new address of resource or module to move which can contain placeholders.
 	destination string
+	// resolved is a list of mv actions generated by the last MultiStateUpdate.
+	resolved []*MultiStateMvAction
 }
 
 var _ MultiStateAction = (*MultiStateXmvAction)(nil)
+var _ actionResolver = (*MultiStateXmvAction)(nil)
 
 // NewMultiStateXmvAction returns a new MultiStateXmvAction instance.
 func NewMultiStateXmvAction(source string, destination string) *MultiStateXmvAction {
@@ -26,14 +29,21 @@ func NewMultiStateXmvAction(source string, destination string) *MultiStateXmvAct
 	}
 }
 
+// String returns the action as "xmv <source> <destination>" before expanding wildcards.
+func (a *MultiStateXmvAction) String() string {
+	return "xmv " + a.source + " " + a.destination
+}
+
 // MultiStateUpdate updates given two states and returns new two states.
 // It moves a resource from a dir to another.
 // It also can rename an address of resource.
 func (a *MultiStateXmvAction) MultiStateUpdate(ctx context.Context, fromTf tfexec.TerraformCLI, toTf tfexec.TerraformCLI, fromState *tfexec.State, toState *tfexec.State) (*tfexec.State, *tfexec.State, error) {
+	a.resolved = nil
 	multiStateMvActions, err := a.generateMvActions(ctx, fromTf, fromState)
 	if err != nil {
 		return nil, nil, err
 	}
+	a.resolved = multiStateMvActions
 
 	for _, action := range multiStateMvActions {
 		fromState, toState, err = action.MultiStateUpdate(ctx, fromTf, toTf, fromState, toState)
@@ -44,6 +54,19 @@ func (a *MultiStateXmvAction) MultiStateUpdate(ctx context.Context, fromTf tfexe
 	return fromState, toState, nil
 }
 
+// resolvedActions returns a list of mv actions generated by the last
+// MultiStateUpdate. It's nil if wildcards have not been expanded yet.
+func (a *MultiStateXmvAction) resolvedActions() []string {
+	if a.resolved == nil {
+		return nil
+	}
+	actions := make([]string, 0, len(a.resolved))
+	for _, action := range a.resolved {
+		actions = append(actions, action.String())
+	}
+	return actions
+}
+
 // generateMvActions uses an xmv and use the state to determine the corresponding mv actions.
 func (a *MultiStateXmvAction) generateMvActions(ctx context.Context, fromTf tfexec.TerraformCLI, fromState *tfexec.State) ([]*MultiStateMvAction, error) {
 	stateList, err := fromTf.StateList(ctx, fromState, nil)
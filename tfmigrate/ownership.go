@@ -0,0 +1,186 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Owner is a team which owns resources under address prefixes.
+type Owner struct {
+	// Team is a name of the team.
+	Team string
+	// Prefixes is a list of address prefixes owned by the team.
+	// e.g.) module.network
+	Prefixes []string
+	// Members is a list of approver identities who belong to the team.
+	// If empty, an approval recorded with the team name is accepted.
+	Members []string
+}
+
+// Ownership is a mapping of address prefixes to owning teams like
+// CODEOWNERS. It prevents a migration from touching resources owned by
+// another team without their approval.
+type Ownership struct {
+	// Owners is a list of teams.
+	Owners []*Owner
+}
+
+// findOwner returns an owner for a given team.
+func (o *Ownership) findOwner(team string) (*Owner, bool) {
+	for _, owner := range o.Owners {
+		if owner.Team == team {
+			return owner, true
+		}
+	}
+	return nil, false
+}
+
+// Validate checks that a given migration carries approved_by for all teams
+// whose resources it touches other than the owner of the migration.
+func (o *Ownership) Validate(mc *MigrationConfig) error {
+	if len(mc.Owner) > 0 {
+		if _, ok := o.findOwner(mc.Owner); !ok {
+			return fmt.Errorf("unknown owner: %s: %s", mc.Owner, mc.Name)
+		}
+	}
+	for _, team := range mc.ApprovedBy {
+		if _, ok := o.findOwner(team); !ok {
+			return fmt.Errorf("unknown team in approved_by: %s: %s", team, mc.Name)
+		}
+	}
+
+	teams, err := o.touchedTeams(mc)
+	if err != nil {
+		return err
+	}
+
+	for _, team := range teams {
+		if team == mc.Owner || slices.Contains(mc.ApprovedBy, team) {
+			continue
+		}
+		return fmt.Errorf("a migration touches resources owned by %s. Add %s to approved_by: %s", team, team, mc.Name)
+	}
+
+	return nil
+}
+
+// CheckApprovals checks that all teams in approved_by of a given migration
+// have actually approved it. The approvers is a list of approver identities
+// recorded for the migration.
+func (o *Ownership) CheckApprovals(mc *MigrationConfig, approvers []string) error {
+	for _, team := range mc.ApprovedBy {
+		owner, ok := o.findOwner(team)
+		if !ok {
+			return fmt.Errorf("unknown team in approved_by: %s: %s", team, mc.Name)
+		}
+
+		members := owner.Members
+		if len(members) == 0 {
+			members = []string{owner.Team}
+		}
+
+		approved := false
+		for _, approver := range approvers {
+			if slices.Contains(members, approver) {
+				approved = true
+				break
+			}
+		}
+		if !approved {
+			return fmt.Errorf("a migration requires an approval by %s, but got none: %s", team, mc.Name)
+		}
+	}
+
+	return nil
+}
+
+// touchedTeams returns a sorted list of teams whose resources a given
+// migration touches. Addresses passed to action plugins are not checked,
+// because we don't know their meanings.
+func (o *Ownership) touchedTeams(mc *MigrationConfig) ([]string, error) {
+	var actions []string
+	switch c := mc.Migrator.(type) {
+	case *StateMigratorConfig:
+		actions = c.declaredActions()
+	case *MultiStateMigratorConfig:
+		actions = c.declaredActions()
+	}
+
+	seen := make(map[string]bool)
+	for _, action := range actions {
+		addresses, err := actionAddresses(action)
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range addresses {
+			for _, owner := range o.Owners {
+				for _, prefix := range owner.Prefixes {
+					if touchesAddressPrefix(address, prefix) {
+						seen[owner.Team] = true
+					}
+				}
+			}
+		}
+	}
+
+	teams := []string{}
+	for team := range seen {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+	return teams, nil
+}
+
+// actionAddresses returns a list of resource addresses in a given action.
+// It returns an empty list for actions which don't take addresses such as
+// replace-provider, and action plugins.
+func actionAddresses(cmdStr string) ([]string, error) {
+	args, err := splitStateAction(cmdStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+	}
+	if len(args) < 2 {
+		return []string{}, nil
+	}
+
+	switch args[0] {
+	case "mv", "xmv":
+		// A multi state action may have a to_dir after addresses.
+		return args[1:min(len(args), 3)], nil
+	case "rm", "xrm":
+		return args[1:], nil
+	case "import":
+		return args[1:2], nil
+	default:
+		return []string{}, nil
+	}
+}
+
+// touchesAddressPrefix returns true if a given address may be under a given
+// prefix. A prefix matches at a boundary of an address, so that
+// module.network matches module.network.aws_vpc.main and
+// module.network["a"], but doesn't match module.network2.
+// An address which contains a wildcard or a reference to matched values of
+// xmv is compared only by a part before them, so that it conservatively
+// matches all prefixes which it may expand to.
+func touchesAddressPrefix(address string, prefix string) bool {
+	if i := strings.IndexAny(address, "*$"); i >= 0 {
+		literal := address[:i]
+		return strings.HasPrefix(prefix, literal) || hasAddressPrefix(literal, prefix)
+	}
+	return hasAddressPrefix(address, prefix)
+}
+
+// hasAddressPrefix returns true if a given address is under a given prefix.
+func hasAddressPrefix(address string, prefix string) bool {
+	if !strings.HasPrefix(address, prefix) {
+		return false
+	}
+	if len(address) == len(prefix) || strings.HasSuffix(prefix, ".") {
+		return true
+	}
+	next := address[len(prefix)]
+	return next == '.' || next == '['
+}
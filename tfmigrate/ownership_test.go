@@ -0,0 +1,205 @@
+package tfmigrate
+
+import (
+	"testing"
+)
+
+func testOwnership() *Ownership {
+	return &Ownership{
+		Owners: []*Owner{
+			{
+				Team:     "network",
+				Prefixes: []string{"module.network", "aws_vpc."},
+				Members:  []string{"alice"},
+			},
+			{
+				Team:     "app",
+				Prefixes: []string{"module.app"},
+			},
+		},
+	}
+}
+
+func TestOwnershipValidate(t *testing.T) {
+	cases := []struct {
+		desc string
+		mc   *MigrationConfig
+		ok   bool
+	}{
+		{
+			desc: "not owned",
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{"mv null_resource.foo null_resource.bar"},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "own resources",
+			mc: &MigrationConfig{
+				Name:  "test",
+				Owner: "app",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{"mv module.app.null_resource.foo module.app.null_resource.bar"},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "another team without approved_by",
+			mc: &MigrationConfig{
+				Name:  "test",
+				Owner: "app",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{`mv module.app.aws_subnet.foo module.network["a:b/c"].aws_subnet.foo`},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "another team with approved_by",
+			mc: &MigrationConfig{
+				Name:       "test",
+				Owner:      "app",
+				ApprovedBy: []string{"network"},
+				Migrator: &MultiStateMigratorConfig{
+					Actions: []string{"mv module.app.aws_subnet.foo module.network.aws_subnet.foo"},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "prefix with a trailing dot",
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{"rm null_resource.foo aws_vpc.main"},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "similar prefix",
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{"import module.network2.aws_vpc.foo vpc-1234"},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "wildcard may expand to another team",
+			mc: &MigrationConfig{
+				Name:  "test",
+				Owner: "app",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{"xmv module.* module.app.$1"},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "replace-provider",
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{"replace-provider module.network module.app"},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "unknown owner",
+			mc: &MigrationConfig{
+				Name:  "test",
+				Owner: "foo",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{"mv null_resource.foo null_resource.bar"},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "unknown team in approved_by",
+			mc: &MigrationConfig{
+				Name:       "test",
+				ApprovedBy: []string{"foo"},
+				Migrator: &StateMigratorConfig{
+					Actions: []string{"mv null_resource.foo null_resource.bar"},
+				},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := testOwnership().Validate(tc.mc)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestOwnershipCheckApprovals(t *testing.T) {
+	cases := []struct {
+		desc       string
+		approvedBy []string
+		approvers  []string
+		ok         bool
+	}{
+		{
+			desc:       "no approved_by",
+			approvedBy: nil,
+			approvers:  nil,
+			ok:         true,
+		},
+		{
+			desc:       "approved by a member",
+			approvedBy: []string{"network"},
+			approvers:  []string{"bob", "alice"},
+			ok:         true,
+		},
+		{
+			desc:       "not approved by a member",
+			approvedBy: []string{"network"},
+			approvers:  []string{"bob"},
+			ok:         false,
+		},
+		{
+			desc:       "approved by a team name without members",
+			approvedBy: []string{"app"},
+			approvers:  []string{"app"},
+			ok:         true,
+		},
+		{
+			desc:       "partially approved",
+			approvedBy: []string{"network", "app"},
+			approvers:  []string{"alice"},
+			ok:         false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mc := &MigrationConfig{
+				Name:       "test",
+				ApprovedBy: tc.approvedBy,
+			}
+			err := testOwnership().CheckApprovals(mc, tc.approvers)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// planActions is a set of valid actions of allowed changes.
+var planActions = []string{"create", "update", "delete", "replace"}
+
+// PlanExpectation is an expected result of terraform plan after a migration.
+// Unlike the default check with -detailed-exitcode, changes are inspected with
+// terraform show -json, so that the migration fails only if changes other than
+// allowed ones appear, and reports which changes are unexpected.
+type PlanExpectation struct {
+	// Allow is a list of allowed changes in the format of <action>:<address>,
+	// such as update:aws_iam_role.foo. If empty, no changes are allowed.
+	Allow []string
+	// Forced is a list of addresses touched by forced actions. Any changes to
+	// them and addresses under them are allowed.
+	Forced []string
+}
+
+// NewPlanExpectation returns a new PlanExpectation for given attributes of a
+// migration. It returns nil if no expectations are declared, which means the
+// default check with -detailed-exitcode.
+func NewPlanExpectation(noChanges bool, allow []string) (*PlanExpectation, error) {
+	if !noChanges && len(allow) == 0 {
+		return nil, nil
+	}
+	if noChanges && len(allow) != 0 {
+		return nil, fmt.Errorf("expect_no_changes and allow cannot be set at the same time")
+	}
+
+	for _, a := range allow {
+		action, address, ok := strings.Cut(a, ":")
+		if !ok || len(address) == 0 || !slices.Contains(planActions, action) {
+			return nil, fmt.Errorf("invalid allowed change: %q, it must be in the format of <action>:<address>, and the action must be one of %s", a, strings.Join(planActions, ", "))
+		}
+	}
+
+	return &PlanExpectation{Allow: allow}, nil
+}
+
+// Check returns an error if a given plan has changes which are not allowed.
+func (e *PlanExpectation) Check(plan *tfexec.JSONPlan) error {
+	unexpected := []string{}
+	for _, rc := range plan.Changes() {
+		change := rc.Action() + ":" + rc.Address
+		if !slices.Contains(e.Allow, change) && !isForcedAddress(rc.Address, e.Forced) {
+			unexpected = append(unexpected, change)
+		}
+	}
+
+	if len(unexpected) > 0 {
+		return fmt.Errorf("terraform plan returns unexpected changes: %s", strings.Join(unexpected, ", "))
+	}
+	return nil
+}
+
+// checkPlanExpectation runs terraform plan with a given state, and returns an
+// error if changes in the plan don't meet a given expectation.
+// The plan is returned even if it doesn't meet the expectation.
+func checkPlanExpectation(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State, e *PlanExpectation, planOpts []string) (*tfexec.JSONPlan, error) {
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] check diffs with expectations\n", tf.Dir())
+	plan, err := tf.PlanJSON(ctx, state, planOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.Check(plan); err != nil {
+		logging.FromContext(ctx).Printf("[ERROR] [migrator@%s] unexpected changes\n", tf.Dir())
+		return plan, fmt.Errorf("%s in %s", err, tf.Dir())
+	}
+	return plan, nil
+}
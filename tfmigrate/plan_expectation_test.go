@@ -0,0 +1,160 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestNewPlanExpectation(t *testing.T) {
+	cases := []struct {
+		desc      string
+		noChanges bool
+		allow     []string
+		want      *PlanExpectation
+		ok        bool
+	}{
+		{
+			desc:      "not declared",
+			noChanges: false,
+			allow:     nil,
+			want:      nil,
+			ok:        true,
+		},
+		{
+			desc:      "no changes",
+			noChanges: true,
+			allow:     nil,
+			want:      &PlanExpectation{},
+			ok:        true,
+		},
+		{
+			desc:      "allow",
+			noChanges: false,
+			allow:     []string{"update:aws_iam_role.foo", "replace:module.bar.aws_instance.baz[0]"},
+			want:      &PlanExpectation{Allow: []string{"update:aws_iam_role.foo", "replace:module.bar.aws_instance.baz[0]"}},
+			ok:        true,
+		},
+		{
+			desc:      "both",
+			noChanges: true,
+			allow:     []string{"update:aws_iam_role.foo"},
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc:      "unknown action",
+			noChanges: false,
+			allow:     []string{"modify:aws_iam_role.foo"},
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc:      "no action",
+			noChanges: false,
+			allow:     []string{"aws_iam_role.foo"},
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc:      "no address",
+			noChanges: false,
+			allow:     []string{"update:"},
+			want:      nil,
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := NewPlanExpectation(tc.noChanges, tc.allow)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlanExpectationCheck(t *testing.T) {
+	change := func(address string, actions ...string) tfexec.ResourceChange {
+		return tfexec.ResourceChange{
+			Address: address,
+			Change:  tfexec.Change{Actions: actions},
+		}
+	}
+	plan := &tfexec.JSONPlan{
+		ResourceChanges: []tfexec.ResourceChange{
+			change("aws_iam_role.foo", "update"),
+			change("aws_instance.bar", "create", "delete"),
+			change("aws_s3_bucket.baz", "no-op"),
+			change("data.aws_caller_identity.current", "read"),
+		},
+	}
+
+	cases := []struct {
+		desc   string
+		expect *PlanExpectation
+		ok     bool
+	}{
+		{
+			desc:   "no changes",
+			expect: &PlanExpectation{},
+			ok:     false,
+		},
+		{
+			desc: "all allowed",
+			expect: &PlanExpectation{
+				Allow: []string{"update:aws_iam_role.foo", "replace:aws_instance.bar"},
+			},
+			ok: true,
+		},
+		{
+			desc: "partially allowed",
+			expect: &PlanExpectation{
+				Allow: []string{"update:aws_iam_role.foo"},
+			},
+			ok: false,
+		},
+		{
+			desc: "different action",
+			expect: &PlanExpectation{
+				Allow: []string{"update:aws_iam_role.foo", "update:aws_instance.bar"},
+			},
+			ok: false,
+		},
+		{
+			desc: "allowed and forced",
+			expect: &PlanExpectation{
+				Allow:  []string{"update:aws_iam_role.foo"},
+				Forced: []string{"aws_instance.bar"},
+			},
+			ok: true,
+		},
+		{
+			desc: "forced partially",
+			expect: &PlanExpectation{
+				Forced: []string{"aws_instance.bar"},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.expect.Check(plan)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package tfmigrate
+
+import (
+	"context"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// PlanSummary is a summary of changes in terraform plan with a new state
+// computed by a migration.
+type PlanSummary struct {
+	// Dir is a working directory of the plan.
+	Dir string
+	// Workspace is a workspace of the plan.
+	Workspace string
+	// Add is a number of resource instances to be created.
+	// A replacement is counted as both Add and Destroy as terraform does.
+	Add int
+	// Change is a number of resource instances to be updated in-place.
+	Change int
+	// Destroy is a number of resource instances to be destroyed.
+	Destroy int
+	// Changes is a list of changes in the format of <action>:<address>,
+	// such as update:aws_iam_role.foo, which is the same format as allow.
+	Changes []string
+}
+
+// PlanSummaryReporter is an optional interface for a Migrator which reports
+// summaries of terraform plan in the last Plan or Apply.
+// They are computed only if the PlanSummary option is set, and not computed
+// for a directory where terraform plan is skipped.
+type PlanSummaryReporter interface {
+	// PlanSummaries returns a list of plan summaries for each working directory.
+	PlanSummaries() []PlanSummary
+}
+
+// newPlanSummary returns a PlanSummary of a given plan in JSON.
+func newPlanSummary(dir string, workspace string, plan *tfexec.JSONPlan) PlanSummary {
+	s := PlanSummary{
+		Dir:       dir,
+		Workspace: workspace,
+		Changes:   []string{},
+	}
+	for _, rc := range plan.Changes() {
+		action := rc.Action()
+		switch action {
+		case "create":
+			s.Add++
+		case "update":
+			s.Change++
+		case "delete":
+			s.Destroy++
+		case "replace":
+			s.Add++
+			s.Destroy++
+		}
+		s.Changes = append(s.Changes, action+":"+rc.Address)
+	}
+	return s
+}
+
+// summarizePlan returns a PlanSummary of a given plan with terraform show.
+func summarizePlan(ctx context.Context, tf tfexec.TerraformCLI, workspace string, plan *tfexec.Plan) (PlanSummary, error) {
+	p, err := tf.ShowPlanJSON(ctx, plan)
+	if err != nil {
+		return PlanSummary{}, err
+	}
+	return newPlanSummary(tf.Dir(), workspace, p), nil
+}
+
+// isPlanDiffs returns true if a given error of terraform plan with the
+// -detailed-exitcode option means that the plan has diffs.
+func isPlanDiffs(err error) bool {
+	exitErr, ok := err.(tfexec.ExitError)
+	return ok && exitErr.ExitCode() == 2
+}
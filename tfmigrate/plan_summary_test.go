@@ -0,0 +1,59 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestNewPlanSummary(t *testing.T) {
+	cases := []struct {
+		desc    string
+		changes []tfexec.ResourceChange
+		want    PlanSummary
+	}{
+		{
+			desc:    "no changes",
+			changes: []tfexec.ResourceChange{},
+			want: PlanSummary{
+				Dir:       "foo",
+				Workspace: "default",
+				Changes:   []string{},
+			},
+		},
+		{
+			desc: "mixed changes",
+			changes: []tfexec.ResourceChange{
+				{Address: "aws_iam_role.foo", Change: tfexec.Change{Actions: []string{"update"}}},
+				{Address: "aws_instance.bar", Change: tfexec.Change{Actions: []string{"delete", "create"}}},
+				{Address: "aws_s3_bucket.baz", Change: tfexec.Change{Actions: []string{"no-op"}}},
+				{Address: "data.aws_caller_identity.current", Change: tfexec.Change{Actions: []string{"read"}}},
+				{Address: "aws_sqs_queue.qux", Change: tfexec.Change{Actions: []string{"create"}}},
+				{Address: "aws_sns_topic.quux", Change: tfexec.Change{Actions: []string{"delete"}}},
+			},
+			want: PlanSummary{
+				Dir:       "foo",
+				Workspace: "default",
+				Add:       2,
+				Change:    1,
+				Destroy:   2,
+				Changes: []string{
+					"update:aws_iam_role.foo",
+					"replace:aws_instance.bar",
+					"create:aws_sqs_queue.qux",
+					"delete:aws_sns_topic.quux",
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := newPlanSummary("foo", "default", &tfexec.JSONPlan{ResourceChanges: tc.changes})
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+)
+
+// MigrationPolicy is an organization policy for migrations.
+// It allows us to keep rules in the config file rather than in review
+// comments. It is enforced when a migration file is loaded.
+type MigrationPolicy struct {
+	// DefaultForce is a value of force for migrations which don't set it.
+	DefaultForce bool
+	// DenyForce rejects migrations which set force to true.
+	DenyForce bool
+	// DenySkipPlan rejects migrations which skip terraform plan.
+	DenySkipPlan bool
+	// RequiredPlanOptions is a list of options which must be passed to
+	// terraform plan, such as `-lock-timeout`.
+	RequiredPlanOptions []string
+	// BannedPlanOptions is a list of options which must not be passed to
+	// terraform plan, such as `-refresh=false`.
+	BannedPlanOptions []string
+	// StrictDirs turns warnings on checking working directories, such as no
+	// terraform configuration or not initialized, into errors.
+	StrictDirs bool
+	// ProtectedAddresses is a list of address patterns which no migration may
+	// rm or mv unless it lists the pattern in unprotect.
+	// A pattern can contain a wildcard `*` which matches any characters.
+	// e.g.) aws_kms_key.*, module.prod_db
+	ProtectedAddresses []string
+}
+
+// Validate checks if a given migration complies with the policy.
+// The planOptions is a list of extra options passed to terraform plan.
+func (p *MigrationPolicy) Validate(mc *MigrationConfig, planOptions []string) error {
+	force, skipPlan := false, false
+	switch c := mc.Migrator.(type) {
+	case *StateMigratorConfig:
+		force, skipPlan = c.Force || anyActionForced(c.ActionConfigs), c.SkipPlan
+	case *MultiStateMigratorConfig:
+		force, skipPlan = c.Force || anyActionForced(c.ActionConfigs), c.FromSkipPlan || c.ToSkipPlan
+	}
+
+	if p.DenyForce && force {
+		return fmt.Errorf("policy violation: force is denied by policy: %s", mc.Name)
+	}
+
+	if p.DenySkipPlan && skipPlan {
+		return fmt.Errorf("policy violation: skipping plan is denied by policy: %s", mc.Name)
+	}
+
+	for _, required := range p.RequiredPlanOptions {
+		if !containsPlanOption(planOptions, required) {
+			return fmt.Errorf("policy violation: plan option %s is required by policy. Set it to TF_CLI_ARGS_plan: %s", required, mc.Name)
+		}
+	}
+
+	for _, banned := range p.BannedPlanOptions {
+		if containsPlanOption(planOptions, banned) {
+			return fmt.Errorf("policy violation: plan option %s is banned by policy: %s", banned, mc.Name)
+		}
+	}
+
+	return p.validateProtectedAddresses(mc)
+}
+
+// validateProtectedAddresses checks that a given migration doesn't rm or mv
+// resources matching protected address patterns which it doesn't unprotect.
+// Addresses passed to action plugins are not checked, because we don't know
+// their meanings.
+func (p *MigrationPolicy) validateProtectedAddresses(mc *MigrationConfig) error {
+	for _, pattern := range mc.Unprotect {
+		if !slices.Contains(p.ProtectedAddresses, pattern) {
+			return fmt.Errorf("unknown protected address in unprotect: %s: %s", pattern, mc.Name)
+		}
+	}
+
+	var actions []string
+	switch c := mc.Migrator.(type) {
+	case *StateMigratorConfig:
+		actions = c.declaredActions()
+	case *MultiStateMigratorConfig:
+		actions = c.declaredActions()
+	}
+
+	for _, action := range actions {
+		args, err := splitStateAction(action)
+		if err != nil {
+			return fmt.Errorf("failed to parse action: %s, err: %s", action, err)
+		}
+		if len(args) < 2 {
+			continue
+		}
+		switch args[0] {
+		case "mv", "xmv", "rm", "xrm":
+		default:
+			continue
+		}
+
+		addresses, err := actionAddresses(action)
+		if err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			for _, pattern := range p.ProtectedAddresses {
+				if slices.Contains(mc.Unprotect, pattern) || !touchesProtectedAddress(address, pattern) {
+					continue
+				}
+				return fmt.Errorf("policy violation: %s action touches %s protected by %s. Add %q to unprotect if intended: %s", args[0], address, pattern, pattern, mc.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// touchesProtectedAddress returns true if a given address may refer to
+// resources matching a given protected pattern, or a module which contains
+// them. A pattern without a wildcard matches the address and everything
+// under it. An address which contains a wildcard or a reference to matched
+// values of xmv is compared only by a part before them, so that it
+// conservatively matches all patterns which it may expand to.
+func touchesProtectedAddress(address string, pattern string) bool {
+	if !strings.Contains(pattern, "*") {
+		return touchesAddressPrefix(address, pattern) || hasAddressPrefix(pattern, address)
+	}
+	if i := strings.IndexAny(address, "*$"); i >= 0 {
+		return matchGlob(pattern, address[:i], true)
+	}
+	return matchGlob(pattern, address, false) ||
+		matchGlob(pattern, address+".", true) ||
+		matchGlob(pattern, address+"[", true)
+}
+
+// matchGlob returns true if a given string matches a given pattern, in which
+// a wildcard `*` matches any characters. If partial is true, it returns true
+// if the string is a prefix of any string which matches the pattern.
+func matchGlob(pattern string, s string, partial bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == '*' {
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern[1:], s[i:], partial) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(s) == 0 {
+			return partial
+		}
+		if pattern[0] != s[0] {
+			return false
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// PlanOptionsFromEnv returns a list of extra options passed to terraform
+// plan via the TF_CLI_ARGS and TF_CLI_ARGS_plan environment variables.
+func PlanOptionsFromEnv() ([]string, error) {
+	options := []string{}
+	for _, name := range []string{"TF_CLI_ARGS", "TF_CLI_ARGS_plan"} {
+		args, err := shellwords.Parse(os.Getenv(name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", name, err)
+		}
+		options = append(options, args...)
+	}
+	return options, nil
+}
+
+// containsPlanOption returns true if a given option is included in options.
+// If the option doesn't contain a value such as `-lock-timeout`, it matches
+// the option with any value. Otherwise, such as `-refresh=false`, it matches
+// only the exact value. Terraform accepts both a single and double dash, so
+// we don't distinguish them.
+func containsPlanOption(options []string, option string) bool {
+	option = normalizePlanOption(option)
+	for _, o := range options {
+		o = normalizePlanOption(o)
+		if o == option {
+			return true
+		}
+		if !strings.Contains(option, "=") && strings.HasPrefix(o, option+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePlanOption normalizes a leading double dash to a single dash.
+func normalizePlanOption(option string) string {
+	if strings.HasPrefix(option, "--") {
+		return option[1:]
+	}
+	return option
+}
@@ -0,0 +1,260 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrationPolicyValidate(t *testing.T) {
+	cases := []struct {
+		desc        string
+		policy      *MigrationPolicy
+		mc          *MigrationConfig
+		planOptions []string
+		ok          bool
+	}{
+		{
+			desc:   "empty policy",
+			policy: &MigrationPolicy{},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{Force: true, SkipPlan: true},
+			},
+			planOptions: []string{"-refresh=false"},
+			ok:          true,
+		},
+		{
+			desc:   "deny force",
+			policy: &MigrationPolicy{DenyForce: true},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{Force: true},
+			},
+			ok: false,
+		},
+		{
+			desc:   "deny force of action",
+			policy: &MigrationPolicy{DenyForce: true},
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					ActionConfigs: []ActionConfig{
+						{Command: "mv null_resource.foo null_resource.foo2", Force: true},
+					},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc:   "allow_changes of action with deny force",
+			policy: &MigrationPolicy{DenyForce: true},
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &MultiStateMigratorConfig{
+					ActionConfigs: []ActionConfig{
+						{Command: "mv null_resource.foo null_resource.foo2", AllowChanges: []string{"update:null_resource.foo2"}},
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc:   "deny skip plan (multi_state)",
+			policy: &MigrationPolicy{DenySkipPlan: true},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &MultiStateMigratorConfig{ToSkipPlan: true},
+			},
+			ok: false,
+		},
+		{
+			desc:   "required option found",
+			policy: &MigrationPolicy{RequiredPlanOptions: []string{"-lock-timeout"}},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{},
+			},
+			planOptions: []string{"--lock-timeout=60s"},
+			ok:          true,
+		},
+		{
+			desc:   "required option not found",
+			policy: &MigrationPolicy{RequiredPlanOptions: []string{"-lock-timeout"}},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{},
+			},
+			planOptions: []string{"-lock=true"},
+			ok:          false,
+		},
+		{
+			desc:   "banned option found",
+			policy: &MigrationPolicy{BannedPlanOptions: []string{"-refresh=false"}},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{},
+			},
+			planOptions: []string{"-refresh=false"},
+			ok:          false,
+		},
+		{
+			desc:   "banned option with another value",
+			policy: &MigrationPolicy{BannedPlanOptions: []string{"-refresh=false"}},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{},
+			},
+			planOptions: []string{"-refresh=true"},
+			ok:          true,
+		},
+		{
+			desc:   "rm a protected address",
+			policy: &MigrationPolicy{ProtectedAddresses: []string{"aws_kms_key.*"}},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{Actions: []string{"rm aws_instance.foo aws_kms_key.foo"}},
+			},
+			ok: false,
+		},
+		{
+			desc:   "xrm a protected address",
+			policy: &MigrationPolicy{ProtectedAddresses: []string{"module.prod_db"}},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{Actions: []string{"xrm module.prod*"}},
+			},
+			ok: false,
+		},
+		{
+			desc:   "mv to a protected address",
+			policy: &MigrationPolicy{ProtectedAddresses: []string{"module.prod_db"}},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{Actions: []string{"mv aws_db_instance.foo module.prod_db.aws_db_instance.foo"}},
+			},
+			ok: false,
+		},
+		{
+			desc:   "xmv from a protected address (multi_state)",
+			policy: &MigrationPolicy{ProtectedAddresses: []string{"module.prod_db.*"}},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &MultiStateMigratorConfig{Actions: []string{"xmv module.* $1"}},
+			},
+			ok: false,
+		},
+		{
+			desc:   "import a protected address",
+			policy: &MigrationPolicy{ProtectedAddresses: []string{"aws_kms_key.*"}},
+			mc: &MigrationConfig{
+				Name:     "test",
+				Migrator: &StateMigratorConfig{Actions: []string{"import aws_kms_key.foo foo"}},
+			},
+			ok: true,
+		},
+		{
+			desc:   "unprotected",
+			policy: &MigrationPolicy{ProtectedAddresses: []string{"aws_kms_key.*", "module.prod_db"}},
+			mc: &MigrationConfig{
+				Name:      "test",
+				Unprotect: []string{"aws_kms_key.*"},
+				Migrator:  &StateMigratorConfig{Actions: []string{"mv aws_kms_key.foo aws_kms_key.bar"}},
+			},
+			ok: true,
+		},
+		{
+			desc:   "unknown pattern in unprotect",
+			policy: &MigrationPolicy{ProtectedAddresses: []string{"aws_kms_key.*"}},
+			mc: &MigrationConfig{
+				Name:      "test",
+				Unprotect: []string{"aws_kms_key.foo"},
+				Migrator:  &StateMigratorConfig{Actions: []string{"rm aws_instance.foo"}},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.policy.Validate(tc.mc, tc.planOptions)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestPlanOptionsFromEnv(t *testing.T) {
+	t.Setenv("TF_CLI_ARGS", "-no-color")
+	t.Setenv("TF_CLI_ARGS_plan", `-lock-timeout=60s -var "foo=bar baz"`)
+
+	got, err := PlanOptionsFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	want := []string{"-no-color", "-lock-timeout=60s", "-var", "foo=bar baz"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+}
+
+func TestTouchesProtectedAddress(t *testing.T) {
+	cases := []struct {
+		address string
+		pattern string
+		want    bool
+	}{
+		{address: "aws_kms_key.foo", pattern: "aws_kms_key.*", want: true},
+		{address: `aws_kms_key.foo["a"]`, pattern: "aws_kms_key.*", want: true},
+		{address: "module.foo.aws_kms_key.foo", pattern: "aws_kms_key.*", want: false},
+		{address: "aws_kms_alias.foo", pattern: "aws_kms_key.*", want: false},
+		{address: "module.foo.aws_kms_key.foo", pattern: "module.*.aws_kms_key.*", want: true},
+		{address: "module.foo", pattern: "module.*.aws_kms_key.*", want: true},
+		{address: `module.foo["a"]`, pattern: "module.*.aws_kms_key.*", want: true},
+		{address: "module.prod_db", pattern: "module.prod_db.*", want: true},
+		{address: "module.prod_db2", pattern: "module.prod_db.*", want: false},
+		{address: "module.prod_db.aws_db_instance.foo", pattern: "module.prod_db", want: true},
+		{address: "module.prod_db", pattern: "module.prod_db.aws_db_instance.foo", want: true},
+		{address: "module.prod_db2.aws_db_instance.foo", pattern: "module.prod_db", want: false},
+		{address: "aws_*.foo", pattern: "aws_kms_key.*", want: true},
+		{address: "aws_*.foo", pattern: "module.prod_db", want: false},
+		{address: "module.${1}", pattern: "module.prod_db", want: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.address+" "+tc.pattern, func(t *testing.T) {
+			got := touchesProtectedAddress(tc.address, tc.pattern)
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func FuzzContainsPlanOption(f *testing.F) {
+	f.Add("refresh", "false")
+	f.Add("lock-timeout", "10m")
+	f.Add("var", "foo=bar")
+
+	f.Fuzz(func(t *testing.T, name string, value string) {
+		if len(name) == 0 || strings.ContainsAny(name, "=") || strings.HasPrefix(name, "-") {
+			t.Skip()
+		}
+		option := "-" + name + "=" + value
+		for _, o := range []string{option, "-" + option} {
+			if !containsPlanOption([]string{o}, "-"+name) {
+				t.Errorf("%s must match -%s with any value", o, name)
+			}
+			if !containsPlanOption([]string{o}, "-"+option) {
+				t.Errorf("%s must match -%s with a double dash", o, option)
+			}
+			if containsPlanOption([]string{o}, "-"+name+"x") {
+				t.Errorf("%s must not match another option -%sx", o, name)
+			}
+		}
+	})
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package tfmigrate
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processExists returns true if a process with a given pid exists.
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	// EPERM means the process exists but is owned by another user.
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
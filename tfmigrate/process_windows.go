@@ -0,0 +1,19 @@
+//go:build windows
+
+package tfmigrate
+
+import "os"
+
+// processExists returns true if a process with a given pid exists.
+// On Windows, os.FindProcess opens a handle and fails if it doesn't exist.
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
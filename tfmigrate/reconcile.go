@@ -0,0 +1,189 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// Reconciliation is a declared end state of addresses, which is an
+// experimental alternative to actions. Instead of running given actions as
+// they are, a migrator computes a minimal set of actions to reconcile the
+// current state to the declared one on every plan, so that a migration which
+// has been fully or partially applied can be re-run safely.
+type Reconciliation struct {
+	// Mapping is a map of current addresses to desired addresses.
+	// A source address is moved to its destination unless it has already been
+	// moved.
+	Mapping map[string]string
+	// Absent is a list of addresses which must not exist in the end state.
+	// They are removed if they exist.
+	Absent []string
+	// Imports is a map of addresses which must exist in the end state to
+	// their IDs. They are imported if they don't exist.
+	Imports map[string]string
+}
+
+// NewReconciliation returns a new Reconciliation for given attributes of a
+// migration. It returns nil if no end state is declared.
+func NewReconciliation(mapping map[string]string, absent []string, imports map[string]string) (*Reconciliation, error) {
+	if len(mapping) == 0 && len(absent) == 0 && len(imports) == 0 {
+		return nil, nil
+	}
+
+	r := &Reconciliation{
+		Mapping: mapping,
+		Absent:  absent,
+		Imports: imports,
+	}
+
+	// Each address must appear only once in the declaration, because the end
+	// state would be ambiguous otherwise.
+	seen := make(map[string]string)
+	declare := func(address string, attr string) error {
+		if len(address) == 0 {
+			return fmt.Errorf("an empty address in %s", attr)
+		}
+		if prev, ok := seen[address]; ok {
+			return fmt.Errorf("address %s is declared more than once in %s and %s", address, prev, attr)
+		}
+		seen[address] = attr
+		return nil
+	}
+
+	for _, source := range r.sources() {
+		if err := declare(source, "mapping"); err != nil {
+			return nil, err
+		}
+		if err := declare(r.Mapping[source], "mapping"); err != nil {
+			return nil, err
+		}
+	}
+	for _, address := range r.Absent {
+		if err := declare(address, "absent"); err != nil {
+			return nil, err
+		}
+	}
+	for _, address := range r.importAddresses() {
+		if err := declare(address, "imports"); err != nil {
+			return nil, err
+		}
+		if len(r.Imports[address]) == 0 {
+			return nil, fmt.Errorf("an empty ID for %s in imports", address)
+		}
+	}
+
+	return r, nil
+}
+
+// sources returns a sorted list of source addresses of the mapping.
+func (r *Reconciliation) sources() []string {
+	sources := make([]string, 0, len(r.Mapping))
+	for source := range r.Mapping {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// importAddresses returns a sorted list of addresses to be imported.
+func (r *Reconciliation) importAddresses() []string {
+	addresses := make([]string, 0, len(r.Imports))
+	for address := range r.Imports {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// Actions returns a list of all actions which may be computed from the end
+// state, which is used for static checks such as policies and ownership
+// before knowing the current state.
+func (r *Reconciliation) Actions() []string {
+	actions := []string{}
+	for _, source := range r.sources() {
+		actions = append(actions, "mv "+source+" "+r.Mapping[source])
+	}
+	for _, address := range r.Absent {
+		actions = append(actions, "rm "+address)
+	}
+	for _, address := range r.importAddresses() {
+		actions = append(actions, "import "+address+" "+singleQuote(r.Imports[address]))
+	}
+	return actions
+}
+
+// resolve returns a minimal list of actions to reconcile the current state to
+// the end state. sourceExists and destinationExists report whether a given
+// address exists in the state where resources move from and to,
+// respectively. Absent and imported addresses are looked up with
+// destinationExists.
+// It returns an error if a mapping cannot be reconciled, that is, both or
+// neither of its source and destination exist.
+func (r *Reconciliation) resolve(sourceExists func(string) bool, destinationExists func(string) bool) ([]string, error) {
+	actions := []string{}
+	for _, source := range r.sources() {
+		destination := r.Mapping[source]
+		src, dst := sourceExists(source), destinationExists(destination)
+		switch {
+		case src && !dst:
+			actions = append(actions, "mv "+source+" "+destination)
+		case !src && dst:
+			// already moved.
+		case src && dst:
+			return nil, fmt.Errorf("failed to reconcile mapping %s => %s: both addresses exist", source, destination)
+		default:
+			return nil, fmt.Errorf("failed to reconcile mapping %s => %s: neither address exists", source, destination)
+		}
+	}
+	for _, address := range r.Absent {
+		if destinationExists(address) {
+			actions = append(actions, "rm "+address)
+		}
+	}
+	for _, address := range r.importAddresses() {
+		if !destinationExists(address) {
+			actions = append(actions, "import "+address+" "+singleQuote(r.Imports[address]))
+		}
+	}
+	return actions, nil
+}
+
+// stateAddressExistsFunc returns a function which reports whether a given
+// address exists in a given state. An address exists if any resource
+// instance is under it, so that it can refer to a module or a resource with
+// multiple instances.
+func stateAddressExistsFunc(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (func(string) bool, error) {
+	instances, err := tf.StateList(ctx, state, nil)
+	if err != nil {
+		return nil, err
+	}
+	return addressExistsIn(instances), nil
+}
+
+// addressExistsIn returns a function which reports whether a given address
+// exists in a given list of resource instances.
+func addressExistsIn(instances []string) func(string) bool {
+	return func(address string) bool {
+		for _, instance := range instances {
+			if hasAddressPrefix(instance, address) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// logReconciledActions logs a list of actions computed from an end state.
+func logReconciledActions(ctx context.Context, dir string, actions []string) {
+	if len(actions) == 0 {
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] already reconciled, no actions required\n", dir)
+		return
+	}
+	for _, action := range actions {
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] reconcile: %s\n", dir, action)
+	}
+}
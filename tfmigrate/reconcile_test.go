@@ -0,0 +1,194 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewReconciliation(t *testing.T) {
+	cases := []struct {
+		desc    string
+		mapping map[string]string
+		absent  []string
+		imports map[string]string
+		want    *Reconciliation
+		ok      bool
+	}{
+		{
+			desc:    "not declared",
+			mapping: nil,
+			absent:  nil,
+			imports: nil,
+			want:    nil,
+			ok:      true,
+		},
+		{
+			desc:    "declared",
+			mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+			absent:  []string{"null_resource.bar"},
+			imports: map[string]string{"time_static.qux": "2006-01-02T15:04:05Z"},
+			want: &Reconciliation{
+				Mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+				Absent:  []string{"null_resource.bar"},
+				Imports: map[string]string{"time_static.qux": "2006-01-02T15:04:05Z"},
+			},
+			ok: true,
+		},
+		{
+			desc:    "same source and destination",
+			mapping: map[string]string{"null_resource.foo": "null_resource.foo"},
+			want:    nil,
+			ok:      false,
+		},
+		{
+			desc: "duplicated destination",
+			mapping: map[string]string{
+				"null_resource.foo": "null_resource.baz",
+				"null_resource.bar": "null_resource.baz",
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "chained mapping",
+			mapping: map[string]string{
+				"null_resource.foo":  "null_resource.foo2",
+				"null_resource.foo2": "null_resource.foo3",
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc:    "absent in mapping",
+			mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+			absent:  []string{"null_resource.foo"},
+			want:    nil,
+			ok:      false,
+		},
+		{
+			desc:    "empty address",
+			mapping: map[string]string{"null_resource.foo": ""},
+			want:    nil,
+			ok:      false,
+		},
+		{
+			desc:    "empty ID",
+			imports: map[string]string{"time_static.qux": ""},
+			want:    nil,
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := NewReconciliation(tc.mapping, tc.absent, tc.imports)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReconciliationActions(t *testing.T) {
+	r := &Reconciliation{
+		Mapping: map[string]string{
+			"null_resource.foo": "null_resource.foo2",
+			"module.bar":        "module.bar2",
+		},
+		Absent:  []string{"null_resource.baz"},
+		Imports: map[string]string{"time_static.qux": "2006-01-02T15:04:05Z"},
+	}
+	got := r.Actions()
+	want := []string{
+		"mv module.bar module.bar2",
+		"mv null_resource.foo null_resource.foo2",
+		"rm null_resource.baz",
+		"import time_static.qux '2006-01-02T15:04:05Z'",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+}
+
+func TestReconciliationResolve(t *testing.T) {
+	r := &Reconciliation{
+		Mapping: map[string]string{
+			"null_resource.foo": "null_resource.foo2",
+			"module.bar":        "module.bar2",
+		},
+		Absent:  []string{"null_resource.baz"},
+		Imports: map[string]string{"time_static.qux": "2006-01-02T15:04:05Z"},
+	}
+
+	cases := []struct {
+		desc        string
+		source      []string
+		destination []string
+		want        []string
+		ok          bool
+	}{
+		{
+			desc:        "not applied",
+			source:      []string{"null_resource.foo", "module.bar.null_resource.a[0]", "null_resource.baz"},
+			destination: []string{"null_resource.foo", "module.bar.null_resource.a[0]", "null_resource.baz"},
+			want: []string{
+				"mv module.bar module.bar2",
+				"mv null_resource.foo null_resource.foo2",
+				"rm null_resource.baz",
+				"import time_static.qux '2006-01-02T15:04:05Z'",
+			},
+			ok: true,
+		},
+		{
+			desc:        "partially applied",
+			source:      []string{"null_resource.foo"},
+			destination: []string{"null_resource.foo", "module.bar2.null_resource.a[0]", "time_static.qux"},
+			want: []string{
+				"mv null_resource.foo null_resource.foo2",
+			},
+			ok: true,
+		},
+		{
+			desc:        "fully applied",
+			source:      []string{},
+			destination: []string{"null_resource.foo2", "module.bar2.null_resource.a[0]", "time_static.qux"},
+			want:        []string{},
+			ok:          true,
+		},
+		{
+			desc:        "both exist",
+			source:      []string{"null_resource.foo", "module.bar.null_resource.a[0]"},
+			destination: []string{"null_resource.foo2", "module.bar2.null_resource.a[0]"},
+			want:        nil,
+			ok:          false,
+		},
+		{
+			desc:        "neither exists",
+			source:      []string{"null_resource.foo"},
+			destination: []string{"null_resource.foo2", "module.bar22.null_resource.a[0]"},
+			want:        nil,
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := r.resolve(addressExistsIn(tc.source), addressExistsIn(tc.destination))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
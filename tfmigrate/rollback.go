@@ -0,0 +1,304 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RollbackMigration returns an inverse migration which undoes a given
+// migration. If rollback_actions is set, they are used as they are.
+// Otherwise, inverse actions are computed from actions in reverse order.
+// Only mv and xmv actions are reversible, and the other actions such as rm and
+// import are rejected because the removed resources and their IDs cannot be
+// recovered from the migration file. A replace-provider action is also
+// rejected, because swapping its arguments would change resources which
+// already used the new provider before the migration.
+// A multi_state migration is rolled back by moving resources from to_dir back
+// to from_dir, and actions with a to_dir override are rejected.
+// A mapping of an end state is rolled back by swapping its sources and
+// destinations, but absent and imports are not reversible.
+func RollbackMigration(mc *MigrationConfig) (*MigrationConfig, error) {
+	var migrator MigratorConfig
+	switch m := mc.Migrator.(type) {
+	case *StateMigratorConfig:
+		actions := m.RollbackActions
+		var mapping map[string]string
+		if len(actions) == 0 {
+			if len(m.Absent) != 0 || len(m.Imports) != 0 {
+				return nil, fmt.Errorf("absent and imports are not reversible. Set rollback_actions to roll back the migration explicitly: %s", mc.Name)
+			}
+			mapping = inverseMapping(m.Mapping)
+			var err error
+			actions, err = inverseStateActions(m.Actions)
+			if err != nil {
+				return nil, err
+			}
+		}
+		migrator = &StateMigratorConfig{
+			Dir:       m.Dir,
+			Workspace: m.Workspace,
+			Env:       m.Env,
+			VarFiles:  m.VarFiles,
+			Vars:      m.Vars,
+			Actions:   actions,
+			Mapping:   mapping,
+			Force:     m.Force,
+			SkipPlan:  m.SkipPlan,
+			// Allow is not inherited, because the inverse migration may have
+			// different changes.
+			ExpectNoChanges: m.ExpectNoChanges,
+		}
+
+	case *MultiStateMigratorConfig:
+		actions := m.RollbackActions
+		var mapping map[string]string
+		if len(actions) == 0 {
+			mapping = inverseMapping(m.Mapping)
+			var err error
+			actions, err = inverseMultiStateActions(m.Actions)
+			if err != nil {
+				return nil, err
+			}
+		}
+		migrator = &MultiStateMigratorConfig{
+			FromDir:                     m.ToDir,
+			FromSkipPlan:                m.ToSkipPlan,
+			ToDir:                       m.FromDir,
+			ToSkipPlan:                  m.FromSkipPlan,
+			FromWorkspace:               m.ToWorkspace,
+			ToWorkspace:                 m.FromWorkspace,
+			Actions:                     actions,
+			Mapping:                     mapping,
+			Force:                       m.Force,
+			BatchSize:                   m.BatchSize,
+			Env:                         m.Env,
+			FromEnv:                     m.ToEnv,
+			ToEnv:                       m.FromEnv,
+			VarFiles:                    m.VarFiles,
+			FromVarFiles:                m.ToVarFiles,
+			ToVarFiles:                  m.FromVarFiles,
+			Vars:                        m.Vars,
+			FromVars:                    m.ToVars,
+			ToVars:                      m.FromVars,
+			FromBackendConfig:           m.ToBackendConfig,
+			ToBackendConfig:             m.FromBackendConfig,
+			FromIsBackendTerraformCloud: m.ToIsBackendTerraformCloud,
+			ToIsBackendTerraformCloud:   m.FromIsBackendTerraformCloud,
+			ExpectNoChanges:             m.ExpectNoChanges,
+		}
+
+	case *MockMigratorConfig:
+		// A mock migration does nothing, so it is the inverse of itself.
+		migrator = m
+
+	default:
+		return nil, fmt.Errorf("rolling back a migration of type %s is not supported: %s", mc.Type, mc.Name)
+	}
+
+	rollback := &MigrationConfig{
+		Type:      mc.Type,
+		Name:      mc.Name,
+		Owner:     mc.Owner,
+		Unprotect: mc.Unprotect,
+		Migrator:  migrator,
+	}
+	return rollback, nil
+}
+
+// inverseMapping returns a mapping of an end state which undoes a given one.
+// It returns nil if the given mapping is empty.
+func inverseMapping(mapping map[string]string) map[string]string {
+	if len(mapping) == 0 {
+		return nil
+	}
+	inverse := make(map[string]string, len(mapping))
+	for source, destination := range mapping {
+		inverse[destination] = source
+	}
+	return inverse
+}
+
+// inverseStateActions returns a list of state actions which undo given
+// actions.
+func inverseStateActions(actions []string) ([]string, error) {
+	inverse := make([]string, 0, len(actions))
+	for i := len(actions) - 1; i >= 0; i-- {
+		cmdStr := actions[i]
+		args, err := splitStateAction(cmdStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("state action is empty: %s", cmdStr)
+		}
+
+		switch args[0] {
+		case "mv":
+			if len(args) != 3 {
+				return nil, fmt.Errorf("state mv action is invalid: %s", cmdStr)
+			}
+			inverse = append(inverse, joinStateAction("mv", args[2], args[1]))
+
+		case "xmv":
+			if len(args) != 3 {
+				return nil, fmt.Errorf("state xmv action is invalid: %s", cmdStr)
+			}
+			source, destination, err := inverseXmv(args[1], args[2])
+			if err != nil {
+				return nil, fmt.Errorf("failed to roll back %s: %s", cmdStr, err)
+			}
+			inverse = append(inverse, joinStateAction("xmv", source, destination))
+
+		default:
+			return nil, fmt.Errorf("%s action is not reversible: %s. Set rollback_actions to roll back the migration explicitly", args[0], cmdStr)
+		}
+	}
+	return inverse, nil
+}
+
+// inverseMultiStateActions returns a list of multi state actions which move
+// resources moved by given actions back.
+func inverseMultiStateActions(actions []string) ([]string, error) {
+	inverse := make([]string, 0, len(actions))
+	for i := len(actions) - 1; i >= 0; i-- {
+		cmdStr := actions[i]
+		args, err := splitStateAction(cmdStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("multi state action is empty: %s", cmdStr)
+		}
+		if len(args) == 4 {
+			return nil, fmt.Errorf("an action with to_dir cannot be rolled back automatically, because resources cannot move from more than one from_dir: %s. Set rollback_actions to roll back the migration explicitly", cmdStr)
+		}
+		if len(args) != 3 {
+			return nil, fmt.Errorf("multi state %s action is invalid: %s", args[0], cmdStr)
+		}
+
+		switch args[0] {
+		case "mv":
+			inverse = append(inverse, joinStateAction("mv", args[2], args[1]))
+
+		case "xmv":
+			source, destination, err := inverseXmv(args[1], args[2])
+			if err != nil {
+				return nil, fmt.Errorf("failed to roll back %s: %s", cmdStr, err)
+			}
+			inverse = append(inverse, joinStateAction("xmv", source, destination))
+
+		default:
+			return nil, fmt.Errorf("unknown multi state action type: %s", cmdStr)
+		}
+	}
+	return inverse, nil
+}
+
+// joinStateAction returns a state action string of given arguments, which
+// are quoted if needed.
+func joinStateAction(args ...string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, quoteStateActionArg(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// xmvReferenceRegex matches an escaped $ or a reference to a wildcard in a
+// destination of xmv such as $1 and ${1}. A name of reference consists of the
+// same characters as regexp.Expand. A $ which doesn't match it is kept as it
+// is by regexp.Expand.
+var xmvReferenceRegex = regexp.MustCompile(`\$\$|\$\{([\p{L}\p{Nd}_]+)\}|\$([\p{L}\p{Nd}_]+)`)
+
+// xmvWildcardIndexRegex matches a name of reference which regexp.Expand
+// treats as an index of a wildcard.
+var xmvWildcardIndexRegex = regexp.MustCompile(`^[1-9][0-9]{0,7}$`)
+
+// inverseXmv returns a source and destination of an xmv action which undoes
+// an xmv action of a given source and destination.
+// It's reversible only if the destination refers to each wildcard in the
+// source exactly once, because otherwise some parts of the original
+// addresses are lost.
+func inverseXmv(source string, destination string) (string, string, error) {
+	// A literal wildcard in the destination would be a wildcard in the
+	// inverse source, which matches unrelated resources.
+	if strings.Contains(destination, wildcardChar) {
+		return "", "", fmt.Errorf("a destination with a literal %q is not reversible: %s", wildcardChar, destination)
+	}
+	// Adjacent wildcards split a matched address ambiguously.
+	if strings.Contains(source, wildcardChar+wildcardChar) {
+		return "", "", fmt.Errorf("a source with adjacent wildcards is not reversible: %s", source)
+	}
+	n := strings.Count(source, wildcardChar)
+	if n == 0 {
+		// The destination is used as it is without expansion.
+		return destination, source, nil
+	}
+
+	// order is a list of wildcard indexes in order of references in the
+	// destination.
+	order := []int{}
+	var b strings.Builder
+	last := 0
+	for _, m := range xmvReferenceRegex.FindAllStringSubmatchIndex(destination, -1) {
+		b.WriteString(destination[last:m[0]])
+		last = m[1]
+		if m[2] < 0 && m[4] < 0 {
+			// An escaped $ is expanded to a literal $.
+			b.WriteString("$")
+			continue
+		}
+
+		var ref string
+		if m[2] >= 0 {
+			ref = destination[m[2]:m[3]]
+		} else {
+			ref = destination[m[4]:m[5]]
+			// Go's regexp expands $1x as ${1x}, not ${1} followed by x.
+			if j := strings.IndexFunc(ref, isNotDigit); j > 0 {
+				return "", "", fmt.Errorf("an ambiguous reference in the destination is not reversible, use ${%s} instead: %s", ref[:j], destination)
+			}
+		}
+		if !xmvWildcardIndexRegex.MatchString(ref) {
+			return "", "", fmt.Errorf("the destination refers to an unknown wildcard $%s: %s", ref, destination)
+		}
+		i, _ := strconv.Atoi(ref)
+		if i > n {
+			return "", "", fmt.Errorf("the destination refers to an unknown wildcard $%s: %s", ref, destination)
+		}
+		if slices.Contains(order, i) {
+			return "", "", fmt.Errorf("the destination refers to the wildcard $%d more than once: %s", i, destination)
+		}
+		order = append(order, i)
+		b.WriteString(wildcardChar)
+	}
+	b.WriteString(destination[last:])
+	if len(order) != n {
+		return "", "", fmt.Errorf("the destination doesn't refer to all wildcards in the source: %s %s", source, destination)
+	}
+	inverseSource := b.String()
+	if strings.Contains(inverseSource, wildcardChar+wildcardChar) {
+		return "", "", fmt.Errorf("adjacent references in the destination are not reversible: %s", destination)
+	}
+
+	// Replace the i-th wildcard in the source with a reference to its
+	// position in the inverse source. A literal $ in the source needs to be
+	// escaped in the inverse destination.
+	parts := strings.Split(source, wildcardChar)
+	var d strings.Builder
+	for i, part := range parts {
+		d.WriteString(strings.ReplaceAll(part, "$", "$$"))
+		if i < len(parts)-1 {
+			d.WriteString(fmt.Sprintf("${%d}", slices.Index(order, i+1)+1))
+		}
+	}
+	return inverseSource, d.String(), nil
+}
+
+// isNotDigit returns true if a given rune is not an ASCII digit.
+func isNotDigit(r rune) bool {
+	return r < '0' || r > '9'
+}
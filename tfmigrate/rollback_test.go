@@ -0,0 +1,387 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRollbackMigration(t *testing.T) {
+	cases := []struct {
+		desc string
+		mc   *MigrationConfig
+		want *MigrationConfig
+		ok   bool
+	}{
+		{
+			desc: "state",
+			mc: &MigrationConfig{
+				Type:      "state",
+				Name:      "test",
+				Owner:     "team-a",
+				Unprotect: []string{"null_resource.*"},
+				DependsOn: []string{"20201109000001_test1.hcl"},
+				Migrator: &StateMigratorConfig{
+					Dir:       "dir1",
+					Workspace: "work1",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						`mv 'null_resource.bar["a b"]' null_resource.bar2`,
+						"xmv null_resource.* module.baz.null_resource.$1",
+					},
+					Force: true,
+				},
+			},
+			want: &MigrationConfig{
+				Type:      "state",
+				Name:      "test",
+				Owner:     "team-a",
+				Unprotect: []string{"null_resource.*"},
+				Migrator: &StateMigratorConfig{
+					Dir:       "dir1",
+					Workspace: "work1",
+					Actions: []string{
+						"xmv module.baz.null_resource.* null_resource.${1}",
+						`mv null_resource.bar2 null_resource.bar["a b"]`,
+						"mv null_resource.foo2 null_resource.foo",
+					},
+					Force: true,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "state rm is not reversible",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						"rm null_resource.bar",
+					},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "state import is not reversible",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"import null_resource.foo foo",
+					},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "state replace-provider is not reversible",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						"replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+					},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "state replace-provider with rollback_actions",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+					},
+					RollbackActions: []string{
+						"replace-provider registry.terraform.io/hashicorp/null registry.terraform.io/-/null",
+					},
+				},
+			},
+			want: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"replace-provider registry.terraform.io/hashicorp/null registry.terraform.io/-/null",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "state rollback_actions",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"rm null_resource.foo",
+					},
+					RollbackActions: []string{
+						"import null_resource.foo foo",
+					},
+				},
+			},
+			want: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{
+						"import null_resource.foo foo",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi_state",
+			mc: &MigrationConfig{
+				Type: "multi_state",
+				Name: "test",
+				Migrator: &MultiStateMigratorConfig{
+					FromDir:       "dir1",
+					ToDir:         "dir2",
+					FromWorkspace: "work1",
+					ToWorkspace:   "work2",
+					FromSkipPlan:  true,
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						"xmv null_resource.bar* null_resource.baz$1",
+					},
+					FromBackendConfig: []string{"s3.tfbackend"},
+				},
+			},
+			want: &MigrationConfig{
+				Type: "multi_state",
+				Name: "test",
+				Migrator: &MultiStateMigratorConfig{
+					FromDir:       "dir2",
+					ToDir:         "dir1",
+					FromWorkspace: "work2",
+					ToWorkspace:   "work1",
+					ToSkipPlan:    true,
+					Actions: []string{
+						"xmv null_resource.baz* null_resource.bar${1}",
+						"mv null_resource.foo2 null_resource.foo",
+					},
+					ToBackendConfig: []string{"s3.tfbackend"},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi_state with to_dir",
+			mc: &MigrationConfig{
+				Type: "multi_state",
+				Name: "test",
+				Migrator: &MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo dir3",
+					},
+				},
+			},
+			ok: false,
+		},
+		{
+			desc: "state with mapping",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir:     "dir1",
+					Mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+				},
+			},
+			want: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir:     "dir1",
+					Actions: []string{},
+					Mapping: map[string]string{"null_resource.foo2": "null_resource.foo"},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "state with absent",
+			mc: &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir:     "dir1",
+					Mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+					Absent:  []string{"null_resource.bar"},
+				},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := RollbackMigration(tc.mc)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInverseXmv(t *testing.T) {
+	cases := []struct {
+		desc        string
+		source      string
+		destination string
+		wantSource  string
+		wantDest    string
+		ok          bool
+	}{
+		{
+			desc:        "no wildcard",
+			source:      "null_resource.foo",
+			destination: "null_resource.bar",
+			wantSource:  "null_resource.bar",
+			wantDest:    "null_resource.foo",
+			ok:          true,
+		},
+		{
+			desc:        "single wildcard",
+			source:      "null_resource.*",
+			destination: "module.foo.null_resource.$1",
+			wantSource:  "module.foo.null_resource.*",
+			wantDest:    "null_resource.${1}",
+			ok:          true,
+		},
+		{
+			desc:        "swapped wildcards",
+			source:      "*.foo_*",
+			destination: "${2}.bar_${1}",
+			wantSource:  "*.bar_*",
+			wantDest:    "${2}.foo_${1}",
+			ok:          true,
+		},
+		{
+			desc:        "unreferenced wildcard",
+			source:      "*.*",
+			destination: "null_resource.$2",
+			ok:          false,
+		},
+		{
+			desc:        "duplicate references",
+			source:      "null_resource.*",
+			destination: "null_resource.${1}_${1}",
+			ok:          false,
+		},
+		{
+			desc:        "unknown reference",
+			source:      "null_resource.*",
+			destination: "null_resource.$2",
+			ok:          false,
+		},
+		{
+			desc:        "ambiguous reference",
+			source:      "null_resource.*",
+			destination: "null_resource.$1x",
+			ok:          false,
+		},
+		{
+			desc:        "adjacent references",
+			source:      "null_resource.*_*",
+			destination: "null_resource.${1}${2}",
+			ok:          false,
+		},
+		{
+			desc:        "literal dollar",
+			source:      "null_resource.a$*",
+			destination: "null_resource.b$$${1}",
+			wantSource:  "null_resource.b$*",
+			wantDest:    "null_resource.a$$${1}",
+			ok:          true,
+		},
+		{
+			desc:        "adjacent wildcards",
+			source:      "null_resource.**",
+			destination: "null_resource.${1}_${2}",
+			ok:          false,
+		},
+		{
+			desc:        "literal wildcard without wildcards in the source",
+			source:      "null_resource.foo",
+			destination: "null_resource.*",
+			ok:          false,
+		},
+		{
+			desc:        "literal wildcard",
+			source:      "null_resource.*",
+			destination: "null_resource.*$1",
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			gotSource, gotDest, err := inverseXmv(tc.source, tc.destination)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s %s", gotSource, gotDest)
+			}
+			if tc.ok && (gotSource != tc.wantSource || gotDest != tc.wantDest) {
+				t.Errorf("got: %s %s, want: %s %s", gotSource, gotDest, tc.wantSource, tc.wantDest)
+			}
+		})
+	}
+}
+
+func FuzzInverseXmv(f *testing.F) {
+	f.Add("null_resource.*", "module.foo.null_resource.$1")
+	f.Add("*.foo_*", "${2}.bar_${1}")
+	f.Add("null_resource.*_*", "null_resource.${1}${2}")
+	f.Add("null_resource.*", "null_resource.$1x")
+	f.Add("null_resource.foo", "null_resource.bar")
+
+	f.Fuzz(func(t *testing.T, source string, destination string) {
+		s1, d1, err := inverseXmv(source, destination)
+		if err != nil {
+			return
+		}
+		if got, want := strings.Count(s1, wildcardChar), strings.Count(source, wildcardChar); got != want {
+			t.Fatalf("the inverse must have the same number of wildcards, got: %d, want: %d", got, want)
+		}
+
+		// The inverse of the inverse must be reversible again, and it must
+		// return the same inverse as the first one.
+		s2, d2, err := inverseXmv(s1, d1)
+		if err != nil {
+			t.Fatalf("failed to inverse the inverse %q %q: %s", s1, d1, err)
+		}
+		if s2 != source {
+			t.Errorf("the inverse of the inverse must have the original source, got: %q, want: %q", s2, source)
+		}
+		s3, d3, err := inverseXmv(s2, d2)
+		if err != nil {
+			t.Fatalf("failed to inverse %q %q: %s", s2, d2, err)
+		}
+		if s3 != s1 || d3 != d1 {
+			t.Errorf("got: %q %q, want: %q %q", s3, d3, s1, d1)
+		}
+	})
+}
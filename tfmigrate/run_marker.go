@@ -0,0 +1,98 @@
+package tfmigrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/clock"
+)
+
+// RunMarkerFileName is a name of file which marks a working directory as in
+// use by a running migration. It is removed after switching back to remote,
+// so that a leftover means the run crashed or is still alive.
+const RunMarkerFileName = "_tfmigrate_run.json"
+
+// OverrideFileName is a name of the override file to switch the backend to
+// local temporarily.
+const OverrideFileName = "_tfmigrate_override.tf"
+
+// RunMarker is an owner of a working directory written by a running migration.
+type RunMarker struct {
+	// PID is a process ID of the run.
+	PID int `json:"pid"`
+	// Hostname is a host name where the run is executed.
+	Hostname string `json:"hostname"`
+	// StartedAt is a timestamp when the run started.
+	StartedAt time.Time `json:"started_at"`
+	// Workspace is a workspace which was selected before the run switched it.
+	Workspace string `json:"workspace"`
+}
+
+// newRunMarker returns a new RunMarker for the current process.
+func newRunMarker(ctx context.Context, workspace string) *RunMarker {
+	hostname, _ := os.Hostname()
+	return &RunMarker{
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		StartedAt: clock.Now(ctx).UTC(),
+		Workspace: workspace,
+	}
+}
+
+// writeRunMarker writes a run marker to a given dir.
+func writeRunMarker(dir string, m *RunMarker) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, RunMarkerFileName), b, 0600); err != nil {
+		return fmt.Errorf("failed to write run marker: %s", err)
+	}
+	return nil
+}
+
+// removeRunMarker removes a run marker in a given dir.
+// If it doesn't exist, no-op.
+func removeRunMarker(dir string) error {
+	if err := os.Remove(filepath.Join(dir, RunMarkerFileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove run marker: %s", err)
+	}
+	return nil
+}
+
+// ReadRunMarker reads a run marker in a given dir.
+// It returns nil if the marker doesn't exist.
+func ReadRunMarker(dir string) (*RunMarker, error) {
+	b, err := os.ReadFile(filepath.Join(dir, RunMarkerFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read run marker: %s", err)
+	}
+
+	var m RunMarker
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse run marker: %s", err)
+	}
+	return &m, nil
+}
+
+// Alive returns true if the run may still be alive.
+// A run on another host is assumed to be alive because we can't confirm it.
+func (m *RunMarker) Alive() bool {
+	hostname, _ := os.Hostname()
+	if m.Hostname != hostname {
+		return true
+	}
+	return processExists(m.PID)
+}
+
+// String returns a human-readable owner of the run.
+func (m *RunMarker) String() string {
+	return fmt.Sprintf("pid=%d, host=%s, started_at=%s", m.PID, m.Hostname, m.StartedAt.Format(time.RFC3339))
+}
@@ -0,0 +1,79 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// sourceKeyAttributes is a list of attributes shown for source resources.
+// They are common identifiers across providers. Attributes which don't exist
+// in a resource are just ignored.
+var sourceKeyAttributes = []string{"id", "name", "arn"}
+
+// maxShownSourceInstances is a maximum number of resource instances shown for
+// a single source address, such as a module which contains many resources.
+const maxShownSourceInstances = 10
+
+// sourceAddresses returns a list of source addresses of a given action,
+// which must exist in state before running it.
+// It returns nil for actions which don't have sources, such as import, and
+// actions whose sources cannot be checked, such as xmv with wildcards.
+func sourceAddresses(action StateAction) []string {
+	switch a := action.(type) {
+	case *StateMvAction:
+		return []string{a.source}
+	case *StateRmAction:
+		return a.addresses
+	default:
+		return nil
+	}
+}
+
+// checkSources verifies that all source resources of a given action exist in
+// a given state, and logs their key attributes, so that users can see what
+// they're about to move or remove.
+func checkSources(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State, action StateAction) error {
+	for _, address := range sourceAddresses(action) {
+		// A source address may be a module or a resource with multiple
+		// instances, so we list instances under it first.
+		instances, err := tf.StateList(ctx, state, []string{address})
+		if err != nil {
+			return err
+		}
+		if len(instances) == 0 {
+			return fmt.Errorf("source resource not found in state: %s, action: %s", address, action)
+		}
+
+		for i, instance := range instances {
+			if i == maxShownSourceInstances {
+				logging.FromContext(ctx).Printf("[INFO] [migrator@%s] ... and %d more in %s\n", tf.Dir(), len(instances)-i, address)
+				break
+			}
+			attrs, err := tf.StateShow(ctx, state, instance)
+			if err != nil {
+				return err
+			}
+			logging.FromContext(ctx).Printf("[INFO] [migrator@%s] source %s: %s\n", tf.Dir(), instance, formatKeyAttributes(attrs))
+		}
+	}
+	return nil
+}
+
+// formatKeyAttributes returns key attributes as a string like
+// `id="sg-123" name="foo"`.
+func formatKeyAttributes(attrs map[string]string) string {
+	kvs := []string{}
+	for _, k := range sourceKeyAttributes {
+		if v, ok := attrs[k]; ok {
+			kvs = append(kvs, fmt.Sprintf("%s=%q", k, v))
+		}
+	}
+	if len(kvs) == 0 {
+		return "(no key attributes)"
+	}
+	return strings.Join(kvs, " ")
+}
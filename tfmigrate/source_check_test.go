@@ -0,0 +1,118 @@
+package tfmigrate
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestSourceAddresses(t *testing.T) {
+	cases := []struct {
+		desc   string
+		action StateAction
+		want   []string
+	}{
+		{
+			desc:   "mv",
+			action: NewStateMvAction("null_resource.foo", "null_resource.foo2"),
+			want:   []string{"null_resource.foo"},
+		},
+		{
+			desc:   "rm",
+			action: NewStateRmAction([]string{"null_resource.foo", "module.bar"}),
+			want:   []string{"null_resource.foo", "module.bar"},
+		},
+		{
+			desc:   "import",
+			action: NewStateImportAction("null_resource.foo", "foo"),
+			want:   nil,
+		},
+		{
+			desc:   "xmv",
+			action: NewStateXmvAction("null_resource.*", "null_resource.${1}2"),
+			want:   nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := sourceAddresses(tc.action)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatKeyAttributes(t *testing.T) {
+	cases := []struct {
+		desc  string
+		attrs map[string]string
+		want  string
+	}{
+		{
+			desc:  "key attributes",
+			attrs: map[string]string{"name": "foo bar", "id": "sg-123", "vpc_id": "vpc-123"},
+			want:  `id="sg-123" name="foo bar"`,
+		},
+		{
+			desc:  "no key attributes",
+			attrs: map[string]string{"vpc_id": "vpc-123"},
+			want:  "(no key attributes)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := formatKeyAttributes(tc.attrs)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAccStateMigratorPlanWithCheckSources(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+
+	backend := tfexec.GetTestAccBackendS3Config(t.Name())
+
+	source := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+`
+
+	workspace := "default"
+	tf := tfexec.SetupTestAccWithApply(t, workspace, backend+source)
+	ctx := context.Background()
+
+	updatedSource := `
+resource "null_resource" "foo2" {}
+`
+
+	tfexec.UpdateTestAccSource(t, tf, backend+updatedSource)
+
+	actions := []StateAction{
+		NewStateMvAction("null_resource.foo", "null_resource.foo2"),
+		NewStateRmAction([]string{"null_resource.bar"}),
+	}
+
+	o := &MigratorOption{CheckSources: true}
+	m := NewStateMigrator(tf.Dir(), workspace, actions, o, false, false)
+	err := m.Plan(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+
+	actions = []StateAction{
+		NewStateRmAction([]string{"null_resource.baz"}),
+	}
+	m = NewStateMigrator(tf.Dir(), workspace, actions, o, true, false)
+	err = m.Plan(ctx)
+	if err == nil || !strings.Contains(err.Error(), "source resource not found in state: null_resource.baz") {
+		t.Fatalf("expected to return a not found error, but got: %v", err)
+	}
+}
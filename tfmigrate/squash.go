@@ -0,0 +1,289 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SquashStateMigrations merges a given list of state migrations into a
+// single equivalent migration with a given name.
+// All migrations must work with the same directory, workspace and env.
+// Chained mv actions are collapsed, and rm actions for resources which have
+// been moved or imported in the migrations are folded into them.
+// Actions which cannot be analyzed statically such as xmv, replace-provider
+// and action plugins are kept as they are, and no actions are moved across
+// them.
+func SquashStateMigrations(mcs []*MigrationConfig, name string) (*MigrationConfig, error) {
+	if len(mcs) == 0 {
+		return nil, fmt.Errorf("no migrations to squash")
+	}
+
+	var base *StateMigratorConfig
+	owner := mcs[0].Owner
+	approvedBy := []string{}
+	unprotect := []string{}
+	actions := []string{}
+	force := false
+	for _, mc := range mcs {
+		m, ok := mc.Migrator.(*StateMigratorConfig)
+		if !ok {
+			return nil, fmt.Errorf("squashing a migration of type %s is not supported: %s", mc.Type, mc.Name)
+		}
+		if mc.Skip {
+			return nil, fmt.Errorf("failed to squash a migration skipped by skip_if: %s", mc.Name)
+		}
+		if len(m.Mapping) != 0 || len(m.Absent) != 0 || len(m.Imports) != 0 {
+			return nil, fmt.Errorf("squashing a migration with mapping, absent or imports is not supported: %s", mc.Name)
+		}
+		if mc.Hooks != nil {
+			return nil, fmt.Errorf("squashing a migration with hooks is not supported: %s", mc.Name)
+		}
+		if len(m.ActionConfigs) != 0 {
+			return nil, fmt.Errorf("squashing a migration with action blocks is not supported: %s", mc.Name)
+		}
+		if mc.Owner != owner {
+			return nil, fmt.Errorf("failed to squash migrations with different owners: %s, %s", owner, mc.Owner)
+		}
+
+		if base == nil {
+			base = m
+		} else {
+			if squashDir(m.Dir) != squashDir(base.Dir) {
+				return nil, fmt.Errorf("failed to squash migrations with different dirs: %s, %s", squashDir(base.Dir), squashDir(m.Dir))
+			}
+			if squashWorkspace(m.Workspace) != squashWorkspace(base.Workspace) {
+				return nil, fmt.Errorf("failed to squash migrations with different workspaces: %s, %s", squashWorkspace(base.Workspace), squashWorkspace(m.Workspace))
+			}
+			if len(m.Env) != len(base.Env) || (len(m.Env) > 0 && !reflect.DeepEqual(m.Env, base.Env)) {
+				return nil, fmt.Errorf("failed to squash migrations with different env: %s", mc.Name)
+			}
+			if len(m.VarFiles) != len(base.VarFiles) || (len(m.VarFiles) > 0 && !reflect.DeepEqual(m.VarFiles, base.VarFiles)) {
+				return nil, fmt.Errorf("failed to squash migrations with different var_files: %s", mc.Name)
+			}
+			if len(m.Vars) != len(base.Vars) || (len(m.Vars) > 0 && !reflect.DeepEqual(m.Vars, base.Vars)) {
+				return nil, fmt.Errorf("failed to squash migrations with different vars: %s", mc.Name)
+			}
+			if m.SkipPlan != base.SkipPlan {
+				return nil, fmt.Errorf("failed to squash migrations with different to_skip_plan: %s", mc.Name)
+			}
+			if m.ExpectNoChanges != base.ExpectNoChanges {
+				return nil, fmt.Errorf("failed to squash migrations with different expect_no_changes: %s", mc.Name)
+			}
+			if len(m.Allow) != len(base.Allow) || (len(m.Allow) > 0 && !reflect.DeepEqual(m.Allow, base.Allow)) {
+				return nil, fmt.Errorf("failed to squash migrations with different allow: %s", mc.Name)
+			}
+		}
+
+		for _, team := range mc.ApprovedBy {
+			if !slices.Contains(approvedBy, team) {
+				approvedBy = append(approvedBy, team)
+			}
+		}
+		for _, pattern := range mc.Unprotect {
+			if !slices.Contains(unprotect, pattern) {
+				unprotect = append(unprotect, pattern)
+			}
+		}
+		actions = append(actions, m.Actions...)
+		force = force || m.Force
+	}
+	sort.Strings(approvedBy)
+
+	squashed, err := squashActions(actions)
+	if err != nil {
+		return nil, err
+	}
+	if len(squashed) == 0 {
+		return nil, fmt.Errorf("all actions cancel each other out")
+	}
+
+	mc := &MigrationConfig{
+		Type:  "state",
+		Name:  name,
+		Owner: owner,
+		Migrator: &StateMigratorConfig{
+			Dir:             base.Dir,
+			Workspace:       base.Workspace,
+			Env:             base.Env,
+			VarFiles:        base.VarFiles,
+			Vars:            base.Vars,
+			Actions:         squashed,
+			Force:           force,
+			SkipPlan:        base.SkipPlan,
+			ExpectNoChanges: base.ExpectNoChanges,
+			Allow:           base.Allow,
+		},
+	}
+	if len(approvedBy) > 0 {
+		mc.ApprovedBy = approvedBy
+	}
+	if len(unprotect) > 0 {
+		mc.Unprotect = unprotect
+	}
+	return mc, nil
+}
+
+// squashDir returns a directory of a state migration with the default applied.
+func squashDir(dir string) string {
+	if len(dir) == 0 {
+		return "."
+	}
+	return dir
+}
+
+// squashWorkspace returns a workspace of a state migration with the default
+// applied.
+func squashWorkspace(workspace string) string {
+	if len(workspace) == 0 {
+		return "default"
+	}
+	return workspace
+}
+
+// squashOp is a state action in the middle of squashing.
+type squashOp struct {
+	// args is a list of arguments of the action such as mv, rm and import.
+	// It is nil for a barrier.
+	args []string
+	// raw is an original action string of a barrier.
+	raw string
+}
+
+// overlaps returns true if the op refers to a given address or an address
+// which contains it or is contained by it.
+func (op *squashOp) overlaps(address string) bool {
+	addresses := op.args[1:]
+	if op.args[0] == "import" {
+		// The last argument of import is an ID.
+		addresses = op.args[1:2]
+	}
+	for _, a := range addresses {
+		if hasAddressPrefix(a, address) || hasAddressPrefix(address, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// squashActions returns an equivalent list of state actions for a given list.
+// The rules are the following:
+//   - mv A B after mv X A becomes mv X B, and is dropped if X equals B.
+//   - mv A B after import A ID becomes import B ID.
+//   - rm A after mv X A becomes rm X.
+//   - rm A after import A ID drops both.
+//   - rm A after rm A is dropped.
+//
+// An action is folded into the last previous one only if no actions between
+// them refer to the same resources or modules. The other actions work as a
+// barrier, and no actions are folded across them.
+func squashActions(actions []string) ([]string, error) {
+	ops := []*squashOp{}
+
+	// find returns an index of the last op which refers to any of given
+	// addresses after the last barrier, or -1 if not found.
+	find := func(addresses ...string) int {
+		for i := len(ops) - 1; i >= 0; i-- {
+			if ops[i].args == nil {
+				return -1
+			}
+			for _, address := range addresses {
+				if ops[i].overlaps(address) {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	remove := func(i int) {
+		ops = append(ops[:i], ops[i+1:]...)
+	}
+
+	for _, cmdStr := range actions {
+		args, err := splitStateAction(cmdStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("state action is empty: %s", cmdStr)
+		}
+
+		switch args[0] {
+		case "mv":
+			if len(args) != 3 {
+				return nil, fmt.Errorf("state mv action is invalid: %s", cmdStr)
+			}
+			source, destination := args[1], args[2]
+			if i := find(source, destination); i >= 0 {
+				prev := ops[i].args
+				switch {
+				case prev[0] == "mv" && prev[2] == source && prev[1] == destination:
+					remove(i)
+					continue
+				case prev[0] == "mv" && prev[2] == source && !ops[i].overlaps(destination):
+					ops[i].args = []string{"mv", prev[1], destination}
+					continue
+				case prev[0] == "import" && prev[1] == source:
+					ops[i].args = []string{"import", destination, prev[2]}
+					continue
+				}
+			}
+			ops = append(ops, &squashOp{args: args})
+
+		case "rm":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("state rm action is invalid: %s", cmdStr)
+			}
+			for _, address := range args[1:] {
+				if i := find(address); i >= 0 {
+					prev := ops[i].args
+					switch {
+					case prev[0] == "mv" && prev[2] == address:
+						ops[i].args = []string{"rm", prev[1]}
+						continue
+					case prev[0] == "import" && prev[1] == address:
+						remove(i)
+						continue
+					case prev[0] == "rm" && prev[1] == address:
+						continue
+					}
+				}
+				ops = append(ops, &squashOp{args: []string{"rm", address}})
+			}
+
+		case "import":
+			if len(args) != 3 {
+				return nil, fmt.Errorf("state import action is invalid: %s", cmdStr)
+			}
+			ops = append(ops, &squashOp{args: args})
+
+		default:
+			ops = append(ops, &squashOp{raw: cmdStr})
+		}
+	}
+
+	squashed := make([]string, 0, len(ops))
+	for _, op := range ops {
+		if op.args == nil {
+			squashed = append(squashed, op.raw)
+			continue
+		}
+		quoted := make([]string, 0, len(op.args))
+		for _, arg := range op.args {
+			quoted = append(quoted, quoteStateActionArg(arg))
+		}
+		squashed = append(squashed, strings.Join(quoted, " "))
+	}
+	return squashed, nil
+}
+
+// quoteStateActionArg returns a given argument of a state action as it is if
+// it's parsed back to itself, otherwise returns it in single quotes.
+func quoteStateActionArg(arg string) string {
+	if args, err := splitStateAction(arg); err == nil && len(args) == 1 && args[0] == arg {
+		return arg
+	}
+	return singleQuote(arg)
+}
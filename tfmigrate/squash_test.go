@@ -0,0 +1,382 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSquashActions(t *testing.T) {
+	cases := []struct {
+		desc    string
+		actions []string
+		want    []string
+		ok      bool
+	}{
+		{
+			desc: "chained mv",
+			actions: []string{
+				"mv null_resource.foo null_resource.bar",
+				"mv null_resource.bar null_resource.baz",
+				"mv null_resource.baz module.qux.null_resource.baz",
+			},
+			want: []string{
+				"mv null_resource.foo module.qux.null_resource.baz",
+			},
+			ok: true,
+		},
+		{
+			desc: "mv back to the original",
+			actions: []string{
+				"mv null_resource.foo null_resource.bar",
+				"mv null_resource.bar null_resource.foo",
+			},
+			want: []string{},
+			ok:   true,
+		},
+		{
+			desc: "mv after import",
+			actions: []string{
+				"import null_resource.foo foo",
+				"mv null_resource.foo null_resource.bar",
+			},
+			want: []string{
+				"import null_resource.bar foo",
+			},
+			ok: true,
+		},
+		{
+			desc: "rm after mv",
+			actions: []string{
+				"mv null_resource.foo null_resource.bar",
+				"rm null_resource.bar null_resource.baz",
+			},
+			want: []string{
+				"rm null_resource.foo",
+				"rm null_resource.baz",
+			},
+			ok: true,
+		},
+		{
+			desc: "rm after import",
+			actions: []string{
+				"import null_resource.foo foo",
+				"rm null_resource.foo",
+			},
+			want: []string{},
+			ok:   true,
+		},
+		{
+			desc: "duplicate rm",
+			actions: []string{
+				"rm null_resource.foo",
+				"rm null_resource.bar null_resource.foo",
+			},
+			want: []string{
+				"rm null_resource.foo",
+				"rm null_resource.bar",
+			},
+			ok: true,
+		},
+		{
+			desc: "not folded across an action referring to the same module",
+			actions: []string{
+				"mv null_resource.foo module.bar.null_resource.foo",
+				"mv module.bar module.baz",
+				"mv module.bar.null_resource.foo null_resource.qux",
+			},
+			want: []string{
+				"mv null_resource.foo module.bar.null_resource.foo",
+				"mv module.bar module.baz",
+				"mv module.bar.null_resource.foo null_resource.qux",
+			},
+			ok: true,
+		},
+		{
+			desc: "not folded across a barrier",
+			actions: []string{
+				"mv null_resource.foo null_resource.bar",
+				"xmv null_resource.* module.baz.null_resource.$1",
+				"mv null_resource.bar null_resource.qux",
+			},
+			want: []string{
+				"mv null_resource.foo null_resource.bar",
+				"xmv null_resource.* module.baz.null_resource.$1",
+				"mv null_resource.bar null_resource.qux",
+			},
+			ok: true,
+		},
+		{
+			desc: "unrelated actions between",
+			actions: []string{
+				"mv null_resource.foo null_resource.bar",
+				"import null_resource.baz baz",
+				"mv null_resource.bar null_resource.qux",
+			},
+			want: []string{
+				"mv null_resource.foo null_resource.qux",
+				"import null_resource.baz baz",
+			},
+			ok: true,
+		},
+		{
+			desc: "keys with spaces",
+			actions: []string{
+				`mv null_resource.foo["a b"] null_resource.bar["a b"]`,
+				`mv null_resource.bar["a b"] null_resource.baz["c d"]`,
+			},
+			want: []string{
+				`mv null_resource.foo["a b"] null_resource.baz["c d"]`,
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid mv",
+			actions: []string{
+				"mv null_resource.foo",
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := squashActions(tc.actions)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSquashStateMigrations(t *testing.T) {
+	cases := []struct {
+		desc string
+		mcs  []*MigrationConfig
+		want *MigrationConfig
+		ok   bool
+	}{
+		{
+			desc: "simple",
+			mcs: []*MigrationConfig{
+				{
+					Type:       "state",
+					Name:       "foo",
+					Owner:      "team1",
+					ApprovedBy: []string{"team3"},
+					Migrator: &StateMigratorConfig{
+						Dir:     "dir1",
+						Actions: []string{"mv null_resource.foo null_resource.bar"},
+					},
+				},
+				{
+					Type:       "state",
+					Name:       "bar",
+					Owner:      "team1",
+					ApprovedBy: []string{"team2", "team3"},
+					Migrator: &StateMigratorConfig{
+						Dir:       "dir1",
+						Workspace: "default",
+						Actions:   []string{"mv null_resource.bar null_resource.baz"},
+						Force:     true,
+					},
+				},
+			},
+			want: &MigrationConfig{
+				Type:       "state",
+				Name:       "squashed",
+				Owner:      "team1",
+				ApprovedBy: []string{"team2", "team3"},
+				Migrator: &StateMigratorConfig{
+					Dir:     "dir1",
+					Actions: []string{"mv null_resource.foo null_resource.baz"},
+					Force:   true,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "different dirs",
+			mcs: []*MigrationConfig{
+				{
+					Type:     "state",
+					Name:     "foo",
+					Migrator: &StateMigratorConfig{Actions: []string{"rm null_resource.foo"}},
+				},
+				{
+					Type:     "state",
+					Name:     "bar",
+					Migrator: &StateMigratorConfig{Dir: "dir1", Actions: []string{"rm null_resource.bar"}},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "different env",
+			mcs: []*MigrationConfig{
+				{
+					Type:     "state",
+					Name:     "foo",
+					Migrator: &StateMigratorConfig{Actions: []string{"rm null_resource.foo"}},
+				},
+				{
+					Type:     "state",
+					Name:     "bar",
+					Migrator: &StateMigratorConfig{Env: map[string]string{"FOO": "bar"}, Actions: []string{"rm null_resource.bar"}},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "different vars",
+			mcs: []*MigrationConfig{
+				{
+					Type:     "state",
+					Name:     "foo",
+					Migrator: &StateMigratorConfig{Vars: map[string]string{"env": "prod"}, Actions: []string{"rm null_resource.foo"}},
+				},
+				{
+					Type:     "state",
+					Name:     "bar",
+					Migrator: &StateMigratorConfig{Vars: map[string]string{"env": "stg"}, Actions: []string{"rm null_resource.bar"}},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "different allow",
+			mcs: []*MigrationConfig{
+				{
+					Type:     "state",
+					Name:     "foo",
+					Migrator: &StateMigratorConfig{Allow: []string{"update:null_resource.baz"}, Actions: []string{"rm null_resource.foo"}},
+				},
+				{
+					Type:     "state",
+					Name:     "bar",
+					Migrator: &StateMigratorConfig{Actions: []string{"rm null_resource.bar"}},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "mapping",
+			mcs: []*MigrationConfig{
+				{
+					Type:     "state",
+					Name:     "foo",
+					Migrator: &StateMigratorConfig{Mapping: map[string]string{"null_resource.foo": "null_resource.foo2"}},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "hooks",
+			mcs: []*MigrationConfig{
+				{
+					Type:     "state",
+					Name:     "foo",
+					Hooks:    &HooksConfig{PostApply: []string{"./notify.sh"}},
+					Migrator: &StateMigratorConfig{Actions: []string{"mv null_resource.foo null_resource.foo2"}},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "action blocks",
+			mcs: []*MigrationConfig{
+				{
+					Type: "state",
+					Name: "foo",
+					Migrator: &StateMigratorConfig{
+						Actions: []string{"mv null_resource.foo null_resource.foo2"},
+						ActionConfigs: []ActionConfig{
+							{Command: "mv null_resource.foo null_resource.foo2", Force: true},
+						},
+					},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "multi_state",
+			mcs: []*MigrationConfig{
+				{
+					Type:     "multi_state",
+					Name:     "foo",
+					Migrator: &MultiStateMigratorConfig{FromDir: "dir1", ToDir: "dir2", Actions: []string{"mv null_resource.foo null_resource.foo"}},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "all actions cancel out",
+			mcs: []*MigrationConfig{
+				{
+					Type:     "state",
+					Name:     "foo",
+					Migrator: &StateMigratorConfig{Actions: []string{"import null_resource.foo foo"}},
+				},
+				{
+					Type:     "state",
+					Name:     "bar",
+					Migrator: &StateMigratorConfig{Actions: []string{"rm null_resource.foo"}},
+				},
+			},
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := SquashStateMigrations(tc.mcs, "squashed")
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func FuzzQuoteStateActionArg(f *testing.F) {
+	for _, arg := range []string{"null_resource.foo", `null_resource.foo["a b"]`, "it's", `"quoted"`, `back\slash`, "-flag", "$HOME", " "} {
+		f.Add(arg)
+	}
+
+	f.Fuzz(func(t *testing.T, arg string) {
+		if len(arg) == 0 || !utf8.ValidString(arg) {
+			// An empty argument is never written to an action, and a migration
+			// file must be encoded in UTF-8.
+			t.Skip()
+		}
+		got, err := splitStateAction("mv " + quoteStateActionArg(arg) + " " + quoteStateActionArg(arg))
+		if err != nil {
+			t.Fatalf("unexpected err: %s", err)
+		}
+		want := []string{"mv", arg, arg}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got: %#v, want: %#v", got, want)
+		}
+	})
+}
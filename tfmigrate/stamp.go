@@ -0,0 +1,106 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// DefaultStampKey is a default key of a tag stamped on resources.
+const DefaultStampKey = "tfmigrate:last-migration"
+
+// StampConfig is a config for an exec-based post-apply step which stamps
+// resources moved or imported by a migration with a tag, so that cloud-side
+// auditing can correlate resources with migrations.
+//
+// The command is executed in the working directory of each resource with its
+// address appended, after the migration has been applied. The key and value
+// of the tag are passed via the TFMIGRATE_STAMP_KEY and TFMIGRATE_STAMP_VALUE
+// environment variables, and the command is expected to set it through a
+// targeted apply or a provider API. Note that destinations of xmv are not
+// stamped, because they are not determined by the migration file alone.
+type StampConfig struct {
+	// Command is a command line to stamp a resource.
+	// It may contain spaces like a shell.
+	Command string
+	// Key is a key of the tag.
+	// Default to DefaultStampKey.
+	Key string
+}
+
+// Stamp runs the command for each resource moved or imported by a given
+// migration. The value of the tag is the name of the migration.
+// It tries all resources even if some of them fail, and returns an error
+// which lists the failed ones.
+func (c *StampConfig) Stamp(ctx context.Context, mc *MigrationConfig) error {
+	parts, err := shellwords.Parse(c.Command)
+	if err != nil {
+		return fmt.Errorf("failed to parse command of stamp: %s", err)
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("command of stamp is empty")
+	}
+
+	key := c.Key
+	if len(key) == 0 {
+		key = DefaultStampKey
+	}
+
+	expectations, err := mc.Expectations()
+	if err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for _, e := range expectations {
+		if !e.Exists {
+			continue
+		}
+
+		logging.FromContext(ctx).Printf("[INFO] [stamp@%s] stamp %s with %s=%s\n", e.Dir, e.Address, key, mc.Name)
+		env := append(os.Environ(), stampEnv(e.Env)...)
+		env = append(env,
+			"TFMIGRATE_STAMP_KEY="+key,
+			"TFMIGRATE_STAMP_VALUE="+mc.Name,
+			"TFMIGRATE_RESOURCE_ADDRESS="+e.Address,
+			"TFMIGRATE_WORKSPACE="+e.Workspace,
+		)
+		ex := tfexec.NewExecutor(e.Dir, env)
+		args := append(append([]string{}, parts[1:]...), e.Address)
+		cmd, err := ex.NewCommandContext(ctx, parts[0], args...)
+		if err == nil {
+			err = ex.Run(cmd)
+		}
+		if err != nil {
+			logging.FromContext(ctx).Printf("[WARN] [stamp@%s] failed to stamp %s: %s\n", e.Dir, e.Address, err)
+			failed = append(failed, e.Address)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to stamp resources: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// stampEnv returns a list of environment variables in the KEY=VALUE format
+// in a deterministic order.
+func stampEnv(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	vars := make([]string, 0, len(env))
+	for _, k := range keys {
+		vars = append(vars, k+"="+env[k])
+	}
+	return vars
+}
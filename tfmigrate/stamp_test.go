@@ -0,0 +1,82 @@
+package tfmigrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStampConfigStamp(t *testing.T) {
+	cases := []struct {
+		desc    string
+		command string
+		actions []string
+		want    string
+		ok      bool
+	}{
+		{
+			desc:    "stamp moved and imported resources",
+			command: `/bin/sh -c "printf '%s %s=%s %s\\n' $1 $TFMIGRATE_STAMP_KEY $TFMIGRATE_STAMP_VALUE $FOO >> stamp.log" --`,
+			actions: []string{
+				"mv null_resource.foo null_resource.foo2",
+				"rm null_resource.bar",
+				"import null_resource.baz baz",
+			},
+			want: "null_resource.foo2 tfmigrate:last-migration=test bar\nnull_resource.baz tfmigrate:last-migration=test bar\n",
+			ok:   true,
+		},
+		{
+			desc:    "nothing to stamp",
+			command: "false",
+			actions: []string{"rm null_resource.bar"},
+			ok:      true,
+		},
+		{
+			desc:    "stamp failed",
+			command: "false",
+			actions: []string{"mv null_resource.foo null_resource.foo2"},
+			ok:      false,
+		},
+		{
+			desc:    "empty command",
+			command: "",
+			actions: []string{"mv null_resource.foo null_resource.foo2"},
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			mc := &MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir:     dir,
+					Actions: tc.actions,
+					Env:     map[string]string{"FOO": "bar"},
+				},
+			}
+			c := &StampConfig{
+				Command: tc.command,
+			}
+			err := c.Stamp(context.Background(), mc)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && len(tc.want) > 0 {
+				got, err := os.ReadFile(filepath.Join(dir, "stamp.log"))
+				if err != nil {
+					t.Fatalf("failed to read stamp.log: %s", err)
+				}
+				if string(got) != tc.want {
+					t.Errorf("got: %s, want: %s", got, tc.want)
+				}
+			}
+		})
+	}
+}
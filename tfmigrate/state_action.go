@@ -23,6 +23,8 @@ type StateAction interface {
 // "rm <addresses>...
 // "import <address> <id>"
 // "xmv <source> <destination>"
+// "replace-provider <source> <destination>"
+// The list of valid formats is defined in stateActionSpecs.
 func NewStateActionFromString(cmdStr string) (StateAction, error) {
 	args, err := splitStateAction(cmdStr)
 	if err != nil {
@@ -34,57 +36,21 @@ func NewStateActionFromString(cmdStr string) (StateAction, error) {
 	}
 	actionType := args[0]
 
-	// switch by action type and parse arguments and build an action.
-	var action StateAction
-	switch actionType {
-	case "mv":
-		if len(args) != 3 {
-			return nil, fmt.Errorf("state mv action is invalid: %s", cmdStr)
-		}
-		src := args[1]
-		dst := args[2]
-		action = NewStateMvAction(src, dst)
-
-	case "replace-provider":
-		if len(args) != 3 {
-			return nil, fmt.Errorf("state replace-provider action is invalid: %s", cmdStr)
-		}
-		src := args[1]
-		dst := args[2]
-		action = NewStateReplaceProviderAction(src, dst)
-
-	case "xmv":
-		if len(args) != 3 {
-			return nil, fmt.Errorf("state xmv action is invalid: %s", cmdStr)
-		}
-		src := args[1]
-		dst := args[2]
-		action = NewStateXmvAction(src, dst)
-
-	case "rm":
-		if len(args) < 2 {
-			return nil, fmt.Errorf("state rm action is invalid: %s", cmdStr)
-		}
-		addrs := args[1:]
-		action = NewStateRmAction(addrs)
-
-	case "import":
-		if len(args) != 3 {
-			return nil, fmt.Errorf("state import action is invalid: %s", cmdStr)
-		}
-		addr := args[1]
-		id := args[2]
-		action = NewStateImportAction(addr, id)
-
-	default:
+	spec, ok := findStateActionSpec(actionType)
+	if !ok {
 		return nil, fmt.Errorf("unknown state action type: %s", cmdStr)
 	}
 
-	return action, nil
+	if err := spec.validateArgs(args[1:]); err != nil {
+		return nil, fmt.Errorf("state %s action is invalid: %s, err: %s", actionType, cmdStr, err)
+	}
+
+	return spec.newAction(args[1:])
 }
 
 // splitStateAction splits a given string like a shell.
+// String keys of unquoted addresses such as foo["bar baz"] are kept as they are.
 func splitStateAction(cmdStr string) ([]string, error) {
 	// Note that we cannot simply split it by space because the address of resource can contain spaces.
-	return shellwords.Parse(cmdStr)
+	return shellwords.Parse(quoteAddressKeys(cmdStr))
 }
@@ -66,6 +66,42 @@ func TestNewStateActionFromString(t *testing.T) {
 			want:   nil,
 			ok:     false,
 		},
+		{
+			desc:   "retype action (valid)",
+			cmdStr: "retype aws_alb aws_lb",
+			want: &StateRetypeAction{
+				source:      "aws_alb",
+				destination: "aws_lb",
+			},
+			ok: true,
+		},
+		{
+			desc:   "retype action (known rename)",
+			cmdStr: "retype aws_alb_listener",
+			want: &StateRetypeAction{
+				source:      "aws_alb_listener",
+				destination: "aws_lb_listener",
+			},
+			ok: true,
+		},
+		{
+			desc:   "retype action (unknown rename)",
+			cmdStr: "retype null_resource",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "retype action (no args)",
+			cmdStr: "retype",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "retype action (3 args)",
+			cmdStr: "retype aws_alb aws_lb aws_lb",
+			want:   nil,
+			ok:     false,
+		},
 		{
 			desc:   "xmv action (valid)",
 			cmdStr: "xmv null_resource.* null_resource.$1",
@@ -115,6 +151,26 @@ func TestNewStateActionFromString(t *testing.T) {
 			},
 			ok: true,
 		},
+		{
+			desc:   "xrm action (valid)",
+			cmdStr: "xrm module.legacy.* null_resource.foo[*]",
+			want: &StateXrmAction{
+				patterns: []string{"module.legacy.*", "null_resource.foo[*]"},
+			},
+			ok: true,
+		},
+		{
+			desc:   "xrm action (no args)",
+			cmdStr: "xrm",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "xrm action (match all)",
+			cmdStr: "xrm *",
+			want:   nil,
+			ok:     false,
+		},
 		{
 			desc:   "import action (valid)",
 			cmdStr: "import time_static.foo 2006-01-02T15:04:05Z",
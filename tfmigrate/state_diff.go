@@ -0,0 +1,58 @@
+package tfmigrate
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// StateDiff is a list of addresses in a state before and after a migration.
+type StateDiff struct {
+	// Dir is a working directory of the state.
+	Dir string
+	// Workspace is a workspace of the state.
+	Workspace string
+	// Before is a list of addresses in the current state.
+	Before []string
+	// After is a list of addresses in the new state computed by the migration.
+	After []string
+}
+
+// StateDiffReporter is an optional interface for a Migrator which reports
+// addresses in states before and after a migration computed in the last Plan
+// or Apply. It's intended to show reviewers which addresses move where.
+// They are computed only if the ShowDiff option is set.
+type StateDiffReporter interface {
+	// StateDiffs returns a list of state diffs for each working directory.
+	StateDiffs() []StateDiff
+}
+
+// newStateDiff returns a StateDiff of given states before and after a
+// migration with terraform state list.
+func newStateDiff(ctx context.Context, tf tfexec.TerraformCLI, workspace string, before *tfexec.State, after *tfexec.State) (StateDiff, error) {
+	beforeAddrs, err := stateAddresses(ctx, tf, before)
+	if err != nil {
+		return StateDiff{}, err
+	}
+	afterAddrs, err := stateAddresses(ctx, tf, after)
+	if err != nil {
+		return StateDiff{}, err
+	}
+	return StateDiff{
+		Dir:       tf.Dir(),
+		Workspace: workspace,
+		Before:    beforeAddrs,
+		After:     afterAddrs,
+	}, nil
+}
+
+// stateAddresses returns a list of addresses in a given state.
+// The terraform state list command fails for an empty state, such as a new
+// workspace, so we return an empty list without running it.
+func stateAddresses(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) ([]string, error) {
+	if len(bytes.TrimSpace(state.Bytes())) == 0 {
+		return []string{}, nil
+	}
+	return tf.StateList(ctx, state, nil)
+}
@@ -0,0 +1,122 @@
+package tfmigrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestAccStateMigratorPlanWithShowDiff(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+
+	backend := tfexec.GetTestAccBackendS3Config(t.Name())
+
+	source := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+`
+
+	workspace := "default"
+	tf := tfexec.SetupTestAccWithApply(t, workspace, backend+source)
+	ctx := context.Background()
+
+	updatedSource := `
+resource "null_resource" "foo2" {}
+resource "null_resource" "baz" {}
+`
+
+	tfexec.UpdateTestAccSource(t, tf, backend+updatedSource)
+
+	actions := []StateAction{
+		NewStateMvAction("null_resource.foo", "null_resource.foo2"),
+		NewStateRmAction([]string{"null_resource.bar"}),
+	}
+
+	o := &MigratorOption{ShowDiff: true}
+	m := NewStateMigrator(tf.Dir(), workspace, actions, o, false, false)
+	err := m.Plan(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+
+	want := []StateDiff{
+		{
+			Dir:       tf.Dir(),
+			Workspace: workspace,
+			Before:    []string{"null_resource.bar", "null_resource.baz", "null_resource.foo"},
+			After:     []string{"null_resource.baz", "null_resource.foo2"},
+		},
+	}
+	got := m.StateDiffs()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+}
+
+func TestAccMultiStateMigratorPlanWithShowDiff(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+	ctx := context.Background()
+
+	fromBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/fromDir")
+	fromSource := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+`
+	workspace := "default"
+	fromTf := tfexec.SetupTestAccWithApply(t, workspace, fromBackend+fromSource)
+
+	toBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/toDir")
+	toSource := `
+resource "null_resource" "baz" {}
+`
+	toTf := tfexec.SetupTestAccWithApply(t, workspace, toBackend+toSource)
+
+	fromUpdatedSource := `
+resource "null_resource" "bar" {}
+`
+	tfexec.UpdateTestAccSource(t, fromTf, fromBackend+fromUpdatedSource)
+
+	toUpdatedSource := `
+resource "null_resource" "foo2" {}
+resource "null_resource" "baz" {}
+`
+	tfexec.UpdateTestAccSource(t, toTf, toBackend+toUpdatedSource)
+
+	config := &MultiStateMigratorConfig{
+		FromDir: fromTf.Dir(),
+		ToDir:   toTf.Dir(),
+		Actions: []string{
+			"mv null_resource.foo null_resource.foo2",
+		},
+	}
+	m, err := config.NewMigrator(&MigratorOption{ShowDiff: true})
+	if err != nil {
+		t.Fatalf("failed to new migrator: %s", err)
+	}
+	err = m.Plan(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+
+	want := []StateDiff{
+		{
+			Dir:       fromTf.Dir(),
+			Workspace: workspace,
+			Before:    []string{"null_resource.bar", "null_resource.foo"},
+			After:     []string{"null_resource.bar"},
+		},
+		{
+			Dir:       toTf.Dir(),
+			Workspace: workspace,
+			Before:    []string{"null_resource.baz"},
+			After:     []string{"null_resource.baz", "null_resource.foo2"},
+		},
+	}
+	got := m.(StateDiffReporter).StateDiffs()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+}
@@ -29,10 +29,15 @@ func NewStateImportAction(address string, id string) *StateImportAction {
 	}
 }
 
+// String returns the action as "import <address> <id>".
+func (a *StateImportAction) String() string {
+	return "import " + a.address + " " + a.id
+}
+
 // StateUpdate updates a given state and returns a new state.
 // It imports an existing resource to state.
 func (a *StateImportAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
 	// Disable unnecessary state backup here,
 	// because we never restore state from the backup generated by each state action.
-	return tf.Import(ctx, state, a.address, a.id, "-input=false", "-no-color", "-backup=/dev/null")
+	return tf.Import(ctx, state, a.address, a.id, "-input=false", "-no-color", disableBackupOpt)
 }
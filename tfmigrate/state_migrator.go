@@ -4,9 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"os"
 
+	"github.com/minamijoyo/tfmigrate/logging"
 	"github.com/minamijoyo/tfmigrate/tfexec"
 )
 
@@ -21,10 +20,22 @@ type StateMigratorConfig struct {
 	// "mv <source> <destination>"
 	// "rm <addresses>...
 	// "import <address> <id>"
+	// In addition, action plugins defined in the config file are available.
 	// We could define strict block schema for action, but intentionally use a
 	// schema-less string to allow us to easily copy terraform state command to
 	// action.
-	Actions []string `hcl:"actions"`
+	Actions []string `hcl:"actions,optional"`
+	// ActionConfigs is a list of action blocks, which are actions with
+	// options such as force for each action. Their commands are merged into
+	// Actions in order of declaration on parsing the migration file, and the
+	// options are applied on checking terraform plan.
+	ActionConfigs []ActionConfig `hcl:"action,block"`
+	// ImportFile is a path to a mapping file of resource addresses to IDs,
+	// which is useful for importing many resources at once.
+	// A relative path is resolved from the directory of the migration file.
+	// It is expanded into import actions after actions on parsing the
+	// migration file. See ReadImportFile for details of the format.
+	ImportFile string `hcl:"import_file,optional"`
 	// Force option controls behaviour in case of unexpected diff in plan.
 	// When set forces applying even if plan shows diff.
 	Force bool `hcl:"force,optional"`
@@ -32,6 +43,41 @@ type StateMigratorConfig struct {
 	SkipPlan bool `hcl:"to_skip_plan,optional"`
 	// Workspace is the state workspace which the migration works with.
 	Workspace string `hcl:"workspace,optional"`
+	// Env is a map of environment variables passed to every terraform command
+	// for the migration, such as AWS_PROFILE.
+	Env map[string]string `hcl:"env,optional"`
+	// VarFiles is a list of variable files passed to terraform plan as
+	// -var-file options. A relative path is resolved from Dir.
+	VarFiles []string `hcl:"var_files,optional"`
+	// Vars is a map of variables passed to terraform plan as -var options.
+	Vars map[string]string `hcl:"vars,optional"`
+	// RollbackActions is a list of state actions which undo the migration.
+	// It's used by the rollback command instead of inverse actions computed
+	// from Actions, which is required to roll back irreversible actions such
+	// as rm, import and replace-provider.
+	RollbackActions []string `hcl:"rollback_actions,optional"`
+	// ExpectNoChanges asserts that terraform plan has no changes after the
+	// migration. Unlike the default check, it takes precedence over Force,
+	// and unexpected changes are reported.
+	ExpectNoChanges bool `hcl:"expect_no_changes,optional"`
+	// Allow is a list of changes allowed in terraform plan after the
+	// migration in the format of <action>:<address>, such as
+	// update:aws_iam_role.foo. Any other changes fail the plan.
+	// It takes precedence over Force.
+	Allow []string `hcl:"allow,optional"`
+	// Mapping is a map of current addresses to desired addresses, which
+	// declares an end state instead of actions. This is experimental.
+	// On every plan, only sources which have not been moved yet are moved,
+	// so that the migration can be re-run after it has been applied.
+	// It cannot be set with actions.
+	Mapping map[string]string `hcl:"mapping,optional"`
+	// Absent is a list of addresses which must not exist in the end state.
+	// They are removed only if they exist. This is experimental.
+	Absent []string `hcl:"absent,optional"`
+	// Imports is a map of addresses which must exist in the end state to
+	// their IDs. They are imported only if they don't exist.
+	// This is experimental.
+	Imports map[string]string `hcl:"imports,optional"`
 }
 
 // StateMigratorConfig implements a MigratorConfig.
@@ -45,14 +91,43 @@ func (c *StateMigratorConfig) NewMigrator(o *MigratorOption) (Migrator, error) {
 		dir = c.Dir
 	}
 
-	if len(c.Actions) == 0 {
+	reconcile, err := NewReconciliation(c.Mapping, c.Absent, c.Imports)
+	if err != nil {
+		return nil, fmt.Errorf("failed to NewMigrator: %s", err)
+	}
+	if reconcile != nil && len(c.Actions) != 0 {
+		return nil, fmt.Errorf("failed to NewMigrator: actions cannot be set with mapping, absent or imports")
+	}
+
+	if len(c.Actions) == 0 && reconcile == nil {
 		return nil, fmt.Errorf("failed to NewMigrator with no actions")
 	}
 
+	if err := validateEnv(c.Env); err != nil {
+		return nil, fmt.Errorf("failed to NewMigrator: %s", err)
+	}
+
+	if err := validateVars(c.Vars); err != nil {
+		return nil, fmt.Errorf("failed to NewMigrator: %s", err)
+	}
+
+	expect, err := newPlanExpectationWithActions(c.ExpectNoChanges, c.Allow, c.Force, c.ActionConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to NewMigrator: %s", err)
+	}
+	if expect != nil && c.SkipPlan {
+		return nil, fmt.Errorf("failed to NewMigrator: to_skip_plan cannot be set with expect_no_changes, allow or options of actions")
+	}
+
 	// build actions from config.
+	var plugins []*ActionPluginConfig
+	if o != nil {
+		plugins = o.ActionPlugins
+	}
+
 	actions := []StateAction{}
 	for _, cmdStr := range c.Actions {
-		action, err := NewStateActionFromString(cmdStr)
+		action, err := newStateActionWithPlugins(cmdStr, plugins)
 		if err != nil {
 			return nil, err
 		}
@@ -64,7 +139,19 @@ func (c *StateMigratorConfig) NewMigrator(o *MigratorOption) (Migrator, error) {
 		c.Workspace = "default"
 	}
 
-	return NewStateMigrator(dir, c.Workspace, actions, o, c.Force, c.SkipPlan), nil
+	m := NewStateMigrator(dir, c.Workspace, actions, o, c.Force, c.SkipPlan)
+	appendEnv(m.tf, c.Env)
+	m.varOptions = planVarOptions(o, c.VarFiles, c.Vars)
+	m.expect = expect
+	m.reconcile = reconcile
+	return m, nil
+}
+
+// declaredActions returns a list of actions and all actions which may be
+// computed from the end state, for static checks of the migration.
+func (c *StateMigratorConfig) declaredActions() []string {
+	r := &Reconciliation{Mapping: c.Mapping, Absent: c.Absent, Imports: c.Imports}
+	return append(append([]string{}, c.Actions...), r.Actions()...)
 }
 
 // StateMigrator implements the Migrator interface.
@@ -82,20 +169,35 @@ type StateMigrator struct {
 	force bool
 	// workspace is the state workspace which the migration works with.
 	workspace string
+	// varOptions is a list of -var-file and -var options passed to terraform
+	// plan.
+	varOptions []string
+	// results is a list of results of actions executed in the last plan.
+	results []ActionResult
+	// diffs is a list of state diffs computed in the last plan.
+	diffs []StateDiff
+	// summaries is a list of plan summaries computed in the last plan.
+	summaries []PlanSummary
+	// base is the remote state pulled in the last plan, which the new state
+	// is computed from.
+	base *tfexec.State
+	// expect is an expected result of terraform plan. If nil, the plan must
+	// have no changes unless force is set.
+	expect *PlanExpectation
+	// reconcile is a declared end state. If set, actions are computed from it
+	// and the current state on every plan.
+	reconcile *Reconciliation
 }
 
 var _ Migrator = (*StateMigrator)(nil)
+var _ ActionResultReporter = (*StateMigrator)(nil)
+var _ StateDiffReporter = (*StateMigrator)(nil)
+var _ PlanSummaryReporter = (*StateMigrator)(nil)
 
 // NewStateMigrator returns a new StateMigrator instance.
 func NewStateMigrator(dir string, workspace string, actions []StateAction,
 	o *MigratorOption, force bool, skipPlan bool) *StateMigrator {
-	e := tfexec.NewExecutor(dir, os.Environ())
-	tf := tfexec.NewTerraformCLI(e)
-	if o != nil && len(o.ExecPath) > 0 {
-		// While NewTerraformCLI reads the environment variable TFMIGRATE_EXEC_PATH
-		// at initialization, the MigratorOption takes precedence over it.
-		tf.SetExecPath(o.ExecPath)
-	}
+	tf := newTerraformCLI(dir, o)
 
 	return &StateMigrator{
 		tf:        tf,
@@ -112,6 +214,11 @@ func NewStateMigrator(dir string, workspace string, actions []StateAction,
 // We intentionally keep this method private as to not expose internal states and unify
 // the Migrator interface between a single and multi state migrator.
 func (m *StateMigrator) plan(ctx context.Context) (currentState *tfexec.State, err error) {
+	m.results = nil
+	m.diffs = nil
+	m.summaries = nil
+	m.base = nil
+
 	ignoreLegacyStateInitErr := false
 	for _, action := range m.actions {
 		// When invoking `state replace-provider`, it's necessary to first
@@ -125,7 +232,7 @@ func (m *StateMigrator) plan(ctx context.Context) (currentState *tfexec.State, e
 	}
 
 	// setup work dir.
-	currentState, switchBackToRemoteFunc, err := setupWorkDir(ctx, m.tf, m.workspace, m.o.IsBackendTerraformCloud, m.o.BackendConfig, ignoreLegacyStateInitErr)
+	currentState, switchBackToRemoteFunc, err := setupWorkDir(ctx, m.tf, m.workspace, destinationMissingWorkspace(ctx, m.o), m.o.IsBackendTerraformCloud, m.o.BackendConfig, false, ignoreLegacyStateInitErr, m.o.ProvidersMirrorDir, m.o.ProvidersLockPlatforms, m.o.Offline, m.o.stateVersion(m.tf.Dir()))
 	if err != nil {
 		return nil, err
 	}
@@ -135,15 +242,57 @@ func (m *StateMigrator) plan(ctx context.Context) (currentState *tfexec.State, e
 		err = errors.Join(err, switchBackToRemoteFunc())
 	}()
 
-	// computes a new state by applying state migration operations to a temporary state.
-	log.Printf("[INFO] [migrator@%s] compute a new state\n", m.tf.Dir())
-	var newState *tfexec.State
+	if m.reconcile != nil {
+		if err := m.reconcileActions(ctx, currentState); err != nil {
+			return nil, err
+		}
+	}
+
+	// warn about moves between incompatible module calls.
+	moves := [][2]string{}
 	for _, action := range m.actions {
-		newState, err = action.StateUpdate(ctx, m.tf, currentState)
+		if a, ok := action.(*StateMvAction); ok {
+			moves = append(moves, [2]string{a.source, a.destination})
+		}
+	}
+	checkModuleMoves(ctx, m.tf.Dir(), m.tf.Dir(), moves)
+
+	// computes a new state by applying state migration operations to a temporary state.
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] compute a new state\n", m.tf.Dir())
+	originalState := currentState
+	m.base = originalState
+	actions := make([]any, len(m.actions))
+	for i, action := range m.actions {
+		actions[i] = action
+	}
+	m.results, err = runActions(ctx, actions, func(i int) error {
+		if m.o.CheckSources {
+			if err := checkSources(ctx, m.tf, currentState, m.actions[i]); err != nil {
+				return err
+			}
+		}
+		newState, err := m.actions[i].StateUpdate(ctx, m.tf, currentState)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := checkStateObjects(ctx, m.actions[i], []string{m.tf.Dir()}, []*tfexec.State{currentState}, []*tfexec.State{newState}); err != nil {
+			return err
 		}
 		currentState = tfexec.NewState(newState.Bytes())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// list addresses before checking diffs, so that they are available even
+	// if terraform plan detects unexpected diffs.
+	if m.o.ShowDiff {
+		diff, err := newStateDiff(ctx, m.tf, m.workspace, originalState, currentState)
+		if err != nil {
+			return nil, err
+		}
+		m.diffs = []StateDiff{diff}
 	}
 
 	// build plan options
@@ -151,19 +300,36 @@ func (m *StateMigrator) plan(ctx context.Context) (currentState *tfexec.State, e
 	if m.o.PlanOut != "" {
 		planOpts = append(planOpts, "-out="+m.o.PlanOut)
 	}
+	planOpts = append(planOpts, m.varOptions...)
 
 	if m.skipPlan {
-		log.Printf("[INFO] [migrator@%s] skipping check diffs\n", m.tf.Dir())
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] skipping check diffs\n", m.tf.Dir())
+	} else if m.expect != nil {
+		plan, err := checkPlanExpectation(ctx, m.tf, currentState, m.expect, planOpts)
+		if plan != nil && m.o.PlanSummary {
+			m.summaries = []PlanSummary{newPlanSummary(m.tf.Dir(), m.workspace, plan)}
+		}
+		if err != nil {
+			return nil, err
+		}
 	} else {
-		log.Printf("[INFO] [migrator@%s] check diffs\n", m.tf.Dir())
-		_, err = m.tf.Plan(ctx, currentState, planOpts...)
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] check diffs\n", m.tf.Dir())
+		var plan *tfexec.Plan
+		plan, err = m.tf.Plan(ctx, currentState, planOpts...)
+		if m.o.PlanSummary && (err == nil || isPlanDiffs(err)) {
+			summary, err := summarizePlan(ctx, m.tf, m.workspace, plan)
+			if err != nil {
+				return nil, err
+			}
+			m.summaries = []PlanSummary{summary}
+		}
 		if err != nil {
-			if exitErr, ok := err.(tfexec.ExitError); ok && exitErr.ExitCode() == 2 {
+			if isPlanDiffs(err) {
 				if !m.force {
-					log.Printf("[ERROR] [migrator@%s] unexpected diffs\n", m.tf.Dir())
+					logging.FromContext(ctx).Printf("[ERROR] [migrator@%s] unexpected diffs\n", m.tf.Dir())
 					return nil, fmt.Errorf("terraform plan command returns unexpected diffs: %s", err)
 				}
-				log.Printf("[INFO] [migrator@%s] unexpected diffs, ignoring as force option is true: %s", m.tf.Dir(), err)
+				logging.FromContext(ctx).Printf("[INFO] [migrator@%s] unexpected diffs, ignoring as force option is true: %s", m.tf.Dir(), err)
 				// reset err to nil to intentionally ignore unexpected diffs.
 				err = nil
 			} else {
@@ -175,38 +341,79 @@ func (m *StateMigrator) plan(ctx context.Context) (currentState *tfexec.State, e
 	return currentState, err
 }
 
+// reconcileActions replaces actions with ones computed from the end state and
+// a given current state.
+func (m *StateMigrator) reconcileActions(ctx context.Context, state *tfexec.State) error {
+	exists, err := stateAddressExistsFunc(ctx, m.tf, state)
+	if err != nil {
+		return err
+	}
+	cmdStrs, err := m.reconcile.resolve(exists, exists)
+	if err != nil {
+		return err
+	}
+	logReconciledActions(ctx, m.tf.Dir(), cmdStrs)
+
+	m.actions = []StateAction{}
+	for _, cmdStr := range cmdStrs {
+		action, err := NewStateActionFromString(cmdStr)
+		if err != nil {
+			return err
+		}
+		m.actions = append(m.actions, action)
+	}
+	return nil
+}
+
 // Plan computes a new state by applying state migration operations to a temporary state.
 // It will fail if terraform plan detects any diffs with the new state.
 func (m *StateMigrator) Plan(ctx context.Context) error {
-	log.Printf("[INFO] [migrator] start state migrator plan\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] start state migrator plan\n")
 	_, err := m.plan(ctx)
 	if err != nil {
 		return err
 	}
-	log.Printf("[INFO] [migrator] state migrator plan success!\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] state migrator plan success!\n")
 	return nil
 }
 
+// ActionResults returns a list of results of actions executed in the last
+// Plan or Apply.
+func (m *StateMigrator) ActionResults() []ActionResult {
+	return m.results
+}
+
+// StateDiffs returns a list of state diffs computed in the last Plan or Apply.
+func (m *StateMigrator) StateDiffs() []StateDiff {
+	return m.diffs
+}
+
+// PlanSummaries returns a list of plan summaries computed in the last Plan or
+// Apply.
+func (m *StateMigrator) PlanSummaries() []PlanSummary {
+	return m.summaries
+}
+
 // Apply computes a new state and pushes it to remote state.
 // It will fail if terraform plan detects any diffs with the new state.
 // We are intended to this is used for state refactoring.
 // Any state migration operations should not break any real resources.
 func (m *StateMigrator) Apply(ctx context.Context) error {
+	ctx = withApplyMode(ctx)
 	// Check if a new state does not have any diffs compared to real resources
 	// before push a new state to remote.
-	log.Printf("[INFO] [migrator] start state migrator plan phase for apply\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] start state migrator plan phase for apply\n")
 	state, err := m.plan(ctx)
 	if err != nil {
 		return err
 	}
 
 	// push the new state to remote.
-	log.Printf("[INFO] [migrator] start state migrator apply phase\n")
-	log.Printf("[INFO] [migrator] push the new state to remote\n")
-	err = m.tf.StatePush(ctx, state)
+	logging.FromContext(ctx).Printf("[INFO] [migrator] start state migrator apply phase\n")
+	err = pushState(ctx, m.tf, state, m.base, m.workspace, m.o)
 	if err != nil {
 		return err
 	}
-	log.Printf("[INFO] [migrator] state migrator apply success!\n")
+	logging.FromContext(ctx).Printf("[INFO] [migrator] state migrator apply success!\n")
 	return nil
 }
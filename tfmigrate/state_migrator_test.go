@@ -119,6 +119,161 @@ func TestStateMigratorConfigNewMigrator(t *testing.T) {
 			o:  nil,
 			ok: true,
 		},
+		{
+			desc: "with env",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Env: map[string]string{
+					"AWS_PROFILE": "legacy",
+				},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "invalid env name",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Env: map[string]string{
+					"AWS_PROFILE=": "legacy",
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "with vars",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				VarFiles: []string{"prod.tfvars"},
+				Vars: map[string]string{
+					"region": "ap-northeast-1",
+				},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "invalid var name",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Vars: map[string]string{
+					"region=": "ap-northeast-1",
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "with allow",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Allow: []string{"update:null_resource.bar"},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "invalid allow",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Allow: []string{"null_resource.bar"},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "expect_no_changes with skip_plan",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				ExpectNoChanges: true,
+				SkipPlan:        true,
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "with options of actions",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				ActionConfigs: []ActionConfig{
+					{Command: "mv null_resource.foo null_resource.foo2", Force: true},
+				},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "options of actions with expect_no_changes",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				ActionConfigs: []ActionConfig{
+					{Command: "mv null_resource.foo null_resource.foo2", Force: true},
+				},
+				ExpectNoChanges: true,
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "with mapping",
+			config: &StateMigratorConfig{
+				Dir:     "dir1",
+				Mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+				Absent:  []string{"null_resource.bar"},
+				Imports: map[string]string{"time_static.qux": "2006-01-02T15:04:05Z"},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "mapping with actions",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Mapping: map[string]string{"null_resource.bar": "null_resource.bar2"},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "invalid mapping",
+			config: &StateMigratorConfig{
+				Dir:     "dir1",
+				Mapping: map[string]string{"null_resource.foo": "null_resource.foo"},
+			},
+			o:  nil,
+			ok: false,
+		},
 	}
 
 	for _, tc := range cases {
@@ -217,6 +372,77 @@ resource "time_static" "qux" { triggers = {} }
 	}
 }
 
+func TestAccStateMigratorApplyWithMapping(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+
+	backend := tfexec.GetTestAccBackendS3Config(t.Name())
+
+	source := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+resource "time_static" "qux" { triggers = {} }
+`
+
+	workspace := "default"
+	tf := tfexec.SetupTestAccWithApply(t, workspace, backend+source)
+	ctx := context.Background()
+
+	updatedSource := `
+resource "null_resource" "foo2" {}
+resource "null_resource" "baz" {}
+resource "time_static" "qux" { triggers = {} }
+`
+
+	tfexec.UpdateTestAccSource(t, tf, backend+updatedSource)
+
+	_, err := tf.StateRm(ctx, nil, []string{"time_static.qux"})
+	if err != nil {
+		t.Fatalf("failed to run terraform state rm: %s", err)
+	}
+
+	config := &StateMigratorConfig{
+		Dir:     tf.Dir(),
+		Mapping: map[string]string{"null_resource.foo": "null_resource.foo2"},
+		Absent:  []string{"null_resource.bar"},
+		Imports: map[string]string{"time_static.qux": "2006-01-02T15:04:05Z"},
+	}
+	m, err := config.NewMigrator(&MigratorOption{})
+	if err != nil {
+		t.Fatalf("failed to new migrator: %s", err)
+	}
+
+	err = m.Apply(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator apply: %s", err)
+	}
+
+	// The end state has been reached, so re-running it requires no actions.
+	err = m.Apply(ctx)
+	if err != nil {
+		t.Fatalf("failed to re-run migrator apply: %s", err)
+	}
+	if got := len(m.(*StateMigrator).actions); got != 0 {
+		t.Errorf("expect no actions on re-run, but got %d", got)
+	}
+
+	got, err := tf.StateList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to run terraform state list: %s", err)
+	}
+
+	want := []string{
+		"null_resource.foo2",
+		"null_resource.baz",
+		"time_static.qux",
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got state: %v, want state: %v", got, want)
+	}
+}
+
 func TestAccStateMigratorApplyWithWorkspace(t *testing.T) {
 	tfexec.SkipUnlessAcceptanceTestEnabled(t)
 
@@ -286,6 +512,105 @@ resource "null_resource" "bar" {}
 	}
 }
 
+func TestAccStateMigratorPlanReadOnlyWithWorkspace(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+
+	backend := tfexec.GetTestAccBackendS3Config(t.Name())
+
+	source := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+`
+
+	workspace := "workspace1"
+	tf := tfexec.SetupTestAccWithApply(t, workspace, backend+source)
+	ctx := context.Background()
+
+	updatedSource := `
+resource "null_resource" "foo2" {}
+resource "null_resource" "bar" {}
+`
+
+	tfexec.UpdateTestAccSource(t, tf, backend+updatedSource)
+
+	// switch to another workspace so that the migrator needs to select it.
+	if err := tf.WorkspaceSelect(ctx, "default"); err != nil {
+		t.Fatalf("failed to switch to default workspace: %s", err)
+	}
+
+	actions := []StateAction{
+		NewStateMvAction("null_resource.foo", "null_resource.foo2"),
+	}
+
+	o := &MigratorOption{ReadOnly: true}
+	m := NewStateMigrator(tf.Dir(), workspace, actions, o, false, false)
+	if err := m.Plan(ctx); err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+
+	// A plan for a missing workspace must not create it.
+	m = NewStateMigrator(tf.Dir(), "workspace2", actions, o, false, false)
+	if err := m.Plan(ctx); err == nil {
+		t.Fatalf("expected to fail to move a resource in an empty state, but no error")
+	}
+	workspaces, err := tf.WorkspaceList(ctx)
+	if err != nil {
+		t.Fatalf("failed to run terraform workspace list: %s", err)
+	}
+	for _, w := range workspaces {
+		if w == "workspace2" {
+			t.Errorf("a workspace was created by plan: %v", workspaces)
+		}
+	}
+}
+
+func TestAccStateMigratorApplyWithProvidersLockPlatforms(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+
+	backend := tfexec.GetTestAccBackendS3Config(t.Name())
+
+	source := `
+resource "null_resource" "foo" {}
+`
+
+	workspace := "default"
+	tf := tfexec.SetupTestAccWithApply(t, workspace, backend+source)
+	ctx := context.Background()
+
+	lockFile := filepath.Join(tf.Dir(), ".terraform.lock.hcl")
+	orig, err := os.ReadFile(lockFile)
+	if err != nil {
+		t.Fatalf("failed to read the dependency lock file: %s", err)
+	}
+
+	updatedSource := `
+resource "null_resource" "foo2" {}
+`
+
+	tfexec.UpdateTestAccSource(t, tf, backend+updatedSource)
+
+	actions := []StateAction{
+		NewStateMvAction("null_resource.foo", "null_resource.foo2"),
+	}
+
+	o := &MigratorOption{
+		ProvidersLockPlatforms: []string{"linux_amd64", "darwin_arm64"},
+	}
+	m := NewStateMigrator(tf.Dir(), workspace, actions, o, false, false)
+	err = m.Apply(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator apply: %s", err)
+	}
+
+	got, err := os.ReadFile(lockFile)
+	if err != nil {
+		t.Fatalf("failed to read the dependency lock file: %s", err)
+	}
+	if string(got) != string(orig) {
+		t.Errorf("the dependency lock file was not restored: got = %s, want = %s", string(got), string(orig))
+	}
+}
+
 func TestAccStateMigratorApplyWithForce(t *testing.T) {
 	tfexec.SkipUnlessAcceptanceTestEnabled(t)
 
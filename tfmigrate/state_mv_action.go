@@ -26,12 +26,21 @@ func NewStateMvAction(source string, destination string) *StateMvAction {
 	}
 }
 
+// String returns the action as "mv <source> <destination>".
+func (a *StateMvAction) String() string {
+	return "mv " + a.source + " " + a.destination
+}
+
 // StateUpdate updates a given state and returns a new state.
 // It moves a resource from source address to destination address in the same tfstate file.
+// Dependencies of other resources referring to the moved resource are also updated.
 func (a *StateMvAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
 	// Disable unnecessary state backup here,
 	// because we never restore state from the backup generated by each state action.
-	// The state mv command doesn't provide a way to disable it, so we backup to /dev/null.
-	newState, _, err := tf.StateMv(ctx, state, nil, a.source, a.destination, "-backup=/dev/null")
-	return newState, err
+	// The state mv command doesn't provide a way to disable it, so we backup to the null device.
+	newState, _, err := tf.StateMv(ctx, state, nil, a.source, a.destination, disableBackupOpt)
+	if err != nil {
+		return nil, err
+	}
+	return relinkDependencies(ctx, tf.Dir(), state, newState, map[string]string{a.source: a.destination}), nil
 }
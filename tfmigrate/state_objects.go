@@ -0,0 +1,141 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// stateObject is an object of a resource instance in a state of a directory.
+type stateObject struct {
+	tfexec.StateObject
+	// dir is a working directory of the state.
+	dir string
+}
+
+// location returns a string which identifies where the object is.
+// e.g.) aws_instance.foo in dir1
+func (o stateObject) location() string {
+	return o.Address + " in " + o.dir
+}
+
+// removedAddresses returns a list of addresses which a given action removes
+// from state explicitly.
+func removedAddresses(action any) []string {
+	switch a := action.(type) {
+	case *StateRmAction:
+		return a.addresses
+	case *StateXrmAction:
+		return a.removed
+	default:
+		return nil
+	}
+}
+
+// checkStateObjects verifies that a given action doesn't silently drop
+// deposed objects or tainted status of resource instances, because a later
+// apply would leak or keep real resources which are supposed to be
+// destroyed or replaced. Objects under addresses removed by the action
+// explicitly are allowed to disappear. Moved deposed objects and tainted
+// instances are logged as warnings, because they will be destroyed or
+// replaced on the next apply in the new location.
+// The dirs, before and after are lists of working directories, states before
+// the action and states after the action in the same order.
+// The check is skipped for states which cannot be parsed, such as a legacy
+// state.
+func checkStateObjects(ctx context.Context, action any, dirs []string, before []*tfexec.State, after []*tfexec.State) error {
+	beforeObjects, err := listStateObjects(dirs, before)
+	if err != nil {
+		logging.FromContext(ctx).Printf("[WARN] [migrator@%s] skip checking deposed objects and tainted instances: %s\n", dirs[0], err)
+		return nil
+	}
+	afterObjects, err := listStateObjects(dirs, after)
+	if err != nil {
+		logging.FromContext(ctx).Printf("[WARN] [migrator@%s] skip checking deposed objects and tainted instances: %s\n", dirs[0], err)
+		return nil
+	}
+
+	removed := removedAddresses(action)
+	isRemoved := func(o stateObject) bool {
+		for _, address := range removed {
+			if hasAddressPrefix(o.Address, address) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// A deposed object keeps its deposed key when moved, so we can find where
+	// it goes.
+	deposed := make(map[string][]stateObject)
+	for _, o := range afterObjects {
+		if len(o.DeposedKey) != 0 {
+			deposed[o.DeposedKey] = append(deposed[o.DeposedKey], o)
+		}
+	}
+	for _, o := range beforeObjects {
+		if len(o.DeposedKey) == 0 {
+			continue
+		}
+		found := deposed[o.DeposedKey]
+		if len(found) == 0 {
+			if isRemoved(o) {
+				logging.FromContext(ctx).Printf("[INFO] [migrator@%s] removed deposed object %s of %s\n", o.dir, o.DeposedKey, o.Address)
+				continue
+			}
+			return fmt.Errorf("action dropped deposed object %s of %s: %s. Run terraform apply to destroy deposed objects before the migration, or remove the resource explicitly with rm", o.DeposedKey, o.location(), action)
+		}
+		deposed[o.DeposedKey] = found[1:]
+		if found[0].location() != o.location() {
+			logging.FromContext(ctx).Printf("[WARN] [migrator@%s] deposed object %s of %s moved to %s, which will be destroyed on the next apply\n", o.dir, o.DeposedKey, o.location(), found[0].location())
+		}
+	}
+
+	// A tainted instance cannot be tracked when moved, so we compare the number
+	// of them.
+	tainted := make(map[string]bool)
+	expected := []string{}
+	for _, o := range beforeObjects {
+		if !o.Tainted || len(o.DeposedKey) != 0 {
+			continue
+		}
+		tainted[o.location()] = true
+		if !isRemoved(o) {
+			expected = append(expected, o.location())
+		}
+	}
+	got := 0
+	for _, o := range afterObjects {
+		if !o.Tainted || len(o.DeposedKey) != 0 {
+			continue
+		}
+		got++
+		if !tainted[o.location()] {
+			logging.FromContext(ctx).Printf("[WARN] [migrator@%s] tainted instance moved to %s, which will be replaced on the next apply\n", o.dir, o.location())
+		}
+	}
+	if got < len(expected) {
+		return fmt.Errorf("action lost tainted status of resource instances: %s. Tainted instances before the action: %s", action, strings.Join(expected, ", "))
+	}
+
+	return nil
+}
+
+// listStateObjects returns a list of objects in given states of given
+// working directories.
+func listStateObjects(dirs []string, states []*tfexec.State) ([]stateObject, error) {
+	objects := []stateObject{}
+	for i, state := range states {
+		list, err := tfexec.StateObjects(state)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range list {
+			objects = append(objects, stateObject{StateObject: o, dir: dirs[i]})
+		}
+	}
+	return objects, nil
+}
@@ -0,0 +1,130 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// testStateWithObjects returns a state which has a resource with given
+// instances written in JSON.
+func testStateWithObjects(name string, instances ...string) *tfexec.State {
+	if len(name) == 0 {
+		return tfexec.NewState([]byte(`{"version": 4, "resources": []}`))
+	}
+	return tfexec.NewState([]byte(fmt.Sprintf(`{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": %q,
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [%s]
+    }
+  ]
+}`, name, strings.Join(instances, ","))))
+}
+
+func TestCheckStateObjects(t *testing.T) {
+	current := `{"attributes": {"id": "1"}}`
+	tainted := `{"status": "tainted", "attributes": {"id": "1"}}`
+	deposed := `{"deposed": "00000001", "attributes": {"id": "0"}}`
+
+	cases := []struct {
+		desc   string
+		action any
+		dirs   []string
+		before []*tfexec.State
+		after  []*tfexec.State
+		ok     bool
+	}{
+		{
+			desc:   "deposed object moved",
+			action: NewStateMvAction("null_resource.foo", "null_resource.bar"),
+			dirs:   []string{"dir1"},
+			before: []*tfexec.State{testStateWithObjects("foo", current, deposed)},
+			after:  []*tfexec.State{testStateWithObjects("bar", current, deposed)},
+			ok:     true,
+		},
+		{
+			desc:   "deposed object dropped",
+			action: NewStateMvAction("null_resource.foo", "null_resource.bar"),
+			dirs:   []string{"dir1"},
+			before: []*tfexec.State{testStateWithObjects("foo", current, deposed)},
+			after:  []*tfexec.State{testStateWithObjects("bar", current)},
+			ok:     false,
+		},
+		{
+			desc:   "deposed object removed explicitly",
+			action: NewStateRmAction([]string{"null_resource.foo"}),
+			dirs:   []string{"dir1"},
+			before: []*tfexec.State{testStateWithObjects("foo", current, deposed)},
+			after:  []*tfexec.State{testStateWithObjects("")},
+			ok:     true,
+		},
+		{
+			desc:   "deposed object moved across states",
+			action: NewMultiStateMvAction("null_resource.foo", "null_resource.foo"),
+			dirs:   []string{"dir1", "dir2"},
+			before: []*tfexec.State{testStateWithObjects("foo", current, deposed), testStateWithObjects("")},
+			after:  []*tfexec.State{testStateWithObjects(""), testStateWithObjects("foo", current, deposed)},
+			ok:     true,
+		},
+		{
+			desc:   "deposed object dropped across states",
+			action: NewMultiStateMvAction("null_resource.foo", "null_resource.foo"),
+			dirs:   []string{"dir1", "dir2"},
+			before: []*tfexec.State{testStateWithObjects("foo", current, deposed), testStateWithObjects("")},
+			after:  []*tfexec.State{testStateWithObjects(""), testStateWithObjects("foo", current)},
+			ok:     false,
+		},
+		{
+			desc:   "tainted instance moved",
+			action: NewStateMvAction("null_resource.foo", "null_resource.bar"),
+			dirs:   []string{"dir1"},
+			before: []*tfexec.State{testStateWithObjects("foo", tainted)},
+			after:  []*tfexec.State{testStateWithObjects("bar", tainted)},
+			ok:     true,
+		},
+		{
+			desc:   "tainted status lost",
+			action: NewStateMvAction("null_resource.foo", "null_resource.bar"),
+			dirs:   []string{"dir1"},
+			before: []*tfexec.State{testStateWithObjects("foo", tainted)},
+			after:  []*tfexec.State{testStateWithObjects("bar", current)},
+			ok:     false,
+		},
+		{
+			desc:   "tainted instance removed explicitly",
+			action: NewStateRmAction([]string{"null_resource.foo"}),
+			dirs:   []string{"dir1"},
+			before: []*tfexec.State{testStateWithObjects("foo", tainted)},
+			after:  []*tfexec.State{testStateWithObjects("")},
+			ok:     true,
+		},
+		{
+			desc:   "legacy state is skipped",
+			action: NewStateMvAction("null_resource.foo", "null_resource.bar"),
+			dirs:   []string{"dir1"},
+			before: []*tfexec.State{tfexec.NewState([]byte(`{"version": 3}`))},
+			after:  []*tfexec.State{testStateWithObjects("")},
+			ok:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := checkStateObjects(context.Background(), tc.action, tc.dirs, tc.before, tc.after)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
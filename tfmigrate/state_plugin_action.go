@@ -0,0 +1,220 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// ActionPluginConfig is a config for an exec-based action plugin.
+// It allows users to add bespoke actions which participate in plan and apply
+// like built-in actions.
+//
+// The plugin command is executed in the working directory of the migration
+// with the action arguments appended. The current state is written to a
+// temporary file and its path is passed via the TFMIGRATE_STATE_FILE
+// environment variable. The plugin can update the file in place to return a
+// new state, or leave it untouched if it doesn't change the state.
+// In multi_state migrations, the plugin is executed in from_dir, and the
+// states are passed via TFMIGRATE_FROM_STATE_FILE and TFMIGRATE_TO_STATE_FILE
+// instead.
+// Note that actions are executed in both plan and apply, and the mode is
+// passed via the TFMIGRATE_MODE environment variable, which is either plan or
+// apply. The plugin must be idempotent and must skip any side effects when
+// the mode is plan. Even in apply, the plugin runs before new states are
+// pushed, so the migration may still fail after it.
+type ActionPluginConfig struct {
+	// Name is an action type used in migration files.
+	Name string
+	// Command is a command line to execute the plugin.
+	// It may contain spaces like a shell.
+	Command string
+}
+
+// StatePluginAction implements the StateAction interface.
+// StatePluginAction updates a state by running an external plugin command.
+type StatePluginAction struct {
+	// plugin is a config for the action plugin.
+	plugin *ActionPluginConfig
+	// args is a list of arguments passed to the plugin.
+	args []string
+}
+
+var _ StateAction = (*StatePluginAction)(nil)
+
+// NewStatePluginAction returns a new StatePluginAction instance.
+func NewStatePluginAction(plugin *ActionPluginConfig, args []string) *StatePluginAction {
+	return &StatePluginAction{
+		plugin: plugin,
+		args:   args,
+	}
+}
+
+// String returns the action as "<plugin name> <args>...".
+func (a *StatePluginAction) String() string {
+	return strings.Join(append([]string{a.plugin.Name}, a.args...), " ")
+}
+
+// StateUpdate updates a given state and returns a new state.
+// It runs the plugin command with the current state and reads the updated state.
+func (a *StatePluginAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
+	tmpState, err := tf.WriteTempFile(state.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	defer tf.RemoveTempFile(tmpState.Name())
+
+	if err := runActionPlugin(ctx, a.plugin, a.args, tf.Dir(), "TFMIGRATE_STATE_FILE="+tmpState.Name()); err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(tmpState.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read temporary state file: %s", err)
+	}
+
+	return tfexec.NewState(b), nil
+}
+
+// MultiStatePluginAction implements the MultiStateAction interface.
+// MultiStatePluginAction updates two states by running an external plugin
+// command.
+type MultiStatePluginAction struct {
+	// plugin is a config for the action plugin.
+	plugin *ActionPluginConfig
+	// args is a list of arguments passed to the plugin.
+	args []string
+}
+
+var _ MultiStateAction = (*MultiStatePluginAction)(nil)
+
+// NewMultiStatePluginAction returns a new MultiStatePluginAction instance.
+func NewMultiStatePluginAction(plugin *ActionPluginConfig, args []string) *MultiStatePluginAction {
+	return &MultiStatePluginAction{
+		plugin: plugin,
+		args:   args,
+	}
+}
+
+// String returns the action as "<plugin name> <args>...".
+func (a *MultiStatePluginAction) String() string {
+	return strings.Join(append([]string{a.plugin.Name}, a.args...), " ")
+}
+
+// MultiStateUpdate updates given two states and returns new two states.
+// It runs the plugin command in the from dir with the current states and
+// reads the updated states.
+func (a *MultiStatePluginAction) MultiStateUpdate(ctx context.Context, fromTf tfexec.TerraformCLI, toTf tfexec.TerraformCLI, fromState *tfexec.State, toState *tfexec.State) (*tfexec.State, *tfexec.State, error) {
+	tmpFromState, err := fromTf.WriteTempFile(fromState.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fromTf.RemoveTempFile(tmpFromState.Name())
+
+	tmpToState, err := toTf.WriteTempFile(toState.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer toTf.RemoveTempFile(tmpToState.Name())
+
+	if err := runActionPlugin(ctx, a.plugin, a.args, fromTf.Dir(),
+		"TFMIGRATE_FROM_STATE_FILE="+tmpFromState.Name(),
+		"TFMIGRATE_TO_STATE_FILE="+tmpToState.Name(),
+	); err != nil {
+		return nil, nil, err
+	}
+
+	fromBytes, err := os.ReadFile(tmpFromState.Name())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read temporary state file: %s", err)
+	}
+	toBytes, err := os.ReadFile(tmpToState.Name())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read temporary state file: %s", err)
+	}
+
+	return tfexec.NewState(fromBytes), tfexec.NewState(toBytes), nil
+}
+
+// runActionPlugin runs a plugin command in a given dir with given arguments
+// appended. The action type and the mode of the migration are passed to the
+// plugin via environment variables in addition to given ones.
+func runActionPlugin(ctx context.Context, plugin *ActionPluginConfig, pluginArgs []string, dir string, env ...string) error {
+	parts, err := shellwords.Parse(plugin.Command)
+	if err != nil {
+		return fmt.Errorf("failed to parse command of action plugin %s: %s", plugin.Name, err)
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("command of action plugin %s is empty", plugin.Name)
+	}
+
+	env = append(append(os.Environ(), env...),
+		"TFMIGRATE_ACTION="+plugin.Name,
+		"TFMIGRATE_MODE="+modeFromContext(ctx),
+	)
+	e := tfexec.NewExecutor(dir, env)
+	args := append(parts[1:], pluginArgs...)
+	cmd, err := e.NewCommandContext(ctx, parts[0], args...)
+	if err != nil {
+		return err
+	}
+
+	if err := e.Run(cmd); err != nil {
+		return fmt.Errorf("failed to run action plugin %s: %s", plugin.Name, err)
+	}
+	return nil
+}
+
+// findActionPlugin returns a plugin config for a given action type.
+func findActionPlugin(plugins []*ActionPluginConfig, actionType string) (*ActionPluginConfig, bool) {
+	for _, p := range plugins {
+		if p.Name == actionType {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// newStateActionWithPlugins is a factory method which returns a new
+// StateAction from a given string. If the action type matches one of the
+// given plugins, it returns a StatePluginAction. Otherwise, it falls back to
+// built-in actions.
+func newStateActionWithPlugins(cmdStr string, plugins []*ActionPluginConfig) (StateAction, error) {
+	if len(plugins) > 0 {
+		args, err := splitStateAction(cmdStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+		}
+		if len(args) > 0 {
+			if p, ok := findActionPlugin(plugins, args[0]); ok {
+				return NewStatePluginAction(p, args[1:]), nil
+			}
+		}
+	}
+
+	return NewStateActionFromString(cmdStr)
+}
+
+// newMultiStateActionWithPlugins is a factory method which returns a new
+// MultiStateAction from a given string. If the action type matches one of the
+// given plugins, it returns a MultiStatePluginAction. Otherwise, it falls back
+// to built-in actions.
+func newMultiStateActionWithPlugins(cmdStr string, plugins []*ActionPluginConfig) (MultiStateAction, error) {
+	if len(plugins) > 0 {
+		args, err := splitStateAction(cmdStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+		}
+		if len(args) > 0 {
+			if p, ok := findActionPlugin(plugins, args[0]); ok {
+				return NewMultiStatePluginAction(p, args[1:]), nil
+			}
+		}
+	}
+
+	return NewMultiStateActionFromString(cmdStr)
+}
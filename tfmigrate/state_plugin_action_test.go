@@ -0,0 +1,271 @@
+package tfmigrate
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestStatePluginActionStateUpdate(t *testing.T) {
+	cases := []struct {
+		desc    string
+		command string
+		args    []string
+		apply   bool
+		state   string
+		want    string
+		ok      bool
+	}{
+		{
+			desc:    "update state",
+			command: `/bin/sh -c "printf '%s %s' $TFMIGRATE_ACTION $1 > $TFMIGRATE_STATE_FILE" --`,
+			args:    []string{"foo"},
+			state:   "dummy state",
+			want:    "cmdb_rename foo",
+			ok:      true,
+		},
+		{
+			desc:    "plan mode",
+			command: `/bin/sh -c "printf '%s' $TFMIGRATE_MODE > $TFMIGRATE_STATE_FILE"`,
+			state:   "dummy state",
+			want:    "plan",
+			ok:      true,
+		},
+		{
+			desc:    "apply mode",
+			command: `/bin/sh -c "printf '%s' $TFMIGRATE_MODE > $TFMIGRATE_STATE_FILE"`,
+			apply:   true,
+			state:   "dummy state",
+			want:    "apply",
+			ok:      true,
+		},
+		{
+			desc:    "no change",
+			command: "true",
+			state:   "dummy state",
+			want:    "dummy state",
+			ok:      true,
+		},
+		{
+			desc:    "plugin failed",
+			command: "false",
+			state:   "dummy state",
+			ok:      false,
+		},
+		{
+			desc:    "empty command",
+			command: "",
+			state:   "dummy state",
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			plugin := &ActionPluginConfig{
+				Name:    "cmdb_rename",
+				Command: tc.command,
+			}
+			ctx := context.Background()
+			if tc.apply {
+				ctx = withApplyMode(ctx)
+			}
+			tf := tfexec.NewTerraformCLI(tfexec.NewExecutor(os.TempDir(), os.Environ()))
+			a := NewStatePluginAction(plugin, tc.args)
+			got, err := a.StateUpdate(ctx, tf, tfexec.NewState([]byte(tc.state)))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", got.Bytes())
+			}
+			if tc.ok && string(got.Bytes()) != tc.want {
+				t.Errorf("got: %s, want: %s", got.Bytes(), tc.want)
+			}
+		})
+	}
+}
+
+func TestNewStateActionWithPlugins(t *testing.T) {
+	plugin := &ActionPluginConfig{
+		Name:    "cmdb_rename",
+		Command: "./bin/cmdb-rename",
+	}
+	cases := []struct {
+		desc    string
+		cmdStr  string
+		plugins []*ActionPluginConfig
+		want    StateAction
+		ok      bool
+	}{
+		{
+			desc:    "plugin action",
+			cmdStr:  "cmdb_rename foo 'bar baz'",
+			plugins: []*ActionPluginConfig{plugin},
+			want: &StatePluginAction{
+				plugin: plugin,
+				args:   []string{"foo", "bar baz"},
+			},
+			ok: true,
+		},
+		{
+			desc:    "built-in action",
+			cmdStr:  "mv null_resource.foo null_resource.foo2",
+			plugins: []*ActionPluginConfig{plugin},
+			want: &StateMvAction{
+				source:      "null_resource.foo",
+				destination: "null_resource.foo2",
+			},
+			ok: true,
+		},
+		{
+			desc:    "unknown action",
+			cmdStr:  "cmdb_rename foo",
+			plugins: nil,
+			want:    nil,
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := newStateActionWithPlugins(tc.cmdStr, tc.plugins)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMultiStatePluginActionMultiStateUpdate(t *testing.T) {
+	cases := []struct {
+		desc      string
+		command   string
+		args      []string
+		fromState string
+		toState   string
+		wantFrom  string
+		wantTo    string
+		ok        bool
+	}{
+		{
+			desc:      "update states",
+			command:   `/bin/sh -c "printf '%s' $1 > $TFMIGRATE_FROM_STATE_FILE && printf '%s %s' $TFMIGRATE_ACTION $TFMIGRATE_MODE > $TFMIGRATE_TO_STATE_FILE" --`,
+			args:      []string{"foo"},
+			fromState: "dummy from state",
+			toState:   "dummy to state",
+			wantFrom:  "foo",
+			wantTo:    "cmdb_move plan",
+			ok:        true,
+		},
+		{
+			desc:      "no change",
+			command:   "true",
+			fromState: "dummy from state",
+			toState:   "dummy to state",
+			wantFrom:  "dummy from state",
+			wantTo:    "dummy to state",
+			ok:        true,
+		},
+		{
+			desc:      "plugin failed",
+			command:   "false",
+			fromState: "dummy from state",
+			toState:   "dummy to state",
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			plugin := &ActionPluginConfig{
+				Name:    "cmdb_move",
+				Command: tc.command,
+			}
+			fromTf := tfexec.NewTerraformCLI(tfexec.NewExecutor(os.TempDir(), os.Environ()))
+			toTf := tfexec.NewTerraformCLI(tfexec.NewExecutor(os.TempDir(), os.Environ()))
+			a := NewMultiStatePluginAction(plugin, tc.args)
+			gotFrom, gotTo, err := a.MultiStateUpdate(context.Background(), fromTf, toTf, tfexec.NewState([]byte(tc.fromState)), tfexec.NewState([]byte(tc.toState)))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s, %s", gotFrom.Bytes(), gotTo.Bytes())
+			}
+			if tc.ok {
+				if string(gotFrom.Bytes()) != tc.wantFrom {
+					t.Errorf("got from state: %s, want: %s", gotFrom.Bytes(), tc.wantFrom)
+				}
+				if string(gotTo.Bytes()) != tc.wantTo {
+					t.Errorf("got to state: %s, want: %s", gotTo.Bytes(), tc.wantTo)
+				}
+			}
+		})
+	}
+}
+
+func TestNewMultiStateActionWithPlugins(t *testing.T) {
+	plugin := &ActionPluginConfig{
+		Name:    "cmdb_move",
+		Command: "./bin/cmdb-move",
+	}
+	cases := []struct {
+		desc    string
+		cmdStr  string
+		plugins []*ActionPluginConfig
+		want    MultiStateAction
+		ok      bool
+	}{
+		{
+			desc:    "plugin action",
+			cmdStr:  "cmdb_move foo 'bar baz'",
+			plugins: []*ActionPluginConfig{plugin},
+			want: &MultiStatePluginAction{
+				plugin: plugin,
+				args:   []string{"foo", "bar baz"},
+			},
+			ok: true,
+		},
+		{
+			desc:    "built-in action",
+			cmdStr:  "mv null_resource.foo null_resource.foo2",
+			plugins: []*ActionPluginConfig{plugin},
+			want: &MultiStateMvAction{
+				source:      "null_resource.foo",
+				destination: "null_resource.foo2",
+			},
+			ok: true,
+		},
+		{
+			desc:    "unknown action",
+			cmdStr:  "cmdb_move foo",
+			plugins: nil,
+			want:    nil,
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := newMultiStateActionWithPlugins(tc.cmdStr, tc.plugins)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package tfmigrate
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+const (
+	// StatePushMethodCLI is a method of pushing a state with terraform state
+	// push.
+	StatePushMethodCLI = "cli"
+	// StatePushMethodAPI is a method of pushing a state by creating a state
+	// version with Terraform Cloud API. It's only available for the remote
+	// and cloud backends.
+	StatePushMethodAPI = "api"
+	// StatePushMethodAuto is a method of pushing a state with terraform state
+	// push, and falling back to Terraform Cloud API if it's rejected because
+	// the Terraform version of the workspace doesn't match the local one.
+	StatePushMethodAuto = "auto"
+)
+
+// ValidateStatePushMethod returns an error if a given state push method is
+// unknown. An empty string is valid, which means StatePushMethodCLI.
+func ValidateStatePushMethod(method string) error {
+	switch method {
+	case "", StatePushMethodCLI, StatePushMethodAPI, StatePushMethodAuto:
+		return nil
+	default:
+		return fmt.Errorf("unknown state push method: %s, it must be one of %s, %s or %s", method, StatePushMethodCLI, StatePushMethodAPI, StatePushMethodAuto)
+	}
+}
+
+// pushRemoteState pushes a given state to remote with a given method.
+// The base is the remote state which the new state was computed from.
+func pushRemoteState(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State, base *tfexec.State, workspace string, method string) error {
+	switch method {
+	case StatePushMethodAPI:
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] push the new state to remote with Terraform Cloud API\n", tf.Dir())
+		return pushTfcState(ctx, tf.Dir(), workspace, state, base)
+
+	case StatePushMethodAuto:
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] push the new state to remote\n", tf.Dir())
+		err := tf.StatePush(ctx, state)
+		// Never fall back on a serial conflict, which means another writer
+		// has updated the remote state after we pulled it.
+		if err == nil || !isRemoteVersionMismatch(err) {
+			return err
+		}
+		logging.FromContext(ctx).Printf("[WARN] [migrator@%s] terraform state push was rejected, fall back to Terraform Cloud API: %s\n", tf.Dir(), err)
+		if apiErr := pushTfcState(ctx, tf.Dir(), workspace, state, base); apiErr != nil {
+			return errors.Join(err, apiErr)
+		}
+		return nil
+
+	default:
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] push the new state to remote\n", tf.Dir())
+		return tf.StatePush(ctx, state)
+	}
+}
+
+// isRemoteVersionMismatch returns true if a given error of terraform state
+// push is caused by the Terraform version of the remote workspace, such as:
+// Remote workspace Terraform version "1.5.7" does not match local Terraform version "1.6.0"
+func isRemoteVersionMismatch(err error) bool {
+	return strings.Contains(err.Error(), "does not match local Terraform version")
+}
+
+// pushTfcState pushes a given state to a workspace of Terraform Cloud by
+// creating a state version with the API. The backend config is read from the
+// backend state file in an initialized working directory, and only the remote
+// and cloud backends are supported. The base is the remote state which the new
+// state was computed from, and the upload fails if the remote state has been
+// changed since then.
+func pushTfcState(ctx context.Context, dir string, workspace string, state *tfexec.State, base *tfexec.State) error {
+	s, err := readBackendState(dir)
+	if err != nil {
+		return err
+	}
+	if s.Backend.Type != "remote" && s.Backend.Type != "cloud" {
+		return fmt.Errorf("pushing a state with Terraform Cloud API is not supported for the %s backend", s.Backend.Type)
+	}
+
+	organization := configString(s.Backend.Config, "organization")
+	if len(organization) == 0 {
+		organization = os.Getenv("TF_CLOUD_ORGANIZATION")
+	}
+	if len(organization) == 0 {
+		return fmt.Errorf("failed to find an organization in the %s backend config", s.Backend.Type)
+	}
+
+	var baseSerial int64
+	if base != nil && len(base.Bytes()) > 0 {
+		b, err := decodeStateHeader(base.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to parse the base state: %s", err)
+		}
+		baseSerial, _, err = stateSerialLineage(b)
+		if err != nil {
+			return fmt.Errorf("failed to parse the base state: %s", err)
+		}
+	}
+
+	return newTfcStateVersionClient(s.Backend.Config).Upload(ctx, organization, tfcWorkspaceName(s.Backend.Config, workspace), state.Bytes(), baseSerial)
+}
+
+// tfcWorkspaceName returns a name of the workspace in Terraform Cloud for a
+// given terraform workspace. A workspace name in the backend config takes
+// precedence, and a prefix of the remote backend is prepended.
+func tfcWorkspaceName(config map[string]interface{}, workspace string) string {
+	workspaces, _ := config["workspaces"].(map[string]interface{})
+	if name := configString(workspaces, "name"); len(name) > 0 {
+		return name
+	}
+	if name := os.Getenv("TF_WORKSPACE"); len(name) > 0 && (len(workspace) == 0 || workspace == "default") {
+		return name
+	}
+	return configString(workspaces, "prefix") + workspace
+}
+
+// Upload creates a new state version of a given workspace with a given state.
+// The workspace is locked during the upload. The baseSerial is a serial of the
+// remote state which the new state was computed from, and 0 means there was
+// no state. The current state version must still have the baseSerial and the
+// same lineage as the new state, and the serial is bumped if it's not greater
+// than the current one, so that the new state is accepted.
+func (c *tfcStateVersionClient) Upload(ctx context.Context, organization string, workspace string, state []byte, baseSerial int64) (err error) {
+	if len(c.token) == 0 {
+		return fmt.Errorf("no API token found for %s. Set TF_TOKEN_<hostname> or TFE_TOKEN", c.baseURL)
+	}
+
+	b, err := c.get(ctx, c.baseURL+"/api/v2/organizations/"+url.PathEscape(organization)+"/workspaces/"+url.PathEscape(workspace))
+	if err != nil {
+		return fmt.Errorf("failed to find the workspace %s/%s: %s", organization, workspace, err)
+	}
+	var ws struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(b, &ws); err != nil {
+		return fmt.Errorf("failed to parse the workspace %s/%s: %s", organization, workspace, err)
+	}
+	wsURL := c.baseURL + "/api/v2/workspaces/" + url.PathEscape(ws.Data.ID)
+
+	// A state version can be created only by the owner of the lock.
+	lock := []byte(`{"reason":"Locked by tfmigrate to push a new state"}`)
+	if _, err := c.do(ctx, http.MethodPost, wsURL+"/actions/lock", lock, http.StatusOK); err != nil {
+		var respErr *tfcResponseError
+		if errors.As(err, &respErr) && respErr.statusCode == http.StatusConflict {
+			return fmt.Errorf("the workspace %s/%s is already locked", organization, workspace)
+		}
+		return fmt.Errorf("failed to lock the workspace %s/%s: %s", organization, workspace, err)
+	}
+	defer func() {
+		if _, unlockErr := c.do(ctx, http.MethodPost, wsURL+"/actions/unlock", nil, http.StatusOK); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to unlock the workspace %s/%s: %s", organization, workspace, unlockErr))
+		}
+	}()
+
+	current, err := c.currentState(ctx, wsURL)
+	if err != nil {
+		return err
+	}
+
+	newState, err := prepareStateUpload(state, baseSerial, current)
+	if err != nil {
+		return err
+	}
+
+	sum := md5.Sum(newState.state)
+	req := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "state-versions",
+			"attributes": map[string]interface{}{
+				"serial":  newState.serial,
+				"md5":     hex.EncodeToString(sum[:]),
+				"lineage": newState.lineage,
+				"state":   base64.StdEncoding.EncodeToString(newState.state),
+			},
+		},
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := c.do(ctx, http.MethodPost, wsURL+"/state-versions", body, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to create a state version of the workspace %s/%s: %s", organization, workspace, err)
+	}
+	return nil
+}
+
+// currentState returns the current state of a given workspace URL.
+// It returns nil if the workspace has no state yet.
+func (c *tfcStateVersionClient) currentState(ctx context.Context, wsURL string) ([]byte, error) {
+	b, err := c.get(ctx, wsURL+"/current-state-version")
+	if err != nil {
+		var respErr *tfcResponseError
+		if errors.As(err, &respErr) && respErr.statusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get the current state version: %s", err)
+	}
+
+	var sv struct {
+		Data struct {
+			Attributes struct {
+				DownloadURL string `json:"hosted-state-download-url"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(b, &sv); err != nil {
+		return nil, fmt.Errorf("failed to parse the current state version: %s", err)
+	}
+	if len(sv.Data.Attributes.DownloadURL) == 0 {
+		return nil, fmt.Errorf("failed to find a download url of the current state version")
+	}
+
+	return c.get(ctx, sv.Data.Attributes.DownloadURL)
+}
+
+// stateUpload is a state to be uploaded as a new state version.
+type stateUpload struct {
+	// state is a state in bytes.
+	state []byte
+	// serial is a serial of the state.
+	serial int64
+	// lineage is a lineage of the state.
+	lineage string
+}
+
+// prepareStateUpload returns a state to be uploaded over a given current
+// state, which may be nil. It returns an error if the lineage doesn't match,
+// or the serial of the current state is not the baseSerial, because someone
+// else has written the remote state since it was pulled. If the serial is not
+// greater than the current one, it's set to the current serial + 1, because
+// the API rejects the state otherwise.
+func prepareStateUpload(state []byte, baseSerial int64, current []byte) (*stateUpload, error) {
+	s, err := decodeStateHeader(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the new state: %s", err)
+	}
+	newSerial, newLineage, err := stateSerialLineage(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the new state: %s", err)
+	}
+
+	var curSerial int64
+	var curLineage string
+	if len(current) > 0 {
+		c, err := decodeStateHeader(current)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the current state: %s", err)
+		}
+		curSerial, curLineage, err = stateSerialLineage(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the current state: %s", err)
+		}
+	}
+
+	if len(curLineage) > 0 && len(newLineage) > 0 && curLineage != newLineage {
+		return nil, fmt.Errorf("lineage mismatch: the remote state has been replaced during the migration: remote = %s, new = %s", curLineage, newLineage)
+	}
+	if curSerial != baseSerial {
+		return nil, fmt.Errorf("serial conflict: the remote state has been changed since it was pulled: remote = %d, pulled = %d", curSerial, baseSerial)
+	}
+	if len(current) == 0 || newSerial > curSerial {
+		return &stateUpload{state: state, serial: newSerial, lineage: newLineage}, nil
+	}
+
+	serial := curSerial + 1
+	s["serial"] = json.RawMessage(fmt.Sprint(serial))
+	if len(newLineage) == 0 {
+		newLineage = curLineage
+		lineage, _ := json.Marshal(newLineage)
+		s["lineage"] = json.RawMessage(lineage)
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the new state: %s", err)
+	}
+	return &stateUpload{state: append(b, '\n'), serial: serial, lineage: newLineage}, nil
+}
+
+// decodeStateHeader decodes top-level attributes of a given state, leaving
+// their values as they are.
+func decodeStateHeader(state []byte) (map[string]json.RawMessage, error) {
+	var s map[string]json.RawMessage
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// stateSerialLineage returns a serial and a lineage of a given decoded state.
+func stateSerialLineage(s map[string]json.RawMessage) (int64, string, error) {
+	var serial int64
+	if v, ok := s["serial"]; ok {
+		if err := json.Unmarshal(v, &serial); err != nil {
+			return 0, "", fmt.Errorf("invalid serial: %s", err)
+		}
+	}
+	var lineage string
+	if v, ok := s["lineage"]; ok {
+		if err := json.Unmarshal(v, &lineage); err != nil {
+			return 0, "", fmt.Errorf("invalid lineage: %s", err)
+		}
+	}
+	return serial, lineage, nil
+}
@@ -0,0 +1,447 @@
+package tfmigrate
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestPrepareStateUpload(t *testing.T) {
+	cases := []struct {
+		desc        string
+		state       string
+		baseSerial  int64
+		current     string
+		wantSerial  int64
+		wantLineage string
+		wantState   string
+		ok          bool
+	}{
+		{
+			desc:        "no current state",
+			state:       `{"version":4,"serial":1,"lineage":"foo"}`,
+			current:     "",
+			wantSerial:  1,
+			wantLineage: "foo",
+			wantState:   `{"version":4,"serial":1,"lineage":"foo"}`,
+			ok:          true,
+		},
+		{
+			desc:        "newer serial",
+			state:       `{"version":4,"serial":3,"lineage":"foo"}`,
+			baseSerial:  2,
+			current:     `{"version":4,"serial":2,"lineage":"foo"}`,
+			wantSerial:  3,
+			wantLineage: "foo",
+			wantState:   `{"version":4,"serial":3,"lineage":"foo"}`,
+			ok:          true,
+		},
+		{
+			desc:        "same serial",
+			state:       `{"version":4,"serial":2,"lineage":"foo"}`,
+			baseSerial:  2,
+			current:     `{"version":4,"serial":2,"lineage":"foo"}`,
+			wantSerial:  3,
+			wantLineage: "foo",
+			wantState: `{
+  "lineage": "foo",
+  "serial": 3,
+  "version": 4
+}
+`,
+			ok: true,
+		},
+		{
+			desc:        "no lineage",
+			state:       `{"version":4,"serial":1}`,
+			baseSerial:  5,
+			current:     `{"version":4,"serial":5,"lineage":"foo"}`,
+			wantSerial:  6,
+			wantLineage: "foo",
+			wantState: `{
+  "lineage": "foo",
+  "serial": 6,
+  "version": 4
+}
+`,
+			ok: true,
+		},
+		{
+			desc:       "lineage mismatch",
+			state:      `{"version":4,"serial":3,"lineage":"foo"}`,
+			baseSerial: 2,
+			current:    `{"version":4,"serial":2,"lineage":"bar"}`,
+			ok:         false,
+		},
+		{
+			desc:       "remote changed since pulled",
+			state:      `{"version":4,"serial":3,"lineage":"foo"}`,
+			baseSerial: 2,
+			current:    `{"version":4,"serial":3,"lineage":"foo"}`,
+			ok:         false,
+		},
+		{
+			desc:       "remote newer than new state",
+			state:      `{"version":4,"serial":3,"lineage":"foo"}`,
+			baseSerial: 2,
+			current:    `{"version":4,"serial":5,"lineage":"foo"}`,
+			ok:         false,
+		},
+		{
+			desc:       "remote created since pulled",
+			state:      `{"version":4,"serial":1,"lineage":"foo"}`,
+			baseSerial: 0,
+			current:    `{"version":4,"serial":1,"lineage":"foo"}`,
+			ok:         false,
+		},
+		{
+			desc:    "invalid state",
+			state:   `foo`,
+			current: "",
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := prepareStateUpload([]byte(tc.state), tc.baseSerial, []byte(tc.current))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if got.serial != tc.wantSerial {
+					t.Errorf("got serial: %d, want: %d", got.serial, tc.wantSerial)
+				}
+				if got.lineage != tc.wantLineage {
+					t.Errorf("got lineage: %s, want: %s", got.lineage, tc.wantLineage)
+				}
+				if string(got.state) != tc.wantState {
+					t.Errorf("got state: %s, want: %s", got.state, tc.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestTfcWorkspaceName(t *testing.T) {
+	cases := []struct {
+		desc      string
+		config    map[string]interface{}
+		workspace string
+		env       string
+		want      string
+	}{
+		{
+			desc:      "name",
+			config:    map[string]interface{}{"workspaces": map[string]interface{}{"name": "foo", "prefix": nil}},
+			workspace: "default",
+			want:      "foo",
+		},
+		{
+			desc:      "prefix",
+			config:    map[string]interface{}{"workspaces": map[string]interface{}{"name": nil, "prefix": "app-"}},
+			workspace: "prod",
+			want:      "app-prod",
+		},
+		{
+			desc:      "tags",
+			config:    map[string]interface{}{"workspaces": map[string]interface{}{"name": nil, "tags": []interface{}{"app"}}},
+			workspace: "app-prod",
+			want:      "app-prod",
+		},
+		{
+			desc:      "TF_WORKSPACE",
+			config:    map[string]interface{}{"workspaces": nil},
+			workspace: "default",
+			env:       "bar",
+			want:      "bar",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("TF_WORKSPACE", tc.env)
+			got := tfcWorkspaceName(tc.config, tc.workspace)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+// fakeTfcStateVersions is a minimal fake server of Terraform Cloud API for
+// uploading a state version.
+type fakeTfcStateVersions struct {
+	// current is a current state. If empty, the workspace has no state.
+	current string
+	// locked is true if the workspace is locked.
+	locked bool
+	// created is an attributes of the last created state version.
+	created map[string]interface{}
+	// unlocked is true if the workspace was unlocked.
+	unlocked bool
+}
+
+func (f *fakeTfcStateVersions) handler(serverURL *string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/example-org/workspaces/foo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":{"id":"ws-123","type":"workspaces"}}`))
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-123/actions/lock", func(w http.ResponseWriter, _ *http.Request) {
+		if f.locked {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.locked = true
+		w.Write([]byte(`{"data":{"id":"ws-123"}}`))
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-123/actions/unlock", func(w http.ResponseWriter, _ *http.Request) {
+		f.locked = false
+		f.unlocked = true
+		w.Write([]byte(`{"data":{"id":"ws-123"}}`))
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-123/current-state-version", func(w http.ResponseWriter, _ *http.Request) {
+		if len(f.current) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"id":"sv-1","attributes":{"hosted-state-download-url":"` + *serverURL + `/download/sv-1"}}}`))
+	})
+	mux.HandleFunc("/download/sv-1", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(f.current))
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-123/state-versions", func(w http.ResponseWriter, r *http.Request) {
+		if !f.locked {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		var req struct {
+			Data struct {
+				Attributes map[string]interface{} `json:"attributes"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(b, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.created = req.Data.Attributes
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"id":"sv-2"}}`))
+	})
+	return mux
+}
+
+func TestTfcStateVersionClientUpload(t *testing.T) {
+	cases := []struct {
+		desc        string
+		token       string
+		workspace   string
+		baseSerial  int64
+		current     string
+		locked      bool
+		state       string
+		wantSerial  float64
+		wantLineage string
+		ok          bool
+	}{
+		{
+			desc:        "new workspace",
+			token:       "token",
+			workspace:   "foo",
+			current:     "",
+			state:       `{"version":4,"serial":1,"lineage":"foo"}`,
+			wantSerial:  1,
+			wantLineage: "foo",
+			ok:          true,
+		},
+		{
+			desc:        "bump serial",
+			token:       "token",
+			workspace:   "foo",
+			baseSerial:  2,
+			current:     `{"version":4,"serial":2,"lineage":"foo"}`,
+			state:       `{"version":4,"serial":2,"lineage":"foo"}`,
+			wantSerial:  3,
+			wantLineage: "foo",
+			ok:          true,
+		},
+		{
+			desc:       "lineage mismatch",
+			token:      "token",
+			workspace:  "foo",
+			baseSerial: 2,
+			current:    `{"version":4,"serial":2,"lineage":"bar"}`,
+			state:      `{"version":4,"serial":3,"lineage":"foo"}`,
+			ok:         false,
+		},
+		{
+			desc:       "serial conflict",
+			token:      "token",
+			workspace:  "foo",
+			baseSerial: 2,
+			current:    `{"version":4,"serial":4,"lineage":"foo"}`,
+			state:      `{"version":4,"serial":3,"lineage":"foo"}`,
+			ok:         false,
+		},
+		{
+			desc:      "already locked",
+			token:     "token",
+			workspace: "foo",
+			locked:    true,
+			state:     `{"version":4,"serial":1,"lineage":"foo"}`,
+			ok:        false,
+		},
+		{
+			desc:      "workspace not found",
+			token:     "token",
+			workspace: "bar",
+			state:     `{"version":4,"serial":1,"lineage":"foo"}`,
+			ok:        false,
+		},
+		{
+			desc:      "no token",
+			token:     "",
+			workspace: "foo",
+			state:     `{"version":4,"serial":1,"lineage":"foo"}`,
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			f := &fakeTfcStateVersions{current: tc.current, locked: tc.locked}
+			var serverURL string
+			server := httptest.NewServer(f.handler(&serverURL))
+			defer server.Close()
+			serverURL = server.URL
+
+			c := &tfcStateVersionClient{
+				baseURL:    server.URL,
+				token:      tc.token,
+				httpClient: server.Client(),
+			}
+			err := c.Upload(context.Background(), "example-org", tc.workspace, []byte(tc.state), tc.baseSerial)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error")
+				}
+				if f.created != nil {
+					t.Errorf("unexpected state version was created: %#v", f.created)
+				}
+				return
+			}
+
+			if !f.unlocked || f.locked {
+				t.Errorf("the workspace was not unlocked")
+			}
+			if f.created["serial"] != tc.wantSerial {
+				t.Errorf("got serial: %v, want: %v", f.created["serial"], tc.wantSerial)
+			}
+			if f.created["lineage"] != tc.wantLineage {
+				t.Errorf("got lineage: %v, want: %v", f.created["lineage"], tc.wantLineage)
+			}
+			state, err := base64.StdEncoding.DecodeString(f.created["state"].(string))
+			if err != nil {
+				t.Fatalf("failed to decode state: %s", err)
+			}
+			sum := md5.Sum(state)
+			if f.created["md5"] != hex.EncodeToString(sum[:]) {
+				t.Errorf("got md5: %v, want: %x", f.created["md5"], sum)
+			}
+		})
+	}
+}
+
+func TestPushTfcStateUnsupported(t *testing.T) {
+	cases := []struct {
+		desc         string
+		backendState string
+	}{
+		{
+			desc:         "no backend state file",
+			backendState: "",
+		},
+		{
+			desc:         "unsupported backend",
+			backendState: `{"version":3,"backend":{"type":"s3","config":{"bucket":"foo"}}}`,
+		},
+		{
+			desc:         "no organization",
+			backendState: `{"version":3,"backend":{"type":"cloud","config":{"organization":null,"workspaces":{"name":"foo"}}}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("TF_CLOUD_ORGANIZATION", "")
+			dir := t.TempDir()
+			if len(tc.backendState) > 0 {
+				if err := os.Mkdir(filepath.Join(dir, ".terraform"), 0755); err != nil {
+					t.Fatalf("failed to create data dir: %s", err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, ".terraform", "terraform.tfstate"), []byte(tc.backendState), 0600); err != nil {
+					t.Fatalf("failed to write backend state file: %s", err)
+				}
+			}
+			err := pushTfcState(context.Background(), dir, "default", tfexec.NewState([]byte(`{"version":4}`)), nil)
+			if err == nil {
+				t.Fatalf("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestIsRemoteVersionMismatch(t *testing.T) {
+	cases := []struct {
+		desc string
+		msg  string
+		want bool
+	}{
+		{
+			desc: "version mismatch",
+			msg:  `Remote workspace Terraform version "1.5.7" does not match local Terraform version "1.6.0"`,
+			want: true,
+		},
+		{
+			desc: "same serial",
+			msg:  "Failed to write state: cannot overwrite existing state with serial 1 with a different state that has the same serial",
+			want: false,
+		},
+		{
+			desc: "newer serial",
+			msg:  "Failed to write state: cannot import state with serial 1 over newer state with serial 2",
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := isRemoteVersionMismatch(errors.New(tc.msg))
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
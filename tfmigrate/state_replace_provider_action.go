@@ -2,6 +2,7 @@ package tfmigrate
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/minamijoyo/tfmigrate/tfexec"
 )
@@ -26,11 +27,27 @@ func NewStateReplaceProviderAction(source string, destination string) *StateRepl
 	}
 }
 
+// String returns the action as "replace-provider <source> <destination>".
+func (a *StateReplaceProviderAction) String() string {
+	return "replace-provider " + a.source + " " + a.destination
+}
+
 // StateUpdate updates a given state and returns a new state.
 // It moves a provider from source address to destination address in the same tfstate file.
+// The destination address must be referenced by configuration. Otherwise, the
+// replaced provider would be unknown to terraform and plan would fail with a
+// confusing error.
 func (a *StateReplaceProviderAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
+	tree, err := tf.ProvidersTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !tree.References(a.destination) {
+		return nil, fmt.Errorf("failed to replace provider: %s is not referenced by configuration in %s", a.destination, tf.Dir())
+	}
+
 	// Disable unnecessary state backup here,
 	// because we never restore state from the backup generated by each state action.
-	// The state replace-provider command doesn't provide a way to disable it, so we backup to /dev/null.
-	return tf.StateReplaceProvider(ctx, state, a.source, a.destination, "-backup=/dev/null", "-auto-approve")
+	// The state replace-provider command doesn't provide a way to disable it, so we backup to the null device.
+	return tf.StateReplaceProvider(ctx, state, a.source, a.destination, disableBackupOpt, "-auto-approve")
 }
@@ -0,0 +1,71 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// StateRetypeAction implements the StateAction interface.
+// StateRetypeAction rewrites a resource type from source to destination in
+// the same tfstate file, such as a type renamed by a provider upgrade.
+type StateRetypeAction struct {
+	// source is the resource type to be rewritten.
+	source string
+	// destination is the new resource type.
+	destination string
+}
+
+var _ StateAction = (*StateRetypeAction)(nil)
+
+// NewStateRetypeAction returns a new StateRetypeAction instance.
+func NewStateRetypeAction(source string, destination string) *StateRetypeAction {
+	return &StateRetypeAction{
+		source:      source,
+		destination: destination,
+	}
+}
+
+// String returns the action as "retype <source> <destination>".
+func (a *StateRetypeAction) String() string {
+	return "retype " + a.source + " " + a.destination
+}
+
+// StateUpdate updates a given state and returns a new state.
+// It rewrites all managed resources of the source type to the destination
+// type. The destination type must exist in the schema of the provider of each
+// resource, and its schema version must not be older than the one recorded in
+// the state. Otherwise, terraform would fail to decode the rewritten state.
+func (a *StateRetypeAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
+	if a.source == a.destination {
+		return nil, fmt.Errorf("failed to retype: source and destination are the same type: %s", a.source)
+	}
+
+	newState, retyped, err := tfexec.RetypeState(state, a.source, a.destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retype: %s", err)
+	}
+	if len(retyped) == 0 {
+		return nil, fmt.Errorf("failed to retype: no resources of type %s in state", a.source)
+	}
+
+	schema, err := tf.ProvidersSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range retyped {
+		version, ok := schema.ResourceSchemaVersion(r.Provider, a.destination)
+		if !ok {
+			return nil, fmt.Errorf("failed to retype: %s is not a resource type of %s in %s", a.destination, r.Provider, tf.Dir())
+		}
+		if r.SchemaVersion > version {
+			return nil, fmt.Errorf("failed to retype: schema version of %s is %d, but %s supports only up to %d", r.Source, r.SchemaVersion, a.destination, version)
+		}
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] retype %s to %s\n", tf.Dir(), r.Source, r.Destination)
+	}
+
+	return newState, nil
+}
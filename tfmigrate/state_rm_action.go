@@ -2,6 +2,7 @@ package tfmigrate
 
 import (
 	"context"
+	"strings"
 
 	"github.com/minamijoyo/tfmigrate/tfexec"
 )
@@ -22,11 +23,16 @@ func NewStateRmAction(addresses []string) *StateRmAction {
 	}
 }
 
+// String returns the action as "rm <addresses>...".
+func (a *StateRmAction) String() string {
+	return "rm " + strings.Join(a.addresses, " ")
+}
+
 // StateUpdate updates a given state and returns a new state.
 // It removes resources from state at given addresses.
 func (a *StateRmAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
 	// Disable unnecessary state backup here,
 	// because we never restore state from the backup generated by each state action.
-	// The state rm command doesn't provide a way to disable it, so we backup to /dev/null.
-	return tf.StateRm(ctx, state, a.addresses, "-backup=/dev/null")
+	// The state rm command doesn't provide a way to disable it, so we backup to the null device.
+	return tf.StateRm(ctx, state, a.addresses, disableBackupOpt)
 }
@@ -0,0 +1,274 @@
+package tfmigrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	awsbase "github.com/hashicorp/aws-sdk-go-base"
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// stateVersion returns a version of the remote state in a given working
+// directory to be used as an input of plan instead of the current state.
+// A version for a specific directory takes precedence over one for any
+// directory. It returns an empty string if not set.
+func (o *MigratorOption) stateVersion(dir string) string {
+	if o == nil {
+		return ""
+	}
+	if v, ok := o.StateVersions[filepath.Clean(dir)]; ok {
+		return v
+	}
+	return o.StateVersions[""]
+}
+
+// backendState is a subset of the backend state file written by terraform
+// init in the data directory. It contains the initialized backend config.
+type backendState struct {
+	Backend *struct {
+		Type   string                 `json:"type"`
+		Config map[string]interface{} `json:"config"`
+	} `json:"backend"`
+}
+
+// pullStateVersion fetches a given version of the remote state for a given
+// workspace in an initialized working directory. The backend is detected from
+// the backend state file. Only the s3 backend with an object version ID and
+// the remote and cloud backends with a state version ID are supported.
+func pullStateVersion(ctx context.Context, dir string, workspace string, version string) (*tfexec.State, error) {
+	s, err := readBackendState(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] get the remote state version %s from the %s backend\n", dir, version, s.Backend.Type)
+	var state []byte
+	switch s.Backend.Type {
+	case "s3":
+		state, err = pullS3StateVersion(ctx, s.Backend.Config, workspace, version)
+	case "remote", "cloud":
+		state, err = newTfcStateVersionClient(s.Backend.Config).Download(ctx, version)
+	default:
+		return nil, fmt.Errorf("selecting a state version is not supported for the %s backend", s.Backend.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tfexec.NewState(state), nil
+}
+
+// readBackendState reads the backend state file in the data directory of an
+// initialized working directory.
+func readBackendState(dir string) (*backendState, error) {
+	dataDir := os.Getenv("TF_DATA_DIR")
+	if len(dataDir) == 0 {
+		dataDir = ".terraform"
+	}
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(dir, dataDir)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dataDir, "terraform.tfstate"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the backend state file: %s", err)
+	}
+	var s backendState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse the backend state file: %s", err)
+	}
+	if s.Backend == nil {
+		return nil, fmt.Errorf("failed to find backend config in %s", dataDir)
+	}
+	return &s, nil
+}
+
+// configString returns a string value in a given backend config.
+// It returns an empty string if not found.
+func configString(config map[string]interface{}, key string) string {
+	if v, ok := config[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// s3StateKey returns an object key of the state for a given workspace in the
+// s3 backend.
+func s3StateKey(config map[string]interface{}, workspace string) string {
+	key := configString(config, "key")
+	if len(workspace) == 0 || workspace == "default" {
+		return key
+	}
+	prefix := configString(config, "workspace_key_prefix")
+	if len(prefix) == 0 {
+		prefix = "env:"
+	}
+	return prefix + "/" + workspace + "/" + key
+}
+
+// pullS3StateVersion fetches a given object version of the state from the s3
+// backend. Credentials are resolved in the same way as the s3 storage.
+func pullS3StateVersion(ctx context.Context, config map[string]interface{}, workspace string, version string) ([]byte, error) {
+	sess, err := awsbase.GetSession(&awsbase.Config{
+		Region:  configString(config, "region"),
+		Profile: configString(config, "profile"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to new s3 client: %s", err)
+	}
+
+	awsConfig := &aws.Config{}
+	if endpoint := configString(config, "endpoint"); len(endpoint) > 0 {
+		awsConfig.Endpoint = aws.String(endpoint)
+	}
+	if pathStyle, ok := config["force_path_style"].(bool); ok && pathStyle {
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+	client := s3.New(sess.Copy(awsConfig))
+
+	input := &s3.GetObjectInput{
+		Bucket:    aws.String(configString(config, "bucket")),
+		Key:       aws.String(s3StateKey(config, workspace)),
+		VersionId: aws.String(version),
+	}
+	output, err := client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the state version %s from s3: %s", version, err)
+	}
+	defer output.Body.Close()
+
+	return io.ReadAll(output.Body)
+}
+
+// tfcToken returns an API token for a given hostname of Terraform Cloud or
+// Terraform Enterprise. It reads TF_TOKEN_<hostname> in the same format as
+// terraform, and falls back to TFE_TOKEN.
+func tfcToken(hostname string) string {
+	name := "TF_TOKEN_" + strings.ReplaceAll(strings.ReplaceAll(hostname, "-", "__"), ".", "_")
+	if token := os.Getenv(name); len(token) > 0 {
+		return token
+	}
+	return os.Getenv("TFE_TOKEN")
+}
+
+// tfcStateVersionClient is a minimal client for the state versions API of
+// Terraform Cloud.
+type tfcStateVersionClient struct {
+	// baseURL is a URL of Terraform Cloud such as https://app.terraform.io.
+	baseURL string
+	// token is an API token.
+	token string
+	// httpClient is a client for HTTP requests.
+	httpClient *http.Client
+}
+
+// newTfcStateVersionClient returns a new client for a given config of the
+// remote or cloud backend. If the hostname is not set in the config, it falls
+// back to TF_CLOUD_HOSTNAME and app.terraform.io.
+func newTfcStateVersionClient(config map[string]interface{}) *tfcStateVersionClient {
+	hostname := configString(config, "hostname")
+	if len(hostname) == 0 {
+		hostname = os.Getenv("TF_CLOUD_HOSTNAME")
+	}
+	if len(hostname) == 0 {
+		hostname = "app.terraform.io"
+	}
+	return &tfcStateVersionClient{
+		baseURL:    "https://" + hostname,
+		token:      tfcToken(hostname),
+		httpClient: http.DefaultClient,
+	}
+}
+
+// Download returns a state of a given state version ID such as sv-xxx.
+func (c *tfcStateVersionClient) Download(ctx context.Context, version string) ([]byte, error) {
+	if len(c.token) == 0 {
+		return nil, fmt.Errorf("no API token found for %s. Set TF_TOKEN_<hostname> or TFE_TOKEN", c.baseURL)
+	}
+
+	b, err := c.get(ctx, c.baseURL+"/api/v2/state-versions/"+url.PathEscape(version))
+	if err != nil {
+		return nil, err
+	}
+
+	var sv struct {
+		Data struct {
+			Attributes struct {
+				DownloadURL string `json:"hosted-state-download-url"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(b, &sv); err != nil {
+		return nil, fmt.Errorf("failed to parse the state version %s: %s", version, err)
+	}
+	if len(sv.Data.Attributes.DownloadURL) == 0 {
+		return nil, fmt.Errorf("failed to find a download url of the state version %s", version)
+	}
+
+	return c.get(ctx, sv.Data.Attributes.DownloadURL)
+}
+
+// get sends a GET request with the API token and returns the response body.
+func (c *tfcStateVersionClient) get(ctx context.Context, u string) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, u, nil, http.StatusOK)
+}
+
+// do sends a request with the API token and returns the response body.
+// It returns an error if the status code is not a given one.
+func (c *tfcStateVersionClient) do(ctx context.Context, method string, u string, body []byte, status int) ([]byte, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.api+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/vnd.api+json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != status {
+		return nil, &tfcResponseError{url: req.URL.Redacted(), status: resp.Status, statusCode: resp.StatusCode}
+	}
+	return b, nil
+}
+
+// tfcResponseError is an error for an unexpected response from Terraform
+// Cloud API.
+type tfcResponseError struct {
+	// url is a URL of the request without credentials.
+	url string
+	// status is a status line such as "404 Not Found".
+	status string
+	// statusCode is a status code of the response.
+	statusCode int
+}
+
+// Error returns a string of the error.
+func (e *tfcResponseError) Error() string {
+	return fmt.Sprintf("unexpected response from %s: %s", e.url, e.status)
+}
@@ -0,0 +1,219 @@
+package tfmigrate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigratorOptionStateVersion(t *testing.T) {
+	cases := []struct {
+		desc string
+		o    *MigratorOption
+		dir  string
+		want string
+	}{
+		{
+			desc: "nil",
+			o:    nil,
+			dir:  "dir1",
+			want: "",
+		},
+		{
+			desc: "not set",
+			o:    &MigratorOption{},
+			dir:  "dir1",
+			want: "",
+		},
+		{
+			desc: "any directory",
+			o:    &MigratorOption{StateVersions: map[string]string{"": "v1"}},
+			dir:  "dir1",
+			want: "v1",
+		},
+		{
+			desc: "specific directory takes precedence",
+			o:    &MigratorOption{StateVersions: map[string]string{"": "v1", "dir1": "v2"}},
+			dir:  "./dir1/",
+			want: "v2",
+		},
+		{
+			desc: "another directory",
+			o:    &MigratorOption{StateVersions: map[string]string{"dir1": "v2"}},
+			dir:  "dir2",
+			want: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.o.stateVersion(tc.dir)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestS3StateKey(t *testing.T) {
+	cases := []struct {
+		desc      string
+		config    map[string]interface{}
+		workspace string
+		want      string
+	}{
+		{
+			desc:      "default workspace",
+			config:    map[string]interface{}{"key": "foo/terraform.tfstate"},
+			workspace: "default",
+			want:      "foo/terraform.tfstate",
+		},
+		{
+			desc:      "non-default workspace",
+			config:    map[string]interface{}{"key": "foo/terraform.tfstate"},
+			workspace: "work1",
+			want:      "env:/work1/foo/terraform.tfstate",
+		},
+		{
+			desc:      "workspace_key_prefix",
+			config:    map[string]interface{}{"key": "foo/terraform.tfstate", "workspace_key_prefix": "workspaces"},
+			workspace: "work1",
+			want:      "workspaces/work1/foo/terraform.tfstate",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := s3StateKey(tc.config, tc.workspace)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPullStateVersionUnsupported(t *testing.T) {
+	cases := []struct {
+		desc         string
+		backendState string
+	}{
+		{
+			desc:         "no backend state file",
+			backendState: "",
+		},
+		{
+			desc:         "unsupported backend",
+			backendState: `{"version":3,"backend":{"type":"local","config":{"path":null}}}`,
+		},
+		{
+			desc:         "no backend",
+			backendState: `{"version":3}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			if len(tc.backendState) > 0 {
+				if err := os.Mkdir(filepath.Join(dir, ".terraform"), 0755); err != nil {
+					t.Fatalf("failed to create data dir: %s", err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, ".terraform", "terraform.tfstate"), []byte(tc.backendState), 0600); err != nil {
+					t.Fatalf("failed to write backend state file: %s", err)
+				}
+			}
+			got, err := pullStateVersion(context.Background(), dir, "default", "v1")
+			if err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", got.Bytes())
+			}
+		})
+	}
+}
+
+func TestTfcStateVersionClientDownload(t *testing.T) {
+	mux := http.NewServeMux()
+	var serverURL string
+	mux.HandleFunc("/api/v2/state-versions/sv-123", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":{"id":"sv-123","attributes":{"hosted-state-download-url":"` + serverURL + `/download/sv-123"}}}`))
+	})
+	mux.HandleFunc("/download/sv-123", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"version":4,"lineage":"foo"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL = server.URL
+
+	cases := []struct {
+		desc    string
+		token   string
+		version string
+		want    string
+		ok      bool
+	}{
+		{
+			desc:    "found",
+			token:   "token",
+			version: "sv-123",
+			want:    `{"version":4,"lineage":"foo"}`,
+			ok:      true,
+		},
+		{
+			desc:    "not found",
+			token:   "token",
+			version: "sv-456",
+			ok:      false,
+		},
+		{
+			desc:    "unauthorized",
+			token:   "invalid",
+			version: "sv-123",
+			ok:      false,
+		},
+		{
+			desc:    "no token",
+			token:   "",
+			version: "sv-123",
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := &tfcStateVersionClient{
+				baseURL:    server.URL,
+				token:      tc.token,
+				httpClient: server.Client(),
+			}
+			got, err := c.Download(context.Background(), tc.version)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", got)
+			}
+			if tc.ok && string(got) != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTfcToken(t *testing.T) {
+	t.Setenv("TF_TOKEN_tfe_example-corp_com", "")
+	t.Setenv("TF_TOKEN_tfe_example__corp_com", "foo")
+	t.Setenv("TFE_TOKEN", "bar")
+
+	if got := tfcToken("tfe.example-corp.com"); got != "foo" {
+		t.Errorf("got: %s, want: foo", got)
+	}
+	if got := tfcToken("app.terraform.io"); got != "bar" {
+		t.Errorf("got: %s, want: bar", got)
+	}
+}
@@ -14,9 +14,12 @@ type StateXmvAction struct {
 	source string
 	// destination is a new address of resource or module to move which can contain placeholders.
 	destination string
+	// resolved is a list of mv actions generated by the last StateUpdate.
+	resolved []*StateMvAction
 }
 
 var _ StateAction = (*StateXmvAction)(nil)
+var _ actionResolver = (*StateXmvAction)(nil)
 
 // NewStateXmvAction returns a new StateXmvAction instance.
 func NewStateXmvAction(source string, destination string) *StateXmvAction {
@@ -26,14 +29,21 @@ func NewStateXmvAction(source string, destination string) *StateXmvAction {
 	}
 }
 
+// String returns the action as "xmv <source> <destination>" before expanding wildcards.
+func (a *StateXmvAction) String() string {
+	return "xmv " + a.source + " " + a.destination
+}
+
 // StateUpdate updates a given state and returns a new state.
 // Source resources have wildcards which should be matched against the tf state.
 // Each occurrence will generate a move command.
 func (a *StateXmvAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
+	a.resolved = nil
 	stateMvActions, err := a.generateMvActions(ctx, tf, state)
 	if err != nil {
 		return nil, err
 	}
+	a.resolved = stateMvActions
 
 	for _, action := range stateMvActions {
 		state, err = action.StateUpdate(ctx, tf, state)
@@ -44,6 +54,19 @@ func (a *StateXmvAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI
 	return state, err
 }
 
+// resolvedActions returns a list of mv actions generated by the last
+// StateUpdate. It's nil if wildcards have not been expanded yet.
+func (a *StateXmvAction) resolvedActions() []string {
+	if a.resolved == nil {
+		return nil
+	}
+	actions := make([]string, 0, len(a.resolved))
+	for _, action := range a.resolved {
+		actions = append(actions, action.String())
+	}
+	return actions
+}
+
 // generateMvActions uses an xmv and use the state to determine the corresponding mv actions.
 func (a *StateXmvAction) generateMvActions(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) ([]*StateMvAction, error) {
 	stateList, err := tf.StateList(ctx, state, nil)
@@ -0,0 +1,119 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// StateXrmAction implements the StateAction interface.
+// StateXrmAction is an extended version of StateRmAction.
+// It allows you to remove multiple resources with wildcard matching.
+type StateXrmAction struct {
+	// patterns is a list of addresses of resources to be removed which can
+	// contain wildcards.
+	patterns []string
+	// removed is a list of addresses removed by the last StateUpdate.
+	// It is used to allow deposed objects and tainted instances under them
+	// to disappear.
+	removed []string
+}
+
+var _ StateAction = (*StateXrmAction)(nil)
+var _ actionResolver = (*StateXrmAction)(nil)
+
+// NewStateXrmAction returns a new StateXrmAction instance.
+func NewStateXrmAction(patterns []string) *StateXrmAction {
+	return &StateXrmAction{
+		patterns: patterns,
+	}
+}
+
+// String returns the action as "xrm <patterns>..." before expanding wildcards.
+func (a *StateXrmAction) String() string {
+	return "xrm " + strings.Join(a.patterns, " ")
+}
+
+// StateUpdate updates a given state and returns a new state.
+// Patterns have wildcards which should be matched against the tf state.
+// All matched resources are removed at once, and they are logged so that
+// they can be confirmed in the plan output.
+func (a *StateXrmAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
+	a.removed = nil
+	stateList, err := tf.StateList(ctx, state, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := expandXrm(a.patterns, stateList)
+	if err != nil {
+		return nil, err
+	}
+	a.removed = addresses
+	if len(addresses) == 0 {
+		logging.FromContext(ctx).Printf("[WARN] [migrator@%s] %s matched no resources\n", tf.Dir(), a)
+		return state, nil
+	}
+
+	logging.FromContext(ctx).Printf("[INFO] [migrator@%s] %s matched %d resources\n", tf.Dir(), a, len(addresses))
+	for _, address := range addresses {
+		logging.FromContext(ctx).Printf("[INFO] [migrator@%s] remove %s\n", tf.Dir(), address)
+	}
+
+	return NewStateRmAction(addresses).StateUpdate(ctx, tf, state)
+}
+
+// resolvedActions returns a list of rm actions for each address removed by
+// the last StateUpdate. It's nil if wildcards have not been expanded yet.
+func (a *StateXrmAction) resolvedActions() []string {
+	if a.removed == nil {
+		return nil
+	}
+	actions := make([]string, 0, len(a.removed))
+	for _, address := range a.removed {
+		actions = append(actions, NewStateRmAction([]string{address}).String())
+	}
+	return actions
+}
+
+// expandXrm returns a list of addresses in a given state list which match
+// any of given patterns in order of the state list. Unlike xmv, a pattern
+// must match a whole address, so that aws_instance.* never removes
+// resources in modules such as module.foo.aws_instance.bar.
+func expandXrm(patterns []string, stateList []string) ([]string, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := makeSrcRegex(p)
+		if err != nil {
+			return nil, err
+		}
+		// Anchor the pattern, which is safely quoted by makeSrcRegex.
+		res = append(res, regexp.MustCompile("^(?:"+re.String()+")$"))
+	}
+
+	matched := []string{}
+	for _, address := range stateList {
+		for _, re := range res {
+			if re.MatchString(address) {
+				matched = append(matched, address)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// checkXrmPatterns returns an error if a given list of patterns is invalid.
+// It is a static check which doesn't require the state.
+func checkXrmPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if strings.Trim(p, wildcardChar) == "" {
+			return fmt.Errorf("xrm pattern matches all resources, which is not allowed: %q", p)
+		}
+	}
+	return nil
+}
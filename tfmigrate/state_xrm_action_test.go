@@ -0,0 +1,132 @@
+package tfmigrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestExpandXrm(t *testing.T) {
+	stateList := []string{
+		"null_resource.foo",
+		"null_resource.bar[0]",
+		"null_resource.bar[1]",
+		`null_resource.baz["a"]`,
+		"module.legacy.null_resource.foo",
+		`module.legacy.module.child["a"].null_resource.foo`,
+		"module.legacy2.null_resource.foo",
+	}
+
+	cases := []struct {
+		desc     string
+		patterns []string
+		want     []string
+	}{
+		{
+			desc:     "module",
+			patterns: []string{"module.legacy.*"},
+			want: []string{
+				"module.legacy.null_resource.foo",
+				`module.legacy.module.child["a"].null_resource.foo`,
+			},
+		},
+		{
+			desc:     "whole address match",
+			patterns: []string{"null_resource.*"},
+			want: []string{
+				"null_resource.foo",
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["a"]`,
+			},
+		},
+		{
+			desc:     "meta characters are quoted",
+			patterns: []string{"null_resource.bar[*]", `null_resource.baz["*"]`},
+			want: []string{
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["a"]`,
+			},
+		},
+		{
+			desc:     "no wildcard",
+			patterns: []string{"null_resource.foo"},
+			want:     []string{"null_resource.foo"},
+		},
+		{
+			desc:     "overlapped patterns",
+			patterns: []string{"*.foo", "null_resource.*"},
+			want: []string{
+				"null_resource.foo",
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["a"]`,
+				"module.legacy.null_resource.foo",
+				`module.legacy.module.child["a"].null_resource.foo`,
+				"module.legacy2.null_resource.foo",
+			},
+		},
+		{
+			desc:     "no match",
+			patterns: []string{"module.foo.*"},
+			want:     []string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := expandXrm(tc.patterns, stateList)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAccStateXrmAction(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+
+	backend := tfexec.GetTestAccBackendS3Config(t.Name())
+
+	source := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+resource "time_static" "qux" {}
+`
+
+	workspace := "default"
+	tf := tfexec.SetupTestAccWithApply(t, workspace, backend+source)
+	ctx := context.Background()
+
+	updatedSource := `
+resource "time_static" "qux" {}
+`
+
+	tfexec.UpdateTestAccSource(t, tf, backend+updatedSource)
+
+	changed, err := tf.PlanHasChange(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to run PlanHasChange: %s", err)
+	}
+	if !changed {
+		t.Fatalf("expect to have changes")
+	}
+
+	actions := []StateAction{
+		NewStateXrmAction([]string{"null_resource.*"}),
+		NewStateXrmAction([]string{"null_resource.*"}),
+	}
+
+	m := NewStateMigrator(tf.Dir(), workspace, actions, &MigratorOption{}, false, false)
+	err = m.Plan(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+}
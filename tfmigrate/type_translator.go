@@ -0,0 +1,107 @@
+package tfmigrate
+
+import (
+	"strings"
+)
+
+// TypeTranslator translates a resource type which is renamed by a provider,
+// such as aws_alb to aws_lb, to the canonical type.
+// It allows us to add provider-specific quirks without changing callers.
+type TypeTranslator interface {
+	// TranslateType returns the canonical type of a given resource type.
+	// The second return value is false if the type is not renamed.
+	TranslateType(resourceType string) (string, bool)
+}
+
+// TypeRenames is a TypeTranslator with a static table of renamed types.
+// A key is an old type and a value is the canonical type.
+type TypeRenames map[string]string
+
+var _ TypeTranslator = (TypeRenames)(nil)
+
+// TranslateType returns the canonical type of a given resource type.
+func (t TypeRenames) TranslateType(resourceType string) (string, bool) {
+	canonical, ok := t[resourceType]
+	return canonical, ok
+}
+
+// awsTypeRenames is a table of resource types renamed in the AWS provider.
+// The old names are still available as aliases sharing the same schemas.
+var awsTypeRenames = TypeRenames{
+	"aws_alb":                         "aws_lb",
+	"aws_alb_listener":                "aws_lb_listener",
+	"aws_alb_listener_certificate":    "aws_lb_listener_certificate",
+	"aws_alb_listener_rule":           "aws_lb_listener_rule",
+	"aws_alb_target_group":            "aws_lb_target_group",
+	"aws_alb_target_group_attachment": "aws_lb_target_group_attachment",
+}
+
+// typeTranslators is a list of TypeTranslators looked up in order.
+var typeTranslators = []TypeTranslator{
+	awsTypeRenames,
+}
+
+// RegisterTypeTranslator adds a given TypeTranslator, which takes precedence
+// over the built-in ones. It is intended to be called at initialization.
+func RegisterTypeTranslator(t TypeTranslator) {
+	typeTranslators = append([]TypeTranslator{t}, typeTranslators...)
+}
+
+// TranslateType returns the canonical type of a given resource type with the
+// registered TypeTranslators. The second return value is false if the type is
+// not renamed.
+func TranslateType(resourceType string) (string, bool) {
+	for _, t := range typeTranslators {
+		if canonical, ok := t.TranslateType(resourceType); ok {
+			return canonical, true
+		}
+	}
+	return "", false
+}
+
+// NormalizeAddress returns a resource address in which renamed resource types
+// are translated to the canonical types, so that addresses before and after a
+// provider upgrade can be compared.
+// e.g.) module.foo.aws_alb.bar => module.foo.aws_lb.bar
+// Module names, data resources and wildcards are kept as they are.
+func NormalizeAddress(address string) string {
+	parts := splitAddress(address)
+	for i := 0; i < len(parts); i++ {
+		switch parts[i] {
+		case "module", "data":
+			// skip the next part which is a module name or a data type.
+			i++
+			continue
+		}
+		name, index, _ := strings.Cut(parts[i], "[")
+		if canonical, ok := TranslateType(name); ok && len(index) == 0 {
+			parts[i] = canonical
+		}
+		// skip the resource name.
+		i++
+	}
+	return strings.Join(parts, ".")
+}
+
+// splitAddress splits a resource address by dots, but dots in instance keys
+// such as module.foo["a.b"] are not treated as separators.
+func splitAddress(address string) []string {
+	parts := []string{}
+	start := 0
+	inKey := false
+	for i := 0; i < len(address); i++ {
+		switch address[i] {
+		case '"':
+			inKey = !inKey
+		case '\\':
+			// skip an escaped character in a key.
+			i++
+		case '.':
+			if !inKey {
+				parts = append(parts, address[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, address[start:])
+}
@@ -0,0 +1,88 @@
+package tfmigrate
+
+import (
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	cases := []struct {
+		desc    string
+		address string
+		want    string
+	}{
+		{
+			desc:    "resource",
+			address: "aws_alb.foo",
+			want:    "aws_lb.foo",
+		},
+		{
+			desc:    "resource with index",
+			address: `aws_alb_listener.foo["a.b"]`,
+			want:    `aws_lb_listener.foo["a.b"]`,
+		},
+		{
+			desc:    "resource in module",
+			address: `module.aws_alb["a.b"].module.bar.aws_alb_target_group.foo[0]`,
+			want:    `module.aws_alb["a.b"].module.bar.aws_lb_target_group.foo[0]`,
+		},
+		{
+			desc:    "data resource",
+			address: "data.aws_alb.foo",
+			want:    "data.aws_alb.foo",
+		},
+		{
+			desc:    "resource name is not a type",
+			address: "null_resource.aws_alb",
+			want:    "null_resource.aws_alb",
+		},
+		{
+			desc:    "wildcard",
+			address: "aws_alb.*",
+			want:    "aws_lb.*",
+		},
+		{
+			desc:    "not renamed",
+			address: "aws_lb.foo",
+			want:    "aws_lb.foo",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := NormalizeAddress(tc.address)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRegisterTypeTranslator(t *testing.T) {
+	orig := typeTranslators
+	defer func() { typeTranslators = orig }()
+
+	RegisterTypeTranslator(TypeRenames{
+		"aws_alb":    "aws_lb_custom",
+		"foo_legacy": "foo_resource",
+	})
+
+	cases := []struct {
+		resourceType string
+		want         string
+		ok           bool
+	}{
+		{resourceType: "aws_alb", want: "aws_lb_custom", ok: true},
+		{resourceType: "foo_legacy", want: "foo_resource", ok: true},
+		{resourceType: "aws_alb_listener", want: "aws_lb_listener", ok: true},
+		{resourceType: "null_resource", want: "", ok: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.resourceType, func(t *testing.T) {
+			got, ok := TranslateType(tc.resourceType)
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("got: (%s, %t), want: (%s, %t)", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/minamijoyo/tfmigrate/logging"
+)
+
+// StateExpectation is an expected presence of a resource address in a state
+// after a migration has been applied.
+type StateExpectation struct {
+	// Dir is a working directory of the state.
+	Dir string
+	// Workspace is a workspace of the state.
+	Workspace string
+	// Env is a map of environment variables to access the state.
+	Env map[string]string
+	// Address is a resource address.
+	Address string
+	// Exists is true if the address is expected to exist in the state,
+	// and false if it is expected to be absent.
+	Exists bool
+}
+
+// Expectations returns a list of expectations on the current state implied
+// by a given migration. Actions whose results cannot be determined from the
+// migration file alone are ignored, such as xmv with wildcards,
+// replace-provider and action plugins.
+func (c *MigrationConfig) Expectations() ([]*StateExpectation, error) {
+	expectations := []*StateExpectation{}
+	switch m := c.Migrator.(type) {
+	case *StateMigratorConfig:
+		dir := m.Dir
+		if len(dir) == 0 {
+			dir = "."
+		}
+		workspace := m.Workspace
+		if len(workspace) == 0 {
+			workspace = "default"
+		}
+		for _, cmdStr := range m.Actions {
+			args, err := splitStateAction(cmdStr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+			}
+			if len(args) < 2 {
+				continue
+			}
+			expect := func(address string, exists bool) {
+				expectations = append(expectations, &StateExpectation{
+					Dir:       dir,
+					Workspace: workspace,
+					Env:       m.Env,
+					Address:   address,
+					Exists:    exists,
+				})
+			}
+			switch args[0] {
+			case "mv":
+				if len(args) == 3 {
+					expect(args[1], false)
+					expect(args[2], true)
+				}
+			case "rm":
+				for _, address := range args[1:] {
+					expect(address, false)
+				}
+			case "import":
+				expect(args[1], true)
+			}
+		}
+
+	case *MultiStateMigratorConfig:
+		fromWorkspace := m.FromWorkspace
+		if len(fromWorkspace) == 0 {
+			fromWorkspace = "default"
+		}
+		toWorkspace := m.ToWorkspace
+		if len(toWorkspace) == 0 {
+			toWorkspace = "default"
+		}
+		for _, cmdStr := range m.Actions {
+			args, err := splitStateAction(cmdStr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+			}
+			if (len(args) != 3 && len(args) != 4) || args[0] != "mv" {
+				continue
+			}
+			expectations = append(expectations,
+				&StateExpectation{
+					Dir:       m.FromDir,
+					Workspace: fromWorkspace,
+					Env:       mergeEnv(m.Env, m.FromEnv),
+					Address:   args[1],
+					Exists:    false,
+				},
+				&StateExpectation{
+					Dir:       m.actionToDir(cmdStr),
+					Workspace: toWorkspace,
+					Env:       mergeEnv(m.Env, m.ToEnv),
+					Address:   args[2],
+					Exists:    true,
+				},
+			)
+		}
+	}
+
+	return expectations, nil
+}
+
+// Overlaps returns true if a given expectation is on the same state and
+// either address is under the other, so that the later one supersedes the
+// earlier one.
+func (e *StateExpectation) Overlaps(other *StateExpectation) bool {
+	if filepath.Clean(e.Dir) != filepath.Clean(other.Dir) || e.Workspace != other.Workspace {
+		return false
+	}
+	return hasAddressPrefix(e.Address, other.Address) || hasAddressPrefix(other.Address, e.Address)
+}
+
+// Satisfied returns true if a given list of addresses in a state meets the
+// expectation. An address matches an instance under it, so that
+// aws_instance.foo exists if aws_instance.foo[0] exists.
+func (e *StateExpectation) Satisfied(addresses []string) bool {
+	found := false
+	for _, address := range addresses {
+		if hasAddressPrefix(address, e.Address) {
+			found = true
+			break
+		}
+	}
+	return found == e.Exists
+}
+
+// ListStateAddresses initializes a given working directory, switches to a
+// given workspace and returns a list of resource addresses in the current
+// remote state.
+func ListStateAddresses(ctx context.Context, dir string, workspace string, env map[string]string, o *MigratorOption) ([]string, error) {
+	if err := validateEnv(env); err != nil {
+		return nil, err
+	}
+	tf := newTerraformCLI(dir, o)
+	appendEnv(tf, env)
+
+	logging.FromContext(ctx).Printf("[INFO] [verifier@%s] initialize work dir\n", dir)
+	if err := tf.Init(ctx, "-input=false", "-no-color"); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Printf("[INFO] [verifier@%s] switch to workspace %s\n", dir, workspace)
+	if err := tf.WorkspaceSelect(ctx, workspace); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Printf("[INFO] [verifier@%s] list resources in the current remote state\n", dir)
+	return tf.StateList(ctx, nil, nil)
+}
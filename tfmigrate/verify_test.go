@@ -0,0 +1,206 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMigrationConfigExpectations(t *testing.T) {
+	cases := []struct {
+		desc string
+		mc   *MigrationConfig
+		want []*StateExpectation
+		ok   bool
+	}{
+		{
+			desc: "state",
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Dir: "dir1",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						"xmv null_resource.* module.foo.null_resource.$1",
+						"rm null_resource.bar null_resource.baz",
+						`import null_resource.qux["a b"] qux`,
+						"replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+					},
+					Env: map[string]string{"FOO": "bar"},
+				},
+			},
+			want: []*StateExpectation{
+				{Dir: "dir1", Workspace: "default", Env: map[string]string{"FOO": "bar"}, Address: "null_resource.foo", Exists: false},
+				{Dir: "dir1", Workspace: "default", Env: map[string]string{"FOO": "bar"}, Address: "null_resource.foo2", Exists: true},
+				{Dir: "dir1", Workspace: "default", Env: map[string]string{"FOO": "bar"}, Address: "null_resource.bar", Exists: false},
+				{Dir: "dir1", Workspace: "default", Env: map[string]string{"FOO": "bar"}, Address: "null_resource.baz", Exists: false},
+				{Dir: "dir1", Workspace: "default", Env: map[string]string{"FOO": "bar"}, Address: `null_resource.qux["a b"]`, Exists: true},
+			},
+			ok: true,
+		},
+		{
+			desc: "state with default dir and workspace",
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Workspace: "work1",
+					Actions:   []string{"rm null_resource.foo"},
+				},
+			},
+			want: []*StateExpectation{
+				{Dir: ".", Workspace: "work1", Address: "null_resource.foo", Exists: false},
+			},
+			ok: true,
+		},
+		{
+			desc: "multi_state",
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &MultiStateMigratorConfig{
+					FromDir:       "dir1",
+					ToDir:         "dir2",
+					FromWorkspace: "work1",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+						"xmv null_resource.* null_resource.$1",
+					},
+					Env:   map[string]string{"FOO": "bar"},
+					ToEnv: map[string]string{"BAZ": "qux"},
+				},
+			},
+			want: []*StateExpectation{
+				{Dir: "dir1", Workspace: "work1", Env: map[string]string{"FOO": "bar"}, Address: "null_resource.foo", Exists: false},
+				{Dir: "dir2", Workspace: "default", Env: map[string]string{"FOO": "bar", "BAZ": "qux"}, Address: "null_resource.foo2", Exists: true},
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid action",
+			mc: &MigrationConfig{
+				Name: "test",
+				Migrator: &StateMigratorConfig{
+					Actions: []string{`mv "null_resource.foo null_resource.foo2`},
+				},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.mc.Expectations()
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStateExpectationSatisfied(t *testing.T) {
+	addresses := []string{
+		"null_resource.foo[0]",
+		`module.bar["a"].null_resource.baz`,
+	}
+
+	cases := []struct {
+		desc    string
+		address string
+		exists  bool
+		want    bool
+	}{
+		{
+			desc:    "instance of a resource exists",
+			address: "null_resource.foo",
+			exists:  true,
+			want:    true,
+		},
+		{
+			desc:    "resource in a module exists",
+			address: `module.bar["a"]`,
+			exists:  true,
+			want:    true,
+		},
+		{
+			desc:    "resource not found",
+			address: "null_resource.foo2",
+			exists:  true,
+			want:    false,
+		},
+		{
+			desc:    "resource absent",
+			address: "null_resource.fo",
+			exists:  false,
+			want:    true,
+		},
+		{
+			desc:    "resource reverted",
+			address: "null_resource.foo[0]",
+			exists:  false,
+			want:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := &StateExpectation{Dir: ".", Workspace: "default", Address: tc.address, Exists: tc.exists}
+			got := e.Satisfied(addresses)
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStateExpectationOverlaps(t *testing.T) {
+	cases := []struct {
+		desc string
+		a    *StateExpectation
+		b    *StateExpectation
+		want bool
+	}{
+		{
+			desc: "same address",
+			a:    &StateExpectation{Dir: "dir1", Workspace: "default", Address: "null_resource.foo"},
+			b:    &StateExpectation{Dir: "./dir1", Workspace: "default", Address: "null_resource.foo"},
+			want: true,
+		},
+		{
+			desc: "under the other",
+			a:    &StateExpectation{Dir: "dir1", Workspace: "default", Address: "module.foo.null_resource.bar"},
+			b:    &StateExpectation{Dir: "dir1", Workspace: "default", Address: "module.foo"},
+			want: true,
+		},
+		{
+			desc: "similar prefix",
+			a:    &StateExpectation{Dir: "dir1", Workspace: "default", Address: "module.foo2.null_resource.bar"},
+			b:    &StateExpectation{Dir: "dir1", Workspace: "default", Address: "module.foo"},
+			want: false,
+		},
+		{
+			desc: "another workspace",
+			a:    &StateExpectation{Dir: "dir1", Workspace: "default", Address: "null_resource.foo"},
+			b:    &StateExpectation{Dir: "dir1", Workspace: "work1", Address: "null_resource.foo"},
+			want: false,
+		},
+		{
+			desc: "another dir",
+			a:    &StateExpectation{Dir: "dir1", Workspace: "default", Address: "null_resource.foo"},
+			b:    &StateExpectation{Dir: "dir2", Workspace: "default", Address: "null_resource.foo"},
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.a.Overlaps(tc.b)
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
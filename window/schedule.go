@@ -0,0 +1,175 @@
+package window
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron-like expression.
+// It consists of 5 fields separated by spaces:
+//
+//	minute (0-59) hour (0-23) day-of-month (1-31) month (1-12) day-of-week (0-6)
+//
+// Each field accepts `*`, a single value, a range `a-b`, a step `*/n` or
+// `a-b/n`, and a comma separated list of them. Sunday can be written as
+// either 0 or 7 in the day-of-week field.
+// Unlike cron, which fires at the beginning of a matching minute, a Schedule
+// matches every minute it covers, so that it describes a time window.
+// e.g.) "* 22-23 * * 1-5" matches 22:00-23:59 on weekdays.
+type Schedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// domStar and dowStar are true if the field is `*`.
+	// As in cron, if both day-of-month and day-of-week are restricted,
+	// a day matches when either of them matches.
+	domStar bool
+	dowStar bool
+}
+
+// fieldRange is a range of valid values for a field.
+type fieldRange struct {
+	name string
+	min  int
+	max  int
+}
+
+var (
+	minuteRange = fieldRange{name: "minute", min: 0, max: 59}
+	hourRange   = fieldRange{name: "hour", min: 0, max: 23}
+	domRange    = fieldRange{name: "day-of-month", min: 1, max: 31}
+	monthRange  = fieldRange{name: "month", min: 1, max: 12}
+	dowRange    = fieldRange{name: "day-of-week", min: 0, max: 7}
+)
+
+// ParseSchedule parses a given cron-like expression and returns a Schedule.
+func ParseSchedule(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule must have 5 fields, but got %d: %s", len(fields), expr)
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteRange); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %s, err: %s", expr, err)
+	}
+	if s.hour, err = parseField(fields[1], hourRange); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %s, err: %s", expr, err)
+	}
+	if s.dom, err = parseField(fields[2], domRange); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %s, err: %s", expr, err)
+	}
+	if s.month, err = parseField(fields[3], monthRange); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %s, err: %s", expr, err)
+	}
+	if s.dow, err = parseField(fields[4], dowRange); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %s, err: %s", expr, err)
+	}
+	// Sunday is either 0 or 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 << 0
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+
+	return s, nil
+}
+
+// parseField parses a single field and returns a bit set of matched values.
+func parseField(field string, r fieldRange) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		b, err := parseItem(item, r)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parseItem parses an item of comma separated list in a field.
+func parseItem(item string, r fieldRange) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepPart)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid step in %s field: %s", r.name, item)
+		}
+		step = n
+	}
+
+	var start, end int
+	switch {
+	case rangePart == "*":
+		start, end = r.min, r.max
+	case strings.Contains(rangePart, "-"):
+		lo, hi, _ := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = parseValue(lo, r); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(hi, r); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range in %s field: %s", r.name, item)
+		}
+	default:
+		v, err := parseValue(rangePart, r)
+		if err != nil {
+			return 0, err
+		}
+		start, end = v, v
+		if hasStep {
+			// `a/n` means from a to the max.
+			end = r.max
+		}
+	}
+
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+// parseValue parses a single value and checks its range.
+func parseValue(s string, r fieldRange) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field: %s", r.name, s)
+	}
+	if v < r.min || v > r.max {
+		return 0, fmt.Errorf("%s must be between %d and %d, but got %d", r.name, r.min, r.max, v)
+	}
+	return v, nil
+}
+
+// Match returns true if a given time matches the schedule.
+// The time is evaluated in its own location.
+func (s *Schedule) Match(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 {
+		return false
+	}
+	if s.hour&(1<<uint(t.Hour())) == 0 {
+		return false
+	}
+	if s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,168 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	cases := []struct {
+		desc string
+		expr string
+		ok   bool
+	}{
+		{
+			desc: "all stars",
+			expr: "* * * * *",
+			ok:   true,
+		},
+		{
+			desc: "ranges, lists and steps",
+			expr: "*/15 0-6,22-23 1-15/2 1,4,7,10 1-5",
+			ok:   true,
+		},
+		{
+			desc: "sunday as 7",
+			expr: "* * * * 7",
+			ok:   true,
+		},
+		{
+			desc: "too few fields",
+			expr: "* * * *",
+			ok:   false,
+		},
+		{
+			desc: "too many fields",
+			expr: "* * * * * *",
+			ok:   false,
+		},
+		{
+			desc: "out of range",
+			expr: "60 * * * *",
+			ok:   false,
+		},
+		{
+			desc: "day-of-month zero",
+			expr: "* * 0 * *",
+			ok:   false,
+		},
+		{
+			desc: "reversed range",
+			expr: "* 23-22 * * *",
+			ok:   false,
+		},
+		{
+			desc: "invalid step",
+			expr: "*/0 * * * *",
+			ok:   false,
+		},
+		{
+			desc: "not a number",
+			expr: "* * * jan *",
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseSchedule(tc.expr)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+		})
+	}
+}
+
+func TestScheduleMatch(t *testing.T) {
+	// 2020-10-13 is Tuesday.
+	cases := []struct {
+		desc string
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{
+			desc: "all stars",
+			expr: "* * * * *",
+			t:    time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+			want: true,
+		},
+		{
+			desc: "in hour range",
+			expr: "* 22-23 * * 1-5",
+			t:    time.Date(2020, 10, 13, 23, 59, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			desc: "out of hour range",
+			expr: "* 22-23 * * 1-5",
+			t:    time.Date(2020, 10, 13, 21, 59, 0, 0, time.UTC),
+			want: false,
+		},
+		{
+			desc: "out of day-of-week",
+			expr: "* 22-23 * * 1-5",
+			t:    time.Date(2020, 10, 17, 22, 0, 0, 0, time.UTC),
+			want: false,
+		},
+		{
+			desc: "sunday as 7",
+			expr: "* * * * 7",
+			t:    time.Date(2020, 10, 18, 0, 0, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			desc: "step",
+			expr: "*/15 * * * *",
+			t:    time.Date(2020, 10, 13, 1, 30, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			desc: "not on step",
+			expr: "*/15 * * * *",
+			t:    time.Date(2020, 10, 13, 1, 31, 0, 0, time.UTC),
+			want: false,
+		},
+		{
+			desc: "out of month",
+			expr: "* * * 1-9 *",
+			t:    time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+			want: false,
+		},
+		{
+			desc: "day-of-month or day-of-week (dom matches)",
+			expr: "* * 13 * 0",
+			t:    time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+			want: true,
+		},
+		{
+			desc: "day-of-month or day-of-week (dow matches)",
+			expr: "* * 1 * 2",
+			t:    time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+			want: true,
+		},
+		{
+			desc: "day-of-month or day-of-week (neither matches)",
+			expr: "* * 1 * 0",
+			t:    time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := ParseSchedule(tc.expr)
+			if err != nil {
+				t.Fatalf("failed to parse schedule: %s", err)
+			}
+
+			got := s.Match(tc.t)
+			if got != tc.want {
+				t.Errorf("got = %t, but want = %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package window
+
+import (
+	"fmt"
+	"time"
+	// Embed the timezone database, so that a timezone can be loaded even in
+	// a minimal container image without zoneinfo, such as distroless.
+	_ "time/tzdata"
+)
+
+// Window is a time window in which apply is allowed.
+type Window struct {
+	// Schedule is a cron-like expression which describes the window.
+	Schedule string
+	// Timezone is a name of timezone in which the schedule is evaluated,
+	// such as Asia/Tokyo. Default to UTC.
+	Timezone string
+
+	schedule *Schedule
+	location *time.Location
+}
+
+// NewWindow returns a new Window instance.
+func NewWindow(schedule string, timezone string) (*Window, error) {
+	s, err := ParseSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(timezone) == 0 {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone: %s, err: %s", timezone, err)
+	}
+
+	w := &Window{
+		Schedule: schedule,
+		Timezone: timezone,
+		schedule: s,
+		location: loc,
+	}
+	return w, nil
+}
+
+// Contains returns true if a given time is in the window.
+func (w *Window) Contains(t time.Time) bool {
+	return w.schedule.Match(t.In(w.location))
+}
+
+// String returns a human readable representation of the window.
+func (w *Window) String() string {
+	return fmt.Sprintf("%q (%s)", w.Schedule, w.Timezone)
+}
+
+// Windows is a list of windows in which apply is allowed.
+type Windows []*Window
+
+// Contains returns true if a given time is in any of windows.
+// If no window is defined, it always returns true.
+func (ws Windows) Contains(t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	for _, w := range ws {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewWindow(t *testing.T) {
+	cases := []struct {
+		desc     string
+		schedule string
+		timezone string
+		want     string
+		ok       bool
+	}{
+		{
+			desc:     "default timezone",
+			schedule: "* 22-23 * * 1-5",
+			timezone: "",
+			want:     "UTC",
+			ok:       true,
+		},
+		{
+			desc:     "UTC",
+			schedule: "* 22-23 * * 1-5",
+			timezone: "UTC",
+			want:     "UTC",
+			ok:       true,
+		},
+		{
+			desc:     "invalid timezone",
+			schedule: "* 22-23 * * 1-5",
+			timezone: "Foo/Bar",
+			ok:       false,
+		},
+		{
+			desc:     "invalid schedule",
+			schedule: "foo",
+			timezone: "UTC",
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := NewWindow(tc.schedule, tc.timezone)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && got.Timezone != tc.want {
+				t.Errorf("got = %s, but want = %s", got.Timezone, tc.want)
+			}
+		})
+	}
+}
+
+func TestWindowsContains(t *testing.T) {
+	weekday, err := NewWindow("* 22-23 * * 1-5", "UTC")
+	if err != nil {
+		t.Fatalf("failed to create window: %s", err)
+	}
+	weekend, err := NewWindow("* * * * 0,6", "UTC")
+	if err != nil {
+		t.Fatalf("failed to create window: %s", err)
+	}
+	// UTC+9 without depending on the tz database.
+	jst := time.FixedZone("JST", 9*60*60)
+
+	cases := []struct {
+		desc string
+		ws   Windows
+		t    time.Time
+		want bool
+	}{
+		{
+			desc: "no window",
+			ws:   nil,
+			t:    time.Date(2020, 10, 13, 12, 0, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			desc: "in the first window",
+			ws:   Windows{weekday, weekend},
+			t:    time.Date(2020, 10, 13, 22, 0, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			desc: "in the second window",
+			ws:   Windows{weekday, weekend},
+			t:    time.Date(2020, 10, 17, 12, 0, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			desc: "out of windows",
+			ws:   Windows{weekday, weekend},
+			t:    time.Date(2020, 10, 13, 12, 0, 0, 0, time.UTC),
+			want: false,
+		},
+		{
+			desc: "converted to the timezone of window",
+			ws:   Windows{weekday},
+			// 2020-10-14 07:00 JST is 2020-10-13 22:00 UTC.
+			t:    time.Date(2020, 10, 14, 7, 0, 0, 0, jst),
+			want: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.ws.Contains(tc.t)
+			if got != tc.want {
+				t.Errorf("got = %t, but want = %t", got, tc.want)
+			}
+		})
+	}
+}